@@ -0,0 +1,64 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+const (
+	AccessRequestStatePending  = "pending"
+	AccessRequestStateApproved = "approved"
+	AccessRequestStateDenied   = "denied"
+)
+
+type AccessRequest struct {
+	ID      uid.ID `json:"id"`
+	Created Time   `json:"created"`
+	Updated Time   `json:"updated"`
+
+	User       uid.ID   `json:"user" note:"id of the user that requested access"`
+	Privilege  string   `json:"privilege" note:"a role or permission"`
+	Resource   string   `json:"resource" note:"a resource name in Infra's Universal Resource Notation"`
+	Reason     string   `json:"reason"`
+	Duration   Duration `json:"duration,omitempty" note:"how long the access should last once approved, empty if the access should not expire"`
+	State      string   `json:"state" note:"one of pending, approved, or denied"`
+	ReviewedBy uid.ID   `json:"reviewedBy,omitempty" note:"id of the user that approved or denied the request"`
+	Grant      uid.ID   `json:"grant,omitempty" note:"id of the grant created when the request was approved"`
+}
+
+type ListAccessRequestsRequest struct {
+	User  uid.ID `form:"user"`
+	State string `form:"state" example:"pending"`
+	PaginationRequest
+}
+
+func (r ListAccessRequestsRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Enum("state", r.State, []string{
+			AccessRequestStatePending,
+			AccessRequestStateApproved,
+			AccessRequestStateDenied,
+		}),
+	}
+}
+
+func (req ListAccessRequestsRequest) SetPage(page int) Paginatable {
+	req.PaginationRequest.Page = page
+
+	return req
+}
+
+type CreateAccessRequestRequest struct {
+	Privilege string   `json:"privilege" example:"edit" note:"a role or permission"`
+	Resource  string   `json:"resource" example:"production.payments" note:"a resource name in Infra's Universal Resource Notation"`
+	Reason    string   `json:"reason" note:"why the access is needed, shown to the approver"`
+	Duration  Duration `json:"duration,omitempty" note:"how long the access should last once approved"`
+}
+
+func (r CreateAccessRequestRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("privilege", r.Privilege),
+		validate.Required("resource", r.Resource),
+		validate.String("reason", r.Reason, 0, 1024),
+	}
+}
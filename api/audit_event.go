@@ -0,0 +1,40 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+type AuditEvent struct {
+	ID         uid.ID `json:"id"`
+	Created    Time   `json:"created"`
+	ActorID    uid.ID `json:"actorID" note:"the user that made the request, empty if the request was not authenticated"`
+	ActorName  string `json:"actorName"`
+	Method     string `json:"method" example:"POST"`
+	Path       string `json:"path" example:"/api/grants/:id"`
+	Resource   string `json:"resource" example:"grants"`
+	ResourceID uid.ID `json:"resourceID"`
+	Request    string `json:"request" note:"summary of the request body, with secrets redacted"`
+	StatusCode int    `json:"statusCode" example:"201"`
+}
+
+type ListAuditEventsRequest struct {
+	After      Time   `form:"after" note:"only include events created after this time"`
+	Before     Time   `form:"before" note:"only include events created before this time"`
+	ActorID    uid.ID `form:"actorID"`
+	Resource   string `form:"resource" example:"grants"`
+	ResourceID uid.ID `form:"resourceID"`
+	PaginationRequest
+}
+
+func (r ListAuditEventsRequest) ValidationRules() []validate.ValidationRule {
+	// no-op ValidationRules implementation so that the rules from the
+	// embedded PaginationRequest struct are not applied twice.
+	return nil
+}
+
+func (req ListAuditEventsRequest) SetPage(page int) Paginatable {
+	req.PaginationRequest.Page = page
+
+	return req
+}
@@ -69,6 +69,12 @@ func ErrorStatusCode(err error) int32 {
 	return 0
 }
 
+// responseHeaderReader is implemented by responses that include values from
+// the headers of the HTTP response.
+type responseHeaderReader interface {
+	readResponseHeader(header http.Header)
+}
+
 func request[Req, Res any](client Client, method string, path string, query Query, reqBody *Req) (*Res, error) {
 	var body []byte
 
@@ -135,6 +141,10 @@ func request[Req, Res any](client Client, method string, path string, query Quer
 		}
 	}
 
+	if r, ok := any(&resBody).(responseHeaderReader); ok {
+		r.readResponseHeader(resp.Header)
+	}
+
 	return &resBody, nil
 }
 
@@ -187,6 +197,27 @@ func (c Client) DeleteUser(id uid.ID) error {
 	return delete(c, fmt.Sprintf("/api/users/%s", id))
 }
 
+func (c Client) UnlockUser(id uid.ID) error {
+	_, err := post[EmptyRequest, EmptyResponse](c, fmt.Sprintf("/api/users/%s/unlock", id), &EmptyRequest{})
+	return err
+}
+
+func (c Client) EnrollMFA(id IDOrSelf) (*EnrollMFAResponse, error) {
+	return post[EmptyRequest, EnrollMFAResponse](c, fmt.Sprintf("/api/users/%s/mfa", id), &EmptyRequest{})
+}
+
+func (c Client) VerifyMFA(req *VerifyMFARequest) (*MFARecoveryCodesResponse, error) {
+	return post[VerifyMFARequest, MFARecoveryCodesResponse](c, fmt.Sprintf("/api/users/%s/mfa/verify", req.ID), req)
+}
+
+func (c Client) RegenerateMFARecoveryCodes(id IDOrSelf) (*MFARecoveryCodesResponse, error) {
+	return post[EmptyRequest, MFARecoveryCodesResponse](c, fmt.Sprintf("/api/users/%s/mfa/recovery-codes", id), &EmptyRequest{})
+}
+
+func (c Client) DisableMFA(id IDOrSelf) error {
+	return delete(c, fmt.Sprintf("/api/users/%s/mfa", id))
+}
+
 // Deprecated: use ListGrants
 func (c Client) ListUserGrants(id uid.ID) (*ListResponse[Grant], error) {
 	return get[ListResponse[Grant]](c, fmt.Sprintf("/api/users/%s/grants", id), Query{})
@@ -216,6 +247,11 @@ func (c Client) UpdateUsersInGroup(req *UpdateUsersInGroupRequest) error {
 	return err
 }
 
+func (c Client) UpdateGroupsInGroup(req *UpdateGroupsInGroupRequest) error {
+	_, err := patch[UpdateGroupsInGroupRequest, EmptyResponse](c, fmt.Sprintf("/api/groups/%s/groups", req.GroupID), req)
+	return err
+}
+
 // Deprecated: use ListGrants
 func (c Client) ListGroupGrants(id uid.ID) (*ListResponse[Grant], error) {
 	return get[ListResponse[Grant]](c, fmt.Sprintf("/api/groups/%s/grants", id), Query{})
@@ -247,6 +283,16 @@ func (c Client) DeleteOrganization(id uid.ID) error {
 	return delete(c, fmt.Sprintf("/api/organizations/%s", id))
 }
 
+func (c Client) ExportOrganization(id IDOrSelf) (*OrganizationExport, error) {
+	return get[OrganizationExport](c, fmt.Sprintf("/api/organizations/%s/export", id), Query{})
+}
+
+func (c Client) ImportOrganization(req *ImportOrganizationRequest) (*ImportOrganizationResponse, error) {
+	path := fmt.Sprintf("/api/organizations/%s/import", req.ID)
+	query := Query{"dryRun": {strconv.FormatBool(req.DryRun)}}
+	return request[ImportOrganizationRequest, ImportOrganizationResponse](c, http.MethodPost, path, query, req)
+}
+
 func (c Client) GetProvider(id uid.ID) (*Provider, error) {
 	return get[Provider](c, fmt.Sprintf("/api/providers/%s", id), Query{})
 }
@@ -263,15 +309,20 @@ func (c Client) DeleteProvider(id uid.ID) error {
 	return delete(c, fmt.Sprintf("/api/providers/%s", id))
 }
 
+func (c Client) CreateSCIMAccessKey(req *CreateSCIMAccessKeyRequest) (*CreateAccessKeyResponse, error) {
+	return post[CreateSCIMAccessKeyRequest, CreateAccessKeyResponse](c, fmt.Sprintf("/api/providers/%s/scim-access-keys", req.ID), req)
+}
+
 func (c Client) ListGrants(req ListGrantsRequest) (*ListResponse[Grant], error) {
 	return get[ListResponse[Grant]](c, "/api/grants", Query{
-		"user":          {req.User.String()},
-		"group":         {req.Group.String()},
-		"resource":      {req.Resource},
-		"privilege":     {req.Privilege},
-		"showInherited": {strconv.FormatBool(req.ShowInherited)},
-		"showSystem":    {strconv.FormatBool(req.ShowSystem)},
-		"page":          {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+		"user":           {req.User.String()},
+		"group":          {req.Group.String()},
+		"resource":       {req.Resource},
+		"resourcePrefix": {req.ResourcePrefix},
+		"privilege":      {req.Privilege},
+		"showInherited":  {strconv.FormatBool(req.ShowInherited)},
+		"showSystem":     {strconv.FormatBool(req.ShowSystem)},
+		"page":           {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
 	})
 }
 
@@ -283,6 +334,30 @@ func (c Client) DeleteGrant(id uid.ID) error {
 	return delete(c, fmt.Sprintf("/api/grants/%s", id))
 }
 
+func (c Client) ListAccessRequests(req ListAccessRequestsRequest) (*ListResponse[AccessRequest], error) {
+	return get[ListResponse[AccessRequest]](c, "/api/access-requests", Query{
+		"user":  {req.User.String()},
+		"state": {req.State},
+		"page":  {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) GetAccessRequest(id uid.ID) (*AccessRequest, error) {
+	return get[AccessRequest](c, fmt.Sprintf("/api/access-requests/%s", id), Query{})
+}
+
+func (c Client) CreateAccessRequest(req *CreateAccessRequestRequest) (*AccessRequest, error) {
+	return post[CreateAccessRequestRequest, AccessRequest](c, "/api/access-requests", req)
+}
+
+func (c Client) ApproveAccessRequest(id uid.ID) (*AccessRequest, error) {
+	return post[EmptyRequest, AccessRequest](c, fmt.Sprintf("/api/access-requests/%s/approve", id), &EmptyRequest{})
+}
+
+func (c Client) DenyAccessRequest(id uid.ID) (*AccessRequest, error) {
+	return post[EmptyRequest, AccessRequest](c, fmt.Sprintf("/api/access-requests/%s/deny", id), &EmptyRequest{})
+}
+
 func (c Client) ListDestinations(req ListDestinationsRequest) (*ListResponse[Destination], error) {
 	return get[ListResponse[Destination]](c, "/api/destinations", Query{
 		"name":      {req.Name},
@@ -303,6 +378,32 @@ func (c Client) DeleteDestination(id uid.ID) error {
 	return delete(c, fmt.Sprintf("/api/destinations/%s", id))
 }
 
+func (c Client) ListDestinationEvents(req ListDestinationEventsRequest) (*ListDestinationEventsResponse, error) {
+	return get[ListDestinationEventsResponse](c, fmt.Sprintf("/api/destinations/%s/events", req.ID), Query{
+		"cursor": {req.Cursor},
+		"wait":   {req.Wait.String()},
+	})
+}
+
+// ListDestinationGrants returns the grants that apply to a destination. When
+// req.ETag is set and the grants have not changed, the response has NotModified
+// set and no items.
+func (c Client) ListDestinationGrants(req ListDestinationGrantsRequest) (*ListDestinationGrantsResponse, error) {
+	if req.ETag != "" {
+		c.Headers = c.Headers.Clone()
+		if c.Headers == nil {
+			c.Headers = http.Header{}
+		}
+		c.Headers.Set("If-None-Match", req.ETag)
+	}
+
+	resp, err := get[ListDestinationGrantsResponse](c, fmt.Sprintf("/api/destinations/%s/grants", req.ID), Query{})
+	if ErrorStatusCode(err) == http.StatusNotModified {
+		return &ListDestinationGrantsResponse{ETag: req.ETag, NotModified: true}, nil
+	}
+	return resp, err
+}
+
 func (c Client) ListAccessKeys(req ListAccessKeysRequest) (*ListResponse[AccessKey], error) {
 	return get[ListResponse[AccessKey]](c, "/api/access-keys", Query{
 		"user_id":      {req.UserID.String()},
@@ -320,8 +421,16 @@ func (c Client) DeleteAccessKey(id uid.ID) error {
 	return delete(c, fmt.Sprintf("/api/access-keys/%s", id))
 }
 
-func (c Client) CreateToken() (*CreateTokenResponse, error) {
-	return post[EmptyRequest, CreateTokenResponse](c, "/api/tokens", &EmptyRequest{})
+func (c Client) CreateToken(req *CreateTokenRequest) (*CreateTokenResponse, error) {
+	return post[CreateTokenRequest, CreateTokenResponse](c, "/api/tokens", req)
+}
+
+func (c Client) CreateSSHCertificate(req *CreateSSHCertificateRequest) (*CreateSSHCertificateResponse, error) {
+	return post[CreateSSHCertificateRequest, CreateSSHCertificateResponse](c, "/api/ssh/certificates", req)
+}
+
+func (c Client) GetSSHUserCA() (*SSHUserCA, error) {
+	return get[SSHUserCA](c, "/api/ssh/user-ca", Query{})
 }
 
 func (c Client) Login(req *LoginRequest) (*LoginResponse, error) {
@@ -333,6 +442,75 @@ func (c Client) Logout() error {
 	return err
 }
 
+func (c Client) ListFederations(req ListFederationsRequest) (*ListResponse[Federation], error) {
+	return get[ListResponse[Federation]](c, "/api/federations", Query{
+		"name": {req.Name},
+		"page": {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) GetFederation(id uid.ID) (*Federation, error) {
+	return get[Federation](c, fmt.Sprintf("/api/federations/%s", id), Query{})
+}
+
+func (c Client) CreateFederation(req *CreateFederationRequest) (*Federation, error) {
+	return post[CreateFederationRequest, Federation](c, "/api/federations", req)
+}
+
+func (c Client) UpdateFederation(req UpdateFederationRequest) (*Federation, error) {
+	return put[UpdateFederationRequest, Federation](c, fmt.Sprintf("/api/federations/%s", req.ID.String()), &req)
+}
+
+func (c Client) DeleteFederation(id uid.ID) error {
+	return delete(c, fmt.Sprintf("/api/federations/%s", id))
+}
+
+func (c Client) ListOIDCClients(req ListOIDCClientsRequest) (*ListResponse[OIDCClient], error) {
+	return get[ListResponse[OIDCClient]](c, "/api/oidc-clients", Query{
+		"name": {req.Name},
+		"page": {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) GetOIDCClient(id uid.ID) (*OIDCClient, error) {
+	return get[OIDCClient](c, fmt.Sprintf("/api/oidc-clients/%s", id), Query{})
+}
+
+func (c Client) CreateOIDCClient(req *CreateOIDCClientRequest) (*CreateOIDCClientResponse, error) {
+	return post[CreateOIDCClientRequest, CreateOIDCClientResponse](c, "/api/oidc-clients", req)
+}
+
+func (c Client) UpdateOIDCClient(req UpdateOIDCClientRequest) (*OIDCClient, error) {
+	return put[UpdateOIDCClientRequest, OIDCClient](c, fmt.Sprintf("/api/oidc-clients/%s", req.ID.String()), &req)
+}
+
+func (c Client) DeleteOIDCClient(id uid.ID) error {
+	return delete(c, fmt.Sprintf("/api/oidc-clients/%s", id))
+}
+
+func (c Client) StartDeviceFlow() (*StartDeviceFlowResponse, error) {
+	return post[EmptyRequest, StartDeviceFlowResponse](c, "/api/device", &EmptyRequest{})
+}
+
+func (c Client) PollDeviceFlow(req *PollDeviceFlowRequest) (*PollDeviceFlowResponse, error) {
+	return post[PollDeviceFlowRequest, PollDeviceFlowResponse](c, "/api/device/token", req)
+}
+
+func (c Client) ApproveDeviceFlow(req *ApproveDeviceFlowRequest) error {
+	_, err := post[ApproveDeviceFlowRequest, EmptyResponse](c, "/api/device/approve", req)
+	return err
+}
+
+func (c Client) ListDeviceFlowAuthRequests(req ListDeviceFlowAuthRequestsRequest) (*ListResponse[DeviceFlowAuthRequest], error) {
+	return get[ListResponse[DeviceFlowAuthRequest]](c, "/api/device", Query{
+		"page": {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) DeleteDeviceFlowAuthRequest(id uid.ID) error {
+	return delete(c, fmt.Sprintf("/api/device/%s", id))
+}
+
 func (c Client) Signup(req *SignupRequest) (*SignupResponse, error) {
 	return post[SignupRequest, SignupResponse](c, "/api/signup", req)
 }
@@ -349,6 +527,73 @@ func (c Client) UpdateSettings(req *Settings) (*Settings, error) {
 	return put[Settings, Settings](c, "/api/settings", req)
 }
 
+func (c Client) ListSigningKeys(req ListSigningKeysRequest) (*ListResponse[SigningKey], error) {
+	return get[ListResponse[SigningKey]](c, "/api/settings/signing-keys", Query{
+		"page": {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) RotateSigningKeys(req *RotateSigningKeysRequest) (*SigningKey, error) {
+	return post[RotateSigningKeysRequest, SigningKey](c, "/api/settings/signing-keys/rotate", req)
+}
+
+func (c Client) ListAuditEvents(req ListAuditEventsRequest) (*ListResponse[AuditEvent], error) {
+	query := Query{
+		"actorID":    {req.ActorID.String()},
+		"resource":   {req.Resource},
+		"resourceID": {req.ResourceID.String()},
+		"page":       {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+	}
+	if !req.After.Time().IsZero() {
+		query["after"] = []string{req.After.String()}
+	}
+	if !req.Before.Time().IsZero() {
+		query["before"] = []string{req.Before.String()}
+	}
+	return get[ListResponse[AuditEvent]](c, "/api/audit-events", query)
+}
+
+func (c Client) ListWebhooks(req ListWebhooksRequest) (*ListResponse[Webhook], error) {
+	return get[ListResponse[Webhook]](c, "/api/webhooks", Query{
+		"page": {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) GetWebhook(id uid.ID) (*Webhook, error) {
+	return get[Webhook](c, fmt.Sprintf("/api/webhooks/%s", id), Query{})
+}
+
+func (c Client) CreateWebhook(req *CreateWebhookRequest) (*CreateWebhookResponse, error) {
+	return post[CreateWebhookRequest, CreateWebhookResponse](c, "/api/webhooks", req)
+}
+
+func (c Client) DeleteWebhook(id uid.ID) error {
+	return delete(c, fmt.Sprintf("/api/webhooks/%s", id))
+}
+
+func (c Client) ListRoles(req ListRolesRequest) (*ListResponse[Role], error) {
+	return get[ListResponse[Role]](c, "/api/roles", Query{
+		"name": {req.Name},
+		"page": {strconv.Itoa(req.Page)}, "limit": {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) GetRole(id uid.ID) (*Role, error) {
+	return get[Role](c, fmt.Sprintf("/api/roles/%s", id), Query{})
+}
+
+func (c Client) CreateRole(req *CreateRoleRequest) (*Role, error) {
+	return post[CreateRoleRequest, Role](c, "/api/roles", req)
+}
+
+func (c Client) UpdateRole(req *UpdateRoleRequest) (*Role, error) {
+	return put[UpdateRoleRequest, Role](c, fmt.Sprintf("/api/roles/%s", req.ID), req)
+}
+
+func (c Client) DeleteRole(id uid.ID) error {
+	return delete(c, fmt.Sprintf("/api/roles/%s", id))
+}
+
 func partialText(body []byte, limit int) string {
 	if len(body) <= limit {
 		return string(body)
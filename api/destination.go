@@ -1,6 +1,8 @@
 package api
 
 import (
+	"net/http"
+
 	"github.com/infrahq/infra/internal/validate"
 	"github.com/infrahq/infra/uid"
 )
@@ -88,3 +90,92 @@ func (req ListDestinationsRequest) SetPage(page int) Paginatable {
 
 	return req
 }
+
+const (
+	DestinationEventGrantCreated = "grant.created"
+	DestinationEventGrantUpdated = "grant.updated"
+	DestinationEventGrantDeleted = "grant.deleted"
+	DestinationEventRoleUpdated  = "role.updated"
+	DestinationEventRoleDeleted  = "role.deleted"
+	DestinationEventUserUpdated  = "user.updated"
+	DestinationEventGroupUpdated = "group.updated"
+)
+
+// DestinationEvent is a change to a grant, role, user, or group that can
+// change access to a destination. Only one of Grant, Role, User, or Group is
+// set, depending on the type of the event.
+type DestinationEvent struct {
+	Sequence int64  `json:"sequence"`
+	Type     string `json:"type" example:"grant.created"`
+	Created  Time   `json:"created"`
+
+	Grant *Grant `json:"grant,omitempty"`
+	Role  *Role  `json:"role,omitempty"`
+	User  *User  `json:"user,omitempty"`
+	Group *Group `json:"group,omitempty"`
+}
+
+type ListDestinationEventsRequest struct {
+	ID     uid.ID   `uri:"id" json:"-"`
+	Cursor string   `form:"cursor" note:"the cursor from the previous response, or empty to get the current cursor without any events"`
+	Wait   Duration `form:"wait" note:"how long to wait for an event when there are no events after the cursor, at most 30s" example:"30s"`
+}
+
+func (r ListDestinationEventsRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+	}
+}
+
+type ListDestinationEventsResponse struct {
+	Items  []DestinationEvent `json:"items"`
+	Cursor string             `json:"cursor" note:"the cursor to use in the next request"`
+	Resync bool               `json:"resync" note:"true when the events after the cursor are no longer available. The destination must list grants and roles again, and use the cursor from this response"`
+}
+
+// DestinationGrant is a grant that applies to a destination, with the name of
+// the user or group it was granted to.
+type DestinationGrant struct {
+	Grant       `json:",inline"`
+	SubjectKind string `json:"subjectKind" example:"user" note:"user or group"`
+	SubjectName string `json:"subjectName" example:"alice@example.com" note:"the name of the user or group"`
+}
+
+const (
+	DestinationGrantSubjectUser  = "user"
+	DestinationGrantSubjectGroup = "group"
+)
+
+type ListDestinationGrantsRequest struct {
+	ID uid.ID `uri:"id" json:"-"`
+	// ETag is sent as the If-None-Match header. It is the ETag of the
+	// previous response.
+	ETag string `form:"-" json:"-"`
+}
+
+func (r ListDestinationGrantsRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+	}
+}
+
+type ListDestinationGrantsResponse struct {
+	Items []DestinationGrant `json:"items"`
+
+	// ETag identifies the grants in Items, and is sent as the ETag header.
+	ETag string `json:"-"`
+	// NotModified is true when the grants have not changed since the ETag of
+	// the request. Items is empty when NotModified is true.
+	NotModified bool `json:"-"`
+}
+
+func (r *ListDestinationGrantsResponse) StatusCode() int {
+	if r.NotModified {
+		return http.StatusNotModified
+	}
+	return http.StatusOK
+}
+
+func (r *ListDestinationGrantsResponse) readResponseHeader(header http.Header) {
+	r.ETag = header.Get("ETag")
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+const (
+	DeviceFlowStatusPending   = "pending"
+	DeviceFlowStatusConfirmed = "confirmed"
+	// DeviceFlowStatusSlowDown is returned when the device polls more often
+	// than the interval. The device must add 5 seconds to its interval.
+	DeviceFlowStatusSlowDown = "slow_down"
+)
+
+type StartDeviceFlowResponse struct {
+	DeviceCode      string `json:"deviceCode" note:"secret used by the device to poll for an access key"`
+	UserCode        string `json:"userCode" example:"BDSD-HQMK" note:"code the user enters in the browser to approve the login"`
+	VerificationURI string `json:"verificationURI" example:"https://infra.example.com/device"`
+	ExpiresIn       int64  `json:"expiresIn" note:"number of seconds until the device code expires"`
+	Interval        int64  `json:"interval" note:"minimum number of seconds the device should wait between polls"`
+}
+
+type PollDeviceFlowRequest struct {
+	DeviceCode string `json:"deviceCode"`
+}
+
+func (r PollDeviceFlowRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("deviceCode", r.DeviceCode),
+	}
+}
+
+type PollDeviceFlowResponse struct {
+	Status     string         `json:"status" note:"one of pending, slow_down, or confirmed. Add 5 seconds to the interval after slow_down"`
+	DeviceCode string         `json:"deviceCode"`
+	Login      *LoginResponse `json:"login,omitempty" note:"set once the status is confirmed"`
+}
+
+type ApproveDeviceFlowRequest struct {
+	UserCode string `json:"userCode" example:"BDSD-HQMK"`
+}
+
+func (r ApproveDeviceFlowRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("userCode", r.UserCode),
+	}
+}
+
+type DeviceFlowAuthRequest struct {
+	ID         uid.ID `json:"id"`
+	Created    Time   `json:"created"`
+	Expires    Time   `json:"expires"`
+	UserCode   string `json:"userCode" example:"BDSD-HQMK"`
+	ApprovedBy uid.ID `json:"approvedBy,omitempty" note:"id of the user that approved the login, empty while the login is pending"`
+}
+
+type ListDeviceFlowAuthRequestsRequest struct {
+	PaginationRequest
+}
+
+func (r ListDeviceFlowAuthRequestsRequest) ValidationRules() []validate.ValidationRule {
+	// no-op ValidationRules implementation so that the rules from the
+	// embedded PaginationRequest struct are not applied twice.
+	return nil
+}
+
+func (req ListDeviceFlowAuthRequestsRequest) SetPage(page int) Paginatable {
+	req.PaginationRequest.Page = page
+
+	return req
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+// Federation allows a workload, like a CI job, to login with a JWT signed by
+// an external issuer instead of an access key. A token from the issuer with
+// the expected audience, that matches all of the conditions, is exchanged for
+// a short-lived access key for the user.
+type Federation struct {
+	ID         uid.ID            `json:"id"`
+	Created    Time              `json:"created"`
+	Updated    Time              `json:"updated"`
+	Name       string            `json:"name" example:"github-deploy"`
+	Issuer     string            `json:"issuer" example:"https://token.actions.githubusercontent.com"`
+	JWKSURL    string            `json:"jwksURL,omitempty" note:"URL of the JSON Web Key Set of the issuer. When empty it is read from the OpenID configuration of the issuer"`
+	Audience   string            `json:"audience" example:"https://infra.example.com"`
+	Conditions map[string]string `json:"conditions" example:"{\"repository\": \"example/app\", \"ref\": \"refs/heads/main\"}" note:"claims that the token must have. Values may use * as a wildcard. Must include a sub or repository claim"`
+	User       uid.ID            `json:"user" note:"id of the user that the access key is issued for"`
+	TTL        Duration          `json:"ttl" note:"how long the access key is valid"`
+}
+
+type ListFederationsRequest struct {
+	Name string `form:"name"`
+	PaginationRequest
+}
+
+func (r ListFederationsRequest) ValidationRules() []validate.ValidationRule {
+	// no-op ValidationRules implementation so that the rules from the
+	// embedded PaginationRequest struct are not applied twice.
+	return nil
+}
+
+func (req ListFederationsRequest) SetPage(page int) Paginatable {
+	req.PaginationRequest.Page = page
+
+	return req
+}
+
+type CreateFederationRequest struct {
+	Name       string            `json:"name" example:"github-deploy"`
+	Issuer     string            `json:"issuer" example:"https://token.actions.githubusercontent.com"`
+	JWKSURL    string            `json:"jwksURL,omitempty"`
+	Audience   string            `json:"audience" example:"https://infra.example.com"`
+	Conditions map[string]string `json:"conditions" note:"claims that the token must have. Must include a sub or repository claim"`
+	User       uid.ID            `json:"user"`
+	TTL        Duration          `json:"ttl,omitempty" note:"how long the access key is valid, defaults to 1 hour"`
+}
+
+func (r CreateFederationRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("name", r.Name),
+		validate.String("name", r.Name, 2, 256,
+			validate.AlphabetLower,
+			validate.Numbers,
+			validate.Dash,
+			validate.Underscore,
+			validate.Dot,
+		),
+		validate.Required("issuer", r.Issuer),
+		validate.Required("audience", r.Audience),
+		validate.Required("conditions", r.Conditions),
+		validate.Required("user", r.User),
+	}
+}
+
+type UpdateFederationRequest struct {
+	ID         uid.ID            `uri:"id" json:"-"`
+	Issuer     string            `json:"issuer" example:"https://token.actions.githubusercontent.com"`
+	JWKSURL    string            `json:"jwksURL,omitempty"`
+	Audience   string            `json:"audience" example:"https://infra.example.com"`
+	Conditions map[string]string `json:"conditions" note:"claims that the token must have. Must include a sub or repository claim"`
+	User       uid.ID            `json:"user"`
+	TTL        Duration          `json:"ttl,omitempty" note:"how long the access key is valid, defaults to 1 hour"`
+}
+
+func (r UpdateFederationRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.Required("issuer", r.Issuer),
+		validate.Required("audience", r.Audience),
+		validate.Required("conditions", r.Conditions),
+		validate.Required("user", r.User),
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/infrahq/infra/internal/validate"
 	"github.com/infrahq/infra/uid"
@@ -18,6 +19,7 @@ type Grant struct {
 	Group     uid.ID `json:"group,omitempty"`
 	Privilege string `json:"privilege" note:"a role or permission"`
 	Resource  string `json:"resource" note:"a resource name in Infra's Universal Resource Notation"`
+	Expires   Time   `json:"expires" note:"the grant is no longer valid after this time, empty if the grant does not expire"`
 }
 
 type CreateGrantResponse struct {
@@ -33,12 +35,13 @@ func (r *CreateGrantResponse) StatusCode() int {
 }
 
 type ListGrantsRequest struct {
-	User          uid.ID `form:"user"`
-	Group         uid.ID `form:"group"`
-	Resource      string `form:"resource" example:"production"`
-	Privilege     string `form:"privilege" example:"view"`
-	ShowInherited bool   `form:"showInherited" note:"if true, this field includes grants that the user inherits through groups"`
-	ShowSystem    bool   `form:"showSystem" note:"if true, this shows the connector and other internal grants"`
+	User           uid.ID `form:"user"`
+	Group          uid.ID `form:"group"`
+	Resource       string `form:"resource" example:"production"`
+	ResourcePrefix string `form:"resourcePrefix" example:"production" note:"includes grants for this resource, its sub-resources, and grants with a wildcard resource"`
+	Privilege      string `form:"privilege" example:"view"`
+	ShowInherited  bool   `form:"showInherited" note:"if true, this field includes grants that the user inherits through groups"`
+	ShowSystem     bool   `form:"showSystem" note:"if true, this shows the connector and other internal grants"`
 	PaginationRequest
 }
 
@@ -48,6 +51,10 @@ func (r ListGrantsRequest) ValidationRules() []validate.ValidationRule {
 			validate.Field{Name: "user", Value: r.User},
 			validate.Field{Name: "group", Value: r.Group},
 		),
+		validate.MutuallyExclusive(
+			validate.Field{Name: "resource", Value: r.Resource},
+			validate.Field{Name: "resourcePrefix", Value: r.ResourcePrefix},
+		),
 	}
 }
 
@@ -56,6 +63,7 @@ type CreateGrantRequest struct {
 	Group     uid.ID `json:"group"`
 	Privilege string `json:"privilege" example:"view" note:"a role or permission"`
 	Resource  string `json:"resource" example:"production" note:"a resource name in Infra's Universal Resource Notation"`
+	Expires   Time   `json:"expires" note:"optional time when the grant should stop being valid"`
 }
 
 func (r CreateGrantRequest) ValidationRules() []validate.ValidationRule {
@@ -74,3 +82,50 @@ func (req ListGrantsRequest) SetPage(page int) Paginatable {
 
 	return req
 }
+
+// IsResourcePattern returns true if resource contains a '*' wildcard.
+func IsResourcePattern(resource string) bool {
+	return strings.Contains(resource, "*")
+}
+
+// MatchResource returns true if resource matches pattern. Each dot separated
+// part of the pattern may contain '*' wildcards, which match any sequence of
+// characters within that part. A pattern only matches resources with the same
+// number of parts, so "production.*" matches every namespace of the
+// production cluster, but not the cluster itself.
+func MatchResource(pattern, resource string) bool {
+	patternParts := strings.Split(pattern, ".")
+	resourceParts := strings.Split(resource, ".")
+	if len(patternParts) != len(resourceParts) {
+		return false
+	}
+
+	for i := range patternParts {
+		if !matchWildcard(patternParts[i], resourceParts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchWildcard(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, last)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestMatchResource(t *testing.T) {
+	type testCase struct {
+		pattern  string
+		resource string
+		expected bool
+	}
+
+	testCases := []testCase{
+		{pattern: "production", resource: "production", expected: true},
+		{pattern: "production", resource: "production-eu", expected: false},
+		{pattern: "production.default", resource: "production.default", expected: true},
+		{pattern: "production.*", resource: "production.default", expected: true},
+		{pattern: "production.*", resource: "production", expected: false},
+		{pattern: "production.*", resource: "staging.default", expected: false},
+		{pattern: "*.monitoring", resource: "production.monitoring", expected: true},
+		{pattern: "*.monitoring", resource: "production.default", expected: false},
+		{pattern: "staging-*", resource: "staging-eu", expected: true},
+		{pattern: "staging-*", resource: "staging", expected: false},
+		{pattern: "staging-*", resource: "staging-eu.default", expected: false},
+		{pattern: "*", resource: "production", expected: true},
+		{pattern: "*-eu.team-*", resource: "staging-eu.team-a", expected: true},
+		{pattern: "*-eu.team-*", resource: "staging-us.team-a", expected: false},
+		{pattern: "a*b*c", resource: "abc", expected: true},
+		{pattern: "a*b*c", resource: "axxbyyc", expected: true},
+		{pattern: "a*b*c", resource: "axxc", expected: false},
+		{pattern: "ab*ba", resource: "aba", expected: false},
+	}
+
+	for _, tc := range testCases {
+		actual := MatchResource(tc.pattern, tc.resource)
+		assert.Equal(t, actual, tc.expected, "pattern=%v resource=%v", tc.pattern, tc.resource)
+	}
+}
@@ -11,6 +11,8 @@ type Group struct {
 	Created    Time   `json:"created"`
 	Updated    Time   `json:"updated"`
 	TotalUsers int    `json:"totalUsers"`
+	// Parents are the IDs of the groups that this group is a member of.
+	Parents []uid.ID `json:"parents,omitempty" note:"IDs of the groups that this group is a member of"`
 }
 
 type ListGroupsRequest struct {
@@ -49,6 +51,18 @@ func (r UpdateUsersInGroupRequest) ValidationRules() []validate.ValidationRule {
 	}
 }
 
+type UpdateGroupsInGroupRequest struct {
+	GroupID          uid.ID   `uri:"id" json:"-"`
+	GroupIDsToAdd    []uid.ID `json:"groupsToAdd"`
+	GroupIDsToRemove []uid.ID `json:"groupsToRemove"`
+}
+
+func (r UpdateGroupsInGroupRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.GroupID),
+	}
+}
+
 func (req ListGroupsRequest) SetPage(page int) Paginatable {
 
 	req.PaginationRequest.Page = page
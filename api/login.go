@@ -22,6 +22,7 @@ func (r LoginRequestOIDC) ValidationRules() []validate.ValidationRule {
 type LoginRequestPasswordCredentials struct {
 	Name     string `json:"name"`
 	Password string `json:"password"`
+	MFACode  string `json:"mfaCode,omitempty" note:"a code from the user's authenticator app, or one of their recovery codes. Required when the login response has mfaRequired"`
 }
 
 func (r LoginRequestPasswordCredentials) ValidationRules() []validate.ValidationRule {
@@ -31,10 +32,49 @@ func (r LoginRequestPasswordCredentials) ValidationRules() []validate.Validation
 	}
 }
 
+type LoginRequestFederation struct {
+	Token string `json:"token" note:"a JWT signed by the issuer of a federation"`
+}
+
+func (r LoginRequestFederation) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("token", r.Token),
+	}
+}
+
+type LoginRequestLDAP struct {
+	ProviderID uid.ID `json:"providerID"`
+	Name       string `json:"name" note:"the name used to find the user with the user filter of the provider"`
+	Password   string `json:"password"`
+}
+
+func (r LoginRequestLDAP) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("providerID", r.ProviderID),
+		validate.Required("name", r.Name),
+		validate.Required("password", r.Password),
+	}
+}
+
+type LoginRequestSAML struct {
+	ProviderID   uid.ID `json:"providerID"`
+	SAMLResponse string `json:"samlResponse" note:"the base64 encoded SAML response the identity provider sent to the assertion consumer service"`
+}
+
+func (r LoginRequestSAML) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("providerID", r.ProviderID),
+		validate.Required("samlResponse", r.SAMLResponse),
+	}
+}
+
 type LoginRequest struct {
 	AccessKey           string                           `json:"accessKey"`
 	PasswordCredentials *LoginRequestPasswordCredentials `json:"passwordCredentials"`
 	OIDC                *LoginRequestOIDC                `json:"oidc"`
+	Federation          *LoginRequestFederation          `json:"federation"`
+	LDAP                *LoginRequestLDAP                `json:"ldap"`
+	SAML                *LoginRequestSAML                `json:"saml"`
 }
 
 func (r LoginRequest) ValidationRules() []validate.ValidationRule {
@@ -43,6 +83,9 @@ func (r LoginRequest) ValidationRules() []validate.ValidationRule {
 			validate.Field{Name: "accessKey", Value: r.AccessKey},
 			validate.Field{Name: "passwordCredentials", Value: r.PasswordCredentials},
 			validate.Field{Name: "oidc", Value: r.OIDC},
+			validate.Field{Name: "federation", Value: r.Federation},
+			validate.Field{Name: "ldap", Value: r.LDAP},
+			validate.Field{Name: "saml", Value: r.SAML},
 		),
 	}
 }
@@ -52,5 +95,11 @@ type LoginResponse struct {
 	Name                   string `json:"name"`
 	AccessKey              string `json:"accessKey"`
 	PasswordUpdateRequired bool   `json:"passwordUpdateRequired,omitempty"`
-	Expires                Time   `json:"expires"`
+	// MFARequired is true when the user must login again with an MFA code. No
+	// access key is issued.
+	MFARequired bool `json:"mfaRequired,omitempty"`
+	// MFAEnrollmentRequired is true when the user must enable multi-factor
+	// authentication. The access key can only be used to enable it.
+	MFAEnrollmentRequired bool `json:"mfaEnrollmentRequired,omitempty"`
+	Expires               Time `json:"expires"`
 }
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+)
+
+type EnrollMFARequest struct {
+	ID IDOrSelf `uri:"id"`
+}
+
+func (r EnrollMFARequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+	}
+}
+
+type EnrollMFAResponse struct {
+	Secret string `json:"secret" note:"the TOTP secret, to add to an authenticator app"`
+	URI    string `json:"uri" note:"an otpauth:// URI for the secret, usually shown as a QR code" example:"otpauth://totp/Infra:admin@example.com?issuer=Infra&secret=JBSWY3DPEHPK3PXP"`
+}
+
+type VerifyMFARequest struct {
+	ID   IDOrSelf `uri:"id" json:"-"`
+	Code string   `json:"code" example:"123456"`
+}
+
+func (r VerifyMFARequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.Required("code", r.Code),
+	}
+}
+
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes" note:"codes that can each be used once to login instead of a TOTP code. They are not shown again"`
+}
+
+type RegenerateMFARecoveryCodesRequest struct {
+	ID IDOrSelf `uri:"id"`
+}
+
+func (r RegenerateMFARecoveryCodesRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+	}
+}
+
+type DisableMFARequest struct {
+	ID IDOrSelf `uri:"id"`
+}
+
+func (r DisableMFARequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+	}
+}
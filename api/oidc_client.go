@@ -0,0 +1,75 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+// OIDCClient is a web application that logs in users with Infra as its OpenID
+// Connect identity provider. Users can only login to the application if they
+// have a grant with the name of the application as the resource.
+type OIDCClient struct {
+	ID           uid.ID   `json:"id"`
+	Created      Time     `json:"created"`
+	Updated      Time     `json:"updated"`
+	Name         string   `json:"name" example:"grafana"`
+	ClientID     string   `json:"clientID" example:"lS8bAuxUtWkQTDzoryT1hBJb"`
+	RedirectURIs []string `json:"redirectURIs" example:"[\"https://grafana.example.com/login/generic_oauth\"]"`
+}
+
+type ListOIDCClientsRequest struct {
+	Name string `form:"name"`
+	PaginationRequest
+}
+
+func (r ListOIDCClientsRequest) ValidationRules() []validate.ValidationRule {
+	// no-op ValidationRules implementation so that the rules from the
+	// embedded PaginationRequest struct are not applied twice.
+	return nil
+}
+
+func (req ListOIDCClientsRequest) SetPage(page int) Paginatable {
+	req.PaginationRequest.Page = page
+
+	return req
+}
+
+type CreateOIDCClientRequest struct {
+	Name         string   `json:"name" example:"grafana"`
+	RedirectURIs []string `json:"redirectURIs" example:"[\"https://grafana.example.com/login/generic_oauth\"]"`
+}
+
+func (r CreateOIDCClientRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("name", r.Name),
+		validate.String("name", r.Name, 2, 256,
+			validate.AlphabetLower,
+			validate.Numbers,
+			validate.Dash,
+			validate.Underscore,
+			validate.Dot,
+		),
+		validate.Required("redirectURIs", r.RedirectURIs),
+	}
+}
+
+type CreateOIDCClientResponse struct {
+	ID           uid.ID   `json:"id"`
+	Created      Time     `json:"created"`
+	Name         string   `json:"name"`
+	ClientID     string   `json:"clientID"`
+	ClientSecret string   `json:"clientSecret" note:"only returned when the client is created"`
+	RedirectURIs []string `json:"redirectURIs"`
+}
+
+type UpdateOIDCClientRequest struct {
+	ID           uid.ID   `uri:"id" json:"-"`
+	RedirectURIs []string `json:"redirectURIs" example:"[\"https://grafana.example.com/login/generic_oauth\"]"`
+}
+
+func (r UpdateOIDCClientRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.Required("redirectURIs", r.RedirectURIs),
+	}
+}
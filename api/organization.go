@@ -1,6 +1,8 @@
 package api
 
 import (
+	"net/http"
+
 	"github.com/infrahq/infra/internal/validate"
 	"github.com/infrahq/infra/uid"
 )
@@ -41,3 +43,69 @@ func (req ListOrganizationsRequest) SetPage(page int) Paginatable {
 	req.PaginationRequest.Page = page
 	return req
 }
+
+// OrganizationExportVersion is the version of the OrganizationExport document
+// produced by this version of the API.
+const OrganizationExportVersion = 1
+
+// OrganizationExport is a copy of the users, groups, grants, providers,
+// destinations, and settings of an organization. Secrets, like provider
+// client secrets and user passwords, are not included.
+type OrganizationExport struct {
+	Version      int             `json:"version" example:"1"`
+	Organization Organization    `json:"organization"`
+	Providers    []Provider      `json:"providers"`
+	Users        []User          `json:"users"`
+	Groups       []ExportedGroup `json:"groups"`
+	Grants       []Grant         `json:"grants"`
+	Destinations []Destination   `json:"destinations"`
+	Settings     Settings        `json:"settings"`
+}
+
+type ExportOrganizationRequest struct {
+	ID IDOrSelf `uri:"id"`
+}
+
+func (r ExportOrganizationRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+	}
+}
+
+type ExportedGroup struct {
+	Group `json:",inline"`
+	Users []uid.ID `json:"users" note:"IDs of the users that are members of the group"`
+}
+
+type ImportOrganizationRequest struct {
+	ID     IDOrSelf           `uri:"id" json:"-"`
+	DryRun bool               `form:"dryRun" json:"-" note:"if true, report conflicts without importing anything"`
+	Export OrganizationExport `json:"export"`
+}
+
+type ImportOrganizationResponse struct {
+	DryRun    bool             `json:"dryRun"`
+	Imported  ImportCounts     `json:"imported" note:"the number of each kind of resource that was, or would be, imported"`
+	Conflicts []ImportConflict `json:"conflicts" note:"resources that already exist in the organization"`
+}
+
+func (r *ImportOrganizationResponse) StatusCode() int {
+	if r.DryRun {
+		return http.StatusOK
+	}
+	return http.StatusCreated
+}
+
+type ImportCounts struct {
+	Providers    int `json:"providers"`
+	Users        int `json:"users"`
+	Groups       int `json:"groups"`
+	Grants       int `json:"grants"`
+	Destinations int `json:"destinations"`
+}
+
+type ImportConflict struct {
+	Kind  string `json:"kind" example:"user"`
+	Field string `json:"field" example:"name"`
+	Value string `json:"value" example:"alice@example.com"`
+}
@@ -19,6 +19,42 @@ func (r ProviderAPICredentials) ValidationRules() []validate.ValidationRule {
 	}
 }
 
+// ProviderLDAP is the configuration of an LDAP provider. The URL of the
+// provider is the address of the LDAP server, like ldaps://ldap.example.com.
+// It contains sensitive fields, it should not be sent on a response.
+type ProviderLDAP struct {
+	BindDN       string `json:"bindDN" example:"cn=infra,ou=services,dc=example,dc=com" note:"the user Infra binds as to search the directory"`
+	BindPassword string `json:"bindPassword"`
+	SearchBase   string `json:"searchBase" example:"dc=example,dc=com"`
+	UserFilter   string `json:"userFilter" example:"(sAMAccountName={username})" note:"the filter used to find a user, {username} is replaced by the name used to login. Defaults to (uid={username})"`
+	GroupFilter  string `json:"groupFilter" example:"(member={dn})" note:"the filter used to find the groups of a user, {dn} is replaced by the DN of the user. Defaults to (member={dn})"`
+}
+
+func (r ProviderLDAP) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("bindDN", r.BindDN),
+		validate.Required("bindPassword", r.BindPassword),
+		validate.Required("searchBase", r.SearchBase),
+	}
+}
+
+// ProviderSAML is the configuration of a SAML identity provider. The URL of
+// the provider is the single sign-on URL of the identity provider, which uses
+// the HTTP-Redirect binding.
+type ProviderSAML struct {
+	EntityID        string `json:"entityID" example:"http://www.okta.com/exk1fcia6d6EMsf331d8" note:"the entity ID of the identity provider, which must match the issuer of assertions"`
+	Certificate     PEM    `json:"certificate" note:"the PEM encoded certificate the identity provider signs assertions with"`
+	NameAttribute   string `json:"nameAttribute" example:"email" note:"the attribute used as the name of the user. Defaults to the NameID of the subject"`
+	GroupsAttribute string `json:"groupsAttribute" example:"groups" note:"the attribute that lists the groups of the user. Defaults to groups"`
+}
+
+func (r ProviderSAML) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("entityID", r.EntityID),
+		validate.Required("certificate", r.Certificate),
+	}
+}
+
 type Provider struct {
 	ID       uid.ID   `json:"id"`
 	Name     string   `json:"name" example:"okta"`
@@ -38,18 +74,37 @@ type CreateProviderRequest struct {
 	ClientSecret string                  `json:"clientSecret" example:"jmda5eG93ax3jMDxTGrbHd_TBGT6kgNZtrCugLbU"`
 	Kind         string                  `json:"kind" example:"oidc"`
 	API          *ProviderAPICredentials `json:"api"`
+	LDAP         *ProviderLDAP           `json:"ldap" note:"required when kind is ldap"`
+	SAML         *ProviderSAML           `json:"saml" note:"required when kind is saml"`
 }
 
-var kinds = []string{"oidc", "okta", "azure", "google"}
+var kinds = []string{"oidc", "okta", "azure", "google", "ldap", "saml"}
 
 func (r CreateProviderRequest) ValidationRules() []validate.ValidationRule {
-	return []validate.ValidationRule{
+	return append([]validate.ValidationRule{
 		ValidateName(r.Name),
 		validate.Required("name", r.Name),
 		validate.Required("url", r.URL),
-		validate.Required("clientID", r.ClientID),
-		validate.Required("clientSecret", r.ClientSecret),
 		validate.Enum("kind", r.Kind, kinds),
+	}, providerCredentialRules(r.Kind, r.ClientID, r.ClientSecret, r.LDAP, r.SAML)...)
+}
+
+// providerCredentialRules returns the rules for the fields used to
+// authenticate with the provider, which depend on the kind of provider.
+func providerCredentialRules(kind, clientID, clientSecret string, ldap *ProviderLDAP, saml *ProviderSAML) []validate.ValidationRule {
+	switch kind {
+	case "ldap":
+		return []validate.ValidationRule{
+			validate.Required("ldap", ldap),
+		}
+	case "saml":
+		return []validate.ValidationRule{
+			validate.Required("saml", saml),
+		}
+	}
+	return []validate.ValidationRule{
+		validate.Required("clientID", clientID),
+		validate.Required("clientSecret", clientSecret),
 	}
 }
 
@@ -61,18 +116,18 @@ type UpdateProviderRequest struct {
 	ClientSecret string                  `json:"clientSecret" example:"jmda5eG93ax3jMDxTGrbHd_TBGT6kgNZtrCugLbU"`
 	Kind         string                  `json:"kind" example:"oidc"`
 	API          *ProviderAPICredentials `json:"api"`
+	LDAP         *ProviderLDAP           `json:"ldap" note:"required when kind is ldap"`
+	SAML         *ProviderSAML           `json:"saml" note:"required when kind is saml"`
 }
 
 func (r UpdateProviderRequest) ValidationRules() []validate.ValidationRule {
-	return []validate.ValidationRule{
+	return append([]validate.ValidationRule{
 		ValidateName(r.Name),
 		validate.Required("id", r.ID),
 		validate.Required("name", r.Name),
 		validate.Required("url", r.URL),
-		validate.Required("clientID", r.ClientID),
-		validate.Required("clientSecret", r.ClientSecret),
 		validate.Enum("kind", r.Kind, kinds),
-	}
+	}, providerCredentialRules(r.Kind, r.ClientID, r.ClientSecret, r.LDAP, r.SAML)...)
 }
 
 type ListProvidersRequest struct {
@@ -91,3 +146,15 @@ func (req ListProvidersRequest) SetPage(page int) Paginatable {
 
 	return req
 }
+
+type CreateSCIMAccessKeyRequest struct {
+	ID  uid.ID   `uri:"id" json:"-"`
+	TTL Duration `json:"ttl" note:"maximum time valid"`
+}
+
+func (r CreateSCIMAccessKeyRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.Required("ttl", r.TTL),
+	}
+}
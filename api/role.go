@@ -0,0 +1,95 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+// Role is a set of Kubernetes policy rules. Connectors create a ClusterRole
+// named infra:<name> for each role, so that the name of the role can be used
+// as the privilege of a grant on any Kubernetes destination.
+type Role struct {
+	ID          uid.ID       `json:"id"`
+	Created     Time         `json:"created"`
+	Updated     Time         `json:"updated"`
+	Name        string       `json:"name" example:"db-reader"`
+	Description string       `json:"description" example:"Read access to database secrets"`
+	Rules       []PolicyRule `json:"rules"`
+}
+
+// PolicyRule is a Kubernetes RBAC policy rule.
+type PolicyRule struct {
+	APIGroups       []string `json:"apiGroups" example:"['']"`
+	Resources       []string `json:"resources" example:"['secrets']"`
+	ResourceNames   []string `json:"resourceNames,omitempty"`
+	NonResourceURLs []string `json:"nonResourceURLs,omitempty"`
+	Verbs           []string `json:"verbs" example:"['get', 'list']"`
+}
+
+func (r PolicyRule) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("verbs", r.Verbs),
+		validate.RequireAnyOf(
+			validate.Field{Name: "resources", Value: r.Resources},
+			validate.Field{Name: "nonResourceURLs", Value: r.NonResourceURLs},
+		),
+	}
+}
+
+type ListRolesRequest struct {
+	// Name filters the results to only the role matching this name.
+	Name string `form:"name"`
+	PaginationRequest
+}
+
+func (r ListRolesRequest) ValidationRules() []validate.ValidationRule {
+	// no-op ValidationRules implementation so that the rules from the
+	// embedded PaginationRequest struct are not applied twice.
+	return nil
+}
+
+func (req ListRolesRequest) SetPage(page int) Paginatable {
+	req.PaginationRequest.Page = page
+
+	return req
+}
+
+type CreateRoleRequest struct {
+	Name        string       `json:"name" example:"db-reader"`
+	Description string       `json:"description"`
+	Rules       []PolicyRule `json:"rules"`
+}
+
+func (r CreateRoleRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("name", r.Name),
+		ValidateRoleName(r.Name),
+		validate.String("description", r.Description, 0, 256),
+		validate.Required("rules", r.Rules),
+	}
+}
+
+type UpdateRoleRequest struct {
+	ID          uid.ID       `uri:"id" json:"-"`
+	Description string       `json:"description"`
+	Rules       []PolicyRule `json:"rules"`
+}
+
+func (r UpdateRoleRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.String("description", r.Description, 0, 256),
+		validate.Required("rules", r.Rules),
+	}
+}
+
+// ValidateRoleName returns a rule that checks name can be used in the name
+// of a Kubernetes ClusterRole.
+func ValidateRoleName(name string) validate.StringRule {
+	return validate.String("name", name, 2, 200,
+		validate.AlphabetLower,
+		validate.Numbers,
+		validate.Dash,
+		validate.Dot,
+	)
+}
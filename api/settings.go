@@ -1,7 +1,38 @@
 package api
 
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+const (
+	MFARequirementNone   = "none"
+	MFARequirementAdmins = "admins"
+	MFARequirementAll    = "all"
+)
+
 type Settings struct {
 	PasswordRequirements PasswordRequirements `json:"passwordRequirements"`
+	RequireMFA           string               `json:"requireMFA" note:"which users must use multi-factor authentication to login with a password"`
+	Lockout              *LockoutSettings     `json:"lockout,omitempty" note:"if not set when updating settings, the lockout settings are unchanged"`
+}
+
+func (s Settings) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Enum("requireMFA", s.RequireMFA,
+			[]string{MFARequirementNone, MFARequirementAdmins, MFARequirementAll}),
+	}
+}
+
+type LockoutSettings struct {
+	Threshold int      `json:"threshold" note:"number of consecutive failed logins after which a user is locked out. 0 disables lockout"`
+	Duration  Duration `json:"duration" note:"how long a user is locked out" example:"15m"`
+}
+
+func (s LockoutSettings) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.IntRule{Name: "threshold", Value: s.Threshold, Min: validate.Int(0)},
+	}
 }
 
 type PasswordRequirements struct {
@@ -11,3 +42,25 @@ type PasswordRequirements struct {
 	SymbolMin    int `json:"symbolMin"`
 	LengthMin    int `json:"lengthMin"`
 }
+
+type SigningKey struct {
+	ID      uid.ID `json:"id"`
+	Created Time   `json:"created"`
+	Updated Time   `json:"updated"`
+	KeyID   string `json:"kid" note:"the key ID included in the header of tokens signed by this key"`
+	State   string `json:"state" note:"one of active, retiring, or retired"`
+}
+
+type ListSigningKeysRequest struct {
+	PaginationRequest
+}
+
+func (req ListSigningKeysRequest) SetPage(page int) Paginatable {
+	req.PaginationRequest.Page = page
+
+	return req
+}
+
+type RotateSigningKeysRequest struct {
+	RetirePrevious bool `json:"retirePrevious" note:"if true, the previous active key is no longer published, and tokens signed by it stop being valid immediately. Use this when a key may have been leaked."`
+}
@@ -0,0 +1,38 @@
+package api
+
+import "github.com/infrahq/infra/internal/validate"
+
+type CreateSSHCertificateRequest struct {
+	Destination string `json:"destination" example:"web-1" note:"name of the destination that will accept the certificate"`
+	PublicKey   string `json:"publicKey" example:"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHQcmGcnpTEaV56y8kDdwdqYKcVEDMmmSiJIavwOQ5fU" note:"SSH public key of the user, in the authorized_keys format"`
+}
+
+func (r CreateSSHCertificateRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("destination", r.Destination),
+		validate.Required("publicKey", r.PublicKey),
+	}
+}
+
+type CreateSSHCertificateResponse struct {
+	// Certificate is the SSH certificate in the authorized_keys format
+	Certificate string `json:"certificate"`
+	// Logins are the local users of the destination that the certificate can
+	// log in as.
+	Logins  []string `json:"logins"`
+	Expires Time     `json:"expires"`
+}
+
+type SSHUserCA struct {
+	// PublicKey of the CA in the authorized_keys format, as used by the sshd
+	// TrustedUserCAKeys option.
+	PublicKey string `json:"publicKey"`
+}
+
+// SSHPrincipal returns the principal of an SSH certificate that allows
+// logging in to the destination as login. Principals include the name of the
+// destination so that a certificate can not be used to log in to other
+// destinations.
+func SSHPrincipal(destinationName, login string) string {
+	return destinationName + ":" + login
+}
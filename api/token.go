@@ -1,5 +1,17 @@
 package api
 
+import "github.com/infrahq/infra/internal/validate"
+
+type CreateTokenRequest struct {
+	Destination string `json:"destination" example:"production" note:"name of the destination that will accept the token"`
+}
+
+func (r CreateTokenRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("destination", r.Destination),
+	}
+}
+
 type CreateTokenResponse struct {
 	Expires Time   `json:"expires"`
 	Token   string `json:"token"`
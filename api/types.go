@@ -40,6 +40,13 @@ func (i *IDOrSelf) UnmarshalText(b []byte) error {
 	return err
 }
 
+func (i IDOrSelf) String() string {
+	if i.IsSelf {
+		return "self"
+	}
+	return i.ID.String()
+}
+
 func (i IDOrSelf) DescribeSchema(schema *openapi3.Schema) {
 	schema.Type = "string"
 	schema.Format = "uid|self"
@@ -74,6 +81,19 @@ func (t *Time) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// UnmarshalText allows Time to be used in query parameters.
+func (t *Time) UnmarshalText(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	tmp, err := time.Parse(time.RFC3339, string(data))
+	if err != nil {
+		return err
+	}
+	*t = Time(tmp.UTC())
+	return nil
+}
+
 func (t Time) String() string {
 	return time.Time(t).Format(time.RFC3339)
 }
@@ -106,6 +126,21 @@ func (d *Duration) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// UnmarshalText parses a duration from a query parameter. An empty value is
+// a zero duration.
+func (d *Duration) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*d = 0
+		return nil
+	}
+	dur, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(dur)
+	return nil
+}
+
 func (d Duration) String() string {
 	return time.Duration(d).String()
 }
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+const (
+	WebhookEventGrantCreated            = "grant.created"
+	WebhookEventGrantDeleted            = "grant.deleted"
+	WebhookEventUserCreated             = "user.created"
+	WebhookEventUserDeleted             = "user.deleted"
+	WebhookEventDestinationConnected    = "destination.connected"
+	WebhookEventDestinationDisconnected = "destination.disconnected"
+)
+
+// WebhookEventTypes are all the types of events that can be sent to a webhook.
+var WebhookEventTypes = []string{
+	WebhookEventGrantCreated,
+	WebhookEventGrantDeleted,
+	WebhookEventUserCreated,
+	WebhookEventUserDeleted,
+	WebhookEventDestinationConnected,
+	WebhookEventDestinationDisconnected,
+}
+
+type Webhook struct {
+	ID      uid.ID   `json:"id"`
+	Created Time     `json:"created"`
+	Updated Time     `json:"updated"`
+	URL     string   `json:"url" example:"https://example.com/infra-events"`
+	Events  []string `json:"events" example:"['grant.created', 'grant.deleted']"`
+}
+
+type ListWebhooksRequest struct {
+	PaginationRequest
+}
+
+func (r ListWebhooksRequest) ValidationRules() []validate.ValidationRule {
+	// no-op ValidationRules implementation so that the rules from the
+	// embedded PaginationRequest struct are not applied twice.
+	return nil
+}
+
+func (req ListWebhooksRequest) SetPage(page int) Paginatable {
+	req.PaginationRequest.Page = page
+
+	return req
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" example:"https://example.com/infra-events"`
+	Secret string   `json:"secret" note:"used to sign the payloads sent to the webhook, generated if not set"`
+	Events []string `json:"events" example:"['grant.created', 'grant.deleted']"`
+}
+
+func (r CreateWebhookRequest) ValidationRules() []validate.ValidationRule {
+	rules := []validate.ValidationRule{
+		validate.Required("url", r.URL),
+		validate.Required("events", r.Events),
+		validate.String("secret", r.Secret, 0, 256),
+	}
+	for _, event := range r.Events {
+		rules = append(rules, validate.Enum("events", event, WebhookEventTypes))
+	}
+	return rules
+}
+
+type CreateWebhookResponse struct {
+	Webhook
+	Secret string `json:"secret" note:"the secret is only returned when the webhook is created"`
+}
+
+// WebhookEvent is the payload sent to a webhook. The payload is signed with
+// the secret of the webhook, and the signature is sent in the Infra-Signature
+// header.
+type WebhookEvent struct {
+	ID      uid.ID `json:"id"`
+	Type    string `json:"type" example:"grant.created"`
+	Created Time   `json:"created"`
+	// Data is a Grant, User, or Destination depending on the type of event.
+	Data any `json:"data"`
+}
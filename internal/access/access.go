@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
@@ -54,8 +55,9 @@ func RequireInfraRole(c *gin.Context, oneOfRoles ...string) (data.GormTxn, error
 		}
 	}
 
-	// check if they belong to a group that is authorized
-	groups, err := data.ListGroups(db, nil, data.ByGroupMember(identity.ID))
+	// check if they belong to a group that is authorized, directly or through
+	// a nested group
+	groups, err := data.ListGroups(db, nil, data.ByInheritedGroupMember(identity.ID))
 	if err != nil {
 		return nil, fmt.Errorf("auth user groups: %w", err)
 	}
@@ -87,6 +89,10 @@ type AuthorizationError struct {
 }
 
 func (e AuthorizationError) Error() string {
+	if len(e.RequiredRoles) == 0 {
+		return fmt.Sprintf("you do not have permission to %v %v", e.Operation, e.Resource)
+	}
+
 	var roles strings.Builder
 	switch len(e.RequiredRoles) {
 	case 1:
@@ -122,12 +128,28 @@ func HandleAuthErr(err error, resource, operation string, roles ...string) error
 	}
 }
 
-// Can checks if an identity has a privilege that means it can perform an action on a resource
+// Can checks if an identity has a privilege that means it can perform an action on a resource.
+// Grants with a wildcard resource match the resource as described by api.MatchResource,
+// except for the infra resource, which is only matched by name.
 func Can(db data.GormTxn, identity uid.PolymorphicID, privilege, resource string) (bool, error) {
-	grants, err := data.ListGrants(db, &models.Pagination{Limit: 1}, data.BySubject(identity), data.ByPrivilege(privilege), data.ByResource(resource))
+	if resource == ResourceInfraAPI {
+		grants, err := data.ListGrants(db, &models.Pagination{Limit: 1}, data.BySubject(identity), data.ByPrivilege(privilege), data.ByResource(resource))
+		if err != nil {
+			return false, fmt.Errorf("has grants: %w", err)
+		}
+
+		return len(grants) > 0, nil
+	}
+
+	grants, err := data.ListGrants(db, nil, data.BySubject(identity), data.ByPrivilege(privilege), data.ByOptionalResourcePrefix(resource))
 	if err != nil {
 		return false, fmt.Errorf("has grants: %w", err)
 	}
 
-	return len(grants) > 0, nil
+	for _, grant := range grants {
+		if grant.Resource == resource || api.MatchResource(grant.Resource, resource) {
+			return true, nil
+		}
+	}
+	return false, nil
 }
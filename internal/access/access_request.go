@@ -0,0 +1,165 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// CreateAccessRequest creates a pending request for access on behalf of the
+// authenticated user. Any user may request access.
+func CreateAccessRequest(c *gin.Context, req *models.AccessRequest) error {
+	identity := AuthenticatedIdentity(c)
+	if identity == nil {
+		return fmt.Errorf("no active identity")
+	}
+
+	req.UserID = identity.ID
+	req.State = models.AccessRequestStatePending
+
+	return data.CreateAccessRequest(getDB(c), req)
+}
+
+func GetAccessRequest(c *gin.Context, id uid.ID) (*models.AccessRequest, error) {
+	roles := []string{models.InfraAdminRole, models.InfraViewRole}
+	db, err := RequireInfraRole(c, roles...)
+	err = HandleAuthErr(err, "access request", "get", roles...)
+	if errors.Is(err, ErrNotAuthorized) {
+		// Allow users to view their own access requests
+		req, err2 := data.GetAccessRequest(getDB(c), data.ByID(id))
+		if err2 != nil {
+			return nil, err
+		}
+		if self, _ := isIdentitySelf(c, req.UserID); self {
+			return req, nil
+		}
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return data.GetAccessRequest(db, data.ByID(id))
+}
+
+func ListAccessRequests(c *gin.Context, userID uid.ID, state models.AccessRequestState, p *models.Pagination) ([]models.AccessRequest, error) {
+	roles := []string{models.InfraAdminRole, models.InfraViewRole}
+	db, err := hasAuthorization(c, userID, isIdentitySelf, roles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "access requests", "list", roles...)
+	}
+
+	return data.ListAccessRequests(db, p,
+		data.ByOptionalUserID(userID),
+		data.ByOptionalAccessRequestState(state))
+}
+
+// ApproveAccessRequest grants the requested access to the user that made the
+// request. The grant is created by the authenticated user, so only users who
+// are allowed to create the grant may approve the request.
+func ApproveAccessRequest(c *gin.Context, id uid.ID) (*models.AccessRequest, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "access request", "approve", models.InfraAdminRole)
+	}
+
+	req, err := getPendingAccessRequest(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	grant := &models.Grant{
+		Subject:   uid.NewIdentityPolymorphicID(req.UserID),
+		Privilege: req.Privilege,
+		Resource:  req.Resource,
+	}
+	if req.Duration > 0 {
+		grant.ExpiresAt = time.Now().Add(req.Duration)
+	}
+
+	err = CreateGrant(c, grant)
+	var ucerr data.UniqueConstraintError
+	switch {
+	case errors.As(err, &ucerr):
+		// the user already has this grant, link the request to it
+		existing, err := data.GetGrant(db,
+			data.BySubject(grant.Subject),
+			data.ByPrivilege(grant.Privilege),
+			data.ByResource(grant.Resource))
+		if err != nil {
+			return nil, err
+		}
+		// extend the grant so that it lasts at least as long as requested
+		if expiresAt, ok := extendedExpiry(existing.ExpiresAt, grant.ExpiresAt); ok {
+			if err := data.UpdateGrantExpiry(db, existing, expiresAt); err != nil {
+				return nil, err
+			}
+		}
+		grant = existing
+	case err != nil:
+		return nil, err
+	}
+
+	req.State = models.AccessRequestStateApproved
+	req.ReviewedBy = AuthenticatedIdentity(c).ID
+	req.GrantID = grant.ID
+
+	if err := data.SaveAccessRequest(db, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// extendedExpiry returns the later of the expiry of an existing grant and the
+// requested expiry, where a zero time never expires. It returns false if the
+// existing grant already lasts as long as requested.
+func extendedExpiry(existing, requested time.Time) (time.Time, bool) {
+	switch {
+	case existing.IsZero():
+		return time.Time{}, false
+	case requested.IsZero():
+		return time.Time{}, true
+	case requested.After(existing):
+		return requested, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func DenyAccessRequest(c *gin.Context, id uid.ID) (*models.AccessRequest, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "access request", "deny", models.InfraAdminRole)
+	}
+
+	req, err := getPendingAccessRequest(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req.State = models.AccessRequestStateDenied
+	req.ReviewedBy = AuthenticatedIdentity(c).ID
+
+	if err := data.SaveAccessRequest(db, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func getPendingAccessRequest(db data.GormTxn, id uid.ID) (*models.AccessRequest, error) {
+	req, err := data.GetAccessRequest(db, data.ByID(id))
+	if err != nil {
+		return nil, err
+	}
+
+	if req.State != models.AccessRequestStatePending {
+		return nil, fmt.Errorf("%w: access request has already been %s", internal.ErrBadRequest, req.State)
+	}
+	return req, nil
+}
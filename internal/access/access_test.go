@@ -86,6 +86,34 @@ func TestBasicGrant(t *testing.T) {
 	cant(t, db, "i:alice", "write", "infra.machines")
 }
 
+func TestWildcardGrant(t *testing.T) {
+	db := setupDB(t)
+	err := data.CreateIdentity(db, tom)
+	assert.NilError(t, err)
+
+	grant(t, db, tom, "i:steven", "view", "production.*")
+	can(t, db, "i:steven", "view", "production.default")
+	can(t, db, "i:steven", "view", "production.kube-system")
+	cant(t, db, "i:steven", "view", "production")
+	cant(t, db, "i:steven", "view", "staging.default")
+	cant(t, db, "i:steven", "edit", "production.default")
+
+	grant(t, db, tom, "i:bob", "view", "*.monitoring")
+	can(t, db, "i:bob", "view", "production.monitoring")
+	can(t, db, "i:bob", "view", "staging.monitoring")
+	cant(t, db, "i:bob", "view", "staging.default")
+
+	grant(t, db, tom, "i:alice", "admin", "staging-*")
+	can(t, db, "i:alice", "admin", "staging-eu")
+	cant(t, db, "i:alice", "admin", "staging-eu.default")
+	cant(t, db, "i:alice", "admin", "production")
+
+	// wildcards never match the infra resource
+	grant(t, db, tom, "i:eve", models.InfraAdminRole, "*")
+	can(t, db, "i:eve", models.InfraAdminRole, "production")
+	cant(t, db, "i:eve", models.InfraAdminRole, ResourceInfraAPI)
+}
+
 func TestUsersGroupGrant(t *testing.T) {
 	db := setupDB(t)
 
@@ -0,0 +1,24 @@
+package access
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func ListAuditEvents(c *gin.Context, actorID uid.ID, resource string, resourceID uid.ID, after, before time.Time, p *models.Pagination) ([]models.AuditEvent, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "audit events", "list", models.InfraAdminRole)
+	}
+
+	return data.ListAuditEvents(db, p,
+		data.ByOptionalActorID(actorID),
+		data.ByOptionalResource(resource),
+		data.ByOptionalResourceID(resourceID),
+		data.ByOptionalCreatedBetween(after, before))
+}
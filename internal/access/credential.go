@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"time"
 	"unicode"
 
 	"github.com/gin-gonic/gin"
@@ -14,6 +15,7 @@ import (
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
 )
 
 func CreateCredential(c *gin.Context, user models.Identity) (string, error) {
@@ -96,21 +98,35 @@ func updateCredential(c *gin.Context, user *models.Identity, newPassword string,
 
 	if isSelf {
 		// if we updated our own password, remove the password-reset scope from our access key.
-		if raw, ok := c.Get(RequestContextKey); ok {
-			if rCtx, ok := raw.(RequestContext); ok {
-				if accessKey := rCtx.Authenticated.AccessKey; accessKey != nil {
-					accessKey.Scopes = models.CommaSeparatedStrings{}
-					if err = data.SaveAccessKey(db, accessKey); err != nil {
-						return fmt.Errorf("updating access key: %w", err)
-					}
-				}
-			}
+		if err := removeAccessKeyScope(c, db, models.ScopePasswordReset); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// UnlockCredential allows a user that was locked out because of failed logins
+// to login again.
+func UnlockCredential(c *gin.Context, userID uid.ID) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "user", "unlock", models.InfraAdminRole)
+	}
+
+	credential, err := data.GetCredential(db, data.ByIdentityID(userID))
+	if err != nil {
+		return fmt.Errorf("get credential: %w", err)
+	}
+
+	credential.FailedLoginAttempts = 0
+	credential.LockedUntil = time.Time{}
+	if err := data.SaveCredential(db, credential); err != nil {
+		return fmt.Errorf("save credential: %w", err)
+	}
+	return nil
+}
+
 func GetRequestContext(c *gin.Context) RequestContext {
 	if raw, ok := c.Get(RequestContextKey); ok {
 		if rCtx, ok := raw.(RequestContext); ok {
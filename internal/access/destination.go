@@ -1,8 +1,11 @@
 package access
 
 import (
+	"strings"
+
 	"github.com/gin-gonic/gin"
 
+	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
@@ -47,3 +50,91 @@ func DeleteDestination(c *gin.Context, id uid.ID) error {
 
 	return data.DeleteDestinations(db, data.ByID(id))
 }
+
+// ListDestinationEvents returns up to limit events after the cursor that apply
+// to the destination, and the cursor of the last event that was read. resync
+// is true when some of the events after the cursor have been deleted, in
+// which case the returned cursor is the sequence of the last event. A
+// negative cursor always results in resync.
+func ListDestinationEvents(c *gin.Context, destination *models.Destination, cursor int64, limit int) (events []models.DestinationEvent, next int64, resync bool, err error) {
+	roles := []string{models.InfraAdminRole, models.InfraConnectorRole}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, 0, false, HandleAuthErr(err, "destination events", "list", roles...)
+	}
+
+	last, oldest, err := data.DestinationEventSequences(db)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	switch {
+	case cursor == last:
+		return nil, cursor, false, nil
+	case cursor > last, oldest == 0, oldest > cursor+1:
+		return nil, last, true, nil
+	}
+
+	all, err := data.ListDestinationEvents(db, cursor, limit)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	next = cursor
+	for _, event := range all {
+		next = event.Sequence
+		if destinationEventApplies(event, destination.Name) {
+			events = append(events, event)
+		}
+	}
+	return events, next, false, nil
+}
+
+func destinationEventApplies(event models.DestinationEvent, destinationName string) bool {
+	return event.Resource == "" || resourceInDestination(event.Resource, destinationName)
+}
+
+// resourceInDestination returns true if the resource of a grant is the
+// destination or one of its sub-resources.
+func resourceInDestination(resource, destinationName string) bool {
+	pattern, _, _ := strings.Cut(resource, ".")
+	return api.MatchResource(pattern, destinationName)
+}
+
+// ListDestinationGrants returns the grants that apply to the destination, and
+// the names of the users and groups of those grants. Grants for users or
+// groups that no longer exist are not included.
+func ListDestinationGrants(c *gin.Context, destination *models.Destination) ([]models.Grant, map[uid.PolymorphicID]string, error) {
+	roles := []string{models.InfraAdminRole, models.InfraConnectorRole}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, nil, HandleAuthErr(err, "destination grants", "list", roles...)
+	}
+
+	all, err := data.ListGrants(db, nil, data.ByOptionalResourcePrefix(destination.Name))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	grants := make([]models.Grant, 0, len(all))
+	subjects := make([]uid.PolymorphicID, 0, len(all))
+	for _, grant := range all {
+		if !resourceInDestination(grant.Resource, destination.Name) {
+			continue
+		}
+		grants = append(grants, grant)
+		subjects = append(subjects, grant.Subject)
+	}
+
+	names, err := data.SubjectNames(db, subjects)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := grants[:0]
+	for _, grant := range grants {
+		if _, ok := names[grant.Subject]; ok {
+			result = append(result, grant)
+		}
+	}
+	return result, names, nil
+}
@@ -0,0 +1,97 @@
+package access
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// StartDeviceFlow creates a device flow request that expires after ttl.
+func StartDeviceFlow(c *gin.Context, ttl time.Duration) (*models.DeviceFlowAuthRequest, error) {
+	// no auth required
+	db := getDB(c)
+
+	req := &models.DeviceFlowAuthRequest{
+		ExpiresAt: time.Now().Add(ttl).UTC(),
+	}
+	if err := data.CreateDeviceFlowAuthRequest(db, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// ApproveDeviceFlow approves the device flow request with userCode on behalf
+// of the authenticated user. The device that started the request can then
+// exchange its device code for an access key issued to the user.
+func ApproveDeviceFlow(c *gin.Context, userCode string) (*models.DeviceFlowAuthRequest, error) {
+	rCtx := GetRequestContext(c)
+	identity := rCtx.Authenticated.User
+	if identity == nil {
+		return nil, fmt.Errorf("no active identity")
+	}
+
+	db := rCtx.DBTxn
+	req, err := data.GetDeviceFlowAuthRequest(db,
+		data.ByUserCode(NormalizeUserCode(userCode)),
+		data.ByNotExpired(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ApprovedBy != 0 {
+		return nil, fmt.Errorf("%w: the login has already been approved", internal.ErrBadRequest)
+	}
+
+	req.ApprovedBy = identity.ID
+	req.ProviderID = rCtx.Authenticated.AccessKey.ProviderID
+	if err := data.SaveDeviceFlowAuthRequest(db, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// NormalizeUserCode removes the separator and any whitespace from a user code,
+// and converts it to upper case, so that users can enter the code the way
+// they read it.
+func NormalizeUserCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+// ListDeviceFlowAuthRequests returns device flow requests that have not
+// expired or been exchanged for an access key.
+func ListDeviceFlowAuthRequests(c *gin.Context, p *models.Pagination) ([]models.DeviceFlowAuthRequest, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "device logins", "list", models.InfraAdminRole)
+	}
+
+	return data.ListDeviceFlowAuthRequests(db, p, data.ByNotExpired(time.Now()))
+}
+
+// DeleteDeviceFlowAuthRequest expires a device flow request, so that it can no
+// longer be approved or exchanged for an access key.
+func DeleteDeviceFlowAuthRequest(c *gin.Context, id uid.ID) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "device login", "delete", models.InfraAdminRole)
+	}
+
+	req, err := data.GetDeviceFlowAuthRequest(db, data.ByID(id))
+	if err != nil {
+		return err
+	}
+	return data.DeleteDeviceFlowAuthRequest(db, req)
+}
@@ -0,0 +1,84 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func ListFederations(c *gin.Context, name string, p *models.Pagination) ([]models.Federation, error) {
+	roles := []string{models.InfraAdminRole, models.InfraViewRole}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "federations", "list", roles...)
+	}
+
+	return data.ListFederations(db, p, data.ByOptionalName(name))
+}
+
+func GetFederation(c *gin.Context, id uid.ID) (*models.Federation, error) {
+	roles := []string{models.InfraAdminRole, models.InfraViewRole}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "federation", "get", roles...)
+	}
+
+	return data.GetFederation(db, data.ByID(id))
+}
+
+func CreateFederation(c *gin.Context, federation *models.Federation) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "federation", "create", models.InfraAdminRole)
+	}
+
+	if err := federationIdentityExists(db, federation); err != nil {
+		return err
+	}
+
+	federation.CreatedBy = AuthenticatedIdentity(c).ID
+	return data.CreateFederation(db, federation)
+}
+
+func UpdateFederation(c *gin.Context, federation *models.Federation) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "federation", "update", models.InfraAdminRole)
+	}
+
+	if err := federationIdentityExists(db, federation); err != nil {
+		return err
+	}
+
+	return data.SaveFederation(db, federation)
+}
+
+// DeleteFederation deletes the federation. Access keys issued by logging in
+// with the federation remain valid until they expire.
+func DeleteFederation(c *gin.Context, id uid.ID) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "federation", "delete", models.InfraAdminRole)
+	}
+
+	if _, err := data.GetFederation(db, data.ByID(id)); err != nil {
+		return err
+	}
+	return data.DeleteFederations(db, data.ByID(id))
+}
+
+// federationIdentityExists checks that the identity of the federation is a
+// user in the organization.
+func federationIdentityExists(db data.GormTxn, federation *models.Federation) error {
+	_, err := data.GetIdentity(db, data.ByID(federation.IdentityID))
+	if errors.Is(err, internal.ErrNotFound) {
+		return fmt.Errorf("%w: user %v does not exist", internal.ErrBadRequest, federation.IdentityID)
+	}
+	return err
+}
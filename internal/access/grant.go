@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
@@ -19,9 +20,10 @@ func GetGrant(c *gin.Context, id uid.ID) (*models.Grant, error) {
 	return data.GetGrant(db, data.ByID(id))
 }
 
-func ListGrants(c *gin.Context, subject uid.PolymorphicID, resource string, privilege string, inherited bool, showSystem bool, p *models.Pagination) ([]models.Grant, error) {
+func ListGrants(c *gin.Context, subject uid.PolymorphicID, resource string, resourcePrefix string, privilege string, inherited bool, showSystem bool, p *models.Pagination) ([]models.Grant, error) {
 	selectors := []data.SelectorFunc{
 		data.ByOptionalResource(resource),
+		data.ByOptionalResourcePrefix(resourcePrefix),
 		data.ByOptionalPrivilege(privilege),
 	}
 
@@ -75,7 +77,7 @@ func ListGrants(c *gin.Context, subject uid.PolymorphicID, resource string, priv
 }
 
 func userInGroup(db data.GormTxn, authnUserID uid.ID, groupID uid.ID) bool {
-	groups, err := data.ListGroups(db, &models.Pagination{Limit: 1}, data.ByGroupMember(authnUserID), data.ByID(groupID))
+	groups, err := data.ListGroups(db, &models.Pagination{Limit: 1}, data.ByInheritedGroupMember(authnUserID), data.ByID(groupID))
 	if err != nil {
 		return false
 	}
@@ -106,7 +108,11 @@ func CreateGrant(c *gin.Context, grant *models.Grant) error {
 	creator := AuthenticatedIdentity(c)
 	grant.CreatedBy = creator.ID
 
-	return data.CreateGrant(db, grant)
+	if err := data.CreateGrant(db, grant); err != nil {
+		return err
+	}
+
+	return createWebhookEvent(db, api.WebhookEventGrantCreated, grant.ToAPI())
 }
 
 func DeleteGrant(c *gin.Context, id uid.ID) error {
@@ -115,5 +121,19 @@ func DeleteGrant(c *gin.Context, id uid.ID) error {
 		return HandleAuthErr(err, "grant", "delete", models.InfraAdminRole)
 	}
 
-	return data.DeleteGrants(db, data.ByID(id))
+	grants, err := data.ListGrants(db, nil, data.ByID(id))
+	if err != nil {
+		return err
+	}
+
+	if err := data.DeleteGrants(db, data.ByID(id)); err != nil {
+		return err
+	}
+
+	for _, grant := range grants {
+		if err := createWebhookEvent(db, api.WebhookEventGrantDeleted, grant.ToAPI()); err != nil {
+			return err
+		}
+	}
+	return nil
 }
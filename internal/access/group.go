@@ -141,3 +141,56 @@ func UpdateUsersInGroup(c *gin.Context, groupID uid.ID, uidsToAdd []uid.ID, uids
 	}
 	return data.RemoveUsersFromGroup(db, groupID, rmIDList)
 }
+
+// UpdateGroupsInGroup adds and removes groups as members of the group with
+// groupID. Members of the added groups inherit the grants of the group.
+func UpdateGroupsInGroup(c *gin.Context, groupID uid.ID, idsToAdd []uid.ID, idsToRemove []uid.ID) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "group", "update", models.InfraAdminRole)
+	}
+
+	_, err = data.GetGroup(db, data.ByID(groupID))
+	if err != nil {
+		return err
+	}
+
+	if err := checkGroupsInList(db, idsToAdd); err != nil {
+		return err
+	}
+	if err := checkGroupsInList(db, idsToRemove); err != nil {
+		return err
+	}
+
+	if err := data.AddGroupsToGroup(db, groupID, idsToAdd); err != nil {
+		return err
+	}
+	return data.RemoveGroupsFromGroup(db, groupID, idsToRemove)
+}
+
+func checkGroupsInList(db data.GormTxn, ids []uid.ID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	groups, err := data.ListGroups(db, nil, data.ByIDs(ids))
+	if err != nil {
+		return err
+	}
+
+	found := make(map[uid.ID]bool, len(groups))
+	for _, group := range groups {
+		found[group.ID] = true
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id.String())
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: Couldn't find group IDs: %s", internal.ErrBadRequest, strings.Join(missing, ","))
+	}
+	return nil
+}
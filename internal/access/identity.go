@@ -1,11 +1,13 @@
 package access
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/logging"
 	"github.com/infrahq/infra/internal/server/data"
@@ -52,7 +54,11 @@ func CreateIdentity(c *gin.Context, identity *models.Identity) error {
 		return HandleAuthErr(err, "user", "create", models.InfraAdminRole)
 	}
 
-	return data.CreateIdentity(db, identity)
+	if err := data.CreateIdentity(db, identity); err != nil {
+		return err
+	}
+
+	return createWebhookEvent(db, api.WebhookEventUserCreated, identity.ToAPI())
 }
 
 func InfraConnectorIdentity(c *gin.Context) *models.Identity {
@@ -79,6 +85,11 @@ func DeleteIdentity(c *gin.Context, id uid.ID) error {
 		return HandleAuthErr(err, "user", "delete", models.InfraAdminRole)
 	}
 
+	identity, err := data.GetIdentity(db, data.Preload("Providers"), data.ByID(id))
+	if err != nil {
+		return err
+	}
+
 	if err := data.DeleteAccessKeys(db, data.ByIssuedFor(id)); err != nil {
 		return fmt.Errorf("delete identity access keys: %w", err)
 	}
@@ -111,7 +122,11 @@ func DeleteIdentity(c *gin.Context, id uid.ID) error {
 		return fmt.Errorf("delete identity creds: %w", err)
 	}
 
-	return data.DeleteIdentity(db, id)
+	if err := data.DeleteIdentity(db, id); err != nil {
+		return err
+	}
+
+	return createWebhookEvent(db, api.WebhookEventUserDeleted, identity.ToAPI())
 }
 
 func ListIdentities(c *gin.Context, name string, groupID uid.ID, ids []uid.ID, showSystem bool, p *models.Pagination) ([]models.Identity, error) {
@@ -157,6 +172,22 @@ func GetContextProviderIdentity(c RequestContext) (*models.Provider, string, err
 
 // UpdateIdentityInfoFromProvider calls the identity provider used to authenticate this user session to update their current information
 func UpdateIdentityInfoFromProvider(c RequestContext, oidc providers.OIDCClient) error {
+	return updateIdentityInfo(c, func(ctx context.Context, db data.GormTxn, identity *models.Identity, provider *models.Provider) error {
+		return data.SyncProviderUser(ctx, db, identity, provider, oidc)
+	})
+}
+
+// UpdateIdentityInfoFromLDAP searches the LDAP directory used to authenticate
+// this user session to update their current information
+func UpdateIdentityInfoFromLDAP(c RequestContext, ldap providers.LDAPClient) error {
+	return updateIdentityInfo(c, func(ctx context.Context, db data.GormTxn, identity *models.Identity, provider *models.Provider) error {
+		return data.SyncLDAPProviderUser(ctx, db, identity, provider, ldap)
+	})
+}
+
+type syncProviderUserFunc func(ctx context.Context, db data.GormTxn, identity *models.Identity, provider *models.Provider) error
+
+func updateIdentityInfo(c RequestContext, sync syncProviderUserFunc) error {
 	// does not need authorization check, this action is limited to the calling user
 	ctx := c.Request.Context()
 
@@ -173,7 +204,7 @@ func UpdateIdentityInfoFromProvider(c RequestContext, oidc providers.OIDCClient)
 	}
 
 	// get current identity provider groups and account status
-	err = data.SyncProviderUser(ctx, db, identity, provider, oidc)
+	err = sync(ctx, db, identity, provider)
 	if err != nil {
 		if errors.Is(err, internal.ErrBadGateway) {
 			return err
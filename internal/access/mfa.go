@@ -0,0 +1,169 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/authn"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+const mfaIssuer = "Infra"
+
+// selfCredential returns the credential of the authenticated user. Only the
+// user can enable multi-factor authentication, because it requires their
+// authenticator app.
+func selfCredential(c *gin.Context, userID uid.ID) (*models.Identity, *models.Credential, error) {
+	identity := AuthenticatedIdentity(c)
+	if identity == nil {
+		return nil, nil, fmt.Errorf("no active identity")
+	}
+	if identity.ID != userID {
+		return nil, nil, fmt.Errorf("%w: multi-factor authentication can only be enabled by the user", internal.ErrBadRequest)
+	}
+
+	credential, err := data.GetCredential(getDB(c), data.ByIdentityID(identity.ID))
+	if err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			return nil, nil, fmt.Errorf("%w: multi-factor authentication requires an infra user with a password", internal.ErrBadRequest)
+		}
+		return nil, nil, fmt.Errorf("get credential: %w", err)
+	}
+	return identity, credential, nil
+}
+
+// EnrollMFA creates a new TOTP secret for the user. Multi-factor
+// authentication is not enabled until the user verifies a code with VerifyMFA.
+func EnrollMFA(c *gin.Context, userID uid.ID) (secret string, uri string, err error) {
+	identity, credential, err := selfCredential(c, userID)
+	if err != nil {
+		return "", "", err
+	}
+	if credential.MFAEnabled {
+		return "", "", fmt.Errorf("%w: multi-factor authentication is already enabled", internal.ErrBadRequest)
+	}
+
+	secret, err = authn.NewTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	credential.MFASecret = models.EncryptedAtRest(secret)
+	if err := data.SaveCredential(getDB(c), credential); err != nil {
+		return "", "", fmt.Errorf("save credential: %w", err)
+	}
+
+	return secret, authn.TOTPKeyURI(mfaIssuer, identity.Name, secret), nil
+}
+
+// VerifyMFA enables multi-factor authentication for the user if code is valid
+// for the secret created by EnrollMFA. It returns new recovery codes.
+func VerifyMFA(c *gin.Context, userID uid.ID, code string) ([]string, error) {
+	_, credential, err := selfCredential(c, userID)
+	if err != nil {
+		return nil, err
+	}
+	if credential.MFAEnabled {
+		return nil, fmt.Errorf("%w: multi-factor authentication is already enabled", internal.ErrBadRequest)
+	}
+	if credential.MFASecret == "" {
+		return nil, fmt.Errorf("%w: multi-factor authentication enrollment has not started", internal.ErrBadRequest)
+	}
+
+	step, ok := authn.ValidateTOTP(string(credential.MFASecret), code, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid multi-factor authentication code", internal.ErrBadRequest)
+	}
+
+	codes, hashes, err := authn.NewMFARecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	db := getDB(c)
+	credential.MFAEnabled = true
+	credential.MFALastUsedStep = step
+	credential.MFARecoveryCodes = models.EncryptedAtRest(hashes)
+	if err := data.SaveCredential(db, credential); err != nil {
+		return nil, fmt.Errorf("save credential: %w", err)
+	}
+
+	// the access key no longer needs to be limited to enrollment
+	if err := removeAccessKeyScope(c, db, models.ScopeMFAEnrollment); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RegenerateMFARecoveryCodes replaces the recovery codes of the user.
+func RegenerateMFARecoveryCodes(c *gin.Context, userID uid.ID) ([]string, error) {
+	_, credential, err := selfCredential(c, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !credential.MFAEnabled {
+		return nil, fmt.Errorf("%w: multi-factor authentication is not enabled", internal.ErrBadRequest)
+	}
+
+	codes, hashes, err := authn.NewMFARecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	credential.MFARecoveryCodes = models.EncryptedAtRest(hashes)
+	if err := data.SaveCredential(getDB(c), credential); err != nil {
+		return nil, fmt.Errorf("save credential: %w", err)
+	}
+	return codes, nil
+}
+
+// DisableMFA disables multi-factor authentication for a user. Admins can
+// disable it for other users, for example when a user has lost their
+// authenticator app and their recovery codes.
+func DisableMFA(c *gin.Context, userID uid.ID) error {
+	db, err := hasAuthorization(c, userID, isIdentitySelf, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "multi-factor authentication", "disable", models.InfraAdminRole)
+	}
+
+	credential, err := data.GetCredential(db, data.ByIdentityID(userID))
+	if err != nil {
+		return fmt.Errorf("get credential: %w", err)
+	}
+
+	credential.MFAEnabled = false
+	credential.MFASecret = ""
+	credential.MFARecoveryCodes = ""
+	credential.MFALastUsedStep = 0
+	if err := data.SaveCredential(db, credential); err != nil {
+		return fmt.Errorf("save credential: %w", err)
+	}
+	return nil
+}
+
+// removeAccessKeyScope removes scope from the access key used to authenticate
+// the request.
+func removeAccessKeyScope(c *gin.Context, db data.GormTxn, scope string) error {
+	accessKey := GetRequestContext(c).Authenticated.AccessKey
+	if accessKey == nil || !accessKey.Scopes.Includes(scope) {
+		return nil
+	}
+
+	scopes := models.CommaSeparatedStrings{}
+	for _, s := range accessKey.Scopes {
+		if s != scope {
+			scopes = append(scopes, s)
+		}
+	}
+	accessKey.Scopes = scopes
+	if err := data.SaveAccessKey(db, accessKey); err != nil {
+		return fmt.Errorf("updating access key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package access
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func ListOIDCClients(c *gin.Context, name string, p *models.Pagination) ([]models.OIDCClient, error) {
+	roles := []string{models.InfraAdminRole, models.InfraViewRole}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "OIDC clients", "list", roles...)
+	}
+
+	return data.ListOIDCClients(db, p, data.ByOptionalName(name))
+}
+
+func GetOIDCClient(c *gin.Context, id uid.ID) (*models.OIDCClient, error) {
+	roles := []string{models.InfraAdminRole, models.InfraViewRole}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "OIDC client", "get", roles...)
+	}
+
+	return data.GetOIDCClient(db, data.ByID(id))
+}
+
+func CreateOIDCClient(c *gin.Context, client *models.OIDCClient) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "OIDC client", "create", models.InfraAdminRole)
+	}
+
+	client.CreatedBy = AuthenticatedIdentity(c).ID
+	return data.CreateOIDCClient(db, client)
+}
+
+func UpdateOIDCClient(c *gin.Context, client *models.OIDCClient) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "OIDC client", "update", models.InfraAdminRole)
+	}
+
+	return data.SaveOIDCClient(db, client)
+}
+
+// DeleteOIDCClient deletes the client. Tokens that were issued to the client
+// remain valid until they expire.
+func DeleteOIDCClient(c *gin.Context, id uid.ID) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "OIDC client", "delete", models.InfraAdminRole)
+	}
+
+	if _, err := data.GetOIDCClient(db, data.ByID(id)); err != nil {
+		return err
+	}
+	return data.DeleteOIDCClients(db, data.ByID(id))
+}
+
+// AuthorizeOIDCClient issues an authorization code to the client for the
+// authenticated user. The user must have a grant, directly or through one of
+// their groups, for a resource that matches the name of the client.
+func AuthorizeOIDCClient(c RequestContext, client *models.OIDCClient, authCode *models.OIDCAuthorizationCode) error {
+	identity := c.Authenticated.User
+	if identity == nil {
+		return fmt.Errorf("no active identity")
+	}
+
+	grants, err := data.ListGrants(c.DBTxn, nil,
+		data.GrantsInheritedBySubject(identity.PolyID()),
+		data.ByOptionalResourcePrefix(client.Name))
+	if err != nil {
+		return fmt.Errorf("grants for OIDC client: %w", err)
+	}
+	if !hasGrantForResource(grants, client.Name) {
+		return ErrNotAuthorized
+	}
+
+	authCode.OIDCClientID = client.ID
+	authCode.IdentityID = identity.ID
+	return data.CreateOIDCAuthorizationCode(c.DBTxn, authCode)
+}
+
+// OIDCTokenRequest is a request from an OIDC client to exchange an
+// authorization code for tokens.
+type OIDCTokenRequest struct {
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	// Issuer is the URL of the server, used as the issuer of the tokens.
+	Issuer string
+	TTL    time.Duration
+}
+
+// OIDCTokens are issued to an OIDC client in exchange for an authorization code.
+type OIDCTokens struct {
+	IDToken     string
+	AccessToken string
+	Scopes      []string
+	Expires     time.Time
+}
+
+// OIDCUserInfo are the claims about a user, returned by the userinfo
+// endpoint and included in ID tokens.
+type OIDCUserInfo struct {
+	Subject string   `json:"sub"`
+	Name    string   `json:"name,omitempty"`
+	Email   string   `json:"email,omitempty"`
+	Groups  []string `json:"groups"`
+}
+
+// oidcAccessTokenClaims are the claims of an access token issued to an OIDC
+// client, in addition to the registered claims.
+type oidcAccessTokenClaims struct {
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+}
+
+// ExchangeOIDCAuthorizationCode authenticates the client, and exchanges the
+// authorization code for an ID token and an access token. An authorization
+// code can only be exchanged once.
+//
+// Errors from authenticating the client wrap internal.ErrUnauthorized, and
+// errors from an invalid authorization code wrap internal.ErrBadRequest.
+func ExchangeOIDCAuthorizationCode(c RequestContext, req OIDCTokenRequest) (*OIDCTokens, error) {
+	// no auth required, the client is authenticated by its secret
+	db := c.DBTxn
+
+	client, err := data.GetOIDCClient(db, data.ByClientID(req.ClientID))
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		return nil, fmt.Errorf("%w: unknown client", internal.ErrUnauthorized)
+	case err != nil:
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(client.ClientSecret), []byte(req.ClientSecret)) != 1 {
+		return nil, fmt.Errorf("%w: invalid client secret", internal.ErrUnauthorized)
+	}
+
+	authCode, err := data.GetOIDCAuthorizationCode(db,
+		data.ByCode(req.Code),
+		data.ByOIDCClientID(client.ID),
+		data.ByNotExpired(time.Now()))
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		return nil, fmt.Errorf("%w: invalid or expired authorization code", internal.ErrBadRequest)
+	case err != nil:
+		return nil, err
+	}
+
+	// the code is deleted before it is checked, so that a code can not be
+	// guessed by a client with many attempts
+	if err := data.DeleteOIDCAuthorizationCode(db, authCode); err != nil {
+		return nil, err
+	}
+
+	if authCode.RedirectURI != req.RedirectURI {
+		return nil, fmt.Errorf("%w: redirect_uri does not match the authorization request", internal.ErrBadRequest)
+	}
+	if authCode.CodeChallenge != "" && !verifyCodeChallenge(authCode.CodeChallenge, req.CodeVerifier) {
+		return nil, fmt.Errorf("%w: invalid code_verifier", internal.ErrBadRequest)
+	}
+
+	userInfo, err := oidcUserInfo(db, authCode.IdentityID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	expires := now.Add(req.TTL)
+
+	idToken, err := data.SignJWT(db, jwt.Claims{
+		Issuer:   req.Issuer,
+		Subject:  userInfo.Subject,
+		Audience: jwt.Audience{client.ClientID},
+		Expiry:   jwt.NewNumericDate(expires),
+		IssuedAt: jwt.NewNumericDate(now),
+	}, userInfo, struct {
+		Nonce string `json:"nonce,omitempty"`
+	}{Nonce: authCode.Nonce})
+	if err != nil {
+		return nil, fmt.Errorf("sign ID token: %w", err)
+	}
+
+	accessToken, err := data.SignJWT(db, jwt.Claims{
+		Issuer:   req.Issuer,
+		Subject:  userInfo.Subject,
+		Audience: jwt.Audience{oidcUserInfoAudience(req.Issuer)},
+		Expiry:   jwt.NewNumericDate(expires),
+		IssuedAt: jwt.NewNumericDate(now),
+	}, oidcAccessTokenClaims{
+		ClientID: client.ClientID,
+		Scope:    strings.Join(authCode.Scopes, " "),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sign access token: %w", err)
+	}
+
+	return &OIDCTokens{
+		IDToken:     idToken,
+		AccessToken: accessToken,
+		Scopes:      authCode.Scopes,
+		Expires:     expires,
+	}, nil
+}
+
+// GetOIDCUserInfo returns the claims about the user of an access token that
+// was issued to an OIDC client. Errors from validating the token wrap
+// internal.ErrUnauthorized.
+func GetOIDCUserInfo(c RequestContext, accessToken string, issuer string) (*OIDCUserInfo, error) {
+	// no auth required, the caller is authenticated by the access token
+	db := c.DBTxn
+
+	var registered jwt.Claims
+	var custom oidcAccessTokenClaims
+	if err := data.ParseSignedJWT(db, accessToken, &registered, &custom); err != nil {
+		return nil, fmt.Errorf("%w: %v", internal.ErrUnauthorized, err)
+	}
+
+	err := registered.ValidateWithLeeway(jwt.Expected{
+		Issuer:   issuer,
+		Audience: jwt.Audience{oidcUserInfoAudience(issuer)},
+		Time:     time.Now(),
+	}, time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", internal.ErrUnauthorized, err)
+	}
+
+	// tokens issued to a client that was deleted are no longer accepted
+	if _, err := data.GetOIDCClient(db, data.ByClientID(custom.ClientID)); err != nil {
+		return nil, fmt.Errorf("%w: client of access token: %v", internal.ErrUnauthorized, err)
+	}
+
+	id, err := uid.Parse([]byte(registered.Subject))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid subject: %v", internal.ErrUnauthorized, err)
+	}
+
+	userInfo, err := oidcUserInfo(db, id)
+	if errors.Is(err, internal.ErrNotFound) {
+		return nil, fmt.Errorf("%w: user of access token: %v", internal.ErrUnauthorized, err)
+	}
+	return userInfo, err
+}
+
+// oidcUserInfoAudience is the audience of access tokens, so that ID tokens
+// and destination tokens are not accepted as access tokens.
+func oidcUserInfoAudience(issuer string) string {
+	return issuer + "/oidc/userinfo"
+}
+
+func oidcUserInfo(db data.GormTxn, identityID uid.ID) (*OIDCUserInfo, error) {
+	identity, err := data.GetIdentity(db, data.ByID(identityID))
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := data.ListGroups(db, nil, data.ByInheritedGroupMember(identityID))
+	if err != nil {
+		return nil, err
+	}
+
+	userInfo := &OIDCUserInfo{
+		Subject: identity.ID.String(),
+		Name:    identity.Name,
+		Groups:  make([]string, 0, len(groups)),
+	}
+	if strings.Contains(identity.Name, "@") {
+		userInfo.Email = identity.Name
+	}
+	for _, g := range groups {
+		userInfo.Groups = append(userInfo.Groups, g.Name)
+	}
+	return userInfo, nil
+}
+
+// verifyCodeChallenge checks the PKCE code verifier against the S256 code
+// challenge of the authorization request.
+func verifyCodeChallenge(challenge, verifier string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// hasGrantForResource returns true if any of the grants is for resource, or
+// for a pattern that matches resource.
+func hasGrantForResource(grants []models.Grant, resource string) bool {
+	for _, grant := range grants {
+		if grant.Resource == resource || api.MatchResource(grant.Resource, resource) {
+			return true
+		}
+	}
+	return false
+}
@@ -56,6 +56,58 @@ func DeleteOrganization(c *gin.Context, id uid.ID) error {
 	return data.DeleteOrganizations(db, data.ByID(id))
 }
 
+// organizationDB returns a transaction scoped to the organization with id.
+// Admins can use their own organization, and support admins can use any
+// organization.
+func organizationDB(c *gin.Context, id uid.ID, operation string) (data.GormTxn, error) {
+	rCtx := GetRequestContext(c)
+	if org := rCtx.Authenticated.Organization; org != nil && org.ID == id {
+		if db, err := RequireInfraRole(c, models.InfraAdminRole); err == nil {
+			return db, nil
+		}
+	}
+
+	db, err := RequireInfraRole(c, models.InfraSupportAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "organization", operation, models.InfraAdminRole, models.InfraSupportAdminRole)
+	}
+
+	if _, err := data.GetOrganization(db, data.ByID(id)); err != nil {
+		return nil, err
+	}
+	return data.NewTransaction(db.GormDB(), id), nil
+}
+
+func ExportOrganization(c *gin.Context, id uid.ID) (*models.Organization, *data.OrganizationExport, error) {
+	db, err := organizationDB(c, id, "export")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	org, err := data.GetOrganization(db, data.ByID(id))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	export, err := data.ExportOrganization(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	return org, export, nil
+}
+
+func ImportOrganization(c *gin.Context, id uid.ID, export *data.OrganizationExport, dryRun bool) ([]data.ImportConflict, error) {
+	db, err := organizationDB(c, id, "import")
+	if err != nil {
+		return nil, err
+	}
+
+	return data.ImportOrganization(db, export, data.ImportOptions{
+		DryRun:    dryRun,
+		CreatedBy: AuthenticatedIdentity(c).ID,
+	})
+}
+
 var domainNameReplacer = regexp.MustCompile(`[^\da-zA-Z-]`)
 
 func SanitizedDomain(subDomain, serverBaseDomain string) string {
@@ -0,0 +1,82 @@
+package access
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// reservedRoleNames can not be used as the name of a role, because grants
+// with these privileges already have a meaning.
+var reservedRoleNames = []string{
+	models.InfraSupportAdminRole,
+	models.InfraAdminRole,
+	models.InfraViewRole,
+	models.InfraConnectorRole,
+	models.BasePermissionConnect,
+}
+
+func ListRoles(c *gin.Context, name string, p *models.Pagination) ([]models.Role, error) {
+	// connectors list roles to create them in each cluster
+	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "roles", "list", roles...)
+	}
+
+	return data.ListRoles(db, p, data.ByOptionalName(name))
+}
+
+func GetRole(c *gin.Context, id uid.ID) (*models.Role, error) {
+	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "role", "get", roles...)
+	}
+
+	return data.GetRole(db, data.ByID(id))
+}
+
+func CreateRole(c *gin.Context, role *models.Role) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "role", "create", models.InfraAdminRole)
+	}
+
+	for _, reserved := range reservedRoleNames {
+		if role.Name == reserved {
+			return fmt.Errorf("%w: %q is a reserved role name", internal.ErrBadRequest, role.Name)
+		}
+	}
+
+	role.CreatedBy = AuthenticatedIdentity(c).ID
+	return data.CreateRole(db, role)
+}
+
+func UpdateRole(c *gin.Context, role *models.Role) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "role", "update", models.InfraAdminRole)
+	}
+
+	return data.SaveRole(db, role)
+}
+
+// DeleteRole deletes the role. Grants of the role are not removed, but they no
+// longer give access once connectors remove the ClusterRole of the role.
+func DeleteRole(c *gin.Context, id uid.ID) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "role", "delete", models.InfraAdminRole)
+	}
+
+	if _, err := data.GetRole(db, data.ByID(id)); err != nil {
+		return err
+	}
+	return data.DeleteRoles(db, data.ByID(id))
+}
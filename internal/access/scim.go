@@ -0,0 +1,311 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// CreateSCIMAccessKey creates an access key that an identity provider can use
+// to provision users and groups with SCIM. The key is issued for the calling
+// user, and can only be used with the SCIM endpoints.
+func CreateSCIMAccessKey(c *gin.Context, providerID uid.ID, expiresAt time.Time) (*models.AccessKey, string, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, "", HandleAuthErr(err, "SCIM access key", "create", models.InfraAdminRole)
+	}
+
+	provider, err := data.GetProvider(db, data.ByID(providerID))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if provider.Kind == models.ProviderKindInfra {
+		return nil, "", fmt.Errorf("%w: users of the infra provider can not be provisioned with SCIM", internal.ErrBadRequest)
+	}
+
+	key := &models.AccessKey{
+		IssuedFor:  AuthenticatedIdentity(c).ID,
+		ProviderID: provider.ID,
+		ExpiresAt:  expiresAt,
+		Scopes:     models.CommaSeparatedStrings{models.ScopeSCIM},
+	}
+	key.ID = uid.New()
+	key.Name = fmt.Sprintf("scim-%s-%s", provider.Name, key.ID)
+
+	body, err := data.CreateAccessKey(db, key)
+	if err != nil {
+		return nil, "", fmt.Errorf("create scim access key: %w", err)
+	}
+	return key, body, nil
+}
+
+// SCIMProvider returns the provider that the access key used to authenticate
+// the request was issued for. It returns an error if the access key is not a
+// SCIM access key.
+func SCIMProvider(c RequestContext) (*models.Provider, error) {
+	key := c.Authenticated.AccessKey
+	if key == nil || !key.Scopes.Includes(models.ScopeSCIM) {
+		return nil, fmt.Errorf("%w: a SCIM access key is required", internal.ErrUnauthorized)
+	}
+
+	return data.GetProvider(c.DBTxn, data.ByID(key.ProviderID))
+}
+
+func ListSCIMUsers(c RequestContext, name string) ([]models.Identity, error) {
+	provider, err := SCIMProvider(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.ListIdentities(c.DBTxn, nil,
+		data.Preload("Groups"),
+		data.ByIdentityProviderID(provider.ID),
+		data.ByOptionalName(name))
+}
+
+func GetSCIMUser(c RequestContext, id uid.ID) (*models.Identity, error) {
+	provider, err := SCIMProvider(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.GetIdentity(c.DBTxn,
+		data.Preload("Groups"),
+		data.ByIdentityProviderID(provider.ID),
+		data.ByID(id))
+}
+
+// CreateSCIMUser provisions a user for the provider. If a user with the same
+// name already exists, and is not a user of any provider, the existing user is
+// added to the provider. Users of other providers, like the local users of the
+// infra provider, can not be taken over by the provider.
+func CreateSCIMUser(c RequestContext, name string) (*models.Identity, error) {
+	provider, err := SCIMProvider(c)
+	if err != nil {
+		return nil, err
+	}
+
+	db := c.DBTxn
+	identity, err := data.GetIdentity(db, data.Preload("Providers"), data.ByName(name))
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		identity = &models.Identity{Name: name, CreatedBy: c.Authenticated.User.ID}
+		if err := data.CreateIdentity(db, identity); err != nil {
+			return nil, fmt.Errorf("create identity: %w", err)
+		}
+		if err := createWebhookEvent(db, api.WebhookEventUserCreated, identity.ToAPI()); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case len(identity.Providers) > 0:
+		return nil, data.UniqueConstraintError{Table: "identities", Column: "name"}
+	}
+
+	if _, err := data.CreateProviderUser(db, provider, identity); err != nil {
+		return nil, fmt.Errorf("create provider user: %w", err)
+	}
+	return identity, nil
+}
+
+// UpdateSCIMUser saves changes to the name of a user of the provider.
+func UpdateSCIMUser(c RequestContext, identity *models.Identity) error {
+	provider, err := SCIMProvider(c)
+	if err != nil {
+		return err
+	}
+
+	db := c.DBTxn
+	// don't save the preloaded associations
+	saved := *identity
+	saved.Groups, saved.Providers = nil, nil
+	if err := data.SaveIdentity(db, &saved); err != nil {
+		return err
+	}
+	// connectors bind grants to the name of the user
+	event := api.DestinationEvent{Type: api.DestinationEventUserUpdated, User: saved.ToAPI()}
+	if err := data.CreateDestinationEvent(db, "", event); err != nil {
+		return err
+	}
+
+	providerUser, err := data.GetProviderUser(db, provider.ID, identity.ID)
+	if err != nil {
+		return err
+	}
+	providerUser.Email = identity.Name
+	providerUser.LastUpdate = time.Now().UTC()
+	return data.UpdateProviderUser(db, providerUser)
+}
+
+// DeleteSCIMUser deprovisions a user of the provider. Any access keys issued
+// to the user are revoked immediately, and the user is removed from the groups
+// of the provider. The user is deleted if they are not a user of any other
+// provider.
+func DeleteSCIMUser(c RequestContext, id uid.ID) error {
+	provider, err := SCIMProvider(c)
+	if err != nil {
+		return err
+	}
+
+	if c.Authenticated.User.ID == id {
+		return fmt.Errorf("%w: the user that issued the SCIM access key can not be deprovisioned", internal.ErrBadRequest)
+	}
+
+	db := c.DBTxn
+	identity, err := data.GetIdentity(db,
+		data.Preload("Providers"),
+		data.ByIdentityProviderID(provider.ID),
+		data.ByID(id))
+	if err != nil {
+		return err
+	}
+
+	if err := data.DeleteAccessKeys(db, data.ByIssuedFor(identity.ID)); err != nil {
+		return fmt.Errorf("delete access keys: %w", err)
+	}
+
+	groups, err := data.ListGroups(db, nil, data.ByGroupMember(identity.ID), data.ByCreatedByProvider(provider.ID))
+	if err != nil {
+		return fmt.Errorf("list groups: %w", err)
+	}
+	for _, group := range groups {
+		if err := data.RemoveUsersFromGroup(db, group.ID, []uid.ID{identity.ID}); err != nil {
+			return fmt.Errorf("remove from group: %w", err)
+		}
+	}
+
+	if err := data.DeleteProviderUsers(db, data.ByIdentityID(identity.ID), data.ByProviderID(provider.ID)); err != nil {
+		return fmt.Errorf("delete provider user: %w", err)
+	}
+
+	if len(identity.Providers) > 1 {
+		return nil
+	}
+	if err := data.DeleteIdentities(db, data.ByID(identity.ID)); err != nil {
+		return err
+	}
+	return createWebhookEvent(db, api.WebhookEventUserDeleted, identity.ToAPI())
+}
+
+func ListSCIMGroups(c RequestContext, name string) ([]models.Group, error) {
+	provider, err := SCIMProvider(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.ListGroups(c.DBTxn, nil,
+		data.Preload("Identities"),
+		data.ByCreatedByProvider(provider.ID),
+		data.ByOptionalName(name))
+}
+
+func GetSCIMGroup(c RequestContext, id uid.ID) (*models.Group, error) {
+	provider, err := SCIMProvider(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.GetGroup(c.DBTxn,
+		data.Preload("Identities"),
+		data.ByCreatedByProvider(provider.ID),
+		data.ByID(id))
+}
+
+// CreateSCIMGroup creates a group for the provider with the users in
+// memberIDs. All members must be users of the provider.
+func CreateSCIMGroup(c RequestContext, group *models.Group, memberIDs []uid.ID) error {
+	provider, err := SCIMProvider(c)
+	if err != nil {
+		return err
+	}
+
+	if err := checkSCIMGroupMembers(c.DBTxn, provider, memberIDs); err != nil {
+		return err
+	}
+
+	group.CreatedBy = c.Authenticated.User.ID
+	group.CreatedByProvider = provider.ID
+	if err := data.CreateGroup(c.DBTxn, group); err != nil {
+		return err
+	}
+	return data.AddUsersToGroup(c.DBTxn, group.ID, memberIDs)
+}
+
+// UpdateSCIMGroup saves changes to the name of a group of the provider, and
+// updates its members. All members to add must be users of the provider.
+func UpdateSCIMGroup(c RequestContext, group *models.Group, addIDs, removeIDs []uid.ID) error {
+	provider, err := SCIMProvider(c)
+	if err != nil {
+		return err
+	}
+
+	if group.CreatedByProvider != provider.ID {
+		return internal.ErrNotFound
+	}
+
+	if err := checkSCIMGroupMembers(c.DBTxn, provider, addIDs); err != nil {
+		return err
+	}
+
+	db := c.DBTxn
+	// don't save the preloaded associations, members are updated below
+	saved := *group
+	saved.Identities = nil
+	if err := data.SaveGroup(db, &saved); err != nil {
+		return err
+	}
+	event := api.DestinationEvent{Type: api.DestinationEventGroupUpdated, Group: saved.ToAPI()}
+	if err := data.CreateDestinationEvent(db, "", event); err != nil {
+		return err
+	}
+	if err := data.RemoveUsersFromGroup(db, group.ID, removeIDs); err != nil {
+		return err
+	}
+	return data.AddUsersToGroup(db, group.ID, addIDs)
+}
+
+func DeleteSCIMGroup(c RequestContext, id uid.ID) error {
+	provider, err := SCIMProvider(c)
+	if err != nil {
+		return err
+	}
+
+	db := c.DBTxn
+	if _, err := data.GetGroup(db, data.ByCreatedByProvider(provider.ID), data.ByID(id)); err != nil {
+		return err
+	}
+	return data.DeleteGroups(db, data.ByID(id))
+}
+
+func checkSCIMGroupMembers(db data.GormTxn, provider *models.Provider, memberIDs []uid.ID) error {
+	if len(memberIDs) == 0 {
+		return nil
+	}
+
+	members, err := data.ListIdentities(db, nil,
+		data.ByIdentityProviderID(provider.ID),
+		data.ByIDs(memberIDs))
+	if err != nil {
+		return err
+	}
+
+	found := make(map[uid.ID]bool, len(members))
+	for _, member := range members {
+		found[member.ID] = true
+	}
+	for _, id := range memberIDs {
+		if !found[id] {
+			return fmt.Errorf("%w: member %v is not a user of provider %v", internal.ErrBadRequest, id, provider.Name)
+		}
+	}
+	return nil
+}
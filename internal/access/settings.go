@@ -2,6 +2,7 @@ package access
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/gin-gonic/gin"
 	"gopkg.in/square/go-jose.v2"
@@ -10,18 +11,48 @@ import (
 	"github.com/infrahq/infra/internal/server/models"
 )
 
+// GetPublicJWK returns the public keys of all the signing keys that have not
+// been retired. The active key is always first, because older connectors
+// verify tokens using only the first key.
 func GetPublicJWK(c RequestContext) ([]jose.JSONWebKey, error) {
-	settings, err := data.GetSettings(c.DBTxn)
+	keys, err := data.ListSigningKeys(c.DBTxn, nil,
+		data.BySigningKeyState(models.SigningKeyStateActive, models.SigningKeyStateRetiring))
 	if err != nil {
 		return nil, fmt.Errorf("could not get JWKs: %w", err)
 	}
 
-	var pubKey jose.JSONWebKey
-	if err := pubKey.UnmarshalJSON(settings.PublicJWK); err != nil {
-		return nil, fmt.Errorf("could not get JWKs: %w", err)
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].State == models.SigningKeyStateActive && keys[j].State != models.SigningKeyStateActive
+	})
+
+	result := make([]jose.JSONWebKey, 0, len(keys))
+	for _, key := range keys {
+		var pubKey jose.JSONWebKey
+		if err := pubKey.UnmarshalJSON(key.PublicJWK); err != nil {
+			return nil, fmt.Errorf("could not get JWKs: %w", err)
+		}
+		result = append(result, pubKey)
+	}
+
+	return result, nil
+}
+
+func ListSigningKeys(c *gin.Context, p *models.Pagination) ([]models.SigningKey, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "signing keys", "list", models.InfraAdminRole)
+	}
+
+	return data.ListSigningKeys(db, p)
+}
+
+func RotateSigningKeys(c *gin.Context, retirePrevious bool) (*models.SigningKey, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "signing keys", "rotate", models.InfraAdminRole)
 	}
 
-	return []jose.JSONWebKey{pubKey}, nil
+	return data.RotateSigningKeys(db, retirePrevious)
 }
 
 func GetSettings(c *gin.Context) (*models.Settings, error) {
@@ -48,7 +48,7 @@ func TestSignup(t *testing.T) {
 		c.Set("db", tx)
 
 		// check "admin" user can login
-		userPassLogin := authn.NewPasswordCredentialAuthentication(user, pass)
+		userPassLogin := authn.NewPasswordCredentialAuthentication(user, pass, "")
 		key, _, requiresUpdate, err := Login(c, userPassLogin, time.Now().Add(time.Hour), time.Hour)
 		assert.NilError(t, err)
 		assert.Equal(t, identity.ID, key.IssuedFor)
@@ -61,7 +61,9 @@ func TestSignup(t *testing.T) {
 		}
 
 		// check "admin" can create token
-		_, err = CreateToken(rCtx)
+		err = data.CreateDestination(tx, &models.Destination{Name: "production"})
+		assert.NilError(t, err)
+		_, err = CreateToken(rCtx, "production", "")
 		assert.NilError(t, err)
 	})
 }
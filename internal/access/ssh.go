@@ -0,0 +1,96 @@
+package access
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/pki"
+)
+
+// SSHCertificateLifetime is how long an SSH certificate is valid. The
+// certificate is only used to log in, so it is created just before it is used.
+const SSHCertificateLifetime = 5 * time.Minute
+
+// GetSSHUserCA returns the certificate authority that signs SSH certificates.
+// SSH destinations trust the CA.
+func GetSSHUserCA(c RequestContext) (*models.SSHUserCA, error) {
+	// does not need authorization check, the public key is not a secret
+	return data.GetSSHUserCA(c.DBTxn)
+}
+
+// CreateSSHCertificate signs an SSH certificate for the public key of the
+// authenticated user, that can log in to the destination named
+// destinationName. The privilege of each grant of the user for the destination
+// is a local user of the destination that the certificate can log in as.
+//
+// It returns the certificate, and the logins that its principals allow, as
+// described by api.SSHPrincipal.
+func CreateSSHCertificate(c RequestContext, destinationName string, key ssh.PublicKey) (*ssh.Certificate, []string, error) {
+	// does not need authorization check, limited to calling identity
+	user := c.Authenticated.User
+	if user == nil {
+		return nil, nil, fmt.Errorf("no active identity")
+	}
+
+	destination, err := data.GetDestination(c.DBTxn, data.ByName(destinationName))
+	if err != nil {
+		return nil, nil, fmt.Errorf("get destination: %w", err)
+	}
+
+	grants, err := data.ListGrants(c.DBTxn, nil,
+		data.GrantsInheritedBySubject(user.PolyID()),
+		data.ByOptionalResourcePrefix(destination.Name))
+	if err != nil {
+		return nil, nil, fmt.Errorf("list grants: %w", err)
+	}
+
+	logins := sshLogins(grants, destination.Name)
+	if len(logins) == 0 {
+		return nil, nil, AuthorizationError{Resource: destination.Name, Operation: "log in to"}
+	}
+
+	principals := make([]string, 0, len(logins))
+	for _, login := range logins {
+		principals = append(principals, api.SSHPrincipal(destination.Name, login))
+	}
+
+	ca, err := data.GetSSHUserCA(c.DBTxn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get ssh user ca: %w", err)
+	}
+
+	cert, err := pki.SignSSHUserCert(ca.PrivateKey, key, user.Name, principals, SSHCertificateLifetime)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, logins, nil
+}
+
+// sshLogins returns the sorted local users of the destination that the grants
+// allow logging in as. Grants for the resources of the destination, and
+// connect grants, do not allow logging in.
+func sshLogins(grants []models.Grant, destinationName string) []string {
+	seen := make(map[string]struct{})
+	logins := []string{}
+	for _, grant := range grants {
+		if grant.Privilege == "connect" {
+			continue
+		}
+		if !api.MatchResource(grant.Resource, destinationName) {
+			continue
+		}
+		if _, ok := seen[grant.Privilege]; ok {
+			continue
+		}
+		seen[grant.Privilege] = struct{}{}
+		logins = append(logins, grant.Privilege)
+	}
+	sort.Strings(logins)
+	return logins
+}
@@ -7,11 +7,24 @@ import (
 	"github.com/infrahq/infra/internal/server/models"
 )
 
-func CreateToken(c RequestContext) (token *models.Token, err error) {
+// CreateToken creates a token for the authenticated user that is only valid
+// for the destination named destinationName. The issuer is the URL of the
+// server, or empty if the URL is not known.
+func CreateToken(c RequestContext, destinationName string, issuer string) (token *models.Token, err error) {
 	// does not need authorization check, limited to calling identity
 	if c.Authenticated.User == nil {
 		return nil, fmt.Errorf("no active identity")
 	}
 
-	return data.CreateIdentityToken(c.DBTxn, c.Authenticated.User.ID)
+	destination, err := data.GetDestination(c.DBTxn, data.ByName(destinationName))
+	if err != nil {
+		return nil, fmt.Errorf("get destination: %w", err)
+	}
+
+	audience := []string{destination.Name}
+	if destination.UniqueID != "" {
+		audience = append(audience, destination.UniqueID)
+	}
+
+	return data.CreateIdentityToken(c.DBTxn, c.Authenticated.User.ID, audience, issuer)
 }
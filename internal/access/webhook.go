@@ -0,0 +1,65 @@
+package access
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func ListWebhooks(c *gin.Context, p *models.Pagination) ([]models.Webhook, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "webhooks", "list", models.InfraAdminRole)
+	}
+
+	return data.ListWebhooks(db, p)
+}
+
+func GetWebhook(c *gin.Context, id uid.ID) (*models.Webhook, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "webhook", "get", models.InfraAdminRole)
+	}
+
+	return data.GetWebhook(db, data.ByID(id))
+}
+
+func CreateWebhook(c *gin.Context, webhook *models.Webhook) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "webhook", "create", models.InfraAdminRole)
+	}
+
+	webhook.CreatedBy = AuthenticatedIdentity(c).ID
+	return data.CreateWebhook(db, webhook)
+}
+
+func DeleteWebhook(c *gin.Context, id uid.ID) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "webhook", "delete", models.InfraAdminRole)
+	}
+
+	if _, err := data.GetWebhook(db, data.ByID(id)); err != nil {
+		return err
+	}
+	return data.DeleteWebhooks(db, data.ByID(id))
+}
+
+// CreateWebhookEvent queues an event for delivery to the webhooks of the
+// organization. Events are created as a side effect of other changes, so
+// this does not need an authorization check.
+func CreateWebhookEvent(c *gin.Context, eventType string, payload any) error {
+	return createWebhookEvent(getDB(c), eventType, payload)
+}
+
+func createWebhookEvent(db data.GormTxn, eventType string, payload any) error {
+	if err := data.CreateWebhookEvent(db, eventType, payload); err != nil {
+		return fmt.Errorf("create %v webhook event: %w", eventType, err)
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/uid"
+)
+
+func newAccessCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "access",
+		Short: "Request and approve access to resources",
+		Group: "Core commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newAccessRequestCmd(cli))
+	cmd.AddCommand(newAccessListCmd(cli))
+	cmd.AddCommand(newAccessApproveCmd(cli))
+	cmd.AddCommand(newAccessDenyCmd(cli))
+
+	return cmd
+}
+
+type accessRequestOptions struct {
+	Role     string
+	Reason   string
+	Duration time.Duration
+}
+
+func newAccessRequestCmd(cli *CLI) *cobra.Command {
+	var options accessRequestOptions
+
+	cmd := &cobra.Command{
+		Use:   "request RESOURCE",
+		Short: "Request access to a resource",
+		Example: `# Request edit access to a namespace
+$ infra access request production.payments --role edit --reason "investigate failed payments"
+
+# Request access that expires after 4 hours once approved
+$ infra access request production --role view --duration 4h`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			createReq := &api.CreateAccessRequestRequest{
+				Privilege: options.Role,
+				Resource:  args[0],
+				Reason:    options.Reason,
+				Duration:  api.Duration(options.Duration),
+			}
+
+			logging.Debugf("call server: create access request %#v", createReq)
+			accessReq, err := client.CreateAccessRequest(createReq)
+			if err != nil {
+				return err
+			}
+
+			cli.Output("Requested %q access to %q", accessReq.Privilege, accessReq.Resource)
+			cli.Output("Ask an admin to approve the request with: infra access approve %s", accessReq.ID)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Role, "role", "", "Role to request")
+	cmd.Flags().StringVar(&options.Reason, "reason", "", "Why the access is needed")
+	cmd.Flags().DurationVar(&options.Duration, "duration", 0, "How long the access should last once approved")
+	_ = cmd.MarkFlagRequired("role")
+	return cmd
+}
+
+func newAccessListCmd(cli *CLI) *cobra.Command {
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List access requests",
+		Example: `# List access requests waiting for approval
+$ infra access list
+
+# List all access requests
+$ infra access list --all`,
+		Args: NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			listReq := api.ListAccessRequestsRequest{}
+			if !all {
+				listReq.State = api.AccessRequestStatePending
+			}
+
+			logging.Debugf("call server: list access requests")
+			reqs, err := listAll(client.ListAccessRequests, listReq)
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot list access requests: missing privileges for ListAccessRequests",
+					}
+				}
+				return err
+			}
+
+			users := make(map[uid.ID]string)
+
+			type row struct {
+				ID       string `header:"ID"`
+				User     string `header:"USER"`
+				Role     string `header:"ROLE"`
+				Resource string `header:"RESOURCE"`
+				Duration string `header:"DURATION"`
+				State    string `header:"STATE"`
+				Reason   string `header:"REASON"`
+			}
+
+			var rows []row
+			for _, r := range reqs {
+				name, ok := users[r.User]
+				if !ok {
+					name = r.User.String()
+					if user, err := client.GetUser(r.User); err == nil {
+						name = user.Name
+					}
+					users[r.User] = name
+				}
+
+				rows = append(rows, row{
+					ID:       r.ID.String(),
+					User:     name,
+					Role:     r.Privilege,
+					Resource: r.Resource,
+					Duration: formatAccessRequestDuration(r.Duration),
+					State:    r.State,
+					Reason:   r.Reason,
+				})
+			}
+
+			if len(rows) > 0 {
+				printTable(rows, cli.Stdout)
+			} else {
+				cli.Output("No access requests found")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Include approved and denied requests")
+	return cmd
+}
+
+func newAccessApproveCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "approve ID",
+		Short: "Approve an access request",
+		Example: `# Approve an access request
+$ infra access approve 4yJ3n3D8E2`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return reviewAccessRequest(cli, args[0], true)
+		},
+	}
+}
+
+func newAccessDenyCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "deny ID",
+		Short: "Deny an access request",
+		Example: `# Deny an access request
+$ infra access deny 4yJ3n3D8E2`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return reviewAccessRequest(cli, args[0], false)
+		},
+	}
+}
+
+func reviewAccessRequest(cli *CLI, rawID string, approve bool) error {
+	id, err := uid.Parse([]byte(rawID))
+	if err != nil || id == 0 {
+		return Error{Message: fmt.Sprintf("Invalid access request ID %q", rawID)}
+	}
+
+	client, err := defaultAPIClient()
+	if err != nil {
+		return err
+	}
+
+	review, action := client.DenyAccessRequest, "deny"
+	if approve {
+		review, action = client.ApproveAccessRequest, "approve"
+	}
+
+	logging.Debugf("call server: %s access request %s", action, id)
+	accessReq, err := review(id)
+	if err != nil {
+		switch api.ErrorStatusCode(err) {
+		case 403:
+			logging.Debugf("%s", err.Error())
+			return Error{
+				Message: fmt.Sprintf("Cannot %s access request: missing privileges", action),
+			}
+		case 404:
+			return Error{Message: fmt.Sprintf("Access request %s not found", id)}
+		}
+		return err
+	}
+
+	cli.Output("Access request %s %s: %q access to %q", accessReq.ID, accessReq.State, accessReq.Privilege, accessReq.Resource)
+	return nil
+}
+
+func formatAccessRequestDuration(d api.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return time.Duration(d).String()
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAccessCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	reqID := uid.ID(7654321)
+
+	setup := func(t *testing.T) chan api.CreateAccessRequestRequest {
+		createCh := make(chan api.CreateAccessRequestRequest, 1)
+
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			switch {
+			case requestMatches(req, http.MethodPost, "/api/access-requests"):
+				var createReq api.CreateAccessRequestRequest
+				err := json.NewDecoder(req.Body).Decode(&createReq)
+				assert.Check(t, err)
+				createCh <- createReq
+
+				resp.WriteHeader(http.StatusCreated)
+				err = json.NewEncoder(resp).Encode(api.AccessRequest{
+					ID:        reqID,
+					Privilege: createReq.Privilege,
+					Resource:  createReq.Resource,
+					State:     api.AccessRequestStatePending,
+				})
+				assert.Check(t, err)
+			case requestMatches(req, http.MethodPost, "/api/access-requests/"+reqID.String()+"/approve"):
+				resp.WriteHeader(http.StatusCreated)
+				err := json.NewEncoder(resp).Encode(api.AccessRequest{
+					ID:        reqID,
+					Privilege: "edit",
+					Resource:  "production.payments",
+					State:     api.AccessRequestStateApproved,
+				})
+				assert.Check(t, err)
+			case requestMatches(req, http.MethodPost, "/api/access-requests/"+reqID.String()+"/deny"):
+				resp.WriteHeader(http.StatusForbidden)
+				err := json.NewEncoder(resp).Encode(api.Error{Code: http.StatusForbidden})
+				assert.Check(t, err)
+			default:
+				resp.WriteHeader(http.StatusBadRequest)
+			}
+		}
+
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		err := writeConfig(&cfg)
+		assert.NilError(t, err)
+		return createCh
+	}
+
+	t.Run("request", func(t *testing.T) {
+		ch := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "access", "request", "production.payments",
+			"--role", "edit", "--reason", "fix payments", "--duration", "4h")
+		assert.NilError(t, err)
+
+		createReq := <-ch
+		expected := api.CreateAccessRequestRequest{
+			Privilege: "edit",
+			Resource:  "production.payments",
+			Reason:    "fix payments",
+			Duration:  api.Duration(4 * time.Hour),
+		}
+		assert.DeepEqual(t, createReq, expected)
+		assert.Equal(t, bufs.Stdout.String(), `Requested "edit" access to "production.payments"
+Ask an admin to approve the request with: infra access approve `+reqID.String()+"\n")
+	})
+
+	t.Run("request without role", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "access", "request", "production.payments")
+		assert.ErrorContains(t, err, `"role" not set`)
+	})
+
+	t.Run("approve", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "access", "approve", reqID.String())
+		assert.NilError(t, err)
+		assert.Equal(t, bufs.Stdout.String(),
+			`Access request `+reqID.String()+` approved: "edit" access to "production.payments"`+"\n")
+	})
+
+	t.Run("deny without privileges", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "access", "deny", reqID.String())
+		assert.ErrorContains(t, err, "Cannot deny access request: missing privileges")
+	})
+
+	t.Run("approve invalid id", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "access", "approve", "not-an-id!")
+		assert.ErrorContains(t, err, `Invalid access request ID "not-an-id!"`)
+	})
+}
@@ -41,6 +41,7 @@ func newAgentCmd() *cobra.Command {
 			defer cancel()
 
 			repeat.InGroup(&wg, ctx, cancel, 1*time.Minute, syncKubeConfig)
+			repeat.InGroup(&wg, ctx, cancel, 1*time.Minute, refreshTokens)
 			// add the next agent task here
 
 			logging.Infof("starting infra agent (%s)", internal.FullVersion())
@@ -163,3 +164,25 @@ func syncKubeConfig(ctx context.Context, cancel context.CancelFunc) {
 		cancel()
 	}
 }
+
+// refreshTokens replaces cached destination tokens before they expire, so that
+// kubectl does not need to wait for the server to create a token
+func refreshTokens(ctx context.Context, cancel context.CancelFunc) {
+	config, err := currentHostConfig()
+	if err != nil {
+		logging.Errorf("agent failed to read config: %v\n", err)
+		return
+	}
+
+	client, err := defaultAPIClient()
+	if err != nil {
+		logging.Errorf("api client: %v\n", err)
+		return
+	}
+
+	client.Name = "agent"
+
+	if err := refreshCachedTokens(client, config); err != nil {
+		logging.Errorf("agent failed to refresh tokens: %v\n", err)
+	}
+}
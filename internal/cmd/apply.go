@@ -0,0 +1,563 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/uid"
+)
+
+// accessConfig is the declarative description of users, groups, and grants
+// read by infra apply and infra diff.
+type accessConfig struct {
+	Users  []accessConfigUser  `json:"users"`
+	Groups []accessConfigGroup `json:"groups"`
+	Grants []accessConfigGrant `json:"grants"`
+}
+
+type accessConfigUser struct {
+	Name string `json:"name"`
+}
+
+type accessConfigGroup struct {
+	Name  string   `json:"name"`
+	Users []string `json:"users"`
+}
+
+type accessConfigGrant struct {
+	User     string `json:"user"`
+	Group    string `json:"group"`
+	Resource string `json:"resource"`
+	Role     string `json:"role"`
+}
+
+func readAccessConfig(cli *CLI, filename string) (*accessConfig, error) {
+	var raw []byte
+	var err error
+	if filename == "-" {
+		raw, err = io.ReadAll(cli.Stdin)
+	} else {
+		raw, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg accessConfig
+	if err := yaml.UnmarshalStrict(raw, &cfg); err != nil {
+		return nil, Error{Message: fmt.Sprintf("Invalid file %q: %v", filename, err)}
+	}
+
+	for i, user := range cfg.Users {
+		if user.Name == "" {
+			return nil, Error{Message: fmt.Sprintf("Invalid file %q: users[%d] is missing a name", filename, i)}
+		}
+	}
+	for i, group := range cfg.Groups {
+		if group.Name == "" {
+			return nil, Error{Message: fmt.Sprintf("Invalid file %q: groups[%d] is missing a name", filename, i)}
+		}
+	}
+	for i, grant := range cfg.Grants {
+		switch {
+		case (grant.User == "") == (grant.Group == ""):
+			return nil, Error{Message: fmt.Sprintf("Invalid file %q: grants[%d] must have one of user or group", filename, i)}
+		case grant.Resource == "":
+			return nil, Error{Message: fmt.Sprintf("Invalid file %q: grants[%d] is missing a resource", filename, i)}
+		case grant.Role == "":
+			return nil, Error{Message: fmt.Sprintf("Invalid file %q: grants[%d] is missing a role", filename, i)}
+		}
+	}
+	return &cfg, nil
+}
+
+// accessState is the users, groups, and grants that exist in Infra.
+type accessState struct {
+	users   map[string]uid.ID
+	groups  map[string]uid.ID
+	members map[string][]string
+	grants  []api.Grant
+}
+
+func loadAccessState(client *api.Client) (*accessState, error) {
+	state := &accessState{
+		users:   map[string]uid.ID{},
+		groups:  map[string]uid.ID{},
+		members: map[string][]string{},
+	}
+
+	logging.Debugf("call server: list users")
+	users, err := listAll(client.ListUsers, api.ListUsersRequest{})
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		state.users[user.Name] = user.ID
+	}
+
+	logging.Debugf("call server: list groups")
+	groups, err := listAll(client.ListGroups, api.ListGroupsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		state.groups[group.Name] = group.ID
+
+		logging.Debugf("call server: list users in group %q", group.Name)
+		members, err := listAll(client.ListUsers, api.ListUsersRequest{Group: group.ID})
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			state.members[group.Name] = append(state.members[group.Name], member.Name)
+		}
+	}
+
+	logging.Debugf("call server: list grants")
+	state.grants, err = listAll(client.ListGrants, api.ListGrantsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+type accessChangeOp string
+
+const (
+	accessChangeAdd    accessChangeOp = "+"
+	accessChangeRemove accessChangeOp = "-"
+)
+
+// accessChange is a single change required to make Infra match an
+// accessConfig.
+type accessChange struct {
+	Op   accessChangeOp
+	Kind string // user, group, member, or grant
+
+	User     string
+	Group    string
+	Resource string
+	Role     string
+
+	// ID of the grant to remove
+	GrantID uid.ID
+}
+
+func (c accessChange) String() string {
+	switch c.Kind {
+	case "user":
+		return fmt.Sprintf("%s user %s", c.Op, c.User)
+	case "group":
+		return fmt.Sprintf("%s group %s", c.Op, c.Group)
+	case "member":
+		return fmt.Sprintf("%s user %s in group %s", c.Op, c.User, c.Group)
+	default:
+		subject := "user " + c.User
+		if c.Group != "" {
+			subject = "group " + c.Group
+		}
+		return fmt.Sprintf("%s grant %s %s on %s", c.Op, subject, c.Role, c.Resource)
+	}
+}
+
+// apiOperation returns the name of the API operation used to make the change.
+func (c accessChange) apiOperation() string {
+	if c.Kind == "member" {
+		return "UpdateUsersInGroup"
+	}
+
+	op := "Delete"
+	if c.Op == accessChangeAdd {
+		op = "Create"
+	}
+	switch c.Kind {
+	case "user":
+		return op + "User"
+	case "group":
+		return op + "Group"
+	default:
+		return op + "Grant"
+	}
+}
+
+type accessPlanOptions struct {
+	// Prune removes users, groups, group members, and grants that are not
+	// in the config.
+	Prune bool
+	// Self is the name of the logged in user. Prune never removes this user,
+	// or their grants, so that apply can not lock out the user running it.
+	Self string
+}
+
+// planAccessChanges returns the changes required to make the current state
+// match the config. Additions are ordered before removals, and each kind is
+// ordered so that users and groups exist before they are used.
+func planAccessChanges(cfg *accessConfig, current *accessState, opts accessPlanOptions) []accessChange {
+	wantUsers := map[string]bool{}
+	wantGroups := map[string]bool{}
+	wantMembers := map[string]map[string]bool{}
+
+	for _, user := range cfg.Users {
+		wantUsers[user.Name] = true
+	}
+	for _, group := range cfg.Groups {
+		wantGroups[group.Name] = true
+		wantMembers[group.Name] = map[string]bool{}
+		for _, name := range group.Users {
+			wantUsers[name] = true
+			wantMembers[group.Name][name] = true
+		}
+	}
+	for _, grant := range cfg.Grants {
+		if grant.User != "" {
+			wantUsers[grant.User] = true
+		} else {
+			wantGroups[grant.Group] = true
+		}
+	}
+
+	var adds, removes []accessChange
+
+	for _, name := range sortedKeys(wantUsers) {
+		if _, ok := current.users[name]; !ok {
+			adds = append(adds, accessChange{Op: accessChangeAdd, Kind: "user", User: name})
+		}
+	}
+	for _, name := range sortedKeys(wantGroups) {
+		if _, ok := current.groups[name]; !ok {
+			adds = append(adds, accessChange{Op: accessChangeAdd, Kind: "group", Group: name})
+		}
+	}
+
+	for _, group := range sortedKeys(wantMembers) {
+		existing := map[string]bool{}
+		for _, name := range current.members[group] {
+			existing[name] = true
+		}
+		for _, name := range sortedKeys(wantMembers[group]) {
+			if !existing[name] {
+				adds = append(adds, accessChange{Op: accessChangeAdd, Kind: "member", User: name, Group: group})
+			}
+		}
+		if !opts.Prune {
+			continue
+		}
+		for _, name := range sortedKeys(existing) {
+			if !wantMembers[group][name] {
+				removes = append(removes, accessChange{Op: accessChangeRemove, Kind: "member", User: name, Group: group})
+			}
+		}
+	}
+
+	userNames := map[uid.ID]string{}
+	for name, id := range current.users {
+		userNames[id] = name
+	}
+	groupNames := map[uid.ID]string{}
+	for name, id := range current.groups {
+		groupNames[id] = name
+	}
+
+	type grantKey struct {
+		user, group, resource, role string
+	}
+	existingGrants := map[grantKey]bool{}
+	var pruneGrants []accessChange
+	for _, grant := range current.grants {
+		key := grantKey{
+			user:     userNames[grant.User],
+			group:    groupNames[grant.Group],
+			resource: grant.Resource,
+			role:     grant.Privilege,
+		}
+		if key.user == "" && key.group == "" {
+			// the grant is for a system user, which is not managed by apply
+			continue
+		}
+		existingGrants[key] = true
+
+		// grants that expire are temporary, usually from an access request,
+		// so they are never pruned.
+		if !grant.Expires.Time().IsZero() || (key.user != "" && key.user == opts.Self) {
+			continue
+		}
+		pruneGrants = append(pruneGrants, accessChange{
+			Op:       accessChangeRemove,
+			Kind:     "grant",
+			User:     key.user,
+			Group:    key.group,
+			Resource: key.resource,
+			Role:     key.role,
+			GrantID:  grant.ID,
+		})
+	}
+
+	wantGrants := map[grantKey]bool{}
+	for _, grant := range cfg.Grants {
+		key := grantKey{user: grant.User, group: grant.Group, resource: grant.Resource, role: grant.Role}
+		if wantGrants[key] {
+			continue
+		}
+		wantGrants[key] = true
+		if !existingGrants[key] {
+			adds = append(adds, accessChange{
+				Op:       accessChangeAdd,
+				Kind:     "grant",
+				User:     grant.User,
+				Group:    grant.Group,
+				Resource: grant.Resource,
+				Role:     grant.Role,
+			})
+		}
+	}
+
+	if !opts.Prune {
+		return adds
+	}
+
+	for _, change := range pruneGrants {
+		key := grantKey{user: change.User, group: change.Group, resource: change.Resource, role: change.Role}
+		if !wantGrants[key] {
+			removes = append(removes, change)
+		}
+	}
+	for _, name := range sortedKeys(current.groups) {
+		if !wantGroups[name] {
+			removes = append(removes, accessChange{Op: accessChangeRemove, Kind: "group", Group: name})
+		}
+	}
+	for _, name := range sortedKeys(current.users) {
+		if !wantUsers[name] && name != opts.Self {
+			removes = append(removes, accessChange{Op: accessChangeRemove, Kind: "user", User: name})
+		}
+	}
+
+	return append(adds, removes...)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// applyAccessChange makes the change using client. New users and groups are
+// added to state so that later changes can refer to them.
+func applyAccessChange(client *api.Client, state *accessState, change accessChange) error {
+	switch {
+	case change.Kind == "user" && change.Op == accessChangeAdd:
+		logging.Debugf("call server: create user named %q", change.User)
+		user, err := client.CreateUser(&api.CreateUserRequest{Name: change.User})
+		if err != nil {
+			return err
+		}
+		state.users[change.User] = user.ID
+		return nil
+
+	case change.Kind == "user":
+		logging.Debugf("call server: delete user %s", state.users[change.User])
+		return client.DeleteUser(state.users[change.User])
+
+	case change.Kind == "group" && change.Op == accessChangeAdd:
+		logging.Debugf("call server: create group named %q", change.Group)
+		group, err := client.CreateGroup(&api.CreateGroupRequest{Name: change.Group})
+		if err != nil {
+			return err
+		}
+		state.groups[change.Group] = group.ID
+		return nil
+
+	case change.Kind == "group":
+		logging.Debugf("call server: delete group %s", state.groups[change.Group])
+		return client.DeleteGroup(state.groups[change.Group])
+
+	case change.Kind == "member":
+		req := &api.UpdateUsersInGroupRequest{GroupID: state.groups[change.Group]}
+		if change.Op == accessChangeAdd {
+			req.UserIDsToAdd = []uid.ID{state.users[change.User]}
+		} else {
+			req.UserIDsToRemove = []uid.ID{state.users[change.User]}
+		}
+		logging.Debugf("call server: update users in group %s", req.GroupID)
+		return client.UpdateUsersInGroup(req)
+
+	case change.Op == accessChangeAdd:
+		req := &api.CreateGrantRequest{
+			Privilege: change.Role,
+			Resource:  change.Resource,
+		}
+		if change.Group != "" {
+			req.Group = state.groups[change.Group]
+		} else {
+			req.User = state.users[change.User]
+		}
+		logging.Debugf("call server: create grant %#v", req)
+		_, err := client.CreateGrant(req)
+		return err
+
+	default:
+		logging.Debugf("call server: delete grant %s", change.GrantID)
+		return client.DeleteGrant(change.GrantID)
+	}
+}
+
+type applyOptions struct {
+	Filename string
+	Prune    bool
+}
+
+func newApplyCmd(cli *CLI) *cobra.Command {
+	var options applyOptions
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Apply users, groups, and grants from a file",
+		Long: `Apply users, groups, and grants from a file.
+
+Users and groups are created if they do not exist, users are added to groups,
+and grants are created. With --prune, users, groups, group members, and grants
+that are not in the file are removed. Prune never removes the logged in user,
+their grants, or grants that expire.
+
+Use 'infra diff' to see the changes that would be made.`,
+		Example: `
+# Apply the access defined in access.yaml
+$ infra apply -f access.yaml
+
+# access.yaml
+users:
+  - name: alice@example.com
+groups:
+  - name: developers
+    users:
+      - alice@example.com
+grants:
+  - group: developers
+    role: edit
+    resource: staging
+`,
+		Args:  NoArgs,
+		Group: "Management commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, changes, state, err := planAccessChangesFromFile(cli, options)
+			if err != nil {
+				return err
+			}
+
+			if len(changes) == 0 {
+				cli.Output("No changes")
+				return nil
+			}
+
+			for _, change := range changes {
+				if err := applyAccessChange(client, state, change); err != nil {
+					return handleApplyError(err, "apply changes", change.apiOperation())
+				}
+				cli.Output(change.String())
+			}
+			cli.Output("Applied %d changes", len(changes))
+			return nil
+		},
+	}
+
+	addApplyFlags(cmd, &options)
+	return cmd
+}
+
+func newDiffCmd(cli *CLI) *cobra.Command {
+	var options applyOptions
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show the changes that apply would make",
+		Example: `
+# Show the changes required to match access.yaml
+$ infra diff -f access.yaml --prune
+`,
+		Args:  NoArgs,
+		Group: "Management commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			_, changes, _, err := planAccessChangesFromFile(cli, options)
+			if err != nil {
+				return err
+			}
+
+			if len(changes) == 0 {
+				cli.Output("No changes")
+				return nil
+			}
+
+			for _, change := range changes {
+				cli.Output(change.String())
+			}
+			return nil
+		},
+	}
+
+	addApplyFlags(cmd, &options)
+	return cmd
+}
+
+func addApplyFlags(cmd *cobra.Command, options *applyOptions) {
+	cmd.Flags().StringVarP(&options.Filename, "file", "f", "", "File with the users, groups, and grants, or - to read from stdin")
+	cmd.Flags().BoolVar(&options.Prune, "prune", false, "Remove users, groups, group members, and grants that are not in the file")
+	_ = cmd.MarkFlagRequired("file")
+}
+
+func planAccessChangesFromFile(cli *CLI, options applyOptions) (*api.Client, []accessChange, *accessState, error) {
+	cfg, err := readAccessConfig(cli, options.Filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	hostConfig, err := currentHostConfig()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	client, err := defaultAPIClient()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	state, err := loadAccessState(client)
+	if err != nil {
+		return nil, nil, nil, handleApplyError(err, "read current access", "ListUsers, ListGroups, and ListGrants")
+	}
+
+	changes := planAccessChanges(cfg, state, accessPlanOptions{
+		Prune: options.Prune,
+		Self:  hostConfig.Name,
+	})
+	return client, changes, state, nil
+}
+
+func handleApplyError(err error, action, operation string) error {
+	if api.ErrorStatusCode(err) == 403 {
+		logging.Debugf("%s", err.Error())
+		return Error{Message: fmt.Sprintf("Cannot %s: missing privileges for %s", action, operation)}
+	}
+	return err
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestPlanAccessChanges(t *testing.T) {
+	newState := func() *accessState {
+		return &accessState{
+			users: map[string]uid.ID{
+				"admin@example.com": 1,
+				"alice@example.com": 2,
+				"bob@example.com":   3,
+			},
+			groups: map[string]uid.ID{
+				"developers": 10,
+				"old-team":   11,
+			},
+			members: map[string][]string{
+				"developers": {"alice@example.com", "bob@example.com"},
+			},
+			grants: []api.Grant{
+				{ID: 100, User: 1, Privilege: "admin", Resource: "infra"},
+				{ID: 101, Group: 10, Privilege: "edit", Resource: "staging"},
+				{ID: 102, User: 3, Privilege: "view", Resource: "production"},
+				{ID: 103, User: 2, Privilege: "admin", Resource: "production", Expires: api.Time(time.Now().Add(time.Hour))},
+			},
+		}
+	}
+
+	cfg := &accessConfig{
+		Users: []accessConfigUser{{Name: "alice@example.com"}},
+		Groups: []accessConfigGroup{
+			{Name: "developers", Users: []string{"alice@example.com", "carol@example.com"}},
+		},
+		Grants: []accessConfigGrant{
+			{Group: "developers", Role: "edit", Resource: "staging"},
+			{Group: "operators", Role: "admin", Resource: "production"},
+			{User: "carol@example.com", Role: "view", Resource: "production"},
+		},
+	}
+
+	formatChanges := func(changes []accessChange) []string {
+		var result []string
+		for _, change := range changes {
+			result = append(result, change.String())
+		}
+		return result
+	}
+
+	t.Run("without prune", func(t *testing.T) {
+		changes := planAccessChanges(cfg, newState(), accessPlanOptions{Self: "admin@example.com"})
+		expected := []string{
+			"+ user carol@example.com",
+			"+ group operators",
+			"+ user carol@example.com in group developers",
+			"+ grant group operators admin on production",
+			"+ grant user carol@example.com view on production",
+		}
+		assert.DeepEqual(t, formatChanges(changes), expected)
+	})
+
+	t.Run("with prune", func(t *testing.T) {
+		changes := planAccessChanges(cfg, newState(), accessPlanOptions{Prune: true, Self: "admin@example.com"})
+		expected := []string{
+			"+ user carol@example.com",
+			"+ group operators",
+			"+ user carol@example.com in group developers",
+			"+ grant group operators admin on production",
+			"+ grant user carol@example.com view on production",
+			"- user bob@example.com in group developers",
+			"- grant user bob@example.com view on production",
+			"- group old-team",
+			"- user bob@example.com",
+		}
+		assert.DeepEqual(t, formatChanges(changes), expected)
+		assert.Equal(t, changes[6].GrantID, uid.ID(102))
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		state := newState()
+		cfg := &accessConfig{
+			Grants: []accessConfigGrant{
+				{Group: "developers", Role: "edit", Resource: "staging"},
+			},
+		}
+		changes := planAccessChanges(cfg, state, accessPlanOptions{})
+		assert.Equal(t, len(changes), 0)
+	})
+}
+
+func TestApplyCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	filename := filepath.Join(t.TempDir(), "access.yaml")
+	err := os.WriteFile(filename, []byte(`
+users:
+  - name: alice@example.com
+groups:
+  - name: developers
+    users:
+      - alice@example.com
+grants:
+  - group: developers
+    role: edit
+    resource: staging
+`), 0o600)
+	assert.NilError(t, err)
+
+	setup := func(t *testing.T) *[]string {
+		var lock sync.Mutex
+		calls := &[]string{}
+
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			write := func(v any) {
+				resp.WriteHeader(http.StatusOK)
+				assert.Check(t, json.NewEncoder(resp).Encode(v))
+			}
+
+			switch {
+			case requestMatches(req, http.MethodGet, "/api/users"):
+				if req.URL.Query().Get("group") != "" {
+					write(api.ListResponse[api.User]{})
+					return
+				}
+				write(api.ListResponse[api.User]{
+					Count: 2,
+					Items: []api.User{
+						{ID: 1, Name: "testuser@example.com"},
+						{ID: 2, Name: "bob@example.com"},
+					},
+				})
+			case requestMatches(req, http.MethodGet, "/api/groups"):
+				write(api.ListResponse[api.Group]{})
+			case requestMatches(req, http.MethodGet, "/api/grants"):
+				write(api.ListResponse[api.Grant]{
+					Count: 2,
+					Items: []api.Grant{
+						{ID: 100, User: 1, Privilege: "admin", Resource: "infra"},
+						{ID: 101, User: 2, Privilege: "view", Resource: "staging"},
+					},
+				})
+			case requestMatches(req, http.MethodPost, "/api/users"):
+				*calls = append(*calls, "create user")
+				write(api.CreateUserResponse{ID: 3, Name: "alice@example.com"})
+			case requestMatches(req, http.MethodPost, "/api/groups"):
+				*calls = append(*calls, "create group")
+				write(api.Group{ID: 10, Name: "developers"})
+			case requestMatches(req, http.MethodPatch, "/api/groups/"+uid.ID(10).String()+"/users"):
+				var body api.UpdateUsersInGroupRequest
+				assert.Check(t, json.NewDecoder(req.Body).Decode(&body))
+				*calls = append(*calls, fmt.Sprintf("add users %v", body.UserIDsToAdd))
+				resp.WriteHeader(http.StatusOK)
+			case requestMatches(req, http.MethodPost, "/api/grants"):
+				var body api.CreateGrantRequest
+				assert.Check(t, json.NewDecoder(req.Body).Decode(&body))
+				*calls = append(*calls, fmt.Sprintf("create grant group=%v %v %v", body.Group, body.Privilege, body.Resource))
+				write(api.CreateGrantResponse{Grant: &api.Grant{ID: 102}, WasCreated: true})
+			case req.Method == http.MethodDelete:
+				*calls = append(*calls, "delete "+req.URL.Path)
+				resp.WriteHeader(http.StatusNoContent)
+			default:
+				resp.WriteHeader(http.StatusBadRequest)
+			}
+		}
+
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		err := writeConfig(&cfg)
+		assert.NilError(t, err)
+		return calls
+	}
+
+	t.Run("diff", func(t *testing.T) {
+		calls := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "diff", "-f", filename, "--prune")
+		assert.NilError(t, err)
+		assert.Equal(t, len(*calls), 0)
+
+		expected := `+ user alice@example.com
++ group developers
++ user alice@example.com in group developers
++ grant group developers edit on staging
+- grant user bob@example.com view on staging
+- user bob@example.com
+`
+		assert.Equal(t, bufs.Stdout.String(), expected)
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		calls := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "apply", "-f", filename)
+		assert.NilError(t, err)
+
+		expected := []string{
+			"create user",
+			"create group",
+			"add users [" + uid.ID(3).String() + "]",
+			"create grant group=" + uid.ID(10).String() + " edit staging",
+		}
+		assert.DeepEqual(t, *calls, expected)
+		assert.Assert(t, strings.HasSuffix(bufs.Stdout.String(), "Applied 4 changes\n"))
+	})
+
+	t.Run("apply with prune", func(t *testing.T) {
+		calls := setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "apply", "-f", filename, "--prune")
+		assert.NilError(t, err)
+
+		assert.Equal(t, len(*calls), 6)
+		assert.DeepEqual(t, (*calls)[4:], []string{
+			"delete /api/grants/" + uid.ID(101).String(),
+			"delete /api/users/" + uid.ID(2).String(),
+		})
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+		err := os.WriteFile(invalid, []byte("grants:\n  - resource: staging\n    role: view\n"), 0o600)
+		assert.NilError(t, err)
+
+		err = Run(ctx, "apply", "-f", invalid)
+		assert.ErrorContains(t, err, "grants[0] must have one of user or group")
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+		err := os.WriteFile(invalid, []byte("grant:\n  - user: alice@example.com\n"), 0o600)
+		assert.NilError(t, err)
+
+		err = Run(ctx, "apply", "-f", invalid)
+		assert.ErrorContains(t, err, "Invalid file")
+	})
+}
@@ -48,6 +48,23 @@ func MaxArgs(max int) cobra.PositionalArgs {
 	}
 }
 
+// MinArgs validates that a cobra command is executed with at least min command
+// line arguments, otherwise it returns an error that includes the usage string.
+func MinArgs(min int) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(args) >= min {
+			return nil
+		}
+		return fmt.Errorf(
+			"%q requires at least %d %s.\nSee \"%s --help\".\n\nUsage:  %s\n",
+			cmd.CommandPath(),
+			min,
+			pluralize("argument", min),
+			cmd.CommandPath(),
+			cmd.UseLine())
+	}
+}
+
 // NoArgs validates that a cobra command is executed with no arguments, otherwise
 // it returns an error that includes the usage string.
 func NoArgs(cmd *cobra.Command, args []string) error {
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+)
+
+func newAuditCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "View the audit log",
+		Group: "Management commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newAuditListCmd(cli))
+
+	return cmd
+}
+
+type auditListOptions struct {
+	UserName string
+	Resource string
+	Since    time.Duration
+	Until    time.Duration
+}
+
+func newAuditListCmd(cli *CLI) *cobra.Command {
+	var options auditListOptions
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List audit events",
+		Example: `
+# List all changes made in the last day
+$ infra audit list --since 24h
+
+# List changes to grants made by a user
+$ infra audit list --user user@example.com --resource grants
+`,
+		Args: NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			listReq := api.ListAuditEventsRequest{Resource: options.Resource}
+
+			if options.UserName != "" {
+				user, err := getUserByNameOrID(client, options.UserName)
+				if err != nil {
+					return err
+				}
+				listReq.ActorID = user.ID
+			}
+
+			now := time.Now()
+			if options.Since > 0 {
+				listReq.After = api.Time(now.Add(-options.Since))
+			}
+			if options.Until > 0 {
+				listReq.Before = api.Time(now.Add(-options.Until))
+			}
+
+			logging.Debugf("call server: list audit events")
+			events, err := listAll(client.ListAuditEvents, listReq)
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot list audit events: missing privileges for ListAuditEvents",
+					}
+				}
+				return err
+			}
+
+			type row struct {
+				Time     string `header:"TIME"`
+				User     string `header:"USER"`
+				Method   string `header:"METHOD"`
+				Path     string `header:"PATH"`
+				Resource string `header:"RESOURCE ID"`
+				Status   string `header:"STATUS"`
+			}
+
+			var rows []row
+			for _, e := range events {
+				user := e.ActorName
+				if user == "" && e.ActorID != 0 {
+					user = e.ActorID.String()
+				}
+
+				resourceID := ""
+				if e.ResourceID != 0 {
+					resourceID = e.ResourceID.String()
+				}
+
+				rows = append(rows, row{
+					Time:     e.Created.Time().UTC().Format(time.RFC3339),
+					User:     user,
+					Method:   e.Method,
+					Path:     e.Path,
+					Resource: resourceID,
+					Status:   strconv.Itoa(e.StatusCode),
+				})
+			}
+
+			if len(rows) > 0 {
+				printTable(rows, cli.Stdout)
+			} else {
+				cli.Output("No audit events found")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.UserName, "user", "", "Filter by the name or id of the user that made the change")
+	cmd.Flags().StringVar(&options.Resource, "resource", "", "Filter by the kind of resource, ex: grants")
+	cmd.Flags().DurationVar(&options.Since, "since", 0, "Only show events from within this duration")
+	cmd.Flags().DurationVar(&options.Until, "until", 0, "Only show events older than this duration")
+	return cmd
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAuditListCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	created := time.Date(2022, 8, 22, 14, 58, 0, 0, time.UTC)
+
+	setup := func(t *testing.T) chan url.Values {
+		queryCh := make(chan url.Values, 1)
+
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			query := req.URL.Query()
+
+			// the command does a lookup for user ID
+			if requestMatches(req, http.MethodGet, "/api/users") {
+				if query.Get("name") != "my-user" {
+					resp.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				resp.WriteHeader(http.StatusOK)
+				err := json.NewEncoder(resp).Encode(api.ListResponse[api.User]{
+					Count: 1,
+					Items: []api.User{
+						{ID: uid.ID(12345678)},
+					},
+				})
+				assert.Check(t, err)
+				return
+			}
+
+			if !requestMatches(req, http.MethodGet, "/api/audit-events") {
+				resp.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			queryCh <- query
+			resp.WriteHeader(http.StatusOK)
+			err := json.NewEncoder(resp).Encode(api.ListResponse[api.AuditEvent]{
+				Count: 2,
+				Items: []api.AuditEvent{
+					{
+						Created:    api.Time(created),
+						ActorID:    uid.ID(12345678),
+						ActorName:  "my-user",
+						Method:     http.MethodPost,
+						Path:       "/api/grants",
+						Resource:   "grants",
+						ResourceID: uid.ID(9876),
+						StatusCode: http.StatusCreated,
+					},
+					{
+						Created:    api.Time(created.Add(time.Minute)),
+						Method:     http.MethodPost,
+						Path:       "/api/login",
+						Resource:   "login",
+						StatusCode: http.StatusUnauthorized,
+					},
+				},
+			})
+			assert.Check(t, err)
+		}
+
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		err := writeConfig(&cfg)
+		assert.NilError(t, err)
+		return queryCh
+	}
+
+	t.Run("list all", func(t *testing.T) {
+		ch := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "audit", "list")
+		assert.NilError(t, err)
+
+		query := <-ch
+		assert.Equal(t, query.Get("after"), "")
+		assert.Equal(t, query.Get("before"), "")
+		golden.Assert(t, bufs.Stdout.String(), t.Name())
+	})
+
+	t.Run("with filters", func(t *testing.T) {
+		ch := setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "audit", "list", "--user", "my-user", "--resource", "grants", "--since", "24h")
+		assert.NilError(t, err)
+
+		query := <-ch
+		assert.Equal(t, query.Get("actorID"), uid.ID(12345678).String())
+		assert.Equal(t, query.Get("resource"), "grants")
+
+		after, err := time.Parse(time.RFC3339, query.Get("after"))
+		assert.NilError(t, err)
+		assert.Assert(t, time.Since(after) > 23*time.Hour)
+	})
+}
@@ -261,6 +261,7 @@ func newConnectorCmd() *cobra.Command {
 	cmd.Flags().StringP("server-url", "s", "", "Infra server hostname")
 	cmd.Flags().StringP("server-access-key", "a", "", "Infra access key (use file:// to load from a file)")
 	cmd.Flags().StringP("name", "n", "", "Destination name")
+	cmd.Flags().String("kind", "", "Kind of destination: kubernetes, ssh, or postgres")
 	cmd.Flags().String("ca-cert", "", "Path to CA certificate file")
 	cmd.Flags().String("ca-key", "", "Path to CA key file")
 	cmd.Flags().Bool("server-skip-tls-verify", false, "Skip verifying server TLS certificates")
@@ -301,14 +302,26 @@ func NewRootCmd(cli *CLI) *cobra.Command {
 	rootCmd.AddCommand(newLogoutCmd(cli))
 	rootCmd.AddCommand(newListCmd(cli))
 	rootCmd.AddCommand(newUseCmd(cli))
+	rootCmd.AddCommand(newSSHCmd(cli))
+	rootCmd.AddCommand(newDBCmd(cli))
+	rootCmd.AddCommand(newAccessCmd(cli))
 
 	// Management commands:
 	rootCmd.AddCommand(newDestinationsCmd(cli))
 	rootCmd.AddCommand(newGrantsCmd(cli))
 	rootCmd.AddCommand(newUsersCmd(cli))
+	rootCmd.AddCommand(newMFACmd(cli))
 	rootCmd.AddCommand(newGroupsCmd(cli))
+	rootCmd.AddCommand(newRolesCmd(cli))
+	rootCmd.AddCommand(newFederationCmd(cli))
 	rootCmd.AddCommand(newKeysCmd(cli))
 	rootCmd.AddCommand(newProvidersCmd(cli))
+	rootCmd.AddCommand(newAuditCmd(cli))
+	rootCmd.AddCommand(newWebhooksCmd(cli))
+	rootCmd.AddCommand(newApplyCmd(cli))
+	rootCmd.AddCommand(newDiffCmd(cli))
+	rootCmd.AddCommand(newExportCmd(cli))
+	rootCmd.AddCommand(newImportCmd(cli))
 
 	// Other commands:
 	rootCmd.AddCommand(newInfoCmd(cli))
@@ -178,7 +178,7 @@ func TestUse(t *testing.T) {
 		assert.Equal(t, len(kubeconfig.Contexts), 2)
 		assert.Equal(t, len(kubeconfig.AuthInfos), 1)
 		assert.Equal(t, kubeconfig.CurrentContext, "infra:cluster")
-		assert.Assert(t, is.Contains(kubeconfig.AuthInfos, "testuser@example.com"))
+		assert.Assert(t, is.Contains(kubeconfig.AuthInfos, "infra:cluster"))
 	})
 
 	t.Run("UseNamespace", func(t *testing.T) {
@@ -194,7 +194,7 @@ func TestUse(t *testing.T) {
 		assert.Equal(t, len(kubeconfig.Contexts), 2)
 		assert.Equal(t, len(kubeconfig.AuthInfos), 1)
 		assert.Equal(t, kubeconfig.CurrentContext, "infra:cluster:namespace")
-		assert.Assert(t, is.Contains(kubeconfig.AuthInfos, "testuser@example.com"))
+		assert.Assert(t, is.Contains(kubeconfig.AuthInfos, "infra:cluster"))
 	})
 
 	t.Run("InfraUse", func(t *testing.T) {
@@ -373,8 +373,17 @@ server:
   skipTLSVerify: true
   trustedCertificate: ca.pem
 name: the-name
+kind: ssh
 caCert: /path/to/cert
 caKey: /path/to/key
+ssh:
+  configDir: /etc/ssh
+  port: 2222
+postgres:
+  url: postgres://infra@db.example.com/postgres
+  password: env:PGPASSWORD
+  roles:
+    readonly: env:READONLY_PASSWORD
 `
 
 	dir := fs.NewDir(t, t.Name(), fs.WithFile("config.yaml", content))
@@ -395,8 +404,18 @@ caKey: /path/to/key
 			SkipTLSVerify:      true,
 			TrustedCertificate: "ca.pem",
 		},
+		Kind:   "ssh",
 		CACert: "/path/to/cert",
 		CAKey:  "/path/to/key",
+		SSH: connector.SSHOptions{
+			ConfigDir: "/etc/ssh",
+			Port:      2222,
+		},
+		Postgres: connector.PostgresOptions{
+			URL:      "postgres://infra@db.example.com/postgres",
+			Password: "env:PGPASSWORD",
+			Roles:    map[string]string{"readonly": "env:READONLY_PASSWORD"},
+		},
 	}
 	assert.DeepEqual(t, actual, expected)
 }
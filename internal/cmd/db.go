@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	"github.com/jackc/pgproto3/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+)
+
+func newDBCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Connect to databases",
+		Group: "Core commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newDBConnectCmd(cli))
+	return cmd
+}
+
+func newDBConnectCmd(cli *CLI) *cobra.Command {
+	var port int
+
+	cmd := &cobra.Command{
+		Use:   "connect DESTINATION",
+		Short: "Connect to a database destination",
+		Long: `Connect to a database destination.
+
+A local port is opened for PostgreSQL clients, and each connection is forwarded
+to the destination with a token for the destination as the password. Connect
+to the local port without a password, and with the database role that you were
+granted as the user name.`,
+		Example: `
+# Connect to the db-1 destination
+$ infra db connect db-1
+
+# Listen on port 15432
+$ infra db connect db-1 --port 15432`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return dbConnect(cmd.Context(), cli, args[0], port)
+		},
+	}
+
+	cmd.Flags().IntVarP(&port, "port", "p", 0, "Local port to listen on. Defaults to a random port")
+	return cmd
+}
+
+func dbConnect(ctx context.Context, cli *CLI, destinationName string, port int) error {
+	config, err := currentHostConfig()
+	if err != nil {
+		return err
+	}
+
+	client, err := defaultAPIClient()
+	if err != nil {
+		return err
+	}
+
+	destinations, err := client.ListDestinations(api.ListDestinationsRequest{Name: destinationName})
+	if err != nil {
+		return err
+	}
+	if len(destinations.Items) == 0 {
+		return Error{Message: fmt.Sprintf("Destination %q not found; run 'infra list' to see the destinations you can access", destinationName)}
+	}
+	destination := destinations.Items[0]
+
+	// fail early when the user has no access to the destination
+	if _, err := destinationToken(client, config, destinationName); err != nil {
+		if api.ErrorStatusCode(err) == 403 {
+			return Error{Message: fmt.Sprintf("You do not have access to %q; run 'infra list' to see the destinations you can access", destinationName)}
+		}
+		return err
+	}
+
+	tlsConfig, err := destinationTLSConfig(destination)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	// nolint:forcetypeassert // the listener is always a TCP listener
+	localPort := listener.Addr().(*net.TCPAddr).Port
+	cli.Output("Forwarding connections from 127.0.0.1:%d to %v; connect with", localPort, destinationName)
+	cli.Output("  psql 'host=127.0.0.1 port=%d user=ROLE dbname=DATABASE'", localPort)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		go func() {
+			proxy := dbProxy{destination: destination, tlsConfig: tlsConfig, token: func() (string, error) {
+				token, err := destinationToken(client, config, destinationName)
+				return token.Token, err
+			}}
+			if err := proxy.handleConn(ctx, conn); err != nil {
+				logging.Errorf("%v", err)
+			}
+		}()
+	}
+}
+
+// destinationTLSConfig returns a TLS config that trusts the certificate of
+// the connector of destination.
+func destinationTLSConfig(destination api.Destination) (*tls.Config, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(destination.Connection.CA)) {
+		return nil, fmt.Errorf("destination %q has no valid CA certificate", destination.Name)
+	}
+
+	host, _, err := net.SplitHostPort(destination.Connection.URL)
+	if err != nil {
+		return nil, fmt.Errorf("destination %q has an invalid URL: %w", destination.Name, err)
+	}
+	return &tls.Config{RootCAs: pool, ServerName: host, MinVersion: tls.VersionTLS12}, nil
+}
+
+// dbProxy forwards connections from local PostgreSQL clients to the connector
+// of a destination, and sends the destination token as the password.
+type dbProxy struct {
+	destination api.Destination
+	tlsConfig   *tls.Config
+	token       func() (string, error)
+}
+
+func (p dbProxy) handleConn(ctx context.Context, conn net.Conn) error {
+	defer conn.Close()
+
+	backend := pgproto3.NewBackend(pgproto3.NewChunkReader(conn), conn)
+	var startup *pgproto3.StartupMessage
+	for startup == nil {
+		msg, err := backend.ReceiveStartupMessage()
+		if err != nil {
+			return err
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.SSLRequest, *pgproto3.GSSEncRequest:
+			// the local connection is not encrypted, the connection to the
+			// connector is.
+			if _, err := conn.Write([]byte{'N'}); err != nil {
+				return err
+			}
+		case *pgproto3.CancelRequest:
+			server, err := p.dial(ctx)
+			if err != nil {
+				return err
+			}
+			defer server.Close()
+			_, err = server.Write(msg.Encode(nil))
+			return err
+		case *pgproto3.StartupMessage:
+			startup = msg
+		}
+	}
+
+	sendError := func(message string) {
+		_ = backend.Send(&pgproto3.ErrorResponse{Severity: "FATAL", Code: "08006", Message: message})
+	}
+
+	token, err := p.token()
+	if err != nil {
+		sendError("infra failed to create a token: " + err.Error())
+		return fmt.Errorf("create token: %w", err)
+	}
+
+	server, err := p.dial(ctx)
+	if err != nil {
+		sendError("infra failed to connect to the destination: " + err.Error())
+		return fmt.Errorf("connect to %v: %w", p.destination.Name, err)
+	}
+	defer server.Close()
+
+	if _, err := server.Write(startup.Encode(nil)); err != nil {
+		return err
+	}
+
+	frontend := pgproto3.NewFrontend(pgproto3.NewChunkReader(server), server)
+	msg, err := frontend.Receive()
+	if err != nil {
+		return err
+	}
+	switch msg := msg.(type) {
+	case *pgproto3.AuthenticationCleartextPassword:
+	case *pgproto3.ErrorResponse:
+		return backend.Send(msg)
+	default:
+		return fmt.Errorf("unexpected message %T from the destination", msg)
+	}
+
+	if err := frontend.Send(&pgproto3.PasswordMessage{Password: token}); err != nil {
+		return err
+	}
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(server, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(conn, server)
+		done <- struct{}{}
+	}()
+	<-done
+	return nil
+}
+
+// dial connects to the connector of the destination, and starts TLS the way
+// PostgreSQL clients do.
+func (p dbProxy) dial(ctx context.Context) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", p.destination.Connection.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write((&pgproto3.SSLRequest{}).Encode(nil)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp := make([]byte, 1)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp[0] != 'S' {
+		conn.Close()
+		return nil, errors.New("the destination does not accept SSL connections")
+	}
+
+	tlsConn := tls.Client(conn, p.tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
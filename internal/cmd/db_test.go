@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/certs"
+)
+
+func TestDBConnectCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	var connectorAddr string
+	var caPEM []byte
+	var tokenStatus int
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		switch {
+		case requestMatches(req, http.MethodGet, "/api/destinations"):
+			var items []api.Destination
+			if req.URL.Query().Get("name") == "pg" {
+				items = append(items, api.Destination{
+					Name: "pg",
+					Connection: api.DestinationConnection{
+						URL: connectorAddr,
+						CA:  api.PEM(caPEM),
+					},
+				})
+			}
+			err := json.NewEncoder(resp).Encode(api.ListResponse[api.Destination]{Items: items, Count: len(items)})
+			assert.Check(t, err)
+
+		case requestMatches(req, http.MethodPost, "/api/tokens"):
+			if tokenStatus != http.StatusCreated {
+				resp.WriteHeader(tokenStatus)
+				_ = json.NewEncoder(resp).Encode(api.Error{Code: int32(tokenStatus)})
+				return
+			}
+			resp.WriteHeader(http.StatusCreated)
+			err := json.NewEncoder(resp).Encode(api.CreateTokenResponse{
+				Token:   "the-token",
+				Expires: api.Time(time.Now().Add(time.Hour)),
+			})
+			assert.Check(t, err)
+
+		default:
+			resp.WriteHeader(http.StatusNotFound)
+		}
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(handler))
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	// the fake connector uses the certificate of the API server
+	caPEM = certs.PEMEncodeCertificate(srv.Certificate().Raw)
+	connectorAddr = startFakeDBConnector(t, srv.TLS)
+
+	cfg := newTestClientConfig(srv, api.User{})
+	assert.NilError(t, writeConfig(&cfg))
+
+	// runs before a token is cached by the other tests
+	t.Run("no access", func(t *testing.T) {
+		tokenStatus = http.StatusForbidden
+		ctx, _ := PatchCLI(context.Background())
+		err := Run(ctx, "db", "connect", "pg")
+		assert.ErrorContains(t, err, `You do not have access to "pg"`)
+	})
+
+	t.Run("forwards connections", func(t *testing.T) {
+		tokenStatus = http.StatusCreated
+		port := freePort(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		ctx, bufs := PatchCLI(ctx)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Run(ctx, "db", "connect", "pg", "--port", fmt.Sprint(port))
+		}()
+
+		var conn *pgconn.PgConn
+		var err error
+		connString := fmt.Sprintf("postgres://readonly@127.0.0.1:%d/app?sslmode=prefer", port)
+		for i := 0; i < 100; i++ {
+			if conn, err = pgconn.Connect(ctx, connString); err == nil {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		assert.NilError(t, err)
+
+		result := conn.ExecParams(ctx, "SELECT current_user, current_database()", nil, nil, nil, nil).Read()
+		assert.NilError(t, result.Err)
+		assert.DeepEqual(t, result.Rows, [][][]byte{{[]byte("readonly"), []byte("app")}})
+		assert.NilError(t, conn.Close(ctx))
+
+		cancel()
+		assert.NilError(t, <-errCh)
+		assert.Assert(t, bufs.Stdout.String() != "")
+	})
+
+	t.Run("unknown destination", func(t *testing.T) {
+		ctx, _ := PatchCLI(context.Background())
+		err := Run(ctx, "db", "connect", "other")
+		assert.ErrorContains(t, err, `Destination "other" not found`)
+	})
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	// nolint:forcetypeassert // the listener is always a TCP listener
+	port := l.Addr().(*net.TCPAddr).Port
+	assert.NilError(t, l.Close())
+	return port
+}
+
+// startFakeDBConnector starts a server that accepts connections the way the
+// postgres connector does, and answers queries with the user and database of
+// the connection.
+func startFakeDBConnector(t *testing.T, tlsConfig *tls.Config) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go fakeDBConnectorConn(t, conn, tlsConfig)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func fakeDBConnectorConn(t *testing.T, conn net.Conn, tlsConfig *tls.Config) {
+	defer conn.Close()
+	backend := pgproto3.NewBackend(pgproto3.NewChunkReader(conn), conn)
+
+	msg, err := backend.ReceiveStartupMessage()
+	if !assert.Check(t, err) {
+		return
+	}
+	_, ok := msg.(*pgproto3.SSLRequest)
+	if !assert.Check(t, ok, "expected SSLRequest, got %T", msg) {
+		return
+	}
+	if _, err := conn.Write([]byte{'S'}); err != nil {
+		return
+	}
+	tlsConn := tls.Server(conn, tlsConfig)
+	backend = pgproto3.NewBackend(pgproto3.NewChunkReader(tlsConn), tlsConn)
+
+	msg, err = backend.ReceiveStartupMessage()
+	if !assert.Check(t, err) {
+		return
+	}
+	startup, ok := msg.(*pgproto3.StartupMessage)
+	if !assert.Check(t, ok, "expected StartupMessage, got %T", msg) {
+		return
+	}
+
+	_ = backend.Send(&pgproto3.AuthenticationCleartextPassword{})
+	_ = backend.SetAuthType(pgproto3.AuthTypeCleartextPassword)
+	msg, err = backend.Receive()
+	if !assert.Check(t, err) {
+		return
+	}
+	password, ok := msg.(*pgproto3.PasswordMessage)
+	if !assert.Check(t, ok, "expected PasswordMessage, got %T", msg) {
+		return
+	}
+	assert.Check(t, password.Password == "the-token")
+
+	var buf []byte
+	buf = (&pgproto3.AuthenticationOk{}).Encode(buf)
+	buf = (&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: 2}).Encode(buf)
+	buf = (&pgproto3.ReadyForQuery{TxStatus: 'I'}).Encode(buf)
+	if _, err := tlsConn.Write(buf); err != nil {
+		return
+	}
+
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return
+		}
+
+		switch msg.(type) {
+		case *pgproto3.Parse, *pgproto3.Bind, *pgproto3.Describe, *pgproto3.Execute:
+			continue
+		case *pgproto3.Sync:
+			buf = nil
+			buf = (&pgproto3.ParseComplete{}).Encode(buf)
+			buf = (&pgproto3.BindComplete{}).Encode(buf)
+			desc := &pgproto3.RowDescription{}
+			for _, name := range []string{"user", "database"} {
+				desc.Fields = append(desc.Fields, pgproto3.FieldDescription{Name: []byte(name), DataTypeOID: 25, DataTypeSize: -1})
+			}
+			buf = desc.Encode(buf)
+			buf = (&pgproto3.DataRow{Values: [][]byte{
+				[]byte(startup.Parameters["user"]),
+				[]byte(startup.Parameters["database"]),
+			}}).Encode(buf)
+			buf = (&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")}).Encode(buf)
+			buf = (&pgproto3.ReadyForQuery{TxStatus: 'I'}).Encode(buf)
+			if _, err := tlsConn.Write(buf); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/uid"
+)
+
+func parseOrganizationID(raw string) (api.IDOrSelf, error) {
+	if raw == "" {
+		return api.IDOrSelf{IsSelf: true}, nil
+	}
+	id, err := uid.Parse([]byte(raw))
+	if err != nil || id == 0 {
+		return api.IDOrSelf{}, Error{Message: fmt.Sprintf("Invalid organization ID %q", raw)}
+	}
+	return api.IDOrSelf{ID: id}, nil
+}
+
+func newExportCmd(cli *CLI) *cobra.Command {
+	var organization string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the users, groups, grants, and other resources of an organization",
+		Long: `Export the users, groups, grants, providers, destinations, and settings of an
+organization as JSON. Secrets, like provider client secrets and user
+passwords, are not exported.`,
+		Example: `
+# Export the current organization to a file
+$ infra export > org.json
+`,
+		Args:  NoArgs,
+		Group: "Management commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			id, err := parseOrganizationID(organization)
+			if err != nil {
+				return err
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: export organization %s", id)
+			export, err := client.ExportOrganization(id)
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot export organization: missing privileges for ExportOrganization",
+					}
+				}
+				return err
+			}
+
+			enc := json.NewEncoder(cli.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(export)
+		},
+	}
+
+	cmd.Flags().StringVar(&organization, "organization", "", "ID of the organization to export, defaults to the current organization")
+	return cmd
+}
+
+func newImportCmd(cli *CLI) *cobra.Command {
+	var organization string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "import FILE",
+		Short: "Import the resources of an organization from an export",
+		Long: `Import the users, groups, grants, providers, destinations, and settings from a
+file created by 'infra export'. Resources are created with new IDs. If any of
+the users, groups, providers, or destinations already exist nothing is
+imported. Use - to read the file from stdin.`,
+		Example: `
+# Check an export for conflicts with the current organization
+$ infra import org.json --dry-run
+
+# Import an export into the current organization
+$ infra import org.json
+`,
+		Args:  ExactArgs(1),
+		Group: "Management commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseOrganizationID(organization)
+			if err != nil {
+				return err
+			}
+
+			var raw []byte
+			if args[0] == "-" {
+				raw, err = io.ReadAll(cli.Stdin)
+			} else {
+				raw, err = os.ReadFile(args[0])
+			}
+			if err != nil {
+				return err
+			}
+
+			var export api.OrganizationExport
+			if err := json.Unmarshal(raw, &export); err != nil {
+				return Error{Message: fmt.Sprintf("Invalid export file %q: %v", args[0], err)}
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: import organization %s", id)
+			resp, err := client.ImportOrganization(&api.ImportOrganizationRequest{
+				ID:     id,
+				DryRun: dryRun,
+				Export: export,
+			})
+			if err != nil {
+				switch api.ErrorStatusCode(err) {
+				case 403:
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot import organization: missing privileges for ImportOrganization",
+					}
+				case 409:
+					return Error{
+						Message: fmt.Sprintf("Cannot import organization: %v. Run with --dry-run to list all conflicts", err),
+					}
+				}
+				return err
+			}
+
+			for _, conflict := range resp.Conflicts {
+				cli.Output("Conflict: %s with %s %q already exists", conflict.Kind, conflict.Field, conflict.Value)
+			}
+
+			verb := "Imported"
+			if resp.DryRun {
+				verb = "Would import"
+				if len(resp.Conflicts) > 0 {
+					verb = "Could not import"
+				}
+			}
+			counts := resp.Imported
+			cli.Output("%s %d users, %d groups, %d grants, %d providers, and %d destinations",
+				verb, counts.Users, counts.Groups, counts.Grants, counts.Providers, counts.Destinations)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&organization, "organization", "", "ID of the organization to import into, defaults to the current organization")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Report conflicts without importing anything")
+	return cmd
+}
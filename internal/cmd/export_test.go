@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+)
+
+func TestExportCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	export := api.OrganizationExport{
+		Version: api.OrganizationExportVersion,
+		Users:   []api.User{{ID: 1, Name: "alice@example.com"}},
+	}
+
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		if !requestMatches(req, http.MethodGet, "/api/organizations/self/export") {
+			resp.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		resp.WriteHeader(http.StatusOK)
+		assert.Check(t, json.NewEncoder(resp).Encode(export))
+	}
+	srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+	t.Cleanup(srv.Close)
+
+	cfg := newTestClientConfig(srv, api.User{})
+	assert.NilError(t, writeConfig(&cfg))
+
+	ctx, bufs := PatchCLI(context.Background())
+	err := Run(ctx, "export")
+	assert.NilError(t, err)
+
+	var actual api.OrganizationExport
+	assert.NilError(t, json.Unmarshal(bufs.Stdout.Bytes(), &actual))
+	assert.DeepEqual(t, actual, export)
+}
+
+func TestImportCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	filename := filepath.Join(t.TempDir(), "org.json")
+	raw, err := json.Marshal(api.OrganizationExport{
+		Version: api.OrganizationExportVersion,
+		Users:   []api.User{{ID: 1, Name: "alice@example.com"}},
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, os.WriteFile(filename, raw, 0o600))
+
+	setup := func(t *testing.T, status int, result api.ImportOrganizationResponse) *[]*http.Request {
+		requests := &[]*http.Request{}
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			if !requestMatches(req, http.MethodPost, "/api/organizations/self/import") {
+				resp.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			*requests = append(*requests, req)
+
+			var body api.ImportOrganizationRequest
+			assert.Check(t, json.NewDecoder(req.Body).Decode(&body))
+			assert.Check(t, len(body.Export.Users) == 1)
+
+			resp.WriteHeader(status)
+			if status >= 400 {
+				assert.Check(t, json.NewEncoder(resp).Encode(api.Error{Code: int32(status), Message: "conflict"}))
+				return
+			}
+			assert.Check(t, json.NewEncoder(resp).Encode(result))
+		}
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		assert.NilError(t, writeConfig(&cfg))
+		return requests
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		requests := setup(t, http.StatusOK, api.ImportOrganizationResponse{
+			DryRun:    true,
+			Imported:  api.ImportCounts{Users: 1},
+			Conflicts: []api.ImportConflict{{Kind: "user", Field: "name", Value: "alice@example.com"}},
+		})
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "import", filename, "--dry-run")
+		assert.NilError(t, err)
+
+		assert.Equal(t, len(*requests), 1)
+		assert.Equal(t, (*requests)[0].URL.Query().Get("dryRun"), "true")
+
+		expected := `Conflict: user with name "alice@example.com" already exists
+Could not import 1 users, 0 groups, 0 grants, 0 providers, and 0 destinations
+`
+		assert.Equal(t, bufs.Stdout.String(), expected)
+	})
+
+	t.Run("import", func(t *testing.T) {
+		setup(t, http.StatusCreated, api.ImportOrganizationResponse{
+			Imported: api.ImportCounts{Users: 1},
+		})
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "import", filename)
+		assert.NilError(t, err)
+		assert.Equal(t, bufs.Stdout.String(), "Imported 1 users, 0 groups, 0 grants, 0 providers, and 0 destinations\n")
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		setup(t, http.StatusConflict, api.ImportOrganizationResponse{})
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "import", filename)
+		assert.ErrorContains(t, err, "Run with --dry-run to list all conflicts")
+	})
+}
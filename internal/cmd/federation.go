@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+)
+
+func newFederationCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "federation",
+		Aliases: []string{"federations"},
+		Short:   "Manage workload identity federation",
+		Long: `Manage workload identity federation.
+
+A federation allows a workload, like a CI job, to login with a JWT signed by
+an external issuer instead of an access key. A token from the issuer with the
+expected audience, that matches all of the conditions of the federation, is
+exchanged for a short-lived access key for the user of the federation.`,
+		Group: "Management commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newFederationListCmd(cli))
+	cmd.AddCommand(newFederationAddCmd(cli))
+	cmd.AddCommand(newFederationEditCmd(cli))
+	cmd.AddCommand(newFederationRemoveCmd(cli))
+
+	return cmd
+}
+
+func newFederationListCmd(cli *CLI) *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List federations",
+		Args:    NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: list federations")
+			federations, err := listAll(client.ListFederations, api.ListFederationsRequest{})
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot list federations: missing privileges for ListFederations",
+					}
+				}
+				return err
+			}
+
+			switch format {
+			case "json":
+				jsonOutput, err := json.Marshal(federations)
+				if err != nil {
+					return err
+				}
+				cli.Output(string(jsonOutput))
+			case "yaml":
+				yamlOutput, err := yaml.Marshal(federations)
+				if err != nil {
+					return err
+				}
+				cli.Output(string(yamlOutput))
+			default:
+				type row struct {
+					Name       string `header:"NAME"`
+					Issuer     string `header:"ISSUER"`
+					User       string `header:"USER"`
+					Conditions string `header:"CONDITIONS"`
+				}
+
+				var rows []row
+				for _, federation := range federations {
+					user := federation.User.String()
+					if u, err := client.GetUser(federation.User); err == nil {
+						user = u.Name
+					}
+
+					rows = append(rows, row{
+						Name:       federation.Name,
+						Issuer:     federation.Issuer,
+						User:       user,
+						Conditions: formatConditions(federation.Conditions),
+					})
+				}
+
+				if len(rows) > 0 {
+					printTable(rows, cli.Stdout)
+				} else {
+					cli.Output("No federations found")
+				}
+			}
+
+			return nil
+		},
+	}
+
+	addFormatFlag(cmd.Flags(), &format)
+	return cmd
+}
+
+// formatConditions returns the conditions as a sorted list of key=value.
+func formatConditions(conditions map[string]string) string {
+	items := make([]string, 0, len(conditions))
+	for k, v := range conditions {
+		items = append(items, k+"="+v)
+	}
+	sort.Strings(items)
+	return strings.Join(items, ", ")
+}
+
+type federationOptions struct {
+	Issuer     string
+	JWKSURL    string
+	Audience   string
+	User       string
+	Conditions map[string]string
+	TTL        time.Duration
+}
+
+func addFederationFlags(cmd *cobra.Command, options *federationOptions) {
+	cmd.Flags().StringVar(&options.Issuer, "issuer", "", "URL of the issuer of the tokens")
+	cmd.Flags().StringVar(&options.JWKSURL, "jwks-url", "", "URL of the JSON Web Key Set of the issuer, defaults to the jwks_uri from the OpenID configuration of the issuer")
+	cmd.Flags().StringVar(&options.Audience, "audience", "", "Audience that tokens must be issued for")
+	cmd.Flags().StringVar(&options.User, "user", "", "User that the access keys are issued for")
+	cmd.Flags().StringToStringVar(&options.Conditions, "condition", nil, "Claim that tokens must have, as claim=value. The value may use * as a wildcard. A sub or repository condition with a literal owner is required")
+	cmd.Flags().DurationVar(&options.TTL, "ttl", 0, "How long the access keys are valid, defaults to 1 hour")
+}
+
+func newFederationAddCmd(cli *CLI) *cobra.Command {
+	var options federationOptions
+
+	cmd := &cobra.Command{
+		Use:   "add NAME",
+		Short: "Add a federation",
+		Example: `# Allow GitHub Actions workflows on the main branch of example/app to login as deploy@example.com
+$ infra federation add github-deploy \
+    --issuer https://token.actions.githubusercontent.com \
+    --audience https://infra.example.com \
+    --condition repository=example/app \
+    --condition ref=refs/heads/main \
+    --user deploy@example.com`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case options.Issuer == "":
+				return Error{Message: "Issuer is required, use --issuer"}
+			case options.Audience == "":
+				return Error{Message: "Audience is required, use --audience"}
+			case options.User == "":
+				return Error{Message: "User is required, use --user"}
+			case options.Conditions["sub"] == "" && options.Conditions["repository"] == "":
+				return Error{Message: "A sub or repository condition is required, use --condition"}
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			user, err := getUserByNameOrID(client, options.User)
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: create federation %q", args[0])
+			federation, err := client.CreateFederation(&api.CreateFederationRequest{
+				Name:       args[0],
+				Issuer:     options.Issuer,
+				JWKSURL:    options.JWKSURL,
+				Audience:   options.Audience,
+				Conditions: options.Conditions,
+				User:       user.ID,
+				TTL:        api.Duration(options.TTL),
+			})
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot add federation: missing privileges for CreateFederation",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Added federation %q", federation.Name)
+			return nil
+		},
+	}
+
+	addFederationFlags(cmd, &options)
+	return cmd
+}
+
+func newFederationEditCmd(cli *CLI) *cobra.Command {
+	var options federationOptions
+
+	cmd := &cobra.Command{
+		Use:   "edit NAME",
+		Short: "Update a federation",
+		Long: `Update a federation. Only the flags that are set are changed. When
+--condition is set, it replaces all of the conditions of the federation.`,
+		Example: `# Allow workflows on any branch
+$ infra federation edit github-deploy --condition repository=example/app --condition ref=refs/heads/*`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			federation, err := getFederationByName(client, args[0])
+			if err != nil {
+				return err
+			}
+
+			req := api.UpdateFederationRequest{
+				ID:         federation.ID,
+				Issuer:     federation.Issuer,
+				JWKSURL:    federation.JWKSURL,
+				Audience:   federation.Audience,
+				Conditions: federation.Conditions,
+				User:       federation.User,
+				TTL:        federation.TTL,
+			}
+
+			flags := cmd.Flags()
+			if flags.Changed("issuer") {
+				req.Issuer = options.Issuer
+			}
+			if flags.Changed("jwks-url") {
+				req.JWKSURL = options.JWKSURL
+			}
+			if flags.Changed("audience") {
+				req.Audience = options.Audience
+			}
+			if flags.Changed("condition") {
+				req.Conditions = options.Conditions
+			}
+			if flags.Changed("ttl") {
+				req.TTL = api.Duration(options.TTL)
+			}
+			if flags.Changed("user") {
+				user, err := getUserByNameOrID(client, options.User)
+				if err != nil {
+					return err
+				}
+				req.User = user.ID
+			}
+
+			logging.Debugf("call server: update federation %s", federation.ID)
+			if _, err := client.UpdateFederation(req); err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot edit federation: missing privileges for UpdateFederation",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Updated federation %q", federation.Name)
+			return nil
+		},
+	}
+
+	addFederationFlags(cmd, &options)
+	return cmd
+}
+
+func newFederationRemoveCmd(cli *CLI) *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:     "remove NAME",
+		Aliases: []string{"rm"},
+		Short:   "Remove a federation",
+		Long: `Remove a federation. Access keys issued by logging in with the federation
+remain valid until they expire.`,
+		Example: `# Remove a federation
+$ infra federation remove github-deploy`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			federation, err := getFederationByName(client, args[0])
+			if err != nil {
+				if force && errors.Is(err, ErrFederationNotFound) {
+					return nil
+				}
+				return err
+			}
+
+			logging.Debugf("call server: delete federation %s", federation.ID)
+			if err := client.DeleteFederation(federation.ID); err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot remove federation: missing privileges for DeleteFederation",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Removed federation %q", federation.Name)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Exit successfully even if the federation does not exist")
+	return cmd
+}
+
+func getFederationByName(client *api.Client, name string) (*api.Federation, error) {
+	logging.Debugf("call server: list federations named %q", name)
+	federations, err := client.ListFederations(api.ListFederationsRequest{Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	if federations.Count == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrFederationNotFound, name)
+	}
+	return &federations.Items[0], nil
+}
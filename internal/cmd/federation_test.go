@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestFederationCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	federationID := uid.ID(1234)
+	user := api.User{ID: uid.ID(5678), Name: "deploy@example.com"}
+	existing := api.Federation{
+		ID:         federationID,
+		Name:       "github-deploy",
+		Issuer:     "https://token.actions.githubusercontent.com",
+		Audience:   "https://infra.example.com",
+		Conditions: map[string]string{"repository": "example/app", "ref": "refs/heads/main"},
+		User:       user.ID,
+		TTL:        api.Duration(time.Hour),
+	}
+
+	type requests struct {
+		create chan api.CreateFederationRequest
+		update chan api.UpdateFederationRequest
+	}
+
+	setup := func(t *testing.T) requests {
+		reqs := requests{
+			create: make(chan api.CreateFederationRequest, 1),
+			update: make(chan api.UpdateFederationRequest, 1),
+		}
+
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			switch {
+			case requestMatches(req, http.MethodGet, "/api/users"):
+				users := api.ListResponse[api.User]{Count: 1, Items: []api.User{user}}
+				if req.URL.Query().Get("name") != user.Name {
+					users = api.ListResponse[api.User]{}
+				}
+				err := json.NewEncoder(resp).Encode(users)
+				assert.Check(t, err)
+			case requestMatches(req, http.MethodGet, "/api/users/"+user.ID.String()):
+				err := json.NewEncoder(resp).Encode(user)
+				assert.Check(t, err)
+			case requestMatches(req, http.MethodPost, "/api/federations"):
+				var createReq api.CreateFederationRequest
+				err := json.NewDecoder(req.Body).Decode(&createReq)
+				assert.Check(t, err)
+				reqs.create <- createReq
+
+				resp.WriteHeader(http.StatusCreated)
+				err = json.NewEncoder(resp).Encode(api.Federation{ID: federationID, Name: createReq.Name})
+				assert.Check(t, err)
+			case requestMatches(req, http.MethodGet, "/api/federations"):
+				federations := api.ListResponse[api.Federation]{Count: 1, Items: []api.Federation{existing}}
+				if name := req.URL.Query().Get("name"); name != "" && name != existing.Name {
+					federations = api.ListResponse[api.Federation]{}
+				}
+				err := json.NewEncoder(resp).Encode(federations)
+				assert.Check(t, err)
+			case requestMatches(req, http.MethodPut, "/api/federations/"+federationID.String()):
+				var updateReq api.UpdateFederationRequest
+				err := json.NewDecoder(req.Body).Decode(&updateReq)
+				assert.Check(t, err)
+				reqs.update <- updateReq
+
+				err = json.NewEncoder(resp).Encode(existing)
+				assert.Check(t, err)
+			case requestMatches(req, http.MethodDelete, "/api/federations/"+federationID.String()):
+				resp.WriteHeader(http.StatusNoContent)
+			default:
+				resp.WriteHeader(http.StatusNotFound)
+			}
+		}
+
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		err := writeConfig(&cfg)
+		assert.NilError(t, err)
+		return reqs
+	}
+
+	t.Run("add", func(t *testing.T) {
+		reqs := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "federation", "add", "github-deploy",
+			"--issuer", "https://token.actions.githubusercontent.com",
+			"--audience", "https://infra.example.com",
+			"--condition", "repository=example/app",
+			"--condition", "ref=refs/heads/main",
+			"--user", "deploy@example.com",
+			"--ttl", "15m")
+		assert.NilError(t, err)
+
+		createReq := <-reqs.create
+		expected := api.CreateFederationRequest{
+			Name:       "github-deploy",
+			Issuer:     "https://token.actions.githubusercontent.com",
+			Audience:   "https://infra.example.com",
+			Conditions: map[string]string{"repository": "example/app", "ref": "refs/heads/main"},
+			User:       user.ID,
+			TTL:        api.Duration(15 * time.Minute),
+		}
+		assert.DeepEqual(t, createReq, expected)
+		assert.Equal(t, bufs.Stdout.String(), "Added federation \"github-deploy\"\n")
+	})
+
+	t.Run("add without issuer", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "federation", "add", "github-deploy", "--audience", "infra", "--user", "deploy@example.com")
+		assert.ErrorContains(t, err, "Issuer is required")
+	})
+
+	t.Run("add without a subject condition", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "federation", "add", "github-deploy", "--issuer", "https://token.example.com",
+			"--audience", "infra", "--user", "deploy@example.com", "--condition", "ref=refs/heads/main")
+		assert.ErrorContains(t, err, "A sub or repository condition is required")
+	})
+
+	t.Run("add with unknown user", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "federation", "add", "github-deploy",
+			"--issuer", "https://token.actions.githubusercontent.com",
+			"--audience", "https://infra.example.com",
+			"--condition", "repository=example/app",
+			"--user", "unknown@example.com")
+		assert.ErrorContains(t, err, "user not found")
+	})
+
+	t.Run("list", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "federation", "list")
+		assert.NilError(t, err)
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), "github-deploy"))
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), "deploy@example.com"))
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), "ref=refs/heads/main, repository=example/app"))
+	})
+
+	t.Run("edit replaces conditions", func(t *testing.T) {
+		reqs := setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "federation", "edit", "github-deploy", "--condition", "repository=example/app")
+		assert.NilError(t, err)
+
+		updateReq := <-reqs.update
+		expected := api.UpdateFederationRequest{
+			Issuer:     existing.Issuer,
+			Audience:   existing.Audience,
+			Conditions: map[string]string{"repository": "example/app"},
+			User:       user.ID,
+			TTL:        existing.TTL,
+		}
+		assert.DeepEqual(t, updateReq, expected)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "federation", "remove", "github-deploy")
+		assert.NilError(t, err)
+		assert.Equal(t, bufs.Stdout.String(), "Removed federation \"github-deploy\"\n")
+	})
+
+	t.Run("remove unknown federation", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "federation", "remove", "unknown")
+		assert.ErrorContains(t, err, `federation not found: "unknown"`)
+
+		err = Run(ctx, "federation", "remove", "unknown", "--force")
+		assert.NilError(t, err)
+	})
+}
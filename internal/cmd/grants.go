@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/ssoroka/slice"
@@ -21,6 +22,7 @@ type grantsCmdOptions struct {
 	Role      string
 	Force     bool
 	Inherited bool
+	Duration  time.Duration
 }
 
 func newGrantsCmd(cli *CLI) *cobra.Command {
@@ -328,6 +330,12 @@ $ infra grants add johndoe@example.com staging --role viewer
 
 # Assign a user a role within Infra
 $ infra grants add johndoe@example.com infra --role admin
+
+# Grant temporary access that expires after 4 hours
+$ infra grants add johndoe@example.com production --role edit --duration 4h
+
+# Grant access to every namespace of a destination
+$ infra grants add johndoe@example.com 'production.*' --role view
 `,
 		Args: ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -344,6 +352,7 @@ $ infra grants add johndoe@example.com infra --role admin
 	cmd.Flags().BoolVarP(&isGroup, "group", "g", false, "When set, creates a grant for a group instead of a user")
 	cmd.Flags().StringVar(&options.Role, "role", models.BasePermissionConnect, "Type of access that the user or group will be given")
 	cmd.Flags().BoolVar(&options.Force, "force", false, "Create grant even if requested user, destination, or role are unknown")
+	cmd.Flags().DurationVar(&options.Duration, "duration", 0, "Remove the grant after this duration. The grant does not expire when unset")
 	return cmd
 }
 
@@ -410,6 +419,9 @@ func addGrant(cli *CLI, cmdOptions grantsCmdOptions) error {
 		Privilege: cmdOptions.Role,
 		Resource:  cmdOptions.Resource,
 	}
+	if cmdOptions.Duration > 0 {
+		createGrantReq.Expires = api.Time(time.Now().Add(cmdOptions.Duration))
+	}
 	logging.Debugf("call server: create grant %#v", createGrantReq)
 	response, err := client.CreateGrant(createGrantReq)
 	if err != nil {
@@ -423,6 +435,9 @@ func addGrant(cli *CLI, cmdOptions grantsCmdOptions) error {
 	}
 	if response.WasCreated {
 		cli.Output("Created grant to %q for %q", cmdOptions.Resource, cmdOptions.UserName+cmdOptions.GroupName)
+		if cmdOptions.Duration > 0 {
+			cli.Output("This grant will expire in %s", ExactDuration(cmdOptions.Duration))
+		}
 	} else {
 		cli.Output("%q grant to %q already exists for %q. Nothing changed", cmdOptions.Role, cmdOptions.Resource, cmdOptions.UserName+cmdOptions.GroupName)
 	}
@@ -464,8 +479,14 @@ func checkUserGroup(client *api.Client, user, group string) (userID uid.ID, grou
 
 // checkResourcesPrivileges checks if the requested destination (e.g. cluster), optional
 // resource (e.g. namespace), and role exist. destination "infra" and role "connect" are
-// reserved values and will always pass checks
+// reserved values and will always pass checks. Resources with a wildcard are not checked,
+// because they may match destinations that are connected later. The name of an OIDC
+// client is accepted in place of a destination.
 func checkResourcesPrivileges(client *api.Client, resource, privilege string) error {
+	if api.IsResourcePattern(resource) {
+		return nil
+	}
+
 	parts := strings.SplitN(resource, ".", 2)
 	destination := parts[0]
 	subresource := ""
@@ -485,6 +506,17 @@ func checkResourcesPrivileges(client *api.Client, resource, privilege string) er
 		}
 
 		if destinations.Count == 0 {
+			// grants for an OIDC client give access to log in to the client
+			if subresource == "" {
+				logging.Debugf("call server: list OIDC clients named %q", destination)
+				clients, err := client.ListOIDCClients(api.ListOIDCClientsRequest{Name: destination})
+				if err != nil && api.ErrorStatusCode(err) != 403 {
+					return err
+				}
+				if clients != nil && clients.Count > 0 {
+					return nil
+				}
+			}
 			return Error{Message: fmt.Sprintf("Destination %q not connected; to ignore, run with '--force'", destination)}
 		}
 
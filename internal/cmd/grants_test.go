@@ -67,6 +67,16 @@ func TestGrantsAddCmd(t *testing.T) {
 				return
 			}
 
+			if requestMatches(req, http.MethodGet, "/api/oidc-clients") {
+				resp.WriteHeader(http.StatusOK)
+				if query.Get("name") == "the-app" {
+					writeResponse(t, resp, api.ListResponse[api.OIDCClient]{Count: 1, Items: []api.OIDCClient{{ID: 6000, Name: "the-app"}}})
+					return
+				}
+				writeResponse(t, resp, &api.ListResponse[api.OIDCClient]{})
+				return
+			}
+
 			if !requestMatches(req, http.MethodPost, "/api/grants") {
 				resp.WriteHeader(http.StatusInternalServerError)
 				return
@@ -131,6 +141,20 @@ func TestGrantsAddCmd(t *testing.T) {
 		}
 		assert.DeepEqual(t, createReq, expected)
 	})
+	t.Run("add default role for OIDC client", func(t *testing.T) {
+		ch := setup(t)
+		ctx := context.Background()
+		err := Run(ctx, "grants", "add", "existing@example.com", "the-app")
+		assert.NilError(t, err)
+
+		createReq := <-ch
+		expected := api.CreateGrantRequest{
+			User:      3000,
+			Privilege: "connect",
+			Resource:  "the-app",
+		}
+		assert.DeepEqual(t, createReq, expected)
+	})
 	t.Run("add role to existing group", func(t *testing.T) {
 		ch := setup(t)
 		ctx := context.Background()
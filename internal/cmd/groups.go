@@ -62,9 +62,11 @@ func newGroupsCmd(cli *CLI) *cobra.Command {
 
 	cmd.AddCommand(newGroupsAddCmd(cli))
 	cmd.AddCommand(newGroupsAddUserCmd(cli))
+	cmd.AddCommand(newGroupsAddGroupCmd(cli))
 	cmd.AddCommand(newGroupsListCmd(cli))
 	cmd.AddCommand(newGroupsRemoveCmd(cli))
 	cmd.AddCommand(newGroupsRemoveUserCmd(cli))
+	cmd.AddCommand(newGroupsRemoveGroupCmd(cli))
 
 	return cmd
 }
@@ -76,7 +78,9 @@ func newGroupsListCmd(cli *CLI) *cobra.Command {
 		Use:     "list",
 		Aliases: []string{"ls"},
 		Short:   "List groups",
-		Args:    NoArgs,
+		Long: `List groups. Groups that are members of other groups are listed,
+indented, below each of their parent groups.`,
+		Args: NoArgs,
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			client, err := defaultAPIClient()
 			if err != nil {
@@ -96,7 +100,8 @@ func newGroupsListCmd(cli *CLI) *cobra.Command {
 				return err
 			}
 
-			for _, group := range groups {
+			for _, nested := range nestGroups(groups) {
+				group := nested.group
 				var users []api.User
 				if noTruncate {
 					users, err = listAll(client.ListUsers, api.ListUsersRequest{Group: group.ID})
@@ -124,7 +129,7 @@ func newGroupsListCmd(cli *CLI) *cobra.Command {
 				}
 
 				rows = append(rows, row{
-					Name:      group.Name,
+					Name:      strings.Repeat("  ", nested.depth) + group.Name,
 					Users:     strings.Join(userNames, ", "),
 					UserCount: group.TotalUsers,
 				})
@@ -144,6 +149,57 @@ func newGroupsListCmd(cli *CLI) *cobra.Command {
 	return cmd
 }
 
+type nestedGroup struct {
+	group api.Group
+	depth int
+}
+
+// nestGroups orders groups so that each group is followed by the groups that
+// are its members. A group with more than one parent is included once for
+// each parent.
+func nestGroups(groups []api.Group) []nestedGroup {
+	byID := make(map[uid.ID]bool, len(groups))
+	for _, group := range groups {
+		byID[group.ID] = true
+	}
+
+	children := map[uid.ID][]api.Group{}
+	var roots []api.Group
+	for _, group := range groups {
+		isRoot := true
+		for _, parent := range group.Parents {
+			if byID[parent] {
+				children[parent] = append(children[parent], group)
+				isRoot = false
+			}
+		}
+		if isRoot {
+			roots = append(roots, group)
+		}
+	}
+
+	var result []nestedGroup
+	visiting := map[uid.ID]bool{}
+	var visit func(group api.Group, depth int)
+	visit = func(group api.Group, depth int) {
+		// the server prevents cycles, but don't loop forever if there is one
+		if visiting[group.ID] {
+			return
+		}
+		visiting[group.ID] = true
+		result = append(result, nestedGroup{group: group, depth: depth})
+		for _, child := range children[group.ID] {
+			visit(child, depth+1)
+		}
+		visiting[group.ID] = false
+	}
+
+	for _, root := range roots {
+		visit(root, 0)
+	}
+	return result
+}
+
 func newGroupsAddCmd(cli *CLI) *cobra.Command {
 	return &cobra.Command{
 		Use:   "add GROUP",
@@ -320,3 +376,101 @@ $ infra groups removeuser johndoe@example.com Engineering
 
 	return cmd
 }
+
+func newGroupsAddGroupCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "addgroup CHILD PARENT",
+		Short: "Add a group to another group",
+		Long: `Add the group CHILD as a member of the group PARENT. Members of CHILD
+inherit the grants of PARENT.`,
+		Args: ExactArgs(2),
+		Example: `# Add the sre group to the platform group
+$ infra groups addgroup sre platform
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			child, parent, err := getNestedGroups(client, args[0], args[1])
+			if err != nil {
+				if errors.Is(err, ErrGroupNotFound) {
+					return Error{Message: err.Error()}
+				}
+				return err
+			}
+
+			req := &api.UpdateGroupsInGroupRequest{
+				GroupID:       parent.ID,
+				GroupIDsToAdd: []uid.ID{child.ID},
+			}
+			if err := client.UpdateGroupsInGroup(req); err != nil {
+				return err
+			}
+
+			cli.Output("Added group %q to group %q", child.Name, parent.Name)
+			return nil
+		},
+	}
+}
+
+func newGroupsRemoveGroupCmd(cli *CLI) *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:     "removegroup CHILD PARENT",
+		Short:   "Remove a group from another group",
+		Aliases: []string{"rmgroup"},
+		Args:    ExactArgs(2),
+		Example: `# Remove the sre group from the platform group
+$ infra groups removegroup sre platform
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			child, parent, err := getNestedGroups(client, args[0], args[1])
+			if err != nil {
+				if errors.Is(err, ErrGroupNotFound) {
+					if force {
+						return nil
+					}
+					return Error{Message: err.Error()}
+				}
+				return err
+			}
+
+			req := &api.UpdateGroupsInGroupRequest{
+				GroupID:          parent.ID,
+				GroupIDsToRemove: []uid.ID{child.ID},
+			}
+			if err := client.UpdateGroupsInGroup(req); err != nil {
+				return err
+			}
+
+			cli.Output("Removed group %q from group %q", child.Name, parent.Name)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Exit successfully even if either group does not exist")
+
+	return cmd
+}
+
+// getNestedGroups looks up the child and parent groups for the addgroup and
+// removegroup commands.
+func getNestedGroups(client *api.Client, childName, parentName string) (child, parent *api.Group, err error) {
+	child, err = getGroupByNameOrID(client, childName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parent, err = getGroupByNameOrID(client, parentName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return child, parent, nil
+}
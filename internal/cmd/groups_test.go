@@ -12,6 +12,7 @@ import (
 	"gotest.tools/v3/golden"
 
 	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
 )
 
 func TestGroupsAddCmd(t *testing.T) {
@@ -240,6 +241,86 @@ func TestGroupsAddAndRemoveUserCmds(t *testing.T) {
 
 }
 
+func TestGroupsAddAndRemoveGroupCmds(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	groups := map[string]api.Group{
+		"platform": {ID: 100, Name: "platform"},
+		"sre":      {ID: 101, Name: "sre"},
+	}
+
+	setup := func(t *testing.T) *[]api.UpdateGroupsInGroupRequest {
+		requests := &[]api.UpdateGroupsInGroupRequest{}
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			if requestMatches(req, http.MethodGet, "/api/groups") {
+				resp.WriteHeader(http.StatusOK)
+				result := api.ListResponse[api.Group]{Items: []api.Group{}}
+				if group, ok := groups[req.URL.Query().Get("name")]; ok {
+					result = api.ListResponse[api.Group]{Count: 1, Items: []api.Group{group}}
+				}
+				assert.NilError(t, json.NewEncoder(resp).Encode(result))
+				return
+			}
+
+			if !requestMatches(req, http.MethodPatch, "/api/groups/2J/groups") {
+				resp.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			var updateRequest api.UpdateGroupsInGroupRequest
+			assert.NilError(t, json.NewDecoder(req.Body).Decode(&updateRequest))
+			*requests = append(*requests, updateRequest)
+
+			resp.WriteHeader(http.StatusOK)
+			assert.NilError(t, json.NewEncoder(resp).Encode(map[string]string{}))
+		}
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		assert.NilError(t, writeConfig(&cfg))
+		return requests
+	}
+
+	t.Run("add group", func(t *testing.T) {
+		requests := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "groups", "addgroup", "sre", "platform")
+		assert.NilError(t, err)
+		assert.Equal(t, bufs.Stdout.String(), `Added group "sre" to group "platform"`+"\n")
+		assert.DeepEqual(t, *requests, []api.UpdateGroupsInGroupRequest{
+			{GroupIDsToAdd: []uid.ID{101}},
+		})
+	})
+
+	t.Run("remove group", func(t *testing.T) {
+		requests := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "groups", "removegroup", "sre", "platform")
+		assert.NilError(t, err)
+		assert.Equal(t, bufs.Stdout.String(), `Removed group "sre" from group "platform"`+"\n")
+		assert.DeepEqual(t, *requests, []api.UpdateGroupsInGroupRequest{
+			{GroupIDsToRemove: []uid.ID{101}},
+		})
+	})
+
+	t.Run("unknown group", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+		err := Run(ctx, "groups", "addgroup", "sre", "Nonexistent")
+		assert.ErrorContains(t, err, `unknown group "Nonexistent"`)
+	})
+
+	t.Run("remove unknown group with force", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+		err := Run(ctx, "groups", "removegroup", "sre", "Nonexistent", "--force")
+		assert.NilError(t, err)
+	})
+}
+
 var expectedGroupsAddOutput = `Added group "Test"
 `
 
@@ -329,3 +410,39 @@ func TestGroupsListCmd(t *testing.T) {
 		golden.Assert(t, bufs.Stdout.String(), t.Name())
 	})
 }
+
+func TestGroupsListCmd_Nested(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		if !requestMatches(req, http.MethodGet, "/api/groups") {
+			resp.WriteHeader(http.StatusOK)
+			assert.NilError(t, json.NewEncoder(resp).Encode(api.ListResponse[api.User]{}))
+			return
+		}
+
+		resp.WriteHeader(http.StatusOK)
+		err := json.NewEncoder(resp).Encode(&api.ListResponse[api.Group]{Items: []api.Group{
+			{Name: "dba", ID: 102, Parents: []uid.ID{100}},
+			{Name: "oncall", ID: 103, Parents: []uid.ID{101, 104}},
+			{Name: "platform", ID: 100},
+			{Name: "sre", ID: 101, Parents: []uid.ID{100}},
+			{Name: "support", ID: 104},
+		}})
+		assert.NilError(t, err)
+	}
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+	t.Cleanup(srv.Close)
+
+	cfg := newTestClientConfig(srv, api.User{})
+	assert.NilError(t, writeConfig(&cfg))
+
+	ctx, bufs := PatchCLI(context.Background())
+	err := Run(ctx, "groups", "list")
+	assert.NilError(t, err)
+
+	golden.Assert(t, bufs.Stdout.String(), t.Name())
+}
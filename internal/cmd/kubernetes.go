@@ -84,94 +84,16 @@ func writeKubeconfig(user *api.User, destinations []api.Destination, grants []ap
 	}
 
 	keep := make(map[string]bool)
+	keepAuthInfos := make(map[string]bool)
 
 	for _, g := range grants {
-		parts := strings.Split(g.Resource, ".")
-
-		cluster := parts[0]
-
-		var namespace string
-		if len(parts) > 1 {
-			namespace = parts[1]
-		}
-
-		context := "infra:" + cluster
-
-		if namespace != "" {
-			context += ":" + namespace
-		}
-
-		var (
-			url    string
-			ca     []byte
-			exists bool
-		)
-
-		for _, d := range destinations {
-			// eg resource:  "foo.bar"
-			// eg dest name: "foo"
-			if strings.HasPrefix(g.Resource, d.Name) {
-				url = d.Connection.URL
-				ca = []byte(d.Connection.CA)
-				exists = true
-
-				break
-			}
-		}
-
-		if !exists {
-			continue
-		}
-
-		u, err := urlx.Parse(url)
-		if err != nil {
-			return err
-		}
-
-		u.Scheme = "https"
-
-		logging.Debugf("creating kubeconfig for %s", context)
-
-		kubeConfig.Clusters[context] = &clientcmdapi.Cluster{
-			Server:                   u.String(),
-			CertificateAuthorityData: ca,
-		}
-
-		// use existing kubeContext if possible which may contain
-		// user-defined overrides. preserve them if possible
-		kubeContext, ok := kubeConfig.Contexts[context]
-		if !ok {
-			kubeContext = &clientcmdapi.Context{
-				Cluster:   context,
-				AuthInfo:  user.Name,
-				Namespace: namespace,
-			}
-		}
-
-		if namespace != "" {
-			// force the namespace if defined by Infra
-			if kubeContext.Namespace != namespace {
-				kubeContext.Namespace = namespace
+		for _, r := range grantDestinationResources(g.Resource, destinations) {
+			if err := addKubeconfigContext(&kubeConfig, r); err != nil {
+				return err
 			}
+			keep[r.context()] = true
+			keepAuthInfos[r.authInfo()] = true
 		}
-
-		kubeConfig.Contexts[context] = kubeContext
-
-		executable, err := os.Executable()
-		if err != nil {
-			return err
-		}
-
-		kubeConfig.AuthInfos[user.Name] = &clientcmdapi.AuthInfo{
-			Exec: &clientcmdapi.ExecConfig{
-				Command:         executable,
-				Args:            []string{"tokens", "add"},
-				APIVersion:      "client.authentication.k8s.io/v1beta1",
-				InteractiveMode: clientcmdapi.IfAvailableExecInteractiveMode,
-			},
-		}
-
-		keep[context] = true
 	}
 
 	// cleanup others
@@ -189,7 +111,16 @@ func writeKubeconfig(user *api.User, destinations []api.Destination, grants []ap
 		if _, ok := keep[c]; !ok {
 			delete(kubeConfig.Clusters, c)
 			delete(kubeConfig.Contexts, c)
-			delete(kubeConfig.AuthInfos, c)
+		}
+	}
+
+	for name, authInfo := range kubeConfig.AuthInfos {
+		switch {
+		case strings.HasPrefix(name, "infra:") && !keepAuthInfos[name]:
+			delete(kubeConfig.AuthInfos, name)
+		case name == user.Name && isLegacyInfraAuthInfo(authInfo):
+			// older versions used one user for all destinations
+			delete(kubeConfig.AuthInfos, name)
 		}
 	}
 
@@ -198,6 +129,16 @@ func writeKubeconfig(user *api.User, destinations []api.Destination, grants []ap
 	return safelyWriteConfigToFile(kubeConfig, configFile)
 }
 
+// isLegacyInfraAuthInfo returns true if authInfo creates tokens with 'infra tokens add'
+// without a destination.
+func isLegacyInfraAuthInfo(authInfo *clientcmdapi.AuthInfo) bool {
+	if authInfo == nil || authInfo.Exec == nil {
+		return false
+	}
+	args := authInfo.Exec.Args
+	return len(args) == 2 && args[0] == "tokens" && args[1] == "add"
+}
+
 // safelyWriteConfigToFile creates a temp file, then overwrites the target
 func safelyWriteConfigToFile(kubeConfig clientcmdapi.Config, fileToWrite string) error {
 	// get the directory of the file we're writing to avoid cross-filesystem moves
@@ -270,3 +211,120 @@ func clearKubeconfig() error {
 	}
 	return nil
 }
+
+// destinationResource is a destination, or a namespace of a destination, that
+// a grant applies to.
+type destinationResource struct {
+	Destination api.Destination
+	Namespace   string
+}
+
+func (r destinationResource) context() string {
+	if r.Namespace == "" {
+		return "infra:" + r.Destination.Name
+	}
+	return "infra:" + r.Destination.Name + ":" + r.Namespace
+}
+
+// authInfo is the name of the kubeconfig user for the destination.
+func (r destinationResource) authInfo() string {
+	return "infra:" + r.Destination.Name
+}
+
+func (r destinationResource) String() string {
+	if r.Namespace == "" {
+		return r.Destination.Name
+	}
+	return r.Destination.Name + "." + r.Namespace
+}
+
+// grantDestinationResources returns the destinations and namespaces that
+// match the resource of a grant. A resource with a wildcard is expanded to all
+// the matching destinations, and the matching namespaces of those
+// destinations.
+func grantDestinationResources(resource string, destinations []api.Destination) []destinationResource {
+	parts := strings.SplitN(resource, ".", 2)
+
+	var result []destinationResource
+	for _, d := range destinations {
+		if !api.MatchResource(parts[0], d.Name) {
+			continue
+		}
+
+		switch {
+		case len(parts) == 1:
+			result = append(result, destinationResource{Destination: d})
+		case !api.IsResourcePattern(parts[1]):
+			result = append(result, destinationResource{Destination: d, Namespace: parts[1]})
+		default:
+			for _, namespace := range d.Resources {
+				if api.MatchResource(parts[1], namespace) {
+					result = append(result, destinationResource{Destination: d, Namespace: namespace})
+				}
+			}
+		}
+	}
+	return result
+}
+
+// addKubeconfigContext adds or updates the cluster, context, and user in
+// kubeConfig for the destination resource.
+func addKubeconfigContext(kubeConfig *clientcmdapi.Config, r destinationResource) error {
+	context := r.context()
+	namespace := r.Namespace
+	url := r.Destination.Connection.URL
+	ca := []byte(r.Destination.Connection.CA)
+
+	u, err := urlx.Parse(url)
+	if err != nil {
+		return err
+	}
+
+	u.Scheme = "https"
+
+	logging.Debugf("creating kubeconfig for %s", context)
+
+	kubeConfig.Clusters[context] = &clientcmdapi.Cluster{
+		Server:                   u.String(),
+		CertificateAuthorityData: ca,
+	}
+
+	// use existing kubeContext if possible which may contain
+	// user-defined overrides. preserve them if possible
+	kubeContext, ok := kubeConfig.Contexts[context]
+	if !ok {
+		kubeContext = &clientcmdapi.Context{
+			Cluster:   context,
+			Namespace: namespace,
+		}
+	}
+
+	// each destination has its own user, because tokens are only valid for
+	// the destination they were created for
+	kubeContext.AuthInfo = r.authInfo()
+
+	if namespace != "" {
+		// force the namespace if defined by Infra
+		if kubeContext.Namespace != namespace {
+			kubeContext.Namespace = namespace
+		}
+	}
+
+	kubeConfig.Contexts[context] = kubeContext
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	kubeConfig.AuthInfos[r.authInfo()] = &clientcmdapi.AuthInfo{
+		Exec: &clientcmdapi.ExecConfig{
+			Command:         executable,
+			Args:            []string{"tokens", "add", r.Destination.Name},
+			APIVersion:      "client.authentication.k8s.io/v1beta1",
+			InteractiveMode: clientcmdapi.IfAvailableExecInteractiveMode,
+		},
+	}
+
+	return nil
+}
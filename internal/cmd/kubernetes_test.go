@@ -50,12 +50,12 @@ func TestWriteKubeconfig(t *testing.T) {
 		},
 		Contexts: map[string]*clientcmdapi.Context{
 			"infra:cluster": {
-				AuthInfo: "user",
+				AuthInfo: "infra:cluster",
 				Cluster:  "infra:cluster",
 			},
 		},
 		AuthInfos: map[string]*clientcmdapi.AuthInfo{
-			"user": {},
+			"infra:cluster": {},
 		},
 	}
 
@@ -68,6 +68,8 @@ func TestWriteKubeconfig(t *testing.T) {
 	actual, err := clientConfig().RawConfig()
 	assert.NilError(t, err)
 
+	assert.DeepEqual(t, actual.AuthInfos["infra:cluster"].Exec.Args, []string{"tokens", "add", "cluster"})
+
 	assert.DeepEqual(t, expected, actual,
 		cmpopts.EquateEmpty(),
 		cmpopts.IgnoreFields(clientcmdapi.Cluster{}, "LocationOfOrigin"),
@@ -77,6 +79,90 @@ func TestWriteKubeconfig(t *testing.T) {
 	)
 }
 
+func TestWriteKubeconfig_WildcardGrants(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("KUBECONFIG", filepath.Join(home, "kubeconfig"))
+
+	user := api.User{Name: "user"}
+	destinations := []api.Destination{
+		{
+			Name:       "production",
+			Connection: api.DestinationConnection{URL: "production.example.com", CA: destinationCA},
+			Resources:  []string{"default", "monitoring", "team-a"},
+		},
+		{
+			Name:       "staging-eu",
+			Connection: api.DestinationConnection{URL: "staging-eu.example.com", CA: destinationCA},
+			Resources:  []string{"default", "monitoring"},
+		},
+	}
+	grants := []api.Grant{
+		{Resource: "production.team-*"},
+		{Resource: "*.monitoring"},
+		{Resource: "staging-*"},
+	}
+
+	err := writeKubeconfig(&user, destinations, grants)
+	assert.NilError(t, err)
+
+	actual, err := clientConfig().RawConfig()
+	assert.NilError(t, err)
+
+	contexts := make([]string, 0, len(actual.Contexts))
+	for name := range actual.Contexts {
+		contexts = append(contexts, name)
+	}
+	expected := []string{
+		"infra:production:monitoring",
+		"infra:production:team-a",
+		"infra:staging-eu",
+		"infra:staging-eu:monitoring",
+	}
+	assert.DeepEqual(t, contexts, expected, cmpopts.SortSlices(func(a, b string) bool { return a < b }))
+	assert.Equal(t, actual.Contexts["infra:production:team-a"].Namespace, "team-a")
+	assert.Equal(t, actual.Clusters["infra:staging-eu:monitoring"].Server, "https://staging-eu.example.com")
+}
+
+func TestWriteKubeconfig_RemovesLegacyUser(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	kubeconfig := filepath.Join(home, "kubeconfig")
+	t.Setenv("KUBECONFIG", kubeconfig)
+
+	existing := clientcmdapi.Config{
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"user": {
+				Exec: &clientcmdapi.ExecConfig{Command: "infra", Args: []string{"tokens", "add"}},
+			},
+			"someone-else": {Token: "abcd"},
+		},
+	}
+	err := clientcmd.WriteToFile(existing, kubeconfig)
+	assert.NilError(t, err)
+
+	user := api.User{Name: "user"}
+	destinations := []api.Destination{
+		{Name: "cluster", Connection: api.DestinationConnection{URL: "cluster.example.com", CA: destinationCA}},
+	}
+	grants := []api.Grant{{Resource: "cluster"}}
+
+	err = writeKubeconfig(&user, destinations, grants)
+	assert.NilError(t, err)
+
+	actual, err := clientConfig().RawConfig()
+	assert.NilError(t, err)
+
+	names := make([]string, 0, len(actual.AuthInfos))
+	for name := range actual.AuthInfos {
+		names = append(names, name)
+	}
+	assert.DeepEqual(t, names, []string{"infra:cluster", "someone-else"}, cmpopts.SortSlices(func(a, b string) bool { return a < b }))
+}
+
 func TestWriteKubeconfig_UserNamespaceOverride(t *testing.T) {
 	home := t.TempDir()
 	t.Setenv("HOME", home)
@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/uid"
 )
 
 func newListCmd(cli *CLI) *cobra.Command {
@@ -42,12 +45,15 @@ func list(cli *CLI) error {
 
 	gs := make(map[string]map[string]struct{})
 	for _, g := range grants {
-		// aggregate privileges
-		if gs[g.Resource] == nil {
-			gs[g.Resource] = make(map[string]struct{})
-		}
+		// aggregate privileges, and expand grants with a wildcard resource
+		for _, r := range grantDestinationResources(g.Resource, destinations) {
+			resource := r.String()
+			if gs[resource] == nil {
+				gs[resource] = make(map[string]struct{})
+			}
 
-		gs[g.Resource][g.Privilege] = struct{}{}
+			gs[resource][g.Privilege] = struct{}{}
+		}
 	}
 
 	type row struct {
@@ -59,22 +65,6 @@ func list(cli *CLI) error {
 
 	keys := make([]string, 0, len(gs))
 	for k := range gs {
-		if strings.HasPrefix(k, "infra") {
-			continue
-		}
-
-		var exists bool
-		for _, d := range destinations {
-			if strings.HasPrefix(k, d.Name) {
-				exists = true
-				break
-			}
-		}
-
-		if !exists {
-			continue
-		}
-
 		keys = append(keys, k)
 	}
 
@@ -104,9 +94,53 @@ func list(cli *CLI) error {
 		cli.Output("You have not been granted access to any active destinations")
 	}
 
+	listAccessRequests(cli, client, user.ID)
+
 	return writeKubeconfig(user, destinations, grants)
 }
 
+// recentAccessRequestWindow is how long an approved or denied access request
+// continues to be shown by the list command.
+const recentAccessRequestWindow = 24 * time.Hour
+
+// listAccessRequests prints the access requests of the user that are pending,
+// or that were recently approved or denied. Errors are logged instead of
+// returned, so that list still works with servers that do not support access
+// requests.
+func listAccessRequests(cli *CLI, client *api.Client, userID uid.ID) {
+	reqs, err := listAll(client.ListAccessRequests, api.ListAccessRequestsRequest{User: userID})
+	if err != nil {
+		logging.Debugf("list access requests: %v", err)
+		return
+	}
+
+	type row struct {
+		ID       string `header:"REQUEST ID"`
+		Resource string `header:"RESOURCE"`
+		Role     string `header:"ROLE"`
+		State    string `header:"STATE"`
+	}
+
+	var rows []row
+	for _, r := range reqs {
+		if r.State != api.AccessRequestStatePending && time.Since(r.Updated.Time()) > recentAccessRequestWindow {
+			continue
+		}
+
+		rows = append(rows, row{
+			ID:       r.ID.String(),
+			Resource: r.Resource,
+			Role:     r.Privilege,
+			State:    r.State,
+		})
+	}
+
+	if len(rows) > 0 {
+		cli.Output("")
+		printTable(rows, cli.Stdout)
+	}
+}
+
 func getUserDestinationGrants(client *api.Client) (*api.User, []api.Destination, []api.Grant, error) {
 	config, err := currentHostConfig()
 	if err != nil {
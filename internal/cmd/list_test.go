@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
 	"gotest.tools/v3/golden"
 
 	"github.com/infrahq/infra/api"
@@ -116,6 +117,50 @@ func TestListCmd(t *testing.T) {
 		assert.NilError(t, err)
 		golden.Assert(t, bufs.Stdout.String(), t.Name())
 	})
+
+	t.Run("with access requests", func(t *testing.T) {
+		user := userMap["manygrants@example.com"]
+		userClient := apiClient(srv.Addrs.HTTPS.String(), "0000000003.notadminsecretnotadmin03", httpTransport)
+
+		pending, err := userClient.CreateAccessRequest(&api.CreateAccessRequestRequest{
+			Privilege: "explorer",
+			Resource:  "moon",
+		})
+		assert.NilError(t, err)
+
+		denied, err := userClient.CreateAccessRequest(&api.CreateAccessRequestRequest{
+			Privilege: "admin",
+			Resource:  "space",
+		})
+		assert.NilError(t, err)
+		_, err = c.DenyAccessRequest(denied.ID)
+		assert.NilError(t, err)
+
+		err = writeConfig(&ClientConfig{
+			ClientConfigVersion: clientConfigVersion,
+			Hosts: []ClientHostConfig{
+				{
+					UserID:        user.ID,
+					Name:          user.Name,
+					Host:          srv.Addrs.HTTPS.String(),
+					AccessKey:     "0000000003.notadminsecretnotadmin03",
+					SkipTLSVerify: true,
+					Expires:       api.Time(time.Now().Add(5 * time.Second)),
+					Current:       true,
+				},
+			},
+		})
+		assert.NilError(t, err)
+
+		ctx, bufs := PatchCLI(ctx)
+		err = Run(ctx, "list")
+		assert.NilError(t, err)
+
+		out := bufs.Stdout.String()
+		assert.Assert(t, is.Contains(out, "REQUEST ID"))
+		assert.Assert(t, is.Regexp(pending.ID.String()+`\s+moon\s+explorer\s+pending`, out))
+		assert.Assert(t, is.Regexp(denied.ID.String()+`\s+space\s+admin\s+denied`, out))
+	})
 }
 
 func usersToMap(users []api.User) map[string]api.User {
@@ -36,6 +36,8 @@ type loginCmdOptions struct {
 	TrustedFingerprint string
 	NonInteractive     bool
 	NoAgent            bool
+	Device             bool
+	FederatedToken     string
 }
 
 type loginMethod int8
@@ -43,6 +45,8 @@ type loginMethod int8
 const (
 	localLogin loginMethod = iota
 	oidcLogin
+	ldapLogin
+	samlLogin
 )
 
 const cliLoginRedirectURL = "http://localhost:8301"
@@ -63,10 +67,16 @@ $ infra login infraexampleserver.com
 # Login with a specific identity provider
 $ infra login --provider okta
 
+# Login from a machine without a browser, like a jump host or a container
+$ infra login --device
+
 # Login with an access key
 $ export INFRA_ACCESS_KEY=1M4CWy9wF5.fAKeKEy5sMLH9ZZzAur0ZIjy
 $ infra login
 
+# Login from a CI job with an OIDC token from the CI platform
+$ infra login infraexampleserver.com --federated-token "$CI_JOB_JWT" --non-interactive
+
 # Login with pre-set provider and server
 $ export INFRA_SERVER=example.infrahq.com
 $ export INFRA_PROVIDER=google
@@ -92,6 +102,8 @@ $ infra login`,
 	cmd.Flags().Var((*types.StringOrFile)(&options.TrustedCertificate), "tls-trusted-cert", "TLS certificate or CA used by the server")
 	cmd.Flags().StringVar(&options.TrustedFingerprint, "tls-trusted-fingerprint", "", "SHA256 fingerprint of the server TLS certificate")
 	cmd.Flags().BoolVar(&options.NoAgent, "no-agent", false, "Skip starting the Infra agent in the background")
+	cmd.Flags().BoolVar(&options.Device, "device", false, "Login by approving a code in a browser on any device")
+	cmd.Flags().Var((*types.StringOrFile)(&options.FederatedToken), "federated-token", "Login with a JWT from an issuer configured with 'infra federation add'")
 	addNonInteractiveFlag(cmd.Flags(), &options.NonInteractive)
 	return cmd
 }
@@ -137,8 +149,12 @@ func login(cli *CLI, options loginCmdOptions) error {
 	}
 
 	switch {
+	case options.Device:
+		return loginWithDeviceFlow(cli, lc, options.NoAgent)
 	case options.AccessKey != "":
 		loginReq.AccessKey = options.AccessKey
+	case options.FederatedToken != "":
+		loginReq.Federation = &api.LoginRequestFederation{Token: strings.TrimSpace(options.FederatedToken)}
 	case options.Provider != "":
 		if options.NonInteractive {
 			return Error{Message: "Non-interactive login only supports access keys, set the INFRA_ACCESS_KEY environment variable and try again"}
@@ -149,11 +165,26 @@ func login(cli *CLI, options loginCmdOptions) error {
 			if err != nil {
 				return err
 			}
-		} else {
-			loginReq.OIDC, err = loginToProviderN(lc.APIClient, options.Provider)
+			break
+		}
+
+		provider, err := GetProviderByName(lc.APIClient, options.Provider)
+		if err != nil {
+			return err
+		}
+		if provider.Kind == "ldap" {
+			loginReq.LDAP, err = promptLDAPLogin(cli, provider)
 			if err != nil {
 				return err
 			}
+			break
+		}
+		if provider.Kind == "saml" {
+			return errSAMLLogin(provider)
+		}
+		loginReq.OIDC, err = loginToProvider(provider)
+		if err != nil {
+			return err
 		}
 	default:
 		if options.NonInteractive {
@@ -175,6 +206,13 @@ func login(cli *CLI, options loginCmdOptions) error {
 			if err != nil {
 				return err
 			}
+		case ldapLogin:
+			loginReq.LDAP, err = promptLDAPLogin(cli, provider)
+			if err != nil {
+				return err
+			}
+		case samlLogin:
+			return errSAMLLogin(provider)
 		}
 	}
 	return loginToInfra(cli, lc, loginReq, options.NoAgent)
@@ -192,13 +230,23 @@ func equalHosts(x, y string) bool {
 
 func loginToInfra(cli *CLI, lc loginClient, loginReq *api.LoginRequest, noAgent bool) error {
 	loginRes, err := lc.APIClient.Login(loginReq)
+	if err == nil && loginRes.MFARequired {
+		// the password was accepted, login again with a code from the authenticator app
+		loginReq.PasswordCredentials.MFACode, err = promptMFACode(cli)
+		if err != nil {
+			return err
+		}
+		loginRes, err = lc.APIClient.Login(loginReq)
+	}
 	if err != nil {
 		logging.Debugf("login: %s", err)
 		if api.ErrorStatusCode(err) == http.StatusUnauthorized || api.ErrorStatusCode(err) == http.StatusNotFound {
 			switch {
 			case loginReq.AccessKey != "":
 				return &LoginError{Message: "your access key may be invalid"}
-			case loginReq.PasswordCredentials != nil:
+			case loginReq.PasswordCredentials != nil && loginReq.PasswordCredentials.MFACode != "":
+				return &LoginError{Message: "your verification code may be invalid"}
+			case loginReq.PasswordCredentials != nil, loginReq.LDAP != nil:
 				return &LoginError{Message: "your username or password may be invalid"}
 			case loginReq.OIDC != nil:
 				return &LoginError{Message: "please contact an administrator and check identity provider configurations"}
@@ -207,6 +255,12 @@ func loginToInfra(cli *CLI, lc loginClient, loginReq *api.LoginRequest, noAgent
 
 		return err
 	}
+
+	return finishLogin(cli, lc, loginReq, loginRes, noAgent)
+}
+
+// finishLogin saves the session from a successful login, and starts the agent
+func finishLogin(cli *CLI, lc loginClient, loginReq *api.LoginRequest, loginRes *api.LoginResponse, noAgent bool) error {
 	// Update the API client with the new access key from login
 	lc.APIClient.AccessKey = loginRes.AccessKey
 
@@ -230,6 +284,13 @@ func loginToInfra(cli *CLI, lc loginClient, loginReq *api.LoginRequest, noAgent
 		fmt.Fprintf(os.Stderr, "  Updated password\n")
 	}
 
+	if loginRes.MFAEnrollmentRequired {
+		fmt.Fprintf(cli.Stderr, "  Your organization requires multi-factor authentication. Please enable it to continue.\n")
+		if err := enableMFA(cli, lc.APIClient); err != nil {
+			return err
+		}
+	}
+
 	if err := updateInfraConfig(lc, loginReq, loginRes); err != nil {
 		return err
 	}
@@ -256,6 +317,48 @@ func loginToInfra(cli *CLI, lc loginClient, loginReq *api.LoginRequest, noAgent
 	return nil
 }
 
+// loginWithDeviceFlow starts a device flow login, and waits for the user to
+// approve it from a browser, which does not need to run on this machine.
+func loginWithDeviceFlow(cli *CLI, lc loginClient, noAgent bool) error {
+	logging.Debugf("call server: start device flow")
+	started, err := lc.APIClient.StartDeviceFlow()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cli.Stderr, "  Visit %s and enter the code %s to login\n",
+		started.VerificationURI, termenv.String(started.UserCode).Bold().String())
+
+	interval := time.Duration(started.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	expired := time.After(time.Duration(started.ExpiresIn) * time.Second)
+
+	for {
+		select {
+		case <-expired:
+			return Error{Message: "The login code has expired, run 'infra login --device' to try again"}
+		case <-time.After(interval):
+		}
+
+		logging.Debugf("call server: poll device flow")
+		polled, err := lc.APIClient.PollDeviceFlow(&api.PollDeviceFlowRequest{DeviceCode: started.DeviceCode})
+		switch {
+		case api.ErrorStatusCode(err) == http.StatusGone:
+			return Error{Message: "The login code has expired, run 'infra login --device' to try again"}
+		case api.ErrorStatusCode(err) == http.StatusUnauthorized:
+			return &LoginError{Message: "the login was cancelled"}
+		case err != nil:
+			return err
+		case polled.Status == api.DeviceFlowStatusConfirmed && polled.Login != nil:
+			return finishLogin(cli, lc, &api.LoginRequest{}, polled.Login, noAgent)
+		case polled.Status == api.DeviceFlowStatusSlowDown:
+			interval += 5 * time.Second
+		}
+	}
+}
+
 // Updates all configs with the current logged in session
 func updateInfraConfig(lc loginClient, loginReq *api.LoginRequest, loginRes *api.LoginResponse) error {
 	clientHostConfig := ClientHostConfig{
@@ -278,6 +381,9 @@ func updateInfraConfig(lc loginClient, loginReq *api.LoginRequest, loginRes *api
 	if loginReq.OIDC != nil {
 		clientHostConfig.ProviderID = loginReq.OIDC.ProviderID
 	}
+	if loginReq.LDAP != nil {
+		clientHostConfig.ProviderID = loginReq.LDAP.ProviderID
+	}
 
 	u, err := urlx.Parse(lc.APIClient.URL)
 	if err != nil {
@@ -327,15 +433,6 @@ func oidcflow(provider *api.Provider) (string, error) {
 	return code, nil
 }
 
-// Given the provider name, directs user to its OIDC login page, then saves the auth code (to later login to infra)
-func loginToProviderN(client *api.Client, providerName string) (*api.LoginRequestOIDC, error) {
-	provider, err := GetProviderByName(client, providerName)
-	if err != nil {
-		return nil, err
-	}
-	return loginToProvider(provider)
-}
-
 // Given the provider, directs user to its OIDC login page, then saves the auth code (to later login to infra)
 func loginToProvider(provider *api.Provider) (*api.LoginRequestOIDC, error) {
 	fmt.Fprintf(os.Stderr, "  Logging in with %s...\n", termenv.String(provider.Name).Bold().String())
@@ -516,6 +613,30 @@ func promptLocalLogin(cli *CLI) (*api.LoginRequestPasswordCredentials, error) {
 	}, nil
 }
 
+// errSAMLLogin is returned when logging in with a saml provider. The identity
+// provider sends the response of a saml login to the server, not to the CLI,
+// so the login has to be completed in a browser.
+func errSAMLLogin(provider *api.Provider) error {
+	return Error{Message: fmt.Sprintf("Provider %q uses SAML, which requires a browser. Run 'infra login --device' and choose %s on the login page", provider.Name, provider.Name)}
+}
+
+// promptLDAPLogin asks for the username and password of the user in the
+// directory of an LDAP provider
+func promptLDAPLogin(cli *CLI, provider *api.Provider) (*api.LoginRequestLDAP, error) {
+	fmt.Fprintf(os.Stderr, "  Logging in with %s...\n", termenv.String(provider.Name).Bold().String())
+
+	credentials, err := promptLocalLogin(cli)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.LoginRequestLDAP{
+		ProviderID: provider.ID,
+		Name:       credentials.Name,
+		Password:   credentials.Password,
+	}, nil
+}
+
 func listProviders(client *api.Client) ([]api.Provider, error) {
 	logging.Debugf("call server: list providers")
 	providers, err := client.ListProviders(api.ListProvidersRequest{})
@@ -556,6 +677,12 @@ func promptLoginOptions(cli *CLI, client *api.Client) (loginMethod loginMethod,
 	if i == len(options)-1 {
 		return localLogin, nil, nil
 	}
+	switch providers[i].Kind {
+	case "ldap":
+		return ldapLogin, &providers[i], nil
+	case "saml":
+		return samlLogin, &providers[i], nil
+	}
 	return oidcLogin, &providers[i], nil
 }
 
@@ -2,7 +2,10 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -16,6 +19,7 @@ import (
 	"github.com/hinshun/vt10x"
 	"golang.org/x/sync/errgroup"
 	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
 	"gotest.tools/v3/assert/opt"
 	"gotest.tools/v3/golden"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
@@ -430,6 +434,121 @@ func TestLoginCmd_TLSVerify(t *testing.T) {
 	})
 }
 
+func TestLoginCmd_Device(t *testing.T) {
+	setupEnv(t)
+
+	userID := uid.ID(1234)
+	polls := 0
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		var body any
+		switch {
+		case requestMatches(req, http.MethodPost, "/api/device"):
+			body = api.StartDeviceFlowResponse{
+				DeviceCode:      "the-device-code",
+				UserCode:        "BCDF-GHJK",
+				VerificationURI: "https://infra.example.com/device",
+				ExpiresIn:       60,
+				Interval:        1,
+			}
+		case requestMatches(req, http.MethodPost, "/api/device/token"):
+			var pollReq api.PollDeviceFlowRequest
+			assert.Check(t, json.NewDecoder(req.Body).Decode(&pollReq))
+			assert.Check(t, is.Equal(pollReq.DeviceCode, "the-device-code"))
+
+			polls++
+			polled := api.PollDeviceFlowResponse{Status: api.DeviceFlowStatusPending, DeviceCode: pollReq.DeviceCode}
+			if polls > 1 {
+				polled.Status = api.DeviceFlowStatusConfirmed
+				polled.Login = &api.LoginResponse{
+					UserID:    userID,
+					Name:      "someone@example.com",
+					AccessKey: "aaaaaaaaaa.bbbbbbbbbbbbbbbbbbbbbbbb",
+					Expires:   api.Time(time.Now().Add(time.Hour)),
+				}
+			}
+			body = polled
+		case requestMatches(req, http.MethodGet, "/api/users/"+userID.String()):
+			body = api.User{ID: userID, Name: "someone@example.com"}
+		case requestMatches(req, http.MethodGet, "/api/destinations"):
+			body = api.ListResponse[api.Destination]{}
+		case requestMatches(req, http.MethodGet, "/api/grants"):
+			body = api.ListResponse[api.Grant]{}
+		default:
+			resp.WriteHeader(http.StatusNotFound)
+			return
+		}
+		resp.WriteHeader(http.StatusOK)
+		assert.Check(t, json.NewEncoder(resp).Encode(body))
+	}
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+	t.Cleanup(srv.Close)
+
+	ctx, bufs := PatchCLI(context.Background())
+	err := Run(ctx, "login", srv.Listener.Addr().String(), "--skip-tls-verify", "--no-agent", "--device")
+	assert.NilError(t, err)
+	assert.Equal(t, polls, 2)
+	assert.Assert(t, is.Contains(bufs.Stderr.String(), "Visit https://infra.example.com/device and enter the code"))
+	assert.Assert(t, is.Contains(bufs.Stderr.String(), "BCDF-GHJK"))
+
+	cfg, err := readConfig()
+	assert.NilError(t, err)
+	assert.Equal(t, len(cfg.Hosts), 1)
+	assert.Equal(t, cfg.Hosts[0].UserID, userID)
+	assert.Equal(t, cfg.Hosts[0].Name, "someone@example.com")
+	assert.Equal(t, cfg.Hosts[0].AccessKey, "aaaaaaaaaa.bbbbbbbbbbbbbbbbbbbbbbbb")
+}
+
+func TestLoginCmd_FederatedToken(t *testing.T) {
+	setupEnv(t)
+
+	userID := uid.ID(1234)
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		var body any
+		status := http.StatusOK
+		switch {
+		case requestMatches(req, http.MethodPost, "/api/login"):
+			var loginReq api.LoginRequest
+			assert.Check(t, json.NewDecoder(req.Body).Decode(&loginReq))
+			assert.Assert(t, loginReq.Federation != nil)
+			assert.Check(t, is.Equal(loginReq.Federation.Token, "the-ci-token"))
+
+			status = http.StatusCreated
+			body = api.LoginResponse{
+				UserID:    userID,
+				Name:      "deploy@example.com",
+				AccessKey: "aaaaaaaaaa.bbbbbbbbbbbbbbbbbbbbbbbb",
+				Expires:   api.Time(time.Now().Add(time.Hour)),
+			}
+		case requestMatches(req, http.MethodGet, "/api/users/"+userID.String()):
+			body = api.User{ID: userID, Name: "deploy@example.com"}
+		case requestMatches(req, http.MethodGet, "/api/destinations"):
+			body = api.ListResponse[api.Destination]{}
+		case requestMatches(req, http.MethodGet, "/api/grants"):
+			body = api.ListResponse[api.Grant]{}
+		default:
+			resp.WriteHeader(http.StatusNotFound)
+			return
+		}
+		resp.WriteHeader(status)
+		assert.Check(t, json.NewEncoder(resp).Encode(body))
+	}
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+	t.Cleanup(srv.Close)
+
+	ctx, _ := PatchCLI(context.Background())
+	err := Run(ctx, "login", srv.Listener.Addr().String(), "--skip-tls-verify", "--no-agent",
+		"--non-interactive", "--federated-token", "the-ci-token")
+	assert.NilError(t, err)
+
+	cfg, err := readConfig()
+	assert.NilError(t, err)
+	assert.Equal(t, len(cfg.Hosts), 1)
+	assert.Equal(t, cfg.Hosts[0].UserID, userID)
+	assert.Equal(t, cfg.Hosts[0].AccessKey, "aaaaaaaaaa.bbbbbbbbbbbbbbbbbbbbbbbb")
+}
+
 func TestAuthURLForProvider(t *testing.T) {
 	expectedOktaAuthURL := "https://okta.example.com/oauth2/v1/authorize?client_id=001&redirect_uri=http%3A%2F%2Flocalhost%3A8301&response_type=code&scope=email+openid&state=state"
 	okta := api.Provider{
@@ -70,6 +70,10 @@ func logoutOfServer(hostConfig *ClientHostConfig) (success bool) {
 
 	client := apiClient(hostConfig.Host, hostConfig.AccessKey, httpTransportForHostConfig(hostConfig))
 
+	if err := clearCachedTokens(hostConfig.Host); err != nil {
+		logging.Debugf("clear cached tokens: %v", err)
+	}
+
 	hostConfig.AccessKey = ""
 	hostConfig.UserID = 0
 	hostConfig.Name = ""
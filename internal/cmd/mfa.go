@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	survey "github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+)
+
+func newMFACmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mfa",
+		Short: "Manage multi-factor authentication",
+		Long: `Manage multi-factor authentication for users that login with a password.
+
+When multi-factor authentication is enabled, 'infra login' prompts for a code
+from an authenticator app after the password.`,
+		Group: "Management commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newMFAEnableCmd(cli))
+	cmd.AddCommand(newMFADisableCmd(cli))
+	cmd.AddCommand(newMFARecoveryCodesCmd(cli))
+
+	return cmd
+}
+
+func newMFAEnableCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "enable",
+		Short: "Enable multi-factor authentication for the current user",
+		Args:  NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+			return enableMFA(cli, client)
+		},
+	}
+}
+
+func newMFADisableCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "disable [USER]",
+		Short: "Disable multi-factor authentication",
+		Long: `Disable multi-factor authentication for the current user, or for USER.
+Disabling it for another user requires the admin role.`,
+		Example: `# Disable multi-factor authentication for the current user
+$ infra mfa disable
+
+# Disable multi-factor authentication for a user that lost their authenticator
+$ infra mfa disable johndoe@example.com`,
+		Args: MaxArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			id := api.IDOrSelf{IsSelf: true}
+			if len(args) > 0 {
+				user, err := getUserByNameOrID(client, args[0])
+				if err != nil {
+					if errors.Is(err, ErrUserNotFound) {
+						return Error{Message: fmt.Sprintf("User %q not found", args[0])}
+					}
+					return err
+				}
+				id = api.IDOrSelf{ID: user.ID}
+			}
+
+			logging.Debugf("call server: disable mfa for user %s", id)
+			if err := client.DisableMFA(id); err != nil {
+				if api.ErrorStatusCode(err) == http.StatusForbidden {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot disable multi-factor authentication: missing privileges for DisableMFA",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Disabled multi-factor authentication")
+			return nil
+		},
+	}
+}
+
+func newMFARecoveryCodesCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "recovery-codes",
+		Short: "Replace the recovery codes of the current user",
+		Long: `Replace the recovery codes of the current user. Each recovery code can be used
+once to login instead of a code from an authenticator app. The previous
+recovery codes stop working.`,
+		Args: NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: regenerate mfa recovery codes")
+			resp, err := client.RegenerateMFARecoveryCodes(api.IDOrSelf{IsSelf: true})
+			if err != nil {
+				return err
+			}
+
+			printRecoveryCodes(cli, resp.RecoveryCodes)
+			return nil
+		},
+	}
+}
+
+// enableMFA enrolls the current user in multi-factor authentication. It
+// prompts the user for a code from their authenticator app to verify the
+// enrollment.
+func enableMFA(cli *CLI, client *api.Client) error {
+	logging.Debugf("call server: enroll mfa")
+	enrollment, err := client.EnrollMFA(api.IDOrSelf{IsSelf: true})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cli.Stderr, "  Add this secret to your authenticator app: %s\n", enrollment.Secret)
+	fmt.Fprintf(cli.Stderr, "  Or use this URI: %s\n", enrollment.URI)
+
+	for {
+		code, err := promptMFACode(cli)
+		if err != nil {
+			return err
+		}
+
+		logging.Debugf("call server: verify mfa")
+		resp, err := client.VerifyMFA(&api.VerifyMFARequest{ID: api.IDOrSelf{IsSelf: true}, Code: code})
+		if err != nil {
+			if api.ErrorStatusCode(err) == http.StatusBadRequest {
+				fmt.Fprintf(cli.Stderr, "  Invalid code. Please try again.\n")
+				continue
+			}
+			return err
+		}
+
+		fmt.Fprintf(cli.Stderr, "  Enabled multi-factor authentication\n")
+		printRecoveryCodes(cli, resp.RecoveryCodes)
+		return nil
+	}
+}
+
+func printRecoveryCodes(cli *CLI, codes []string) {
+	cli.Output("Recovery codes, each can be used once to login if you lose your authenticator app:")
+	for _, code := range codes {
+		cli.Output("  %s", code)
+	}
+	cli.Output("Store them somewhere safe, they will not be shown again.")
+}
+
+func promptMFACode(cli *CLI) (string, error) {
+	var code string
+	err := survey.AskOne(
+		&survey.Input{Message: "Verification code:"},
+		&code,
+		cli.surveyIO,
+		survey.WithValidator(survey.Required),
+	)
+	return code, err
+}
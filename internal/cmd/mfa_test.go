@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestMFACmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	userID := uid.ID(1234)
+
+	setup := func(t *testing.T) *[]string {
+		calls := &[]string{}
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			*calls = append(*calls, req.Method+" "+req.URL.Path)
+
+			switch {
+			case requestMatches(req, http.MethodGet, "/api/users"):
+				resp.WriteHeader(http.StatusOK)
+				assert.Check(t, json.NewEncoder(resp).Encode(api.ListResponse[api.User]{
+					Count: 1,
+					Items: []api.User{{ID: userID, Name: "lost@example.com"}},
+				}))
+			case requestMatches(req, http.MethodPost, "/api/users/self/mfa/recovery-codes"):
+				resp.WriteHeader(http.StatusCreated)
+				assert.Check(t, json.NewEncoder(resp).Encode(api.MFARecoveryCodesResponse{
+					RecoveryCodes: []string{"aaaaa-bbbbb", "ccccc-ddddd"},
+				}))
+			case req.Method == http.MethodDelete:
+				resp.WriteHeader(http.StatusNoContent)
+			default:
+				resp.WriteHeader(http.StatusBadRequest)
+			}
+		}
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		assert.NilError(t, writeConfig(&cfg))
+		return calls
+	}
+
+	t.Run("recovery codes", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "mfa", "recovery-codes")
+		assert.NilError(t, err)
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), "  aaaaa-bbbbb\n  ccccc-ddddd\n"))
+	})
+
+	t.Run("disable self", func(t *testing.T) {
+		calls := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "mfa", "disable")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, *calls, []string{"DELETE /api/users/self/mfa"})
+		assert.Equal(t, bufs.Stdout.String(), "Disabled multi-factor authentication\n")
+	})
+
+	t.Run("disable other user", func(t *testing.T) {
+		calls := setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "mfa", "disable", "lost@example.com")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, *calls, []string{
+			"GET /api/users",
+			"DELETE /api/users/" + userID.String() + "/mfa",
+		})
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"sigs.k8s.io/yaml"
 
 	"github.com/infrahq/infra/api"
@@ -63,16 +64,141 @@ func (o providerAPIOptions) Validate(providerKind string) error {
 	return nil
 }
 
+type providerLDAPOptions struct {
+	BindDN       string
+	BindPassword string
+	SearchBase   string
+	UserFilter   string
+	GroupFilter  string
+}
+
+func (o providerLDAPOptions) isEmpty() bool {
+	return o == providerLDAPOptions{}
+}
+
+// Validate checks that the options required to search an LDAP directory are
+// set for ldap providers, and that no LDAP options are set for other kinds.
+func (o providerLDAPOptions) Validate(providerKind string) error {
+	if providerKind != "ldap" {
+		if !o.isEmpty() {
+			return fmt.Errorf("flags --bind-dn, --bind-password, --search-base, --user-filter, and --group-filter are only applicable to LDAP identity providers")
+		}
+		return nil
+	}
+
+	var missing []string
+	if o.BindDN == "" {
+		missing = append(missing, "bind-dn")
+	}
+	if o.BindPassword == "" {
+		missing = append(missing, "bind-password")
+	}
+	if o.SearchBase == "" {
+		missing = append(missing, "search-base")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing value for required flags: %v", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (o providerLDAPOptions) toAPI() *api.ProviderLDAP {
+	return &api.ProviderLDAP{
+		BindDN:       o.BindDN,
+		BindPassword: o.BindPassword,
+		SearchBase:   o.SearchBase,
+		UserFilter:   o.UserFilter,
+		GroupFilter:  o.GroupFilter,
+	}
+}
+
+func addProviderLDAPFlags(flags *pflag.FlagSet, opts *providerLDAPOptions) {
+	flags.StringVar(&opts.BindDN, "bind-dn", "", "The DN Infra binds as to search an LDAP directory")
+	flags.StringVar(&opts.BindPassword, "bind-password", "", "The password of the bind DN")
+	flags.StringVar(&opts.SearchBase, "search-base", "", "The DN to search for users and groups in an LDAP directory (eg. dc=example,dc=com)")
+	flags.StringVar(&opts.UserFilter, "user-filter", "", "The filter used to find users, {username} is replaced by the name used to login (default \"(uid={username})\")")
+	flags.StringVar(&opts.GroupFilter, "group-filter", "", "The filter used to find the groups of a user, {dn} is replaced by the DN of the user (default \"(member={dn})\")")
+}
+
+type providerSAMLOptions struct {
+	EntityID        string
+	Certificate     string
+	NameAttribute   string
+	GroupsAttribute string
+}
+
+func (o providerSAMLOptions) isEmpty() bool {
+	return o == providerSAMLOptions{}
+}
+
+// Validate checks that the metadata of the identity provider is set for saml
+// providers, and that no SAML options are set for other kinds.
+func (o providerSAMLOptions) Validate(providerKind string) error {
+	if providerKind != "saml" {
+		if !o.isEmpty() {
+			return fmt.Errorf("flags --entity-id, --certificate, --name-attribute, and --groups-attribute are only applicable to SAML identity providers")
+		}
+		return nil
+	}
+
+	var missing []string
+	if o.EntityID == "" {
+		missing = append(missing, "entity-id")
+	}
+	if o.Certificate == "" {
+		missing = append(missing, "certificate")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing value for required flags: %v", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (o providerSAMLOptions) toAPI() *api.ProviderSAML {
+	return &api.ProviderSAML{
+		EntityID:        o.EntityID,
+		Certificate:     api.PEM(o.Certificate),
+		NameAttribute:   o.NameAttribute,
+		GroupsAttribute: o.GroupsAttribute,
+	}
+}
+
+func addProviderSAMLFlags(flags *pflag.FlagSet, opts *providerSAMLOptions) {
+	flags.StringVar(&opts.EntityID, "entity-id", "", "The entity ID of the SAML identity provider")
+	flags.Var((*types.StringOrFile)(&opts.Certificate), "certificate", "The certificate the SAML identity provider signs responses with, can be a file or the PEM string directly")
+	flags.StringVar(&opts.NameAttribute, "name-attribute", "", "The SAML attribute used as the name of the user (default is the NameID of the subject)")
+	flags.StringVar(&opts.GroupsAttribute, "groups-attribute", "", "The SAML attribute that lists the groups of the user (default \"groups\")")
+}
+
 type providerEditOptions struct {
 	ClientSecret       string
 	ProviderAPIOptions providerAPIOptions
+	LDAP               providerLDAPOptions
+	SAML               providerSAMLOptions
 }
 
 func (o providerEditOptions) Validate(providerKind string) error {
-	if o.ClientSecret == "" && o.ProviderAPIOptions.PrivateKey == "" && o.ProviderAPIOptions.ClientEmail == "" && o.ProviderAPIOptions.WorkspaceDomainAdminEmail == "" {
+	if o.ClientSecret == "" && o.ProviderAPIOptions.PrivateKey == "" && o.ProviderAPIOptions.ClientEmail == "" && o.ProviderAPIOptions.WorkspaceDomainAdminEmail == "" && o.LDAP.isEmpty() && o.SAML.isEmpty() {
 		return fmt.Errorf("Please specify a field to update.'\n\n%s", newProvidersEditCmd(nil).UsageString())
 	}
 
+	if providerKind == "ldap" {
+		// the whole configuration is replaced, because it is not returned by the server
+		return o.LDAP.Validate(providerKind)
+	}
+
+	if providerKind == "saml" {
+		// the whole configuration is replaced, because it is not returned by the server
+		return o.SAML.Validate(providerKind)
+	}
+
+	if err := o.LDAP.Validate(providerKind); err != nil {
+		return err
+	}
+	if err := o.SAML.Validate(providerKind); err != nil {
+		return err
+	}
+
 	if providerKind != "google" && o.ClientSecret == "" {
 		return fmt.Errorf("Client secret flag must be specified when updating an identity provider that isn't of kind Google")
 	}
@@ -91,6 +217,12 @@ $ infra providers edit okta --client-secret VT_oXtkEDaT7UFY-C3DSRWYb00qyKZ1K1VCq
 
 # Connect Google to Infra with group sync
 $ infra providers edit google --client-secret VT_oXtkEDaT7UFY-C3DSRWYb00qyKZ1K1VCq7YzN --service-account-key ~/client-123.json --service-account-email hello@example.com --workspace-domain-admin admin@example.com
+
+# Set a new bind password for an LDAP provider
+$ infra providers edit active-directory --bind-dn "cn=infra,ou=services,dc=example,dc=com" --bind-password "$BIND_PASSWORD" --search-base "dc=example,dc=com" --user-filter "(sAMAccountName={username})"
+
+# Set a new signing certificate for a SAML provider
+$ infra providers edit adfs --entity-id http://adfs.example.com/adfs/services/trust --certificate ~/adfs-signing.pem
 `,
 		Args: ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -102,6 +234,8 @@ $ infra providers edit google --client-secret VT_oXtkEDaT7UFY-C3DSRWYb00qyKZ1K1V
 	cmd.Flags().Var((*types.StringOrFile)(&opts.ProviderAPIOptions.PrivateKey), "service-account-key", "The private key used to make authenticated requests to Google's API")
 	cmd.Flags().StringVar(&opts.ProviderAPIOptions.ClientEmail, "service-account-email", "", "The email assigned to the Infra service client in Google")
 	cmd.Flags().StringVar(&opts.ProviderAPIOptions.WorkspaceDomainAdminEmail, "workspace-domain-admin", "", "The email of your Google workspace domain admin")
+	addProviderLDAPFlags(cmd.Flags(), &opts.LDAP)
+	addProviderSAMLFlags(cmd.Flags(), &opts.SAML)
 	return cmd
 }
 
@@ -169,9 +303,31 @@ type providerAddOptions struct {
 	ClientSecret       string
 	Kind               string
 	ProviderAPIOptions providerAPIOptions
+	LDAP               providerLDAPOptions
+	SAML               providerSAMLOptions
 }
 
 func (o providerAddOptions) Validate() error {
+	if o.Kind == "ldap" || o.Kind == "saml" {
+		if o.URL == "" {
+			return fmt.Errorf("missing value for required flags: url")
+		}
+		if err := o.ProviderAPIOptions.Validate(o.Kind); err != nil {
+			return err
+		}
+		if err := o.LDAP.Validate(o.Kind); err != nil {
+			return err
+		}
+		return o.SAML.Validate(o.Kind)
+	}
+
+	if err := o.LDAP.Validate(o.Kind); err != nil {
+		return err
+	}
+	if err := o.SAML.Validate(o.Kind); err != nil {
+		return err
+	}
+
 	var missing []string
 	if o.URL == "" {
 		missing = append(missing, "url")
@@ -200,7 +356,13 @@ PROVIDER is a short unique name of the identity provider being added (eg. okta)`
 $ infra providers add okta --url example.okta.com --client-id 0oa3sz06o6do0muoW5d7 --client-secret VT_oXtkEDaT7UFY-C3DSRWYb00qyKZ1K1VCq7YzN --kind okta
 
 # Connect Google to Infra with group sync
-$ infra providers add google --url accounts.google.com --client-id 0oa3sz06o6do0muoW5d7 --client-secret VT_oXtkEDaT7UFY-C3DSRWYb00qyKZ1K1VCq7YzN --service-account-key ~/client-123.json --workspace-domain-admin admin@example.com --kind google`,
+$ infra providers add google --url accounts.google.com --client-id 0oa3sz06o6do0muoW5d7 --client-secret VT_oXtkEDaT7UFY-C3DSRWYb00qyKZ1K1VCq7YzN --service-account-key ~/client-123.json --workspace-domain-admin admin@example.com --kind google
+
+# Connect Active Directory to Infra with LDAP
+$ infra providers add active-directory --kind ldap --url ldaps://dc.example.com --bind-dn "cn=infra,ou=services,dc=example,dc=com" --bind-password "$BIND_PASSWORD" --search-base "dc=example,dc=com" --user-filter "(sAMAccountName={username})"
+
+# Connect ADFS to Infra with SAML
+$ infra providers add adfs --kind saml --url https://adfs.example.com/adfs/ls/ --entity-id http://adfs.example.com/adfs/services/trust --certificate ~/adfs-signing.pem`,
 		Args: ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := cliopts.DefaultsFromEnv("INFRA_PROVIDER", cmd.Flags()); err != nil {
@@ -221,8 +383,7 @@ $ infra providers add google --url accounts.google.com --client-id 0oa3sz06o6do0
 				return err
 			}
 
-			logging.Debugf("call server: create provider named %q", args[0])
-			_, err = client.CreateProvider(&api.CreateProviderRequest{
+			req := &api.CreateProviderRequest{
 				Name:         args[0],
 				URL:          opts.URL,
 				ClientID:     opts.ClientID,
@@ -233,7 +394,16 @@ $ infra providers add google --url accounts.google.com --client-id 0oa3sz06o6do0
 					ClientEmail:      opts.ProviderAPIOptions.ClientEmail,
 					DomainAdminEmail: opts.ProviderAPIOptions.WorkspaceDomainAdminEmail,
 				},
-			})
+			}
+			switch opts.Kind {
+			case "ldap":
+				req.LDAP = opts.LDAP.toAPI()
+			case "saml":
+				req.SAML = opts.SAML.toAPI()
+			}
+
+			logging.Debugf("call server: create provider named %q", args[0])
+			_, err = client.CreateProvider(req)
 			if err != nil {
 				if api.ErrorStatusCode(err) == 403 {
 					logging.Debugf("%s", err.Error())
@@ -252,10 +422,12 @@ $ infra providers add google --url accounts.google.com --client-id 0oa3sz06o6do0
 	cmd.Flags().StringVar(&opts.URL, "url", "", "Base URL of the domain of the OIDC identity provider (eg. acme.okta.com)")
 	cmd.Flags().StringVar(&opts.ClientID, "client-id", "", "OIDC client ID")
 	cmd.Flags().StringVar(&opts.ClientSecret, "client-secret", "", "OIDC client secret")
-	cmd.Flags().StringVar(&opts.Kind, "kind", "oidc", "The identity provider kind. One of 'oidc, okta, azure, or google'")
+	cmd.Flags().StringVar(&opts.Kind, "kind", "oidc", "The identity provider kind. One of 'oidc, okta, azure, google, ldap, or saml'")
 	cmd.Flags().Var((*types.StringOrFile)(&opts.ProviderAPIOptions.PrivateKey), "service-account-key", "The private key used to make authenticated requests to Google's API, can be a file or the key string directly")
 	cmd.Flags().StringVar(&opts.ProviderAPIOptions.ClientEmail, "service-account-email", "", "The email assigned to the Infra service client in Google") // this is only needed with the private key is not a file
 	cmd.Flags().StringVar(&opts.ProviderAPIOptions.WorkspaceDomainAdminEmail, "workspace-domain-admin", "", "The email of your Google Workspace domain admin")
+	addProviderLDAPFlags(cmd.Flags(), &opts.LDAP)
+	addProviderSAMLFlags(cmd.Flags(), &opts.SAML)
 	return cmd
 }
 
@@ -277,7 +449,7 @@ func updateProvider(cli *CLI, name string, opts providerEditOptions) error {
 	}
 	provider := res.Items[0]
 
-	if err := opts.ProviderAPIOptions.Validate(provider.Kind); err != nil {
+	if err := opts.Validate(provider.Kind); err != nil {
 		return err
 	}
 
@@ -286,8 +458,7 @@ func updateProvider(cli *CLI, name string, opts providerEditOptions) error {
 		return err
 	}
 
-	logging.Debugf("call server: update provider named %q", name)
-	_, err = client.UpdateProvider(api.UpdateProviderRequest{
+	req := api.UpdateProviderRequest{
 		ID:           provider.ID,
 		Name:         name,
 		URL:          provider.URL,
@@ -299,7 +470,16 @@ func updateProvider(cli *CLI, name string, opts providerEditOptions) error {
 			ClientEmail:      opts.ProviderAPIOptions.ClientEmail,
 			DomainAdminEmail: opts.ProviderAPIOptions.WorkspaceDomainAdminEmail,
 		},
-	})
+	}
+	switch provider.Kind {
+	case "ldap":
+		req.LDAP = opts.LDAP.toAPI()
+	case "saml":
+		req.SAML = opts.SAML.toAPI()
+	}
+
+	logging.Debugf("call server: update provider named %q", name)
+	_, err = client.UpdateProvider(req)
 
 	if err != nil {
 		if api.ErrorStatusCode(err) == 403 {
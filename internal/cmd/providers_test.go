@@ -189,6 +189,109 @@ func TestProvidersAddCmd(t *testing.T) {
 		assert.ErrorContains(t, err, "missing value for required flags: url, client-id, client-secret")
 	})
 
+	t.Run("ldap provider with flags", func(t *testing.T) {
+		ch := setup(t)
+
+		err := Run(context.Background(),
+			"providers", "add", "active-directory",
+			"--kind", "ldap",
+			"--url", "ldaps://dc.example.com",
+			"--bind-dn", "cn=infra,dc=example,dc=com",
+			"--bind-password", "the-password",
+			"--search-base", "dc=example,dc=com",
+			"--user-filter", "(sAMAccountName={username})",
+		)
+		assert.NilError(t, err)
+
+		createProviderRequest := <-ch
+
+		expected := api.CreateProviderRequest{
+			Name: "active-directory",
+			URL:  "ldaps://dc.example.com",
+			Kind: "ldap",
+			API:  &api.ProviderAPICredentials{},
+			LDAP: &api.ProviderLDAP{
+				BindDN:       "cn=infra,dc=example,dc=com",
+				BindPassword: "the-password",
+				SearchBase:   "dc=example,dc=com",
+				UserFilter:   "(sAMAccountName={username})",
+			},
+		}
+		assert.DeepEqual(t, createProviderRequest, expected)
+	})
+
+	t.Run("ldap provider missing required flags", func(t *testing.T) {
+		err := Run(context.Background(),
+			"providers", "add", "active-directory",
+			"--kind", "ldap",
+			"--url", "ldaps://dc.example.com",
+		)
+		assert.ErrorContains(t, err, "missing value for required flags: bind-dn, bind-password, search-base")
+	})
+
+	t.Run("ldap flags cannot be specified for non-ldap kind", func(t *testing.T) {
+		err := Run(context.Background(),
+			"providers", "add", "okta",
+			"--url", "example.okta.com",
+			"--client-id", "aaa",
+			"--client-secret", "bbb",
+			"--kind", "okta",
+			"--bind-dn", "cn=infra,dc=example,dc=com",
+		)
+		assert.ErrorContains(t, err, "only applicable to LDAP identity providers")
+	})
+
+	t.Run("saml provider with flags", func(t *testing.T) {
+		ch := setup(t)
+
+		certificate := "-----BEGIN CERTIFICATE-----\naaa=\n-----END CERTIFICATE-----\n"
+		err := Run(context.Background(),
+			"providers", "add", "adfs",
+			"--kind", "saml",
+			"--url", "https://adfs.example.com/adfs/ls/",
+			"--entity-id", "http://adfs.example.com/adfs/services/trust",
+			"--certificate", certificate,
+			"--name-attribute", "email",
+		)
+		assert.NilError(t, err)
+
+		createProviderRequest := <-ch
+
+		expected := api.CreateProviderRequest{
+			Name: "adfs",
+			URL:  "https://adfs.example.com/adfs/ls/",
+			Kind: "saml",
+			API:  &api.ProviderAPICredentials{},
+			SAML: &api.ProviderSAML{
+				EntityID:      "http://adfs.example.com/adfs/services/trust",
+				Certificate:   api.PEM(certificate),
+				NameAttribute: "email",
+			},
+		}
+		assert.DeepEqual(t, createProviderRequest, expected)
+	})
+
+	t.Run("saml provider missing required flags", func(t *testing.T) {
+		err := Run(context.Background(),
+			"providers", "add", "adfs",
+			"--kind", "saml",
+			"--url", "https://adfs.example.com/adfs/ls/",
+		)
+		assert.ErrorContains(t, err, "missing value for required flags: entity-id, certificate")
+	})
+
+	t.Run("saml flags cannot be specified for non-saml kind", func(t *testing.T) {
+		err := Run(context.Background(),
+			"providers", "add", "okta",
+			"--url", "example.okta.com",
+			"--client-id", "aaa",
+			"--client-secret", "bbb",
+			"--kind", "okta",
+			"--entity-id", "http://adfs.example.com/adfs/services/trust",
+		)
+		assert.ErrorContains(t, err, "only applicable to SAML identity providers")
+	})
+
 	t.Run("list with json", func(t *testing.T) {
 		setup(t)
 
@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+)
+
+func newRolesCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "roles",
+		Aliases: []string{"role"},
+		Short:   "Manage custom roles for Kubernetes destinations",
+		Long: `Manage custom roles for Kubernetes destinations.
+
+A role is a list of Kubernetes policy rules. Connectors create a ClusterRole
+named infra:ROLE for each role, so a role can be granted on any Kubernetes
+destination with 'infra grants add --role ROLE'.`,
+		Group: "Management commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newRolesListCmd(cli))
+	cmd.AddCommand(newRolesAddCmd(cli))
+	cmd.AddCommand(newRolesEditCmd(cli))
+	cmd.AddCommand(newRolesRemoveCmd(cli))
+
+	return cmd
+}
+
+func newRolesListCmd(cli *CLI) *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List custom roles",
+		Args:    NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: list roles")
+			roles, err := listAll(client.ListRoles, api.ListRolesRequest{})
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot list roles: missing privileges for ListRoles",
+					}
+				}
+				return err
+			}
+
+			switch format {
+			case "json":
+				jsonOutput, err := json.Marshal(roles)
+				if err != nil {
+					return err
+				}
+				cli.Output(string(jsonOutput))
+			case "yaml":
+				yamlOutput, err := yaml.Marshal(roles)
+				if err != nil {
+					return err
+				}
+				cli.Output(string(yamlOutput))
+			default:
+				type row struct {
+					Name        string `header:"NAME"`
+					Rules       int    `header:"RULES"`
+					Description string `header:"DESCRIPTION"`
+				}
+
+				var rows []row
+				for _, role := range roles {
+					rows = append(rows, row{
+						Name:        role.Name,
+						Rules:       len(role.Rules),
+						Description: role.Description,
+					})
+				}
+
+				if len(rows) > 0 {
+					printTable(rows, cli.Stdout)
+				} else {
+					cli.Output("No roles found")
+				}
+			}
+
+			return nil
+		},
+	}
+
+	addFormatFlag(cmd.Flags(), &format)
+	return cmd
+}
+
+type roleOptions struct {
+	Description string
+	File        string
+	APIGroups   []string
+	Resources   []string
+	Verbs       []string
+}
+
+// rules returns the policy rules from the file, and a rule from the
+// --verbs, --resources, and --api-groups flags if any of them are set.
+func (o roleOptions) rules() ([]api.PolicyRule, error) {
+	var rules []api.PolicyRule
+	if o.File != "" {
+		raw, err := os.ReadFile(o.File)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.UnmarshalStrict(raw, &rules); err != nil {
+			return nil, fmt.Errorf("invalid rules in %v: %w", o.File, err)
+		}
+	}
+
+	if len(o.Verbs) > 0 || len(o.Resources) > 0 || len(o.APIGroups) > 0 {
+		apiGroups := o.APIGroups
+		if apiGroups == nil {
+			// the core API group
+			apiGroups = []string{""}
+		}
+		rules = append(rules, api.PolicyRule{
+			APIGroups: apiGroups,
+			Resources: o.Resources,
+			Verbs:     o.Verbs,
+		})
+	}
+
+	if len(rules) == 0 {
+		return nil, Error{Message: "Rules are required, use --file or --verbs and --resources"}
+	}
+	return rules, nil
+}
+
+func addRoleFlags(cmd *cobra.Command, options *roleOptions) {
+	cmd.Flags().StringVar(&options.Description, "description", "", "Description of the role")
+	cmd.Flags().StringVarP(&options.File, "file", "f", "", "YAML file with a list of Kubernetes policy rules")
+	cmd.Flags().StringSliceVar(&options.Verbs, "verbs", nil, "Verbs of a policy rule")
+	cmd.Flags().StringSliceVar(&options.Resources, "resources", nil, "Resources of a policy rule")
+	cmd.Flags().StringSliceVar(&options.APIGroups, "api-groups", nil, "API groups of a policy rule, defaults to the core API group")
+}
+
+func newRolesAddCmd(cli *CLI) *cobra.Command {
+	var options roleOptions
+
+	cmd := &cobra.Command{
+		Use:   "add ROLE",
+		Short: "Add a custom role",
+		Long: `Add a custom role. The rules of the role are read from a YAML file with the
+same format as the rules of a Kubernetes ClusterRole, or from the --verbs,
+--resources, and --api-groups flags.`,
+		Example: `# Add a role that can read secrets
+$ infra roles add secret-reader --verbs get,list,watch --resources secrets
+
+# Add a role with the rules from a file
+$ infra roles add db-admin --file rules.yaml --description "Manage databases"`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rules, err := options.rules()
+			if err != nil {
+				return err
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: create role %q", args[0])
+			role, err := client.CreateRole(&api.CreateRoleRequest{
+				Name:        args[0],
+				Description: options.Description,
+				Rules:       rules,
+			})
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot add role: missing privileges for CreateRole",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Added role %q", role.Name)
+			return nil
+		},
+	}
+
+	addRoleFlags(cmd, &options)
+	return cmd
+}
+
+func newRolesEditCmd(cli *CLI) *cobra.Command {
+	var options roleOptions
+
+	cmd := &cobra.Command{
+		Use:   "edit ROLE",
+		Short: "Replace the rules of a custom role",
+		Example: `# Replace the rules of a role with the rules from a file
+$ infra roles edit db-admin --file rules.yaml`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rules, err := options.rules()
+			if err != nil {
+				return err
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			role, err := getRoleByName(client, args[0])
+			if err != nil {
+				return err
+			}
+
+			req := &api.UpdateRoleRequest{
+				ID:          role.ID,
+				Description: role.Description,
+				Rules:       rules,
+			}
+			if cmd.Flags().Changed("description") {
+				req.Description = options.Description
+			}
+
+			logging.Debugf("call server: update role %s", role.ID)
+			if _, err := client.UpdateRole(req); err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot edit role: missing privileges for UpdateRole",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Updated role %q", role.Name)
+			return nil
+		},
+	}
+
+	addRoleFlags(cmd, &options)
+	return cmd
+}
+
+func newRolesRemoveCmd(cli *CLI) *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:     "remove ROLE",
+		Aliases: []string{"rm"},
+		Short:   "Remove a custom role",
+		Long: `Remove a custom role. Grants of the role are not removed, but they no longer
+give access once connectors remove the ClusterRole of the role.`,
+		Example: `# Remove a role
+$ infra roles remove db-admin`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			role, err := getRoleByName(client, args[0])
+			if err != nil {
+				if force && errors.Is(err, ErrRoleNotFound) {
+					return nil
+				}
+				return err
+			}
+
+			logging.Debugf("call server: delete role %s", role.ID)
+			if err := client.DeleteRole(role.ID); err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot remove role: missing privileges for DeleteRole",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Removed role %q", role.Name)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Exit successfully even if the role does not exist")
+	return cmd
+}
+
+func getRoleByName(client *api.Client, name string) (*api.Role, error) {
+	logging.Debugf("call server: list roles named %q", name)
+	roles, err := client.ListRoles(api.ListRolesRequest{Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	if roles.Count == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrRoleNotFound, name)
+	}
+	return &roles.Items[0], nil
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestRolesCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	roleID := uid.ID(1234)
+	existing := api.Role{
+		ID:          roleID,
+		Name:        "secret-reader",
+		Description: "Read secrets",
+		Rules: []api.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}},
+		},
+	}
+
+	type requests struct {
+		create chan api.CreateRoleRequest
+		update chan api.UpdateRoleRequest
+	}
+
+	setup := func(t *testing.T) requests {
+		reqs := requests{
+			create: make(chan api.CreateRoleRequest, 1),
+			update: make(chan api.UpdateRoleRequest, 1),
+		}
+
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			switch {
+			case requestMatches(req, http.MethodPost, "/api/roles"):
+				var createReq api.CreateRoleRequest
+				err := json.NewDecoder(req.Body).Decode(&createReq)
+				assert.Check(t, err)
+				reqs.create <- createReq
+
+				resp.WriteHeader(http.StatusCreated)
+				err = json.NewEncoder(resp).Encode(api.Role{ID: roleID, Name: createReq.Name, Rules: createReq.Rules})
+				assert.Check(t, err)
+			case requestMatches(req, http.MethodGet, "/api/roles"):
+				roles := api.ListResponse[api.Role]{Count: 1, Items: []api.Role{existing}}
+				if name := req.URL.Query().Get("name"); name != "" && name != existing.Name {
+					roles = api.ListResponse[api.Role]{}
+				}
+				resp.WriteHeader(http.StatusOK)
+				err := json.NewEncoder(resp).Encode(roles)
+				assert.Check(t, err)
+			case requestMatches(req, http.MethodPut, "/api/roles/"+roleID.String()):
+				var updateReq api.UpdateRoleRequest
+				err := json.NewDecoder(req.Body).Decode(&updateReq)
+				assert.Check(t, err)
+				reqs.update <- updateReq
+
+				resp.WriteHeader(http.StatusOK)
+				err = json.NewEncoder(resp).Encode(existing)
+				assert.Check(t, err)
+			case requestMatches(req, http.MethodDelete, "/api/roles/"+roleID.String()):
+				resp.WriteHeader(http.StatusNoContent)
+			default:
+				resp.WriteHeader(http.StatusNotFound)
+			}
+		}
+
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		err := writeConfig(&cfg)
+		assert.NilError(t, err)
+		return reqs
+	}
+
+	t.Run("add with flags", func(t *testing.T) {
+		reqs := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "roles", "add", "pod-reader", "--verbs", "get,list", "--resources", "pods", "--description", "Read pods")
+		assert.NilError(t, err)
+
+		createReq := <-reqs.create
+		expected := api.CreateRoleRequest{
+			Name:        "pod-reader",
+			Description: "Read pods",
+			Rules: []api.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}},
+			},
+		}
+		assert.DeepEqual(t, createReq, expected)
+		assert.Equal(t, bufs.Stdout.String(), "Added role \"pod-reader\"\n")
+	})
+
+	t.Run("add with file", func(t *testing.T) {
+		reqs := setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		filename := filepath.Join(t.TempDir(), "rules.yaml")
+		content := `
+- apiGroups: ["apps"]
+  resources: ["deployments"]
+  verbs: ["get", "list", "watch"]
+- nonResourceURLs: ["/healthz"]
+  verbs: ["get"]
+`
+		assert.NilError(t, os.WriteFile(filename, []byte(content), 0o600))
+
+		err := Run(ctx, "roles", "add", "deploy-reader", "--file", filename)
+		assert.NilError(t, err)
+
+		createReq := <-reqs.create
+		expected := []api.PolicyRule{
+			{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get", "list", "watch"}},
+			{NonResourceURLs: []string{"/healthz"}, Verbs: []string{"get"}},
+		}
+		assert.DeepEqual(t, createReq.Rules, expected)
+	})
+
+	t.Run("add without rules", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "roles", "add", "pod-reader")
+		assert.ErrorContains(t, err, "Rules are required")
+	})
+
+	t.Run("list", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "roles", "list")
+		assert.NilError(t, err)
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), "secret-reader"))
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), "Read secrets"))
+	})
+
+	t.Run("edit keeps description", func(t *testing.T) {
+		reqs := setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "roles", "edit", "secret-reader", "--verbs", "get,list", "--resources", "secrets")
+		assert.NilError(t, err)
+
+		updateReq := <-reqs.update
+		assert.Equal(t, updateReq.Description, "Read secrets")
+		assert.DeepEqual(t, updateReq.Rules, []api.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get", "list"}},
+		})
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "roles", "remove", "secret-reader")
+		assert.NilError(t, err)
+		assert.Equal(t, bufs.Stdout.String(), "Removed role \"secret-reader\"\n")
+	})
+
+	t.Run("remove unknown role", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "roles", "remove", "unknown")
+		assert.ErrorContains(t, err, `role not found: "unknown"`)
+
+		err = Run(ctx, "roles", "remove", "unknown", "--force")
+		assert.NilError(t, err)
+	})
+}
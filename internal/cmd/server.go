@@ -83,8 +83,10 @@ func newServerCmd() *cobra.Command {
 	cmd.Flags().Var(&types.URL{}, "ui-proxy-url", "Enable UI and proxy requests to this url")
 	cmd.Flags().Duration("session-duration", 0, "Maximum session duration per user login")
 	cmd.Flags().Duration("session-extension-deadline", 0, "A user must interact with Infra at least once within this amount of time for their session to remain valid")
+	cmd.Flags().Duration("signing-key-rotation-interval", 0, "Rotate the key used to sign tokens for destinations at this interval")
 	cmd.Flags().Bool("enable-signup", false, "Enable one-time admin signup")
 	cmd.Flags().String("base-domain", "", "base-domain for the server, eg example.com")
+	cmd.Flags().Var(&types.URL{}, "server-url", "URL used to reach the server, eg https://infra.example.com")
 
 	return cmd
 }
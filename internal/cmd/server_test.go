@@ -115,6 +115,20 @@ func TestServerCmd_LoadOptions(t *testing.T) {
 				return expected
 			},
 		},
+		{
+			name: "parse server-url from command line flag",
+			setup: func(t *testing.T, cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--server-url", "https://infra.example.com"})
+			},
+			expected: func(t *testing.T) server.Options {
+				expected := defaultServerOptions(filepath.Join(dir, ".infra"))
+				expected.ServerURL = types.URL{
+					Scheme: "https",
+					Host:   "infra.example.com",
+				}
+				return expected
+			},
+		},
 		{
 			name: "all options from config",
 			setup: func(t *testing.T, cmd *cobra.Command) {
@@ -125,6 +139,7 @@ enableTelemetry: false # default is true
 enableSignup: false    # default is true
 sessionDuration: 3m
 sessionExtensionDeadline: 1m
+signingKeyRotationInterval: 720h
 
 dbFile: /db/file
 dbEncryptionKey: /this-is-the-path
@@ -138,6 +153,10 @@ dbParameters: sslmode=require
 
 baseDomain: foo.example.com
 
+trustedProxies:
+  - 10.0.0.0/8
+  - fc00::/7
+
 tls:
   ca: testdata/ca.crt
   caPrivateKey: file:ca.key
@@ -192,10 +211,11 @@ users:
 			},
 			expected: func(t *testing.T) server.Options {
 				return server.Options{
-					Version:                  0.2,
-					TLSCache:                 "/cache/dir",
-					SessionDuration:          3 * time.Minute,
-					SessionExtensionDeadline: 1 * time.Minute,
+					Version:                    0.2,
+					TLSCache:                   "/cache/dir",
+					SessionDuration:            3 * time.Minute,
+					SessionExtensionDeadline:   1 * time.Minute,
+					SigningKeyRotationInterval: 720 * time.Hour,
 
 					DBEncryptionKey:         "/this-is-the-path",
 					DBEncryptionKeyProvider: "the-provider",
@@ -207,7 +227,8 @@ users:
 					DBUsername:              "infra",
 					DBName:                  "infradbname",
 
-					BaseDomain: "foo.example.com",
+					BaseDomain:     "foo.example.com",
+					TrustedProxies: []string{"10.0.0.0/8", "fc00::/7"},
 
 					Addr: server.ListenerOptions{
 						HTTP:    "1.2.3.4:23",
@@ -291,6 +312,7 @@ users:
 					"--enable-telemetry=false",
 					"--session-duration", "3m",
 					"--session-extension-deadline", "1m",
+					"--signing-key-rotation-interval", "24h",
 					"--enable-signup=false",
 				})
 			},
@@ -303,6 +325,7 @@ users:
 				expected.EnableTelemetry = false
 				expected.SessionDuration = 3 * time.Minute
 				expected.SessionExtensionDeadline = 1 * time.Minute
+				expected.SigningKeyRotationInterval = 24 * time.Hour
 				expected.EnableSignup = false
 				return expected
 			},
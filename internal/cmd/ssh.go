@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+)
+
+func newSSHCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ssh [LOGIN@]DESTINATION [COMMAND...]",
+		Short: "Connect to a destination with SSH",
+		Long: `Connect to a destination with SSH.
+
+A short-lived SSH certificate is created for a key in ~/.infra/ssh, and ssh is
+run with the certificate. LOGIN is the local user of the destination to log in
+as. It is only required when your grants allow more than one login.`,
+		Example: `
+# Connect to the web-1 destination
+$ infra ssh web-1
+
+# Connect as the ubuntu user
+$ infra ssh ubuntu@web-1
+
+# Run a command
+$ infra ssh web-1 uptime`,
+		Args:  MinArgs(1),
+		Group: "Core commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return sshConnect(cli, args[0], args[1:])
+		},
+	}
+
+	// flags after the destination are part of the command
+	cmd.Flags().SetInterspersed(false)
+	return cmd
+}
+
+func sshConnect(cli *CLI, target string, command []string) error {
+	login, destinationName, ok := strings.Cut(target, "@")
+	if !ok {
+		login, destinationName = "", target
+	}
+
+	client, err := defaultAPIClient()
+	if err != nil {
+		return err
+	}
+
+	destinations, err := client.ListDestinations(api.ListDestinationsRequest{Name: destinationName})
+	if err != nil {
+		return err
+	}
+	if len(destinations.Items) == 0 {
+		return Error{Message: fmt.Sprintf("Destination %q not found; run 'infra list' to see the destinations you can access", destinationName)}
+	}
+	destination := destinations.Items[0]
+
+	sshDir, err := initSSHDir()
+	if err != nil {
+		return err
+	}
+
+	keyFile := filepath.Join(sshDir, "id_ecdsa")
+	publicKey, err := sshUserKey(keyFile)
+	if err != nil {
+		return err
+	}
+
+	logging.Debugf("call server: create ssh certificate for destination %q", destinationName)
+	resp, err := client.CreateSSHCertificate(&api.CreateSSHCertificateRequest{
+		Destination: destinationName,
+		PublicKey:   string(ssh.MarshalAuthorizedKey(publicKey)),
+	})
+	if err != nil {
+		if api.ErrorStatusCode(err) == 403 {
+			return Error{Message: fmt.Sprintf("You do not have access to log in to %q; run 'infra list' to see the destinations you can access", destinationName)}
+		}
+		return err
+	}
+
+	login, err = sshLogin(login, destinationName, resp.Logins)
+	if err != nil {
+		return err
+	}
+
+	certFile := filepath.Join(sshDir, destinationName+"-cert.pub")
+	if err := os.WriteFile(certFile, []byte(resp.Certificate+"\n"), 0o600); err != nil {
+		return err
+	}
+
+	host, port := sshEndpoint(destination.Connection.URL)
+	args := []string{
+		"-i", keyFile,
+		"-o", "CertificateFile=" + certFile,
+		"-o", "IdentitiesOnly=yes",
+		"-p", port,
+		"-l", login,
+		host,
+	}
+	return runSSH(cli, append(args, command...))
+}
+
+// sshLogin returns the login to use for the destination. If login is empty,
+// the only login that the user is allowed to use is returned.
+func sshLogin(login, destination string, logins []string) (string, error) {
+	if login == "" {
+		if len(logins) == 1 {
+			return logins[0], nil
+		}
+		return "", Error{Message: fmt.Sprintf(
+			"You can log in to %q as %v; run 'infra ssh LOGIN@%v' with one of them",
+			destination, strings.Join(logins, ", "), destination)}
+	}
+
+	for _, l := range logins {
+		if l == login {
+			return login, nil
+		}
+	}
+	return "", Error{Message: fmt.Sprintf(
+		"You do not have access to log in to %q as %v; you can log in as %v",
+		destination, login, strings.Join(logins, ", "))}
+}
+
+// sshEndpoint returns the host and port from the connection URL of a
+// destination.
+func sshEndpoint(url string) (host, port string) {
+	host, port, err := net.SplitHostPort(url)
+	if err != nil {
+		return url, "22"
+	}
+	return host, port
+}
+
+func initSSHDir() (string, error) {
+	infraDir, err := initInfraHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	sshDir := filepath.Join(infraDir, "ssh")
+	if err := os.MkdirAll(sshDir, 0o700); err != nil {
+		return "", err
+	}
+	return sshDir, nil
+}
+
+// sshUserKey reads the private key of the user from keyFile, creating the
+// key if the file does not exist, and returns the public key.
+func sshUserKey(keyFile string) (ssh.PublicKey, error) {
+	keyPEM, err := os.ReadFile(keyFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("generate ssh key: %w", err)
+		}
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+		if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("read ssh key %v: %w", keyFile, err)
+	}
+	return signer.PublicKey(), nil
+}
+
+// runSSH is a shim for testing
+var runSSH = func(cli *CLI, args []string) error {
+	logging.Debugf("running ssh %v", strings.Join(args, " "))
+	cmd := exec.Command("ssh", args...)
+	cmd.Stdin = cli.Stdin
+	cmd.Stdout = cli.Stdout
+	cmd.Stderr = cli.Stderr
+
+	var exitErr *exec.ExitError
+	if err := cmd.Run(); errors.As(err, &exitErr) {
+		return Error{Message: fmt.Sprintf("ssh exited with status %d", exitErr.ExitCode())}
+	} else if err != nil {
+		return fmt.Errorf("run ssh: %w", err)
+	}
+	return nil
+}
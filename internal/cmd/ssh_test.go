@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+)
+
+func TestSSHCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	sshDir := filepath.Join(home, ".infra", "ssh")
+
+	var logins []string
+	var publicKeys []string
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		switch {
+		case requestMatches(req, http.MethodGet, "/api/destinations"):
+			var items []api.Destination
+			if req.URL.Query().Get("name") == "web" {
+				items = append(items, api.Destination{
+					Name:       "web",
+					Connection: api.DestinationConnection{URL: "web.example.com:2222"},
+				})
+			}
+			err := json.NewEncoder(resp).Encode(api.ListResponse[api.Destination]{Items: items, Count: len(items)})
+			assert.Check(t, err)
+
+		case requestMatches(req, http.MethodPost, "/api/ssh/certificates"):
+			var createReq api.CreateSSHCertificateRequest
+			assert.Check(t, json.NewDecoder(req.Body).Decode(&createReq))
+			assert.Check(t, createReq.Destination == "web")
+			publicKeys = append(publicKeys, createReq.PublicKey)
+
+			if len(logins) == 0 {
+				resp.WriteHeader(http.StatusForbidden)
+				_ = json.NewEncoder(resp).Encode(api.Error{Code: http.StatusForbidden})
+				return
+			}
+
+			resp.WriteHeader(http.StatusCreated)
+			err := json.NewEncoder(resp).Encode(api.CreateSSHCertificateResponse{
+				Certificate: "ssh-ed25519-cert-v01@openssh.com AAAA",
+				Logins:      logins,
+				Expires:     api.Time(time.Now().Add(5 * time.Minute)),
+			})
+			assert.Check(t, err)
+
+		default:
+			resp.WriteHeader(http.StatusNotFound)
+		}
+	}
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+	t.Cleanup(srv.Close)
+
+	cfg := newTestClientConfig(srv, api.User{})
+	assert.NilError(t, writeConfig(&cfg))
+
+	var sshArgs []string
+	patchRunSSH(t, func(_ *CLI, args []string) error {
+		sshArgs = args
+		return nil
+	})
+
+	expectedArgs := func(login string, command ...string) []string {
+		return append([]string{
+			"-i", filepath.Join(sshDir, "id_ecdsa"),
+			"-o", "CertificateFile=" + filepath.Join(sshDir, "web-cert.pub"),
+			"-o", "IdentitiesOnly=yes",
+			"-p", "2222",
+			"-l", login,
+			"web.example.com",
+		}, command...)
+	}
+
+	t.Run("only login", func(t *testing.T) {
+		logins = []string{"ubuntu"}
+		ctx, _ := PatchCLI(context.Background())
+		err := Run(ctx, "ssh", "web")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, sshArgs, expectedArgs("ubuntu"))
+
+		cert, err := os.ReadFile(filepath.Join(sshDir, "web-cert.pub"))
+		assert.NilError(t, err)
+		assert.Equal(t, string(cert), "ssh-ed25519-cert-v01@openssh.com AAAA\n")
+
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKeys[0]))
+		assert.NilError(t, err)
+		assert.Equal(t, key.Type(), ssh.KeyAlgoECDSA256)
+	})
+
+	t.Run("login and command", func(t *testing.T) {
+		logins = []string{"deploy", "ubuntu"}
+		ctx, _ := PatchCLI(context.Background())
+		err := Run(ctx, "ssh", "deploy@web", "ls", "-l")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, sshArgs, expectedArgs("deploy", "ls", "-l"))
+
+		// the key is created once
+		assert.Equal(t, publicKeys[len(publicKeys)-1], publicKeys[0])
+	})
+
+	t.Run("more than one login", func(t *testing.T) {
+		sshArgs = nil
+		logins = []string{"deploy", "ubuntu"}
+		ctx, _ := PatchCLI(context.Background())
+		err := Run(ctx, "ssh", "web")
+		assert.Error(t, err, `You can log in to "web" as deploy, ubuntu; run 'infra ssh LOGIN@web' with one of them`)
+		assert.Assert(t, sshArgs == nil)
+	})
+
+	t.Run("login not allowed", func(t *testing.T) {
+		logins = []string{"deploy"}
+		ctx, _ := PatchCLI(context.Background())
+		err := Run(ctx, "ssh", "root@web")
+		assert.Error(t, err, `You do not have access to log in to "web" as root; you can log in as deploy`)
+	})
+
+	t.Run("no grants", func(t *testing.T) {
+		logins = nil
+		ctx, _ := PatchCLI(context.Background())
+		err := Run(ctx, "ssh", "web")
+		assert.ErrorContains(t, err, `You do not have access to log in to "web"`)
+	})
+
+	t.Run("unknown destination", func(t *testing.T) {
+		ctx, _ := PatchCLI(context.Background())
+		err := Run(ctx, "ssh", "db")
+		assert.ErrorContains(t, err, `Destination "db" not found`)
+	})
+}
+
+func patchRunSSH(t *testing.T, fn func(*CLI, []string) error) {
+	orig := runSSH
+	runSSH = fn
+	t.Cleanup(func() {
+		runSSH = orig
+	})
+}
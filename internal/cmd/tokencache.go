@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/cmd/cliopts"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/uid"
+)
+
+const (
+	// tokenCacheRefreshBefore is how long before a cached token expires that it
+	// is replaced by a new token.
+	tokenCacheRefreshBefore = time.Minute
+
+	// tokenCacheAgentRefreshBefore is how long before a cached token expires
+	// that the agent replaces it. It is longer than tokenCacheRefreshBefore by
+	// the interval of the agent, so that kubectl can always use a cached token.
+	tokenCacheAgentRefreshBefore = tokenCacheRefreshBefore + time.Minute
+
+	// tokenCacheKeepWarm is how long after a token was last used that the
+	// agent continues to refresh it, and that it is kept in the cache.
+	tokenCacheKeepWarm = 30 * time.Minute
+
+	// tokenCacheLockTimeout is how long to wait for another process to release
+	// the lock on the cache.
+	tokenCacheLockTimeout = 30 * time.Second
+)
+
+// cachedToken is a token for a destination, created for a user of a server.
+type cachedToken struct {
+	Host        string    `json:"host"`
+	UserID      uid.ID    `json:"user-id"`
+	Destination string    `json:"destination"`
+	Token       string    `json:"token"`
+	Expires     time.Time `json:"expires"`
+	LastUsed    time.Time `json:"last-used"`
+}
+
+// tokenCache is stored in ~/.infra/tokens, so that kubectl does not need to
+// wait for the server to create a new token every time it runs.
+type tokenCache struct {
+	Tokens []cachedToken `json:"tokens"`
+}
+
+func (c *tokenCache) find(host string, userID uid.ID, destination string) *cachedToken {
+	for i, t := range c.Tokens {
+		if t.Host == host && t.UserID == userID && t.Destination == destination {
+			return &c.Tokens[i]
+		}
+	}
+	return nil
+}
+
+func (c *tokenCache) put(token cachedToken) {
+	if existing := c.find(token.Host, token.UserID, token.Destination); existing != nil {
+		*existing = token
+		return
+	}
+	c.Tokens = append(c.Tokens, token)
+}
+
+// prune removes tokens that have expired and have not been used recently.
+func (c *tokenCache) prune(now time.Time) {
+	tokens := make([]cachedToken, 0, len(c.Tokens))
+	for _, t := range c.Tokens {
+		if now.After(t.Expires) && now.Sub(t.LastUsed) > tokenCacheKeepWarm {
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+	c.Tokens = tokens
+}
+
+func tokenCachePath() (string, error) {
+	infraDir, err := initInfraHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(infraDir, "tokens"), nil
+}
+
+// withTokenCache calls fn with the token cache, and saves the changes made by
+// fn. The cache is locked until fn returns, so that concurrent kubectl
+// commands and the agent do not create tokens for the same destination.
+func withTokenCache(fn func(cache *tokenCache) error) error {
+	path, err := tokenCachePath()
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cache := &tokenCache{}
+	contents, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(contents, cache); err != nil {
+			// the cache can always be recreated, so replace it instead of failing
+			logging.Debugf("invalid token cache: %v", err)
+			cache = &tokenCache{}
+		}
+	}
+
+	if err := fn(cache); err != nil {
+		return err
+	}
+
+	cache.prune(time.Now())
+	contents, err = json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, contents, 0o600)
+}
+
+// lockFile locks the lock file at path, waiting for any other process to
+// unlock it first. The lock is held by the operating system, so it is
+// released when the process exits, even if it exits without unlocking. The
+// returned function unlocks the file.
+func lockFile(path string) (unlock func(), err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(tokenCacheLockTimeout)
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("lock file: %w", err)
+		}
+		if locked {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			_ = f.Close()
+			return nil, fmt.Errorf("timed out waiting for lock file %v", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return func() {
+		if err := unlockFile(f); err != nil {
+			logging.Debugf("unlock file: %v", err)
+		}
+		_ = f.Close()
+	}, nil
+}
+
+// destinationToken returns a token for the destination from the cache. If
+// there is no cached token, or it expires soon, it creates a new token. If the
+// server can not create a token, a cached token is returned until it expires.
+func destinationToken(client *api.Client, config *ClientHostConfig, destination string) (cachedToken, error) {
+	var result cachedToken
+	err := withTokenCache(func(cache *tokenCache) error {
+		now := time.Now()
+		cached := cache.find(config.Host, config.UserID, destination)
+		if cached != nil && now.Add(tokenCacheRefreshBefore).Before(cached.Expires) {
+			cached.LastUsed = now
+			result = *cached
+			return nil
+		}
+
+		logging.Debugf("call server: create token for destination %q", destination)
+		token, err := client.CreateToken(&api.CreateTokenRequest{Destination: destination})
+		if err != nil {
+			if cached != nil && now.Before(cached.Expires) {
+				logging.Debugf("create token: %v, using cached token", err)
+				cached.LastUsed = now
+				result = *cached
+				return nil
+			}
+			return err
+		}
+
+		result = cachedToken{
+			Host:        config.Host,
+			UserID:      config.UserID,
+			Destination: destination,
+			Token:       token.Token,
+			Expires:     time.Time(token.Expires),
+			LastUsed:    now,
+		}
+		cache.put(result)
+		return nil
+	})
+	return result, err
+}
+
+// refreshCachedTokens replaces the cached tokens of the user that expire soon,
+// if they were used recently. A token that can not be refreshed does not stop
+// the others from being refreshed, and the refreshed tokens are saved even
+// when some fail.
+func refreshCachedTokens(client *api.Client, config *ClientHostConfig) error {
+	var errs []error
+	err := withTokenCache(func(cache *tokenCache) error {
+		now := time.Now()
+		for i := range cache.Tokens {
+			cached := &cache.Tokens[i]
+			switch {
+			case cached.Host != config.Host || cached.UserID != config.UserID:
+				continue
+			case now.Sub(cached.LastUsed) > tokenCacheKeepWarm:
+				continue
+			case now.Add(tokenCacheAgentRefreshBefore).Before(cached.Expires):
+				continue
+			}
+
+			logging.Debugf("call server: create token for destination %q", cached.Destination)
+			token, err := client.CreateToken(&api.CreateTokenRequest{Destination: cached.Destination})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("refresh token for destination %q: %w", cached.Destination, err))
+				continue
+			}
+			cached.Token = token.Token
+			cached.Expires = time.Time(token.Expires)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return cliopts.MultiError(errs)
+	}
+	return nil
+}
+
+// clearCachedTokens removes the cached tokens for host, or all cached tokens
+// if host is empty.
+func clearCachedTokens(host string) error {
+	return withTokenCache(func(cache *tokenCache) error {
+		tokens := make([]cachedToken, 0, len(cache.Tokens))
+		for _, t := range cache.Tokens {
+			if host != "" && t.Host != host {
+				tokens = append(tokens, t)
+			}
+		}
+		cache.Tokens = tokens
+		return nil
+	})
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+)
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.lock")
+
+	unlock, err := lockFile(path)
+	assert.NilError(t, err)
+
+	locked := make(chan struct{})
+	go func() {
+		unlock, err := lockFile(path)
+		assert.Check(t, err)
+		close(locked)
+		unlock()
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("lock was acquired while it was held")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	unlock()
+	<-locked
+
+	t.Run("lock of a process that exited without unlocking", func(t *testing.T) {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestLockFileHelperProcess$")
+		cmd.Env = append(os.Environ(), "INFRA_TEST_LOCK_FILE="+path)
+		out, err := cmd.CombinedOutput()
+		assert.NilError(t, err, string(out))
+
+		unlock, err := lockFile(path)
+		assert.NilError(t, err)
+		unlock()
+	})
+}
+
+// TestLockFileHelperProcess is run by TestLockFile in a separate process. It
+// locks the file, and exits without unlocking it.
+func TestLockFileHelperProcess(t *testing.T) {
+	path := os.Getenv("INFRA_TEST_LOCK_FILE")
+	if path == "" {
+		t.Skip("only run by TestLockFile")
+	}
+	_, err := lockFile(path)
+	assert.NilError(t, err)
+	os.Exit(0)
+}
+
+func TestRefreshCachedTokens(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		var createReq api.CreateTokenRequest
+		assert.Check(t, json.NewDecoder(req.Body).Decode(&createReq))
+		if createReq.Destination == "staging" {
+			resp.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(resp).Encode(api.Error{Code: http.StatusNotFound, Message: "not found"})
+			return
+		}
+
+		resp.WriteHeader(http.StatusCreated)
+		assert.Check(t, json.NewEncoder(resp).Encode(api.CreateTokenResponse{
+			Token:   "new-" + createReq.Destination,
+			Expires: api.Time(time.Now().Add(time.Hour)),
+		}))
+	}
+	srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+	t.Cleanup(srv.Close)
+
+	cfg := newTestClientConfig(srv, api.User{})
+	assert.NilError(t, writeConfig(&cfg))
+	hostConfig := &cfg.Hosts[0]
+
+	err := withTokenCache(func(cache *tokenCache) error {
+		for _, destination := range []string{"staging", "production"} {
+			cache.put(cachedToken{
+				Host:        hostConfig.Host,
+				UserID:      hostConfig.UserID,
+				Destination: destination,
+				Token:       "old-" + destination,
+				Expires:     time.Now().Add(30 * time.Second),
+				LastUsed:    time.Now(),
+			})
+		}
+		return nil
+	})
+	assert.NilError(t, err)
+
+	client := apiClient(hostConfig.Host, hostConfig.AccessKey, httpTransportForHostConfig(hostConfig))
+	err = refreshCachedTokens(client, hostConfig)
+	assert.ErrorContains(t, err, `refresh token for destination "staging"`)
+
+	err = withTokenCache(func(cache *tokenCache) error {
+		staging := cache.find(hostConfig.Host, hostConfig.UserID, "staging")
+		assert.Equal(t, staging.Token, "old-staging")
+		production := cache.find(hostConfig.Host, hostConfig.UserID, "production")
+		assert.Equal(t, production.Token, "new-production")
+		return nil
+	})
+	assert.NilError(t, err)
+}
+
+func TestTokenCache_Prune(t *testing.T) {
+	now := time.Now()
+	cache := tokenCache{Tokens: []cachedToken{
+		{Destination: "valid", Expires: now.Add(time.Hour), LastUsed: now.Add(-time.Hour)},
+		{Destination: "recently-used", Expires: now.Add(-time.Minute), LastUsed: now.Add(-time.Minute)},
+		{Destination: "expired", Expires: now.Add(-time.Minute), LastUsed: now.Add(-time.Hour)},
+	}}
+
+	cache.prune(now)
+
+	var destinations []string
+	for _, token := range cache.Tokens {
+		destinations = append(destinations, token.Destination)
+	}
+	assert.DeepEqual(t, destinations, []string{"valid", "recently-used"})
+}
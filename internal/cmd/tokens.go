@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"encoding/json"
-	"time"
 
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -11,40 +10,58 @@ import (
 
 func newTokensCmd(cli *CLI) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:    "tokens",
-		Short:  "Create & manage tokens",
-		Hidden: true,
+		Use:   "tokens",
+		Short: "Manage cached destination tokens",
+		Long: `Manage cached destination tokens.
+
+kubectl runs 'infra tokens add' to get a token for a destination. Tokens are
+cached in ~/.infra/tokens and reused until shortly before they expire, and the
+agent started by 'infra login' replaces them before they expire.`,
+		Group: "Other commands:",
 		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
-			if err := rootPreRun(cmd.Flags()); err != nil {
-				return err
-			}
-			return mustBeLoggedIn()
+			return rootPreRun(cmd.Flags())
 		},
 	}
 
 	cmd.AddCommand(newTokensAddCmd(cli))
+	cmd.AddCommand(newTokensListCmd(cli))
+	cmd.AddCommand(newTokensClearCmd(cli))
 
 	return cmd
 }
 
 func newTokensAddCmd(cli *CLI) *cobra.Command {
 	return &cobra.Command{
-		Use:   "add",
-		Short: "Create a token",
-		Args:  NoArgs,
-		RunE: func(cmd *cobra.Command, _ []string) error {
-			return tokensCreate(cli)
+		Use:    "add DESTINATION",
+		Short:  "Create a token for a destination",
+		Args:   MaxArgs(1),
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				// kubeconfig files written by older versions do not include
+				// the destination
+				return Error{Message: "Missing destination; run 'infra list' to update your kubeconfig"}
+			}
+			if err := mustBeLoggedIn(); err != nil {
+				return err
+			}
+			return tokensCreate(cli, args[0])
 		},
 	}
 }
 
-func tokensCreate(cli *CLI) error {
+func tokensCreate(cli *CLI, destination string) error {
+	config, err := currentHostConfig()
+	if err != nil {
+		return err
+	}
+
 	client, err := defaultAPIClient()
 	if err != nil {
 		return err
 	}
 
-	token, err := client.CreateToken()
+	token, err := destinationToken(client, config, destination)
 	if err != nil {
 		return err
 	}
@@ -57,7 +74,7 @@ func tokensCreate(cli *CLI) error {
 		Spec: clientauthenticationv1beta1.ExecCredentialSpec{},
 		Status: &clientauthenticationv1beta1.ExecCredentialStatus{
 			Token:               token.Token,
-			ExpirationTimestamp: &metav1.Time{Time: time.Time(token.Expires)},
+			ExpirationTimestamp: &metav1.Time{Time: token.Expires},
 		},
 	}
 
@@ -70,3 +87,71 @@ func tokensCreate(cli *CLI) error {
 
 	return nil
 }
+
+func newTokensListCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List cached destination tokens",
+		Args:    NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var tokens []cachedToken
+			err := withTokenCache(func(cache *tokenCache) error {
+				tokens = cache.Tokens
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			type row struct {
+				Destination string `header:"DESTINATION"`
+				Server      string `header:"SERVER"`
+				Expires     string `header:"EXPIRES"`
+				LastUsed    string `header:"LAST USED"`
+			}
+
+			var rows []row
+			for _, token := range tokens {
+				rows = append(rows, row{
+					Destination: token.Destination,
+					Server:      token.Host,
+					Expires:     HumanTime(token.Expires, "never"),
+					LastUsed:    HumanTime(token.LastUsed, "never"),
+				})
+			}
+
+			if len(rows) > 0 {
+				printTable(rows, cli.Stdout)
+			} else {
+				cli.Output("No cached tokens")
+			}
+			return nil
+		},
+	}
+}
+
+func newTokensClearCmd(cli *CLI) *cobra.Command {
+	var server string
+
+	cmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Remove cached destination tokens",
+		Example: `# Remove all cached tokens
+$ infra tokens clear
+
+# Remove the cached tokens of one server
+$ infra tokens clear --server infra.example.com`,
+		Args: NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := clearCachedTokens(server); err != nil {
+				return err
+			}
+			cli.Output("Cleared cached tokens")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&server, "server", "", "Only remove the tokens of this server")
+	return cmd
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	clientauthenticationv1beta1 "k8s.io/client-go/pkg/apis/clientauthentication/v1beta1"
+
+	"github.com/infrahq/infra/api"
+)
+
+func TestTokensAddCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	var requests atomic.Int32
+	setup := func(t *testing.T) chan api.CreateTokenRequest {
+		ch := make(chan api.CreateTokenRequest, 2)
+		requests.Store(0)
+
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			if !requestMatches(req, http.MethodPost, "/api/tokens") {
+				resp.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			var createReq api.CreateTokenRequest
+			err := json.NewDecoder(req.Body).Decode(&createReq)
+			assert.Check(t, err)
+			ch <- createReq
+			requests.Add(1)
+
+			resp.WriteHeader(http.StatusCreated)
+			err = json.NewEncoder(resp).Encode(api.CreateTokenResponse{
+				Token:   "the-token",
+				Expires: api.Time(time.Now().Add(time.Hour)),
+			})
+			assert.Check(t, err)
+		}
+
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		err := writeConfig(&cfg)
+		assert.NilError(t, err)
+		return ch
+	}
+
+	t.Run("with destination", func(t *testing.T) {
+		ch := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "tokens", "add", "production")
+		assert.NilError(t, err)
+
+		createReq := <-ch
+		assert.Equal(t, createReq.Destination, "production")
+
+		var execCredential clientauthenticationv1beta1.ExecCredential
+		assert.NilError(t, json.Unmarshal(bufs.Stdout.Bytes(), &execCredential))
+		assert.Equal(t, execCredential.Status.Token, "the-token")
+	})
+
+	t.Run("uses cached token", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "tokens", "add", "production")
+		assert.NilError(t, err)
+		first := bufs.Stdout.String()
+
+		bufs.Stdout.Reset()
+		err = Run(ctx, "tokens", "add", "production")
+		assert.NilError(t, err)
+		assert.Equal(t, bufs.Stdout.String(), first)
+		assert.Equal(t, requests.Load(), int32(1))
+
+		err = Run(ctx, "tokens", "add", "staging")
+		assert.NilError(t, err)
+		assert.Equal(t, requests.Load(), int32(2))
+	})
+
+	t.Run("replaces expiring token", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "tokens", "add", "production")
+		assert.NilError(t, err)
+
+		err = withTokenCache(func(cache *tokenCache) error {
+			for i := range cache.Tokens {
+				cache.Tokens[i].Expires = time.Now().Add(30 * time.Second)
+			}
+			return nil
+		})
+		assert.NilError(t, err)
+
+		err = Run(ctx, "tokens", "add", "production")
+		assert.NilError(t, err)
+		assert.Equal(t, requests.Load(), int32(2))
+	})
+
+	t.Run("list and clear", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "tokens", "clear")
+		assert.NilError(t, err)
+
+		err = Run(ctx, "tokens", "add", "production")
+		assert.NilError(t, err)
+
+		bufs.Stdout.Reset()
+		err = Run(ctx, "tokens", "list")
+		assert.NilError(t, err)
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), "production"))
+
+		err = Run(ctx, "tokens", "clear")
+		assert.NilError(t, err)
+
+		bufs.Stdout.Reset()
+		err = Run(ctx, "tokens", "list")
+		assert.NilError(t, err)
+		assert.Equal(t, bufs.Stdout.String(), "No cached tokens\n")
+	})
+
+	t.Run("without destination", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "tokens", "add")
+		assert.ErrorContains(t, err, "run 'infra list' to update your kubeconfig")
+	})
+}
@@ -35,6 +35,7 @@ func newUsersCmd(cli *CLI) *cobra.Command {
 	cmd.AddCommand(newUsersEditCmd(cli))
 	cmd.AddCommand(newUsersListCmd(cli))
 	cmd.AddCommand(newUsersRemoveCmd(cli))
+	cmd.AddCommand(newUsersUnlockCmd(cli))
 
 	return cmd
 }
@@ -252,6 +253,48 @@ $ infra users remove janedoe@example.com`,
 	return cmd
 }
 
+func newUsersUnlockCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "unlock USER",
+		Short: "Unlock a user locked out by failed login attempts",
+		Long: `Unlock a user that was locked out after too many failed login attempts.
+The user can login again immediately instead of waiting for the lockout to expire.`,
+		Example: `# Unlock a user
+$ infra users unlock janedoe@example.com`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			user, err := getUserByNameOrID(client, name)
+			if err != nil {
+				if errors.Is(err, ErrUserNotFound) {
+					return Error{Message: fmt.Sprintf("User %q not found", name)}
+				}
+				return err
+			}
+
+			logging.Debugf("call server: unlock user %s", user.ID)
+			if err := client.UnlockUser(user.ID); err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot unlock user: missing privileges for UnlockUser",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Unlocked user %q", user.Name)
+			return nil
+		},
+	}
+}
+
 // CreateUser creates an user within Infra
 func CreateUser(req *api.CreateUserRequest) (*api.CreateUserResponse, error) {
 	client, err := defaultAPIClient()
@@ -221,3 +221,59 @@ func TestUsersCmd(t *testing.T) {
 		golden.Assert(t, bufs.Stdout.String(), t.Name())
 	})
 }
+
+func TestUsersUnlockCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	userID := uid.ID(5678)
+
+	setup := func(t *testing.T, unlockStatus int) *[]string {
+		calls := &[]string{}
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			*calls = append(*calls, req.Method+" "+req.URL.Path)
+
+			switch {
+			case requestMatches(req, http.MethodGet, "/api/users"):
+				resp.WriteHeader(http.StatusOK)
+				assert.Check(t, json.NewEncoder(resp).Encode(api.ListResponse[api.User]{
+					Count: 1,
+					Items: []api.User{{ID: userID, Name: "locked@example.com"}},
+				}))
+			case requestMatches(req, http.MethodPost, "/api/users/"+userID.String()+"/unlock"):
+				resp.WriteHeader(unlockStatus)
+				_, _ = resp.Write([]byte(`{}`))
+			default:
+				resp.WriteHeader(http.StatusBadRequest)
+			}
+		}
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		assert.NilError(t, writeConfig(&cfg))
+		return calls
+	}
+
+	t.Run("success", func(t *testing.T) {
+		calls := setup(t, http.StatusCreated)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "users", "unlock", "locked@example.com")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, *calls, []string{
+			"GET /api/users",
+			"POST /api/users/" + userID.String() + "/unlock",
+		})
+		assert.Equal(t, bufs.Stdout.String(), "Unlocked user \"locked@example.com\"\n")
+	})
+
+	t.Run("missing privileges", func(t *testing.T) {
+		setup(t, http.StatusForbidden)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "users", "unlock", "locked@example.com")
+		assert.ErrorContains(t, err, "missing privileges for UnlockUser")
+	})
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/uid"
+)
+
+func newWebhooksCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "webhooks",
+		Aliases: []string{"webhook"},
+		Short:   "Manage webhooks",
+		Group:   "Management commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newWebhooksAddCmd(cli))
+	cmd.AddCommand(newWebhooksListCmd(cli))
+	cmd.AddCommand(newWebhooksRemoveCmd(cli))
+
+	return cmd
+}
+
+type webhookCreateOptions struct {
+	Events []string
+	Secret string
+}
+
+func newWebhooksAddCmd(cli *CLI) *cobra.Command {
+	var options webhookCreateOptions
+
+	cmd := &cobra.Command{
+		Use:   "add URL",
+		Short: "Add a webhook",
+		Long: fmt.Sprintf(`Add a webhook that receives events from Infra.
+
+Events are sent as a POST request with a JSON body. The body is signed with
+the secret of the webhook using HMAC-SHA256, and the signature is sent in the
+Infra-Signature header.
+
+Event types: %s`, strings.Join(api.WebhookEventTypes, ", ")),
+		Example: `
+# Send events to a webhook when grants are created or deleted
+$ infra webhooks add https://example.com/infra-events --events grant.created,grant.deleted
+`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: create webhook for %q", args[0])
+			resp, err := client.CreateWebhook(&api.CreateWebhookRequest{
+				URL:    args[0],
+				Secret: options.Secret,
+				Events: options.Events,
+			})
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot add webhook: missing privileges for CreateWebhook",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Added webhook %s for %q", resp.ID, resp.URL)
+			if options.Secret == "" {
+				cli.Output("")
+				cli.Output("Secret: %s", resp.Secret)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&options.Events, "events", api.WebhookEventTypes, "Types of events to send to the webhook")
+	cmd.Flags().StringVar(&options.Secret, "secret", "", "Secret used to sign events, generated if not set")
+	return cmd
+}
+
+func newWebhooksListCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List webhooks",
+		Args:    NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: list webhooks")
+			webhooks, err := listAll(client.ListWebhooks, api.ListWebhooksRequest{})
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot list webhooks: missing privileges for ListWebhooks",
+					}
+				}
+				return err
+			}
+
+			type row struct {
+				ID     string `header:"ID"`
+				URL    string `header:"URL"`
+				Events string `header:"EVENTS"`
+			}
+
+			var rows []row
+			for _, webhook := range webhooks {
+				rows = append(rows, row{
+					ID:     webhook.ID.String(),
+					URL:    webhook.URL,
+					Events: strings.Join(webhook.Events, ", "),
+				})
+			}
+
+			if len(rows) > 0 {
+				printTable(rows, cli.Stdout)
+			} else {
+				cli.Output("No webhooks found")
+			}
+
+			return nil
+		},
+	}
+}
+
+func newWebhooksRemoveCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:     "remove ID",
+		Aliases: []string{"rm"},
+		Short:   "Remove a webhook",
+		Example: `# Remove a webhook
+$ infra webhooks remove 4yJ3n3D8E2`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := uid.Parse([]byte(args[0]))
+			if err != nil || id == 0 {
+				return Error{Message: fmt.Sprintf("Invalid webhook ID %q", args[0])}
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: delete webhook %s", id)
+			if err := client.DeleteWebhook(id); err != nil {
+				switch api.ErrorStatusCode(err) {
+				case 403:
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot remove webhook: missing privileges for DeleteWebhook",
+					}
+				case 404:
+					return Error{Message: fmt.Sprintf("No webhook with ID %s", id)}
+				}
+				return err
+			}
+
+			cli.Output("Removed webhook %s", id)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestWebhooksCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	webhookID := uid.ID(1234)
+
+	setup := func(t *testing.T) chan api.CreateWebhookRequest {
+		createCh := make(chan api.CreateWebhookRequest, 1)
+
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			switch {
+			case requestMatches(req, http.MethodPost, "/api/webhooks"):
+				var createReq api.CreateWebhookRequest
+				err := json.NewDecoder(req.Body).Decode(&createReq)
+				assert.Check(t, err)
+				createCh <- createReq
+
+				resp.WriteHeader(http.StatusCreated)
+				err = json.NewEncoder(resp).Encode(api.CreateWebhookResponse{
+					Webhook: api.Webhook{ID: webhookID, URL: createReq.URL, Events: createReq.Events},
+					Secret:  "the-generated-secret",
+				})
+				assert.Check(t, err)
+			case requestMatches(req, http.MethodGet, "/api/webhooks"):
+				resp.WriteHeader(http.StatusOK)
+				err := json.NewEncoder(resp).Encode(api.ListResponse[api.Webhook]{
+					Count: 1,
+					Items: []api.Webhook{
+						{ID: webhookID, URL: "https://example.com/events", Events: []string{"grant.created", "user.deleted"}},
+					},
+				})
+				assert.Check(t, err)
+			case requestMatches(req, http.MethodDelete, "/api/webhooks/"+webhookID.String()):
+				resp.WriteHeader(http.StatusNoContent)
+			default:
+				resp.WriteHeader(http.StatusNotFound)
+			}
+		}
+
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		err := writeConfig(&cfg)
+		assert.NilError(t, err)
+		return createCh
+	}
+
+	t.Run("add", func(t *testing.T) {
+		ch := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "webhooks", "add", "https://example.com/events", "--events", "grant.created,user.deleted")
+		assert.NilError(t, err)
+
+		createReq := <-ch
+		expected := api.CreateWebhookRequest{
+			URL:    "https://example.com/events",
+			Events: []string{"grant.created", "user.deleted"},
+		}
+		assert.DeepEqual(t, createReq, expected)
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), "Secret: the-generated-secret"))
+	})
+
+	t.Run("add with default events", func(t *testing.T) {
+		ch := setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "webhooks", "add", "https://example.com/events", "--secret", "my-secret")
+		assert.NilError(t, err)
+
+		createReq := <-ch
+		assert.DeepEqual(t, createReq.Events, api.WebhookEventTypes)
+		assert.Equal(t, createReq.Secret, "my-secret")
+	})
+
+	t.Run("list", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "webhooks", "list")
+		assert.NilError(t, err)
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), "https://example.com/events"))
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), "grant.created, user.deleted"))
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "webhooks", "remove", webhookID.String())
+		assert.NilError(t, err)
+		assert.Equal(t, bufs.Stdout.String(), "Removed webhook "+webhookID.String()+"\n")
+	})
+
+	t.Run("remove invalid id", func(t *testing.T) {
+		setup(t)
+		ctx, _ := PatchCLI(context.Background())
+
+		err := Run(ctx, "webhooks", "remove", "not-an-id!")
+		assert.ErrorContains(t, err, `Invalid webhook ID "not-an-id!"`)
+	})
+}
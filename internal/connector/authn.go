@@ -19,42 +19,59 @@ import (
 
 type authenticator struct {
 	mu          sync.Mutex
-	key         *jose.JSONWebKey
+	keys        []jose.JSONWebKey
 	lastChecked time.Time
 
 	client  httpClient
 	baseURL string
+	// audience is the name and unique ID of the destination. Tokens must
+	// include one of them in their audience.
+	audience []string
 }
 
 type httpClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
-func newAuthenticator(url string, options Options) *authenticator {
+func newAuthenticator(url string, options Options, audience []string) *authenticator {
 	transport := httpTransportFromOptions(options.Server)
 	return &authenticator{
-		client:  &http.Client{Transport: transport},
-		baseURL: url,
+		client:   &http.Client{Transport: transport},
+		baseURL:  url,
+		audience: audience,
 	}
 }
 
 var JWKCacheRefresh = 5 * time.Minute
 
+// JWKMinRefresh is the minimum time between requests for JWKs. It limits how
+// often tokens signed by an unknown key can cause the keys to be fetched.
+var JWKMinRefresh = 10 * time.Second
+
 func (j *authenticator) Authenticate(req *http.Request) (claims.Custom, error) {
-	c := claims.Custom{}
 	authHeader := req.Header.Get("Authorization")
 
 	raw := strings.TrimPrefix(authHeader, "Bearer ")
 	if raw == "" {
-		return c, fmt.Errorf("no bearer token found")
+		return claims.Custom{}, fmt.Errorf("no bearer token found")
 	}
+	return j.authenticateToken(raw)
+}
 
+// authenticateToken validates a destination token, and returns its claims.
+func (j *authenticator) authenticateToken(raw string) (claims.Custom, error) {
+	c := claims.Custom{}
 	tok, err := jwt.ParseSigned(raw)
 	if err != nil {
 		return c, fmt.Errorf("invalid JWT signature: %w", err)
 	}
 
-	key, err := j.getJWK()
+	var keyID string
+	if len(tok.Headers) > 0 {
+		keyID = tok.Headers[0].KeyID
+	}
+
+	key, err := j.getJWK(keyID)
 	if err != nil {
 		return c, fmt.Errorf("get JWK from server: %w", err)
 	}
@@ -75,6 +92,10 @@ func (j *authenticator) Authenticate(req *http.Request) (claims.Custom, error) {
 		return c, fmt.Errorf("invalid JWT %w", err)
 	}
 
+	if !j.validAudience(allClaims.Audience) {
+		return c, fmt.Errorf("invalid JWT audience %v", allClaims.Audience)
+	}
+
 	if allClaims.Custom.Name == "" {
 		return c, fmt.Errorf("no username in JWT claims")
 	}
@@ -82,12 +103,34 @@ func (j *authenticator) Authenticate(req *http.Request) (claims.Custom, error) {
 	return allClaims.Custom, nil
 }
 
-func (j *authenticator) getJWK() (*jose.JSONWebKey, error) {
+// validAudience returns true if the audience of a token includes the name or
+// unique ID of the destination. Tokens created for other destinations are
+// rejected, even though they are signed by the same key.
+func (j *authenticator) validAudience(audience jwt.Audience) bool {
+	for _, aud := range j.audience {
+		if aud != "" && audience.Contains(aud) {
+			return true
+		}
+	}
+	return false
+}
+
+// getJWK returns the key with keyID from the JWKs published by the server.
+// The keys are cached, and fetched again when the cache expires, or when
+// there is no key with keyID in the cache, which happens when the server
+// rotates its signing key.
+func (j *authenticator) getJWK(keyID string) (*jose.JSONWebKey, error) {
 	j.mu.Lock()
 	defer j.mu.Unlock()
 
-	if !j.lastChecked.IsZero() && time.Now().Before(j.lastChecked.Add(JWKCacheRefresh)) {
-		return j.key, nil
+	if !j.lastChecked.IsZero() {
+		now := time.Now()
+		if key := findJWK(j.keys, keyID); key != nil && now.Before(j.lastChecked.Add(JWKCacheRefresh)) {
+			return key, nil
+		}
+		if now.Before(j.lastChecked.Add(JWKMinRefresh)) {
+			return nil, fmt.Errorf("no jwk with key id %q", keyID)
+		}
 	}
 
 	req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, fmt.Sprintf("%s/.well-known/jwks.json", j.baseURL), nil)
@@ -120,7 +163,22 @@ func (j *authenticator) getJWK() (*jose.JSONWebKey, error) {
 	}
 
 	j.lastChecked = time.Now().UTC()
-	j.key = &response.Keys[0]
+	j.keys = response.Keys
 
-	return &response.Keys[0], nil
+	key := findJWK(j.keys, keyID)
+	if key == nil {
+		return nil, fmt.Errorf("no jwk with key id %q", keyID)
+	}
+	return key, nil
+}
+
+// findJWK returns the key with keyID. Tokens signed by older versions of the
+// server may not include a key ID, in which case the first key is returned.
+func findJWK(keys []jose.JSONWebKey, keyID string) *jose.JSONWebKey {
+	for i := range keys {
+		if keyID == "" || keys[i].KeyID == keyID {
+			return &keys[i]
+		}
+	}
+	return nil
 }
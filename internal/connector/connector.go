@@ -1,16 +1,13 @@
 package connector
 
 import (
-	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
 	"log"
-	"net"
 	"net/http"
-	"net/http/httputil"
 	"os"
 	"strings"
 	"time"
@@ -18,25 +15,30 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/goware/urlx"
 	"github.com/infrahq/secrets"
-	rbacv1 "k8s.io/api/rbac/v1"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/cmd/types"
 	"github.com/infrahq/infra/internal/ginutil"
-	"github.com/infrahq/infra/internal/kubernetes"
 	"github.com/infrahq/infra/internal/logging"
-	"github.com/infrahq/infra/internal/repeat"
 	"github.com/infrahq/infra/metrics"
 )
 
 type Options struct {
 	Server ServerOptions
 	Name   string
+	// Kind is the kind of destination, which selects the driver. Defaults
+	// to kubernetes.
+	Kind   string
 	CACert string
 	CAKey  string
 
 	Addr ListenerOptions
+	// SSH configures the ssh driver.
+	SSH SSHOptions
+	// Postgres configures the postgres driver.
+	Postgres PostgresOptions
 }
 
 type ServerOptions struct {
@@ -51,22 +53,26 @@ type ListenerOptions struct {
 	Metrics string
 }
 
+// Run the connector for the destination kind selected by options.
 func Run(ctx context.Context, options Options) error {
-	k8s, err := kubernetes.NewKubernetes()
+	driver, err := newDriver(options)
 	if err != nil {
 		return err
 	}
+	return run(ctx, options, driver)
+}
 
-	chksm, err := k8s.Checksum()
+func run(ctx context.Context, options Options, driver Driver) error {
+	uniqueID, err := driver.UniqueID()
 	if err != nil {
-		logging.Errorf("k8s checksum error: %s", err)
+		logging.Errorf("%s", err)
 		return err
 	}
 
 	if options.Name == "" {
-		autoname, err := k8s.Name(chksm)
+		autoname, err := driver.DefaultName()
 		if err != nil {
-			logging.Errorf("k8s name error: %s", err)
+			logging.Errorf("%s", err)
 			return err
 		}
 		options.Name = autoname
@@ -91,43 +97,24 @@ func Run(ctx context.Context, options Options) error {
 		return certCache.Certificate()
 	}
 
-	basicSecretStorage := map[string]secrets.SecretStorage{
-		"env":       secrets.NewEnvSecretProviderFromConfig(secrets.GenericConfig{}),
-		"file":      secrets.NewFileSecretProviderFromConfig(secrets.FileConfig{}),
-		"plaintext": secrets.NewPlainSecretProviderFromConfig(secrets.GenericConfig{}),
-	}
-
 	u, err := urlx.Parse(options.Server.URL)
 	if err != nil {
 		return fmt.Errorf("invalid server url: %w", err)
 	}
 
 	// server is localhost which should never be the case. try to infer the actual host
-	if strings.HasPrefix(u.Host, "localhost") {
-		server, err := k8s.Service("server")
-		if err != nil {
-			logging.Warnf("no cluster-local infra server found for %q. check connector configurations", u.Host)
-		} else {
-			host := fmt.Sprintf("%s.%s", server.ObjectMeta.Name, server.ObjectMeta.Namespace)
-			logging.Debugf("using cluster-local infra server at %q instead of %q", host, u.Host)
-			u.Host = host
-		}
+	if k8s, ok := driver.(*kubernetesDriver); ok && strings.HasPrefix(u.Host, "localhost") {
+		u.Host = k8s.localServerHost(u.Host)
 	}
 
 	u.Scheme = "https"
 
 	destination := &api.Destination{
 		Name:     options.Name,
-		UniqueID: chksm,
+		UniqueID: uniqueID,
 	}
 
-	// clone the default http transport which sets reasonable defaults
-	defaultHTTPTransport, ok := http.DefaultTransport.(*http.Transport)
-	if !ok {
-		return errors.New("unexpected type for http.DefaultTransport")
-	}
-
-	accessKey, err := secrets.GetSecret(options.Server.AccessKey, basicSecretStorage)
+	accessKey, err := getSecret(options.Server.AccessKey)
 	if err != nil {
 		return err
 	}
@@ -141,47 +128,21 @@ func Run(ctx context.Context, options Options) error {
 			Transport: httpTransportFromOptions(options.Server),
 		},
 		Headers: http.Header{
-			"Infra-Destination": {chksm},
+			"Infra-Destination": {uniqueID},
 		},
 	}
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	// TODO: make polling time configurable
-	repeat.Start(ctx, 30*time.Second, syncWithServer(k8s, client, destination, certCache, caCertPEM))
-
-	ginutil.SetMode()
-	router := gin.New()
-	router.GET("/healthz", func(c *gin.Context) {
-		c.Status(http.StatusOK)
-	})
-
-	proxyHost, err := urlx.Parse(k8s.Config.Host)
-	if err != nil {
-		return fmt.Errorf("parsing host config: %w", err)
-	}
-
-	clusterCACert, err := kubernetes.CA()
-	if err != nil {
-		return fmt.Errorf("reading CA file: %w", err)
-	}
-
-	certPool := x509.NewCertPool()
-
-	if ok := certPool.AppendCertsFromPEM(clusterCACert); !ok {
-		return errors.New("could not append CA to client cert bundle")
+	destinationSync := &syncer{
+		driver:      driver,
+		client:      client,
+		destination: destination,
+		certCache:   certCache,
+		caCertPEM:   caCertPEM,
 	}
-
-	proxyTransport := defaultHTTPTransport.Clone()
-	proxyTransport.ForceAttemptHTTP2 = false
-	proxyTransport.TLSClientConfig = &tls.Config{
-		RootCAs:    certPool,
-		MinVersion: tls.VersionTLS12,
-	}
-
-	proxy := httputil.NewSingleHostReverseProxy(proxyHost)
-	proxy.Transport = proxyTransport
+	go destinationSync.run(ctx)
 
 	promRegistry := metrics.NewRegistry(internal.FullVersion())
 	httpErrorLog := log.New(logging.NewFilteredHTTPLogger(), "", 0)
@@ -194,28 +155,77 @@ func Run(ctx context.Context, options Options) error {
 	}
 
 	go func() {
-		if err := metricsServer.ListenAndServe(); err != nil {
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logging.Errorf("server: %s", err)
 		}
 	}()
 
-	authn := newAuthenticator(u.String(), options)
-	router.Use(
-		metrics.Middleware(promRegistry),
-		proxyMiddleware(proxy, authn, k8s.Config.BearerToken),
-	)
+	authn := newAuthenticator(u.String(), options, []string{destination.Name, destination.UniqueID})
 	tlsServer := &http.Server{
 		ReadHeaderTimeout: 30 * time.Second,
 		ReadTimeout:       60 * time.Second,
 		Addr:              options.Addr.HTTPS,
 		TLSConfig:         tlsConfig,
-		Handler:           router,
+		Handler:           newRouter(driver, authn, promRegistry),
 		ErrorLog:          httpErrorLog,
 	}
 
+	if server, ok := driver.(connServer); ok {
+		go func() {
+			if err := server.Serve(ctx, authn, tlsConfig); err != nil {
+				logging.Errorf("server: %s", err)
+			}
+		}()
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = metricsServer.Close()
+		_ = tlsServer.Close()
+	}()
+
 	logging.Infof("starting infra connector (%s) - https:%s metrics:%s", internal.FullVersion(), tlsServer.Addr, metricsServer.Addr)
 
-	return tlsServer.ListenAndServeTLS("", "")
+	err = tlsServer.ListenAndServeTLS("", "")
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// newRouter returns the handler of requests to the connector. Requests from
+// users are authenticated and then proxied to the destination.
+func newRouter(driver Driver, authn *authenticator, promRegistry *prometheus.Registry) *gin.Engine {
+	ginutil.SetMode()
+	router := gin.New()
+	router.GET("/healthz", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/readyz", func(c *gin.Context) {
+		if err := driver.Health(); err != nil {
+			logging.L.Warn().Err(err).Msg("destination is not healthy")
+			c.Status(http.StatusServiceUnavailable)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	router.Use(
+		metrics.Middleware(promRegistry),
+		proxyMiddleware(driver, authn),
+	)
+	return router
+}
+
+// getSecret returns the secret with name, which is read from an environment
+// variable or a file when name has an env: or file: prefix.
+func getSecret(name string) (string, error) {
+	storage := map[string]secrets.SecretStorage{
+		"env":       secrets.NewEnvSecretProviderFromConfig(secrets.GenericConfig{}),
+		"file":      secrets.NewFileSecretProviderFromConfig(secrets.FileConfig{}),
+		"plaintext": secrets.NewPlainSecretProviderFromConfig(secrets.GenericConfig{}),
+	}
+	return secrets.GetSecret(name, storage)
 }
 
 func httpTransportFromOptions(opts ServerOptions) *http.Transport {
@@ -241,174 +251,25 @@ func httpTransportFromOptions(opts ServerOptions) *http.Transport {
 	return transport
 }
 
-func syncWithServer(k8s *kubernetes.Kubernetes, client *api.Client, destination *api.Destination, certCache *CertCache, caCertPEM []byte) func(context.Context) {
-
-	return func(context.Context) {
-		host, port, err := k8s.Endpoint()
-		if err != nil {
-			logging.Errorf("failed to lookup endpoint: %v", err)
-			return
-		}
-
-		if ipv4 := net.ParseIP(host); ipv4 == nil {
-			// wait for DNS resolution if endpoint is not an IPv4 address
-			if _, err := net.LookupIP(host); err != nil {
-				logging.Errorf("host could not be resolved")
-				return
-			}
-		}
-
-		// update certificates if the host changed
-		_, err = certCache.AddHost(host)
-		if err != nil {
-			logging.Errorf("could not update self-signed certificates")
-			return
-		}
-
-		endpoint := fmt.Sprintf("%s:%d", host, port)
-		logging.Debugf("connector serving on %s", endpoint)
-
-		namespaces, err := k8s.Namespaces()
-		if err != nil {
-			logging.Errorf("could not get kubernetes namespaces: %v", err)
-			return
-		}
-
-		clusterRoles, err := k8s.ClusterRoles()
-		if err != nil {
-			logging.Errorf("could not get kubernetes cluster-roles: %v", err)
-			return
-		}
-
-		switch {
-		case destination.ID == 0:
-			isClusterIP, err := k8s.IsServiceTypeClusterIP()
-			if err != nil {
-				logging.Debugf("could not determine service type: %v", err)
-			}
-
-			if isClusterIP {
-				logging.Warnf("registering Kubernetes connector with ClusterIP. it may not be externally accessible. if you are experiencing connectivity issues, consider switching to LoadBalancer or Ingress")
-			}
-
-			fallthrough
-
-		case !slicesEqual(destination.Resources, namespaces):
-			destination.Resources = namespaces
-			fallthrough
-
-		case !slicesEqual(destination.Roles, clusterRoles):
-			destination.Roles = clusterRoles
-			fallthrough
-
-		case !bytes.Equal([]byte(destination.Connection.CA), caCertPEM):
-			destination.Connection.CA = api.PEM(caCertPEM)
-			fallthrough
-
-		case destination.Connection.URL != endpoint:
-			destination.Connection.URL = endpoint
-
-			if err := createOrUpdateDestination(client, destination); err != nil {
-				logging.Errorf("initializing destination: %v", err)
-				return
-			}
-		}
-
-		grants, err := client.ListGrants(api.ListGrantsRequest{Resource: destination.Name})
-		if err != nil {
-			logging.Errorf("error listing grants: %v", err)
-			return
-		}
-
-		// TODO(https://github.com/infrahq/infra/issues/2422): support wildcard resource searches
-		for _, n := range namespaces {
-			g, err := client.ListGrants(api.ListGrantsRequest{Resource: fmt.Sprintf("%s.%s", destination.Name, n)})
-			if err != nil {
-				logging.Errorf("error listing grants: %v", err)
-				return
-			}
-
-			grants.Items = append(grants.Items, g.Items...)
+// listAll calls listItems for each page of results, and returns the items
+// from all the pages.
+func listAll[Item any, Req api.Paginatable](listItems func(Req) (*api.ListResponse[Item], error), req Req) ([]Item, error) {
+	var items []Item
+	for page := 1; ; page++ {
+		req, ok := req.SetPage(page).(Req)
+		if !ok {
+			panic("SetPage returned a different request type than expected")
 		}
 
-		err = updateRoles(client, k8s, grants.Items)
+		resp, err := listItems(req)
 		if err != nil {
-			logging.Errorf("error updating grants: %v", err)
-			return
+			return nil, err
 		}
-	}
-}
-
-// UpdateRoles converts infra grants to role-bindings in the current cluster
-func updateRoles(c *api.Client, k *kubernetes.Kubernetes, grants []api.Grant) error {
-	logging.Debugf("syncing local grants from infra configuration")
-
-	crSubjects := make(map[string][]rbacv1.Subject)                           // cluster-role: subject
-	crnSubjects := make(map[kubernetes.ClusterRoleNamespace][]rbacv1.Subject) // cluster-role+namespace: subject
-
-	for _, g := range grants {
-		var name, kind string
-
-		if g.Privilege == "connect" {
-			continue
+		items = append(items, resp.Items...)
+		if page >= resp.TotalPages {
+			return items, nil
 		}
-
-		switch {
-		case g.Group != 0:
-			group, err := c.GetGroup(g.Group)
-			if err != nil {
-				return err
-			}
-
-			name = group.Name
-			kind = rbacv1.GroupKind
-		case g.User != 0:
-			user, err := c.GetUser(g.User)
-			if err != nil {
-				return err
-			}
-
-			name = user.Name
-			kind = rbacv1.UserKind
-		}
-
-		subj := rbacv1.Subject{
-			APIGroup: "rbac.authorization.k8s.io",
-			Kind:     kind,
-			Name:     name,
-		}
-
-		parts := strings.Split(g.Resource, ".")
-
-		var crn kubernetes.ClusterRoleNamespace
-
-		switch len(parts) {
-		// <cluster>
-		case 1:
-			crn.ClusterRole = g.Privilege
-			crSubjects[g.Privilege] = append(crSubjects[g.Privilege], subj)
-
-		// <cluster>.<namespace>
-		case 2:
-			crn.ClusterRole = g.Privilege
-			crn.Namespace = parts[1]
-			crnSubjects[crn] = append(crnSubjects[crn], subj)
-
-		default:
-			logging.Warnf("invalid grant resource: %s", g.Resource)
-			continue
-		}
-	}
-
-	if err := k.UpdateClusterRoleBindings(crSubjects); err != nil {
-		return fmt.Errorf("update cluster role bindings: %w", err)
 	}
-
-	if err := k.UpdateRoleBindings(crnSubjects); err != nil {
-		return fmt.Errorf("update cluster role bindings: %w", err)
-	}
-
-	return nil
 }
 
 // createOrUpdateDestination creates a destination in the infra server if it does not exist and updates it if it does
@@ -0,0 +1,90 @@
+package connector
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/claims"
+)
+
+// Driver connects the connector to a kind of destination. The connector
+// registers the destination with the infra server, keeps the access to the
+// destination up to date with its grants, and proxies authenticated requests
+// from users. The driver does the parts that are specific to the kind of
+// destination.
+//
+// Methods other than Proxy and Health are called from a single goroutine.
+type Driver interface {
+	// UniqueID returns an ID that identifies the destination, even if the
+	// connector is restarted.
+	UniqueID() (string, error)
+	// DefaultName returns the name of the destination when the connector is
+	// not configured with a name.
+	DefaultName() (string, error)
+	// Endpoint returns the host and port that users connect to.
+	Endpoint() (host string, port int, err error)
+
+	// Resources returns the names of the resources of the destination that
+	// grants can refer to, for example the namespaces of a cluster.
+	Resources() ([]string, error)
+	// UpdateRoles updates the custom roles of the destination, and returns
+	// the names of all the roles that can be granted, including the custom
+	// roles.
+	UpdateRoles(roles []api.Role) ([]string, error)
+	// ApplyGrants updates the destination so that the grants are the only
+	// access that infra has granted to the destination. The grants are not
+	// expired, and apply to the destination.
+	ApplyGrants(grants []api.DestinationGrant) error
+
+	// Proxy forwards a request from the authenticated user to the
+	// destination.
+	Proxy(w http.ResponseWriter, req *http.Request, user claims.Custom)
+	// Health returns an error if the destination can not be reached. The
+	// connector is not ready while Health returns an error.
+	Health() error
+}
+
+// sshUserCAInstaller is implemented by drivers of destinations that users log
+// in to with an SSH certificate signed by the server.
+type sshUserCAInstaller interface {
+	// InstallSSHUserCA configures the destination to trust the certificates
+	// signed by the CA with publicKey.
+	InstallSSHUserCA(publicKey string) error
+}
+
+// connServer is implemented by drivers of destinations that users connect to
+// with a protocol other than HTTPS.
+type connServer interface {
+	// Serve accepts connections from users until ctx is cancelled. Users
+	// authenticate with a destination token that is validated by authn.
+	Serve(ctx context.Context, authn *authenticator, tlsConfig *tls.Config) error
+}
+
+const (
+	DriverKindKubernetes = "kubernetes"
+	DriverKindSSH        = "ssh"
+	DriverKindPostgres   = "postgres"
+)
+
+// drivers creates the driver for each kind of destination.
+var drivers = map[string]func(options Options) (Driver, error){
+	DriverKindKubernetes: newKubernetesDriver,
+	DriverKindSSH:        newSSHDriver,
+	DriverKindPostgres:   newPostgresDriver,
+}
+
+func newDriver(options Options) (Driver, error) {
+	kind := options.Kind
+	if kind == "" {
+		kind = DriverKindKubernetes
+	}
+
+	newDriver, ok := drivers[kind]
+	if !ok {
+		return nil, fmt.Errorf("unsupported destination kind %q", kind)
+	}
+	return newDriver(options)
+}
@@ -0,0 +1,251 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/square/go-jose.v2"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/claims"
+	"github.com/infrahq/infra/uid"
+)
+
+// fakeDriver is a Driver for tests that records the grants applied to it.
+type fakeDriver struct {
+	mu      sync.Mutex
+	applied chan []api.DestinationGrant
+	proxied []claims.Custom
+	health  error
+}
+
+func newFakeDriver() *fakeDriver {
+	return &fakeDriver{applied: make(chan []api.DestinationGrant, 10)}
+}
+
+func (d *fakeDriver) UniqueID() (string, error) {
+	return "fake-unique-id", nil
+}
+
+func (d *fakeDriver) DefaultName() (string, error) {
+	return "fake", nil
+}
+
+func (d *fakeDriver) Endpoint() (string, int, error) {
+	return "127.0.0.1", 8443, nil
+}
+
+func (d *fakeDriver) Resources() ([]string, error) {
+	return []string{"default"}, nil
+}
+
+func (d *fakeDriver) UpdateRoles(roles []api.Role) ([]string, error) {
+	names := []string{"view"}
+	for _, role := range roles {
+		names = append(names, role.Name)
+	}
+	return names, nil
+}
+
+func (d *fakeDriver) ApplyGrants(grants []api.DestinationGrant) error {
+	sort.Slice(grants, func(i, j int) bool {
+		return grants[i].ID < grants[j].ID
+	})
+	d.applied <- grants
+	return nil
+}
+
+func (d *fakeDriver) Proxy(w http.ResponseWriter, _ *http.Request, user claims.Custom) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.proxied = append(d.proxied, user)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (d *fakeDriver) Health() error {
+	return d.health
+}
+
+// fakeInfraServer serves the parts of the infra API used by the connector.
+type fakeInfraServer struct {
+	destinationID uid.ID
+	groupID       uid.ID
+	grants        []api.DestinationGrant
+	events        chan api.DestinationEvent
+	done          chan struct{}
+}
+
+func (f *fakeInfraServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	destinationPath := "/api/destinations/" + f.destinationID.String()
+
+	var body interface{}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/destinations":
+		body = api.ListResponse[api.Destination]{}
+	case r.Method == http.MethodPost && r.URL.Path == "/api/destinations":
+		w.WriteHeader(http.StatusCreated)
+		body = api.Destination{ID: f.destinationID}
+	case r.Method == http.MethodPut && r.URL.Path == destinationPath:
+		body = api.Destination{ID: f.destinationID}
+	case r.URL.Path == "/api/roles":
+		body = api.ListResponse[api.Role]{PaginationResponse: api.PaginationResponse{TotalPages: 1}}
+	case r.URL.Path == destinationPath+"/grants":
+		body = api.ListDestinationGrantsResponse{Items: f.grants}
+	case r.URL.Path == "/api/groups/"+f.groupID.String():
+		body = api.Group{ID: f.groupID, Name: "developers"}
+	case r.URL.Path == destinationPath+"/events":
+		body = f.listEvents(r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		body = api.Error{Code: http.StatusNotFound}
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func (f *fakeInfraServer) listEvents(r *http.Request) api.ListDestinationEventsResponse {
+	cursor := r.URL.Query().Get("cursor")
+	if cursor == "" {
+		return api.ListDestinationEventsResponse{Cursor: "0", Resync: true}
+	}
+
+	var wait api.Duration
+	_ = wait.UnmarshalText([]byte(r.URL.Query().Get("wait")))
+	select {
+	case event := <-f.events:
+		return api.ListDestinationEventsResponse{
+			Items:  []api.DestinationEvent{event},
+			Cursor: cursor + "+",
+		}
+	case <-time.After(time.Duration(wait)):
+	case <-f.done:
+	}
+	return api.ListDestinationEventsResponse{Cursor: cursor}
+}
+
+func TestRun_FakeDriver(t *testing.T) {
+	userGrant := api.Grant{ID: 1, User: 10, Privilege: "view", Resource: "fake"}
+	infra := &fakeInfraServer{
+		destinationID: 7,
+		groupID:       20,
+		grants: []api.DestinationGrant{
+			{Grant: userGrant, SubjectKind: "user", SubjectName: "alice@example.com"},
+		},
+		events: make(chan api.DestinationEvent),
+		done:   make(chan struct{}),
+	}
+	srv := httptest.NewTLSServer(infra)
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(infra.done) })
+
+	driver := newFakeDriver()
+	options := Options{
+		Server: ServerOptions{
+			URL:           srv.URL,
+			AccessKey:     "plaintext:the-access-key",
+			SkipTLSVerify: true,
+		},
+		CACert: "./_testdata/test-ca-cert.pem",
+		CAKey:  "./_testdata/test-ca-key.pem",
+		Addr:   ListenerOptions{HTTPS: "127.0.0.1:0", Metrics: "127.0.0.1:0"},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- run(ctx, options, driver)
+	}()
+
+	nextApplied := func(t *testing.T) []api.DestinationGrant {
+		t.Helper()
+		select {
+		case grants := <-driver.applied:
+			return grants
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for grants to be applied")
+			return nil
+		}
+	}
+
+	assert.DeepEqual(t, nextApplied(t), infra.grants)
+
+	groupGrant := api.Grant{ID: 2, Group: 20, Privilege: "edit", Resource: "fake.default"}
+	infra.events <- api.DestinationEvent{Type: api.DestinationEventGrantCreated, Grant: &groupGrant}
+	assert.DeepEqual(t, nextApplied(t), []api.DestinationGrant{
+		infra.grants[0],
+		{Grant: groupGrant, SubjectKind: "group", SubjectName: "developers"},
+	})
+
+	infra.events <- api.DestinationEvent{Type: api.DestinationEventGrantDeleted, Grant: &userGrant}
+	assert.DeepEqual(t, nextApplied(t), []api.DestinationGrant{
+		{Grant: groupGrant, SubjectKind: "group", SubjectName: "developers"},
+	})
+
+	cancel()
+	select {
+	case err := <-runErr:
+		assert.NilError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the connector to stop")
+	}
+}
+
+func TestNewRouter(t *testing.T) {
+	pub, priv := generateJWK(t)
+	authn := newAuthenticator("https://127.0.0.1:12345", Options{}, []string{"fake"})
+	authn.client = &fakeClient{keys: []jose.JSONWebKey{*pub}}
+
+	driver := newFakeDriver()
+	router := newRouter(driver, authn, prometheus.NewRegistry())
+
+	t.Run("ready", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		assert.Equal(t, resp.Code, http.StatusOK)
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+		driver.health = errors.New("destination is down")
+		defer func() { driver.health = nil }()
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		assert.Equal(t, resp.Code, http.StatusServiceUnavailable)
+
+		// the connector is still live
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		assert.Equal(t, resp.Code, http.StatusOK)
+	})
+
+	t.Run("proxy authenticated request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/pods", nil)
+		req.Header.Set("Authorization", "Bearer "+generateJWT(t, priv, "alice@example.com", time.Now().Add(time.Minute), "fake"))
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, resp.Code, http.StatusOK)
+		assert.DeepEqual(t, driver.proxied, []claims.Custom{
+			{Name: "alice@example.com", Groups: []string{"developers"}},
+		})
+	})
+
+	t.Run("unauthenticated request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/pods", nil)
+		req.Header.Set("Authorization", "Bearer "+strings.Repeat("a", 10))
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, resp.Code, http.StatusUnauthorized)
+		assert.Equal(t, len(driver.proxied), 1)
+	})
+}
@@ -0,0 +1,284 @@
+package connector
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+
+	"github.com/goware/urlx"
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/claims"
+	"github.com/infrahq/infra/internal/kubernetes"
+	"github.com/infrahq/infra/internal/logging"
+)
+
+// kubernetesDriver is the Driver for a Kubernetes cluster. Grants are applied
+// as role-bindings and cluster-role-bindings, and requests are proxied to the
+// API server by impersonating the user.
+type kubernetesDriver struct {
+	k8s   *kubernetes.Kubernetes
+	proxy *httputil.ReverseProxy
+	// name is the name of the destination, used to match grant resources
+	name string
+
+	// namespaces from the last call to Resources
+	namespaces []string
+	// customRoles from the last call to UpdateRoles
+	customRoles map[string][]rbacv1.PolicyRule
+	// warnedClusterIP is true once the warning about a ClusterIP service has
+	// been logged
+	warnedClusterIP bool
+}
+
+func newKubernetesDriver(options Options) (Driver, error) {
+	k8s, err := kubernetes.NewKubernetes()
+	if err != nil {
+		return nil, err
+	}
+
+	proxyHost, err := urlx.Parse(k8s.Config.Host)
+	if err != nil {
+		return nil, fmt.Errorf("parsing host config: %w", err)
+	}
+
+	clusterCACert, err := kubernetes.CA()
+	if err != nil {
+		return nil, fmt.Errorf("reading CA file: %w", err)
+	}
+
+	certPool := x509.NewCertPool()
+
+	if ok := certPool.AppendCertsFromPEM(clusterCACert); !ok {
+		return nil, errors.New("could not append CA to client cert bundle")
+	}
+
+	// clone the default http transport which sets reasonable defaults
+	defaultHTTPTransport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("unexpected type for http.DefaultTransport")
+	}
+
+	proxyTransport := defaultHTTPTransport.Clone()
+	proxyTransport.ForceAttemptHTTP2 = false
+	proxyTransport.TLSClientConfig = &tls.Config{
+		RootCAs:    certPool,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(proxyHost)
+	proxy.Transport = proxyTransport
+
+	return &kubernetesDriver{k8s: k8s, proxy: proxy, name: options.Name}, nil
+}
+
+func (d *kubernetesDriver) UniqueID() (string, error) {
+	chksm, err := d.k8s.Checksum()
+	if err != nil {
+		return "", fmt.Errorf("k8s checksum error: %w", err)
+	}
+	return chksm, nil
+}
+
+func (d *kubernetesDriver) DefaultName() (string, error) {
+	chksm, err := d.UniqueID()
+	if err != nil {
+		return "", err
+	}
+
+	name, err := d.k8s.Name(chksm)
+	if err != nil {
+		return "", fmt.Errorf("k8s name error: %w", err)
+	}
+	d.name = name
+	return name, nil
+}
+
+func (d *kubernetesDriver) Endpoint() (string, int, error) {
+	host, port, err := d.k8s.Endpoint()
+	if err != nil {
+		return "", 0, err
+	}
+
+	if !d.warnedClusterIP {
+		isClusterIP, err := d.k8s.IsServiceTypeClusterIP()
+		if err != nil {
+			logging.Debugf("could not determine service type: %v", err)
+		}
+
+		if isClusterIP {
+			logging.Warnf("registering Kubernetes connector with ClusterIP. it may not be externally accessible. if you are experiencing connectivity issues, consider switching to LoadBalancer or Ingress")
+		}
+		d.warnedClusterIP = true
+	}
+	return host, port, nil
+}
+
+func (d *kubernetesDriver) Resources() ([]string, error) {
+	namespaces, err := d.k8s.Namespaces()
+	if err != nil {
+		return nil, fmt.Errorf("could not get kubernetes namespaces: %w", err)
+	}
+	d.namespaces = namespaces
+	return namespaces, nil
+}
+
+func (d *kubernetesDriver) UpdateRoles(roles []api.Role) ([]string, error) {
+	customRoles := customClusterRoles(roles)
+	if err := d.k8s.UpdateClusterRoles(customRoles); err != nil {
+		return nil, fmt.Errorf("could not update kubernetes cluster-roles: %w", err)
+	}
+	d.customRoles = customRoles
+
+	clusterRoles, err := d.k8s.ClusterRoles()
+	if err != nil {
+		return nil, fmt.Errorf("could not get kubernetes cluster-roles: %w", err)
+	}
+
+	// custom roles are reported by name, grants use the name of the role
+	// and are bound to the cluster-role created for the role.
+	for _, role := range roles {
+		clusterRoles = append(clusterRoles, role.Name)
+	}
+	return clusterRoles, nil
+}
+
+// ApplyGrants converts infra grants to role-bindings in the current cluster.
+func (d *kubernetesDriver) ApplyGrants(grants []api.DestinationGrant) error {
+	logging.Debugf("syncing local grants from infra configuration")
+
+	crSubjects := make(map[string][]rbacv1.Subject)                           // cluster-role: subject
+	crnSubjects := make(map[kubernetes.ClusterRoleNamespace][]rbacv1.Subject) // cluster-role+namespace: subject
+
+	for _, g := range grants {
+		cluster, grantedNamespaces := grantNamespaces(g.Resource, d.name, d.namespaces)
+		if !cluster && len(grantedNamespaces) == 0 {
+			continue
+		}
+
+		kind := rbacv1.UserKind
+		if g.SubjectKind == api.DestinationGrantSubjectGroup {
+			kind = rbacv1.GroupKind
+		}
+
+		subj := rbacv1.Subject{
+			APIGroup: "rbac.authorization.k8s.io",
+			Kind:     kind,
+			Name:     g.SubjectName,
+		}
+
+		clusterRole := g.Privilege
+		if _, ok := d.customRoles[g.Privilege]; ok {
+			clusterRole = kubernetes.CustomClusterRoleName(g.Privilege)
+		}
+
+		if cluster {
+			crSubjects[clusterRole] = append(crSubjects[clusterRole], subj)
+		}
+
+		for _, namespace := range grantedNamespaces {
+			crn := kubernetes.ClusterRoleNamespace{ClusterRole: clusterRole, Namespace: namespace}
+			crnSubjects[crn] = append(crnSubjects[crn], subj)
+		}
+	}
+
+	if err := d.k8s.UpdateClusterRoleBindings(crSubjects); err != nil {
+		return fmt.Errorf("update cluster role bindings: %w", err)
+	}
+
+	if err := d.k8s.UpdateRoleBindings(crnSubjects); err != nil {
+		return fmt.Errorf("update cluster role bindings: %w", err)
+	}
+
+	return nil
+}
+
+// Proxy forwards the request to the API server, impersonating the user and
+// their groups.
+func (d *kubernetesDriver) Proxy(w http.ResponseWriter, req *http.Request, user claims.Custom) {
+	req.Header.Set("Impersonate-User", user.Name)
+	for _, g := range user.Groups {
+		req.Header.Add("Impersonate-Group", g)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", d.k8s.Config.BearerToken))
+	d.proxy.ServeHTTP(w, req)
+}
+
+func (d *kubernetesDriver) Health() error {
+	_, err := d.k8s.ServerVersion()
+	return err
+}
+
+// localServerHost returns the host of the infra server service in the
+// cluster, for connectors that are configured with a localhost server URL.
+func (d *kubernetesDriver) localServerHost(host string) string {
+	server, err := d.k8s.Service("server")
+	if err != nil {
+		logging.Warnf("no cluster-local infra server found for %q. check connector configurations", host)
+		return host
+	}
+
+	clusterHost := fmt.Sprintf("%s.%s", server.ObjectMeta.Name, server.ObjectMeta.Namespace)
+	logging.Debugf("using cluster-local infra server at %q instead of %q", clusterHost, host)
+	return clusterHost
+}
+
+// customClusterRoles converts infra roles to the rules of the cluster-roles
+// that are created for them, keyed by the name of the role.
+func customClusterRoles(roles []api.Role) map[string][]rbacv1.PolicyRule {
+	result := make(map[string][]rbacv1.PolicyRule, len(roles))
+	for _, role := range roles {
+		rules := make([]rbacv1.PolicyRule, 0, len(role.Rules))
+		for _, r := range role.Rules {
+			rules = append(rules, rbacv1.PolicyRule{
+				APIGroups:       r.APIGroups,
+				Resources:       r.Resources,
+				ResourceNames:   r.ResourceNames,
+				NonResourceURLs: r.NonResourceURLs,
+				Verbs:           r.Verbs,
+			})
+		}
+		result[role.Name] = rules
+	}
+	return result
+}
+
+// grantNamespaces returns the namespaces that a grant applies to in the
+// cluster named destinationName. It returns cluster true if the grant applies
+// to the whole cluster. Grants with a wildcard resource apply to every
+// namespace that matches the resource.
+func grantNamespaces(resource, destinationName string, namespaces []string) (cluster bool, result []string) {
+	parts := strings.Split(resource, ".")
+	if !api.MatchResource(parts[0], destinationName) {
+		return false, nil
+	}
+
+	switch len(parts) {
+	// <cluster>
+	case 1:
+		return true, nil
+
+	// <cluster>.<namespace>
+	case 2:
+		if !api.IsResourcePattern(parts[1]) {
+			return false, []string{parts[1]}
+		}
+
+		for _, n := range namespaces {
+			if api.MatchResource(parts[1], n) {
+				result = append(result, n)
+			}
+		}
+		return false, result
+
+	default:
+		logging.Warnf("invalid grant resource: %s", resource)
+		return false, nil
+	}
+}
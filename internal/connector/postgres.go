@@ -0,0 +1,515 @@
+package connector
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/claims"
+	"github.com/infrahq/infra/internal/logging"
+)
+
+type PostgresOptions struct {
+	// URL is the connection URL of the PostgreSQL server, with the user that
+	// the connector uses to list databases and check the health of the
+	// server. For example postgres://infra@db.example.com:5432/postgres
+	URL string
+	// Password is the password of the user in URL. Use an env: or file:
+	// prefix to read it from an environment variable or a file.
+	Password string
+	// Roles are the database roles that can be granted, and the password
+	// the connector uses to log in as each of them. Passwords can also use
+	// an env: or file: prefix.
+	Roles map[string]string
+	// Host is the host that users connect to. Defaults to the hostname.
+	Host string
+	// Port is the port that the connector accepts connections from users
+	// on. Defaults to 5432.
+	Port int
+}
+
+// postgresTimeout limits the time to connect to the server, and the time
+// that users have to authenticate.
+const postgresTimeout = 30 * time.Second
+
+// postgresDriver is the Driver for a PostgreSQL server. Users connect to the
+// connector with a destination token as their password, and the connector
+// logs in to the server as the role that the user was granted. The session
+// user is the granted role, so users can not switch to a role that was not
+// granted to them with RESET ROLE or SET ROLE.
+//
+// The privilege of a grant for the destination is a database role. Grants
+// for the destination apply to all databases, and grants for a sub-resource
+// apply to the database with that name.
+type postgresDriver struct {
+	config *pgconn.Config
+	host   string
+	port   int
+	// name is the name of the destination, used to match grant resources
+	name string
+	// passwords are the passwords of the roles that can be granted
+	passwords map[string]string
+
+	mu     sync.Mutex
+	grants []postgresGrant
+}
+
+// postgresGrant allows a user or group to use a role in the databases that
+// match database. Grants with no database apply to all databases.
+type postgresGrant struct {
+	subjectKind string
+	subjectName string
+	role        string
+	database    string
+}
+
+func newPostgresDriver(options Options) (Driver, error) {
+	opts := options.Postgres
+	if opts.URL == "" {
+		return nil, errors.New("postgres.url is required for postgres destinations")
+	}
+
+	config, err := pgconn.ParseConfig(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid postgres url: %w", err)
+	}
+	if opts.Password != "" {
+		config.Password, err = getSecret(opts.Password)
+		if err != nil {
+			return nil, fmt.Errorf("postgres password: %w", err)
+		}
+	}
+
+	passwords := make(map[string]string, len(opts.Roles))
+	for role, password := range opts.Roles {
+		passwords[role], err = getSecret(password)
+		if err != nil {
+			return nil, fmt.Errorf("password of role %v: %w", role, err)
+		}
+	}
+
+	d := &postgresDriver{
+		config:    config,
+		host:      opts.Host,
+		port:      opts.Port,
+		name:      options.Name,
+		passwords: passwords,
+	}
+	if d.port == 0 {
+		d.port = 5432
+	}
+	return d, nil
+}
+
+// UniqueID returns a checksum of the address of the server.
+func (d *postgresDriver) UniqueID() (string, error) {
+	h := sha256.Sum256([]byte(d.serverAddr()))
+	return hex.EncodeToString(h[:]), nil
+}
+
+// DefaultName returns the host of the server. Dots are replaced, because
+// they separate the destination from the database in grant resources.
+func (d *postgresDriver) DefaultName() (string, error) {
+	d.name = strings.ReplaceAll(d.config.Host, ".", "-")
+	return d.name, nil
+}
+
+func (d *postgresDriver) Endpoint() (string, int, error) {
+	if d.host != "" {
+		return d.host, d.port, nil
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return "", 0, err
+	}
+	return host, d.port, nil
+}
+
+func (d *postgresDriver) serverAddr() string {
+	return net.JoinHostPort(d.config.Host, strconv.Itoa(int(d.config.Port)))
+}
+
+// Resources returns the databases that accept connections.
+func (d *postgresDriver) Resources() ([]string, error) {
+	return d.queryNames(`SELECT datname FROM pg_database
+		WHERE datallowconn AND NOT datistemplate ORDER BY datname`)
+}
+
+// UpdateRoles ignores the custom roles of the server. The roles that can be
+// granted are the roles that the connector has a password for.
+func (d *postgresDriver) UpdateRoles([]api.Role) ([]string, error) {
+	roles := make([]string, 0, len(d.passwords))
+	for role := range d.passwords {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles, nil
+}
+
+// queryNames returns the first column of the rows returned by query.
+func (d *postgresDriver) queryNames(query string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresTimeout)
+	defer cancel()
+
+	conn, err := d.connect(ctx, "", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	result := conn.ExecParams(ctx, query, nil, nil, nil, nil).Read()
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	names := make([]string, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		names = append(names, string(row[0]))
+	}
+	return names, nil
+}
+
+// connect connects to database as role, or as the user of the connector when
+// role is empty. The default database of the connector is used when database
+// is empty.
+func (d *postgresDriver) connect(ctx context.Context, database, role string, params map[string]string) (*pgconn.PgConn, error) {
+	config := d.config.Copy()
+	if database != "" {
+		config.Database = database
+	}
+	if role != "" {
+		config.User = role
+		config.Password = d.passwords[role]
+	}
+	if config.RuntimeParams == nil {
+		config.RuntimeParams = make(map[string]string, len(params))
+	}
+	for k, v := range params {
+		config.RuntimeParams[k] = v
+	}
+	return pgconn.ConnectConfig(ctx, config)
+}
+
+// ApplyGrants replaces the grants that are used to select the role of users
+// when they connect. Existing connections keep the role they were given.
+func (d *postgresDriver) ApplyGrants(grants []api.DestinationGrant) error {
+	pgGrants := make([]postgresGrant, 0, len(grants))
+	for _, g := range grants {
+		destination, database, _ := strings.Cut(g.Resource, ".")
+		if !api.MatchResource(destination, d.name) {
+			continue
+		}
+		if _, ok := d.passwords[g.Privilege]; !ok {
+			logging.Warnf("ignoring grant %v, the connector has no password for role %q", g.ID, g.Privilege)
+			continue
+		}
+		pgGrants = append(pgGrants, postgresGrant{
+			subjectKind: g.SubjectKind,
+			subjectName: g.SubjectName,
+			role:        g.Privilege,
+			database:    database,
+		})
+	}
+
+	d.mu.Lock()
+	d.grants = pgGrants
+	d.mu.Unlock()
+	return nil
+}
+
+// roles returns the sorted roles that user was granted for database.
+func (d *postgresDriver) roles(user claims.Custom, database string) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	groups := make(map[string]bool, len(user.Groups))
+	for _, group := range user.Groups {
+		groups[group] = true
+	}
+
+	seen := make(map[string]bool)
+	var roles []string
+	for _, g := range d.grants {
+		switch {
+		case g.subjectKind == api.DestinationGrantSubjectGroup && groups[g.subjectName]:
+		case g.subjectKind != api.DestinationGrantSubjectGroup && g.subjectName == user.Name:
+		default:
+			continue
+		}
+		if g.database != "" && !api.MatchResource(g.database, database) {
+			continue
+		}
+		if !seen[g.role] {
+			seen[g.role] = true
+			roles = append(roles, g.role)
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// Proxy responds with an error, users connect with the PostgreSQL protocol.
+func (d *postgresDriver) Proxy(w http.ResponseWriter, _ *http.Request, _ claims.Custom) {
+	http.Error(w, "postgres destinations do not accept HTTP requests", http.StatusNotFound)
+}
+
+// Health checks that the connector can connect to the server.
+func (d *postgresDriver) Health() error {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresTimeout)
+	defer cancel()
+
+	conn, err := d.connect(ctx, "", "", nil)
+	if err != nil {
+		return err
+	}
+	return conn.Close(ctx)
+}
+
+// Serve accepts connections from users on the port of the destination.
+func (d *postgresDriver) Serve(ctx context.Context, authn *authenticator, tlsConfig *tls.Config) error {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", d.port))
+	if err != nil {
+		return err
+	}
+	logging.Infof("accepting postgres connections on %v", l.Addr())
+	return d.serve(ctx, l, authn, tlsConfig)
+}
+
+func (d *postgresDriver) serve(ctx context.Context, l net.Listener, authn *authenticator, tlsConfig *tls.Config) error {
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		go func() {
+			if err := d.handleConn(ctx, conn, authn, tlsConfig); err != nil {
+				logging.L.Info().Err(err).Str("remote", conn.RemoteAddr().String()).Msg("postgres connection failed")
+			}
+		}()
+	}
+}
+
+// handleConn authenticates the user of conn, connects to the server with the
+// role granted to the user, and then relays the connection to the server.
+func (d *postgresDriver) handleConn(ctx context.Context, conn net.Conn, authn *authenticator, tlsConfig *tls.Config) error {
+	defer func() {
+		_ = conn.Close()
+	}()
+	if err := conn.SetDeadline(time.Now().Add(postgresTimeout)); err != nil {
+		return err
+	}
+
+	backend := pgproto3.NewBackend(pgproto3.NewChunkReader(conn), conn)
+	var startup *pgproto3.StartupMessage
+	for startup == nil {
+		msg, err := backend.ReceiveStartupMessage()
+		if err != nil {
+			return err
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.SSLRequest:
+			if _, err := conn.Write([]byte{'S'}); err != nil {
+				return err
+			}
+			tlsConn := tls.Server(conn, tlsConfig)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				return err
+			}
+			conn = tlsConn
+			backend = pgproto3.NewBackend(pgproto3.NewChunkReader(conn), conn)
+		case *pgproto3.GSSEncRequest:
+			if _, err := conn.Write([]byte{'N'}); err != nil {
+				return err
+			}
+		case *pgproto3.CancelRequest:
+			return d.cancel(ctx, msg)
+		case *pgproto3.StartupMessage:
+			startup = msg
+		}
+	}
+
+	// tokens must not be sent in plaintext
+	if _, ok := conn.(*tls.Conn); !ok {
+		sendFatal(backend, "28000", "the infra connector requires an SSL connection")
+		return errors.New("client did not request SSL")
+	}
+	if _, ok := startup.Parameters["replication"]; ok {
+		sendFatal(backend, "0A000", "replication connections are not supported by the infra connector")
+		return errors.New("client requested a replication connection")
+	}
+
+	if err := backend.Send(&pgproto3.AuthenticationCleartextPassword{}); err != nil {
+		return err
+	}
+	if err := backend.SetAuthType(pgproto3.AuthTypeCleartextPassword); err != nil {
+		return err
+	}
+	msg, err := backend.Receive()
+	if err != nil {
+		return err
+	}
+	password, ok := msg.(*pgproto3.PasswordMessage)
+	if !ok {
+		return fmt.Errorf("expected a password message, received %T", msg)
+	}
+
+	user, err := authn.authenticateToken(password.Password)
+	if err != nil {
+		sendFatal(backend, "28P01", "token authentication failed; run 'infra db connect' to connect with a token")
+		return fmt.Errorf("authenticate: %w", err)
+	}
+
+	database := startup.Parameters["database"]
+	role, err := selectRole(startup.Parameters["user"], user.Name, database, d.roles(user, database))
+	if err != nil {
+		sendFatal(backend, "42501", err.Error())
+		return fmt.Errorf("%v: %w", user.Name, err)
+	}
+
+	params := make(map[string]string, len(startup.Parameters))
+	for k, v := range startup.Parameters {
+		if k != "user" && k != "database" {
+			params[k] = v
+		}
+	}
+
+	server, err := d.connectAs(ctx, database, role, params)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			_ = backend.Send(&pgproto3.ErrorResponse{Severity: pgErr.Severity, Code: pgErr.Code, Message: pgErr.Message})
+		} else {
+			sendFatal(backend, "08006", "the infra connector failed to connect to the database")
+		}
+		return err
+	}
+	defer func() {
+		_ = server.Conn.Close()
+	}()
+
+	// the client receives the parameters and key of the server connection, so
+	// that cancel requests can be forwarded to the server.
+	var buf []byte
+	buf = (&pgproto3.AuthenticationOk{}).Encode(buf)
+	for _, name := range sortedKeys(server.ParameterStatuses) {
+		buf = (&pgproto3.ParameterStatus{Name: name, Value: server.ParameterStatuses[name]}).Encode(buf)
+	}
+	buf = (&pgproto3.BackendKeyData{ProcessID: server.PID, SecretKey: server.SecretKey}).Encode(buf)
+	buf = (&pgproto3.ReadyForQuery{TxStatus: server.TxStatus}).Encode(buf)
+	if _, err := conn.Write(buf); err != nil {
+		return err
+	}
+
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return err
+	}
+	logging.Infof("%v connected to database %q as role %v", user.Name, database, role)
+	relay(conn, server.Conn)
+	return nil
+}
+
+// connectAs logs in to database as role. It returns the connection after the
+// server is ready for the first query.
+func (d *postgresDriver) connectAs(ctx context.Context, database, role string, params map[string]string) (*pgconn.HijackedConn, error) {
+	ctx, cancel := context.WithTimeout(ctx, postgresTimeout)
+	defer cancel()
+
+	conn, err := d.connect(ctx, database, role, params)
+	if err != nil {
+		return nil, err
+	}
+	return conn.Hijack()
+}
+
+// cancel forwards a request to cancel a query to the server. The request
+// includes the secret key of the server connection, which is only known to
+// the client of that connection.
+func (d *postgresDriver) cancel(ctx context.Context, req *pgproto3.CancelRequest) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", d.serverAddr())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write(req.Encode(nil))
+	return err
+}
+
+// selectRole returns the role that the user connects with. The requested role
+// is the user name from the connection parameters. Users with a single role
+// may connect with any user name.
+func selectRole(requested, userName, database string, roles []string) (string, error) {
+	switch {
+	case len(roles) == 0:
+		return "", fmt.Errorf("you do not have access to database %q", database)
+	case requested == "" || requested == userName:
+		if len(roles) == 1 {
+			return roles[0], nil
+		}
+		return "", fmt.Errorf("you can connect to database %q as %v; use one of them as the user name",
+			database, strings.Join(roles, ", "))
+	}
+
+	for _, role := range roles {
+		if role == requested {
+			return role, nil
+		}
+	}
+	return "", fmt.Errorf("you do not have access to database %q as %v; you can connect as %v",
+		database, requested, strings.Join(roles, ", "))
+}
+
+func sendFatal(backend *pgproto3.Backend, code, message string) {
+	_ = backend.Send(&pgproto3.ErrorResponse{Severity: "FATAL", Code: code, Message: message})
+}
+
+// relay copies data between the connections until either of them is closed.
+func relay(client, server net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(server, client)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(client, server)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
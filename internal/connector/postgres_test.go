@@ -0,0 +1,309 @@
+package connector
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+	"gopkg.in/square/go-jose.v2"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+)
+
+func TestPostgresDriver_Serve(t *testing.T) {
+	passwords := map[string]string{"readonly": "ro-password", "readwrite": "rw-password"}
+	serverAddr := startFakePostgres(t, passwords)
+	driver, err := newPostgresDriver(Options{
+		Name: "db",
+		Postgres: PostgresOptions{
+			URL:   "postgres://infra@" + serverAddr + "/postgres?sslmode=disable",
+			Roles: passwords,
+		},
+	})
+	assert.NilError(t, err)
+
+	roles, err := driver.UpdateRoles(nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, roles, []string{"readonly", "readwrite"})
+
+	grant := func(kind, name, privilege, resource string) api.DestinationGrant {
+		return api.DestinationGrant{
+			Grant:       api.Grant{Privilege: privilege, Resource: resource},
+			SubjectKind: kind,
+			SubjectName: name,
+		}
+	}
+	err = driver.ApplyGrants([]api.DestinationGrant{
+		grant("user", "alice@example.com", "readonly", "db"),
+		grant("group", "developers", "readwrite", "db.app"),
+		grant("user", "bob@example.com", "readonly", "db.app"),
+		grant("user", "alice@example.com", "admin", "other"),
+		// the connector has no password for the role
+		grant("user", "alice@example.com", "postgres", "db"),
+	})
+	assert.NilError(t, err)
+
+	pub, priv := generateJWK(t)
+	authn := newAuthenticator("https://127.0.0.1:12345", Options{}, []string{"db"})
+	authn.client = fakeClient{keys: []jose.JSONWebKey{*pub}}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	pgDriver, ok := driver.(*postgresDriver)
+	assert.Assert(t, ok)
+	go func() {
+		assert.Check(t, pgDriver.serve(ctx, l, authn, testTLSConfig(t)))
+	}()
+
+	connect := func(t *testing.T, user, database, token, sslmode string) (*pgconn.PgConn, error) {
+		config, err := pgconn.ParseConfig("postgres://" + l.Addr().String() + "/" + database + "?sslmode=" + sslmode)
+		assert.NilError(t, err)
+		config.User = user
+		config.Password = token
+		return pgconn.ConnectConfig(ctx, config)
+	}
+
+	token := generateJWT(t, priv, "alice@example.com", time.Now().Add(time.Minute), "db")
+
+	t.Run("only role", func(t *testing.T) {
+		conn, err := connect(t, "alice@example.com", "postgres", token, "require")
+		assert.NilError(t, err)
+		defer conn.Close(ctx)
+
+		result := conn.ExecParams(ctx, "SELECT current_user, current_database()", nil, nil, nil, nil).Read()
+		assert.NilError(t, result.Err)
+		assert.DeepEqual(t, result.Rows, [][][]byte{{[]byte("readonly"), []byte("postgres")}})
+	})
+
+	t.Run("reset role keeps the granted role", func(t *testing.T) {
+		conn, err := connect(t, "readonly", "postgres", token, "require")
+		assert.NilError(t, err)
+		defer conn.Close(ctx)
+
+		_, err = conn.Exec(ctx, "RESET ROLE").ReadAll()
+		assert.NilError(t, err)
+		_, err = conn.Exec(ctx, `SET ROLE "readwrite"`).ReadAll()
+		assert.ErrorContains(t, err, `permission denied to set role "readwrite"`)
+
+		result := conn.ExecParams(ctx, "SELECT current_user, current_database()", nil, nil, nil, nil).Read()
+		assert.NilError(t, result.Err)
+		assert.DeepEqual(t, result.Rows, [][][]byte{{[]byte("readonly"), []byte("postgres")}})
+	})
+
+	t.Run("role from user name", func(t *testing.T) {
+		conn, err := connect(t, "readwrite", "app", token, "require")
+		assert.NilError(t, err)
+		defer conn.Close(ctx)
+
+		result := conn.ExecParams(ctx, "SELECT current_user, current_database()", nil, nil, nil, nil).Read()
+		assert.NilError(t, result.Err)
+		assert.DeepEqual(t, result.Rows, [][][]byte{{[]byte("readwrite"), []byte("app")}})
+	})
+
+	t.Run("more than one role", func(t *testing.T) {
+		_, err := connect(t, "alice@example.com", "app", token, "require")
+		assert.ErrorContains(t, err, `you can connect to database "app" as readonly, readwrite`)
+	})
+
+	t.Run("role not granted", func(t *testing.T) {
+		_, err := connect(t, "admin", "postgres", token, "require")
+		assert.ErrorContains(t, err, `you do not have access to database "postgres" as admin`)
+	})
+
+	t.Run("no grants for the database", func(t *testing.T) {
+		token := generateJWT(t, priv, "bob@example.com", time.Now().Add(time.Minute), "db")
+		_, err := connect(t, "readonly", "postgres", token, "require")
+		assert.ErrorContains(t, err, `you do not have access to database "postgres"`)
+	})
+
+	t.Run("token for another destination", func(t *testing.T) {
+		token := generateJWT(t, priv, "alice@example.com", time.Now().Add(time.Minute), "web")
+		_, err := connect(t, "readonly", "postgres", token, "require")
+		assert.ErrorContains(t, err, "token authentication failed")
+	})
+
+	t.Run("no ssl", func(t *testing.T) {
+		_, err := connect(t, "readonly", "postgres", token, "disable")
+		assert.ErrorContains(t, err, "requires an SSL connection")
+	})
+}
+
+func TestSelectRole(t *testing.T) {
+	role, err := selectRole("", "alice", "app", []string{"readonly"})
+	assert.NilError(t, err)
+	assert.Equal(t, role, "readonly")
+
+	role, err = selectRole("readwrite", "alice", "app", []string{"readonly", "readwrite"})
+	assert.NilError(t, err)
+	assert.Equal(t, role, "readwrite")
+
+	_, err = selectRole("alice", "alice", "app", []string{"readonly", "readwrite"})
+	assert.Error(t, err, `you can connect to database "app" as readonly, readwrite; use one of them as the user name`)
+
+	_, err = selectRole("readonly", "alice", "app", nil)
+	assert.Error(t, err, `you do not have access to database "app"`)
+}
+
+// startFakePostgres starts a server that speaks enough of the PostgreSQL
+// protocol to log in users with passwords, and to answer SET ROLE and RESET
+// ROLE like a server where the users are not members of any role. Any other
+// query returns the current role and the database of the connection.
+func startFakePostgres(t *testing.T, passwords map[string]string) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go fakePostgresConn(t, conn, passwords)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func fakePostgresConn(t *testing.T, conn net.Conn, passwords map[string]string) {
+	defer conn.Close()
+	backend := pgproto3.NewBackend(pgproto3.NewChunkReader(conn), conn)
+
+	msg, err := backend.ReceiveStartupMessage()
+	if !assert.Check(t, err) {
+		return
+	}
+	startup, ok := msg.(*pgproto3.StartupMessage)
+	if !assert.Check(t, ok, "unexpected message %T", msg) {
+		return
+	}
+	sessionUser := startup.Parameters["user"]
+	role := sessionUser
+	database := startup.Parameters["database"]
+
+	if sessionUser != "infra" {
+		_ = backend.Send(&pgproto3.AuthenticationCleartextPassword{})
+		_ = backend.SetAuthType(pgproto3.AuthTypeCleartextPassword)
+		msg, err := backend.Receive()
+		if !assert.Check(t, err) {
+			return
+		}
+		password, ok := msg.(*pgproto3.PasswordMessage)
+		if !assert.Check(t, ok, "unexpected message %T", msg) {
+			return
+		}
+		if expected, ok := passwords[sessionUser]; !ok || password.Password != expected {
+			_ = backend.Send(&pgproto3.ErrorResponse{Severity: "FATAL", Code: "28P01", Message: "password authentication failed"})
+			return
+		}
+	}
+
+	var buf []byte
+	buf = (&pgproto3.AuthenticationOk{}).Encode(buf)
+	buf = (&pgproto3.ParameterStatus{Name: "server_version", Value: "14.5"}).Encode(buf)
+	buf = (&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: 2}).Encode(buf)
+	buf = (&pgproto3.ReadyForQuery{TxStatus: 'I'}).Encode(buf)
+	if _, err := conn.Write(buf); err != nil {
+		return
+	}
+
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return
+		}
+
+		buf = nil
+		switch msg := msg.(type) {
+		case *pgproto3.Query:
+			switch {
+			case msg.String == "RESET ROLE":
+				role = sessionUser
+				buf = (&pgproto3.CommandComplete{CommandTag: []byte("RESET")}).Encode(buf)
+			case strings.HasPrefix(msg.String, "SET ROLE "):
+				name := strings.Trim(strings.TrimPrefix(msg.String, "SET ROLE "), `"`)
+				if name != sessionUser {
+					buf = (&pgproto3.ErrorResponse{
+						Severity: "ERROR",
+						Code:     "42501",
+						Message:  fmt.Sprintf("permission denied to set role %q", name),
+					}).Encode(buf)
+					break
+				}
+				role = name
+				buf = (&pgproto3.CommandComplete{CommandTag: []byte("SET")}).Encode(buf)
+			default:
+				buf = queryResult(buf, role, database)
+			}
+		case *pgproto3.Parse, *pgproto3.Bind, *pgproto3.Describe, *pgproto3.Execute:
+			// the extended protocol is answered on Sync
+			continue
+		case *pgproto3.Sync:
+			buf = (&pgproto3.ParseComplete{}).Encode(buf)
+			buf = (&pgproto3.BindComplete{}).Encode(buf)
+			buf = queryResult(buf, role, database)
+		case *pgproto3.Terminate:
+			return
+		default:
+			t.Errorf("unexpected message %T", msg)
+			return
+		}
+
+		buf = (&pgproto3.ReadyForQuery{TxStatus: 'I'}).Encode(buf)
+		if _, err := conn.Write(buf); err != nil {
+			return
+		}
+	}
+}
+
+func queryResult(buf []byte, values ...string) []byte {
+	desc := &pgproto3.RowDescription{}
+	row := &pgproto3.DataRow{}
+	for _, v := range values {
+		desc.Fields = append(desc.Fields, pgproto3.FieldDescription{Name: []byte("column"), DataTypeOID: 25, DataTypeSize: -1})
+		row.Values = append(row.Values, []byte(v))
+	}
+	buf = desc.Encode(buf)
+	buf = row.Encode(buf)
+	return (&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")}).Encode(buf)
+}
+
+// testTLSConfig returns a TLS config with a self-signed certificate, which
+// is much faster to create than the certificates of the CertCache.
+func testTLSConfig(t *testing.T) *tls.Config {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NilError(t, err)
+
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+}
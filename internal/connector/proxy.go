@@ -3,9 +3,7 @@ package connector
 import (
 	"crypto/tls"
 	"crypto/x509"
-	"fmt"
 	"net/http"
-	"net/http/httputil"
 	"sync"
 
 	"github.com/gin-gonic/gin"
@@ -14,11 +12,7 @@ import (
 	"github.com/infrahq/infra/internal/logging"
 )
 
-func proxyMiddleware(
-	proxy *httputil.ReverseProxy,
-	authn *authenticator,
-	bearerToken string,
-) gin.HandlerFunc {
+func proxyMiddleware(driver Driver, authn *authenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		claim, err := authn.Authenticate(c.Request)
 		if err != nil {
@@ -27,13 +21,7 @@ func proxyMiddleware(
 			return
 		}
 
-		c.Request.Header.Set("Impersonate-User", claim.Name)
-		for _, g := range claim.Groups {
-			c.Request.Header.Add("Impersonate-Group", g)
-		}
-
-		c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", bearerToken))
-		proxy.ServeHTTP(c.Writer, c.Request)
+		driver.Proxy(c.Writer, c.Request, claim)
 	}
 }
 
@@ -0,0 +1,248 @@
+package connector
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/claims"
+	"github.com/infrahq/infra/internal/logging"
+)
+
+type SSHOptions struct {
+	// ConfigDir is the directory of the sshd configuration. Defaults to
+	// /etc/ssh.
+	ConfigDir string
+	// Host is the host that users connect to. Defaults to the hostname.
+	Host string
+	// Port is the port of sshd. Defaults to 22.
+	Port int
+}
+
+// sshDriver is the Driver for a Linux host that runs sshd. Users log in with
+// SSH certificates signed by the infra server. The driver configures sshd to
+// trust the SSH user CA of the server, and writes an AuthorizedPrincipalsFile
+// for each local user that grants allow logging in as.
+//
+// The privilege of a grant for the destination is the local user that the
+// subject of the grant can log in as.
+type sshDriver struct {
+	configDir string
+	host      string
+	port      int
+	// name is the name of the destination, used to match grant resources
+	name string
+}
+
+func newSSHDriver(options Options) (Driver, error) {
+	d := &sshDriver{
+		configDir: options.SSH.ConfigDir,
+		host:      options.SSH.Host,
+		port:      options.SSH.Port,
+		name:      options.Name,
+	}
+	if d.configDir == "" {
+		d.configDir = "/etc/ssh"
+	}
+	if d.port == 0 {
+		d.port = 22
+	}
+	return d, nil
+}
+
+func (d *sshDriver) infraDir() string {
+	return filepath.Join(d.configDir, "infra")
+}
+
+func (d *sshDriver) principalsDir() string {
+	return filepath.Join(d.infraDir(), "principals")
+}
+
+// UniqueID returns a checksum of the host keys of sshd.
+func (d *sshDriver) UniqueID() (string, error) {
+	files, err := filepath.Glob(filepath.Join(d.configDir, "ssh_host_*_key.pub"))
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no ssh host keys found in %v", d.configDir)
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, file := range files {
+		key, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("read ssh host key: %w", err)
+		}
+		h.Write(key)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (d *sshDriver) DefaultName() (string, error) {
+	name, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	d.name = name
+	return name, nil
+}
+
+func (d *sshDriver) Endpoint() (string, int, error) {
+	if d.host != "" {
+		return d.host, d.port, nil
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return "", 0, err
+	}
+	return host, d.port, nil
+}
+
+// Resources returns no resources, grants are for the whole host.
+func (d *sshDriver) Resources() ([]string, error) {
+	return nil, nil
+}
+
+// UpdateRoles ignores roles, the privilege of a grant is a local user.
+func (d *sshDriver) UpdateRoles([]api.Role) ([]string, error) {
+	return nil, nil
+}
+
+// validLogin matches the names of local users that can be used as the name of
+// a file.
+var validLogin = regexp.MustCompile(`^[a-z_][a-z0-9_.-]*\$?$`)
+
+// ApplyGrants writes the principals file of each local user that the grants
+// allow logging in as, and removes the principals files of other users.
+func (d *sshDriver) ApplyGrants(grants []api.DestinationGrant) error {
+	logins := make(map[string]struct{})
+	for _, g := range grants {
+		if !api.MatchResource(g.Resource, d.name) {
+			continue
+		}
+		if !validLogin.MatchString(g.Privilege) {
+			logging.Warnf("ignoring grant %v, privilege %q is not a valid local user", g.ID, g.Privilege)
+			continue
+		}
+		logins[g.Privilege] = struct{}{}
+	}
+
+	dir := d.principalsDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	for login := range logins {
+		principal := api.SSHPrincipal(d.name, login) + "\n"
+		if _, err := writeFileIfChanged(filepath.Join(dir, login), []byte(principal)); err != nil {
+			return fmt.Errorf("write principals of %v: %w", login, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if _, ok := logins[entry.Name()]; ok {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return fmt.Errorf("remove principals of %v: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// InstallSSHUserCA writes the public key of the CA to the file that sshd
+// reads TrustedUserCAKeys from, and the sshd configuration that uses the
+// files written by the driver.
+func (d *sshDriver) InstallSSHUserCA(publicKey string) error {
+	if err := os.MkdirAll(d.infraDir(), 0o755); err != nil {
+		return err
+	}
+
+	caFile := filepath.Join(d.infraDir(), "user_ca.pub")
+	if _, err := writeFileIfChanged(caFile, []byte(publicKey+"\n")); err != nil {
+		return fmt.Errorf("write ssh user ca: %w", err)
+	}
+
+	config := fmt.Sprintf(`# written by the infra connector
+TrustedUserCAKeys %v
+AuthorizedPrincipalsFile %v
+`, caFile, filepath.Join(d.principalsDir(), "%u"))
+
+	configDir := filepath.Join(d.configDir, "sshd_config.d")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		return err
+	}
+
+	configFile := filepath.Join(configDir, "infra.conf")
+	changed, err := writeFileIfChanged(configFile, []byte(config))
+	if err != nil {
+		return fmt.Errorf("write sshd config: %w", err)
+	}
+	if changed {
+		logging.Warnf("updated %v, reload sshd to apply the change", configFile)
+	}
+	return nil
+}
+
+// Proxy responds with an error, users connect to sshd directly.
+func (d *sshDriver) Proxy(w http.ResponseWriter, _ *http.Request, _ claims.Custom) {
+	http.Error(w, "ssh destinations do not accept requests from the connector", http.StatusNotFound)
+}
+
+// Health checks that sshd accepts connections.
+func (d *sshDriver) Health() error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(d.port)), 5*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// writeFileIfChanged replaces the file with content, unless it already has
+// that content. It returns true if the file was replaced. The file is replaced
+// by a rename, so sshd never reads a partially written file.
+func writeFileIfChanged(filename string, content []byte) (bool, error) {
+	existing, err := os.ReadFile(filename)
+	switch {
+	case err == nil && bytes.Equal(existing, content):
+		return false, nil
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return false, err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+"-")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(content); err != nil {
+		temp.Close()
+		return false, err
+	}
+	if err := temp.Close(); err != nil {
+		return false, err
+	}
+	if err := os.Chmod(temp.Name(), 0o644); err != nil {
+		return false, err
+	}
+	return true, os.Rename(temp.Name(), filename)
+}
@@ -0,0 +1,109 @@
+package connector
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+
+	"github.com/infrahq/infra/api"
+)
+
+func TestSSHDriver_ApplyGrants(t *testing.T) {
+	dir := t.TempDir()
+	driver, err := newSSHDriver(Options{Name: "web", SSH: SSHOptions{ConfigDir: dir}})
+	assert.NilError(t, err)
+
+	grant := func(privilege, resource string) api.DestinationGrant {
+		return api.DestinationGrant{Grant: api.Grant{Privilege: privilege, Resource: resource}}
+	}
+
+	err = driver.ApplyGrants([]api.DestinationGrant{
+		grant("ubuntu", "web"),
+		grant("ubuntu", "w*"),
+		grant("admin", "w*"),
+		grant("root", "web.default"),
+		grant("postgres", "db"),
+		grant("../../passwd", "web"),
+	})
+	assert.NilError(t, err)
+
+	expected := fs.Expected(t, fs.WithMode(0o755),
+		fs.WithDir("infra", fs.WithMode(0o755),
+			fs.WithDir("principals", fs.WithMode(0o755),
+				fs.WithFile("ubuntu", "web:ubuntu\n", fs.WithMode(0o644)),
+				fs.WithFile("admin", "web:admin\n", fs.WithMode(0o644)))))
+	assert.Assert(t, fs.Equal(dir, expected))
+
+	t.Run("grants removed", func(t *testing.T) {
+		err = driver.ApplyGrants([]api.DestinationGrant{grant("admin", "web")})
+		assert.NilError(t, err)
+
+		expected := fs.Expected(t, fs.WithMode(0o755),
+			fs.WithDir("infra", fs.WithMode(0o755),
+				fs.WithDir("principals", fs.WithMode(0o755),
+					fs.WithFile("admin", "web:admin\n", fs.WithMode(0o644)))))
+		assert.Assert(t, fs.Equal(dir, expected))
+	})
+}
+
+func TestSSHDriver_InstallSSHUserCA(t *testing.T) {
+	dir := t.TempDir()
+	driver, err := newSSHDriver(Options{Name: "web", SSH: SSHOptions{ConfigDir: dir}})
+	assert.NilError(t, err)
+	installer, ok := driver.(sshUserCAInstaller)
+	assert.Assert(t, ok)
+
+	assert.NilError(t, installer.InstallSSHUserCA("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIFirstKey"))
+	assert.NilError(t, installer.InstallSSHUserCA("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAISecondKey"))
+
+	config := `# written by the infra connector
+TrustedUserCAKeys ` + filepath.Join(dir, "infra/user_ca.pub") + `
+AuthorizedPrincipalsFile ` + filepath.Join(dir, "infra/principals/%u") + `
+`
+	expected := fs.Expected(t, fs.WithMode(0o755),
+		fs.WithDir("infra", fs.WithMode(0o755),
+			fs.WithFile("user_ca.pub", "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAISecondKey\n", fs.WithMode(0o644))),
+		fs.WithDir("sshd_config.d", fs.WithMode(0o755),
+			fs.WithFile("infra.conf", config, fs.WithMode(0o644))))
+	assert.Assert(t, fs.Equal(dir, expected))
+}
+
+func TestSSHDriver_UniqueID(t *testing.T) {
+	dir := t.TempDir()
+	driver, err := newSSHDriver(Options{SSH: SSHOptions{ConfigDir: dir}})
+	assert.NilError(t, err)
+
+	_, err = driver.UniqueID()
+	assert.ErrorContains(t, err, "no ssh host keys found")
+
+	hostKey := filepath.Join(dir, "ssh_host_ed25519_key.pub")
+	assert.NilError(t, os.WriteFile(hostKey, []byte("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHostKey\n"), 0o600))
+
+	first, err := driver.UniqueID()
+	assert.NilError(t, err)
+	again, err := driver.UniqueID()
+	assert.NilError(t, err)
+	assert.Equal(t, first, again)
+
+	assert.NilError(t, os.WriteFile(hostKey, []byte("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAINewHostKey\n"), 0o600))
+	changed, err := driver.UniqueID()
+	assert.NilError(t, err)
+	assert.Assert(t, changed != first)
+}
+
+func TestSSHDriver_Health(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+
+	driver, err := newSSHDriver(Options{SSH: SSHOptions{Port: port}})
+	assert.NilError(t, err)
+	assert.NilError(t, driver.Health())
+
+	assert.NilError(t, l.Close())
+	assert.Assert(t, driver.Health() != nil)
+}
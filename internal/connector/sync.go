@@ -0,0 +1,502 @@
+package connector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/uid"
+)
+
+const (
+	// refreshInterval is how often the connector registers the destination
+	// again, and recreates all the role bindings.
+	refreshInterval = 30 * time.Second
+	// retryInterval is how long the connector waits after an error before
+	// it lists all the grants and roles again.
+	retryInterval = 5 * time.Second
+	// maxEventsWait is the longest time the server waits for an event.
+	maxEventsWait = 30 * time.Second
+)
+
+// accessState is the grants and roles that apply to a destination. It is
+// listed from the server once, and then kept up to date by applying the
+// destination events from the server.
+type accessState struct {
+	// cursor is the cursor of the last event that was applied. It is empty
+	// if the server does not support destination events.
+	cursor string
+	// etag identifies the grants that were last listed
+	etag   string
+	grants map[uid.ID]api.Grant
+	roles  map[uid.ID]api.Role
+	// users and groups are the names of the users and groups that have
+	// grants, keyed by their ID. Names are added when they are first needed.
+	users  map[uid.ID]string
+	groups map[uid.ID]string
+}
+
+func newAccessState(cursor string, grants []api.Grant, roles []api.Role) *accessState {
+	state := &accessState{
+		cursor: cursor,
+		grants: make(map[uid.ID]api.Grant, len(grants)),
+		roles:  make(map[uid.ID]api.Role, len(roles)),
+		users:  make(map[uid.ID]string),
+		groups: make(map[uid.ID]string),
+	}
+	for _, grant := range grants {
+		state.grants[grant.ID] = grant
+	}
+	for _, role := range roles {
+		state.roles[role.ID] = role
+	}
+	return state
+}
+
+// apply updates the state with the change from event. It returns
+// grantsChanged true if the role bindings must be updated, and rolesChanged
+// true if the cluster-roles must be updated.
+func (s *accessState) apply(event api.DestinationEvent) (grantsChanged, rolesChanged bool) {
+	switch event.Type {
+	case api.DestinationEventGrantCreated, api.DestinationEventGrantUpdated:
+		if event.Grant != nil {
+			s.grants[event.Grant.ID] = *event.Grant
+			return true, false
+		}
+	case api.DestinationEventGrantDeleted:
+		if event.Grant != nil {
+			if _, ok := s.grants[event.Grant.ID]; ok {
+				delete(s.grants, event.Grant.ID)
+				return true, false
+			}
+		}
+	case api.DestinationEventRoleUpdated:
+		if event.Role != nil {
+			s.roles[event.Role.ID] = *event.Role
+			return true, true
+		}
+	case api.DestinationEventRoleDeleted:
+		if event.Role != nil {
+			delete(s.roles, event.Role.ID)
+			return true, true
+		}
+	case api.DestinationEventUserUpdated:
+		if event.User != nil {
+			if name, ok := s.users[event.User.ID]; ok && name != event.User.Name {
+				s.users[event.User.ID] = event.User.Name
+				return true, false
+			}
+		}
+	case api.DestinationEventGroupUpdated:
+		if event.Group != nil {
+			if name, ok := s.groups[event.Group.ID]; ok && name != event.Group.Name {
+				s.groups[event.Group.ID] = event.Group.Name
+				return true, false
+			}
+		}
+	default:
+		logging.Debugf("ignoring unknown destination event %q", event.Type)
+	}
+	return false, false
+}
+
+func (s *accessState) grantList() []api.Grant {
+	grants := make([]api.Grant, 0, len(s.grants))
+	for _, grant := range s.grants {
+		grants = append(grants, grant)
+	}
+	return grants
+}
+
+func (s *accessState) roleList() []api.Role {
+	roles := make([]api.Role, 0, len(s.roles))
+	for _, role := range s.roles {
+		roles = append(roles, role)
+	}
+	return roles
+}
+
+// nextExpiry returns the earliest time after since that a grant expires, or
+// the zero time if no grants expire after since.
+func (s *accessState) nextExpiry(since time.Time) time.Time {
+	var next time.Time
+	for _, grant := range s.grants {
+		expires := grant.Expires.Time()
+		if expires.IsZero() || !expires.After(since) {
+			continue
+		}
+		if next.IsZero() || expires.Before(next) {
+			next = expires
+		}
+	}
+	return next
+}
+
+// subjectNames returns a function that returns the name of the user or group
+// of a grant. Names are requested from the server the first time they are
+// needed.
+func (s *accessState) subjectNames(client *api.Client) func(api.Grant) (string, error) {
+	return func(grant api.Grant) (string, error) {
+		switch {
+		case grant.Group != 0:
+			if name, ok := s.groups[grant.Group]; ok {
+				return name, nil
+			}
+			group, err := client.GetGroup(grant.Group)
+			if err != nil {
+				return "", err
+			}
+			s.groups[grant.Group] = group.Name
+			return group.Name, nil
+		default:
+			if name, ok := s.users[grant.User]; ok {
+				return name, nil
+			}
+			user, err := client.GetUser(grant.User)
+			if err != nil {
+				return "", err
+			}
+			s.users[grant.User] = user.Name
+			return user.Name, nil
+		}
+	}
+}
+
+// listAccessState lists the grants and roles of the destination, and the
+// cursor of the destination events that follow them.
+func listAccessState(client *api.Client, destination *api.Destination) (*accessState, error) {
+	// get the cursor first, so that no change is missed between listing
+	// grants and reading events.
+	var cursor string
+	events, err := client.ListDestinationEvents(api.ListDestinationEventsRequest{ID: destination.ID})
+	switch {
+	case api.ErrorStatusCode(err) == http.StatusNotFound:
+		logging.Debugf("server does not support destination events, polling for grants")
+	case err != nil:
+		return nil, fmt.Errorf("destination events cursor: %w", err)
+	default:
+		cursor = events.Cursor
+	}
+
+	roles, err := listAll(client.ListRoles, api.ListRolesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing roles: %w", err)
+	}
+
+	state := newAccessState(cursor, nil, roles)
+	if err := state.updateGrants(client, destination); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// updateGrants replaces the grants of the state with the grants of the
+// destination, unless they have not changed since they were last listed.
+func (s *accessState) updateGrants(client *api.Client, destination *api.Destination) error {
+	resp, err := listDestinationGrants(client, destination, s.etag)
+	if err != nil {
+		return fmt.Errorf("error listing grants: %w", err)
+	}
+	if resp.NotModified {
+		return nil
+	}
+
+	s.etag = resp.ETag
+	s.grants = make(map[uid.ID]api.Grant, len(resp.Items))
+	for _, item := range resp.Items {
+		s.grants[item.ID] = item.Grant
+		if item.SubjectName == "" {
+			continue
+		}
+		switch item.SubjectKind {
+		case api.DestinationGrantSubjectUser:
+			s.users[item.User] = item.SubjectName
+		case api.DestinationGrantSubjectGroup:
+			s.groups[item.Group] = item.SubjectName
+		}
+	}
+	return nil
+}
+
+// listDestinationGrants lists the grants of the destination with the names of
+// their users and groups. Servers that do not support listing the grants of a
+// destination return the grants without names.
+func listDestinationGrants(client *api.Client, destination *api.Destination, etag string) (*api.ListDestinationGrantsResponse, error) {
+	resp, err := client.ListDestinationGrants(api.ListDestinationGrantsRequest{ID: destination.ID, ETag: etag})
+	if api.ErrorStatusCode(err) != http.StatusNotFound {
+		return resp, err
+	}
+
+	grants, err := listAll(client.ListGrants, api.ListGrantsRequest{ResourcePrefix: destination.Name})
+	if err != nil {
+		return nil, err
+	}
+	resp = &api.ListDestinationGrantsResponse{Items: make([]api.DestinationGrant, 0, len(grants))}
+	for _, grant := range grants {
+		resp.Items = append(resp.Items, api.DestinationGrant{Grant: grant})
+	}
+	return resp, nil
+}
+
+// syncer keeps the destination registered with the server, and the role
+// bindings of the cluster up to date with the grants of the destination.
+type syncer struct {
+	driver      Driver
+	client      *api.Client
+	destination *api.Destination
+	certCache   *CertCache
+	caCertPEM   []byte
+
+	// state is nil when the grants and roles must be listed again
+	state *accessState
+	// refreshed is the time of the last refresh, or zero if the destination
+	// must be refreshed before the next event is applied.
+	refreshed time.Time
+	// bindingsUpdated is the time the role bindings were last updated
+	bindingsUpdated time.Time
+}
+
+// run syncs with the server until the context is cancelled. Each change to
+// the grants of the destination is applied as soon as the server reports it.
+// Any error results in listing all the grants again after retryInterval.
+func (s *syncer) run(ctx context.Context) {
+	for {
+		if err := s.sync(ctx); err != nil {
+			logging.Errorf("%v", err)
+			s.state = nil
+			s.refreshed = time.Time{}
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryInterval):
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// sync refreshes the destination if it is due, and then waits for the next
+// destination events and applies them.
+func (s *syncer) sync(ctx context.Context) error {
+	if time.Since(s.refreshed) >= refreshInterval {
+		if err := s.refresh(); err != nil {
+			return err
+		}
+	}
+
+	wait := time.Until(s.refreshed.Add(refreshInterval))
+	if next := s.state.nextExpiry(s.bindingsUpdated); !next.IsZero() && time.Until(next) < wait {
+		wait = time.Until(next)
+	}
+	if wait > maxEventsWait {
+		wait = maxEventsWait
+	}
+
+	var grantsChanged, rolesChanged bool
+	switch {
+	case wait <= 0:
+	case s.state.cursor == "":
+		// the server does not support events, list everything again after
+		// waiting.
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+		if time.Since(s.refreshed) >= refreshInterval {
+			s.state = nil
+		}
+	default:
+		resp, err := s.client.ListDestinationEvents(api.ListDestinationEventsRequest{
+			ID:     s.destination.ID,
+			Cursor: s.state.cursor,
+			Wait:   api.Duration(wait),
+		})
+		if err != nil {
+			return fmt.Errorf("error listing destination events: %w", err)
+		}
+
+		if resp.Resync {
+			logging.Debugf("destination events are no longer available, listing all grants")
+			s.state = nil
+			s.refreshed = time.Time{}
+			return nil
+		}
+
+		for _, event := range resp.Items {
+			logging.Debugf("applying destination event %d %s", event.Sequence, event.Type)
+			grants, roles := s.state.apply(event)
+			grantsChanged = grantsChanged || grants
+			rolesChanged = rolesChanged || roles
+		}
+		s.state.cursor = resp.Cursor
+	}
+
+	if rolesChanged {
+		// cluster-roles and the roles of the destination are updated by a
+		// refresh, which also updates role bindings.
+		s.refreshed = time.Time{}
+		return nil
+	}
+
+	if next := s.state.nextExpiry(s.bindingsUpdated); !next.IsZero() && !time.Now().Before(next) {
+		grantsChanged = true
+	}
+
+	if grantsChanged {
+		return s.updateBindings()
+	}
+	return nil
+}
+
+// refresh registers the destination with the server, lists the grants and
+// roles of the destination if necessary, and updates all the cluster-roles and
+// role bindings.
+func (s *syncer) refresh() error {
+	host, port, err := s.driver.Endpoint()
+	if err != nil {
+		return fmt.Errorf("failed to lookup endpoint: %w", err)
+	}
+
+	if ipv4 := net.ParseIP(host); ipv4 == nil {
+		// wait for DNS resolution if endpoint is not an IPv4 address
+		if _, err := net.LookupIP(host); err != nil {
+			return fmt.Errorf("host could not be resolved: %w", err)
+		}
+	}
+
+	// update certificates if the host changed
+	if _, err = s.certCache.AddHost(host); err != nil {
+		return fmt.Errorf("could not update self-signed certificates: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s:%d", host, port)
+	logging.Debugf("connector serving on %s", endpoint)
+
+	resources, err := s.driver.Resources()
+	if err != nil {
+		return err
+	}
+
+	destination := s.destination
+	if destination.ID == 0 {
+		// the destination must be registered before its events can be read
+		destination.Resources = resources
+		destination.Connection.CA = api.PEM(s.caCertPEM)
+		destination.Connection.URL = endpoint
+		if err := createOrUpdateDestination(s.client, destination); err != nil {
+			return fmt.Errorf("initializing destination: %w", err)
+		}
+	}
+
+	if installer, ok := s.driver.(sshUserCAInstaller); ok {
+		ca, err := s.client.GetSSHUserCA()
+		if err != nil {
+			return fmt.Errorf("get ssh user ca: %w", err)
+		}
+		if err := installer.InstallSSHUserCA(ca.PublicKey); err != nil {
+			return fmt.Errorf("install ssh user ca: %w", err)
+		}
+	}
+
+	if s.state == nil {
+		state, err := listAccessState(s.client, destination)
+		if err != nil {
+			return err
+		}
+		s.state = state
+	} else {
+		// events keep the grants up to date, but list them again in case a
+		// change was missed. This is a single 304 response when nothing
+		// has changed.
+		if err := s.state.updateGrants(s.client, destination); err != nil {
+			return err
+		}
+	}
+
+	roles, err := s.driver.UpdateRoles(s.state.roleList())
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !slicesEqual(destination.Resources, resources):
+		destination.Resources = resources
+		fallthrough
+
+	case !slicesEqual(destination.Roles, roles):
+		destination.Roles = roles
+		fallthrough
+
+	case !bytes.Equal([]byte(destination.Connection.CA), s.caCertPEM):
+		destination.Connection.CA = api.PEM(s.caCertPEM)
+		fallthrough
+
+	case destination.Connection.URL != endpoint:
+		destination.Connection.URL = endpoint
+
+		if err := createOrUpdateDestination(s.client, destination); err != nil {
+			return fmt.Errorf("updating destination: %w", err)
+		}
+	}
+
+	s.refreshed = time.Now()
+	return s.updateBindings()
+}
+
+// updateBindings applies the grants of the state to the destination.
+func (s *syncer) updateBindings() error {
+	now := time.Now()
+	subjectName := s.state.subjectNames(s.client)
+
+	grants := make([]api.DestinationGrant, 0, len(s.state.grants))
+	for _, g := range s.state.grantList() {
+		if g.Privilege == "connect" {
+			continue
+		}
+
+		// the server excludes expired grants, but check again in case the
+		// grant expired after it was listed.
+		if expires := g.Expires.Time(); !expires.IsZero() && !now.Before(expires) {
+			logging.Debugf("skipping expired grant %v", g.ID)
+			continue
+		}
+
+		if !resourceInDestination(g.Resource, s.destination.Name) {
+			continue
+		}
+
+		name, err := subjectName(g)
+		if err != nil {
+			return fmt.Errorf("error updating grants: %w", err)
+		}
+
+		kind := api.DestinationGrantSubjectUser
+		if g.Group != 0 {
+			kind = api.DestinationGrantSubjectGroup
+		}
+		grants = append(grants, api.DestinationGrant{Grant: g, SubjectKind: kind, SubjectName: name})
+	}
+
+	if err := s.driver.ApplyGrants(grants); err != nil {
+		return fmt.Errorf("error updating grants: %w", err)
+	}
+	s.bindingsUpdated = now
+	return nil
+}
+
+// resourceInDestination returns true if the resource of a grant is the
+// destination or one of its sub-resources.
+func resourceInDestination(resource, destinationName string) bool {
+	pattern, _, _ := strings.Cut(resource, ".")
+	return api.MatchResource(pattern, destinationName)
+}
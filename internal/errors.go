@@ -16,4 +16,6 @@ var (
 	ErrBadRequest     = fmt.Errorf("bad request")
 	ErrNotImplemented = fmt.Errorf("not implemented")
 	ErrExpired        = fmt.Errorf("expired")
+	// ErrTooManyRequests means the client has been rate limited
+	ErrTooManyRequests = fmt.Errorf("too many requests")
 )
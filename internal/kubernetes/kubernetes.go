@@ -150,6 +150,66 @@ func (k *Kubernetes) UpdateClusterRoleBindings(subjects map[string][]rbacv1.Subj
 	return nil
 }
 
+// CustomClusterRoleName is the name of the cluster-role that is created for
+// a custom role defined in infra.
+func CustomClusterRoleName(role string) string {
+	return "infra:" + role
+}
+
+// UpdateClusterRoles creates or updates a cluster-role for each of the custom
+// roles, and deletes the cluster-roles of custom roles that no longer exist.
+func (k *Kubernetes) UpdateClusterRoles(roles map[string][]rbacv1.PolicyRule) error {
+	clientset, err := kubernetes.NewForConfig(k.Config)
+	if err != nil {
+		return err
+	}
+
+	existing, err := clientset.RbacV1().ClusterRoles().List(context.Background(), metav1.ListOptions{LabelSelector: "app.kubernetes.io/managed-by=infra"})
+	if err != nil {
+		return err
+	}
+
+	toDelete := make(map[string]bool)
+	for _, cr := range existing.Items {
+		toDelete[cr.Name] = true
+	}
+
+	for role, rules := range roles {
+		cr := &rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: CustomClusterRoleName(role),
+				Labels: map[string]string{
+					"app.kubernetes.io/managed-by": "infra",
+				},
+			},
+			Rules: rules,
+		}
+
+		_, err = clientset.RbacV1().ClusterRoles().Update(context.Background(), cr, metav1.UpdateOptions{})
+		if err != nil {
+			if k8sErrors.IsNotFound(err) {
+				_, err = clientset.RbacV1().ClusterRoles().Create(context.Background(), cr, metav1.CreateOptions{})
+				if err != nil {
+					return err
+				}
+			} else {
+				return err
+			}
+		}
+
+		delete(toDelete, cr.Name)
+	}
+
+	for name := range toDelete {
+		err := clientset.RbacV1().ClusterRoles().Delete(context.Background(), name, metav1.DeleteOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (k *Kubernetes) UpdateRoleBindings(subjects map[ClusterRoleNamespace][]rbacv1.Subject) error {
 	clientset, err := kubernetes.NewForConfig(k.Config)
 	if err != nil {
@@ -664,3 +724,18 @@ func (k *Kubernetes) ClusterRoles() ([]string, error) {
 
 	return results, nil
 }
+
+// ServerVersion returns the version of the Kubernetes API server. It is used
+// to check that the API server can be reached.
+func (k *Kubernetes) ServerVersion() (string, error) {
+	clientset, err := kubernetes.NewForConfig(k.Config)
+	if err != nil {
+		return "", err
+	}
+
+	version, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		return "", err
+	}
+	return version.GitVersion, nil
+}
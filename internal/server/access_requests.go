@@ -0,0 +1,72 @@
+package server
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/validate"
+)
+
+func (a *API) ListAccessRequests(c *gin.Context, r *api.ListAccessRequestsRequest) (*api.ListResponse[api.AccessRequest], error) {
+	p := models.RequestToPagination(r.PaginationRequest)
+	reqs, err := access.ListAccessRequests(c, r.User, models.AccessRequestState(r.State), &p)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(reqs, models.PaginationToResponse(p), func(req models.AccessRequest) api.AccessRequest {
+		return *req.ToAPI()
+	})
+
+	return result, nil
+}
+
+func (a *API) GetAccessRequest(c *gin.Context, r *api.Resource) (*api.AccessRequest, error) {
+	req, err := access.GetAccessRequest(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return req.ToAPI(), nil
+}
+
+func (a *API) CreateAccessRequest(c *gin.Context, r *api.CreateAccessRequestRequest) (*api.AccessRequest, error) {
+	if r.Duration < 0 {
+		return nil, validate.Error{"duration": {"must be a positive duration"}}
+	}
+
+	req := &models.AccessRequest{
+		Privilege: r.Privilege,
+		Resource:  r.Resource,
+		Reason:    r.Reason,
+		Duration:  time.Duration(r.Duration),
+	}
+
+	if err := access.CreateAccessRequest(c, req); err != nil {
+		return nil, err
+	}
+
+	return req.ToAPI(), nil
+}
+
+func (a *API) ApproveAccessRequest(c *gin.Context, r *api.Resource) (*api.AccessRequest, error) {
+	req, err := access.ApproveAccessRequest(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return req.ToAPI(), nil
+}
+
+func (a *API) DenyAccessRequest(c *gin.Context, r *api.Resource) (*api.AccessRequest, error) {
+	req, err := access.DenyAccessRequest(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return req.ToAPI(), nil
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAPI_AccessRequests(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	admin, err := data.GetIdentity(srv.DB(), data.ByName("admin@example.com"))
+	assert.NilError(t, err)
+
+	userKey, user := createAccessKey(t, srv.DB(), "requester@example.com")
+	otherKey, _ := createAccessKey(t, srv.DB(), "other@example.com")
+
+	createRequest := func(t *testing.T, body api.CreateAccessRequestRequest) api.AccessRequest {
+		t.Helper()
+		resp := doRequest(t, routes, http.MethodPost, "/api/access-requests", userKey, body)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var accessReq api.AccessRequest
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &accessReq))
+		return accessReq
+	}
+
+	pending := createRequest(t, api.CreateAccessRequestRequest{
+		Privilege: "edit",
+		Resource:  "production.payments",
+		Reason:    "fix the broken deploy",
+		Duration:  api.Duration(4 * time.Hour),
+	})
+
+	t.Run("create", func(t *testing.T) {
+		assert.Equal(t, pending.User, user.ID)
+		assert.Equal(t, pending.State, api.AccessRequestStatePending)
+		assert.Equal(t, pending.Reason, "fix the broken deploy")
+		assert.Equal(t, time.Duration(pending.Duration), 4*time.Hour)
+	})
+
+	t.Run("create missing required fields", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/access-requests", userKey, api.CreateAccessRequestRequest{})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("list own requests", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodGet, "/api/access-requests?user="+user.ID.String(), userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var reqs api.ListResponse[api.AccessRequest]
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &reqs))
+		assert.Equal(t, reqs.Count, 1)
+		assert.Equal(t, reqs.Items[0].ID, pending.ID)
+	})
+
+	t.Run("list requests of another user", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodGet, "/api/access-requests?user="+user.ID.String(), otherKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("approve requires admin", func(t *testing.T) {
+		path := fmt.Sprintf("/api/access-requests/%s/approve", pending.ID)
+		resp := doRequest(t, routes, http.MethodPost, path, userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("approve", func(t *testing.T) {
+		path := fmt.Sprintf("/api/access-requests/%s/approve", pending.ID)
+		resp := doRequest(t, routes, http.MethodPost, path, adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var approved api.AccessRequest
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &approved))
+		assert.Equal(t, approved.State, api.AccessRequestStateApproved)
+		assert.Equal(t, approved.ReviewedBy, admin.ID)
+		assert.Assert(t, approved.Grant != 0)
+
+		grant, err := data.GetGrant(srv.DB(), data.ByID(approved.Grant))
+		assert.NilError(t, err)
+		assert.Equal(t, grant.Subject, uid.NewIdentityPolymorphicID(user.ID))
+		assert.Equal(t, grant.Privilege, "edit")
+		assert.Equal(t, grant.Resource, "production.payments")
+		assert.Equal(t, grant.CreatedBy, admin.ID)
+		assert.Assert(t, grant.ExpiresAt.After(time.Now().Add(3*time.Hour)))
+
+		resp = doRequest(t, routes, http.MethodPost, path, adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("approve extends an existing grant", func(t *testing.T) {
+		approve := func(t *testing.T, duration time.Duration) *models.Grant {
+			t.Helper()
+			accessReq := createRequest(t, api.CreateAccessRequestRequest{
+				Privilege: "edit",
+				Resource:  "production.payments",
+				Duration:  api.Duration(duration),
+			})
+
+			path := fmt.Sprintf("/api/access-requests/%s/approve", accessReq.ID)
+			resp := doRequest(t, routes, http.MethodPost, path, adminAccessKey(srv), nil)
+			assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+			var approved api.AccessRequest
+			assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &approved))
+			grant, err := data.GetGrant(srv.DB(), data.ByID(approved.Grant))
+			assert.NilError(t, err)
+			return grant
+		}
+
+		grant := approve(t, 8*time.Hour)
+		assert.Assert(t, grant.ExpiresAt.After(time.Now().Add(7*time.Hour)))
+
+		// a shorter request does not shorten the grant
+		grant = approve(t, time.Hour)
+		assert.Assert(t, grant.ExpiresAt.After(time.Now().Add(7*time.Hour)))
+
+		grant = approve(t, 0)
+		assert.Assert(t, grant.ExpiresAt.IsZero())
+
+		grant = approve(t, time.Hour)
+		assert.Assert(t, grant.ExpiresAt.IsZero())
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		accessReq := createRequest(t, api.CreateAccessRequestRequest{
+			Privilege: "admin",
+			Resource:  "production",
+		})
+
+		path := fmt.Sprintf("/api/access-requests/%s/deny", accessReq.ID)
+		resp := doRequest(t, routes, http.MethodPost, path, adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var denied api.AccessRequest
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &denied))
+		assert.Equal(t, denied.State, api.AccessRequestStateDenied)
+		assert.Equal(t, denied.ReviewedBy, admin.ID)
+		assert.Equal(t, denied.Grant, uid.ID(0))
+
+		_, err := data.GetGrant(srv.DB(),
+			data.BySubject(uid.NewIdentityPolymorphicID(user.ID)),
+			data.ByPrivilege("admin"))
+		assert.ErrorContains(t, err, "not found")
+	})
+
+	t.Run("list by state", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodGet, "/api/access-requests?state=denied", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var reqs api.ListResponse[api.AccessRequest]
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &reqs))
+		assert.Equal(t, reqs.Count, 1)
+		assert.Equal(t, reqs.Items[0].State, api.AccessRequestStateDenied)
+	})
+}
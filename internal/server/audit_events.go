@@ -0,0 +1,203 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func (a *API) ListAuditEvents(c *gin.Context, r *api.ListAuditEventsRequest) (*api.ListResponse[api.AuditEvent], error) {
+	p := models.RequestToPagination(r.PaginationRequest)
+	events, err := access.ListAuditEvents(c, r.ActorID, r.Resource, r.ResourceID, r.After.Time(), r.Before.Time(), &p)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(events, models.PaginationToResponse(p), func(event models.AuditEvent) api.AuditEvent {
+		return *event.ToAPI()
+	})
+
+	return result, nil
+}
+
+// isAuditedMethod returns true if requests with method should be recorded as
+// an audit event. Only methods which modify state are recorded.
+func isAuditedMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+const (
+	// auditRequestKey is the gin context key of the request body, which is
+	// recorded in the audit event.
+	auditRequestKey = "auditRequest"
+	// auditResponseKey is the gin context key of the response body, which is
+	// used to find the ID of a created resource.
+	auditResponseKey = "auditResponse"
+)
+
+// auditMiddleware records a request in the audit log after the response has
+// been sent, so that requests rejected before reaching the handler, and routes
+// that are not registered with add, are also recorded. Requests that are not
+// associated with an organization are not recorded.
+//
+// The event is saved outside of the transaction of the request, so that a
+// failure to save it can not change the outcome of the request. Errors are
+// logged instead of returned, because the response has already been sent.
+func auditMiddleware(srv *Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		routePath := c.FullPath()
+		if !isAuditedMethod(c.Request.Method) || routePath == "" {
+			return
+		}
+
+		rCtx := getRequestContext(c)
+		if rCtx.Authenticated.Organization == nil {
+			return
+		}
+
+		req, _ := c.Get(auditRequestKey)
+		resp, _ := c.Get(auditResponseKey)
+		event := &models.AuditEvent{
+			Method:     c.Request.Method,
+			Path:       routePath,
+			Resource:   auditResourceName(routePath),
+			ResourceID: auditResourceID(c, resp),
+			Request:    auditRequestSummary(req),
+			StatusCode: c.Writer.Status(),
+		}
+		if user := rCtx.Authenticated.User; user != nil {
+			event.ActorID = user.ID
+			event.ActorName = user.Name
+		}
+
+		db := srv.DB().GormDB().WithContext(c.Request.Context())
+		tx := data.NewTransaction(db, rCtx.Authenticated.Organization.ID)
+		if err := data.CreateAuditEvent(tx, event); err != nil {
+			logging.L.Error().Err(err).Str("path", routePath).Msg("failed to record audit event")
+		}
+	}
+}
+
+// auditResourceName returns the kind of resource from a route path. For
+// example, the resource name of /api/grants/:id is grants, the resource
+// name of /scim/v2/Users/:id is users, and the resource name of /oidc/token
+// is oidc.
+func auditResourceName(routePath string) string {
+	name := strings.TrimPrefix(routePath, "/api/")
+	name = strings.TrimPrefix(name, "/scim/v2/")
+	name = strings.ToLower(strings.TrimPrefix(name, "/"))
+	name, _, _ = strings.Cut(name, "/")
+	return name
+}
+
+// auditResourceID returns the ID of the resource targeted by the request. The
+// ID is read from the path when it is available, otherwise it is read from
+// the response, so that the ID of a newly created resource is recorded.
+func auditResourceID(c *gin.Context, resp any) uid.ID {
+	if id, err := uid.Parse([]byte(c.Param("id"))); err == nil && id != 0 {
+		return id
+	}
+
+	if resp == nil {
+		return 0
+	}
+
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return 0
+	}
+
+	var body struct {
+		ID uid.ID `json:"id"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return 0
+	}
+	return body.ID
+}
+
+// sensitiveFieldNames are the lowercase names of request fields whose values
+// must not be stored in the audit log. Names are matched exactly, so that
+// fields like keyName or publicKey are still recorded.
+var sensitiveFieldNames = map[string]bool{
+	"accesskey":       true,
+	"bindpassword":    true,
+	"clientsecret":    true,
+	"code":            true,
+	"devicecode":      true,
+	"mfacode":         true,
+	"onetimepassword": true,
+	"oldpassword":     true,
+	"password":        true,
+	"privatekey":      true,
+	"recoverycode":    true,
+	"recoverycodes":   true,
+	"secret":          true,
+	"token":           true,
+	"usercode":        true,
+}
+
+const redacted = "[REDACTED]"
+
+// auditRequestSummary returns a JSON representation of the request body, with
+// the value of any field that may contain a secret replaced.
+func auditRequestSummary(req any) string {
+	if req == nil {
+		return ""
+	}
+
+	raw, err := json.Marshal(req)
+	if err != nil {
+		return ""
+	}
+
+	var body any
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return ""
+	}
+
+	raw, err = json.Marshal(redactSecrets(body))
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
+
+func redactSecrets(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			switch {
+			case field == nil || field == "":
+			case sensitiveFieldNames[strings.ToLower(key)]:
+				v[key] = redacted
+			default:
+				v[key] = redactSecrets(field)
+			}
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = redactSecrets(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
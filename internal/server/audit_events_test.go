@@ -0,0 +1,185 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAPI_ListAuditEvents(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	admin, err := data.GetIdentity(srv.DB(), data.ByName("admin@example.com"))
+	assert.NilError(t, err)
+
+	createGrant := func(t *testing.T, createReq api.CreateGrantRequest) *api.CreateGrantResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/grants", jsonBody(t, createReq))
+		req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		grant := &api.CreateGrantResponse{}
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), grant))
+		return grant
+	}
+
+	listEvents := func(t *testing.T, accessKey string, query string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/audit-events?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+accessKey)
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	start := time.Now().UTC().Add(-time.Second)
+	userID := uid.New()
+	grant := createGrant(t, api.CreateGrantRequest{User: userID, Privilege: "view", Resource: "production"})
+
+	t.Run("records mutating requests", func(t *testing.T) {
+		resp := listEvents(t, adminAccessKey(srv), "resource=grants")
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		events := &api.ListResponse[api.AuditEvent]{}
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), events))
+		assert.Equal(t, events.Count, 1)
+
+		event := events.Items[0]
+		assert.Equal(t, event.ActorID, admin.ID)
+		assert.Equal(t, event.ActorName, "admin@example.com")
+		assert.Equal(t, event.Method, http.MethodPost)
+		assert.Equal(t, event.Path, "/api/grants")
+		assert.Equal(t, event.Resource, "grants")
+		assert.Equal(t, event.ResourceID, grant.ID)
+		assert.Equal(t, event.StatusCode, http.StatusCreated)
+		assert.Assert(t, time.Time(event.Created).After(start))
+
+		var summary api.CreateGrantRequest
+		assert.NilError(t, json.Unmarshal([]byte(event.Request), &summary))
+		assert.Equal(t, summary.User, userID)
+		assert.Equal(t, summary.Resource, "production")
+	})
+
+	t.Run("filter by time range", func(t *testing.T) {
+		resp := listEvents(t, adminAccessKey(srv), "resource=grants&before="+start.Format(time.RFC3339))
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		events := &api.ListResponse[api.AuditEvent]{}
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), events))
+		assert.Equal(t, events.Count, 0)
+
+		resp = listEvents(t, adminAccessKey(srv), "resource=grants&after="+start.Format(time.RFC3339))
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), events))
+		assert.Equal(t, events.Count, 1)
+	})
+
+	t.Run("filter by actor", func(t *testing.T) {
+		resp := listEvents(t, adminAccessKey(srv), "actorID="+uid.New().String())
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		events := &api.ListResponse[api.AuditEvent]{}
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), events))
+		assert.Equal(t, events.Count, 0)
+	})
+
+	t.Run("records rejected requests", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/grants", strings.NewReader(`{"privilege": 1}`))
+		req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
+		req.Header.Set("Infra-Version", apiVersionLatest)
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		events, err := data.ListAuditEvents(srv.DB(), nil, data.ByOptionalActorID(admin.ID))
+		assert.NilError(t, err)
+		event := events[len(events)-1]
+		assert.Equal(t, event.Path, "/api/grants")
+		assert.Equal(t, event.StatusCode, http.StatusBadRequest)
+	})
+
+	t.Run("records requests to routes outside of the api", func(t *testing.T) {
+		form := url.Values{"grant_type": {"authorization_code"}}
+		req := httptest.NewRequest(http.MethodPost, "/oidc/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+
+		events, err := data.ListAuditEvents(srv.DB(), nil, data.ByResource("oidc"))
+		assert.NilError(t, err)
+		assert.Equal(t, len(events), 1)
+		assert.Equal(t, events[0].Path, "/oidc/token")
+		assert.Equal(t, events[0].StatusCode, resp.Code)
+	})
+
+	t.Run("requires admin", func(t *testing.T) {
+		key, _ := createAccessKey(t, srv.DB(), "someone@example.com")
+		resp := listEvents(t, key, "")
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+}
+
+func TestAuditRequestSummary(t *testing.T) {
+	req := api.LoginRequest{
+		PasswordCredentials: &api.LoginRequestPasswordCredentials{
+			Name:     "user@example.com",
+			Password: "the-password",
+		},
+	}
+
+	var actual map[string]any
+	assert.NilError(t, json.Unmarshal([]byte(auditRequestSummary(req)), &actual))
+
+	expected := map[string]any{
+		"accessKey":  "",
+		"oidc":       nil,
+		"federation": nil,
+		"ldap":       nil,
+		"saml":       nil,
+		"passwordCredentials": map[string]any{
+			"name":     "user@example.com",
+			"password": redacted,
+		},
+	}
+	assert.DeepEqual(t, actual, expected)
+}
+
+func TestAuditRequestSummary_ExactFieldNames(t *testing.T) {
+	req := map[string]any{
+		"keyName":         "ci",
+		"destinationCode": "prod",
+		"publicKey":       "ssh-ed25519 AAAA",
+		"clientSecret":    "the-secret",
+		"code":            "the-code",
+	}
+
+	var actual map[string]any
+	assert.NilError(t, json.Unmarshal([]byte(auditRequestSummary(req)), &actual))
+
+	expected := map[string]any{
+		"keyName":         "ci",
+		"destinationCode": "prod",
+		"publicKey":       "ssh-ed25519 AAAA",
+		"clientSecret":    redacted,
+		"code":            redacted,
+	}
+	assert.DeepEqual(t, actual, expected)
+}
@@ -24,6 +24,7 @@ type LoginMethod interface {
 
 type AuthScope struct {
 	PasswordResetOnly bool
+	MFAEnrollmentOnly bool
 }
 
 func Login(ctx context.Context, db data.GormTxn, loginMethod LoginMethod, requestedExpiry time.Time, keyExtension time.Duration) (*models.AccessKey, string, error) {
@@ -47,6 +48,9 @@ func Login(ctx context.Context, db data.GormTxn, loginMethod LoginMethod, reques
 	if authenticated.AuthScope.PasswordResetOnly {
 		accessKey.Scopes = append(accessKey.Scopes, models.ScopePasswordReset)
 	}
+	if authenticated.AuthScope.MFAEnrollmentOnly {
+		accessKey.Scopes = append(accessKey.Scopes, models.ScopeMFAEnrollment)
+	}
 
 	bearer, err := data.CreateAccessKey(db, accessKey)
 	if err != nil {
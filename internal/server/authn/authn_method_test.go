@@ -54,7 +54,7 @@ func TestLogin(t *testing.T) {
 	assert.NilError(t, err)
 
 	t.Run("failed login does not create access key", func(t *testing.T) {
-		authn := NewPasswordCredentialAuthentication(username, "invalid password")
+		authn := NewPasswordCredentialAuthentication(username, "invalid password", "")
 		_, bearer, err := Login(ctx, db, authn, time.Now().Add(1*time.Minute), time.Minute)
 
 		assert.ErrorContains(t, err, "failed to login")
@@ -62,7 +62,7 @@ func TestLogin(t *testing.T) {
 	})
 
 	t.Run("successful login does creates access key for authenticated identity", func(t *testing.T) {
-		authn := NewPasswordCredentialAuthentication("gohan@example.com", password)
+		authn := NewPasswordCredentialAuthentication("gohan@example.com", password, "")
 		exp := time.Now().Add(1 * time.Minute)
 		ext := 1 * time.Minute
 		key, bearer, err := Login(ctx, db, authn, exp, ext)
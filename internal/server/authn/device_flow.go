@@ -0,0 +1,91 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/data"
+)
+
+// ErrDeviceFlowPending is returned when the device flow request has not been
+// approved yet. The device should poll again later.
+var ErrDeviceFlowPending = errors.New("device flow request has not been approved")
+
+// ErrDeviceFlowSlowDown is returned when the device polls more often than the
+// interval. The device should increase its interval, as described by
+// RFC 8628 section 3.5.
+var ErrDeviceFlowSlowDown = errors.New("device flow request was polled too often")
+
+// deviceFlowAuthn exchanges the device code of an approved device flow request
+// for an access key issued to the user that approved the request
+type deviceFlowAuthn struct {
+	DeviceCode string
+	// Interval is the minimum time between two polls of the device.
+	Interval time.Duration
+}
+
+func NewDeviceFlowAuthentication(deviceCode string, interval time.Duration) LoginMethod {
+	return &deviceFlowAuthn{
+		DeviceCode: deviceCode,
+		Interval:   interval,
+	}
+}
+
+func (a *deviceFlowAuthn) Authenticate(_ context.Context, db data.GormTxn, requestedExpiry time.Time) (AuthenticatedIdentity, error) {
+	req, err := data.GetDeviceFlowAuthRequest(db, data.ByDeviceCode(a.DeviceCode))
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("get device flow request: %w", err)
+	}
+
+	now := time.Now()
+	if now.After(req.ExpiresAt) {
+		return AuthenticatedIdentity{}, internal.ErrExpired
+	}
+
+	lastPolledAt := req.LastPolledAt
+	req.LastPolledAt = now.UTC()
+	if err := data.SaveDeviceFlowAuthRequest(db, req); err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("update device flow request: %w", err)
+	}
+	if !lastPolledAt.IsZero() && now.Sub(lastPolledAt) < a.Interval {
+		return AuthenticatedIdentity{}, ErrDeviceFlowSlowDown
+	}
+
+	if req.ApprovedBy == 0 {
+		return AuthenticatedIdentity{}, ErrDeviceFlowPending
+	}
+
+	identity, err := data.GetIdentity(db, data.ByID(req.ApprovedBy))
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("user is not valid: %w", err) // the user was probably deleted
+	}
+
+	provider, err := data.GetProvider(db, data.ByID(req.ProviderID))
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("provider is not valid: %w", err)
+	}
+
+	// the device code can only be exchanged once
+	if err := data.DeleteDeviceFlowAuthRequest(db, req); err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("delete device flow request: %w", err)
+	}
+	logging.Debugf("exchanged device flow request %v for an access key", req.ID)
+
+	return AuthenticatedIdentity{
+		Identity:      identity,
+		Provider:      provider,
+		SessionExpiry: requestedExpiry,
+	}, nil
+}
+
+func (a *deviceFlowAuthn) Name() string {
+	return "device"
+}
+
+func (a *deviceFlowAuthn) RequiresUpdate(db data.GormTxn) (bool, error) {
+	return false, nil // not applicable to device flow
+}
@@ -0,0 +1,223 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+// federationAuthn exchanges a JWT signed by the issuer of a federation for an
+// access key issued for the identity of the federation
+type federationAuthn struct {
+	Token string
+}
+
+func NewFederationAuthentication(token string) LoginMethod {
+	return &federationAuthn{
+		Token: token,
+	}
+}
+
+func (a *federationAuthn) Authenticate(ctx context.Context, db data.GormTxn, requestedExpiry time.Time) (AuthenticatedIdentity, error) {
+	issuer, err := unverifiedIssuer(a.Token)
+	if err != nil {
+		return AuthenticatedIdentity{}, err
+	}
+
+	federations, err := data.ListFederations(db, nil, data.ByIssuer(issuer))
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("list federations: %w", err)
+	}
+
+	if len(federations) == 0 {
+		return AuthenticatedIdentity{}, fmt.Errorf("no federation for issuer %q", issuer)
+	}
+
+	var failures []string
+	for _, federation := range federations {
+		// federations created before conditions on the subject were required
+		// would accept tokens issued to any workload
+		if !federation.Conditions.RestrictsSubject() {
+			failures = append(failures, fmt.Sprintf("%v: no sub or repository condition with a literal owner", federation.Name))
+			continue
+		}
+
+		claims, err := verifyFederationToken(ctx, federation, a.Token)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return AuthenticatedIdentity{}, fmt.Errorf("%w: %s", internal.ErrBadGateway, err.Error())
+			}
+			failures = append(failures, fmt.Sprintf("%v: %v", federation.Name, err))
+			continue
+		}
+
+		if err := matchConditions(federation.Conditions, claims); err != nil {
+			failures = append(failures, fmt.Sprintf("%v: %v", federation.Name, err))
+			continue
+		}
+
+		identity, err := data.GetIdentity(db, data.ByID(federation.IdentityID))
+		if err != nil {
+			return AuthenticatedIdentity{}, fmt.Errorf("user is not valid: %w", err) // the user was probably deleted
+		}
+
+		sessionExpiry := time.Now().UTC().Add(federation.TTL)
+		if requestedExpiry.Before(sessionExpiry) {
+			sessionExpiry = requestedExpiry
+		}
+
+		logging.Debugf("token from %q matched federation %q", issuer, federation.Name)
+		return AuthenticatedIdentity{
+			Identity:      identity,
+			Provider:      data.InfraProvider(db),
+			SessionExpiry: sessionExpiry,
+		}, nil
+	}
+
+	return AuthenticatedIdentity{}, fmt.Errorf("token did not match any federation: %v", strings.Join(failures, "; "))
+}
+
+func (a *federationAuthn) Name() string {
+	return "federation"
+}
+
+func (a *federationAuthn) RequiresUpdate(db data.GormTxn) (bool, error) {
+	return false, nil // not applicable to federation
+}
+
+// unverifiedIssuer returns the issuer of the token, so that the federations
+// for the issuer can be used to verify it.
+func unverifiedIssuer(token string) (string, error) {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return "", fmt.Errorf("invalid JWT: %w", err)
+	}
+
+	var claims jwt.Claims
+	if err := parsed.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		return "", fmt.Errorf("invalid JWT claims: %w", err)
+	}
+
+	if claims.Issuer == "" {
+		return "", fmt.Errorf("missing JWT issuer")
+	}
+	return claims.Issuer, nil
+}
+
+var federationSigningAlgs = []string{
+	oidc.RS256, oidc.RS384, oidc.RS512,
+	oidc.ES256, oidc.ES384, oidc.ES512,
+	oidc.PS256, oidc.PS384, oidc.PS512,
+}
+
+// verifyFederationToken checks the signature, issuer, audience, and expiry of
+// the token, and returns its claims.
+func verifyFederationToken(ctx context.Context, federation models.Federation, token string) (map[string]any, error) {
+	jwksURL := federation.JWKSURL
+	if jwksURL == "" {
+		provider, err := oidc.NewProvider(ctx, federation.Issuer)
+		if err != nil {
+			return nil, fmt.Errorf("discover issuer: %w", err)
+		}
+
+		var discovery struct {
+			JWKSURL string `json:"jwks_uri"`
+		}
+		if err := provider.Claims(&discovery); err != nil {
+			return nil, fmt.Errorf("discover issuer: %w", err)
+		}
+		jwksURL = discovery.JWKSURL
+	}
+
+	verifier := oidc.NewVerifier(federation.Issuer, federationKeySet(jwksURL), &oidc.Config{
+		ClientID:             federation.Audience,
+		SupportedSigningAlgs: federationSigningAlgs,
+	})
+
+	verified, err := verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := map[string]any{}
+	if err := verified.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("invalid JWT claims: %w", err)
+	}
+	return claims, nil
+}
+
+var federationKeySets = struct {
+	sync.Mutex
+	byURL map[string]*oidc.RemoteKeySet
+}{byURL: map[string]*oidc.RemoteKeySet{}}
+
+// federationKeySet returns the key set at jwksURL. Key sets are cached, so that
+// the keys are only fetched again when a token is signed with an unknown key.
+func federationKeySet(jwksURL string) *oidc.RemoteKeySet {
+	federationKeySets.Lock()
+	defer federationKeySets.Unlock()
+
+	keySet, ok := federationKeySets.byURL[jwksURL]
+	if !ok {
+		// the key set outlives the request, so it must not use the request context
+		keySet = oidc.NewRemoteKeySet(context.Background(), jwksURL)
+		federationKeySets.byURL[jwksURL] = keySet
+	}
+	return keySet
+}
+
+// matchConditions returns an error if any of the conditions do not match the
+// claims. A condition may use path.Match patterns, for example refs/heads/*.
+// A condition on a claim with a list of values matches if any value matches.
+func matchConditions(conditions models.FederationConditions, claims map[string]any) error {
+	names := make([]string, 0, len(conditions))
+	for name := range conditions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		pattern := conditions[name]
+
+		var values []string
+		switch value := claims[name].(type) {
+		case nil:
+			return fmt.Errorf("missing claim %q", name)
+		case string:
+			values = []string{value}
+		case []any:
+			for _, v := range value {
+				values = append(values, fmt.Sprint(v))
+			}
+		default:
+			values = []string{fmt.Sprint(value)}
+		}
+
+		if !matchAny(pattern, values) {
+			return fmt.Errorf("claim %q does not match %q", name, pattern)
+		}
+	}
+	return nil
+}
+
+func matchAny(pattern string, values []string) bool {
+	for _, value := range values {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+package authn
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+// issuer is a JWT issuer with a JWKS endpoint and OpenID configuration.
+type issuer struct {
+	URL string
+	key *rsa.PrivateKey
+}
+
+func newIssuer(t *testing.T) *issuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NilError(t, err)
+
+	iss := &issuer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   iss.URL,
+			"jwks_uri": iss.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: key.Public(), KeyID: "the-key", Algorithm: string(jose.RS256), Use: "sig"},
+		}})
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	iss.URL = srv.URL
+	return iss
+}
+
+func (i *issuer) token(t *testing.T, audience string, claims map[string]any) string {
+	t.Helper()
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: i.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "the-key"))
+	assert.NilError(t, err)
+
+	now := time.Now()
+	std := jwt.Claims{
+		Issuer:   i.URL,
+		Subject:  "repo:example/app:ref:refs/heads/main",
+		Audience: jwt.Audience{audience},
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(now.Add(5 * time.Minute)),
+	}
+	raw, err := jwt.Signed(signer).Claims(std).Claims(claims).CompactSerialize()
+	assert.NilError(t, err)
+	return raw
+}
+
+func TestFederationAuthentication(t *testing.T) {
+	db := setupDB(t)
+	iss := newIssuer(t)
+
+	user := &models.Identity{Name: "deploy@example.com"}
+	assert.NilError(t, data.CreateIdentity(db, user))
+
+	federation := &models.Federation{
+		Name:     "deploy",
+		Issuer:   iss.URL,
+		JWKSURL:  iss.URL + "/keys",
+		Audience: "https://infra.example.com",
+		Conditions: models.FederationConditions{
+			"repository": "example/app",
+			"ref":        "refs/heads/*",
+		},
+		IdentityID: user.ID,
+		TTL:        10 * time.Minute,
+	}
+	assert.NilError(t, data.CreateFederation(db, federation))
+
+	mainClaims := map[string]any{"repository": "example/app", "ref": "refs/heads/main"}
+	requestedExpiry := time.Now().Add(time.Hour)
+
+	t.Run("matching token", func(t *testing.T) {
+		token := iss.token(t, "https://infra.example.com", mainClaims)
+
+		authenticated, err := NewFederationAuthentication(token).Authenticate(context.Background(), db, requestedExpiry)
+		assert.NilError(t, err)
+		assert.Equal(t, authenticated.Identity.ID, user.ID)
+		assert.Equal(t, authenticated.Provider.ID, data.InfraProvider(db).ID)
+
+		// the access key is limited to the TTL of the federation
+		assert.Assert(t, authenticated.SessionExpiry.Before(time.Now().Add(11*time.Minute)))
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		token := iss.token(t, "https://other.example.com", mainClaims)
+
+		_, err := NewFederationAuthentication(token).Authenticate(context.Background(), db, requestedExpiry)
+		assert.ErrorContains(t, err, "expected audience")
+	})
+
+	t.Run("claims do not match conditions", func(t *testing.T) {
+		token := iss.token(t, "https://infra.example.com", map[string]any{
+			"repository": "example/app",
+			"ref":        "refs/tags/v1",
+		})
+
+		_, err := NewFederationAuthentication(token).Authenticate(context.Background(), db, requestedExpiry)
+		assert.ErrorContains(t, err, `claim "ref" does not match "refs/heads/*"`)
+	})
+
+	t.Run("token for a foreign repository", func(t *testing.T) {
+		// the issuer signs tokens for the workloads of every repository, with
+		// the same audience
+		token := iss.token(t, "https://infra.example.com", map[string]any{
+			"repository": "attacker/app",
+			"ref":        "refs/heads/main",
+		})
+
+		_, err := NewFederationAuthentication(token).Authenticate(context.Background(), db, requestedExpiry)
+		assert.ErrorContains(t, err, `claim "repository" does not match "example/app"`)
+	})
+
+	t.Run("federation without a subject condition", func(t *testing.T) {
+		unrestricted := newIssuer(t)
+		assert.NilError(t, data.CreateFederation(db, &models.Federation{
+			Name:       "unrestricted",
+			Issuer:     unrestricted.URL,
+			JWKSURL:    unrestricted.URL + "/keys",
+			Audience:   "https://infra.example.com",
+			Conditions: models.FederationConditions{"ref": "refs/heads/main"},
+			IdentityID: user.ID,
+			TTL:        time.Hour,
+		}))
+
+		token := unrestricted.token(t, "https://infra.example.com", mainClaims)
+		_, err := NewFederationAuthentication(token).Authenticate(context.Background(), db, requestedExpiry)
+		assert.ErrorContains(t, err, "unrestricted: no sub or repository condition")
+	})
+
+	t.Run("federation with a wildcard owner", func(t *testing.T) {
+		wildcard := newIssuer(t)
+		assert.NilError(t, data.CreateFederation(db, &models.Federation{
+			Name:       "wildcard",
+			Issuer:     wildcard.URL,
+			JWKSURL:    wildcard.URL + "/keys",
+			Audience:   "https://infra.example.com",
+			Conditions: models.FederationConditions{"repository": "*/*"},
+			IdentityID: user.ID,
+			TTL:        time.Hour,
+		}))
+
+		token := wildcard.token(t, "https://infra.example.com", map[string]any{"repository": "attacker/app"})
+		_, err := NewFederationAuthentication(token).Authenticate(context.Background(), db, requestedExpiry)
+		assert.ErrorContains(t, err, "wildcard: no sub or repository condition with a literal owner")
+	})
+
+	t.Run("missing claim", func(t *testing.T) {
+		token := iss.token(t, "https://infra.example.com", map[string]any{"ref": "refs/heads/main"})
+
+		_, err := NewFederationAuthentication(token).Authenticate(context.Background(), db, requestedExpiry)
+		assert.ErrorContains(t, err, `missing claim "repository"`)
+	})
+
+	t.Run("signed by another key", func(t *testing.T) {
+		other := newIssuer(t)
+		other.URL = iss.URL // pretend to be the trusted issuer
+		token := other.token(t, "https://infra.example.com", mainClaims)
+
+		_, err := NewFederationAuthentication(token).Authenticate(context.Background(), db, requestedExpiry)
+		assert.ErrorContains(t, err, "failed to verify signature")
+	})
+
+	t.Run("unknown issuer", func(t *testing.T) {
+		other := newIssuer(t)
+		token := other.token(t, "https://infra.example.com", mainClaims)
+
+		_, err := NewFederationAuthentication(token).Authenticate(context.Background(), db, requestedExpiry)
+		assert.ErrorContains(t, err, "no federation for issuer")
+	})
+
+	t.Run("keys from discovery", func(t *testing.T) {
+		discovered := newIssuer(t)
+		assert.NilError(t, data.CreateFederation(db, &models.Federation{
+			Name:       "discovered",
+			Issuer:     discovered.URL,
+			Audience:   "https://infra.example.com",
+			Conditions: models.FederationConditions{"sub": "repo:example/app:ref:refs/heads/*"},
+			IdentityID: user.ID,
+			TTL:        time.Hour,
+		}))
+
+		token := discovered.token(t, "https://infra.example.com", nil)
+		authenticated, err := NewFederationAuthentication(token).Authenticate(context.Background(), db, requestedExpiry)
+		assert.NilError(t, err)
+		assert.Equal(t, authenticated.Identity.ID, user.ID)
+	})
+}
+
+func TestMatchConditions(t *testing.T) {
+	claims := map[string]any{
+		"repository": "example/app",
+		"ref":        "refs/heads/main",
+		"groups":     []any{"dev", "ops"},
+		"run_number": float64(12),
+	}
+
+	type testCase struct {
+		conditions  models.FederationConditions
+		expectedErr string
+	}
+
+	testCases := []testCase{
+		{conditions: nil},
+		{conditions: models.FederationConditions{"repository": "example/app"}},
+		{conditions: models.FederationConditions{"repository": "example/*"}},
+		{conditions: models.FederationConditions{"groups": "ops"}},
+		{conditions: models.FederationConditions{"run_number": "12"}},
+		{
+			conditions:  models.FederationConditions{"repository": "example/other"},
+			expectedErr: `claim "repository" does not match "example/other"`,
+		},
+		{
+			conditions:  models.FederationConditions{"ref": "refs/*"},
+			expectedErr: `claim "ref" does not match "refs/*"`,
+		},
+		{
+			conditions:  models.FederationConditions{"groups": "admin"},
+			expectedErr: `claim "groups" does not match "admin"`,
+		},
+		{
+			conditions:  models.FederationConditions{"environment": "production"},
+			expectedErr: `missing claim "environment"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		err := matchConditions(tc.conditions, claims)
+		if tc.expectedErr == "" {
+			assert.NilError(t, err, "conditions=%v", tc.conditions)
+			continue
+		}
+		assert.ErrorContains(t, err, tc.expectedErr, "conditions=%v", tc.conditions)
+	}
+}
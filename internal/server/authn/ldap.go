@@ -0,0 +1,82 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+	"github.com/infrahq/infra/uid"
+)
+
+// ldapAuthn allows presenting the username and password of a user in an LDAP
+// directory in exchange for an access key
+type ldapAuthn struct {
+	ProviderID uid.ID
+	Username   string
+	Password   string
+	LDAPClient providers.LDAPClient
+}
+
+func NewLDAPAuthentication(providerID uid.ID, username, password string, ldapClient providers.LDAPClient) LoginMethod {
+	return &ldapAuthn{
+		ProviderID: providerID,
+		Username:   username,
+		Password:   password,
+		LDAPClient: ldapClient,
+	}
+}
+
+func (a *ldapAuthn) Authenticate(ctx context.Context, db data.GormTxn, requestedExpiry time.Time) (AuthenticatedIdentity, error) {
+	provider, err := data.GetProvider(db, data.ByID(a.ProviderID))
+	if err != nil {
+		return AuthenticatedIdentity{}, err
+	}
+	if provider.Kind != models.ProviderKindLDAP {
+		return AuthenticatedIdentity{}, fmt.Errorf("provider %s is not an ldap provider", provider.Name)
+	}
+
+	user, err := a.LDAPClient.Authenticate(ctx, a.Username, a.Password)
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("ldap authentication: %w", err)
+	}
+
+	identity, err := data.GetIdentity(db, data.Preload("Groups"), data.ByName(user.Email))
+	if err != nil {
+		if !errors.Is(err, internal.ErrNotFound) {
+			return AuthenticatedIdentity{}, fmt.Errorf("get user: %w", err)
+		}
+
+		identity = &models.Identity{Name: user.Email}
+
+		if err := data.CreateIdentity(db, identity); err != nil {
+			return AuthenticatedIdentity{}, fmt.Errorf("create user: %w", err)
+		}
+	}
+
+	if _, err := data.CreateProviderUser(db, provider, identity); err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("add user for provider login: %w", err)
+	}
+
+	if err := data.AssignIdentityToGroups(db, identity, provider, user.Groups); err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("assign identity to groups: %w", err)
+	}
+
+	return AuthenticatedIdentity{
+		Identity:      identity,
+		Provider:      provider,
+		SessionExpiry: requestedExpiry,
+	}, nil
+}
+
+func (a *ldapAuthn) Name() string {
+	return "ldap"
+}
+
+func (a *ldapAuthn) RequiresUpdate(db data.GormTxn) (bool, error) {
+	return false, nil // not applicable to ldap
+}
@@ -0,0 +1,119 @@
+package authn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+	"github.com/infrahq/infra/uid"
+)
+
+// mockLDAP is a mock ldap directory with a single user
+type mockLDAPImplementation struct {
+	Username string
+	Password string
+	User     providers.LDAPUser
+}
+
+func (m *mockLDAPImplementation) Validate(_ context.Context) error {
+	return nil
+}
+
+func (m *mockLDAPImplementation) Authenticate(_ context.Context, username, password string) (*providers.LDAPUser, error) {
+	if username != m.Username || password != m.Password {
+		return nil, providers.ErrInvalidLDAPCredentials
+	}
+	user := m.User
+	return &user, nil
+}
+
+func (m *mockLDAPImplementation) GetUser(_ context.Context, email string) (*providers.LDAPUser, error) {
+	if email != m.User.Email {
+		return nil, providers.ErrInvalidLDAPCredentials
+	}
+	user := m.User
+	return &user, nil
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	db := setupDB(t)
+
+	provider := &models.Provider{Name: "ad", Kind: models.ProviderKindLDAP}
+	err := data.CreateProvider(db, provider)
+	assert.NilError(t, err)
+
+	ldap := &mockLDAPImplementation{
+		Username: "bruce",
+		Password: "password",
+		User: providers.LDAPUser{
+			DN:     "uid=bruce,dc=example,dc=com",
+			Email:  "bruce@example.com",
+			Groups: []string{"Everyone", "developers"},
+		},
+	}
+	expiry := time.Now().Add(time.Minute)
+
+	t.Run("invalid provider", func(t *testing.T) {
+		authn := NewLDAPAuthentication(uid.New(), "bruce", "password", ldap)
+		_, err := authn.Authenticate(context.Background(), db, expiry)
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+	})
+
+	t.Run("provider is not ldap", func(t *testing.T) {
+		oidc := &models.Provider{Name: "mockta", Kind: models.ProviderKindOkta}
+		err := data.CreateProvider(db, oidc)
+		assert.NilError(t, err)
+
+		authn := NewLDAPAuthentication(oidc.ID, "bruce", "password", ldap)
+		_, err = authn.Authenticate(context.Background(), db, expiry)
+		assert.ErrorContains(t, err, "not an ldap provider")
+	})
+
+	t.Run("invalid password", func(t *testing.T) {
+		authn := NewLDAPAuthentication(provider.ID, "bruce", "wrong", ldap)
+		_, err := authn.Authenticate(context.Background(), db, expiry)
+		assert.ErrorIs(t, err, providers.ErrInvalidLDAPCredentials)
+	})
+
+	t.Run("successful authentication", func(t *testing.T) {
+		authn := NewLDAPAuthentication(provider.ID, "bruce", "password", ldap)
+		authnIdentity, err := authn.Authenticate(context.Background(), db, expiry)
+		assert.NilError(t, err)
+
+		assert.Equal(t, authnIdentity.Identity.Name, "bruce@example.com")
+		assert.Equal(t, authnIdentity.Provider.ID, provider.ID)
+		assert.Assert(t, authnIdentity.SessionExpiry.Equal(expiry))
+
+		var groupNames []string
+		for _, g := range authnIdentity.Identity.Groups {
+			groupNames = append(groupNames, g.Name)
+		}
+		assert.Assert(t, is.Len(groupNames, 2))
+		assert.Assert(t, is.Contains(groupNames, "Everyone"))
+		assert.Assert(t, is.Contains(groupNames, "developers"))
+
+		pu, err := data.GetProviderUser(db, provider.ID, authnIdentity.Identity.ID)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, pu.Groups, models.CommaSeparatedStrings{"Everyone", "developers"})
+	})
+
+	t.Run("groups removed in the directory are removed from the user", func(t *testing.T) {
+		ldap.User.Groups = []string{"developers"}
+
+		authn := NewLDAPAuthentication(provider.ID, "bruce", "password", ldap)
+		authnIdentity, err := authn.Authenticate(context.Background(), db, expiry)
+		assert.NilError(t, err)
+
+		identity, err := data.GetIdentity(db, data.Preload("Groups"), data.ByID(authnIdentity.Identity.ID))
+		assert.NilError(t, err)
+		assert.Assert(t, is.Len(identity.Groups, 1))
+		assert.Equal(t, identity.Groups[0].Name, "developers")
+	})
+}
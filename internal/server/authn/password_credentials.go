@@ -2,24 +2,49 @@ package authn
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
 	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
 )
 
+// ErrMFACodeRequired is returned when the user has enabled multi-factor
+// authentication, and no code was presented with their password.
+var ErrMFACodeRequired = errors.New("multi-factor authentication code required")
+
+// ErrLockedOut is returned when the user has been locked out because of too
+// many failed logins.
+var ErrLockedOut = errors.New("locked out after too many failed logins")
+
+// LockoutsCounter counts the times that users were locked out because of too
+// many failed logins.
+var LockoutsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "infra",
+	Name:      "user_lockouts_total",
+	Help:      "The number of times users were locked out because of failed logins",
+})
+
 // passwordCredentialAuthn allows presenting username/password credentials in exchange for an access key
 type passwordCredentialAuthn struct {
 	Username string
 	Password string
+	// MFACode is a TOTP code or a recovery code, required if the user has
+	// enabled multi-factor authentication.
+	MFACode string
 }
 
-func NewPasswordCredentialAuthentication(username, password string) LoginMethod {
+func NewPasswordCredentialAuthentication(username, password, mfaCode string) LoginMethod {
 	return &passwordCredentialAuthn{
 		Username: username,
 		Password: password,
+		MFACode:  mfaCode,
 	}
 }
 
@@ -35,9 +60,16 @@ func (a *passwordCredentialAuthn) Authenticate(_ context.Context, db data.GormTx
 		return AuthenticatedIdentity{}, fmt.Errorf("validate creds get user: %w", err)
 	}
 
+	if time.Now().Before(userCredential.LockedUntil) {
+		return AuthenticatedIdentity{}, fmt.Errorf("%w until %v", ErrLockedOut, userCredential.LockedUntil.Format(time.RFC3339))
+	}
+
 	// compare the stored hash of the user's password and the hash of the presented password
 	err = bcrypt.CompareHashAndPassword(userCredential.PasswordHash, []byte(a.Password))
 	if err != nil {
+		if err := recordFailedLogin(db, userCredential); err != nil {
+			return AuthenticatedIdentity{}, err
+		}
 		// this probably means the password was wrong
 		return AuthenticatedIdentity{}, fmt.Errorf("could not verify password: %w", err)
 	}
@@ -53,6 +85,31 @@ func (a *passwordCredentialAuthn) Authenticate(_ context.Context, db data.GormTx
 		authnIdentity.AuthScope.PasswordResetOnly = true
 	}
 
+	if userCredential.MFAEnabled {
+		if err := verifyMFACode(db, userCredential, a.MFACode); err != nil {
+			if !errors.Is(err, ErrMFACodeRequired) {
+				if err := recordFailedLogin(db, userCredential); err != nil {
+					return AuthenticatedIdentity{}, err
+				}
+			}
+			return AuthenticatedIdentity{}, err
+		}
+	} else {
+		required, err := mfaRequired(db, identity)
+		if err != nil {
+			return AuthenticatedIdentity{}, err
+		}
+		// scope the login down to MFA enrollment until the user enables it
+		authnIdentity.AuthScope.MFAEnrollmentOnly = required
+	}
+
+	if userCredential.FailedLoginAttempts > 0 {
+		userCredential.FailedLoginAttempts = 0
+		if err := data.SaveCredential(db, userCredential); err != nil {
+			return AuthenticatedIdentity{}, fmt.Errorf("save credential: %w", err)
+		}
+	}
+
 	// authentication was a success
 	return authnIdentity, nil // password login is always for infra users
 }
@@ -74,3 +131,78 @@ func (a *passwordCredentialAuthn) RequiresUpdate(db data.GormTxn) (bool, error)
 
 	return cred.OneTimePassword, nil
 }
+
+// recordFailedLogin counts a failed login for the credential, and locks out
+// the user once the lockout threshold from the organization settings is
+// reached. The request transaction is committed even when the login fails, so
+// the count is saved.
+func recordFailedLogin(db data.GormTxn, credential *models.Credential) error {
+	settings, err := data.GetSettings(db)
+	if err != nil {
+		return fmt.Errorf("get settings: %w", err)
+	}
+
+	lockedUntil := time.Now().Add(settings.LockoutDuration)
+	lockedOut, err := data.RecordFailedLogin(db, credential, settings.LockoutThreshold, lockedUntil)
+	if err != nil {
+		return fmt.Errorf("record failed login: %w", err)
+	}
+	if lockedOut {
+		logging.L.Info().
+			Str("identityID", credential.IdentityID.String()).
+			Time("lockedUntil", credential.LockedUntil).
+			Msg("user locked out after too many failed logins")
+		LockoutsCounter.Inc()
+	}
+	return nil
+}
+
+// verifyMFACode checks that code is a valid TOTP code or an unused recovery
+// code for the credential. The code is recorded so that it can not be used
+// again.
+func verifyMFACode(db data.GormTxn, credential *models.Credential, code string) error {
+	if code == "" {
+		return ErrMFACodeRequired
+	}
+
+	if step, ok := ValidateTOTP(string(credential.MFASecret), code, time.Now()); ok {
+		if step <= credential.MFALastUsedStep {
+			return fmt.Errorf("multi-factor authentication code was already used")
+		}
+		credential.MFALastUsedStep = step
+	} else if remaining, ok := useMFARecoveryCode(string(credential.MFARecoveryCodes), code); ok {
+		credential.MFARecoveryCodes = models.EncryptedAtRest(remaining)
+	} else {
+		return fmt.Errorf("invalid multi-factor authentication code")
+	}
+
+	if err := data.SaveCredential(db, credential); err != nil {
+		return fmt.Errorf("save credential: %w", err)
+	}
+	return nil
+}
+
+// mfaRequired returns true if the organization settings require identity to
+// use multi-factor authentication.
+func mfaRequired(db data.GormTxn, identity *models.Identity) (bool, error) {
+	settings, err := data.GetSettings(db)
+	if err != nil {
+		return false, fmt.Errorf("get settings: %w", err)
+	}
+
+	switch settings.RequireMFA {
+	case api.MFARequirementAll:
+		return true, nil
+	case api.MFARequirementAdmins:
+		grants, err := data.ListGrants(db, &models.Pagination{Limit: 1},
+			data.GrantsInheritedBySubject(identity.PolyID()),
+			data.ByPrivilege(models.InfraAdminRole),
+			data.ByResource("infra"))
+		if err != nil {
+			return false, fmt.Errorf("list grants: %w", err)
+		}
+		return len(grants) > 0, nil
+	default:
+		return false, nil
+	}
+}
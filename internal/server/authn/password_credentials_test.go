@@ -5,9 +5,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"golang.org/x/crypto/bcrypt"
 	"gotest.tools/v3/assert"
 
+	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
 )
@@ -42,7 +44,7 @@ func TestPasswordCredentialAuthentication(t *testing.T) {
 				err = data.CreateCredential(db, &creds)
 				assert.NilError(t, err)
 
-				return NewPasswordCredentialAuthentication(username, oneTimePassword)
+				return NewPasswordCredentialAuthentication(username, oneTimePassword, "")
 			},
 			expected: func(t *testing.T, authnIdentity AuthenticatedIdentity) {
 				assert.Equal(t, "goku@example.com", authnIdentity.Identity.Name)
@@ -70,7 +72,7 @@ func TestPasswordCredentialAuthentication(t *testing.T) {
 				err = data.CreateCredential(db, &creds)
 				assert.NilError(t, err)
 
-				return NewPasswordCredentialAuthentication(username, password)
+				return NewPasswordCredentialAuthentication(username, password, "")
 			},
 			expected: func(t *testing.T, authnIdentity AuthenticatedIdentity) {
 				assert.Equal(t, "bulma@example.com", authnIdentity.Identity.Name)
@@ -98,7 +100,7 @@ func TestPasswordCredentialAuthentication(t *testing.T) {
 				err = data.CreateCredential(db, &creds)
 				assert.NilError(t, err)
 
-				userPassLogin := NewPasswordCredentialAuthentication(username, password)
+				userPassLogin := NewPasswordCredentialAuthentication(username, password, "")
 
 				_, err = userPassLogin.Authenticate(context.Background(), db, time.Now().Add(1*time.Minute))
 				assert.NilError(t, err)
@@ -118,7 +120,7 @@ func TestPasswordCredentialAuthentication(t *testing.T) {
 				err := data.CreateIdentity(db, user)
 				assert.NilError(t, err)
 
-				return NewPasswordCredentialAuthentication(username, "")
+				return NewPasswordCredentialAuthentication(username, "", "")
 			},
 			expectedErr: "record not found",
 		},
@@ -142,7 +144,7 @@ func TestPasswordCredentialAuthentication(t *testing.T) {
 				err = data.CreateCredential(db, &creds)
 				assert.NilError(t, err)
 
-				return NewPasswordCredentialAuthentication(username, "invalidPassword")
+				return NewPasswordCredentialAuthentication(username, "invalidPassword", "")
 			},
 			expectedErr: "hashedPassword is not the hash of the given password",
 		},
@@ -166,13 +168,13 @@ func TestPasswordCredentialAuthentication(t *testing.T) {
 				err = data.CreateCredential(db, &creds)
 				assert.NilError(t, err)
 
-				return NewPasswordCredentialAuthentication(username, "")
+				return NewPasswordCredentialAuthentication(username, "", "")
 			},
 			expectedErr: "hashedPassword is not the hash of the given password",
 		},
 		"EmptyUsernameAndPasswordFails": {
 			setup: func(t *testing.T, db data.GormTxn) LoginMethod {
-				return NewPasswordCredentialAuthentication("", "whatever")
+				return NewPasswordCredentialAuthentication("", "whatever", "")
 			},
 			expectedErr: "record not found",
 		},
@@ -192,3 +194,174 @@ func TestPasswordCredentialAuthentication(t *testing.T) {
 		})
 	}
 }
+
+func TestPasswordCredentialAuthentication_MFA(t *testing.T) {
+	db := setupDB(t)
+
+	createUser := func(t *testing.T, name string, mfa bool) (*models.Identity, string) {
+		t.Helper()
+		user := &models.Identity{Name: name}
+		assert.NilError(t, data.CreateIdentity(db, user))
+
+		hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+		assert.NilError(t, err)
+
+		secret, err := NewTOTPSecret()
+		assert.NilError(t, err)
+
+		creds := &models.Credential{IdentityID: user.ID, PasswordHash: hash}
+		if mfa {
+			creds.MFAEnabled = true
+			creds.MFASecret = models.EncryptedAtRest(secret)
+		}
+		assert.NilError(t, data.CreateCredential(db, creds))
+		return user, secret
+	}
+
+	login := func(name, code string) (AuthenticatedIdentity, error) {
+		method := NewPasswordCredentialAuthentication(name, "password123", code)
+		return method.Authenticate(context.Background(), db, time.Now().Add(time.Minute))
+	}
+
+	_, secret := createUser(t, "vegeta@example.com", true)
+
+	t.Run("code required", func(t *testing.T) {
+		_, err := login("vegeta@example.com", "")
+		assert.ErrorIs(t, err, ErrMFACodeRequired)
+	})
+
+	t.Run("invalid code", func(t *testing.T) {
+		_, err := login("vegeta@example.com", "000000")
+		assert.ErrorContains(t, err, "invalid multi-factor authentication code")
+	})
+
+	t.Run("valid code", func(t *testing.T) {
+		code, err := TOTPCode(secret, time.Now())
+		assert.NilError(t, err)
+		authnIdentity, err := login("vegeta@example.com", code)
+		assert.NilError(t, err)
+		assert.Equal(t, authnIdentity.Identity.Name, "vegeta@example.com")
+
+		_, err = login("vegeta@example.com", code)
+		assert.ErrorContains(t, err, "already used")
+	})
+
+	t.Run("recovery code", func(t *testing.T) {
+		user, _ := data.GetIdentity(db, data.ByName("vegeta@example.com"))
+		creds, err := data.GetCredential(db, data.ByIdentityID(user.ID))
+		assert.NilError(t, err)
+
+		codes, stored, err := NewMFARecoveryCodes()
+		assert.NilError(t, err)
+		creds.MFARecoveryCodes = models.EncryptedAtRest(stored)
+		assert.NilError(t, data.SaveCredential(db, creds))
+
+		_, err = login("vegeta@example.com", codes[0])
+		assert.NilError(t, err)
+
+		_, err = login("vegeta@example.com", codes[0])
+		assert.ErrorContains(t, err, "invalid multi-factor authentication code")
+	})
+
+	t.Run("enrollment required by settings", func(t *testing.T) {
+		admin, _ := createUser(t, "trunks@example.com", false)
+		createUser(t, "goten@example.com", false)
+		assert.NilError(t, data.CreateGrant(db, &models.Grant{
+			Subject:   admin.PolyID(),
+			Privilege: models.InfraAdminRole,
+			Resource:  "infra",
+		}))
+
+		settings, err := data.GetSettings(db)
+		assert.NilError(t, err)
+		settings.RequireMFA = api.MFARequirementAdmins
+		assert.NilError(t, data.SaveSettings(db, settings))
+
+		authnIdentity, err := login("trunks@example.com", "")
+		assert.NilError(t, err)
+		assert.Assert(t, authnIdentity.AuthScope.MFAEnrollmentOnly)
+
+		authnIdentity, err = login("goten@example.com", "")
+		assert.NilError(t, err)
+		assert.Assert(t, !authnIdentity.AuthScope.MFAEnrollmentOnly)
+
+		settings.RequireMFA = api.MFARequirementAll
+		assert.NilError(t, data.SaveSettings(db, settings))
+
+		authnIdentity, err = login("goten@example.com", "")
+		assert.NilError(t, err)
+		assert.Assert(t, authnIdentity.AuthScope.MFAEnrollmentOnly)
+	})
+}
+
+func TestPasswordCredentialAuthentication_Lockout(t *testing.T) {
+	db := setupDB(t)
+
+	settings, err := data.GetSettings(db)
+	assert.NilError(t, err)
+	settings.LockoutThreshold = 3
+	settings.LockoutDuration = time.Hour
+	assert.NilError(t, data.SaveSettings(db, settings))
+
+	user := &models.Identity{Name: "goku@example.com"}
+	assert.NilError(t, data.CreateIdentity(db, user))
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	assert.NilError(t, err)
+	assert.NilError(t, data.CreateCredential(db, &models.Credential{IdentityID: user.ID, PasswordHash: hash}))
+
+	login := func(password string) error {
+		method := NewPasswordCredentialAuthentication("goku@example.com", password, "")
+		_, err := method.Authenticate(context.Background(), db, time.Now().Add(time.Minute))
+		return err
+	}
+
+	getCredential := func(t *testing.T) *models.Credential {
+		t.Helper()
+		creds, err := data.GetCredential(db, data.ByIdentityID(user.ID))
+		assert.NilError(t, err)
+		return creds
+	}
+
+	t.Run("successful login resets failed attempts", func(t *testing.T) {
+		assert.ErrorContains(t, login("wrong"), "could not verify password")
+		assert.Equal(t, getCredential(t).FailedLoginAttempts, 1)
+
+		assert.NilError(t, login("password123"))
+		assert.Equal(t, getCredential(t).FailedLoginAttempts, 0)
+	})
+
+	t.Run("concurrent failed logins are all counted", func(t *testing.T) {
+		// both requests read the credential before either saves the count
+		first, second := getCredential(t), getCredential(t)
+		assert.NilError(t, recordFailedLogin(db, first))
+		assert.NilError(t, recordFailedLogin(db, second))
+		assert.Equal(t, getCredential(t).FailedLoginAttempts, 2)
+
+		assert.NilError(t, login("password123"))
+		assert.Equal(t, getCredential(t).FailedLoginAttempts, 0)
+	})
+
+	t.Run("locked out at threshold", func(t *testing.T) {
+		lockouts := testutil.ToFloat64(LockoutsCounter)
+		for i := 0; i < 3; i++ {
+			assert.ErrorContains(t, login("wrong"), "could not verify password")
+		}
+
+		creds := getCredential(t)
+		assert.Equal(t, creds.FailedLoginAttempts, 0)
+		assert.Assert(t, creds.LockedUntil.After(time.Now().Add(50*time.Minute)))
+		assert.Equal(t, testutil.ToFloat64(LockoutsCounter), lockouts+1)
+
+		err := login("password123")
+		assert.ErrorIs(t, err, ErrLockedOut)
+	})
+
+	t.Run("login allowed after lockout expires", func(t *testing.T) {
+		creds := getCredential(t)
+		creds.LockedUntil = time.Now().Add(-time.Second)
+		assert.NilError(t, data.SaveCredential(db, creds))
+
+		assert.NilError(t, login("password123"))
+	})
+}
@@ -0,0 +1,88 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+	"github.com/infrahq/infra/uid"
+)
+
+// samlAuthn allows presenting a SAML response, signed by the identity
+// provider, in exchange for an access key
+type samlAuthn struct {
+	ProviderID      uid.ID
+	SAMLResponse    string
+	ServiceProvider *providers.SAMLServiceProvider
+}
+
+func NewSAMLAuthentication(providerID uid.ID, samlResponse string, sp *providers.SAMLServiceProvider) LoginMethod {
+	return &samlAuthn{
+		ProviderID:      providerID,
+		SAMLResponse:    samlResponse,
+		ServiceProvider: sp,
+	}
+}
+
+func (a *samlAuthn) Authenticate(_ context.Context, db data.GormTxn, requestedExpiry time.Time) (AuthenticatedIdentity, error) {
+	provider, err := data.GetProvider(db, data.ByID(a.ProviderID))
+	if err != nil {
+		return AuthenticatedIdentity{}, err
+	}
+	if provider.Kind != models.ProviderKindSAML {
+		return AuthenticatedIdentity{}, fmt.Errorf("provider %s is not a saml provider", provider.Name)
+	}
+
+	user, err := a.ServiceProvider.ParseResponse(a.SAMLResponse)
+	if err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("saml authentication: %w", err)
+	}
+
+	if err := data.UseSAMLAssertion(db, user.AssertionID, user.AssertionExpires); err != nil {
+		var ucErr data.UniqueConstraintError
+		if errors.As(err, &ucErr) {
+			return AuthenticatedIdentity{}, fmt.Errorf("saml authentication: %w: assertion was already used", providers.ErrInvalidSAMLResponse)
+		}
+		return AuthenticatedIdentity{}, fmt.Errorf("saml authentication: %w", err)
+	}
+
+	identity, err := data.GetIdentity(db, data.Preload("Groups"), data.ByName(user.Name))
+	if err != nil {
+		if !errors.Is(err, internal.ErrNotFound) {
+			return AuthenticatedIdentity{}, fmt.Errorf("get user: %w", err)
+		}
+
+		identity = &models.Identity{Name: user.Name}
+
+		if err := data.CreateIdentity(db, identity); err != nil {
+			return AuthenticatedIdentity{}, fmt.Errorf("create user: %w", err)
+		}
+	}
+
+	if _, err := data.CreateProviderUser(db, provider, identity); err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("add user for provider login: %w", err)
+	}
+
+	if err := data.AssignIdentityToGroups(db, identity, provider, user.Groups); err != nil {
+		return AuthenticatedIdentity{}, fmt.Errorf("assign identity to groups: %w", err)
+	}
+
+	return AuthenticatedIdentity{
+		Identity:      identity,
+		Provider:      provider,
+		SessionExpiry: requestedExpiry,
+	}, nil
+}
+
+func (a *samlAuthn) Name() string {
+	return "saml"
+}
+
+func (a *samlAuthn) RequiresUpdate(db data.GormTxn) (bool, error) {
+	return false, nil // not applicable to saml
+}
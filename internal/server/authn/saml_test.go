@@ -0,0 +1,112 @@
+package authn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+	"github.com/infrahq/infra/internal/testing/saml"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestSAMLAuthenticate(t *testing.T) {
+	db := setupDB(t)
+	idp := saml.NewIdP(t)
+
+	provider := &models.Provider{
+		Name:            "adfs",
+		Kind:            models.ProviderKindSAML,
+		URL:             "https://idp.example.com/sso",
+		SAMLEntityID:    idp.EntityID,
+		SAMLCertificate: idp.CertificatePEM,
+	}
+	err := data.CreateProvider(db, provider)
+	assert.NilError(t, err)
+
+	sp, err := providers.NewSAMLServiceProvider(*provider, "https://infra.example.com")
+	assert.NilError(t, err)
+
+	response := func(groups ...string) string {
+		return idp.Response(t, saml.ResponseOptions{
+			Destination: sp.ACSURL,
+			Audience:    sp.EntityID,
+			NameID:      "bruce@example.com",
+			Attributes:  map[string][]string{"groups": groups},
+		})
+	}
+	expiry := time.Now().Add(time.Minute)
+
+	t.Run("invalid provider", func(t *testing.T) {
+		authn := NewSAMLAuthentication(uid.New(), response(), sp)
+		_, err := authn.Authenticate(context.Background(), db, expiry)
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+	})
+
+	t.Run("provider is not saml", func(t *testing.T) {
+		oidc := &models.Provider{Name: "mockta", Kind: models.ProviderKindOkta}
+		err := data.CreateProvider(db, oidc)
+		assert.NilError(t, err)
+
+		authn := NewSAMLAuthentication(oidc.ID, response(), sp)
+		_, err = authn.Authenticate(context.Background(), db, expiry)
+		assert.ErrorContains(t, err, "not a saml provider")
+	})
+
+	t.Run("invalid response", func(t *testing.T) {
+		authn := NewSAMLAuthentication(provider.ID, "not a response", sp)
+		_, err := authn.Authenticate(context.Background(), db, expiry)
+		assert.ErrorIs(t, err, providers.ErrInvalidSAMLResponse)
+	})
+
+	t.Run("successful authentication", func(t *testing.T) {
+		authn := NewSAMLAuthentication(provider.ID, response("Everyone", "developers"), sp)
+		authnIdentity, err := authn.Authenticate(context.Background(), db, expiry)
+		assert.NilError(t, err)
+
+		assert.Equal(t, authnIdentity.Identity.Name, "bruce@example.com")
+		assert.Equal(t, authnIdentity.Provider.ID, provider.ID)
+		assert.Assert(t, authnIdentity.SessionExpiry.Equal(expiry))
+
+		var groupNames []string
+		for _, g := range authnIdentity.Identity.Groups {
+			groupNames = append(groupNames, g.Name)
+		}
+		assert.Assert(t, is.Len(groupNames, 2))
+		assert.Assert(t, is.Contains(groupNames, "Everyone"))
+		assert.Assert(t, is.Contains(groupNames, "developers"))
+
+		pu, err := data.GetProviderUser(db, provider.ID, authnIdentity.Identity.ID)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, pu.Groups, models.CommaSeparatedStrings{"Everyone", "developers"})
+	})
+
+	t.Run("groups removed at the identity provider are removed from the user", func(t *testing.T) {
+		authn := NewSAMLAuthentication(provider.ID, response("developers"), sp)
+		authnIdentity, err := authn.Authenticate(context.Background(), db, expiry)
+		assert.NilError(t, err)
+
+		identity, err := data.GetIdentity(db, data.Preload("Groups"), data.ByID(authnIdentity.Identity.ID))
+		assert.NilError(t, err)
+		assert.Assert(t, is.Len(identity.Groups, 1))
+		assert.Equal(t, identity.Groups[0].Name, "developers")
+	})
+
+	t.Run("response is used again", func(t *testing.T) {
+		samlResponse := response("developers")
+		authn := NewSAMLAuthentication(provider.ID, samlResponse, sp)
+		_, err := authn.Authenticate(context.Background(), db, expiry)
+		assert.NilError(t, err)
+
+		authn = NewSAMLAuthentication(provider.ID, samlResponse, sp)
+		_, err = authn.Authenticate(context.Background(), db, expiry)
+		assert.ErrorIs(t, err, providers.ErrInvalidSAMLResponse)
+		assert.ErrorContains(t, err, "assertion was already used")
+	})
+}
@@ -0,0 +1,138 @@
+package authn
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" // nolint:gosec // RFC 6238 uses SHA-1, and it is the only algorithm supported by most authenticator apps
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/infrahq/infra/internal/generate"
+)
+
+const (
+	totpDigits = 6
+	totpPeriod = 30 * time.Second
+	// totpSkew is the number of time steps before and after the current step
+	// that are accepted, to allow for clock drift.
+	totpSkew = 1
+
+	mfaRecoveryCodeCount = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret returns a random base32 encoded secret for an authenticator app.
+func NewTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("generate totp secret: %w", err)
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPKeyURI returns the otpauth:// URI used by authenticator apps to add
+// secret for account.
+func TOTPKeyURI(issuer, account, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// TOTPCode returns the code for secret at time now.
+func TOTPCode(secret string, now time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("decode totp secret: %w", err)
+	}
+	return totpCode(key, now.Unix()/int64(totpPeriod.Seconds())), nil
+}
+
+// ValidateTOTP checks that code is valid for secret at time now. It returns
+// the time step the code was generated for, which can be used to reject a
+// code that was already used.
+func ValidateTOTP(secret, code string, now time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / int64(totpPeriod.Seconds())
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode returns the HOTP value (RFC 4226) of key for step.
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1_000_000)
+}
+
+// NewMFARecoveryCodes returns new recovery codes, and the value to store for
+// them in models.Credential.MFARecoveryCodes.
+func NewMFARecoveryCodes() ([]string, string, error) {
+	codes := make([]string, 0, mfaRecoveryCodeCount)
+	hashes := make([]string, 0, mfaRecoveryCodeCount)
+	for i := 0; i < mfaRecoveryCodeCount; i++ {
+		code, err := generate.CryptoRandom(10, generate.CharsetAlphaNumeric)
+		if err != nil {
+			return nil, "", fmt.Errorf("generate recovery code: %w", err)
+		}
+		code = strings.ToLower(code[:5] + "-" + code[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, strings.Join(hashes, ","), nil
+}
+
+// useMFARecoveryCode checks code against the stored recovery codes. If code
+// matches, it returns the stored codes without code.
+func useMFARecoveryCode(stored, code string) (string, bool) {
+	if stored == "" {
+		return "", false
+	}
+
+	hash := hashRecoveryCode(strings.ToLower(strings.TrimSpace(code)))
+	hashes := strings.Split(stored, ",")
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			remaining := append(hashes[:i:i], hashes[i+1:]...)
+			return strings.Join(remaining, ","), true
+		}
+	}
+	return stored, false
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,79 @@
+package authn
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestValidateTOTP(t *testing.T) {
+	// the SHA-1 test vectors from RFC 6238, truncated to 6 digits
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	testCases := []struct {
+		time time.Time
+		code string
+	}{
+		{time: time.Unix(59, 0), code: "287082"},
+		{time: time.Unix(1111111109, 0), code: "081804"},
+		{time: time.Unix(1234567890, 0), code: "005924"},
+		{time: time.Unix(2000000000, 0), code: "279037"},
+	}
+	for _, tc := range testCases {
+		step, ok := ValidateTOTP(secret, tc.code, tc.time)
+		assert.Assert(t, ok, tc.code)
+		assert.Equal(t, step, tc.time.Unix()/30)
+	}
+
+	t.Run("accepts the previous step", func(t *testing.T) {
+		_, ok := ValidateTOTP(secret, "287082", time.Unix(59+30, 0))
+		assert.Assert(t, ok)
+	})
+
+	t.Run("rejects old codes", func(t *testing.T) {
+		_, ok := ValidateTOTP(secret, "287082", time.Unix(59+90, 0))
+		assert.Assert(t, !ok)
+	})
+
+	t.Run("rejects invalid codes", func(t *testing.T) {
+		for _, code := range []string{"", "287083", "2870820", "abcdef"} {
+			_, ok := ValidateTOTP(secret, code, time.Unix(59, 0))
+			assert.Assert(t, !ok, code)
+		}
+	})
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	assert.NilError(t, err)
+	assert.Equal(t, len(secret), 32)
+
+	code, err := TOTPCode(secret, time.Now())
+	assert.NilError(t, err)
+	_, ok := ValidateTOTP(secret, code, time.Now())
+	assert.Assert(t, ok)
+
+	uri := TOTPKeyURI("Infra", "admin@example.com", secret)
+	assert.Assert(t, strings.HasPrefix(uri, "otpauth://totp/Infra:admin@example.com?"), uri)
+	assert.Assert(t, strings.Contains(uri, "secret="+secret), uri)
+}
+
+func TestMFARecoveryCodes(t *testing.T) {
+	codes, stored, err := NewMFARecoveryCodes()
+	assert.NilError(t, err)
+	assert.Equal(t, len(codes), mfaRecoveryCodeCount)
+	assert.Assert(t, !strings.Contains(stored, codes[0]))
+
+	remaining, ok := useMFARecoveryCode(stored, strings.ToUpper(codes[3]))
+	assert.Assert(t, ok)
+	assert.Equal(t, len(strings.Split(remaining, ",")), mfaRecoveryCodeCount-1)
+
+	_, ok = useMFARecoveryCode(remaining, codes[3])
+	assert.Assert(t, !ok, "recovery code can only be used once")
+
+	_, ok = useMFARecoveryCode(remaining, "not-a-code")
+	assert.Assert(t, !ok)
+}
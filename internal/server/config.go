@@ -36,6 +36,13 @@ type Provider struct {
 	PrivateKey       string
 	ClientEmail      string
 	DomainAdminEmail string
+
+	// fields used to search an LDAP directory
+	BindDN       string
+	BindPassword string
+	SearchBase   string
+	UserFilter   string
+	GroupFilter  string
 }
 
 func (p Provider) ValidationRules() []validate.ValidationRule {
@@ -700,9 +707,15 @@ func (Server) loadProvider(db data.GormTxn, input Provider) (*models.Provider, e
 			PrivateKey:       models.EncryptedAtRest(input.PrivateKey),
 			ClientEmail:      input.ClientEmail,
 			DomainAdminEmail: input.DomainAdminEmail,
+
+			BindDN:       input.BindDN,
+			BindPassword: models.EncryptedAtRest(input.BindPassword),
+			SearchBase:   input.SearchBase,
+			UserFilter:   input.UserFilter,
+			GroupFilter:  input.GroupFilter,
 		}
 
-		if provider.Kind != models.ProviderKindInfra {
+		if provider.Kind != models.ProviderKindInfra && provider.Kind != models.ProviderKindLDAP {
 			// only call the provider to resolve info if it is not known
 			if input.AuthURL == "" && len(input.Scopes) == 0 {
 				providerClient := providers.NewOIDCClient(*provider, input.ClientSecret, "http://localhost:8301")
@@ -740,6 +753,11 @@ func (Server) loadProvider(db data.GormTxn, input Provider) (*models.Provider, e
 	provider.ClientID = input.ClientID
 	provider.ClientSecret = models.EncryptedAtRest(input.ClientSecret)
 	provider.Kind = kind
+	provider.BindDN = input.BindDN
+	provider.BindPassword = models.EncryptedAtRest(input.BindPassword)
+	provider.SearchBase = input.SearchBase
+	provider.UserFilter = input.UserFilter
+	provider.GroupFilter = input.GroupFilter
 
 	if err := data.SaveProvider(db, provider); err != nil {
 		return nil, err
@@ -0,0 +1,44 @@
+package data
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func CreateAccessRequest(db GormTxn, req *models.AccessRequest) error {
+	return add(db, req)
+}
+
+func GetAccessRequest(db GormTxn, selectors ...SelectorFunc) (*models.AccessRequest, error) {
+	return get[models.AccessRequest](db, selectors...)
+}
+
+func ListAccessRequests(db GormTxn, p *models.Pagination, selectors ...SelectorFunc) ([]models.AccessRequest, error) {
+	return list[models.AccessRequest](db, p, selectors...)
+}
+
+func SaveAccessRequest(db GormTxn, req *models.AccessRequest) error {
+	return save(db, req)
+}
+
+func ByOptionalUserID(id uid.ID) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		if id == 0 {
+			return db
+		}
+
+		return db.Where("user_id = ?", id)
+	}
+}
+
+func ByOptionalAccessRequestState(state models.AccessRequestState) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		if state == "" {
+			return db
+		}
+
+		return db.Where("state = ?", state)
+	}
+}
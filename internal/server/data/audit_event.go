@@ -0,0 +1,53 @@
+package data
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func CreateAuditEvent(db GormTxn, event *models.AuditEvent) error {
+	return add(db, event)
+}
+
+func ListAuditEvents(db GormTxn, p *models.Pagination, selectors ...SelectorFunc) ([]models.AuditEvent, error) {
+	return list[models.AuditEvent](db, p, selectors...)
+}
+
+func ByOptionalActorID(id uid.ID) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		if id == 0 {
+			return db
+		}
+
+		return db.Where("actor_id = ?", id)
+	}
+}
+
+func ByOptionalResourceID(id uid.ID) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		if id == 0 {
+			return db
+		}
+
+		return db.Where("resource_id = ?", id)
+	}
+}
+
+// ByOptionalCreatedBetween selects records created after the after time and
+// before the before time. A zero value for either time leaves that end of the
+// range open.
+func ByOptionalCreatedBetween(after, before time.Time) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		if !after.IsZero() {
+			db = db.Where("created_at > ?", after)
+		}
+		if !before.IsZero() {
+			db = db.Where("created_at < ?", before)
+		}
+		return db
+	}
+}
@@ -2,6 +2,9 @@ package data
 
 import (
 	"fmt"
+	"time"
+
+	"gorm.io/gorm"
 
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
@@ -35,3 +38,52 @@ func GetCredential(db GormTxn, selectors ...SelectorFunc) (*models.Credential, e
 func DeleteCredential(db GormTxn, id uid.ID) error {
 	return delete[models.Credential](db, id)
 }
+
+// RecordFailedLogin counts a failed login for the credential. The count is
+// incremented by the database, so that concurrent failed logins are all
+// counted. When the count reaches threshold, the count is reset and the
+// credential is locked until lockedUntil. RecordFailedLogin returns true if the
+// credential was locked.
+func RecordFailedLogin(tx WriteTxn, credential *models.Credential, threshold int, lockedUntil time.Time) (bool, error) {
+	now := time.Now().UTC()
+	_, err := tx.Exec(`
+UPDATE credentials SET failed_login_attempts = failed_login_attempts + 1, updated_at = ?
+WHERE id = ? AND organization_id = ? AND deleted_at IS NULL`,
+		now, credential.ID, tx.OrganizationID())
+	if err != nil {
+		return false, err
+	}
+
+	// the update locks the row until the transaction ends, so the count can
+	// not change before the lockout is saved
+	var attempts int
+	err = tx.QueryRow(`SELECT failed_login_attempts FROM credentials WHERE id = ? AND organization_id = ?`,
+		credential.ID, tx.OrganizationID()).Scan(&attempts)
+	if err != nil {
+		return false, err
+	}
+	credential.FailedLoginAttempts = attempts
+
+	if threshold <= 0 || attempts < threshold {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`
+UPDATE credentials SET failed_login_attempts = 0, locked_until = ?, updated_at = ?
+WHERE id = ? AND organization_id = ?`,
+		lockedUntil.UTC(), now, credential.ID, tx.OrganizationID())
+	if err != nil {
+		return false, err
+	}
+	credential.FailedLoginAttempts = 0
+	credential.LockedUntil = lockedUntil
+	return true, nil
+}
+
+// ByLockedOut selects the credentials of users that are locked out at time
+// now because of failed logins.
+func ByLockedOut(now time.Time) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("locked_until > ?", now)
+	}
+}
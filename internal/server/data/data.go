@@ -384,6 +384,9 @@ func handleError(err error) error {
 				"idx_identities_name":         "name",
 				"idx_groups_name":             "name",
 				"idx_providers_name":          "name",
+				"idx_roles_name":              "name",
+				"idx_federations_name":        "name",
+				"idx_oidc_clients_name":       "name",
 				"idx_access_keys_name":        "name",
 				"idx_destinations_unique_id":  "uniqueId",
 				"idx_access_keys_key_id":      "keyId",
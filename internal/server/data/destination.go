@@ -65,10 +65,47 @@ type destinationsCount struct {
 func CountDestinationsByConnectedVersion(tx GormTxn) ([]destinationsCount, error) {
 	db := tx.GormDB()
 	var results []destinationsCount
-	timeout := time.Now().Add(-5 * time.Minute)
+	timeout := time.Now().Add(-models.DestinationConnectionTimeout)
 	if err := db.Raw("SELECT *, COUNT(*) AS count FROM (SELECT COALESCE(version, '') AS version, last_seen_at >= ? AS connected FROM destinations WHERE deleted_at IS NULL) AS d GROUP BY version, connected", timeout).Scan(&results).Error; err != nil {
 		return nil, err
 	}
 
 	return results, nil
 }
+
+// ListDisconnectedDestinations returns the destinations from all organizations
+// that are disconnected at time now, and were not found to be disconnected
+// since they were last seen. A destination becomes disconnected when it has not
+// been seen for models.DestinationConnectionTimeout.
+func ListDisconnectedDestinations(tx GormTxn, now time.Time) ([]models.Destination, error) {
+	var destinations []models.Destination
+	err := tx.GormDB().
+		Where("last_seen_at <= ? AND disconnected_at < last_seen_at",
+			now.Add(-models.DestinationConnectionTimeout).UTC()).
+		Find(&destinations).Error
+	return destinations, err
+}
+
+// ClaimDisconnectedDestination sets the DisconnectedAt of the destination to
+// now, so that only one server creates the event for the disconnect. It
+// returns false if the destination was already marked as disconnected by
+// another server.
+func ClaimDisconnectedDestination(tx WriteTxn, destination *models.Destination, now time.Time) (bool, error) {
+	result, err := tx.Exec(`
+UPDATE destinations SET disconnected_at = ?
+WHERE id = ? AND organization_id = ? AND disconnected_at < last_seen_at AND deleted_at IS NULL`,
+		now.UTC(), destination.ID, tx.OrganizationID())
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if count == 0 {
+		return false, nil
+	}
+
+	destination.DisconnectedAt = now
+	return true, nil
+}
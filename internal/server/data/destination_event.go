@@ -0,0 +1,100 @@
+package data
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+// CreateDestinationEvent records a change that connectors apply to their
+// destinations. resource is the resource of the grant of a grant event, or
+// empty if the event applies to every destination.
+//
+// The sequence of the event is incremented in a row that stays locked until
+// the transaction is committed. Transactions that create events are committed
+// in the order of their sequence, so a connector never reads an event before
+// all the events with a lower sequence are visible.
+func CreateDestinationEvent(tx GormTxn, resource string, event api.DestinationEvent) error {
+	sequence, err := nextDestinationEventSequence(tx)
+	if err != nil {
+		return fmt.Errorf("destination event sequence: %w", err)
+	}
+
+	event.Sequence = sequence
+	event.Created = api.Time(time.Now())
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return add(tx, &models.DestinationEvent{
+		Sequence: sequence,
+		Type:     event.Type,
+		Resource: resource,
+		Payload:  payload,
+	})
+}
+
+func nextDestinationEventSequence(tx GormTxn) (int64, error) {
+	_, err := tx.Exec(`
+INSERT INTO destination_event_sequences (organization_id, sequence) VALUES (?, 1)
+ON CONFLICT (organization_id) DO UPDATE SET sequence = destination_event_sequences.sequence + 1`,
+		tx.OrganizationID())
+	if err != nil {
+		return 0, err
+	}
+
+	var sequence int64
+	err = tx.QueryRow(`SELECT sequence FROM destination_event_sequences WHERE organization_id = ?`,
+		tx.OrganizationID()).Scan(&sequence)
+	return sequence, err
+}
+
+// DestinationEventSequences returns the sequence of the last event created in
+// the organization, and the sequence of the oldest event that has not been
+// deleted. Either is 0 if there are no events.
+func DestinationEventSequences(tx ReadTxn) (last int64, oldest int64, err error) {
+	err = tx.QueryRow(`SELECT sequence FROM destination_event_sequences WHERE organization_id = ?`,
+		tx.OrganizationID()).Scan(&last)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, 0, nil
+	case err != nil:
+		return 0, 0, err
+	}
+
+	var first sql.NullInt64
+	err = tx.QueryRow(`SELECT MIN(sequence) FROM destination_events WHERE organization_id = ? AND deleted_at IS NULL`,
+		tx.OrganizationID()).Scan(&first)
+	if err != nil {
+		return 0, 0, err
+	}
+	return last, first.Int64, nil
+}
+
+// ListDestinationEvents returns up to limit events with a sequence after the
+// sequence, in order.
+func ListDestinationEvents(tx GormTxn, after int64, limit int) ([]models.DestinationEvent, error) {
+	var events []models.DestinationEvent
+	err := ByOrgID(tx.OrganizationID())(tx.GormDB()).
+		Where("sequence > ?", after).
+		Order("sequence").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// DeleteOldDestinationEvents removes the events from all organizations that
+// were created before the time. The rows are removed, not soft deleted, so
+// that the table does not grow without bound.
+func DeleteOldDestinationEvents(tx GormTxn, before time.Time) (int64, error) {
+	result := tx.GormDB().Unscoped().
+		Where("created_at < ?", before.UTC()).
+		Delete(&models.DestinationEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,64 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func TestDestinationEvents(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		// creating the organization granted the connector access
+		start, _, err := DestinationEventSequences(db)
+		assert.NilError(t, err)
+
+		grant := &models.Grant{Subject: "i:1234567", Privilege: "view", Resource: "prod.default"}
+		assert.NilError(t, CreateGrant(db, grant))
+		role := &models.Role{Name: "logs"}
+		assert.NilError(t, CreateRole(db, role))
+		assert.NilError(t, DeleteGrants(db, ByID(grant.ID)))
+
+		last, oldest, err := DestinationEventSequences(db)
+		assert.NilError(t, err)
+		assert.Equal(t, last, start+3)
+		assert.Equal(t, oldest, int64(1))
+
+		events, err := ListDestinationEvents(db, start+1, 10)
+		assert.NilError(t, err)
+		assert.Equal(t, len(events), 2)
+
+		assert.Equal(t, events[0].Sequence, start+2)
+		assert.Equal(t, events[0].Type, api.DestinationEventRoleUpdated)
+		assert.Equal(t, events[0].Resource, "")
+
+		assert.Equal(t, events[1].Sequence, start+3)
+		assert.Equal(t, events[1].Type, api.DestinationEventGrantDeleted)
+		assert.Equal(t, events[1].Resource, "prod.default")
+
+		var payload api.DestinationEvent
+		assert.NilError(t, json.Unmarshal(events[1].Payload, &payload))
+		assert.Equal(t, payload.Sequence, start+3)
+		assert.Equal(t, payload.Grant.ID, grant.ID)
+
+		t.Run("delete old events", func(t *testing.T) {
+			count, err := DeleteOldDestinationEvents(db, time.Now().Add(time.Minute))
+			assert.NilError(t, err)
+			assert.Equal(t, count, start+3)
+
+			last, oldest, err := DestinationEventSequences(db)
+			assert.NilError(t, err)
+			assert.Equal(t, last, start+3)
+			assert.Equal(t, oldest, int64(0))
+
+			var remaining int64
+			err = db.Unscoped().Model(&models.DestinationEvent{}).Count(&remaining).Error
+			assert.NilError(t, err)
+			assert.Equal(t, remaining, int64(0))
+		})
+	})
+}
@@ -0,0 +1,86 @@
+package data
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/generate"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+// CharsetDeviceFlowUserCode excludes vowels and similar looking characters, so
+// that user codes are easy to read and type, and never spell words.
+const CharsetDeviceFlowUserCode = "BCDFGHJKLMNPQRSTVWXZ"
+
+// CreateDeviceFlowAuthRequest generates a user code and a device code for req
+// and saves it.
+func CreateDeviceFlowAuthRequest(db GormTxn, req *models.DeviceFlowAuthRequest) error {
+	tries := 0
+retry:
+	userCode, err := generate.CryptoRandom(8, CharsetDeviceFlowUserCode)
+	if err != nil {
+		return err
+	}
+	deviceCode, err := generate.CryptoRandom(38, generate.CharsetAlphaNumeric)
+	if err != nil {
+		return err
+	}
+
+	req.UserCode = userCode
+	req.DeviceCode = deviceCode
+
+	tries++
+	if err := add(db, req); err != nil {
+		if tries <= 3 && errors.Is(err, UniqueConstraintError{}) {
+			logging.Warnf("generated random device flow code already exists in the database")
+			goto retry // on the off chance the code exists.
+		}
+		return err
+	}
+	return nil
+}
+
+func GetDeviceFlowAuthRequest(db GormTxn, selectors ...SelectorFunc) (*models.DeviceFlowAuthRequest, error) {
+	return get[models.DeviceFlowAuthRequest](db, selectors...)
+}
+
+func ListDeviceFlowAuthRequests(db GormTxn, p *models.Pagination, selectors ...SelectorFunc) ([]models.DeviceFlowAuthRequest, error) {
+	return list[models.DeviceFlowAuthRequest](db, p, selectors...)
+}
+
+func SaveDeviceFlowAuthRequest(db GormTxn, req *models.DeviceFlowAuthRequest) error {
+	return save(db, req)
+}
+
+func DeleteDeviceFlowAuthRequest(db GormTxn, req *models.DeviceFlowAuthRequest) error {
+	return delete[models.DeviceFlowAuthRequest](db, req.ID)
+}
+
+// DeleteExpiredDeviceFlowAuthRequests removes all device flow requests which
+// expired before now. Unlike most functions in this package, it removes
+// requests from every organization.
+func DeleteExpiredDeviceFlowAuthRequests(tx GormTxn, now time.Time) (int64, error) {
+	result := tx.GormDB().Where("expires_at <= ?", now.UTC()).Delete(&models.DeviceFlowAuthRequest{})
+	return result.RowsAffected, result.Error
+}
+
+func ByUserCode(code string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_code = ?", code)
+	}
+}
+
+func ByDeviceCode(code string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("device_code = ?", code)
+	}
+}
+
+func ByNotExpired(now time.Time) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("expires_at > ?", now.UTC())
+	}
+}
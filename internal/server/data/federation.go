@@ -0,0 +1,33 @@
+package data
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func CreateFederation(db GormTxn, federation *models.Federation) error {
+	return add(db, federation)
+}
+
+func GetFederation(db GormTxn, selectors ...SelectorFunc) (*models.Federation, error) {
+	return get[models.Federation](db, selectors...)
+}
+
+func ListFederations(db GormTxn, p *models.Pagination, selectors ...SelectorFunc) ([]models.Federation, error) {
+	return list[models.Federation](db, p, selectors...)
+}
+
+func SaveFederation(db GormTxn, federation *models.Federation) error {
+	return save(db, federation)
+}
+
+func DeleteFederations(db GormTxn, selectors ...SelectorFunc) error {
+	return deleteAll[models.Federation](db, selectors...)
+}
+
+func ByIssuer(issuer string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("issuer = ?", issuer)
+	}
+}
@@ -2,9 +2,12 @@ package data
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
+	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal/logging"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
@@ -19,14 +22,47 @@ func CreateGrant(db GormTxn, grant *models.Grant) error {
 	case grant.Resource == "":
 		return fmt.Errorf("resource is required")
 	}
-	return add(db, grant)
+
+	// an expired grant that has not been removed yet would conflict with the new grant
+	err := deleteAll[models.Grant](db,
+		BySubject(grant.Subject),
+		ByPrivilege(grant.Privilege),
+		ByResource(grant.Resource),
+		grantExpired(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	if err := add(db, grant); err != nil {
+		return err
+	}
+	return CreateDestinationEvent(db, grant.Resource, api.DestinationEvent{
+		Type:  api.DestinationEventGrantCreated,
+		Grant: grant.ToAPI(),
+	})
+}
+
+// UpdateGrantExpiry sets the time when the grant expires. A zero expiresAt
+// makes the grant permanent.
+func UpdateGrantExpiry(db GormTxn, grant *models.Grant, expiresAt time.Time) error {
+	grant.ExpiresAt = expiresAt
+	if err := save(db, grant); err != nil {
+		return err
+	}
+	return CreateDestinationEvent(db, grant.Resource, api.DestinationEvent{
+		Type:  api.DestinationEventGrantUpdated,
+		Grant: grant.ToAPI(),
+	})
 }
 
 func GetGrant(db GormTxn, selectors ...SelectorFunc) (*models.Grant, error) {
 	return get[models.Grant](db, selectors...)
 }
 
+// ListGrants returns the grants that match the selectors. Grants which have
+// expired are never included.
 func ListGrants(db GormTxn, p *models.Pagination, selectors ...SelectorFunc) ([]models.Grant, error) {
+	selectors = append(selectors, grantNotExpired(time.Now()))
 	return list[models.Grant](db, p, selectors...)
 }
 
@@ -41,7 +77,79 @@ func DeleteGrants(db GormTxn, selectors ...SelectorFunc) error {
 		ids = append(ids, g.ID)
 	}
 
-	return deleteAll[models.Grant](db, ByIDs(ids))
+	if err := deleteAll[models.Grant](db, ByIDs(ids)); err != nil {
+		return err
+	}
+
+	for i := range toDelete {
+		err := CreateDestinationEvent(db, toDelete[i].Resource, api.DestinationEvent{
+			Type:  api.DestinationEventGrantDeleted,
+			Grant: toDelete[i].ToAPI(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteExpiredGrants removes all grants which expired before now. Unlike
+// most functions in this package, it removes grants from every organization.
+// The grants of each organization are deleted in their own transaction, with
+// the same destination events as DeleteGrants, and a grant.deleted webhook
+// event for each grant.
+func DeleteExpiredGrants(tx GormTxn, now time.Time) (int64, error) {
+	var orgIDs []uid.ID
+	err := grantExpired(now)(tx.GormDB()).
+		Model(&models.Grant{}).
+		Distinct().
+		Pluck("organization_id", &orgIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for _, orgID := range orgIDs {
+		err := tx.GormDB().Transaction(func(db *gorm.DB) error {
+			orgTx := NewTransaction(db, orgID)
+			toDelete, err := list[models.Grant](orgTx, nil, grantExpired(now))
+			if err != nil {
+				return err
+			}
+
+			ids := make([]uid.ID, 0, len(toDelete))
+			for _, g := range toDelete {
+				ids = append(ids, g.ID)
+			}
+			if err := DeleteGrants(orgTx, ByIDs(ids)); err != nil {
+				return err
+			}
+
+			for _, g := range toDelete {
+				if err := CreateWebhookEvent(orgTx, api.WebhookEventGrantDeleted, g.ToAPI()); err != nil {
+					return fmt.Errorf("create %v webhook event: %w", api.WebhookEventGrantDeleted, err)
+				}
+			}
+			count += int64(len(toDelete))
+			return nil
+		})
+		if err != nil {
+			return count, fmt.Errorf("delete expired grants for org %v: %w", orgID, err)
+		}
+	}
+	return count, nil
+}
+
+func grantNotExpired(now time.Time) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(expires_at > ? OR expires_at = ? OR expires_at is null)", now.UTC(), time.Time{})
+	}
+}
+
+func grantExpired(now time.Time) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("expires_at <= ? AND expires_at != ?", now.UTC(), time.Time{})
+	}
 }
 
 func ByOptionalPrivilege(s string) SelectorFunc {
@@ -63,8 +171,7 @@ func GrantsInheritedBySubject(subjectID uid.PolymorphicID) SelectorFunc {
 				logging.Errorf("invalid subject id %q", subjectID)
 				return db.Where("1 = 0")
 			}
-			var groupIDs []uid.ID
-			err = db.Session(&gorm.Session{NewDB: true}).Raw("select distinct group_id from identities_groups where identity_id = ?", userID).Pluck("group_id", &groupIDs).Error
+			groupIDs, err := inheritedGroupIDs(db, userID)
 			if err != nil {
 				logging.Errorf("GrantsInheritedByUser: %s", err)
 				_ = db.AddError(err)
@@ -77,7 +184,23 @@ func GrantsInheritedBySubject(subjectID uid.PolymorphicID) SelectorFunc {
 			}
 			return db.Where("subject in (?)", subjects)
 		case subjectID.IsGroup():
-			return BySubject(subjectID)(db)
+			groupID, err := subjectID.ID()
+			if err != nil {
+				logging.Errorf("invalid subject id %q", subjectID)
+				return db.Where("1 = 0")
+			}
+			groupIDs, err := groupAncestorIDs(db, []uid.ID{groupID})
+			if err != nil {
+				logging.Errorf("GrantsInheritedByGroup: %s", err)
+				_ = db.AddError(err)
+				return db.Where("1 = 0")
+			}
+
+			subjects := make([]string, 0, len(groupIDs))
+			for _, id := range groupIDs {
+				subjects = append(subjects, uid.NewGroupPolymorphicID(id).String())
+			}
+			return db.Where("subject in (?)", subjects)
 		default:
 			panic("unhandled subject type")
 		}
@@ -100,8 +223,78 @@ func ByOptionalResource(s string) SelectorFunc {
 	}
 }
 
+// ByOptionalResourcePrefix selects grants for the resource named prefix, and
+// for any of its sub-resources. Grants with a wildcard resource are also
+// selected, because they may match those resources. Callers must use
+// api.MatchResource to check the grants with a wildcard resource.
+func ByOptionalResourcePrefix(prefix string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		if prefix == "" {
+			return db
+		}
+
+		return db.Where("(resource = ? OR resource LIKE ? ESCAPE '\\' OR resource LIKE ?)",
+			prefix, escapeLike(prefix)+".%", "%*%")
+	}
+}
+
+// escapeLike escapes the special characters of a LIKE pattern, so that s is
+// matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 func ByResource(s string) SelectorFunc {
 	return func(db *gorm.DB) *gorm.DB {
 		return db.Where("resource = ?", s)
 	}
 }
+
+// SubjectNames returns the names of the users and groups of subjects, keyed by
+// subject. Subjects that do not exist are not included.
+func SubjectNames(tx GormTxn, subjects []uid.PolymorphicID) (map[uid.PolymorphicID]string, error) {
+	var userIDs, groupIDs []uid.ID
+	for _, subject := range subjects {
+		id, err := subject.ID()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case subject.IsIdentity():
+			userIDs = append(userIDs, id)
+		case subject.IsGroup():
+			groupIDs = append(groupIDs, id)
+		}
+	}
+
+	type subjectName struct {
+		ID   uid.ID
+		Name string
+	}
+
+	result := make(map[uid.PolymorphicID]string, len(subjects))
+	if len(userIDs) > 0 {
+		var users []subjectName
+		err := ByOrgID(tx.OrganizationID())(tx.GormDB()).Model(&models.Identity{}).
+			Select("id, name").Where("id IN (?)", userIDs).Find(&users).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			result[uid.NewIdentityPolymorphicID(user.ID)] = user.Name
+		}
+	}
+
+	if len(groupIDs) > 0 {
+		var groups []subjectName
+		err := ByOrgID(tx.OrganizationID())(tx.GormDB()).Model(&models.Group{}).
+			Select("id, name").Where("id IN (?)", groupIDs).Find(&groups).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			result[uid.NewGroupPolymorphicID(group.ID)] = group.Name
+		}
+	}
+	return result, nil
+}
@@ -1,12 +1,17 @@
 package data
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
 
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
 )
 
 func TestDuplicateGrant(t *testing.T) {
@@ -46,3 +51,98 @@ func TestDuplicateGrant(t *testing.T) {
 		assert.NilError(t, err)
 	})
 }
+
+func TestExpiredGrants(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		active := models.Grant{
+			Subject:   "i:1234567",
+			Privilege: "view",
+			Resource:  "production",
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+		expired := models.Grant{
+			Subject:   "i:1234567",
+			Privilege: "edit",
+			Resource:  "production",
+			ExpiresAt: time.Now().Add(-time.Minute),
+		}
+		permanent := models.Grant{
+			Subject:   "i:1234567",
+			Privilege: "admin",
+			Resource:  "staging",
+		}
+		for _, g := range []*models.Grant{&active, &expired, &permanent} {
+			assert.NilError(t, CreateGrant(db, g))
+		}
+
+		t.Run("list excludes expired grants", func(t *testing.T) {
+			grants, err := ListGrants(db, nil, BySubject("i:1234567"))
+			assert.NilError(t, err)
+
+			var ids []uid.ID
+			for _, g := range grants {
+				ids = append(ids, g.ID)
+			}
+			assert.DeepEqual(t, ids, []uid.ID{active.ID, permanent.ID})
+		})
+
+		t.Run("create replaces an expired grant", func(t *testing.T) {
+			replacement := models.Grant{
+				Subject:   "i:1234567",
+				Privilege: "edit",
+				Resource:  "production",
+			}
+			assert.NilError(t, CreateGrant(db, &replacement))
+
+			grants, err := ListGrants(db, nil, BySubject("i:1234567"), ByPrivilege("edit"))
+			assert.NilError(t, err)
+			assert.Assert(t, is.Len(grants, 1))
+			assert.Equal(t, grants[0].ID, replacement.ID)
+		})
+
+		t.Run("delete expired grants", func(t *testing.T) {
+			expiring := models.Grant{
+				Subject:   "i:7654321",
+				Privilege: "view",
+				Resource:  "production",
+				ExpiresAt: time.Now().Add(time.Minute),
+			}
+			assert.NilError(t, CreateGrant(db, &expiring))
+
+			webhook := &models.Webhook{
+				URL:        "https://example.com/events",
+				EventTypes: models.CommaSeparatedStrings{api.WebhookEventGrantDeleted},
+			}
+			assert.NilError(t, CreateWebhook(db, webhook))
+
+			last, _, err := DestinationEventSequences(db)
+			assert.NilError(t, err)
+
+			count, err := DeleteExpiredGrants(db, time.Now().Add(2*time.Minute))
+			assert.NilError(t, err)
+			assert.Equal(t, count, int64(1))
+
+			_, err = GetGrant(db, ByID(expiring.ID))
+			assert.ErrorIs(t, err, internal.ErrNotFound)
+
+			_, err = GetGrant(db, ByID(permanent.ID))
+			assert.NilError(t, err)
+
+			events, err := ListDestinationEvents(db, last, 10)
+			assert.NilError(t, err)
+			assert.Equal(t, len(events), 1)
+			assert.Equal(t, events[0].Type, api.DestinationEventGrantDeleted)
+
+			deliveries, err := ListWebhookDeliveries(db, nil, ByWebhookIDs([]uid.ID{webhook.ID}))
+			assert.NilError(t, err)
+			assert.Equal(t, len(deliveries), 1)
+			assert.Equal(t, deliveries[0].EventType, api.WebhookEventGrantDeleted)
+
+			var payload api.WebhookEvent
+			assert.NilError(t, json.Unmarshal(deliveries[0].Payload, &payload))
+			grant, ok := payload.Data.(map[string]any)
+			assert.Assert(t, ok)
+			assert.Equal(t, grant["id"], expiring.ID.String())
+		})
+	})
+}
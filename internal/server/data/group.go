@@ -1,8 +1,11 @@
 package data
 
 import (
+	"fmt"
+
 	"gorm.io/gorm"
 
+	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
 )
@@ -22,6 +25,11 @@ func GetGroup(db GormTxn, selectors ...SelectorFunc) (*models.Group, error) {
 		return nil, err
 	}
 	group.TotalUsers = int(count)
+
+	group.ParentGroupIDs, err = listParentGroupIDs(db.GormDB(), []uid.ID{group.ID})
+	if err != nil {
+		return nil, err
+	}
 	return group, nil
 }
 
@@ -38,6 +46,11 @@ func ListGroups(db GormTxn, p *models.Pagination, selectors ...SelectorFunc) ([]
 			return nil, err
 		}
 		groups[i].TotalUsers = int(count)
+
+		groups[i].ParentGroupIDs, err = listParentGroupIDs(db.GormDB(), []uid.ID{groups[i].ID})
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return groups, nil
@@ -52,6 +65,74 @@ func ByGroupMember(id uid.ID) SelectorFunc {
 	}
 }
 
+// ByInheritedGroupMember selects the groups that the identity is a member of,
+// either directly or through the groups that contain its groups.
+func ByInheritedGroupMember(id uid.ID) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		groupIDs, err := inheritedGroupIDs(db, id)
+		if err != nil {
+			_ = db.AddError(err)
+			return db.Where("1 = 0")
+		}
+		return db.Where("groups.id IN (?)", groupIDs)
+	}
+}
+
+// inheritedGroupIDs returns the IDs of the groups that the identity is a
+// member of, including the ancestors of those groups.
+func inheritedGroupIDs(db *gorm.DB, identityID uid.ID) ([]uid.ID, error) {
+	var groupIDs []uid.ID
+	err := db.Session(&gorm.Session{NewDB: true}).Raw("SELECT DISTINCT group_id FROM identities_groups WHERE identity_id = ?", identityID).Pluck("group_id", &groupIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return groupAncestorIDs(db, groupIDs)
+}
+
+// groupAncestorIDs returns groupIDs, and the IDs of every group that contains
+// one of groupIDs directly or through other groups.
+func groupAncestorIDs(db *gorm.DB, groupIDs []uid.ID) ([]uid.ID, error) {
+	seen := make(map[uid.ID]bool, len(groupIDs))
+	result := make([]uid.ID, 0, len(groupIDs))
+
+	next := groupIDs
+	for len(next) > 0 {
+		var unseen []uid.ID
+		for _, id := range next {
+			if !seen[id] {
+				seen[id] = true
+				unseen = append(unseen, id)
+			}
+		}
+		if len(unseen) == 0 {
+			break
+		}
+		result = append(result, unseen...)
+
+		var err error
+		next, err = listParentGroupIDs(db, unseen)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// listParentGroupIDs returns the IDs of the groups that directly contain any of
+// groupIDs.
+func listParentGroupIDs(db *gorm.DB, groupIDs []uid.ID) ([]uid.ID, error) {
+	parents := []uid.ID{}
+	err := db.Session(&gorm.Session{NewDB: true}).Raw("SELECT DISTINCT group_id FROM nested_groups WHERE member_group_id IN (?)", groupIDs).Pluck("group_id", &parents).Error
+	if err != nil {
+		return nil, fmt.Errorf("list parent groups: %w", err)
+	}
+	return parents, nil
+}
+
+func SaveGroup(db GormTxn, group *models.Group) error {
+	return save(db, group)
+}
+
 func DeleteGroups(db GormTxn, selectors ...SelectorFunc) error {
 	toDelete, err := ListGroups(db, nil, selectors...)
 	if err != nil {
@@ -80,6 +161,11 @@ func DeleteGroups(db GormTxn, selectors ...SelectorFunc) error {
 		if err != nil {
 			return err
 		}
+
+		_, err = db.Exec("DELETE FROM nested_groups WHERE group_id = ? OR member_group_id = ?", g.ID, g.ID)
+		if err != nil {
+			return err
+		}
 	}
 
 	return deleteAll[models.Group](db, ByIDs(ids))
@@ -107,6 +193,39 @@ func RemoveUsersFromGroup(db GormTxn, groupID uid.ID, idsToRemove []uid.ID) erro
 	return nil
 }
 
+// AddGroupsToGroup makes the groups in idsToAdd members of the group with
+// groupID, so that their members inherit the grants of the group. It returns
+// an error if adding a group would create a cycle.
+func AddGroupsToGroup(db GormTxn, groupID uid.ID, idsToAdd []uid.ID) error {
+	for _, id := range idsToAdd {
+		ancestors, err := groupAncestorIDs(db.GormDB(), []uid.ID{groupID})
+		if err != nil {
+			return err
+		}
+		for _, ancestor := range ancestors {
+			if ancestor == id {
+				return fmt.Errorf("%w: adding group %s to group %s would create a cycle", internal.ErrBadRequest, id, groupID)
+			}
+		}
+
+		_, err = db.Exec("INSERT INTO nested_groups (group_id, member_group_id) SELECT ?, ? WHERE NOT EXISTS (SELECT 1 FROM nested_groups WHERE group_id = ? AND member_group_id = ?)", groupID, id, groupID, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func RemoveGroupsFromGroup(db GormTxn, groupID uid.ID, idsToRemove []uid.ID) error {
+	for _, id := range idsToRemove {
+		_, err := db.Exec("DELETE FROM nested_groups WHERE group_id = ? AND member_group_id = ?", groupID, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func CountUsersInGroup(tx GormTxn, groupID uid.ID) (int64, error) {
 	db := tx.GormDB()
 	var count int64
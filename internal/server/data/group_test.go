@@ -1,11 +1,15 @@
 package data
 
 import (
+	"sort"
 	"testing"
 
 	gocmp "github.com/google/go-cmp/cmp"
+	"gopkg.in/square/go-jose.v2/jwt"
 	"gotest.tools/v3/assert"
 
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/claims"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
 )
@@ -217,3 +221,98 @@ func TestAddUsersToGroup(t *testing.T) {
 		})
 	})
 }
+
+func TestAddGroupsToGroup(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		var (
+			platform = models.Group{Name: "platform"}
+			sre      = models.Group{Name: "sre"}
+			oncall   = models.Group{Name: "oncall"}
+			dba      = models.Group{Name: "dba"}
+		)
+		createGroups(t, db, &platform, &sre, &oncall, &dba)
+
+		user := models.Identity{Name: "pager@example.com", Groups: []models.Group{oncall}}
+		createIdentities(t, db, &user)
+
+		assert.NilError(t, AddGroupsToGroup(db, platform.ID, []uid.ID{sre.ID, dba.ID}))
+		assert.NilError(t, AddGroupsToGroup(db, sre.ID, []uid.ID{oncall.ID}))
+		// adding a group again is a no-op
+		assert.NilError(t, AddGroupsToGroup(db, sre.ID, []uid.ID{oncall.ID}))
+
+		t.Run("parent groups", func(t *testing.T) {
+			group, err := GetGroup(db, ByID(oncall.ID))
+			assert.NilError(t, err)
+			assert.DeepEqual(t, group.ParentGroupIDs, []uid.ID{sre.ID})
+
+			group, err = GetGroup(db, ByID(platform.ID))
+			assert.NilError(t, err)
+			assert.DeepEqual(t, group.ParentGroupIDs, []uid.ID{})
+		})
+
+		t.Run("cycles are rejected", func(t *testing.T) {
+			err := AddGroupsToGroup(db, oncall.ID, []uid.ID{platform.ID})
+			assert.ErrorIs(t, err, internal.ErrBadRequest)
+			assert.ErrorContains(t, err, "would create a cycle")
+
+			err = AddGroupsToGroup(db, sre.ID, []uid.ID{sre.ID})
+			assert.ErrorIs(t, err, internal.ErrBadRequest)
+		})
+
+		t.Run("inherited group membership", func(t *testing.T) {
+			actual, err := ListGroups(db, nil, ByInheritedGroupMember(user.ID))
+			assert.NilError(t, err)
+			expected := []models.Group{
+				{Name: "oncall"},
+				{Name: "platform"},
+				{Name: "sre"},
+			}
+			assert.DeepEqual(t, actual, expected, cmpGroupShallow)
+		})
+
+		t.Run("inherited grants", func(t *testing.T) {
+			platformGrant := &models.Grant{Subject: platform.PolyID(), Privilege: "view", Resource: "prod"}
+			dbaGrant := &models.Grant{Subject: dba.PolyID(), Privilege: "admin", Resource: "db"}
+			assert.NilError(t, CreateGrant(db, platformGrant))
+			assert.NilError(t, CreateGrant(db, dbaGrant))
+
+			grants, err := ListGrants(db, nil, GrantsInheritedBySubject(user.PolyID()))
+			assert.NilError(t, err)
+			assert.Equal(t, len(grants), 1)
+			assert.Equal(t, grants[0].ID, platformGrant.ID)
+
+			grants, err = ListGrants(db, nil, GrantsInheritedBySubject(sre.PolyID()))
+			assert.NilError(t, err)
+			assert.Equal(t, len(grants), 1)
+			assert.Equal(t, grants[0].ID, platformGrant.ID)
+		})
+
+		t.Run("token groups", func(t *testing.T) {
+			token, err := CreateIdentityToken(db, user.ID, []string{"production"}, "https://infra.example.com")
+			assert.NilError(t, err)
+
+			parsed, err := jwt.ParseSigned(token.Token)
+			assert.NilError(t, err)
+			var custom claims.Custom
+			assert.NilError(t, parsed.UnsafeClaimsWithoutVerification(&custom))
+			sort.Strings(custom.Groups)
+			assert.DeepEqual(t, custom.Groups, []string{"oncall", "platform", "sre"})
+		})
+
+		t.Run("remove group from group", func(t *testing.T) {
+			assert.NilError(t, RemoveGroupsFromGroup(db, sre.ID, []uid.ID{oncall.ID}))
+
+			actual, err := ListGroups(db, nil, ByInheritedGroupMember(user.ID))
+			assert.NilError(t, err)
+			assert.DeepEqual(t, actual, []models.Group{{Name: "oncall"}}, cmpGroupShallow)
+		})
+
+		t.Run("delete group removes nesting", func(t *testing.T) {
+			assert.NilError(t, DeleteGroups(db, ByID(platform.ID)))
+
+			group, err := GetGroup(db, ByID(sre.ID))
+			assert.NilError(t, err)
+			assert.DeepEqual(t, group.ParentGroupIDs, []uid.ID{})
+		})
+	})
+}
@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	_ "embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -64,6 +65,26 @@ func migrations() []*migrator.Migration {
 		addDefaultOrganization(),
 		addOrganizationDomain(),
 		dropOrganizationNameIndex(),
+		addAuditEvents(),
+		addGrantExpiresAt(),
+		addAccessRequests(),
+		moveJWKsToSigningKeys(),
+		addWebhooks(),
+		addMultiFactorAuthentication(),
+		addLoginLockout(),
+		addNestedGroups(),
+		addRoles(),
+		addDeviceFlowAuthRequests(),
+		addFederations(),
+		addOIDCClients(),
+		addLDAPProviders(),
+		addSAMLProviders(),
+		addDestinationEvents(),
+		addSSHUserCAs(),
+		addSAMLAssertions(),
+		addDeviceFlowLastPolledAt(),
+		addSigningKeyRetireAt(),
+		addDestinationDisconnectedAt(),
 		// next one here
 	}
 }
@@ -100,6 +121,20 @@ func autoMigrateSchema(db *gorm.DB) error {
 		&models.Credential{},
 		&models.Organization{},
 		&models.PasswordResetToken{},
+		&models.AuditEvent{},
+		&models.AccessRequest{},
+		&models.SigningKey{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.Role{},
+		&models.DeviceFlowAuthRequest{},
+		&models.Federation{},
+		&models.OIDCClient{},
+		&models.OIDCAuthorizationCode{},
+		&models.DestinationEvent{},
+		&models.DestinationEventSequence{},
+		&models.SSHUserCA{},
+		&models.SAMLAssertion{},
 	}
 
 	for _, table := range tables {
@@ -558,3 +593,625 @@ func dropOrganizationNameIndex() *migrator.Migration {
 		},
 	}
 }
+
+func addAuditEvents() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-08-22T14:58",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasTable(tx, "audit_events") {
+				return nil
+			}
+			_, err := tx.Exec(`
+CREATE TABLE audit_events (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    actor_id bigint,
+    actor_name text,
+    method text,
+    path text,
+    resource text,
+    resource_id bigint,
+    request text,
+    status_code bigint,
+    PRIMARY KEY (id)
+);
+`)
+			return err
+		},
+	}
+}
+
+func addGrantExpiresAt() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-08-24T10:12",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasColumn(tx, "grants", "expires_at") {
+				return nil
+			}
+			_, err := tx.Exec(`ALTER TABLE grants ADD COLUMN expires_at timestamp with time zone`)
+			return err
+		},
+	}
+}
+
+func addAccessRequests() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-08-25T09:40",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasTable(tx, "access_requests") {
+				return nil
+			}
+			_, err := tx.Exec(`
+CREATE TABLE access_requests (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    user_id bigint,
+    privilege text,
+    resource text,
+    reason text,
+    duration bigint,
+    state text,
+    reviewed_by bigint,
+    grant_id bigint,
+    PRIMARY KEY (id)
+);
+`)
+			return err
+		},
+	}
+}
+
+// moveJWKsToSigningKeys moves the JWK of each organization from the settings
+// table into the new signing_keys table, so that keys can be rotated.
+func moveJWKsToSigningKeys() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-08-26T11:05",
+		Migrate: func(tx migrator.DB) error {
+			if !migrator.HasTable(tx, "signing_keys") {
+				_, err := tx.Exec(`
+CREATE TABLE signing_keys (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    key_id text,
+    private_jwk bytea,
+    public_jwk bytea,
+    state text,
+    PRIMARY KEY (id)
+);
+`)
+				if err != nil {
+					return err
+				}
+			}
+
+			if !migrator.HasColumn(tx, "settings", "private_jwk") {
+				return nil
+			}
+
+			type jwk struct {
+				orgID   uid.ID
+				private []byte
+				public  []byte
+			}
+			var keys []jwk
+
+			rows, err := tx.Query(`SELECT organization_id, private_jwk, public_jwk FROM settings WHERE deleted_at IS NULL`)
+			if err != nil {
+				return err
+			}
+			for rows.Next() {
+				var key jwk
+				if err := rows.Scan(&key.orgID, &key.private, &key.public); err != nil {
+					rows.Close()
+					return err
+				}
+				keys = append(keys, key)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+
+			stmt := `
+INSERT INTO signing_keys(id, created_at, updated_at, organization_id, key_id, private_jwk, public_jwk, state)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+`
+			now := time.Now()
+			for _, key := range keys {
+				// the public key is not encrypted, so the key ID can be read from it
+				var pub struct {
+					KeyID string `json:"kid"`
+				}
+				if err := json.Unmarshal(key.public, &pub); err != nil {
+					return fmt.Errorf("read key id of jwk for org %v: %w", key.orgID, err)
+				}
+
+				_, err := tx.Exec(stmt, uid.New(), now, now, key.orgID, pub.KeyID,
+					key.private, key.public, models.SigningKeyStateActive)
+				if err != nil {
+					return err
+				}
+			}
+
+			for _, column := range []string{"private_jwk", "public_jwk"} {
+				stmt := `ALTER TABLE settings DROP COLUMN IF EXISTS ` + column
+				if tx.DriverName() == "sqlite" {
+					stmt = `ALTER TABLE settings DROP COLUMN ` + column
+				}
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func addWebhooks() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-08-29T14:20",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasTable(tx, "webhooks") {
+				return nil
+			}
+			stmts := []string{`
+CREATE TABLE webhook_deliveries (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    webhook_id bigint,
+    event_type text,
+    payload bytea,
+    state text,
+    attempts bigint,
+    next_attempt_at timestamp with time zone,
+    last_status_code bigint,
+    last_error text,
+    PRIMARY KEY (id)
+);
+`, `
+CREATE TABLE webhooks (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    url text,
+    secret text,
+    event_types text,
+    created_by bigint,
+    PRIMARY KEY (id)
+);
+`}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func addMultiFactorAuthentication() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-08-31T09:45",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasColumn(tx, "credentials", "mfa_secret") {
+				return nil
+			}
+			stmts := []string{
+				`ALTER TABLE credentials ADD COLUMN mfa_secret text`,
+				`ALTER TABLE credentials ADD COLUMN mfa_enabled boolean`,
+				`ALTER TABLE credentials ADD COLUMN mfa_recovery_codes text`,
+				`ALTER TABLE credentials ADD COLUMN mfa_last_used_step bigint`,
+				`ALTER TABLE settings ADD COLUMN require_mfa text`,
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func addLoginLockout() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-09-01T11:20",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasColumn(tx, "credentials", "failed_login_attempts") {
+				return nil
+			}
+			stmts := []string{
+				`ALTER TABLE credentials ADD COLUMN failed_login_attempts bigint DEFAULT 0`,
+				`ALTER TABLE credentials ADD COLUMN locked_until timestamp with time zone`,
+				`ALTER TABLE settings ADD COLUMN lockout_threshold bigint DEFAULT 10`,
+				`ALTER TABLE settings ADD COLUMN lockout_duration bigint DEFAULT 900000000000`,
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func addNestedGroups() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-09-02T10:30",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasTable(tx, "nested_groups") {
+				return nil
+			}
+			_, err := tx.Exec(`
+CREATE TABLE nested_groups (
+    group_id bigint NOT NULL,
+    member_group_id bigint NOT NULL,
+    PRIMARY KEY (group_id, member_group_id)
+);
+`)
+			return err
+		},
+	}
+}
+
+func addRoles() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-09-05T13:15",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasTable(tx, "roles") {
+				return nil
+			}
+			stmts := []string{`
+CREATE TABLE roles (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    name text,
+    description text,
+    rules text,
+    created_by bigint,
+    PRIMARY KEY (id)
+);
+`,
+				`CREATE UNIQUE INDEX idx_roles_name ON roles USING btree (organization_id, name) WHERE (deleted_at IS NULL);`,
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func addDeviceFlowAuthRequests() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-09-07T10:00",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasTable(tx, "device_flow_auth_requests") {
+				return nil
+			}
+			stmts := []string{`
+CREATE TABLE device_flow_auth_requests (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    user_code text,
+    device_code text,
+    expires_at timestamp with time zone,
+    approved_by bigint,
+    provider_id bigint,
+    PRIMARY KEY (id)
+);
+`,
+				`CREATE UNIQUE INDEX idx_device_flow_auth_requests_user_code ON device_flow_auth_requests USING btree (user_code) WHERE (deleted_at IS NULL);`,
+				`CREATE UNIQUE INDEX idx_device_flow_auth_requests_device_code ON device_flow_auth_requests USING btree (device_code) WHERE (deleted_at IS NULL);`,
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func addFederations() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-09-08T09:30",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasTable(tx, "federations") {
+				return nil
+			}
+			stmts := []string{`
+CREATE TABLE federations (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    name text,
+    issuer text,
+    jwks_url text,
+    audience text,
+    conditions text,
+    identity_id bigint,
+    ttl bigint,
+    created_by bigint,
+    PRIMARY KEY (id)
+);
+`,
+				`CREATE UNIQUE INDEX idx_federations_name ON federations USING btree (organization_id, name) WHERE (deleted_at IS NULL);`,
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func addOIDCClients() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-09-09T14:00",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasTable(tx, "oidc_clients") {
+				return nil
+			}
+			stmts := []string{`
+CREATE TABLE oidc_clients (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    name text,
+    client_id text,
+    client_secret text,
+    redirect_uris text,
+    created_by bigint,
+    PRIMARY KEY (id)
+);
+`, `
+CREATE TABLE oidc_authorization_codes (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    code text,
+    oidc_client_id bigint,
+    identity_id bigint,
+    redirect_uri text,
+    scopes text,
+    nonce text,
+    code_challenge text,
+    expires_at timestamp with time zone,
+    PRIMARY KEY (id)
+);
+`,
+				`CREATE UNIQUE INDEX idx_oidc_clients_name ON oidc_clients USING btree (organization_id, name) WHERE (deleted_at IS NULL);`,
+				`CREATE UNIQUE INDEX idx_oidc_clients_client_id ON oidc_clients USING btree (client_id) WHERE (deleted_at IS NULL);`,
+				`CREATE UNIQUE INDEX idx_oidc_authorization_codes_code ON oidc_authorization_codes USING btree (code) WHERE (deleted_at IS NULL);`,
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func addLDAPProviders() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-09-12T10:00",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasColumn(tx, "providers", "bind_dn") {
+				return nil
+			}
+			stmts := []string{
+				`ALTER TABLE providers ADD COLUMN bind_dn text`,
+				`ALTER TABLE providers ADD COLUMN bind_password text`,
+				`ALTER TABLE providers ADD COLUMN search_base text`,
+				`ALTER TABLE providers ADD COLUMN user_filter text`,
+				`ALTER TABLE providers ADD COLUMN group_filter text`,
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func addSAMLProviders() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-09-13T10:00",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasColumn(tx, "providers", "saml_entity_id") {
+				return nil
+			}
+			stmts := []string{
+				`ALTER TABLE providers ADD COLUMN saml_entity_id text`,
+				`ALTER TABLE providers ADD COLUMN saml_certificate text`,
+				`ALTER TABLE providers ADD COLUMN saml_name_attribute text`,
+				`ALTER TABLE providers ADD COLUMN saml_groups_attribute text`,
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func addDestinationEvents() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-09-14T10:00",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasTable(tx, "destination_events") {
+				return nil
+			}
+			stmts := []string{`
+CREATE TABLE destination_event_sequences (
+    organization_id bigint NOT NULL,
+    sequence bigint,
+    PRIMARY KEY (organization_id)
+);
+`, `
+CREATE TABLE destination_events (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    sequence bigint,
+    type text,
+    resource text,
+    payload bytea,
+    PRIMARY KEY (id)
+);
+`,
+				`CREATE UNIQUE INDEX idx_destination_events_sequence ON destination_events USING btree (organization_id, sequence) WHERE (deleted_at IS NULL);`,
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func addSSHUserCAs() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-09-16T10:00",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasTable(tx, "ssh_user_cas") {
+				return nil
+			}
+			_, err := tx.Exec(`
+CREATE TABLE ssh_user_cas (
+    organization_id bigint NOT NULL,
+    created_at timestamp with time zone,
+    private_key bytea,
+    public_key text,
+    PRIMARY KEY (organization_id)
+);
+`)
+			return err
+		},
+	}
+}
+
+func addSAMLAssertions() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-09-19T10:00",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasTable(tx, "saml_assertions") {
+				return nil
+			}
+			_, err := tx.Exec(`
+CREATE TABLE saml_assertions (
+    organization_id bigint NOT NULL,
+    id text NOT NULL,
+    expires_at timestamp with time zone,
+    PRIMARY KEY (organization_id, id)
+);
+`)
+			return err
+		},
+	}
+}
+
+func addDeviceFlowLastPolledAt() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-09-20T10:00",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasColumn(tx, "device_flow_auth_requests", "last_polled_at") {
+				return nil
+			}
+
+			stmt := `ALTER TABLE device_flow_auth_requests ADD COLUMN last_polled_at timestamp with time zone`
+			if tx.DriverName() == "sqlite" {
+				stmt = `ALTER TABLE device_flow_auth_requests ADD COLUMN last_polled_at datetime`
+			}
+			_, err := tx.Exec(stmt)
+			return err
+		},
+	}
+}
+
+func addSigningKeyRetireAt() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-09-21T10:00",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasColumn(tx, "signing_keys", "retire_at") {
+				return nil
+			}
+
+			stmt := `ALTER TABLE signing_keys ADD COLUMN retire_at timestamp with time zone`
+			if tx.DriverName() == "sqlite" {
+				stmt = `ALTER TABLE signing_keys ADD COLUMN retire_at datetime`
+			}
+			_, err := tx.Exec(stmt)
+			return err
+		},
+	}
+}
+
+// addDestinationDisconnectedAt adds the column used to create one destination
+// disconnected event for each disconnect. Existing destinations are marked as
+// already disconnected, so that events are not created for old disconnects.
+func addDestinationDisconnectedAt() *migrator.Migration {
+	return &migrator.Migration{
+		ID: "2022-09-22T10:00",
+		Migrate: func(tx migrator.DB) error {
+			if migrator.HasColumn(tx, "destinations", "disconnected_at") {
+				return nil
+			}
+
+			stmt := `ALTER TABLE destinations ADD COLUMN disconnected_at timestamp with time zone`
+			if tx.DriverName() == "sqlite" {
+				stmt = `ALTER TABLE destinations ADD COLUMN disconnected_at datetime`
+			}
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+
+			_, err := tx.Exec(`UPDATE destinations SET disconnected_at = ?`, time.Now().UTC())
+			return err
+		},
+	}
+}
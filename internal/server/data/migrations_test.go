@@ -494,6 +494,153 @@ DELETE FROM settings WHERE id=24567;
 				// dropped indexes are tested by schema comparison
 			},
 		},
+		{
+			label: testCaseLine("2022-08-22T14:58"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// new tables are tested by schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-08-24T10:12"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// column changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-08-25T09:40"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// new tables are tested by schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-08-26T11:05"),
+			setup: func(t *testing.T, db WriteTxn) {
+				stmt := `
+INSERT INTO settings(id, created_at, updated_at, organization_id, private_jwk, public_jwk)
+VALUES (24681357, '2022-08-26 11:05:00', '2022-08-26 11:05:00', 13572468, ?, ?);`
+				_, err := db.Exec(stmt, []byte("sealed-private-key"), []byte(`{"kid":"the-key-id"}`))
+				assert.NilError(t, err)
+			},
+			cleanup: func(t *testing.T, db WriteTxn) {
+				_, err := db.Exec(`DELETE FROM settings WHERE id = 24681357`)
+				assert.NilError(t, err)
+				_, err = db.Exec(`DELETE FROM signing_keys`)
+				assert.NilError(t, err)
+			},
+			expected: func(t *testing.T, db WriteTxn) {
+				var orgID uid.ID
+				var keyID, state string
+				var private, public []byte
+				err := db.QueryRow(`SELECT organization_id, key_id, private_jwk, public_jwk, state FROM signing_keys`).
+					Scan(&orgID, &keyID, &private, &public, &state)
+				assert.NilError(t, err)
+
+				assert.Equal(t, orgID, uid.ID(13572468))
+				assert.Equal(t, keyID, "the-key-id")
+				assert.Equal(t, string(private), "sealed-private-key")
+				assert.Equal(t, string(public), `{"kid":"the-key-id"}`)
+				assert.Equal(t, state, string(models.SigningKeyStateActive))
+
+				assert.Assert(t, !migrator.HasColumn(db, "settings", "private_jwk"))
+				assert.Assert(t, !migrator.HasColumn(db, "settings", "public_jwk"))
+			},
+		},
+		{
+			label: testCaseLine("2022-08-29T14:20"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// new tables are tested by schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-08-31T09:45"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// column changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-09-01T11:20"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// column changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-09-02T10:30"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// new tables are tested by schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-09-05T13:15"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// new tables are tested by schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-09-07T10:00"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// new tables are tested by schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-09-08T09:30"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// new tables are tested by schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-09-09T14:00"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// new tables are tested by schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-09-12T10:00"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// column changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-09-13T10:00"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// column changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-09-14T10:00"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// new tables are tested by schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-09-16T10:00"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// new tables are tested by schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-09-19T10:00"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// new tables are tested by schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-09-20T10:00"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// column changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-09-21T10:00"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// column changes are tested with schema comparison
+			},
+		},
+		{
+			label: testCaseLine("2022-09-22T10:00"),
+			expected: func(t *testing.T, tx WriteTxn) {
+				// column changes are tested with schema comparison
+			},
+		},
 	}
 
 	ids := make(map[string]struct{}, len(testCases))
@@ -0,0 +1,117 @@
+package data
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/generate"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// CreateOIDCClient generates a client ID and a client secret for client and
+// saves it.
+func CreateOIDCClient(db GormTxn, client *models.OIDCClient) error {
+	clientID, err := generate.CryptoRandom(24, generate.CharsetAlphaNumeric)
+	if err != nil {
+		return err
+	}
+	secret, err := generate.CryptoRandom(48, generate.CharsetAlphaNumeric)
+	if err != nil {
+		return err
+	}
+
+	client.ClientID = clientID
+	client.ClientSecret = models.EncryptedAtRest(secret)
+	return add(db, client)
+}
+
+func GetOIDCClient(db GormTxn, selectors ...SelectorFunc) (*models.OIDCClient, error) {
+	return get[models.OIDCClient](db, selectors...)
+}
+
+func ListOIDCClients(db GormTxn, p *models.Pagination, selectors ...SelectorFunc) ([]models.OIDCClient, error) {
+	return list[models.OIDCClient](db, p, selectors...)
+}
+
+func SaveOIDCClient(db GormTxn, client *models.OIDCClient) error {
+	return save(db, client)
+}
+
+// DeleteOIDCClients deletes the clients, and the authorization codes that were
+// issued to them and not yet exchanged.
+func DeleteOIDCClients(db GormTxn, selectors ...SelectorFunc) error {
+	clients, err := ListOIDCClients(db, nil, selectors...)
+	if err != nil {
+		return err
+	}
+
+	for _, client := range clients {
+		if err := deleteAll[models.OIDCAuthorizationCode](db, ByOIDCClientID(client.ID)); err != nil {
+			return err
+		}
+		if err := delete[models.OIDCClient](db, client.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ByClientID(clientID string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("client_id = ?", clientID)
+	}
+}
+
+// CreateOIDCAuthorizationCode generates a code for authCode and saves it.
+func CreateOIDCAuthorizationCode(db GormTxn, authCode *models.OIDCAuthorizationCode) error {
+	tries := 0
+retry:
+	code, err := generate.CryptoRandom(38, generate.CharsetAlphaNumeric)
+	if err != nil {
+		return err
+	}
+
+	authCode.Code = code
+
+	tries++
+	if err := add(db, authCode); err != nil {
+		if tries <= 3 && errors.Is(err, UniqueConstraintError{}) {
+			logging.Warnf("generated random OIDC authorization code already exists in the database")
+			goto retry // on the off chance the code exists.
+		}
+		return err
+	}
+	return nil
+}
+
+func GetOIDCAuthorizationCode(db GormTxn, selectors ...SelectorFunc) (*models.OIDCAuthorizationCode, error) {
+	return get[models.OIDCAuthorizationCode](db, selectors...)
+}
+
+func DeleteOIDCAuthorizationCode(db GormTxn, authCode *models.OIDCAuthorizationCode) error {
+	return delete[models.OIDCAuthorizationCode](db, authCode.ID)
+}
+
+// DeleteExpiredOIDCAuthorizationCodes removes all authorization codes which
+// expired before now. Unlike most functions in this package, it removes codes
+// from every organization.
+func DeleteExpiredOIDCAuthorizationCodes(tx GormTxn, now time.Time) (int64, error) {
+	result := tx.GormDB().Where("expires_at <= ?", now.UTC()).Delete(&models.OIDCAuthorizationCode{})
+	return result.RowsAffected, result.Error
+}
+
+func ByCode(code string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("code = ?", code)
+	}
+}
+
+func ByOIDCClientID(id uid.ID) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("oidc_client_id = ?", id)
+	}
+}
@@ -0,0 +1,329 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// OrganizationExport contains the resources of an organization that can be
+// copied to another organization. Resources created by the system for every
+// organization, like the infra provider and the connector identity, are not
+// included.
+type OrganizationExport struct {
+	Providers []models.Provider
+	// Identities are loaded with their Providers.
+	Identities []models.Identity
+	// Groups are loaded with their Identities.
+	Groups       []models.Group
+	Grants       []models.Grant
+	Destinations []models.Destination
+	Settings     *models.Settings
+}
+
+// ExportOrganization returns the resources of the organization in tx.
+func ExportOrganization(tx GormTxn) (*OrganizationExport, error) {
+	export := &OrganizationExport{}
+
+	providers, err := ListProviders(tx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("list providers: %w", err)
+	}
+	for _, provider := range providers {
+		if provider.Kind != models.ProviderKindInfra {
+			export.Providers = append(export.Providers, provider)
+		}
+	}
+
+	identities, err := ListIdentities(tx, nil, Preload("Providers"))
+	if err != nil {
+		return nil, fmt.Errorf("list identities: %w", err)
+	}
+	exported := map[uid.PolymorphicID]bool{}
+	for _, identity := range identities {
+		if identity.Name == models.InternalInfraConnectorIdentityName {
+			continue
+		}
+		export.Identities = append(export.Identities, identity)
+		exported[identity.PolyID()] = true
+	}
+
+	export.Groups, err = ListGroups(tx, nil, Preload("Identities"))
+	if err != nil {
+		return nil, fmt.Errorf("list groups: %w", err)
+	}
+	for _, group := range export.Groups {
+		exported[group.PolyID()] = true
+	}
+
+	grants, err := ListGrants(tx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("list grants: %w", err)
+	}
+	for _, grant := range grants {
+		if exported[grant.Subject] {
+			export.Grants = append(export.Grants, grant)
+		}
+	}
+
+	export.Destinations, err = ListDestinations(tx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("list destinations: %w", err)
+	}
+
+	export.Settings, err = GetSettings(tx)
+	if err != nil {
+		return nil, fmt.Errorf("get settings: %w", err)
+	}
+	return export, nil
+}
+
+// ImportConflict is a resource from an OrganizationExport that can not be
+// imported because it would violate a unique constraint.
+type ImportConflict struct {
+	Table  string
+	Column string
+	Value  string
+}
+
+type ImportOptions struct {
+	// DryRun checks for conflicts without importing anything.
+	DryRun bool
+	// CreatedBy is the identity recorded as the creator of the imported
+	// resources.
+	CreatedBy uid.ID
+}
+
+// ImportOrganization creates the resources from export in the organization in
+// tx. The resources are created with new IDs, and references between them are
+// updated to use the new IDs.
+//
+// Resources which already exist in the organization are returned as
+// conflicts. If there are any conflicts nothing is imported, and an error
+// is returned unless opts.DryRun is true.
+func ImportOrganization(tx GormTxn, export *OrganizationExport, opts ImportOptions) ([]ImportConflict, error) {
+	conflicts, err := findImportConflicts(tx, export)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return conflicts, nil
+	}
+	if len(conflicts) > 0 {
+		first := conflicts[0]
+		return conflicts, fmt.Errorf("import %d conflicting resources, %v %q: %w",
+			len(conflicts), first.Column, first.Value,
+			UniqueConstraintError{Table: first.Table, Column: first.Column})
+	}
+
+	// the request transaction is committed even when the request fails, so
+	// use a nested transaction to make sure a partial import is rolled back.
+	err = tx.GormDB().Transaction(func(db *gorm.DB) error {
+		return importOrganization(NewTransaction(db, tx.OrganizationID()), export, opts)
+	})
+	return nil, err
+}
+
+func importOrganization(tx GormTxn, export *OrganizationExport, opts ImportOptions) error {
+	providerIDs := map[uid.ID]*models.Provider{}
+	for _, exported := range export.Providers {
+		provider := exported
+		provider.Model = models.Model{}
+		provider.CreatedBy = opts.CreatedBy
+		if err := CreateProvider(tx, &provider); err != nil {
+			return fmt.Errorf("create provider %q: %w", exported.Name, err)
+		}
+		providerIDs[exported.ID] = &provider
+	}
+
+	infraProvider := InfraProvider(tx)
+	identityIDs := map[uid.ID]uid.ID{}
+	for _, exported := range export.Identities {
+		identity := &models.Identity{Name: exported.Name, CreatedBy: opts.CreatedBy}
+		if err := CreateIdentity(tx, identity); err != nil {
+			return fmt.Errorf("create identity %q: %w", exported.Name, err)
+		}
+		identityIDs[exported.ID] = identity.ID
+
+		for _, exportedProvider := range exported.Providers {
+			provider := providerIDs[exportedProvider.ID]
+			if exportedProvider.Kind == models.ProviderKindInfra {
+				provider = infraProvider
+			}
+			if provider == nil {
+				continue
+			}
+			if _, err := CreateProviderUser(tx, provider, identity); err != nil {
+				return fmt.Errorf("create provider user %q: %w", exported.Name, err)
+			}
+		}
+	}
+
+	groupIDs := map[uid.ID]uid.ID{}
+	for _, exported := range export.Groups {
+		group := &models.Group{Name: exported.Name, CreatedBy: opts.CreatedBy}
+		if provider, ok := providerIDs[exported.CreatedByProvider]; ok {
+			group.CreatedByProvider = provider.ID
+		}
+		if err := CreateGroup(tx, group); err != nil {
+			return fmt.Errorf("create group %q: %w", exported.Name, err)
+		}
+		groupIDs[exported.ID] = group.ID
+
+		members := make([]uid.ID, 0, len(exported.Identities))
+		for _, member := range exported.Identities {
+			if id, ok := identityIDs[member.ID]; ok {
+				members = append(members, id)
+			}
+		}
+		if err := AddUsersToGroup(tx, group.ID, members); err != nil {
+			return fmt.Errorf("add users to group %q: %w", exported.Name, err)
+		}
+	}
+
+	// nested groups are added once all the groups exist
+	for _, exported := range export.Groups {
+		for _, parent := range exported.ParentGroupIDs {
+			parentID, ok := groupIDs[parent]
+			if !ok {
+				continue
+			}
+			if err := AddGroupsToGroup(tx, parentID, []uid.ID{groupIDs[exported.ID]}); err != nil {
+				return fmt.Errorf("add group %q to parent group: %w", exported.Name, err)
+			}
+		}
+	}
+
+	for _, exported := range export.Grants {
+		subject, ok := remapSubject(exported.Subject, identityIDs, groupIDs)
+		if !ok {
+			continue
+		}
+		grant := &models.Grant{
+			Subject:   subject,
+			Privilege: exported.Privilege,
+			Resource:  exported.Resource,
+			ExpiresAt: exported.ExpiresAt,
+			CreatedBy: opts.CreatedBy,
+		}
+		if err := CreateGrant(tx, grant); err != nil {
+			return fmt.Errorf("create grant %v %v: %w", exported.Privilege, exported.Resource, err)
+		}
+	}
+
+	for _, exported := range export.Destinations {
+		destination := &models.Destination{
+			Name:          exported.Name,
+			UniqueID:      exported.UniqueID,
+			ConnectionURL: exported.ConnectionURL,
+			ConnectionCA:  exported.ConnectionCA,
+			Version:       exported.Version,
+			Resources:     exported.Resources,
+			Roles:         exported.Roles,
+		}
+		if err := CreateDestination(tx, destination); err != nil {
+			return fmt.Errorf("create destination %q: %w", exported.Name, err)
+		}
+	}
+
+	if export.Settings != nil {
+		settings := &models.Settings{
+			LowercaseMin: export.Settings.LowercaseMin,
+			UppercaseMin: export.Settings.UppercaseMin,
+			NumberMin:    export.Settings.NumberMin,
+			SymbolMin:    export.Settings.SymbolMin,
+			LengthMin:    export.Settings.LengthMin,
+			RequireMFA:   export.Settings.RequireMFA,
+
+			LockoutThreshold: export.Settings.LockoutThreshold,
+			LockoutDuration:  export.Settings.LockoutDuration,
+		}
+		if err := SaveSettings(tx, settings); err != nil {
+			return fmt.Errorf("save settings: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// findImportConflicts returns the resources in export that conflict with the
+// unique indices of the existing resources in the organization.
+func findImportConflicts(tx GormTxn, export *OrganizationExport) ([]ImportConflict, error) {
+	var conflicts []ImportConflict
+	check := func(table, column, value string, exists func() error) error {
+		err := exists()
+		switch {
+		case errors.Is(err, internal.ErrNotFound):
+			return nil
+		case err != nil:
+			return err
+		}
+		conflicts = append(conflicts, ImportConflict{Table: table, Column: column, Value: value})
+		return nil
+	}
+
+	for _, provider := range export.Providers {
+		err := check("providers", "name", provider.Name, func() error {
+			_, err := GetProvider(tx, ByName(provider.Name))
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, identity := range export.Identities {
+		err := check("identities", "name", identity.Name, func() error {
+			_, err := GetIdentity(tx, ByName(identity.Name))
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, group := range export.Groups {
+		err := check("groups", "name", group.Name, func() error {
+			_, err := GetGroup(tx, ByName(group.Name))
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, destination := range export.Destinations {
+		if destination.UniqueID == "" {
+			continue
+		}
+		err := check("destinations", "uniqueId", destination.UniqueID, func() error {
+			_, err := GetDestination(tx, ByOptionalUniqueID(destination.UniqueID))
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return conflicts, nil
+}
+
+func remapSubject(subject uid.PolymorphicID, identityIDs, groupIDs map[uid.ID]uid.ID) (uid.PolymorphicID, bool) {
+	id, err := subject.ID()
+	if err != nil {
+		return "", false
+	}
+
+	switch {
+	case subject.IsIdentity():
+		if newID, ok := identityIDs[id]; ok {
+			return uid.NewIdentityPolymorphicID(newID), true
+		}
+	case subject.IsGroup():
+		if newID, ok := groupIDs[id]; ok {
+			return uid.NewGroupPolymorphicID(newID), true
+		}
+	}
+	return "", false
+}
@@ -1,13 +1,16 @@
 package data
 
 import (
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"gotest.tools/v3/assert"
 
+	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
 )
 
 // PostgreSQL only has microsecond precision
@@ -66,3 +69,116 @@ func TestCreateOrganization(t *testing.T) {
 		assert.DeepEqual(t, connectorGrant, expectedConnectorGrant)
 	})
 }
+
+func TestExportImportOrganization(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		source := &models.Organization{Name: "source", Domain: "source-123"}
+		assert.NilError(t, CreateOrganization(db, source))
+		sourceTx := NewTransaction(db.DB, source.ID)
+
+		okta := models.Provider{Name: "okta", URL: "example.okta.com", Kind: models.ProviderKindOkta}
+		createProviders(t, sourceTx, okta)
+		okta = *getProvider(t, sourceTx, "okta")
+
+		alice := &models.Identity{Name: "alice@example.com"}
+		bob := &models.Identity{Name: "bob@example.com"}
+		createIdentities(t, sourceTx, alice, bob)
+		_, err := CreateProviderUser(sourceTx, InfraProvider(sourceTx), alice)
+		assert.NilError(t, err)
+		_, err = CreateProviderUser(sourceTx, &okta, bob)
+		assert.NilError(t, err)
+
+		devs := &models.Group{Name: "developers", CreatedByProvider: okta.ID}
+		createGroups(t, sourceTx, devs)
+		assert.NilError(t, AddUsersToGroup(sourceTx, devs.ID, []uid.ID{alice.ID, bob.ID}))
+
+		assert.NilError(t, CreateGrant(sourceTx, &models.Grant{Subject: alice.PolyID(), Privilege: "admin", Resource: "infra"}))
+		assert.NilError(t, CreateGrant(sourceTx, &models.Grant{Subject: devs.PolyID(), Privilege: "edit", Resource: "staging"}))
+
+		assert.NilError(t, CreateDestination(sourceTx, &models.Destination{Name: "staging", UniqueID: "abcd"}))
+		assert.NilError(t, SaveSettings(sourceTx, &models.Settings{LengthMin: 12}))
+
+		export, err := ExportOrganization(sourceTx)
+		assert.NilError(t, err)
+		assert.Equal(t, len(export.Providers), 1)
+		assert.Equal(t, len(export.Identities), 2) // connector is excluded
+		assert.Equal(t, len(export.Groups), 1)
+		assert.Equal(t, len(export.Grants), 2) // connector grant is excluded
+		assert.Equal(t, len(export.Destinations), 1)
+
+		target := &models.Organization{Name: "target", Domain: "target-123"}
+		assert.NilError(t, CreateOrganization(db, target))
+		targetTx := NewTransaction(db.DB, target.ID)
+
+		t.Run("dry run", func(t *testing.T) {
+			conflicts, err := ImportOrganization(targetTx, export, ImportOptions{DryRun: true})
+			assert.NilError(t, err)
+			assert.Equal(t, len(conflicts), 0)
+
+			_, err = GetIdentity(targetTx, ByName("alice@example.com"))
+			assert.ErrorIs(t, err, internal.ErrNotFound)
+		})
+
+		t.Run("import", func(t *testing.T) {
+			conflicts, err := ImportOrganization(targetTx, export, ImportOptions{CreatedBy: 1234})
+			assert.NilError(t, err)
+			assert.Equal(t, len(conflicts), 0)
+
+			newAlice, err := GetIdentity(targetTx, ByName("alice@example.com"), Preload("Providers"))
+			assert.NilError(t, err)
+			assert.Assert(t, newAlice.ID != alice.ID)
+			assert.Equal(t, newAlice.CreatedBy, uid.ID(1234))
+			assert.Equal(t, len(newAlice.Providers), 1)
+			assert.Equal(t, newAlice.Providers[0].ID, InfraProvider(targetTx).ID)
+
+			newOkta := getProvider(t, targetTx, "okta")
+			newBob, err := GetIdentity(targetTx, ByName("bob@example.com"), Preload("Providers"))
+			assert.NilError(t, err)
+			assert.Equal(t, len(newBob.Providers), 1)
+			assert.Equal(t, newBob.Providers[0].ID, newOkta.ID)
+
+			newDevs, err := GetGroup(targetTx, ByName("developers"))
+			assert.NilError(t, err)
+			assert.Equal(t, newDevs.CreatedByProvider, newOkta.ID)
+			members, err := ListIdentities(targetTx, nil, ByOptionalIdentityGroupID(newDevs.ID))
+			assert.NilError(t, err)
+			assert.Equal(t, len(members), 2)
+
+			_, err = GetGrant(targetTx, BySubject(newAlice.PolyID()), ByPrivilege("admin"), ByResource("infra"))
+			assert.NilError(t, err)
+			_, err = GetGrant(targetTx, BySubject(newDevs.PolyID()), ByPrivilege("edit"), ByResource("staging"))
+			assert.NilError(t, err)
+
+			_, err = GetDestination(targetTx, ByOptionalUniqueID("abcd"))
+			assert.NilError(t, err)
+
+			settings, err := GetSettings(targetTx)
+			assert.NilError(t, err)
+			assert.Equal(t, settings.LengthMin, 12)
+		})
+
+		t.Run("conflicts", func(t *testing.T) {
+			conflicts, err := ImportOrganization(targetTx, export, ImportOptions{DryRun: true})
+			assert.NilError(t, err)
+			expected := []ImportConflict{
+				{Table: "providers", Column: "name", Value: "okta"},
+				{Table: "identities", Column: "name", Value: "alice@example.com"},
+				{Table: "identities", Column: "name", Value: "bob@example.com"},
+				{Table: "groups", Column: "name", Value: "developers"},
+				{Table: "destinations", Column: "uniqueId", Value: "abcd"},
+			}
+			assert.DeepEqual(t, conflicts, expected)
+
+			_, err = ImportOrganization(targetTx, export, ImportOptions{})
+			var ucErr UniqueConstraintError
+			assert.Assert(t, errors.As(err, &ucErr), "wrong error type %T", err)
+		})
+	})
+}
+
+func getProvider(t *testing.T, tx GormTxn, name string) *models.Provider {
+	t.Helper()
+	provider, err := GetProvider(tx, ByName(name))
+	assert.NilError(t, err)
+	return provider
+}
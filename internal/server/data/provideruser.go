@@ -104,3 +104,23 @@ func SyncProviderUser(ctx context.Context, tx GormTxn, user *models.Identity, pr
 
 	return nil
 }
+
+// SyncLDAPProviderUser updates the groups of the user from the LDAP directory
+// of the provider. It fails if the user no longer exists in the directory.
+func SyncLDAPProviderUser(ctx context.Context, tx GormTxn, user *models.Identity, provider *models.Provider, ldapClient providers.LDAPClient) error {
+	providerUser, err := GetProviderUser(tx, provider.ID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	ldapUser, err := ldapClient.GetUser(ctx, providerUser.Email)
+	if err != nil {
+		return fmt.Errorf("ldap user sync failed: %w", err)
+	}
+
+	if err := AssignIdentityToGroups(tx, user, provider, ldapUser.Groups); err != nil {
+		return fmt.Errorf("assign identity to groups: %w", err)
+	}
+
+	return nil
+}
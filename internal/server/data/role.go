@@ -0,0 +1,52 @@
+package data
+
+import (
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func CreateRole(db GormTxn, role *models.Role) error {
+	if err := add(db, role); err != nil {
+		return err
+	}
+	return createRoleEvent(db, api.DestinationEventRoleUpdated, role)
+}
+
+func GetRole(db GormTxn, selectors ...SelectorFunc) (*models.Role, error) {
+	return get[models.Role](db, selectors...)
+}
+
+func ListRoles(db GormTxn, p *models.Pagination, selectors ...SelectorFunc) ([]models.Role, error) {
+	return list[models.Role](db, p, selectors...)
+}
+
+func SaveRole(db GormTxn, role *models.Role) error {
+	if err := save(db, role); err != nil {
+		return err
+	}
+	return createRoleEvent(db, api.DestinationEventRoleUpdated, role)
+}
+
+func DeleteRoles(db GormTxn, selectors ...SelectorFunc) error {
+	toDelete, err := ListRoles(db, nil, selectors...)
+	if err != nil {
+		return err
+	}
+
+	if err := deleteAll[models.Role](db, selectors...); err != nil {
+		return err
+	}
+
+	for i := range toDelete {
+		if err := createRoleEvent(db, api.DestinationEventRoleDeleted, &toDelete[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createRoleEvent records the change to a role for every destination, because
+// custom roles are created in every destination.
+func createRoleEvent(db GormTxn, eventType string, role *models.Role) error {
+	return CreateDestinationEvent(db, "", api.DestinationEvent{Type: eventType, Role: role.ToAPI()})
+}
@@ -0,0 +1,34 @@
+package data
+
+import (
+	"fmt"
+	"time"
+)
+
+// UseSAMLAssertion records that the SAML assertion with id was used to login.
+// A UniqueConstraintError is returned when the assertion was used before,
+// which prevents a captured response from being replayed. Assertions that have
+// expired are deleted, because they can no longer be used to login.
+func UseSAMLAssertion(tx WriteTxn, id string, expiresAt time.Time) error {
+	_, err := tx.Exec(`DELETE FROM saml_assertions WHERE organization_id = ? AND expires_at < ?`,
+		tx.OrganizationID(), time.Now())
+	if err != nil {
+		return fmt.Errorf("delete expired saml assertions: %w", err)
+	}
+
+	result, err := tx.Exec(`
+INSERT INTO saml_assertions (organization_id, id, expires_at) VALUES (?, ?, ?)
+ON CONFLICT (organization_id, id) DO NOTHING`,
+		tx.OrganizationID(), id, expiresAt)
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return UniqueConstraintError{Table: "saml_assertions", Column: "id"}
+	}
+	return nil
+}
@@ -21,6 +21,38 @@ CREATE TABLE access_keys (
     organization_id bigint
 );
 
+CREATE TABLE access_requests (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    user_id bigint,
+    privilege text,
+    resource text,
+    reason text,
+    duration bigint,
+    state text,
+    reviewed_by bigint,
+    grant_id bigint
+);
+
+CREATE TABLE audit_events (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    actor_id bigint,
+    actor_name text,
+    method text,
+    path text,
+    resource text,
+    resource_id bigint,
+    request text,
+    status_code bigint
+);
+
 CREATE TABLE credentials (
     id bigint NOT NULL,
     created_at timestamp with time zone,
@@ -29,7 +61,30 @@ CREATE TABLE credentials (
     identity_id bigint,
     password_hash bytea,
     one_time_password boolean,
-    organization_id bigint
+    organization_id bigint,
+    mfa_secret text,
+    mfa_enabled boolean,
+    mfa_recovery_codes text,
+    mfa_last_used_step bigint,
+    failed_login_attempts bigint DEFAULT 0,
+    locked_until timestamp with time zone
+);
+
+CREATE TABLE destination_event_sequences (
+    organization_id bigint NOT NULL,
+    sequence bigint
+);
+
+CREATE TABLE destination_events (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    sequence bigint,
+    type text,
+    resource text,
+    payload bytea
 );
 
 CREATE TABLE destinations (
@@ -45,7 +100,22 @@ CREATE TABLE destinations (
     version text,
     resources text,
     roles text,
-    organization_id bigint
+    organization_id bigint,
+    disconnected_at timestamp with time zone
+);
+
+CREATE TABLE device_flow_auth_requests (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    user_code text,
+    device_code text,
+    expires_at timestamp with time zone,
+    approved_by bigint,
+    provider_id bigint,
+    last_polled_at timestamp with time zone
 );
 
 CREATE TABLE encryption_keys (
@@ -60,6 +130,22 @@ CREATE TABLE encryption_keys (
     root_key_id text
 );
 
+CREATE TABLE federations (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    name text,
+    issuer text,
+    jwks_url text,
+    audience text,
+    conditions text,
+    identity_id bigint,
+    ttl bigint,
+    created_by bigint
+);
+
 CREATE TABLE grants (
     id bigint NOT NULL,
     created_at timestamp with time zone,
@@ -69,7 +155,8 @@ CREATE TABLE grants (
     privilege text,
     resource text,
     created_by bigint,
-    organization_id bigint
+    organization_id bigint,
+    expires_at timestamp with time zone
 );
 
 CREATE TABLE groups (
@@ -99,6 +186,40 @@ CREATE TABLE identities_groups (
     group_id bigint NOT NULL
 );
 
+CREATE TABLE nested_groups (
+    group_id bigint NOT NULL,
+    member_group_id bigint NOT NULL
+);
+
+CREATE TABLE oidc_authorization_codes (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    code text,
+    oidc_client_id bigint,
+    identity_id bigint,
+    redirect_uri text,
+    scopes text,
+    nonce text,
+    code_challenge text,
+    expires_at timestamp with time zone
+);
+
+CREATE TABLE oidc_clients (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    name text,
+    client_id text,
+    client_secret text,
+    redirect_uris text,
+    created_by bigint
+);
+
 CREATE TABLE organizations (
     id bigint NOT NULL,
     created_at timestamp with time zone,
@@ -145,7 +266,34 @@ CREATE TABLE providers (
     private_key text,
     client_email text,
     domain_admin_email text,
-    organization_id bigint
+    organization_id bigint,
+    bind_dn text,
+    bind_password text,
+    search_base text,
+    user_filter text,
+    group_filter text,
+    saml_entity_id text,
+    saml_certificate text,
+    saml_name_attribute text,
+    saml_groups_attribute text
+);
+
+CREATE TABLE roles (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    name text,
+    description text,
+    rules text,
+    created_by bigint
+);
+
+CREATE TABLE saml_assertions (
+    organization_id bigint NOT NULL,
+    id text NOT NULL,
+    expires_at timestamp with time zone
 );
 
 CREATE TABLE settings (
@@ -153,28 +301,95 @@ CREATE TABLE settings (
     created_at timestamp with time zone,
     updated_at timestamp with time zone,
     deleted_at timestamp with time zone,
-    private_jwk bytea,
-    public_jwk bytea,
     lowercase_min bigint DEFAULT 0,
     uppercase_min bigint DEFAULT 0,
     number_min bigint DEFAULT 0,
     symbol_min bigint DEFAULT 0,
     length_min bigint DEFAULT 8,
-    organization_id bigint
+    organization_id bigint,
+    require_mfa text,
+    lockout_threshold bigint DEFAULT 10,
+    lockout_duration bigint DEFAULT '900000000000'::bigint
+);
+
+CREATE TABLE signing_keys (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    key_id text,
+    private_jwk bytea,
+    public_jwk bytea,
+    state text,
+    retire_at timestamp with time zone
+);
+
+CREATE TABLE ssh_user_cas (
+    organization_id bigint NOT NULL,
+    created_at timestamp with time zone,
+    private_key bytea,
+    public_key text
+);
+
+CREATE TABLE webhook_deliveries (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    webhook_id bigint,
+    event_type text,
+    payload bytea,
+    state text,
+    attempts bigint,
+    next_attempt_at timestamp with time zone,
+    last_status_code bigint,
+    last_error text
+);
+
+CREATE TABLE webhooks (
+    id bigint NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    organization_id bigint,
+    url text,
+    secret text,
+    event_types text,
+    created_by bigint
 );
 
 ALTER TABLE ONLY access_keys
     ADD CONSTRAINT access_keys_pkey PRIMARY KEY (id);
 
+ALTER TABLE ONLY access_requests
+    ADD CONSTRAINT access_requests_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY audit_events
+    ADD CONSTRAINT audit_events_pkey PRIMARY KEY (id);
+
 ALTER TABLE ONLY credentials
     ADD CONSTRAINT credentials_pkey PRIMARY KEY (id);
 
+ALTER TABLE ONLY destination_event_sequences
+    ADD CONSTRAINT destination_event_sequences_pkey PRIMARY KEY (organization_id);
+
+ALTER TABLE ONLY destination_events
+    ADD CONSTRAINT destination_events_pkey PRIMARY KEY (id);
+
 ALTER TABLE ONLY destinations
     ADD CONSTRAINT destinations_pkey PRIMARY KEY (id);
 
+ALTER TABLE ONLY device_flow_auth_requests
+    ADD CONSTRAINT device_flow_auth_requests_pkey PRIMARY KEY (id);
+
 ALTER TABLE ONLY encryption_keys
     ADD CONSTRAINT encryption_keys_pkey PRIMARY KEY (id);
 
+ALTER TABLE ONLY federations
+    ADD CONSTRAINT federations_pkey PRIMARY KEY (id);
+
 ALTER TABLE ONLY grants
     ADD CONSTRAINT grants_pkey PRIMARY KEY (id);
 
@@ -187,6 +402,15 @@ ALTER TABLE ONLY identities_groups
 ALTER TABLE ONLY identities
     ADD CONSTRAINT identities_pkey PRIMARY KEY (id);
 
+ALTER TABLE ONLY nested_groups
+    ADD CONSTRAINT nested_groups_pkey PRIMARY KEY (group_id, member_group_id);
+
+ALTER TABLE ONLY oidc_authorization_codes
+    ADD CONSTRAINT oidc_authorization_codes_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY oidc_clients
+    ADD CONSTRAINT oidc_clients_pkey PRIMARY KEY (id);
+
 ALTER TABLE ONLY organizations
     ADD CONSTRAINT organizations_pkey PRIMARY KEY (id);
 
@@ -199,29 +423,63 @@ ALTER TABLE ONLY provider_users
 ALTER TABLE ONLY providers
     ADD CONSTRAINT providers_pkey PRIMARY KEY (id);
 
+ALTER TABLE ONLY roles
+    ADD CONSTRAINT roles_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY saml_assertions
+    ADD CONSTRAINT saml_assertions_pkey PRIMARY KEY (organization_id, id);
+
 ALTER TABLE ONLY settings
     ADD CONSTRAINT settings_pkey PRIMARY KEY (id);
 
+ALTER TABLE ONLY signing_keys
+    ADD CONSTRAINT signing_keys_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY ssh_user_cas
+    ADD CONSTRAINT ssh_user_cas_pkey PRIMARY KEY (organization_id);
+
+ALTER TABLE ONLY webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY webhooks
+    ADD CONSTRAINT webhooks_pkey PRIMARY KEY (id);
+
 CREATE UNIQUE INDEX idx_access_keys_key_id ON access_keys USING btree (key_id) WHERE (deleted_at IS NULL);
 
 CREATE UNIQUE INDEX idx_access_keys_name ON access_keys USING btree (organization_id, name) WHERE (deleted_at IS NULL);
 
 CREATE UNIQUE INDEX idx_credentials_identity_id ON credentials USING btree (organization_id, identity_id) WHERE (deleted_at IS NULL);
 
+CREATE UNIQUE INDEX idx_destination_events_sequence ON destination_events USING btree (organization_id, sequence) WHERE (deleted_at IS NULL);
+
 CREATE UNIQUE INDEX idx_destinations_unique_id ON destinations USING btree (organization_id, unique_id) WHERE (deleted_at IS NULL);
 
+CREATE UNIQUE INDEX idx_device_flow_auth_requests_device_code ON device_flow_auth_requests USING btree (device_code) WHERE (deleted_at IS NULL);
+
+CREATE UNIQUE INDEX idx_device_flow_auth_requests_user_code ON device_flow_auth_requests USING btree (user_code) WHERE (deleted_at IS NULL);
+
 CREATE UNIQUE INDEX idx_encryption_keys_key_id ON encryption_keys USING btree (key_id);
 
+CREATE UNIQUE INDEX idx_federations_name ON federations USING btree (organization_id, name) WHERE (deleted_at IS NULL);
+
 CREATE UNIQUE INDEX idx_grant_srp ON grants USING btree (organization_id, subject, privilege, resource) WHERE (deleted_at IS NULL);
 
 CREATE UNIQUE INDEX idx_groups_name ON groups USING btree (organization_id, name) WHERE (deleted_at IS NULL);
 
 CREATE UNIQUE INDEX idx_identities_name ON identities USING btree (organization_id, name) WHERE (deleted_at IS NULL);
 
+CREATE UNIQUE INDEX idx_oidc_authorization_codes_code ON oidc_authorization_codes USING btree (code) WHERE (deleted_at IS NULL);
+
+CREATE UNIQUE INDEX idx_oidc_clients_client_id ON oidc_clients USING btree (client_id) WHERE (deleted_at IS NULL);
+
+CREATE UNIQUE INDEX idx_oidc_clients_name ON oidc_clients USING btree (organization_id, name) WHERE (deleted_at IS NULL);
+
 CREATE UNIQUE INDEX idx_organizations_domain ON organizations USING btree (domain) WHERE (deleted_at IS NULL);
 
 CREATE UNIQUE INDEX idx_password_reset_tokens_token ON password_reset_tokens USING btree (token);
 
 CREATE UNIQUE INDEX idx_providers_name ON providers USING btree (organization_id, name) WHERE (deleted_at IS NULL);
 
+CREATE UNIQUE INDEX idx_roles_name ON roles USING btree (organization_id, name) WHERE (deleted_at IS NULL);
+
 CREATE UNIQUE INDEX settings_org_id ON settings USING btree (organization_id) WHERE (deleted_at IS NULL);
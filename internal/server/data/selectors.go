@@ -195,6 +195,21 @@ func ByOptionalIdentityGroupID(groupID uid.ID) SelectorFunc {
 	}
 }
 
+// ByIdentityProviderID selects the identities that are users of the provider.
+func ByIdentityProviderID(providerID uid.ID) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.
+			Joins("JOIN provider_users ON provider_users.identity_id = identities.id").
+			Where("provider_users.provider_id = ?", providerID)
+	}
+}
+
+func ByCreatedByProvider(providerID uid.ID) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("created_by_provider = ?", providerID)
+	}
+}
+
 func Preload(name string) SelectorFunc {
 	return func(db *gorm.DB) *gorm.DB {
 		return db.Preload(name)
@@ -1,12 +1,7 @@
 package data
 
 import (
-	"crypto"
-	"crypto/ed25519"
-	"crypto/rand"
-	"encoding/base64"
-
-	"gopkg.in/square/go-jose.v2"
+	"fmt"
 
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
@@ -18,36 +13,8 @@ func initializeSettings(tx GormTxn) (*models.Settings, error) {
 		return settings, err
 	}
 
-	pubkey, seckey, err := ed25519.GenerateKey(rand.Reader)
-	if err != nil {
-		return nil, err
-	}
-
-	sec := jose.JSONWebKey{Key: seckey, KeyID: "", Algorithm: string(jose.ED25519), Use: "sig"}
-
-	thumb, err := sec.Thumbprint(crypto.SHA256)
-	if err != nil {
-		return nil, err
-	}
-
-	sec.KeyID = base64.URLEncoding.EncodeToString(thumb)
-
-	pub := jose.JSONWebKey{Key: pubkey, KeyID: sec.KeyID, Algorithm: string(jose.ED25519), Use: "sig"}
-
-	secs, err := sec.MarshalJSON()
-	if err != nil {
-		return nil, err
-	}
-
-	pubs, err := pub.MarshalJSON()
-	if err != nil {
-		return nil, err
-	}
-
 	settings = &models.Settings{
 		OrganizationMember: models.OrganizationMember{OrganizationID: tx.OrganizationID()},
-		PrivateJWK:         secs,
-		PublicJWK:          pubs,
 	}
 
 	db := tx.GormDB()
@@ -56,6 +23,10 @@ func initializeSettings(tx GormTxn) (*models.Settings, error) {
 		return nil, err
 	}
 
+	if _, err := CreateSigningKey(tx); err != nil {
+		return nil, fmt.Errorf("create signing key: %w", err)
+	}
+
 	return settings, nil
 }
 
@@ -21,8 +21,11 @@ func TestInitializeSettings(t *testing.T) {
 			assert.NilError(t, err)
 
 			assert.Assert(t, settings.ID != 0)
-			assert.Assert(t, len(settings.PrivateJWK) != 0)
-			assert.Assert(t, len(settings.PublicJWK) != 0)
+
+			key, err := GetSigningKey(db, BySigningKeyState(models.SigningKeyStateActive))
+			assert.NilError(t, err)
+			assert.Assert(t, len(key.PrivateJWK) != 0)
+			assert.Assert(t, len(key.PublicJWK) != 0)
 		})
 
 		runStep(t, "next call returns existing settings", func(t *testing.T) {
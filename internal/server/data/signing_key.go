@@ -0,0 +1,147 @@
+package data
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+// MaxSignedTokenLifetime is the longest lifetime of any token signed with a
+// signing key. A rotated key stays published for this long, so that every
+// token it signed expires before the key is retired.
+const MaxSignedTokenLifetime = time.Hour
+
+// newSigningKey generates a new ED25519 JSON Web Key for signing tokens.
+func newSigningKey() (*models.SigningKey, error) {
+	pubkey, seckey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	sec := jose.JSONWebKey{Key: seckey, KeyID: "", Algorithm: string(jose.ED25519), Use: "sig"}
+
+	thumb, err := sec.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	sec.KeyID = base64.URLEncoding.EncodeToString(thumb)
+
+	pub := jose.JSONWebKey{Key: pubkey, KeyID: sec.KeyID, Algorithm: string(jose.ED25519), Use: "sig"}
+
+	secs, err := sec.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	pubs, err := pub.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SigningKey{
+		KeyID:      sec.KeyID,
+		PrivateJWK: secs,
+		PublicJWK:  pubs,
+		State:      models.SigningKeyStateActive,
+	}, nil
+}
+
+// CreateSigningKey generates a new active signing key.
+func CreateSigningKey(db GormTxn) (*models.SigningKey, error) {
+	key, err := newSigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := add(db, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func GetSigningKey(db GormTxn, selectors ...SelectorFunc) (*models.SigningKey, error) {
+	return get[models.SigningKey](db, selectors...)
+}
+
+func ListSigningKeys(db GormTxn, p *models.Pagination, selectors ...SelectorFunc) ([]models.SigningKey, error) {
+	return list[models.SigningKey](db, p, selectors...)
+}
+
+func BySigningKeyState(states ...models.SigningKeyState) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("state IN (?)", states)
+	}
+}
+
+// RotateSigningKeys creates a new active signing key. The previously active
+// key is kept as a retiring key, so that tokens it signed remain valid until
+// they expire. Keys that were already retiring are retired once their
+// RetireAt has passed. If retirePrevious is true, all previous keys are
+// retired immediately instead.
+func RotateSigningKeys(db GormTxn, retirePrevious bool) (*models.SigningKey, error) {
+	keys, err := ListSigningKeys(db, nil, BySigningKeyState(models.SigningKeyStateActive, models.SigningKeyStateRetiring))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range keys {
+		key := &keys[i]
+		switch {
+		case retirePrevious:
+			key.State = models.SigningKeyStateRetired
+		case key.State == models.SigningKeyStateActive:
+			key.State = models.SigningKeyStateRetiring
+			key.RetireAt = now.Add(MaxSignedTokenLifetime)
+		case key.RetireAt.After(now):
+			continue
+		default:
+			key.State = models.SigningKeyStateRetired
+		}
+
+		if err := save(db, key); err != nil {
+			return nil, fmt.Errorf("retire signing key: %w", err)
+		}
+	}
+
+	return CreateSigningKey(db)
+}
+
+// RotateExpiredSigningKeys rotates the signing keys of every organization
+// where the active key was created more than maxAge ago, and retires the
+// retiring keys whose RetireAt has passed. It returns the number of
+// organizations that had their keys rotated.
+func RotateExpiredSigningKeys(tx GormTxn, maxAge time.Duration) (int, error) {
+	now := time.Now()
+	err := tx.GormDB().Model(&models.SigningKey{}).
+		Where("state = ? AND (retire_at IS NULL OR retire_at <= ?)", models.SigningKeyStateRetiring, now).
+		Update("state", models.SigningKeyStateRetired).Error
+	if err != nil {
+		return 0, fmt.Errorf("retire signing keys: %w", err)
+	}
+
+	var keys []models.SigningKey
+	err = tx.GormDB().
+		Where("state = ? AND created_at < ?", models.SigningKeyStateActive, now.Add(-maxAge)).
+		Find(&keys).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for _, key := range keys {
+		orgTx := NewTransaction(tx.GormDB(), key.OrganizationID)
+		if _, err := RotateSigningKeys(orgTx, false); err != nil {
+			return 0, fmt.Errorf("rotate signing keys for org %v: %w", key.OrganizationID, err)
+		}
+	}
+	return len(keys), nil
+}
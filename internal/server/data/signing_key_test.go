@@ -0,0 +1,86 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func TestRotateSigningKeys(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		states := func(t *testing.T) map[string]models.SigningKeyState {
+			t.Helper()
+			keys, err := ListSigningKeys(db, nil)
+			assert.NilError(t, err)
+
+			result := make(map[string]models.SigningKeyState)
+			for _, key := range keys {
+				result[key.KeyID] = key.State
+			}
+			return result
+		}
+
+		first, err := GetSigningKey(db, BySigningKeyState(models.SigningKeyStateActive))
+		assert.NilError(t, err)
+
+		second, err := RotateSigningKeys(db, false)
+		assert.NilError(t, err)
+		assert.Assert(t, second.KeyID != first.KeyID)
+		assert.DeepEqual(t, states(t), map[string]models.SigningKeyState{
+			first.KeyID:  models.SigningKeyStateRetiring,
+			second.KeyID: models.SigningKeyStateActive,
+		})
+
+		third, err := RotateSigningKeys(db, false)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, states(t), map[string]models.SigningKeyState{
+			first.KeyID:  models.SigningKeyStateRetiring,
+			second.KeyID: models.SigningKeyStateRetiring,
+			third.KeyID:  models.SigningKeyStateActive,
+		})
+
+		t.Run("retire keys after retire at", func(t *testing.T) {
+			err := db.Model(&models.SigningKey{}).
+				Where("key_id = ?", first.KeyID).
+				Update("retire_at", time.Now().Add(-time.Minute)).Error
+			assert.NilError(t, err)
+
+			count, err := RotateExpiredSigningKeys(db, time.Hour)
+			assert.NilError(t, err)
+			assert.Equal(t, count, 0)
+			assert.DeepEqual(t, states(t), map[string]models.SigningKeyState{
+				first.KeyID:  models.SigningKeyStateRetired,
+				second.KeyID: models.SigningKeyStateRetiring,
+				third.KeyID:  models.SigningKeyStateActive,
+			})
+		})
+
+		t.Run("retire previous", func(t *testing.T) {
+			fourth, err := RotateSigningKeys(db, true)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, states(t), map[string]models.SigningKeyState{
+				first.KeyID:  models.SigningKeyStateRetired,
+				second.KeyID: models.SigningKeyStateRetired,
+				third.KeyID:  models.SigningKeyStateRetired,
+				fourth.KeyID: models.SigningKeyStateActive,
+			})
+		})
+
+		t.Run("rotate expired keys", func(t *testing.T) {
+			count, err := RotateExpiredSigningKeys(db, time.Hour)
+			assert.NilError(t, err)
+			assert.Equal(t, count, 0)
+
+			count, err = RotateExpiredSigningKeys(db, 0)
+			assert.NilError(t, err)
+			assert.Equal(t, count, 1)
+
+			active, err := ListSigningKeys(db, nil, BySigningKeyState(models.SigningKeyStateActive))
+			assert.NilError(t, err)
+			assert.Equal(t, len(active), 1)
+		})
+	})
+}
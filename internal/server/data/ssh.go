@@ -0,0 +1,46 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/pki"
+)
+
+// GetSSHUserCA returns the certificate authority that signs the SSH
+// certificates of users. The CA is created the first time it is used.
+func GetSSHUserCA(tx WriteTxn) (*models.SSHUserCA, error) {
+	ca, err := getSSHUserCA(tx)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return ca, err
+	}
+
+	privateKey, publicKey, err := pki.GenerateSSHCA()
+	if err != nil {
+		return nil, err
+	}
+
+	// another transaction may have created the CA since the select, in which
+	// case its CA is used.
+	_, err = tx.Exec(`
+INSERT INTO ssh_user_cas (organization_id, created_at, private_key, public_key) VALUES (?, ?, ?, ?)
+ON CONFLICT (organization_id) DO NOTHING`,
+		tx.OrganizationID(), time.Now(), models.EncryptedAtRestBytes(privateKey), publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return getSSHUserCA(tx)
+}
+
+func getSSHUserCA(tx ReadTxn) (*models.SSHUserCA, error) {
+	ca := &models.SSHUserCA{}
+	err := tx.QueryRow(`
+SELECT organization_id, created_at, private_key, public_key FROM ssh_user_cas WHERE organization_id = ?`,
+		tx.OrganizationID()).Scan(&ca.OrganizationID, &ca.CreatedAt, &ca.PrivateKey, &ca.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return ca, nil
+}
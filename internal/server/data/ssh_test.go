@@ -0,0 +1,30 @@
+package data
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func TestGetSSHUserCA(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *DB) {
+		ca, err := GetSSHUserCA(db)
+		assert.NilError(t, err)
+		assert.Equal(t, ca.OrganizationID, db.DefaultOrg.ID)
+		assert.Assert(t, len(ca.PrivateKey) > 0)
+		assert.Assert(t, ca.PublicKey != "")
+
+		again, err := GetSSHUserCA(db)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, again, ca)
+
+		org := &models.Organization{Name: "other", Domain: "other-123"}
+		assert.NilError(t, CreateOrganization(db, org))
+
+		other, err := GetSSHUserCA(NewTransaction(db.DB, org.ID))
+		assert.NilError(t, err)
+		assert.Assert(t, other.PublicKey != ca.PublicKey)
+	})
+}
@@ -17,14 +17,17 @@ var signatureAlgorithmFromKeyAlgorithm = map[string]string{
 	"ED25519": "EdDSA", // elliptic curve 25519
 }
 
-func createJWT(db GormTxn, identity *models.Identity, groups []string, expires time.Time) (string, error) {
-	settings, err := GetSettings(db)
+// SignJWT signs the claims with the active signing key of the organization,
+// and returns the compact serialization of the token.
+func SignJWT(db GormTxn, claims ...any) (string, error) {
+	key, err := GetSigningKey(db, BySigningKeyState(models.SigningKeyStateActive))
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("get signing key: %w", err)
 	}
 
+	// the key ID of sec is included in the token header as the kid
 	var sec jose.JSONWebKey
-	if err := sec.UnmarshalJSON(settings.PrivateJWK); err != nil {
+	if err := sec.UnmarshalJSON(key.PrivateJWK); err != nil {
 		return "", err
 	}
 
@@ -40,9 +43,52 @@ func createJWT(db GormTxn, identity *models.Identity, groups []string, expires t
 		return "", err
 	}
 
+	builder := jwt.Signed(signer)
+	for _, c := range claims {
+		builder = builder.Claims(c)
+	}
+	return builder.CompactSerialize()
+}
+
+// ParseSignedJWT verifies that raw was signed by one of the published signing
+// keys of the organization, and decodes its claims into out. The caller must
+// validate the claims.
+func ParseSignedJWT(db GormTxn, raw string, out ...any) error {
+	token, err := jwt.ParseSigned(raw)
+	if err != nil {
+		return err
+	}
+	if len(token.Headers) != 1 {
+		return fmt.Errorf("expected one signature, got %d", len(token.Headers))
+	}
+
+	keys, err := ListSigningKeys(db, nil,
+		BySigningKeyState(models.SigningKeyStateActive, models.SigningKeyStateRetiring))
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if key.KeyID != token.Headers[0].KeyID {
+			continue
+		}
+
+		var pub jose.JSONWebKey
+		if err := pub.UnmarshalJSON(key.PublicJWK); err != nil {
+			return err
+		}
+		return token.Claims(pub, out...)
+	}
+	return fmt.Errorf("unknown signing key %q", token.Headers[0].KeyID)
+}
+
+func createJWT(db GormTxn, identity *models.Identity, groups []string, audience []string, issuer string, expires time.Time) (string, error) {
 	now := time.Now().UTC()
 
 	claim := jwt.Claims{
+		Issuer:    issuer,
+		Subject:   identity.ID.String(),
+		Audience:  audience,
 		NotBefore: jwt.NewNumericDate(now.Add(time.Minute * -5)), // adjust for clock drift
 		Expiry:    jwt.NewNumericDate(expires),
 		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
@@ -54,21 +100,23 @@ func createJWT(db GormTxn, identity *models.Identity, groups []string, expires t
 		Nonce:  generate.MathRandom(10, generate.CharsetAlphaNumeric),
 	}
 
-	raw, err := jwt.Signed(signer).Claims(claim).Claims(custom).CompactSerialize()
-	if err != nil {
-		return "", err
-	}
-
-	return raw, nil
+	return SignJWT(db, claim, custom)
 }
 
-func CreateIdentityToken(db GormTxn, identityID uid.ID) (token *models.Token, err error) {
+// identityTokenLifetime is how long a token created by CreateIdentityToken is
+// valid. It must not exceed MaxSignedTokenLifetime.
+const identityTokenLifetime = 5 * time.Minute
+
+// CreateIdentityToken creates a token for a destination. The audience of the
+// token identifies the destination, so that the token is only accepted by the
+// connector of that destination.
+func CreateIdentityToken(db GormTxn, identityID uid.ID, audience []string, issuer string) (token *models.Token, err error) {
 	identity, err := GetIdentity(db, ByID(identityID))
 	if err != nil {
 		return nil, err
 	}
 
-	identityGroups, err := ListGroups(db, nil, ByGroupMember(identityID))
+	identityGroups, err := ListGroups(db, nil, ByInheritedGroupMember(identityID))
 	if err != nil {
 		return nil, err
 	}
@@ -78,9 +126,9 @@ func CreateIdentityToken(db GormTxn, identityID uid.ID) (token *models.Token, er
 		groups = append(groups, g.Name)
 	}
 
-	expires := time.Now().Add(time.Minute * 5).UTC()
+	expires := time.Now().Add(identityTokenLifetime).UTC()
 
-	jwt, err := createJWT(db, identity, groups, expires)
+	jwt, err := createJWT(db, identity, groups, audience, issuer, expires)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,153 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func CreateWebhook(db GormTxn, webhook *models.Webhook) error {
+	return add(db, webhook)
+}
+
+func GetWebhook(db GormTxn, selectors ...SelectorFunc) (*models.Webhook, error) {
+	return get[models.Webhook](db, selectors...)
+}
+
+func ListWebhooks(db GormTxn, p *models.Pagination, selectors ...SelectorFunc) ([]models.Webhook, error) {
+	return list[models.Webhook](db, p, selectors...)
+}
+
+// DeleteWebhooks deletes the webhooks, and any of their deliveries that have
+// not been sent yet.
+func DeleteWebhooks(db GormTxn, selectors ...SelectorFunc) error {
+	toDelete, err := ListWebhooks(db, nil, selectors...)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]uid.ID, 0, len(toDelete))
+	for _, webhook := range toDelete {
+		ids = append(ids, webhook.ID)
+	}
+
+	if err := deleteAll[models.WebhookDelivery](db, ByWebhookIDs(ids)); err != nil {
+		return fmt.Errorf("delete deliveries: %w", err)
+	}
+
+	return deleteAll[models.Webhook](db, ByIDs(ids))
+}
+
+// CreateWebhookEvent queues a delivery of the event to every webhook in the
+// organization that is subscribed to the event type. The deliveries are
+// created in the same transaction as the change that caused the event, so
+// events are only sent for changes that were committed.
+func CreateWebhookEvent(db GormTxn, eventType string, data any) error {
+	webhooks, err := ListWebhooks(db, nil)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	for _, webhook := range webhooks {
+		if !webhook.EventTypes.Includes(eventType) {
+			continue
+		}
+
+		if payload == nil {
+			payload, err = json.Marshal(api.WebhookEvent{
+				ID:      uid.New(),
+				Type:    eventType,
+				Created: api.Time(time.Now()),
+				Data:    data,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		delivery := &models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventType:     eventType,
+			Payload:       payload,
+			State:         models.WebhookDeliveryStatePending,
+			NextAttemptAt: time.Now().UTC(),
+		}
+		if err := add(db, delivery); err != nil {
+			return fmt.Errorf("create webhook delivery: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListPendingWebhookDeliveries returns up to limit deliveries from all
+// organizations that are due to be sent at now.
+func ListPendingWebhookDeliveries(tx GormTxn, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := tx.GormDB().
+		Where("state = ? AND next_attempt_at <= ?", models.WebhookDeliveryStatePending, now.UTC()).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimWebhookDelivery claims a pending delivery for an attempt to send it.
+// The attempts of the delivery are incremented, and the next attempt is moved
+// to now plus lease, so that the delivery is not listed as pending by another
+// server while it is being sent. If the server exits before it saves the
+// result of the attempt, the delivery is attempted again after the lease.
+//
+// The claim only succeeds if the delivery has not been attempted since it was
+// listed. ClaimWebhookDelivery returns false if another server claimed it first.
+func ClaimWebhookDelivery(tx GormTxn, delivery *models.WebhookDelivery, now time.Time, lease time.Duration) (bool, error) {
+	nextAttemptAt := now.Add(lease).UTC()
+	result, err := tx.Exec(`
+UPDATE webhook_deliveries SET attempts = attempts + 1, next_attempt_at = ?, updated_at = ?
+WHERE id = ? AND organization_id = ? AND state = ? AND attempts = ? AND deleted_at IS NULL`,
+		nextAttemptAt, now.UTC(), delivery.ID, tx.OrganizationID(),
+		models.WebhookDeliveryStatePending, delivery.Attempts)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if count == 0 {
+		return false, nil
+	}
+
+	delivery.Attempts++
+	delivery.NextAttemptAt = nextAttemptAt
+	return true, nil
+}
+
+func ByWebhookIDs(ids []uid.ID) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("webhook_id IN (?)", ids)
+	}
+}
+
+func SaveWebhookDelivery(db GormTxn, delivery *models.WebhookDelivery) error {
+	return save(db, delivery)
+}
+
+func ListWebhookDeliveries(db GormTxn, p *models.Pagination, selectors ...SelectorFunc) ([]models.WebhookDelivery, error) {
+	return list[models.WebhookDelivery](db, p, selectors...)
+}
+
+// DeleteOldWebhookDeliveries removes the deliveries from all organizations
+// that were sent, or failed, before the time.
+func DeleteOldWebhookDeliveries(tx GormTxn, before time.Time) (int64, error) {
+	result := tx.GormDB().
+		Where("state != ? AND updated_at < ?", models.WebhookDeliveryStatePending, before.UTC()).
+		Delete(&models.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
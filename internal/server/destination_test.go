@@ -12,6 +12,9 @@ import (
 	"gotest.tools/v3/assert"
 
 	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
 )
 
 func TestAPI_CreateDestination(t *testing.T) {
@@ -112,3 +115,161 @@ var cmpAPIDestinationJSON = gocmp.Options{
 	gocmp.FilterPath(pathMapKey(`created`, `updated`), cmpApproximateTime),
 	gocmp.FilterPath(pathMapKey(`id`), cmpAnyValidUID),
 }
+
+func TestAPI_ListDestinationEvents(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	destination := &models.Destination{Name: "prod", UniqueID: "prod-unique-id"}
+	assert.NilError(t, data.CreateDestination(srv.DB(), destination))
+
+	listEvents := func(t *testing.T, query string) (*httptest.ResponseRecorder, api.ListDestinationEventsResponse) {
+		t.Helper()
+		path := fmt.Sprintf("/api/destinations/%s/events?%s", destination.ID, query)
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+
+		var body api.ListDestinationEventsResponse
+		if resp.Code == http.StatusOK {
+			assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		}
+		return resp, body
+	}
+
+	resp, first := listEvents(t, "")
+	assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+	assert.Assert(t, first.Resync)
+	assert.Equal(t, len(first.Items), 0)
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		resp, _ := listEvents(t, "cursor=abc")
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("no events", func(t *testing.T) {
+		resp, body := listEvents(t, "cursor="+first.Cursor)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Assert(t, !body.Resync)
+		assert.Equal(t, len(body.Items), 0)
+		assert.Equal(t, body.Cursor, first.Cursor)
+	})
+
+	var cursor string
+	t.Run("events of the destination", func(t *testing.T) {
+		prodGrant := &models.Grant{Subject: "i:1234567", Privilege: "view", Resource: "prod.default"}
+		assert.NilError(t, data.CreateGrant(srv.DB(), prodGrant))
+		stagingGrant := &models.Grant{Subject: "i:1234567", Privilege: "view", Resource: "staging"}
+		assert.NilError(t, data.CreateGrant(srv.DB(), stagingGrant))
+		assert.NilError(t, data.CreateRole(srv.DB(), &models.Role{Name: "logs"}))
+
+		resp, body := listEvents(t, "cursor="+first.Cursor)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Assert(t, !body.Resync)
+		assert.Equal(t, len(body.Items), 2)
+		assert.Equal(t, body.Items[0].Type, api.DestinationEventGrantCreated)
+		assert.Equal(t, body.Items[0].Grant.ID, prodGrant.ID)
+		assert.Equal(t, body.Items[1].Type, api.DestinationEventRoleUpdated)
+		assert.Equal(t, body.Items[1].Role.Name, "logs")
+		assert.Assert(t, body.Cursor != first.Cursor)
+		cursor = body.Cursor
+	})
+
+	t.Run("waits for an event", func(t *testing.T) {
+		grant := &models.Grant{Subject: "i:1234567", Privilege: "edit", Resource: "prod"}
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			assert.Check(t, data.CreateGrant(srv.DB(), grant))
+		}()
+
+		resp, body := listEvents(t, "wait=10s&cursor="+cursor)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Equal(t, len(body.Items), 1)
+		assert.Equal(t, body.Items[0].Grant.Privilege, "edit")
+	})
+
+	t.Run("resync after events are deleted", func(t *testing.T) {
+		_, err := data.DeleteOldDestinationEvents(srv.DB(), time.Now().Add(time.Minute))
+		assert.NilError(t, err)
+
+		var count int64
+		err = srv.DB().GormDB().Unscoped().Model(&models.DestinationEvent{}).Count(&count).Error
+		assert.NilError(t, err)
+		assert.Equal(t, count, int64(0))
+
+		resp, body := listEvents(t, "cursor="+first.Cursor)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Assert(t, body.Resync)
+		assert.Equal(t, len(body.Items), 0)
+	})
+}
+
+func TestAPI_ListDestinationGrants(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+	db := srv.DB()
+
+	destination := &models.Destination{Name: "prod", UniqueID: "prod-unique-id"}
+	assert.NilError(t, data.CreateDestination(db, destination))
+
+	user := &models.Identity{Name: "alice@example.com"}
+	assert.NilError(t, data.CreateIdentity(db, user))
+	group := &models.Group{Name: "developers"}
+	assert.NilError(t, data.CreateGroup(db, group))
+
+	userGrant := &models.Grant{Subject: uid.NewIdentityPolymorphicID(user.ID), Privilege: "view", Resource: "prod.default"}
+	groupGrant := &models.Grant{Subject: uid.NewGroupPolymorphicID(group.ID), Privilege: "edit", Resource: "prod*"}
+	for _, grant := range []*models.Grant{
+		userGrant,
+		groupGrant,
+		{Subject: uid.NewIdentityPolymorphicID(user.ID), Privilege: "view", Resource: "staging"},
+		{Subject: uid.NewIdentityPolymorphicID(123456), Privilege: "view", Resource: "prod"},
+	} {
+		assert.NilError(t, data.CreateGrant(db, grant))
+	}
+
+	listGrants := func(t *testing.T, etag string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/destinations/%s/grants", destination.ID), nil)
+		req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
+		req.Header.Set("Infra-Version", apiVersionLatest)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := listGrants(t, "")
+	assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+	etag := resp.Header().Get("ETag")
+	assert.Assert(t, etag != "")
+
+	var body api.ListDestinationGrantsResponse
+	assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	expected := []api.DestinationGrant{
+		{Grant: *userGrant.ToAPI(), SubjectKind: "user", SubjectName: "alice@example.com"},
+		{Grant: *groupGrant.ToAPI(), SubjectKind: "group", SubjectName: "developers"},
+	}
+	assert.DeepEqual(t, body.Items, expected, cmpAPIGrantShallow)
+
+	t.Run("not modified", func(t *testing.T) {
+		resp := listGrants(t, etag)
+		assert.Equal(t, resp.Code, http.StatusNotModified, resp.Body.String())
+		assert.Equal(t, resp.Body.Len(), 0)
+		assert.Equal(t, resp.Header().Get("ETag"), etag)
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		assert.NilError(t, data.DeleteGrants(db, data.ByID(userGrant.ID)))
+
+		resp := listGrants(t, etag)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Assert(t, resp.Header().Get("ETag") != etag)
+	})
+}
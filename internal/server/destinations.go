@@ -1,11 +1,18 @@
 package server
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/access"
 	"github.com/infrahq/infra/internal/server/models"
 )
@@ -76,3 +83,130 @@ func (a *API) UpdateDestination(c *gin.Context, r *api.UpdateDestinationRequest)
 func (a *API) DeleteDestination(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
 	return nil, access.DeleteDestination(c, r.ID)
 }
+
+const (
+	destinationEventsLimit        = 100
+	destinationEventsMaxWait      = 30 * time.Second
+	destinationEventsPollInterval = time.Second
+)
+
+// ListDestinationEvents returns the changes to grants, roles, users, and
+// groups that apply to a destination. When there are no events after the
+// cursor the request waits for new events, so that connectors can apply
+// changes within seconds without listing every grant.
+//
+// This handler is registered with longPollMiddleware, so each query runs
+// outside of a transaction, and the database is free for other requests
+// while it waits.
+func (a *API) ListDestinationEvents(c *gin.Context, r *api.ListDestinationEventsRequest) (*api.ListDestinationEventsResponse, error) {
+	destination, err := access.GetDestination(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := int64(-1)
+	if r.Cursor != "" {
+		cursor, err = strconv.ParseInt(r.Cursor, 10, 64)
+		if err != nil || cursor < 0 {
+			return nil, fmt.Errorf("%w: invalid cursor %q", internal.ErrBadRequest, r.Cursor)
+		}
+	}
+
+	wait := time.Duration(r.Wait)
+	if wait > destinationEventsMaxWait {
+		wait = destinationEventsMaxWait
+	}
+	deadline := time.Now().Add(wait)
+
+	for {
+		events, next, resync, err := access.ListDestinationEvents(c, destination, cursor, destinationEventsLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		remaining := time.Until(deadline)
+		if len(events) > 0 || resync || remaining <= 0 {
+			resp := &api.ListDestinationEventsResponse{
+				Items:  make([]api.DestinationEvent, 0, len(events)),
+				Cursor: strconv.FormatInt(next, 10),
+				Resync: resync,
+			}
+			for _, event := range events {
+				var item api.DestinationEvent
+				if err := json.Unmarshal(event.Payload, &item); err != nil {
+					return nil, fmt.Errorf("destination event %d: %w", event.Sequence, err)
+				}
+				resp.Items = append(resp.Items, item)
+			}
+			return resp, nil
+		}
+
+		// skip the events of other destinations while waiting
+		cursor = next
+		if remaining > destinationEventsPollInterval {
+			remaining = destinationEventsPollInterval
+		}
+		select {
+		case <-c.Request.Context().Done():
+			return nil, c.Request.Context().Err()
+		case <-time.After(remaining):
+		}
+	}
+}
+
+// ListDestinationGrants returns every grant that applies to a destination,
+// with the name of its user or group, so that a connector can update the
+// destination with a single request. The response has an ETag, and a request
+// with a matching If-None-Match header gets a 304 Not Modified response.
+func (a *API) ListDestinationGrants(c *gin.Context, r *api.ListDestinationGrantsRequest) (*api.ListDestinationGrantsResponse, error) {
+	destination, err := access.GetDestination(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	grants, names, err := access.ListDestinationGrants(c, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &api.ListDestinationGrantsResponse{
+		Items: make([]api.DestinationGrant, 0, len(grants)),
+	}
+	for _, grant := range grants {
+		kind := api.DestinationGrantSubjectUser
+		if grant.Subject.IsGroup() {
+			kind = api.DestinationGrantSubjectGroup
+		}
+		resp.Items = append(resp.Items, api.DestinationGrant{
+			Grant:       *grant.ToAPI(),
+			SubjectKind: kind,
+			SubjectName: names[grant.Subject],
+		})
+	}
+
+	body, err := json.Marshal(resp.Items)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	resp.ETag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", resp.ETag)
+
+	if etagMatches(c.GetHeader("If-None-Match"), resp.ETag) {
+		resp.Items = nil
+		resp.NotModified = true
+	}
+	return resp, nil
+}
+
+// etagMatches returns true if the value of an If-None-Match header includes
+// etag.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, value := range strings.Split(ifNoneMatch, ",") {
+		value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+		if value == etag || value == "*" {
+			return true
+		}
+	}
+	return false
+}
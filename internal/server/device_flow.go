@@ -0,0 +1,114 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/authn"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+const (
+	// deviceFlowTTL is how long the user has to approve a device flow request.
+	deviceFlowTTL = 10 * time.Minute
+	// deviceFlowPollInterval is how often the device should poll for an
+	// access key, in seconds.
+	deviceFlowPollInterval = 5
+)
+
+func (a *API) StartDeviceFlow(c *gin.Context, _ *api.EmptyRequest) (*api.StartDeviceFlowResponse, error) {
+	serverURL, err := a.serverURL(c)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := access.StartDeviceFlow(c, deviceFlowTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.StartDeviceFlowResponse{
+		DeviceCode:      req.DeviceCode,
+		UserCode:        req.DisplayUserCode(),
+		VerificationURI: serverURL + "/device",
+		ExpiresIn:       int64(deviceFlowTTL.Seconds()),
+		Interval:        deviceFlowPollInterval,
+	}, nil
+}
+
+func (a *API) PollDeviceFlow(c *gin.Context, r *api.PollDeviceFlowRequest) (*api.PollDeviceFlowResponse, error) {
+	loginMethod := authn.NewDeviceFlowAuthentication(r.DeviceCode, deviceFlowPollInterval*time.Second)
+
+	expires := time.Now().UTC().Add(a.server.options.SessionDuration)
+	key, bearer, _, err := access.Login(c, loginMethod, expires, a.server.options.SessionExtensionDeadline)
+	switch {
+	case errors.Is(err, authn.ErrDeviceFlowPending):
+		return &api.PollDeviceFlowResponse{Status: api.DeviceFlowStatusPending, DeviceCode: r.DeviceCode}, nil
+	case errors.Is(err, authn.ErrDeviceFlowSlowDown):
+		return &api.PollDeviceFlowResponse{Status: api.DeviceFlowStatusSlowDown, DeviceCode: r.DeviceCode}, nil
+	case errors.Is(err, internal.ErrExpired):
+		return nil, err
+	case err != nil:
+		return nil, fmt.Errorf("%w: login failed: %v", internal.ErrUnauthorized, err)
+	}
+
+	a.t.Event("login", key.IssuedFor.String(), Properties{"method": loginMethod.Name()})
+
+	return &api.PollDeviceFlowResponse{
+		Status:     api.DeviceFlowStatusConfirmed,
+		DeviceCode: r.DeviceCode,
+		Login: &api.LoginResponse{
+			UserID:    key.IssuedFor,
+			Name:      key.IssuedForIdentity.Name,
+			AccessKey: bearer,
+			Expires:   api.Time(key.ExpiresAt),
+		},
+	}, nil
+}
+
+func (a *API) ApproveDeviceFlow(c *gin.Context, r *api.ApproveDeviceFlowRequest) (*api.EmptyResponse, error) {
+	if _, err := access.ApproveDeviceFlow(c, r.UserCode); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (a *API) ListDeviceFlowAuthRequests(c *gin.Context, r *api.ListDeviceFlowAuthRequestsRequest) (*api.ListResponse[api.DeviceFlowAuthRequest], error) {
+	p := models.RequestToPagination(r.PaginationRequest)
+	reqs, err := access.ListDeviceFlowAuthRequests(c, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(reqs, models.PaginationToResponse(p), func(req models.DeviceFlowAuthRequest) api.DeviceFlowAuthRequest {
+		return *req.ToAPI()
+	})
+
+	return result, nil
+}
+
+func (a *API) DeleteDeviceFlowAuthRequest(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
+	return nil, access.DeleteDeviceFlowAuthRequest(c, r.ID)
+}
+
+// deleteExpiredDeviceFlowAuthRequests removes device flow requests which have
+// expired. Expired requests can not be approved or exchanged for an access
+// key, so this only keeps the table from growing.
+func (s *Server) deleteExpiredDeviceFlowAuthRequests() {
+	count, err := data.DeleteExpiredDeviceFlowAuthRequests(s.db, time.Now())
+	if err != nil {
+		logging.L.Warn().Err(err).Msg("failed to delete expired device flow requests")
+		return
+	}
+	if count > 0 {
+		logging.Debugf("deleted %d expired device flow requests", count)
+	}
+}
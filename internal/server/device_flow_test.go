@@ -0,0 +1,178 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+)
+
+func TestAPI_DeviceFlow(t *testing.T) {
+	srv := setupServer(t, withAdminUser, func(t *testing.T, opts *Options) {
+		assert.NilError(t, opts.ServerURL.Set("https://infra.example.com"))
+	})
+	routes := srv.GenerateRoutes()
+
+	adminKey := adminAccessKey(srv)
+	userKey, user := createAccessKey(t, srv.DB(), "someone@example.com")
+
+	start := func(t *testing.T) api.StartDeviceFlowResponse {
+		t.Helper()
+		resp := doRequest(t, routes, http.MethodPost, "/api/device", "", nil)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var started api.StartDeviceFlowResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &started))
+		return started
+	}
+
+	poll := func(t *testing.T, deviceCode string) *httptest.ResponseRecorder {
+		t.Helper()
+		return doRequest(t, routes, http.MethodPost, "/api/device/token", "", api.PollDeviceFlowRequest{DeviceCode: deviceCode})
+	}
+
+	// waitInterval moves the last poll of the device back by the interval, so
+	// that the next poll is not too soon.
+	waitInterval := func(t *testing.T, deviceCode string) {
+		t.Helper()
+		req, err := data.GetDeviceFlowAuthRequest(srv.DB(), data.ByDeviceCode(deviceCode))
+		assert.NilError(t, err)
+		req.LastPolledAt = req.LastPolledAt.Add(-deviceFlowPollInterval * time.Second)
+		assert.NilError(t, data.SaveDeviceFlowAuthRequest(srv.DB(), req))
+	}
+
+	t.Run("approve and exchange", func(t *testing.T) {
+		started := start(t)
+		assert.Equal(t, started.VerificationURI, "https://infra.example.com/device")
+		assert.Equal(t, len(started.UserCode), 9)
+		assert.Equal(t, started.Interval, int64(deviceFlowPollInterval))
+
+		resp := poll(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		var polled api.PollDeviceFlowResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &polled))
+		assert.Equal(t, polled.Status, api.DeviceFlowStatusPending)
+		assert.Assert(t, polled.Login == nil)
+
+		// users may type the code in lower case, without the separator
+		userCode := strings.ToLower(strings.ReplaceAll(started.UserCode, "-", ""))
+		resp = doRequest(t, routes, http.MethodPost, "/api/device/approve", userKey, api.ApproveDeviceFlowRequest{UserCode: userCode})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		resp = doRequest(t, routes, http.MethodPost, "/api/device/approve", userKey, api.ApproveDeviceFlowRequest{UserCode: started.UserCode})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		waitInterval(t, started.DeviceCode)
+		resp = poll(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		polled = api.PollDeviceFlowResponse{}
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &polled))
+		assert.Equal(t, polled.Status, api.DeviceFlowStatusConfirmed)
+		assert.Equal(t, polled.Login.UserID, user.ID)
+		assert.Equal(t, polled.Login.Name, user.Name)
+
+		// the access key belongs to the user that approved the request
+		resp = doRequest(t, routes, http.MethodGet, fmt.Sprintf("/api/users/%s", user.ID), polled.Login.AccessKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		// the device code can only be used once
+		resp = poll(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("poll too often", func(t *testing.T) {
+		started := start(t)
+
+		resp := poll(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		var polled api.PollDeviceFlowResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &polled))
+		assert.Equal(t, polled.Status, api.DeviceFlowStatusPending)
+
+		resp = poll(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &polled))
+		assert.Equal(t, polled.Status, api.DeviceFlowStatusSlowDown)
+
+		waitInterval(t, started.DeviceCode)
+		resp = poll(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &polled))
+		assert.Equal(t, polled.Status, api.DeviceFlowStatusPending)
+
+		req, err := data.GetDeviceFlowAuthRequest(srv.DB(), data.ByDeviceCode(started.DeviceCode))
+		assert.NilError(t, err)
+		assert.NilError(t, data.DeleteDeviceFlowAuthRequest(srv.DB(), req))
+	})
+
+	t.Run("approve with unknown code", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/device/approve", userKey, api.ApproveDeviceFlowRequest{UserCode: "BCDF-GHJK"})
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		started := start(t)
+
+		req, err := data.GetDeviceFlowAuthRequest(srv.DB(), data.ByDeviceCode(started.DeviceCode))
+		assert.NilError(t, err)
+		req.ExpiresAt = time.Now().Add(-time.Minute)
+		assert.NilError(t, data.SaveDeviceFlowAuthRequest(srv.DB(), req))
+
+		resp := poll(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusGone, resp.Body.String())
+
+		resp = doRequest(t, routes, http.MethodPost, "/api/device/approve", userKey, api.ApproveDeviceFlowRequest{UserCode: started.UserCode})
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+
+		count, err := data.DeleteExpiredDeviceFlowAuthRequests(srv.DB(), time.Now())
+		assert.NilError(t, err)
+		assert.Equal(t, count, int64(1))
+	})
+
+	t.Run("list and delete", func(t *testing.T) {
+		started := start(t)
+
+		resp := doRequest(t, routes, http.MethodGet, "/api/device", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = doRequest(t, routes, http.MethodGet, "/api/device", adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var reqs api.ListResponse[api.DeviceFlowAuthRequest]
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &reqs))
+		assert.Equal(t, reqs.Count, 1)
+		assert.Equal(t, reqs.Items[0].UserCode, started.UserCode)
+
+		path := fmt.Sprintf("/api/device/%s", reqs.Items[0].ID)
+		resp = doRequest(t, routes, http.MethodDelete, path, userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = doRequest(t, routes, http.MethodDelete, path, adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		resp = poll(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("verification URI ignores the host header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/device", nil)
+		req.Host = "attacker.example.com"
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var started api.StartDeviceFlowResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &started))
+		assert.Equal(t, started.VerificationURI, "https://infra.example.com/device")
+	})
+}
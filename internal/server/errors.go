@@ -89,6 +89,10 @@ func sendAPIError(c *gin.Context, err error) {
 		resp.Code = http.StatusBadRequest
 		resp.Message = err.Error()
 
+	case errors.Is(err, internal.ErrTooManyRequests):
+		resp.Code = http.StatusTooManyRequests
+		resp.Message = internal.ErrTooManyRequests.Error()
+
 	case errors.Is(err, internal.ErrNotImplemented):
 		resp.Code = http.StatusNotImplemented
 		resp.Message = internal.ErrNotImplemented.Error()
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/validate"
+)
+
+// defaultFederationTTL is how long an access key issued by logging in with a
+// federation is valid, when the federation does not set a TTL.
+const defaultFederationTTL = time.Hour
+
+func (a *API) ListFederations(c *gin.Context, r *api.ListFederationsRequest) (*api.ListResponse[api.Federation], error) {
+	p := models.RequestToPagination(r.PaginationRequest)
+	federations, err := access.ListFederations(c, r.Name, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(federations, models.PaginationToResponse(p), func(federation models.Federation) api.Federation {
+		return *federation.ToAPI()
+	})
+
+	return result, nil
+}
+
+func (a *API) GetFederation(c *gin.Context, r *api.Resource) (*api.Federation, error) {
+	federation, err := access.GetFederation(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return federation.ToAPI(), nil
+}
+
+func (a *API) CreateFederation(c *gin.Context, r *api.CreateFederationRequest) (*api.Federation, error) {
+	federation := &models.Federation{Name: r.Name}
+	err := updateFederationFromRequest(federation, api.UpdateFederationRequest{
+		Issuer:     r.Issuer,
+		JWKSURL:    r.JWKSURL,
+		Audience:   r.Audience,
+		Conditions: r.Conditions,
+		User:       r.User,
+		TTL:        r.TTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := access.CreateFederation(c, federation); err != nil {
+		return nil, err
+	}
+
+	return federation.ToAPI(), nil
+}
+
+func (a *API) UpdateFederation(c *gin.Context, r *api.UpdateFederationRequest) (*api.Federation, error) {
+	federation, err := access.GetFederation(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := updateFederationFromRequest(federation, *r); err != nil {
+		return nil, err
+	}
+
+	if err := access.UpdateFederation(c, federation); err != nil {
+		return nil, err
+	}
+
+	return federation.ToAPI(), nil
+}
+
+func (a *API) DeleteFederation(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
+	return nil, access.DeleteFederation(c, r.ID)
+}
+
+func updateFederationFromRequest(federation *models.Federation, r api.UpdateFederationRequest) error {
+	if !isHTTPURL(r.Issuer) {
+		return validate.Error{"issuer": {"must be an http or https URL"}}
+	}
+	if r.JWKSURL != "" && !isHTTPURL(r.JWKSURL) {
+		return validate.Error{"jwksURL": {"must be an http or https URL"}}
+	}
+
+	if !models.FederationConditions(r.Conditions).RestrictsSubject() {
+		return validate.Error{"conditions": {"must include a sub or repository condition with a literal owner"}}
+	}
+
+	ttl := r.TTL
+	if ttl < 0 {
+		return validate.Error{"ttl": {"must be a positive duration"}}
+	}
+	if ttl == 0 {
+		ttl = api.Duration(defaultFederationTTL)
+	}
+
+	federation.Issuer = r.Issuer
+	federation.JWKSURL = r.JWKSURL
+	federation.Audience = r.Audience
+	federation.Conditions = r.Conditions
+	federation.IdentityID = r.User
+	federation.TTL = time.Duration(ttl)
+	return nil
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
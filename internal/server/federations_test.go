@@ -0,0 +1,188 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+)
+
+func TestAPI_Federations(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	adminKey := adminAccessKey(srv)
+	userKey, _ := createAccessKey(t, srv.DB(), "someone@example.com")
+	_, deployUser := createAccessKey(t, srv.DB(), "deploy@example.com")
+
+	// a local issuer, like the OIDC token endpoint of a CI platform
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NilError(t, err)
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: key.Public(), KeyID: "ci", Algorithm: string(jose.RS256), Use: "sig"},
+		}})
+	}))
+	t.Cleanup(jwks.Close)
+	issuerURL := "https://ci.example.com"
+
+	signToken := func(t *testing.T, claims map[string]any) string {
+		t.Helper()
+		signer, err := jose.NewSigner(
+			jose.SigningKey{Algorithm: jose.RS256, Key: key},
+			(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "ci"))
+		assert.NilError(t, err)
+
+		raw, err := jwt.Signed(signer).Claims(jwt.Claims{
+			Issuer:   issuerURL,
+			Audience: jwt.Audience{"https://infra.example.com"},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
+		}).Claims(claims).CompactSerialize()
+		assert.NilError(t, err)
+		return raw
+	}
+
+	var created api.Federation
+
+	t.Run("create", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/federations", adminKey, api.CreateFederationRequest{
+			Name:       "deploy",
+			Issuer:     issuerURL,
+			JWKSURL:    jwks.URL,
+			Audience:   "https://infra.example.com",
+			Conditions: map[string]string{"repository": "example/app", "ref": "refs/heads/main"},
+			User:       deployUser.ID,
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+		assert.Equal(t, created.Name, "deploy")
+		assert.Equal(t, created.User, deployUser.ID)
+		assert.Equal(t, created.TTL, api.Duration(time.Hour))
+	})
+
+	t.Run("create requires admin", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/federations", userKey, api.CreateFederationRequest{
+			Name:       "other",
+			Issuer:     issuerURL,
+			Audience:   "https://infra.example.com",
+			Conditions: map[string]string{"repository": "example/app"},
+			User:       deployUser.ID,
+		})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("create with invalid issuer", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/federations", adminKey, api.CreateFederationRequest{
+			Name:       "other",
+			Issuer:     "ci.example.com",
+			Audience:   "https://infra.example.com",
+			Conditions: map[string]string{"repository": "example/app"},
+			User:       deployUser.ID,
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("create without a subject condition", func(t *testing.T) {
+		for _, conditions := range []map[string]string{
+			nil,
+			{"ref": "refs/heads/main"},
+			{"repository": "*", "ref": "refs/heads/main"},
+			{"repository": "*/*"},
+			{"repository": "exam?le/app"},
+			{"sub": "repo:*/app:ref:refs/heads/main"},
+		} {
+			resp := doRequest(t, routes, http.MethodPost, "/api/federations", adminKey, api.CreateFederationRequest{
+				Name:       "other",
+				Issuer:     issuerURL,
+				Audience:   "https://infra.example.com",
+				Conditions: conditions,
+				User:       deployUser.ID,
+			})
+			assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+		}
+	})
+
+	t.Run("create with unknown user", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/federations", adminKey, api.CreateFederationRequest{
+			Name:       "other",
+			Issuer:     issuerURL,
+			Audience:   "https://infra.example.com",
+			Conditions: map[string]string{"repository": "example/app"},
+			User:       1234,
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("login", func(t *testing.T) {
+		token := signToken(t, map[string]any{"repository": "example/app", "ref": "refs/heads/main"})
+		resp := doRequest(t, routes, http.MethodPost, "/api/login", "", api.LoginRequest{
+			Federation: &api.LoginRequestFederation{Token: token},
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var login api.LoginResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &login))
+		assert.Equal(t, login.UserID, deployUser.ID)
+		assert.Assert(t, time.Time(login.Expires).Before(time.Now().Add(61*time.Minute)))
+
+		resp = doRequest(t, routes, http.MethodGet, fmt.Sprintf("/api/users/%s", deployUser.ID), login.AccessKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+	})
+
+	t.Run("login with token that does not match", func(t *testing.T) {
+		token := signToken(t, map[string]any{"repository": "example/app", "ref": "refs/heads/feature"})
+		resp := doRequest(t, routes, http.MethodPost, "/api/login", "", api.LoginRequest{
+			Federation: &api.LoginRequestFederation{Token: token},
+		})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("update", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPut, fmt.Sprintf("/api/federations/%s", created.ID), adminKey, api.UpdateFederationRequest{
+			Issuer:     issuerURL,
+			JWKSURL:    jwks.URL,
+			Audience:   "https://infra.example.com",
+			Conditions: map[string]string{"repository": "example/app", "ref": "refs/heads/*"},
+			User:       deployUser.ID,
+			TTL:        api.Duration(15 * time.Minute),
+		})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = doRequest(t, routes, http.MethodGet, "/api/federations?name=deploy", adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var federations api.ListResponse[api.Federation]
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &federations))
+		assert.Equal(t, federations.Count, 1)
+		assert.Equal(t, federations.Items[0].Conditions["ref"], "refs/heads/*")
+		assert.Equal(t, federations.Items[0].TTL, api.Duration(15*time.Minute))
+
+		token := signToken(t, map[string]any{"repository": "example/app", "ref": "refs/heads/feature"})
+		resp = doRequest(t, routes, http.MethodPost, "/api/login", "", api.LoginRequest{
+			Federation: &api.LoginRequestFederation{Token: token},
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodDelete, fmt.Sprintf("/api/federations/%s", created.ID), userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = doRequest(t, routes, http.MethodDelete, fmt.Sprintf("/api/federations/%s", created.ID), adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		resp = doRequest(t, routes, http.MethodGet, fmt.Sprintf("/api/federations/%s", created.ID), adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+	})
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/infrahq/infra/internal/access"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/validate"
 	"github.com/infrahq/infra/uid"
 )
 
@@ -24,7 +26,7 @@ func (a *API) ListGrants(c *gin.Context, r *api.ListGrantsRequest) (*api.ListRes
 		subject = uid.NewGroupPolymorphicID(r.Group)
 	}
 
-	grants, err := access.ListGrants(c, subject, r.Resource, r.Privilege, r.ShowInherited, r.ShowSystem, &p)
+	grants, err := access.ListGrants(c, subject, r.Resource, r.ResourcePrefix, r.Privilege, r.ShowInherited, r.ShowSystem, &p)
 	if err != nil {
 		return nil, err
 	}
@@ -55,17 +57,22 @@ func (a *API) CreateGrant(c *gin.Context, r *api.CreateGrantRequest) (*api.Creat
 		subject = uid.NewGroupPolymorphicID(r.Group)
 	}
 
+	if expires := r.Expires.Time(); !expires.IsZero() && !expires.After(time.Now()) {
+		return nil, validate.Error{"expires": {"must be in the future"}}
+	}
+
 	grant := &models.Grant{
 		Subject:   subject,
 		Resource:  r.Resource,
 		Privilege: r.Privilege,
+		ExpiresAt: r.Expires.Time(),
 	}
 
 	err := access.CreateGrant(c, grant)
 	var ucerr data.UniqueConstraintError
 
 	if errors.As(err, &ucerr) {
-		grants, err := access.ListGrants(c, grant.Subject, grant.Resource, grant.Privilege, false, false, nil)
+		grants, err := access.ListGrants(c, grant.Subject, grant.Resource, "", grant.Privilege, false, false, nil)
 
 		if err != nil {
 			return nil, err
@@ -93,7 +100,7 @@ func (a *API) DeleteGrant(c *gin.Context, r *api.Resource) (*api.EmptyResponse,
 	}
 
 	if grant.Resource == access.ResourceInfraAPI && grant.Privilege == models.InfraAdminRole {
-		infraAdminGrants, err := access.ListGrants(c, "", grant.Resource, grant.Privilege, false, false, nil)
+		infraAdminGrants, err := access.ListGrants(c, "", grant.Resource, "", grant.Privilege, false, false, nil)
 		if err != nil {
 			return nil, err
 		}
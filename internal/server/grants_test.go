@@ -394,7 +394,8 @@ func TestAPI_ListGrants(t *testing.T) {
 							"resource": "res1",
 							"user": "%[2]v",
 							"created": "%[3]v",
-							"updated": "%[3]v"
+							"updated": "%[3]v",
+							"expires": null
 						}]
 					}`,
 					admin.ID,
@@ -413,6 +414,74 @@ func TestAPI_ListGrants(t *testing.T) {
 	}
 }
 
+func TestAPI_ListGrants_ResourcePrefix(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	_, user := createAccessKey(t, srv.DB(), "someone@example.com")
+
+	for _, resource := range []string{
+		"production",
+		"production.default",
+		"production.*",
+		"production-eu",
+		"production-eu.default",
+		"*.monitoring",
+		"staging",
+		"prod_ction",
+	} {
+		err := data.CreateGrant(srv.DB(), &models.Grant{
+			Subject:   user.PolyID(),
+			Privilege: "view",
+			Resource:  resource,
+		})
+		assert.NilError(t, err)
+	}
+
+	listResources := func(t *testing.T, query string) []string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/grants?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var grants api.ListResponse[api.Grant]
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &grants))
+
+		resources := make([]string, 0, len(grants.Items))
+		for _, g := range grants.Items {
+			resources = append(resources, g.Resource)
+		}
+		sort.Strings(resources)
+		return resources
+	}
+
+	t.Run("resource and sub-resources, and wildcards", func(t *testing.T) {
+		actual := listResources(t, "resourcePrefix=production")
+		expected := []string{"*.monitoring", "production", "production.*", "production.default"}
+		assert.DeepEqual(t, actual, expected)
+	})
+
+	t.Run("like characters are matched literally", func(t *testing.T) {
+		actual := listResources(t, "resourcePrefix=prod_ction")
+		expected := []string{"*.monitoring", "prod_ction", "production.*"}
+		assert.DeepEqual(t, actual, expected)
+	})
+
+	t.Run("resource and resourcePrefix are exclusive", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/grants?resource=production&resourcePrefix=production", nil)
+		req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+}
+
 func TestAPI_ListGrants_InheritedGrants(t *testing.T) {
 	srv := setupServer(t, withAdminUser)
 	routes := srv.GenerateRoutes()
@@ -708,6 +777,7 @@ func TestAPI_CreateGrant(t *testing.T) {
 					"user": "%[3]v",
 					"created": "%[4]v",
 					"updated": "%[4]v",
+					"expires": null,
 					"wasCreated": true
 				}`,
 					accessKey.IssuedFor,
@@ -719,6 +789,51 @@ func TestAPI_CreateGrant(t *testing.T) {
 				assert.DeepEqual(t, actual, expected, cmpAPIGrantJSON)
 			},
 		},
+		"expires in the past": {
+			setup: func(t *testing.T, req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
+			},
+			body: api.CreateGrantRequest{
+				User:      someUser.ID,
+				Privilege: "view",
+				Resource:  "some-cluster",
+				Expires:   api.Time(time.Now().Add(-time.Minute)),
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+				respBody := &api.Error{}
+				err := json.Unmarshal(resp.Body.Bytes(), respBody)
+				assert.NilError(t, err)
+
+				expected := []api.FieldError{
+					{FieldName: "expires", Errors: []string{"must be in the future"}},
+				}
+				assert.DeepEqual(t, respBody.FieldErrors, expected)
+			},
+		},
+		"success with expiry": {
+			setup: func(t *testing.T, req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
+			},
+			body: api.CreateGrantRequest{
+				User:      someUser.ID,
+				Privilege: "view",
+				Resource:  "some-cluster",
+				Expires:   api.Time(time.Now().Add(time.Hour)),
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+				respBody := &api.CreateGrantResponse{}
+				err := json.Unmarshal(resp.Body.Bytes(), respBody)
+				assert.NilError(t, err)
+
+				expires := time.Time(respBody.Expires)
+				assert.Assert(t, expires.After(time.Now().Add(59*time.Minute)))
+				assert.Assert(t, expires.Before(time.Now().Add(time.Hour)))
+			},
+		},
 		"admin can not grant infra support admin role": {
 			setup: func(t *testing.T, req *http.Request) {
 				req.Header.Set("Authorization", "Bearer "+adminAccessKey(srv))
@@ -753,6 +868,7 @@ func TestAPI_CreateGrant(t *testing.T) {
 					"user": "%[3]v",
 					"created": "%[4]v",
 					"updated": "%[4]v",
+					"expires": null,
 					"wasCreated": true
 				}`,
 					supportAdmin.ID,
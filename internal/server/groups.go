@@ -56,3 +56,7 @@ func (a *API) DeleteGroup(c *gin.Context, r *api.Resource) (*api.EmptyResponse,
 func (a *API) UpdateUsersInGroup(c *gin.Context, r *api.UpdateUsersInGroupRequest) (*api.EmptyResponse, error) {
 	return nil, access.UpdateUsersInGroup(c, r.GroupID, r.UserIDsToAdd, r.UserIDsToRemove)
 }
+
+func (a *API) UpdateGroupsInGroup(c *gin.Context, r *api.UpdateGroupsInGroupRequest) (*api.EmptyResponse, error) {
+	return nil, access.UpdateGroupsInGroup(c, r.GroupID, r.GroupIDsToAdd, r.GroupIDsToRemove)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -475,3 +476,78 @@ func TestAPI_UpdateUsersInGroup(t *testing.T) {
 var cmpModelsIdentityShallow = cmp.Comparer(func(x, y models.Identity) bool {
 	return x.Name == y.Name
 })
+
+func TestAPI_UpdateGroupsInGroup(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	var (
+		platform = models.Group{Name: "platform"}
+		sre      = models.Group{Name: "sre"}
+	)
+	createGroups(t, srv.DB(), &platform, &sre)
+
+	userKey, user := createAccessKey(t, srv.DB(), "sre@example.com")
+	assert.NilError(t, data.AddUsersToGroup(srv.DB(), sre.ID, []uid.ID{user.ID}))
+	assert.NilError(t, data.CreateGrant(srv.DB(), &models.Grant{
+		Subject:   platform.PolyID(),
+		Privilege: models.InfraViewRole,
+		Resource:  "infra",
+	}))
+
+	platformPath := fmt.Sprintf("/api/groups/%s/groups", platform.ID)
+
+	t.Run("requires admin", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPatch, platformPath, userKey, api.UpdateGroupsInGroupRequest{
+			GroupIDsToAdd: []uid.ID{sre.ID},
+		})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("grants are not inherited before nesting", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodGet, "/api/users", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("add group", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPatch, platformPath, adminAccessKey(srv), api.UpdateGroupsInGroupRequest{
+			GroupIDsToAdd: []uid.ID{sre.ID},
+		})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = doRequest(t, routes, http.MethodGet, "/api/groups/"+sre.ID.String(), adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		var group api.Group
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &group))
+		assert.DeepEqual(t, group.Parents, []uid.ID{platform.ID})
+
+		// members of sre inherit the grants of platform
+		resp = doRequest(t, routes, http.MethodGet, "/api/users", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPatch, fmt.Sprintf("/api/groups/%s/groups", sre.ID), adminAccessKey(srv), api.UpdateGroupsInGroupRequest{
+			GroupIDsToAdd: []uid.ID{platform.ID},
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+		assert.Assert(t, strings.Contains(resp.Body.String(), "would create a cycle"))
+	})
+
+	t.Run("unknown group", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPatch, platformPath, adminAccessKey(srv), api.UpdateGroupsInGroupRequest{
+			GroupIDsToAdd: []uid.ID{1337},
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("remove group", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPatch, platformPath, adminAccessKey(srv), api.UpdateGroupsInGroupRequest{
+			GroupIDsToRemove: []uid.ID{sre.ID},
+		})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = doRequest(t, routes, http.MethodGet, "/api/users", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+}
@@ -27,7 +27,7 @@ type API struct {
 	openAPIDoc openapi3.T
 }
 
-func (a *API) CreateToken(c *gin.Context, r *api.EmptyRequest) (*api.CreateTokenResponse, error) {
+func (a *API) CreateToken(c *gin.Context, r *api.CreateTokenRequest) (*api.CreateTokenResponse, error) {
 	rCtx := getRequestContext(c)
 
 	if rCtx.Authenticated.User != nil {
@@ -37,7 +37,11 @@ func (a *API) CreateToken(c *gin.Context, r *api.EmptyRequest) (*api.CreateToken
 			return nil, fmt.Errorf("%w: failed to update identity info from provider: %s", internal.ErrUnauthorized, err)
 		}
 
-		token, err := access.CreateToken(rCtx)
+		// the issuer is optional, tokens are still created when the server
+		// URL is not configured.
+		issuer, _ := a.serverURL(c)
+
+		token, err := access.CreateToken(rCtx, r.Destination, issuer)
 		if err != nil {
 			return nil, err
 		}
@@ -156,7 +160,7 @@ func (a *API) Login(c *gin.Context, r *api.LoginRequest) (*api.LoginResponse, er
 	case r.AccessKey != "":
 		loginMethod = authn.NewKeyExchangeAuthentication(r.AccessKey)
 	case r.PasswordCredentials != nil:
-		loginMethod = authn.NewPasswordCredentialAuthentication(r.PasswordCredentials.Name, r.PasswordCredentials.Password)
+		loginMethod = authn.NewPasswordCredentialAuthentication(r.PasswordCredentials.Name, r.PasswordCredentials.Password, r.PasswordCredentials.MFACode)
 	case r.OIDC != nil:
 		provider, err := access.GetProvider(c, r.OIDC.ProviderID)
 		if err != nil {
@@ -169,6 +173,32 @@ func (a *API) Login(c *gin.Context, r *api.LoginRequest) (*api.LoginResponse, er
 		}
 
 		loginMethod = authn.NewOIDCAuthentication(r.OIDC.ProviderID, r.OIDC.RedirectURL, r.OIDC.Code, providerClient)
+	case r.Federation != nil:
+		loginMethod = authn.NewFederationAuthentication(r.Federation.Token)
+	case r.LDAP != nil:
+		provider, err := access.GetProvider(c, r.LDAP.ProviderID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity provider: %w", err)
+		}
+
+		ldapClient, err := a.ldapClient(c, provider)
+		if err != nil {
+			return nil, fmt.Errorf("update provider client: %w", err)
+		}
+
+		loginMethod = authn.NewLDAPAuthentication(r.LDAP.ProviderID, r.LDAP.Name, r.LDAP.Password, ldapClient)
+	case r.SAML != nil:
+		provider, err := access.GetProvider(c, r.SAML.ProviderID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity provider: %w", err)
+		}
+
+		sp, err := a.samlServiceProvider(c, provider)
+		if err != nil {
+			return nil, fmt.Errorf("saml service provider: %w", err)
+		}
+
+		loginMethod = authn.NewSAMLAuthentication(r.SAML.ProviderID, r.SAML.SAMLResponse, sp)
 	default:
 		// make sure to always fail by default
 		return nil, fmt.Errorf("%w: missing login credentials", internal.ErrBadRequest)
@@ -178,6 +208,10 @@ func (a *API) Login(c *gin.Context, r *api.LoginRequest) (*api.LoginResponse, er
 	expires := time.Now().UTC().Add(a.server.options.SessionDuration)
 	key, bearer, requiresUpdate, err := access.Login(c, loginMethod, expires, a.server.options.SessionExtensionDeadline)
 	if err != nil {
+		if errors.Is(err, authn.ErrMFACodeRequired) {
+			// the password was correct, ask the client to login again with a code
+			return &api.LoginResponse{MFARequired: true}, nil
+		}
 		if errors.Is(err, internal.ErrBadGateway) {
 			// the user should be shown this explicitly
 			// this means an external request failed, probably to an IDP
@@ -197,7 +231,14 @@ func (a *API) Login(c *gin.Context, r *api.LoginRequest) (*api.LoginResponse, er
 
 	a.t.Event("login", key.IssuedFor.String(), Properties{"method": loginMethod.Name()})
 
-	return &api.LoginResponse{UserID: key.IssuedFor, Name: key.IssuedForIdentity.Name, AccessKey: bearer, Expires: api.Time(key.ExpiresAt), PasswordUpdateRequired: requiresUpdate}, nil
+	return &api.LoginResponse{
+		UserID:                 key.IssuedFor,
+		Name:                   key.IssuedForIdentity.Name,
+		AccessKey:              bearer,
+		Expires:                api.Time(key.ExpiresAt),
+		PasswordUpdateRequired: requiresUpdate,
+		MFAEnrollmentRequired:  key.Scopes.Includes(models.ScopeMFAEnrollment),
+	}, nil
 }
 
 func (a *API) Logout(c *gin.Context, _ *api.EmptyRequest) (*api.EmptyResponse, error) {
@@ -229,6 +270,20 @@ func (a *API) UpdateIdentityInfoFromProvider(c *gin.Context) error {
 		return err
 	}
 
+	if provider.Kind == models.ProviderKindSAML {
+		// there is no way to check a saml user without the user logging in
+		// again, the session expires instead
+		return nil
+	}
+
+	if provider.Kind == models.ProviderKindLDAP {
+		ldap, err := a.ldapClient(rCtx.Request.Context(), provider)
+		if err != nil {
+			return fmt.Errorf("update provider client: %w", err)
+		}
+		return access.UpdateIdentityInfoFromLDAP(rCtx, ldap)
+	}
+
 	oidc, err := a.providerClient(rCtx.Request.Context(), provider, redirectURL)
 	if err != nil {
 		return fmt.Errorf("update provider client: %w", err)
@@ -251,3 +306,18 @@ func (a *API) providerClient(ctx context.Context, provider *models.Provider, red
 
 	return providers.NewOIDCClient(*provider, clientSecret, redirectURL), nil
 }
+
+func (a *API) ldapClient(ctx context.Context, provider *models.Provider) (providers.LDAPClient, error) {
+	if c := providers.LDAPClientFromContext(ctx); c != nil {
+		// ldap is added to the context during unit tests
+		return c, nil
+	}
+
+	bindPassword, err := secrets.GetSecret(string(provider.BindPassword), a.server.secrets)
+	if err != nil {
+		logging.Debugf("could not get bind password: %s", err)
+		return nil, fmt.Errorf("bind password not found")
+	}
+
+	return providers.NewLDAPClient(*provider, bindPassword), nil
+}
@@ -91,6 +91,27 @@ func jsonBody(t *testing.T, body interface{}) *bytes.Buffer {
 	return buf
 }
 
+// doRequest sends a request to routes, and returns the response. The body is
+// encoded as JSON when it is not nil, and the Authorization header is only set
+// when accessKey is not empty.
+func doRequest(t *testing.T, routes http.Handler, method, path, accessKey string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var req *http.Request
+	if body != nil {
+		req = httptest.NewRequest(method, path, jsonBody(t, body))
+	} else {
+		req = httptest.NewRequest(method, path, nil)
+	}
+	if accessKey != "" {
+		req.Header.Set("Authorization", "Bearer "+accessKey)
+	}
+	req.Header.Set("Infra-Version", apiVersionLatest)
+
+	resp := httptest.NewRecorder()
+	routes.ServeHTTP(resp, req)
+	return resp
+}
+
 // cmpApproximateTime is a gocmp.Option that compares a time formatted as an
 // RFC3339 string. The times may be up to 2 seconds different from each other,
 // to account for the runtime of a test.
@@ -12,6 +12,7 @@ import (
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
 	"github.com/infrahq/infra/uid"
 )
 
@@ -101,7 +102,7 @@ func TestAPI_Login(t *testing.T) {
 				assert.NilError(t, err)
 
 				expected := []api.FieldError{
-					{Errors: []string{"one of (accessKey, passwordCredentials, oidc) is required"}},
+					{Errors: []string{"one of (accessKey, passwordCredentials, oidc, federation, ldap, saml) is required"}},
 				}
 				assert.DeepEqual(t, respBody.FieldErrors, expected)
 			},
@@ -198,3 +199,64 @@ func TestAPI_Login(t *testing.T) {
 		})
 	}
 }
+
+func TestAPI_Login_LDAP(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	provider := &models.Provider{
+		Name:       "ad",
+		Kind:       models.ProviderKindLDAP,
+		URL:        "ldaps://ldap.example.com",
+		BindDN:     "cn=infra,dc=example,dc=com",
+		SearchBase: "dc=example,dc=com",
+	}
+	err := data.CreateProvider(srv.DB(), provider)
+	assert.NilError(t, err)
+
+	ldap := &fakeLDAPImplementation{
+		User: &providers.LDAPUser{
+			DN:     "uid=alice,dc=example,dc=com",
+			Email:  "alice@example.com",
+			Groups: []string{"developers"},
+		},
+	}
+
+	login := func(t *testing.T, password string) *httptest.ResponseRecorder {
+		body := jsonBody(t, api.LoginRequest{
+			LDAP: &api.LoginRequestLDAP{
+				ProviderID: provider.ID,
+				Name:       "alice",
+				Password:   password,
+			},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/login", body)
+		req.Header.Add("Infra-Version", apiVersionLatest)
+		req = req.WithContext(providers.WithLDAPClient(req.Context(), ldap))
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("wrong password", func(t *testing.T) {
+		resp := login(t, "wrong")
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		resp := login(t, "password")
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		loginResp := &api.LoginResponse{}
+		err := json.Unmarshal(resp.Body.Bytes(), loginResp)
+		assert.NilError(t, err)
+		assert.Assert(t, loginResp.AccessKey != "")
+		assert.Equal(t, loginResp.Name, "alice@example.com")
+
+		identity, err := data.GetIdentity(srv.DB(), data.Preload("Groups"), data.ByName("alice@example.com"))
+		assert.NilError(t, err)
+		assert.Equal(t, len(identity.Groups), 1)
+		assert.Equal(t, identity.Groups[0].Name, "developers")
+	})
+}
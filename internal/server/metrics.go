@@ -1,10 +1,13 @@
 package server
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 
 	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/authn"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/metrics"
@@ -124,5 +127,23 @@ func setupMetrics(db data.GormTxn) *prometheus.Registry {
 		}
 	}))
 
+	registry.MustRegister(metrics.NewCollector(prometheus.Opts{
+		Namespace: "infra",
+		Name:      "locked_users",
+		Help:      "The number of users locked out because of failed logins",
+	}, []string{}, func() []metrics.Metric {
+		count, err := data.GlobalCount[models.Credential](db, data.ByLockedOut(time.Now()))
+		if err != nil {
+			logging.L.Warn().Err(err).Msg("locked users")
+			return []metrics.Metric{}
+		}
+
+		return []metrics.Metric{
+			{Count: float64(count)},
+		}
+	}))
+
+	registry.MustRegister(authn.LockoutsCounter)
+
 	return registry
 }
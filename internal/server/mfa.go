@@ -0,0 +1,70 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/uid"
+)
+
+func userIDOrSelf(c *gin.Context, id api.IDOrSelf) (uid.ID, error) {
+	if !id.IsSelf {
+		return id.ID, nil
+	}
+	identity := access.AuthenticatedIdentity(c)
+	if identity == nil {
+		return 0, fmt.Errorf("%w: no user is logged in", internal.ErrUnauthorized)
+	}
+	return identity.ID, nil
+}
+
+func (a *API) EnrollMFA(c *gin.Context, r *api.EnrollMFARequest) (*api.EnrollMFAResponse, error) {
+	userID, err := userIDOrSelf(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, uri, err := access.EnrollMFA(c, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &api.EnrollMFAResponse{Secret: secret, URI: uri}, nil
+}
+
+func (a *API) VerifyMFA(c *gin.Context, r *api.VerifyMFARequest) (*api.MFARecoveryCodesResponse, error) {
+	userID, err := userIDOrSelf(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := access.VerifyMFA(c, userID, r.Code)
+	if err != nil {
+		return nil, err
+	}
+	return &api.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (a *API) RegenerateMFARecoveryCodes(c *gin.Context, r *api.RegenerateMFARecoveryCodesRequest) (*api.MFARecoveryCodesResponse, error) {
+	userID, err := userIDOrSelf(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := access.RegenerateMFARecoveryCodes(c, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &api.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (a *API) DisableMFA(c *gin.Context, r *api.DisableMFARequest) (*api.EmptyResponse, error) {
+	userID, err := userIDOrSelf(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	return nil, access.DisableMFA(c, userID)
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/authn"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func TestAPI_MFA(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+	adminKey := adminAccessKey(srv)
+
+	user := &models.Identity{Name: "mfa@example.com"}
+	assert.NilError(t, data.CreateIdentity(srv.DB(), user))
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	assert.NilError(t, err)
+	assert.NilError(t, data.CreateCredential(srv.DB(), &models.Credential{IdentityID: user.ID, PasswordHash: hash}))
+
+	login := func(t *testing.T, code string) api.LoginResponse {
+		t.Helper()
+		resp := doRequest(t, routes, http.MethodPost, "/api/login", "", api.LoginRequest{
+			PasswordCredentials: &api.LoginRequestPasswordCredentials{
+				Name:     "mfa@example.com",
+				Password: "password123",
+				MFACode:  code,
+			},
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var loginResp api.LoginResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &loginResp))
+		return loginResp
+	}
+
+	var secret string
+
+	t.Run("enrollment required by settings", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPut, "/api/settings", adminKey, api.Settings{
+			PasswordRequirements: api.PasswordRequirements{LengthMin: 8},
+			RequireMFA:           api.MFARequirementAll,
+		})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		loginResp := login(t, "")
+		assert.Assert(t, loginResp.MFAEnrollmentRequired)
+		userKey := loginResp.AccessKey
+
+		// the key can only be used to enroll
+		resp = doRequest(t, routes, http.MethodGet, "/api/users/self", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+
+		resp = doRequest(t, routes, http.MethodPost, "/api/users/self/mfa", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var enrollment api.EnrollMFAResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &enrollment))
+		assert.Assert(t, enrollment.Secret != "")
+		secret = enrollment.Secret
+
+		resp = doRequest(t, routes, http.MethodPost, "/api/users/self/mfa/verify", userKey, api.VerifyMFARequest{Code: "000000"})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		code, err := authn.TOTPCode(secret, time.Now())
+		assert.NilError(t, err)
+		resp = doRequest(t, routes, http.MethodPost, "/api/users/self/mfa/verify", userKey, api.VerifyMFARequest{Code: code})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var codes api.MFARecoveryCodesResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &codes))
+		assert.Equal(t, len(codes.RecoveryCodes), 10)
+
+		// the key is no longer limited after enrollment
+		resp = doRequest(t, routes, http.MethodGet, "/api/users/self", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+	})
+
+	t.Run("login requires a code", func(t *testing.T) {
+		loginResp := login(t, "")
+		assert.Assert(t, loginResp.MFARequired)
+		assert.Equal(t, loginResp.AccessKey, "")
+
+		resp := doRequest(t, routes, http.MethodPost, "/api/login", "", api.LoginRequest{
+			PasswordCredentials: &api.LoginRequestPasswordCredentials{
+				Name:     "mfa@example.com",
+				Password: "password123",
+				MFACode:  "000000",
+			},
+		})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("recovery codes", func(t *testing.T) {
+		// use the next time step, the current one was used to verify
+		code, err := authn.TOTPCode(secret, time.Now().Add(30*time.Second))
+		assert.NilError(t, err)
+		loginResp := login(t, code)
+		assert.Assert(t, loginResp.AccessKey != "")
+
+		resp := doRequest(t, routes, http.MethodPost, "/api/users/self/mfa/recovery-codes", loginResp.AccessKey, nil)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var codes api.MFARecoveryCodesResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &codes))
+		assert.Equal(t, len(codes.RecoveryCodes), 10)
+
+		loginResp = login(t, codes.RecoveryCodes[0])
+		assert.Assert(t, loginResp.AccessKey != "")
+		assert.Assert(t, !loginResp.MFAEnrollmentRequired)
+	})
+
+	t.Run("admin can disable", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodDelete, "/api/users/"+user.ID.String()+"/mfa", adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		loginResp := login(t, "")
+		assert.Assert(t, !loginResp.MFARequired)
+		assert.Assert(t, loginResp.MFAEnrollmentRequired)
+	})
+
+	t.Run("only the user can enroll", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/users/"+user.ID.String()+"/mfa", adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("invalid setting", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPut, "/api/settings", adminKey, api.Settings{RequireMFA: "sometimes"})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+}
@@ -13,11 +13,13 @@ import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
+	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/access"
 	"github.com/infrahq/infra/internal/logging"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
 )
 
 // TimeoutMiddleware adds a timeout to the request context within the Gin context.
@@ -57,10 +59,17 @@ func handleInfraDestinationHeader(c *gin.Context) error {
 		destination := destinations[0]
 		// only save if there's significant difference between LastSeenAt and Now
 		if time.Since(destination.LastSeenAt) > time.Second {
+			wasConnected := destination.Connected()
 			destination.LastSeenAt = time.Now()
 			if err := access.SaveDestination(c, &destination); err != nil {
 				return fmt.Errorf("failed to update destination lastSeenAt: %w", err)
 			}
+			if !wasConnected {
+				err := access.CreateWebhookEvent(c, api.WebhookEventDestinationConnected, destination.ToAPI())
+				if err != nil {
+					return err
+				}
+			}
 		}
 		return nil
 	default:
@@ -74,38 +83,68 @@ func handleInfraDestinationHeader(c *gin.Context) error {
 func authenticatedMiddleware(srv *Server) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		withDBTxn(c.Request.Context(), srv.DB().GormDB(), func(db *gorm.DB) {
-
-			tx := data.NewTransaction(db, 0)
-			authned, err := requireAccessKey(c, tx, srv)
-			if err != nil {
-				sendAPIError(c, err)
-				return
+			if authenticateRequest(c, srv, db) {
+				c.Next()
 			}
+		})
+	}
+}
 
-			if _, err := validateOrgMatchesRequest(c.Request, tx, authned.Organization); err != nil {
-				logging.L.Warn().Err(err).Msg("org validation failed")
-				sendAPIError(c, internal.ErrBadRequest)
-				return
-			}
+// longPollMiddleware authenticates a request the same way as
+// authenticatedMiddleware, but commits the transaction before calling the
+// handler. It is used by handlers that wait for changes, which must not hold
+// a transaction open while they wait. The handler queries the database
+// without a transaction.
+func longPollMiddleware(srv *Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ok bool
+		withDBTxn(c.Request.Context(), srv.DB().GormDB(), func(db *gorm.DB) {
+			ok = authenticateRequest(c, srv, db)
+		})
+		if !ok {
+			return
+		}
 
-			tx = data.NewTransaction(db, authned.Organization.ID)
-			rCtx := access.RequestContext{
-				Request:       c.Request,
-				DBTxn:         tx,
-				Authenticated: authned,
-			}
-			c.Set(access.RequestContextKey, rCtx)
+		rCtx := getRequestContext(c)
+		db := srv.DB().GormDB().WithContext(c.Request.Context())
+		rCtx.DBTxn = data.NewTransaction(db, rCtx.Authenticated.Organization.ID)
+		c.Set(access.RequestContextKey, rCtx)
+		c.Next()
+	}
+}
 
-			// TODO: remove once everything uses RequestContext
-			c.Set("identity", authned.User)
+// authenticateRequest validates the access key of the request, and sets the
+// request context. It returns false if an error response was sent.
+func authenticateRequest(c *gin.Context, srv *Server, db *gorm.DB) bool {
+	tx := data.NewTransaction(db, 0)
+	authned, err := requireAccessKey(c, tx, srv)
+	if err != nil {
+		sendAPIError(c, err)
+		return false
+	}
 
-			if err := handleInfraDestinationHeader(c); err != nil {
-				sendAPIError(c, err)
-				return
-			}
-			c.Next()
-		})
+	if _, err := validateOrgMatchesRequest(c.Request, tx, authned.Organization); err != nil {
+		logging.L.Warn().Err(err).Msg("org validation failed")
+		sendAPIError(c, internal.ErrBadRequest)
+		return false
 	}
+
+	tx = data.NewTransaction(db, authned.Organization.ID)
+	rCtx := access.RequestContext{
+		Request:       c.Request,
+		DBTxn:         tx,
+		Authenticated: authned,
+	}
+	c.Set(access.RequestContextKey, rCtx)
+
+	// TODO: remove once everything uses RequestContext
+	c.Set("identity", authned.User)
+
+	if err := handleInfraDestinationHeader(c); err != nil {
+		sendAPIError(c, err)
+		return false
+	}
+	return true
 }
 
 // validateOrgMatchesRequest checks that if both the accessKeyOrg and the org
@@ -195,6 +234,18 @@ func orgRequired() gin.HandlerFunc {
 	}
 }
 
+// isMFAEnrollmentPath returns true if path is one of the endpoints used to
+// enable multi-factor authentication for the user.
+func isMFAEnrollmentPath(path string, userID uid.ID) bool {
+	for _, id := range []string{"self", userID.String()} {
+		prefix := "/api/users/" + id + "/mfa"
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 // requireAccessKey checks the bearer token is present and valid
 func requireAccessKey(c *gin.Context, db data.GormTxn, srv *Server) (access.Authenticated, error) {
 	var u access.Authenticated
@@ -212,10 +263,27 @@ func requireAccessKey(c *gin.Context, db data.GormTxn, srv *Server) (access.Auth
 		return u, fmt.Errorf("%w: invalid token: %s", internal.ErrUnauthorized, err)
 	}
 
-	if accessKey.Scopes.Includes(models.ScopePasswordReset) {
+	// a key with both scopes can be used for either, so that a user with a
+	// temporary password can also enable multi-factor authentication.
+	isPasswordUpdate := c.Request.URL.Path == "/api/users/"+accessKey.IssuedFor.String() && c.Request.Method == http.MethodPut
+	isMFAEnrollment := isMFAEnrollmentPath(c.Request.URL.Path, accessKey.IssuedFor)
+	passwordResetScope := accessKey.Scopes.Includes(models.ScopePasswordReset)
+	mfaEnrollmentScope := accessKey.Scopes.Includes(models.ScopeMFAEnrollment)
+
+	if passwordResetScope && !isPasswordUpdate && !(mfaEnrollmentScope && isMFAEnrollment) {
 		// PUT /api/users/:id only
-		if c.Request.URL.Path != "/api/users/"+accessKey.IssuedFor.String() || c.Request.Method != http.MethodPut {
-			return u, fmt.Errorf("%w: temporary passwords can only be used to set new passwords", internal.ErrUnauthorized)
+		return u, fmt.Errorf("%w: temporary passwords can only be used to set new passwords", internal.ErrUnauthorized)
+	}
+
+	if mfaEnrollmentScope && !isMFAEnrollment && !(passwordResetScope && isPasswordUpdate) {
+		// /api/users/:id/mfa only
+		return u, fmt.Errorf("%w: multi-factor authentication must be enabled to use this access key", internal.ErrUnauthorized)
+	}
+
+	if accessKey.Scopes.Includes(models.ScopeSCIM) {
+		// SCIM provisioning endpoints only
+		if !strings.HasPrefix(c.Request.URL.Path, "/scim/v2/") {
+			return u, fmt.Errorf("%w: SCIM access keys can only be used for SCIM provisioning", internal.ErrUnauthorized)
 		}
 	}
 
@@ -289,6 +357,22 @@ hostLookup:
 	return org, nil
 }
 
+// serverURL returns the URL used to reach the server for the organization of
+// the request. The host of the request is not used, because it is set by the
+// client. Organizations with a domain are reached at that domain, and all
+// other organizations at the URL from the server options.
+func (a *API) serverURL(c *gin.Context) (string, error) {
+	if org := getRequestContext(c).Authenticated.Organization; org != nil && org.Domain != "" {
+		return "https://" + org.Domain, nil
+	}
+
+	serverURL := a.server.options.ServerURL
+	if serverURL.Host == "" {
+		return "", fmt.Errorf("the server URL is not configured, set it with --server-url")
+	}
+	return strings.TrimSuffix(serverURL.String(), "/"), nil
+}
+
 func reqBearerToken(c *gin.Context, baseDomain string) (string, error) {
 	header := c.Request.Header.Get("Authorization")
 
@@ -1,15 +1,58 @@
 package server
 
 import (
+	"fmt"
 	"net/http"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/gin-gonic/gin"
 
 	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
 )
 
 func (a *API) addRequestRewrites() {
 	// all request migrations go here
+	a.addCreateTokenUpgradeRequired()
+}
+
+// addCreateTokenUpgradeRequired rejects token requests without a destination
+// from clients before v0.14.5. Those clients, and the kubeconfigs they wrote,
+// do not know the destination of the token, and connectors only accept tokens
+// issued for their destination. The error tells the user to upgrade instead
+// of the generic error for the missing field.
+func (a *API) addCreateTokenUpgradeRequired() {
+	const version = "0.14.4"
+	migrationVersion := semver.MustParse(version)
+
+	a.migrations = append(a.migrations, apiMigration{
+		method:  http.MethodPost,
+		path:    "/api/tokens",
+		version: version,
+		index:   len(a.migrations),
+		requestRewrite: func(c *gin.Context) {
+			if !rewriteRequired(c, migrationVersion) {
+				c.Next()
+				return
+			}
+
+			oldReq := &api.CreateTokenRequest{}
+			if c.Request.Body != nil && c.Request.ContentLength > 0 {
+				if err := c.ShouldBindJSON(oldReq); err != nil {
+					sendAPIError(c, fmt.Errorf("%w: %s", internal.ErrBadRequest, err))
+					return
+				}
+			}
+
+			if oldReq.Destination == "" {
+				sendAPIError(c, fmt.Errorf("%w: this version of the CLI is no longer supported, upgrade the CLI and run 'infra login' again", internal.ErrBadRequest))
+				return
+			}
+
+			rebuildRequest(c, *oldReq)
+			c.Next()
+		},
+	})
 }
 
 func (a *API) addResponseRewrites() {
@@ -12,7 +12,15 @@ var (
 	AccessKeySecretLength = 24 // the length of the secret used to validate an access key
 )
 
-const ScopePasswordReset = "password-reset"
+const (
+	ScopePasswordReset = "password-reset"
+	// ScopeMFAEnrollment limits an access key to enabling multi-factor
+	// authentication for the user the key was issued for.
+	ScopeMFAEnrollment = "mfa-enrollment"
+	// ScopeSCIM limits an access key to the SCIM provisioning endpoints of
+	// the provider the key was issued for.
+	ScopeSCIM = "scim"
+)
 
 // AccessKey is a session token presented to the Infra server as proof of authentication
 type AccessKey struct {
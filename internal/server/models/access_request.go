@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+type AccessRequestState string
+
+const (
+	AccessRequestStatePending  AccessRequestState = api.AccessRequestStatePending
+	AccessRequestStateApproved AccessRequestState = api.AccessRequestStateApproved
+	AccessRequestStateDenied   AccessRequestState = api.AccessRequestStateDenied
+)
+
+// AccessRequest is a request from a user for a privilege on a resource. When
+// the request is approved a Grant is created for the user.
+type AccessRequest struct {
+	Model
+	OrganizationMember
+
+	UserID    uid.ID
+	Privilege string
+	Resource  string
+	Reason    string
+	// Duration is how long the grant should last once it is approved. A zero
+	// value means the grant does not expire.
+	Duration time.Duration
+	State    AccessRequestState
+
+	// ReviewedBy is the ID of the user that approved or denied the request.
+	ReviewedBy uid.ID
+	// GrantID is the ID of the grant created when the request was approved.
+	GrantID uid.ID
+}
+
+func (r *AccessRequest) ToAPI() *api.AccessRequest {
+	return &api.AccessRequest{
+		ID:         r.ID,
+		Created:    api.Time(r.CreatedAt),
+		Updated:    api.Time(r.UpdatedAt),
+		User:       r.UserID,
+		Privilege:  r.Privilege,
+		Resource:   r.Resource,
+		Reason:     r.Reason,
+		Duration:   api.Duration(r.Duration),
+		State:      string(r.State),
+		ReviewedBy: r.ReviewedBy,
+		Grant:      r.GrantID,
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+// AuditEvent is a record of a single API call that modified the state of an
+// organization.
+type AuditEvent struct {
+	Model
+	OrganizationMember
+
+	// ActorID is the identity that made the request. It is zero for requests
+	// that were not authenticated, like login.
+	ActorID   uid.ID
+	ActorName string
+
+	Method string
+	// Path is the route that handled the request, ex: /api/grants/:id
+	Path string
+	// Resource is the kind of resource targeted by the request, ex: grants
+	Resource   string
+	ResourceID uid.ID
+
+	// Request is a JSON summary of the request, with any secrets redacted.
+	Request    string
+	StatusCode int
+}
+
+func (e *AuditEvent) ToAPI() *api.AuditEvent {
+	return &api.AuditEvent{
+		ID:         e.ID,
+		Created:    api.Time(e.CreatedAt),
+		ActorID:    e.ActorID,
+		ActorName:  e.ActorName,
+		Method:     e.Method,
+		Path:       e.Path,
+		Resource:   e.Resource,
+		ResourceID: e.ResourceID,
+		Request:    e.Request,
+		StatusCode: e.StatusCode,
+	}
+}
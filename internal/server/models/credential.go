@@ -1,6 +1,10 @@
 package models
 
-import "github.com/infrahq/infra/uid"
+import (
+	"time"
+
+	"github.com/infrahq/infra/uid"
+)
 
 type Credential struct {
 	Model
@@ -9,4 +13,23 @@ type Credential struct {
 	IdentityID      uid.ID `gorm:"<-;uniqueIndex:idx_credentials_identity_id,where:deleted_at is NULL"`
 	PasswordHash    []byte
 	OneTimePassword bool
+
+	// MFASecret is the TOTP secret of the user. A code is only required at
+	// login once MFAEnabled is true, after the user has verified a code
+	// generated from the secret.
+	MFASecret  EncryptedAtRest
+	MFAEnabled bool
+	// MFARecoveryCodes are the comma separated SHA-256 hashes of the recovery
+	// codes that have not been used yet.
+	MFARecoveryCodes EncryptedAtRest
+	// MFALastUsedStep is the TOTP time step of the last code used to login. It
+	// prevents the same code from being used more than once.
+	MFALastUsedStep int64
+
+	// FailedLoginAttempts is the number of failed logins since the last
+	// successful login or lockout.
+	FailedLoginAttempts int
+	// LockedUntil is the time until which logins are rejected, because of too
+	// many failed logins.
+	LockedUntil time.Time
 }
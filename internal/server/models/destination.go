@@ -6,6 +6,12 @@ import (
 	"github.com/infrahq/infra/api"
 )
 
+// DestinationConnectionTimeout is how long after it was last seen that a
+// destination is considered disconnected.
+// TODO: this should be configurable
+// https://github.com/infrahq/infra/issues/2505
+const DestinationConnectionTimeout = 5 * time.Minute
+
 type Destination struct {
 	Model
 	OrganizationMember
@@ -16,20 +22,17 @@ type Destination struct {
 	ConnectionCA  string
 
 	LastSeenAt time.Time
-	Version    string
+	// DisconnectedAt is the time that the destination was found to be
+	// disconnected, after it was last seen. A destination disconnected event is
+	// created when it is set.
+	DisconnectedAt time.Time
+	Version        string
 
 	Resources CommaSeparatedStrings
 	Roles     CommaSeparatedStrings
 }
 
 func (d *Destination) ToAPI() *api.Destination {
-	connected := false
-	// TODO: this should be configurable
-	// https://github.com/infrahq/infra/issues/2505
-	if time.Since(d.LastSeenAt) < 5*time.Minute {
-		connected = true
-	}
-
 	return &api.Destination{
 		ID:       d.ID,
 		Created:  api.Time(d.CreatedAt),
@@ -43,7 +46,12 @@ func (d *Destination) ToAPI() *api.Destination {
 		Resources: d.Resources,
 		Roles:     d.Roles,
 		LastSeen:  api.Time(d.LastSeenAt),
-		Connected: connected,
+		Connected: d.Connected(),
 		Version:   d.Version,
 	}
 }
+
+// Connected returns true if the destination was seen recently.
+func (d *Destination) Connected() bool {
+	return time.Since(d.LastSeenAt) < DestinationConnectionTimeout
+}
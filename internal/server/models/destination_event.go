@@ -0,0 +1,28 @@
+package models
+
+import "github.com/infrahq/infra/uid"
+
+// DestinationEvent is a change to a grant, role, user, or group that can
+// change access to destinations. Connectors list the events after the last
+// event they applied, instead of listing every grant again.
+type DestinationEvent struct {
+	Model
+	OrganizationMember
+
+	// Sequence orders the events of an organization in the order that their
+	// transactions were committed.
+	Sequence int64
+	Type     string
+	// Resource is the resource of the grant of a grant event. It is empty for
+	// events that apply to every destination.
+	Resource string
+	// Payload is the JSON encoded api.DestinationEvent.
+	Payload []byte
+}
+
+// DestinationEventSequence is the sequence of the last destination event of
+// an organization.
+type DestinationEventSequence struct {
+	OrganizationID uid.ID `gorm:"primaryKey;autoIncrement:false"`
+	Sequence       int64
+}
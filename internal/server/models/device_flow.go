@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+// DeviceFlowAuthRequest is a login started from a device which can not open a
+// browser, like a jump host or a container. The user approves the request from
+// any browser by entering the UserCode, and the device exchanges the
+// DeviceCode for an access key once the request is approved.
+type DeviceFlowAuthRequest struct {
+	Model
+	OrganizationMember
+
+	UserCode   string `gorm:"uniqueIndex:idx_device_flow_auth_requests_user_code,where:deleted_at is NULL"`
+	DeviceCode string `gorm:"uniqueIndex:idx_device_flow_auth_requests_device_code,where:deleted_at is NULL"`
+	ExpiresAt  time.Time
+
+	// ApprovedBy is the ID of the user that approved the request. The access
+	// key is issued for this user.
+	ApprovedBy uid.ID
+	// ProviderID is the provider of the session that approved the request.
+	ProviderID uid.ID
+	// LastPolledAt is when the device last polled for an access key. It is
+	// used to slow down devices that poll more often than the interval.
+	LastPolledAt time.Time
+}
+
+func (r *DeviceFlowAuthRequest) ToAPI() *api.DeviceFlowAuthRequest {
+	return &api.DeviceFlowAuthRequest{
+		ID:         r.ID,
+		Created:    api.Time(r.CreatedAt),
+		Expires:    api.Time(r.ExpiresAt),
+		UserCode:   r.DisplayUserCode(),
+		ApprovedBy: r.ApprovedBy,
+	}
+}
+
+// DisplayUserCode returns the user code with a separator in the middle, so
+// that it is easier to read.
+func (r *DeviceFlowAuthRequest) DisplayUserCode() string {
+	if len(r.UserCode) != 8 {
+		return r.UserCode
+	}
+	return r.UserCode[:4] + "-" + r.UserCode[4:]
+}
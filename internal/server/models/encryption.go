@@ -35,6 +35,12 @@ func (s EncryptedAtRest) Value() (driver.Value, error) {
 }
 
 func (s *EncryptedAtRest) Scan(v interface{}) error {
+	if v == nil {
+		// columns added to an existing table are NULL until they are set
+		*s = ""
+		return nil
+	}
+
 	vStr, ok := v.(string)
 	if !ok {
 		return fmt.Errorf("unsupported type: %T", v)
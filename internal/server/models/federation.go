@@ -0,0 +1,100 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+// Federation is an external JWT issuer trusted to login as an identity. A
+// token signed by the issuer, with the audience, and claims that match all of
+// the conditions is exchanged for an access key issued for the identity.
+type Federation struct {
+	Model
+	OrganizationMember
+
+	Name       string `gorm:"uniqueIndex:idx_federations_name,where:deleted_at is NULL"`
+	Issuer     string
+	JWKSURL    string `gorm:"column:jwks_url"`
+	Audience   string
+	Conditions FederationConditions
+	IdentityID uid.ID
+	// TTL is how long the access key issued at login is valid.
+	TTL       time.Duration
+	CreatedBy uid.ID
+}
+
+func (f *Federation) ToAPI() *api.Federation {
+	return &api.Federation{
+		ID:         f.ID,
+		Created:    api.Time(f.CreatedAt),
+		Updated:    api.Time(f.UpdatedAt),
+		Name:       f.Name,
+		Issuer:     f.Issuer,
+		JWKSURL:    f.JWKSURL,
+		Audience:   f.Audience,
+		Conditions: f.Conditions,
+		User:       f.IdentityID,
+		TTL:        api.Duration(f.TTL),
+	}
+}
+
+// FederationConditions map the name of a claim to the value it must have.
+// They are stored as a JSON encoded object.
+type FederationConditions map[string]string
+
+// federationSubjectClaims are the claims that identify the workload that a
+// token was issued to. Every federation must have a condition on one of them,
+// otherwise any token from the issuer for the audience would match, including
+// tokens issued to the workloads of other tenants of the issuer.
+var federationSubjectClaims = []string{"sub", "repository"}
+
+// RestrictsSubject returns true if the conditions include a condition on the
+// subject of the token with a literal owner. The owner is the part of the
+// pattern before the first "/", like the owner of a repository. It must not
+// include wildcards, otherwise a pattern like "*/*" would match the workloads
+// of every tenant of the issuer.
+func (c FederationConditions) RestrictsSubject() bool {
+	for _, claim := range federationSubjectClaims {
+		owner, _, _ := strings.Cut(c[claim], "/")
+		if owner != "" && !strings.ContainsAny(owner, `*?[\`) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c FederationConditions) Value() (driver.Value, error) {
+	if c == nil {
+		c = FederationConditions{}
+	}
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+func (c *FederationConditions) Scan(v interface{}) error {
+	var raw []byte
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case string:
+		raw = []byte(value)
+	case []byte:
+		raw = value
+	default:
+		return fmt.Errorf("expected string type for federation conditions, got %T", v)
+	}
+	return json.Unmarshal(raw, c)
+}
+
+func (c FederationConditions) GormDataType() string {
+	return "text"
+}
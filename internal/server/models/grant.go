@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/uid"
 )
@@ -46,6 +48,14 @@ type Grant struct {
 	Privilege string            `gorm:"uniqueIndex:idx_grant_srp,where:deleted_at is NULL"` // role or permission
 	Resource  string            `gorm:"uniqueIndex:idx_grant_srp,where:deleted_at is NULL"` // Universal Resource Notation
 	CreatedBy uid.ID
+	// ExpiresAt is the time when the grant stops being valid. A zero value
+	// means the grant does not expire.
+	ExpiresAt time.Time
+}
+
+// IsExpired returns true if the grant has an expiry, and it has passed.
+func (r *Grant) IsExpired() bool {
+	return !r.ExpiresAt.IsZero() && !time.Now().Before(r.ExpiresAt)
 }
 
 func (r *Grant) ToAPI() *api.Grant {
@@ -56,6 +66,7 @@ func (r *Grant) ToAPI() *api.Grant {
 		CreatedBy: r.CreatedBy,
 		Privilege: r.Privilege,
 		Resource:  r.Resource,
+		Expires:   api.Time(r.ExpiresAt),
 	}
 
 	switch {
@@ -14,8 +14,14 @@ type Group struct {
 	CreatedByProvider uid.ID
 
 	Identities []Identity `gorm:"many2many:identities_groups"`
+	// MemberGroups are the groups that are members of this group. Members of
+	// the member groups inherit the grants of this group.
+	MemberGroups []Group `gorm:"many2many:nested_groups;joinForeignKey:GroupID;joinReferences:MemberGroupID"`
 
 	TotalUsers int `gorm:"-:all"`
+	// ParentGroupIDs are the groups that this group is a member of. Members
+	// of the group inherit the grants of its parent groups.
+	ParentGroupIDs []uid.ID `gorm:"-:all"`
 }
 
 func (g *Group) ToAPI() *api.Group {
@@ -25,6 +31,7 @@ func (g *Group) ToAPI() *api.Group {
 		Updated:    api.Time(g.UpdatedAt),
 		Name:       g.Name,
 		TotalUsers: g.TotalUsers,
+		Parents:    g.ParentGroupIDs,
 	}
 }
 
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+// OIDCClient is a web application that uses Infra as its OpenID Connect
+// identity provider.
+type OIDCClient struct {
+	Model
+	OrganizationMember
+
+	Name         string `gorm:"uniqueIndex:idx_oidc_clients_name,where:deleted_at is NULL"`
+	ClientID     string `gorm:"uniqueIndex:idx_oidc_clients_client_id,where:deleted_at is NULL"`
+	ClientSecret EncryptedAtRest
+	RedirectURIs CommaSeparatedStrings `gorm:"column:redirect_uris"`
+	CreatedBy    uid.ID
+}
+
+func (o *OIDCClient) ToAPI() *api.OIDCClient {
+	return &api.OIDCClient{
+		ID:           o.ID,
+		Created:      api.Time(o.CreatedAt),
+		Updated:      api.Time(o.UpdatedAt),
+		Name:         o.Name,
+		ClientID:     o.ClientID,
+		RedirectURIs: o.RedirectURIs,
+	}
+}
+
+// HasRedirectURI returns true if uri is one of the redirect URIs of the
+// client. Redirect URIs must match exactly.
+func (o *OIDCClient) HasRedirectURI(uri string) bool {
+	for _, u := range o.RedirectURIs {
+		if u == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// OIDCAuthorizationCode is issued to an OIDC client when a user logs in to the
+// client. The client exchanges the code for an ID token once.
+type OIDCAuthorizationCode struct {
+	Model
+	OrganizationMember
+
+	Code         string `gorm:"uniqueIndex:idx_oidc_authorization_codes_code,where:deleted_at is NULL"`
+	OIDCClientID uid.ID `gorm:"column:oidc_client_id"`
+	IdentityID   uid.ID
+	RedirectURI  string
+	Scopes       CommaSeparatedStrings
+	Nonce        string
+	// CodeChallenge is the S256 PKCE code challenge of the request, if the
+	// client sent one.
+	CodeChallenge string
+	ExpiresAt     time.Time
+}
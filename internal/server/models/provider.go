@@ -17,6 +17,8 @@ const (
 	ProviderKindOkta   ProviderKind = "okta"
 	ProviderKindAzure  ProviderKind = "azure"
 	ProviderKindGoogle ProviderKind = "google"
+	ProviderKindLDAP   ProviderKind = "ldap"
+	ProviderKindSAML   ProviderKind = "saml"
 )
 
 func (p ProviderKind) String() string {
@@ -30,6 +32,8 @@ var providerKindMap = map[string]ProviderKind{
 	ProviderKindOkta.String():   ProviderKindOkta,
 	ProviderKindAzure.String():  ProviderKindAzure,
 	ProviderKindGoogle.String(): ProviderKindGoogle,
+	ProviderKindLDAP.String():   ProviderKindLDAP,
+	ProviderKindSAML.String():   ProviderKindSAML,
 }
 
 // ParseProviderKind validates that a string is valid kind then returns the ProviderKind
@@ -59,6 +63,21 @@ type Provider struct {
 	PrivateKey       EncryptedAtRest
 	ClientEmail      string
 	DomainAdminEmail string
+
+	// fields used to search an LDAP directory, the URL is the address of the
+	// LDAP server
+	BindDN       string `gorm:"column:bind_dn"`
+	BindPassword EncryptedAtRest
+	SearchBase   string
+	UserFilter   string
+	GroupFilter  string
+
+	// fields used to verify assertions from a SAML identity provider, the URL
+	// is the single sign-on URL of the identity provider
+	SAMLEntityID        string `gorm:"column:saml_entity_id"`
+	SAMLCertificate     string `gorm:"column:saml_certificate"`
+	SAMLNameAttribute   string `gorm:"column:saml_name_attribute"`
+	SAMLGroupsAttribute string `gorm:"column:saml_groups_attribute"`
 }
 
 func (p *Provider) ToAPI() *api.Provider {
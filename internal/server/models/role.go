@@ -0,0 +1,89 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+// Role is a user-defined set of Kubernetes policy rules. A grant with the name
+// of the role as its privilege is bound to the ClusterRole that connectors
+// create for the role.
+type Role struct {
+	Model
+	OrganizationMember
+
+	Name        string `gorm:"uniqueIndex:idx_roles_name,where:deleted_at is NULL"`
+	Description string
+	Rules       PolicyRules
+	CreatedBy   uid.ID
+}
+
+func (r *Role) ToAPI() *api.Role {
+	rules := make([]api.PolicyRule, 0, len(r.Rules))
+	for _, rule := range r.Rules {
+		rules = append(rules, api.PolicyRule(rule))
+	}
+
+	return &api.Role{
+		ID:          r.ID,
+		Created:     api.Time(r.CreatedAt),
+		Updated:     api.Time(r.UpdatedAt),
+		Name:        r.Name,
+		Description: r.Description,
+		Rules:       rules,
+	}
+}
+
+// PolicyRule is a Kubernetes RBAC policy rule.
+type PolicyRule struct {
+	APIGroups       []string `json:"apiGroups"`
+	Resources       []string `json:"resources"`
+	ResourceNames   []string `json:"resourceNames,omitempty"`
+	NonResourceURLs []string `json:"nonResourceURLs,omitempty"`
+	Verbs           []string `json:"verbs"`
+}
+
+// PolicyRules are stored as a JSON encoded list.
+type PolicyRules []PolicyRule
+
+func PolicyRulesFromAPI(rules []api.PolicyRule) PolicyRules {
+	result := make(PolicyRules, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, PolicyRule(rule))
+	}
+	return result
+}
+
+func (r PolicyRules) Value() (driver.Value, error) {
+	if r == nil {
+		r = PolicyRules{}
+	}
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+func (r *PolicyRules) Scan(v interface{}) error {
+	var raw []byte
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case string:
+		raw = []byte(value)
+	case []byte:
+		raw = value
+	default:
+		return fmt.Errorf("expected string type for policy rules, got %T", v)
+	}
+	return json.Unmarshal(raw, r)
+}
+
+func (r PolicyRules) GormDataType() string {
+	return "text"
+}
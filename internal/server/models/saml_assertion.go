@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/infrahq/infra/uid"
+)
+
+// SAMLAssertion is an assertion from a SAML identity provider that was used to
+// login. Assertions are kept until they expire, so that each one can only be
+// used once.
+type SAMLAssertion struct {
+	OrganizationID uid.ID `gorm:"primaryKey;autoIncrement:false"`
+	ID             string `gorm:"primaryKey;autoIncrement:false"`
+	ExpiresAt      time.Time
+}
@@ -1,21 +1,36 @@
 package models
 
 import (
+	"time"
+
 	"github.com/infrahq/infra/api"
 )
 
+const (
+	// DefaultLockoutThreshold and DefaultLockoutDuration must match the
+	// defaults of the Settings columns.
+	DefaultLockoutThreshold = 10
+	DefaultLockoutDuration  = 15 * time.Minute
+)
+
 type Settings struct {
 	Model
 	OrganizationMember
 
-	PrivateJWK EncryptedAtRestBytes
-	PublicJWK  []byte
-
 	LowercaseMin int `gorm:"default:0"`
 	UppercaseMin int `gorm:"default:0"`
 	NumberMin    int `gorm:"default:0"`
 	SymbolMin    int `gorm:"default:0"`
 	LengthMin    int `gorm:"default:8"`
+
+	// RequireMFA is one of the api.MFARequirement values. Empty is the same
+	// as api.MFARequirementNone.
+	RequireMFA string
+
+	// LockoutThreshold is the number of consecutive failed logins after which
+	// a user is locked out for LockoutDuration. Zero disables lockout.
+	LockoutThreshold int           `gorm:"default:10"`
+	LockoutDuration  time.Duration `gorm:"default:900000000000"`
 }
 
 func (s *Settings) ToAPI() *api.Settings {
@@ -27,6 +42,11 @@ func (s *Settings) ToAPI() *api.Settings {
 			SymbolMin:    s.SymbolMin,
 			LengthMin:    s.LengthMin,
 		},
+		RequireMFA: s.RequireMFA,
+		Lockout: &api.LockoutSettings{
+			Threshold: s.LockoutThreshold,
+			Duration:  api.Duration(s.LockoutDuration),
+		},
 	}
 }
 
@@ -36,4 +56,11 @@ func (s *Settings) SetFromAPI(a *api.Settings) {
 	s.LowercaseMin = a.PasswordRequirements.LowercaseMin
 	s.SymbolMin = a.PasswordRequirements.SymbolMin
 	s.NumberMin = a.PasswordRequirements.NumberMin
+	s.RequireMFA = a.RequireMFA
+	// lockout is optional so that clients which do not know about it do not
+	// disable it when they update other settings.
+	if a.Lockout != nil {
+		s.LockoutThreshold = a.Lockout.Threshold
+		s.LockoutDuration = time.Duration(a.Lockout.Duration)
+	}
 }
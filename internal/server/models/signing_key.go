@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/infrahq/infra/api"
+)
+
+type SigningKeyState string
+
+const (
+	// SigningKeyStateActive is the state of the key used to sign new tokens.
+	// There is at most one active key per organization.
+	SigningKeyStateActive SigningKeyState = "active"
+	// SigningKeyStateRetiring is the state of a key that is no longer used to
+	// sign tokens, but is still published so that tokens signed before the
+	// rotation remain valid until they expire.
+	SigningKeyStateRetiring SigningKeyState = "retiring"
+	// SigningKeyStateRetired is the state of a key that is no longer published.
+	SigningKeyStateRetired SigningKeyState = "retired"
+)
+
+// SigningKey is a JSON Web Key used to sign the tokens that destinations use
+// to authenticate users.
+type SigningKey struct {
+	Model
+	OrganizationMember
+
+	// KeyID is the kid of the JSON Web Key.
+	KeyID      string
+	PrivateJWK EncryptedAtRestBytes
+	PublicJWK  []byte
+	State      SigningKeyState
+	// RetireAt is the time after which a retiring key is retired, once every
+	// token it signed has expired.
+	RetireAt time.Time
+}
+
+func (k *SigningKey) ToAPI() *api.SigningKey {
+	return &api.SigningKey{
+		ID:      k.ID,
+		Created: api.Time(k.CreatedAt),
+		Updated: api.Time(k.UpdatedAt),
+		KeyID:   k.KeyID,
+		State:   string(k.State),
+	}
+}
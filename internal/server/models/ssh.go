@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/infrahq/infra/uid"
+)
+
+// SSHUserCA is the certificate authority that signs the SSH certificates of
+// the users of an organization. SSH destinations trust the public key of the
+// CA.
+type SSHUserCA struct {
+	OrganizationID uid.ID `gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt      time.Time
+
+	// PrivateKey is the PEM encoded private key.
+	PrivateKey EncryptedAtRestBytes
+	// PublicKey is the public key in the authorized_keys format.
+	PublicKey string
+}
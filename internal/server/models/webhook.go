@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+// Webhook is an endpoint that receives events from Infra.
+type Webhook struct {
+	Model
+	OrganizationMember
+
+	URL string
+	// Secret is used to sign the payloads sent to the webhook.
+	Secret     EncryptedAtRest
+	EventTypes CommaSeparatedStrings
+	CreatedBy  uid.ID
+}
+
+func (w *Webhook) ToAPI() *api.Webhook {
+	return &api.Webhook{
+		ID:      w.ID,
+		Created: api.Time(w.CreatedAt),
+		Updated: api.Time(w.UpdatedAt),
+		URL:     w.URL,
+		Events:  w.EventTypes,
+	}
+}
+
+type WebhookDeliveryState string
+
+const (
+	WebhookDeliveryStatePending   WebhookDeliveryState = "pending"
+	WebhookDeliveryStateDelivered WebhookDeliveryState = "delivered"
+	WebhookDeliveryStateFailed    WebhookDeliveryState = "failed"
+)
+
+// WebhookDelivery is an event waiting to be sent to a webhook, or the result
+// of sending it.
+type WebhookDelivery struct {
+	Model
+	OrganizationMember
+
+	WebhookID uid.ID
+	EventType string
+	// Payload is the JSON encoded api.WebhookEvent.
+	Payload []byte
+	State   WebhookDeliveryState
+
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+}
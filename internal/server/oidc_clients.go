@@ -0,0 +1,106 @@
+package server
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/validate"
+)
+
+func (a *API) ListOIDCClients(c *gin.Context, r *api.ListOIDCClientsRequest) (*api.ListResponse[api.OIDCClient], error) {
+	p := models.RequestToPagination(r.PaginationRequest)
+	clients, err := access.ListOIDCClients(c, r.Name, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(clients, models.PaginationToResponse(p), func(client models.OIDCClient) api.OIDCClient {
+		return *client.ToAPI()
+	})
+
+	return result, nil
+}
+
+func (a *API) GetOIDCClient(c *gin.Context, r *api.Resource) (*api.OIDCClient, error) {
+	client, err := access.GetOIDCClient(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.ToAPI(), nil
+}
+
+func (a *API) CreateOIDCClient(c *gin.Context, r *api.CreateOIDCClientRequest) (*api.CreateOIDCClientResponse, error) {
+	if r.Name == access.ResourceInfraAPI {
+		// grants for the infra resource are roles in Infra
+		return nil, validate.Error{"name": {"infra is reserved"}}
+	}
+	if err := validateRedirectURIs(r.RedirectURIs); err != nil {
+		return nil, err
+	}
+
+	client := &models.OIDCClient{
+		Name:         r.Name,
+		RedirectURIs: r.RedirectURIs,
+	}
+	if err := access.CreateOIDCClient(c, client); err != nil {
+		return nil, err
+	}
+
+	return &api.CreateOIDCClientResponse{
+		ID:           client.ID,
+		Created:      api.Time(client.CreatedAt),
+		Name:         client.Name,
+		ClientID:     client.ClientID,
+		ClientSecret: string(client.ClientSecret),
+		RedirectURIs: client.RedirectURIs,
+	}, nil
+}
+
+func (a *API) UpdateOIDCClient(c *gin.Context, r *api.UpdateOIDCClientRequest) (*api.OIDCClient, error) {
+	client, err := access.GetOIDCClient(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateRedirectURIs(r.RedirectURIs); err != nil {
+		return nil, err
+	}
+	client.RedirectURIs = r.RedirectURIs
+
+	if err := access.UpdateOIDCClient(c, client); err != nil {
+		return nil, err
+	}
+
+	return client.ToAPI(), nil
+}
+
+func (a *API) DeleteOIDCClient(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
+	return nil, access.DeleteOIDCClient(c, r.ID)
+}
+
+// validateRedirectURIs checks that each redirect URI is an absolute http or
+// https URL without a fragment, as required by OpenID Connect.
+func validateRedirectURIs(uris []string) error {
+	if len(uris) == 0 {
+		return validate.Error{"redirectURIs": {"is required"}}
+	}
+
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		switch {
+		case err != nil || !isHTTPURL(uri):
+			return validate.Error{"redirectURIs": {uri + " must be an http or https URL"}}
+		case u.Fragment != "":
+			return validate.Error{"redirectURIs": {uri + " must not include a fragment"}}
+		case strings.Contains(uri, ","):
+			return validate.Error{"redirectURIs": {uri + " must not include a comma"}}
+		}
+	}
+	return nil
+}
@@ -34,15 +34,20 @@ var funcPartialNameToTagNames = []struct {
 	partial string
 	tag     string
 }{
+	{partial: "AccessRequest", tag: "Access Requests"},
+	{partial: "AuditEvent", tag: "Audit"},
 	{partial: "AccessKey", tag: "Authentication"},
 	{partial: "Login", tag: "Authentication"},
 	{partial: "Logout", tag: "Authentication"},
+	{partial: "MFA", tag: "Authentication"},
 	{partial: "Destination", tag: "Destinations"},
 	{partial: "Token", tag: "Destinations"},
 	{partial: "Grant", tag: "Grants"},
 	{partial: "Group", tag: "Groups"},
 	{partial: "Provider", tag: "Providers"},
+	{partial: "Role", tag: "Roles"},
 	{partial: "User", tag: "Users"},
+	{partial: "Webhook", tag: "Webhooks"},
 }
 
 // openAPIRouteDefinition converts the route into a format that can be used
@@ -188,6 +193,11 @@ func buildProperty(f reflect.StructField, t, parent reflect.Type, parentSchema *
 		s.Items = buildProperty(f, t.Elem(), parent, parentSchema)
 	}
 
+	if s.Type == "object" && t.Kind() == reflect.Map {
+		s.AdditionalProperties = buildProperty(reflect.StructField{}, t.Elem(), parent, parentSchema)
+		return &openapi3.SchemaRef{Value: s}
+	}
+
 	if s.Type == "object" {
 		s.Properties = openapi3.Schemas{}
 
@@ -295,7 +305,7 @@ func setTypeInfo(t reflect.Type, schema *openapi3.Schema) {
 			return
 		}
 		schema.Type = "array"
-	case reflect.Struct:
+	case reflect.Struct, reflect.Map:
 		schema.Type = "object"
 	default:
 		panic("unexpected type " + t.Kind().String())
@@ -0,0 +1,284 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+const (
+	// oidcAuthorizationCodeTTL is how long an OIDC client has to exchange an
+	// authorization code for tokens.
+	oidcAuthorizationCodeTTL = 5 * time.Minute
+	// oidcTokenTTL is how long the tokens issued to OIDC clients are valid.
+	// It is the longest lifetime allowed for a signed token, so that tokens
+	// remain valid after the signing key is rotated.
+	oidcTokenTTL = data.MaxSignedTokenLifetime
+)
+
+// OpenIDConfiguration is the OpenID Connect discovery document of the
+// organization, which describes Infra as an identity provider.
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+}
+
+func wellKnownOpenIDConfigurationHandler(c *gin.Context, _ *api.EmptyRequest) (OpenIDConfiguration, error) {
+	issuer := oidcIssuer(c)
+	return OpenIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oidc/authorize",
+		TokenEndpoint:                     issuer + "/oidc/token",
+		UserInfoEndpoint:                  issuer + "/oidc/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"EdDSA"},
+		ScopesSupported:                   []string{"openid", "email", "profile", "groups"},
+		ClaimsSupported:                   []string{"sub", "name", "email", "groups", "nonce"},
+		GrantTypesSupported:               []string{"authorization_code"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+	}, nil
+}
+
+// oidcIssuer returns the issuer of the tokens issued to OIDC clients. It is
+// the same issuer used for destination tokens.
+func oidcIssuer(c *gin.Context) string {
+	return "https://" + c.Request.Host
+}
+
+// oidcAuthorize is the authorization endpoint of the OpenID Connect
+// authorization code flow. Users that are not logged in are redirected to the
+// login page of the UI, which returns to this endpoint once they login with
+// any of the login methods of the organization.
+func (a *API) oidcAuthorize(c *gin.Context) {
+	rCtx := getRequestContext(c)
+	query := c.Request.URL.Query()
+
+	client, err := data.GetOIDCClient(rCtx.DBTxn, data.ByClientID(query.Get("client_id")))
+	if err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			err = fmt.Errorf("%w: unknown client_id", internal.ErrBadRequest)
+		}
+		sendAPIError(c, err)
+		return
+	}
+
+	// errors about the redirect URI are shown to the user, because the user
+	// must not be redirected to a URI that was not registered.
+	redirectURI := query.Get("redirect_uri")
+	if !client.HasRedirectURI(redirectURI) {
+		sendAPIError(c, fmt.Errorf("%w: redirect_uri is not registered for the client", internal.ErrBadRequest))
+		return
+	}
+
+	redirect := func(params url.Values) {
+		u, err := url.Parse(redirectURI)
+		if err != nil {
+			sendAPIError(c, err)
+			return
+		}
+		q := u.Query()
+		for k, v := range params {
+			q[k] = v
+		}
+		if state := query.Get("state"); state != "" {
+			q.Set("state", state)
+		}
+		u.RawQuery = q.Encode()
+		c.Redirect(http.StatusFound, u.String())
+	}
+	redirectError := func(code, description string) {
+		redirect(url.Values{"error": {code}, "error_description": {description}})
+	}
+
+	if query.Get("response_type") != "code" {
+		redirectError("unsupported_response_type", "only the code response type is supported")
+		return
+	}
+
+	scopes := strings.Fields(query.Get("scope"))
+	if !containsString(scopes, "openid") {
+		redirectError("invalid_scope", "the openid scope is required")
+		return
+	}
+
+	codeChallenge := query.Get("code_challenge")
+	if codeChallenge != "" && query.Get("code_challenge_method") != "S256" {
+		redirectError("invalid_request", "code_challenge_method must be S256")
+		return
+	}
+
+	if rCtx.Authenticated.User == nil {
+		if query.Get("prompt") == "none" {
+			redirectError("login_required", "the user is not logged in")
+			return
+		}
+		c.Redirect(http.StatusFound, "/login?next="+url.QueryEscape(c.Request.URL.RequestURI()))
+		return
+	}
+
+	authCode := &models.OIDCAuthorizationCode{
+		RedirectURI:   redirectURI,
+		Scopes:        scopes,
+		Nonce:         query.Get("nonce"),
+		CodeChallenge: codeChallenge,
+		ExpiresAt:     time.Now().Add(oidcAuthorizationCodeTTL).UTC(),
+	}
+	err = access.AuthorizeOIDCClient(rCtx, client, authCode)
+	switch {
+	case errors.Is(err, access.ErrNotAuthorized):
+		redirectError("access_denied", "the user does not have access to "+client.Name)
+		return
+	case err != nil:
+		logging.L.Error().Err(err).Msg("failed to authorize OIDC client")
+		redirectError("server_error", "")
+		return
+	}
+
+	redirect(url.Values{"code": {authCode.Code}})
+}
+
+// oidcError is the error response of the token and userinfo endpoints, as
+// defined by RFC 6749.
+type oidcError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type oidcTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// oidcToken is the token endpoint of the OpenID Connect authorization code
+// flow. Clients authenticate with their client secret, using either HTTP basic
+// authentication or the client_secret form parameter.
+func (a *API) oidcToken(c *gin.Context) {
+	rCtx := getRequestContext(c)
+
+	if grantType := c.PostForm("grant_type"); grantType != "authorization_code" {
+		c.JSON(http.StatusBadRequest, oidcError{
+			Error:            "unsupported_grant_type",
+			ErrorDescription: "only the authorization_code grant type is supported",
+		})
+		return
+	}
+
+	req := access.OIDCTokenRequest{
+		Code:         c.PostForm("code"),
+		RedirectURI:  c.PostForm("redirect_uri"),
+		CodeVerifier: c.PostForm("code_verifier"),
+		Issuer:       oidcIssuer(c),
+		TTL:          oidcTokenTTL,
+	}
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		// the client credentials are form encoded before they are encoded as
+		// basic auth credentials
+		req.ClientID, _ = url.QueryUnescape(id)
+		req.ClientSecret, _ = url.QueryUnescape(secret)
+	} else {
+		req.ClientID = c.PostForm("client_id")
+		req.ClientSecret = c.PostForm("client_secret")
+	}
+
+	tokens, err := access.ExchangeOIDCAuthorizationCode(rCtx, req)
+	switch {
+	case errors.Is(err, internal.ErrUnauthorized):
+		logging.L.Info().Err(err).Msg("OIDC client authentication failed")
+		c.Header("WWW-Authenticate", `Basic realm="infra"`)
+		c.JSON(http.StatusUnauthorized, oidcError{Error: "invalid_client"})
+		return
+	case errors.Is(err, internal.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, oidcError{Error: "invalid_grant", ErrorDescription: err.Error()})
+		return
+	case err != nil:
+		logging.L.Error().Err(err).Msg("failed to exchange OIDC authorization code")
+		c.JSON(http.StatusInternalServerError, oidcError{Error: "server_error"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, oidcTokenResponse{
+		AccessToken: tokens.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(time.Until(tokens.Expires).Seconds()),
+		IDToken:     tokens.IDToken,
+		Scope:       strings.Join(tokens.Scopes, " "),
+	})
+}
+
+// oidcUserInfo is the userinfo endpoint, which returns the claims about the
+// user of an access token issued by oidcToken.
+func (a *API) oidcUserInfo(c *gin.Context) {
+	rCtx := getRequestContext(c)
+
+	var accessToken string
+	if parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2); len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
+		accessToken = parts[1]
+	}
+
+	userInfo, err := access.GetOIDCUserInfo(rCtx, accessToken, oidcIssuer(c))
+	switch {
+	case errors.Is(err, internal.ErrUnauthorized):
+		logging.L.Info().Err(err).Msg("invalid OIDC access token")
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.JSON(http.StatusUnauthorized, oidcError{Error: "invalid_token"})
+		return
+	case err != nil:
+		logging.L.Error().Err(err).Msg("failed to get OIDC user info")
+		c.JSON(http.StatusInternalServerError, oidcError{Error: "server_error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, userInfo)
+}
+
+// deleteExpiredOIDCAuthorizationCodes removes authorization codes which have
+// expired without being exchanged for tokens.
+func (s *Server) deleteExpiredOIDCAuthorizationCodes() {
+	count, err := data.DeleteExpiredOIDCAuthorizationCodes(s.db, time.Now())
+	if err != nil {
+		logging.L.Warn().Err(err).Msg("failed to delete expired OIDC authorization codes")
+		return
+	}
+	if count > 0 {
+		logging.Debugf("deleted %d expired OIDC authorization codes", count)
+	}
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
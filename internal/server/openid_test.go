@@ -0,0 +1,373 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAPI_OIDCClients(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	adminKey := adminAccessKey(srv)
+	userKey, _ := createAccessKey(t, srv.DB(), "someone@example.com")
+
+	var created api.CreateOIDCClientResponse
+
+	t.Run("create", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/oidc-clients", adminKey, api.CreateOIDCClientRequest{
+			Name:         "grafana",
+			RedirectURIs: []string{"https://grafana.example.com/login/generic_oauth"},
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+		assert.Equal(t, created.Name, "grafana")
+		assert.Equal(t, len(created.ClientID), 24)
+		assert.Equal(t, len(created.ClientSecret), 48)
+	})
+
+	t.Run("create requires admin", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/oidc-clients", userKey, api.CreateOIDCClientRequest{
+			Name:         "other",
+			RedirectURIs: []string{"https://other.example.com/callback"},
+		})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("create with invalid redirect URI", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/oidc-clients", adminKey, api.CreateOIDCClientRequest{
+			Name:         "other",
+			RedirectURIs: []string{"/callback"},
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("create with reserved name", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/oidc-clients", adminKey, api.CreateOIDCClientRequest{
+			Name:         "infra",
+			RedirectURIs: []string{"https://other.example.com/callback"},
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("list does not include the secret", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodGet, "/api/oidc-clients?name=grafana", adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Assert(t, !strings.Contains(resp.Body.String(), created.ClientSecret))
+
+		var clients api.ListResponse[api.OIDCClient]
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &clients))
+		assert.Equal(t, clients.Count, 1)
+		assert.Equal(t, clients.Items[0].ClientID, created.ClientID)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		uris := []string{"https://grafana.example.com/login/generic_oauth", "http://localhost:3000/login/generic_oauth"}
+		resp := doRequest(t, routes, http.MethodPut, fmt.Sprintf("/api/oidc-clients/%s", created.ID), adminKey, api.UpdateOIDCClientRequest{
+			RedirectURIs: uris,
+		})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var client api.OIDCClient
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &client))
+		assert.DeepEqual(t, client.RedirectURIs, uris)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodDelete, fmt.Sprintf("/api/oidc-clients/%s", created.ID), userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = doRequest(t, routes, http.MethodDelete, fmt.Sprintf("/api/oidc-clients/%s", created.ID), adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		resp = doRequest(t, routes, http.MethodGet, fmt.Sprintf("/api/oidc-clients/%s", created.ID), adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+	})
+}
+
+func TestOpenIDConnect(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+	db := srv.DB()
+
+	userKey, user := createAccessKey(t, db, "someone@example.com")
+	otherKey, other := createAccessKey(t, db, "other@example.com")
+	wildcardKey, wildcard := createAccessKey(t, db, "wildcard@example.com")
+
+	group := &models.Group{Name: "developers"}
+	assert.NilError(t, data.CreateGroup(db, group))
+	assert.NilError(t, data.AddUsersToGroup(db, group.ID, []uid.ID{user.ID}))
+	assert.NilError(t, data.CreateGrant(db, &models.Grant{
+		Subject:   group.PolyID(),
+		Privilege: models.BasePermissionConnect,
+		Resource:  "grafana",
+	}))
+	// a grant for a resource within the client does not grant the client
+	assert.NilError(t, data.CreateGrant(db, &models.Grant{
+		Subject:   other.PolyID(),
+		Privilege: models.BasePermissionConnect,
+		Resource:  "grafana.admin",
+	}))
+	assert.NilError(t, data.CreateGrant(db, &models.Grant{
+		Subject:   wildcard.PolyID(),
+		Privilege: models.BasePermissionConnect,
+		Resource:  "graf*",
+	}))
+
+	redirectURI := "https://grafana.example.com/login/generic_oauth"
+	client := &models.OIDCClient{Name: "grafana", RedirectURIs: []string{redirectURI}}
+	assert.NilError(t, data.CreateOIDCClient(db, client))
+
+	serve := func(t *testing.T, req *http.Request) *httptest.ResponseRecorder {
+		t.Helper()
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	authorize := func(t *testing.T, accessKey string, query url.Values) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/oidc/authorize?"+query.Encode(), nil)
+		if accessKey != "" {
+			req.Header.Set("Authorization", "Bearer "+accessKey)
+		}
+		return serve(t, req)
+	}
+
+	authorizeQuery := func() url.Values {
+		return url.Values{
+			"client_id":     {client.ClientID},
+			"redirect_uri":  {redirectURI},
+			"response_type": {"code"},
+			"scope":         {"openid email groups"},
+			"state":         {"the-state"},
+			"nonce":         {"the-nonce"},
+		}
+	}
+
+	// authorizeCode returns the code from the redirect of a successful request
+	// to the authorization endpoint.
+	authorizeCode := func(t *testing.T, query url.Values) string {
+		t.Helper()
+		resp := authorize(t, userKey, query)
+		assert.Equal(t, resp.Code, http.StatusFound, resp.Body.String())
+
+		location, err := url.Parse(resp.Header().Get("Location"))
+		assert.NilError(t, err)
+		assert.Equal(t, location.Scheme+"://"+location.Host+location.Path, redirectURI)
+		assert.Equal(t, location.Query().Get("state"), "the-state")
+		assert.Equal(t, location.Query().Get("error"), "")
+		return location.Query().Get("code")
+	}
+
+	exchange := func(t *testing.T, form url.Values, secret string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/oidc/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(client.ClientID, secret)
+		return serve(t, req)
+	}
+
+	t.Run("discovery", func(t *testing.T) {
+		resp := serve(t, httptest.NewRequest(http.MethodGet, "/.well-known/openid-configuration", nil))
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var config OpenIDConfiguration
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &config))
+		assert.Equal(t, config.Issuer, "https://example.com")
+		assert.Equal(t, config.AuthorizationEndpoint, "https://example.com/oidc/authorize")
+		assert.Equal(t, config.TokenEndpoint, "https://example.com/oidc/token")
+		assert.Equal(t, config.UserInfoEndpoint, "https://example.com/oidc/userinfo")
+		assert.Equal(t, config.JWKSURI, "https://example.com/.well-known/jwks.json")
+	})
+
+	t.Run("authorize with unknown redirect URI", func(t *testing.T) {
+		query := authorizeQuery()
+		query.Set("redirect_uri", "https://attacker.example.com/callback")
+		resp := authorize(t, userKey, query)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("authorize redirects to login", func(t *testing.T) {
+		query := authorizeQuery()
+		resp := authorize(t, "", query)
+		assert.Equal(t, resp.Code, http.StatusFound, resp.Body.String())
+
+		location, err := url.Parse(resp.Header().Get("Location"))
+		assert.NilError(t, err)
+		assert.Equal(t, location.Path, "/login")
+		assert.Equal(t, location.Query().Get("next"), "/oidc/authorize?"+query.Encode())
+	})
+
+	t.Run("authorize without a grant", func(t *testing.T) {
+		resp := authorize(t, otherKey, authorizeQuery())
+		assert.Equal(t, resp.Code, http.StatusFound, resp.Body.String())
+
+		location, err := url.Parse(resp.Header().Get("Location"))
+		assert.NilError(t, err)
+		assert.Equal(t, location.Query().Get("error"), "access_denied")
+		assert.Equal(t, location.Query().Get("state"), "the-state")
+	})
+
+	t.Run("authorize with a wildcard grant", func(t *testing.T) {
+		resp := authorize(t, wildcardKey, authorizeQuery())
+		assert.Equal(t, resp.Code, http.StatusFound, resp.Body.String())
+
+		location, err := url.Parse(resp.Header().Get("Location"))
+		assert.NilError(t, err)
+		assert.Equal(t, location.Query().Get("error"), "")
+		assert.Assert(t, location.Query().Get("code") != "")
+	})
+
+	var tokens oidcTokenResponse
+
+	t.Run("exchange code for tokens", func(t *testing.T) {
+		code := authorizeCode(t, authorizeQuery())
+
+		form := url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {redirectURI}}
+		resp := exchange(t, form, string(client.ClientSecret))
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &tokens))
+		assert.Equal(t, tokens.TokenType, "Bearer")
+		assert.Equal(t, tokens.Scope, "openid email groups")
+
+		// verify the ID token with the published keys
+		resp = serve(t, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		var keys jose.JSONWebKeySet
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &keys))
+
+		idToken, err := jwt.ParseSigned(tokens.IDToken)
+		assert.NilError(t, err)
+		var registered jwt.Claims
+		var custom struct {
+			Email  string   `json:"email"`
+			Groups []string `json:"groups"`
+			Nonce  string   `json:"nonce"`
+		}
+		key := keys.Key(idToken.Headers[0].KeyID)
+		assert.Equal(t, len(key), 1)
+		assert.NilError(t, idToken.Claims(key[0], &registered, &custom))
+
+		assert.NilError(t, registered.Validate(jwt.Expected{
+			Issuer:   "https://example.com",
+			Audience: jwt.Audience{client.ClientID},
+			Subject:  user.ID.String(),
+		}))
+		assert.Equal(t, custom.Email, "someone@example.com")
+		assert.DeepEqual(t, custom.Groups, []string{"developers"})
+		assert.Equal(t, custom.Nonce, "the-nonce")
+	})
+
+	t.Run("userinfo", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/oidc/userinfo", nil)
+		req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+		resp := serve(t, req)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var userInfo map[string]any
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &userInfo))
+		expected := map[string]any{
+			"sub":    user.ID.String(),
+			"name":   "someone@example.com",
+			"email":  "someone@example.com",
+			"groups": []any{"developers"},
+		}
+		assert.DeepEqual(t, userInfo, expected)
+	})
+
+	t.Run("userinfo does not accept ID tokens", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/oidc/userinfo", nil)
+		req.Header.Set("Authorization", "Bearer "+tokens.IDToken)
+		resp := serve(t, req)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+		assert.Equal(t, resp.Header().Get("WWW-Authenticate"), `Bearer error="invalid_token"`)
+	})
+
+	t.Run("tokens remain valid after the signing key is rotated", func(t *testing.T) {
+		_, err := data.RotateSigningKeys(db, false)
+		assert.NilError(t, err)
+		_, err = data.RotateSigningKeys(db, false)
+		assert.NilError(t, err)
+
+		// the rotated key must be kept until the tokens it signed expire
+		retiring, err := data.ListSigningKeys(db, nil, data.BySigningKeyState(models.SigningKeyStateRetiring))
+		assert.NilError(t, err)
+		assert.Assert(t, len(retiring) > 0)
+		for _, key := range retiring {
+			assert.Assert(t, !key.RetireAt.Before(time.Now().Add(oidcTokenTTL-time.Minute)))
+		}
+
+		idToken, err := jwt.ParseSigned(tokens.IDToken)
+		assert.NilError(t, err)
+		resp := serve(t, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		var keys jose.JSONWebKeySet
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &keys))
+		assert.Equal(t, len(keys.Key(idToken.Headers[0].KeyID)), 1)
+
+		req := httptest.NewRequest(http.MethodGet, "/oidc/userinfo", nil)
+		req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+		resp = serve(t, req)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+	})
+
+	t.Run("code can only be exchanged once", func(t *testing.T) {
+		code := authorizeCode(t, authorizeQuery())
+
+		form := url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {redirectURI}}
+		resp := exchange(t, form, string(client.ClientSecret))
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = exchange(t, form, string(client.ClientSecret))
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+		assert.Assert(t, strings.Contains(resp.Body.String(), "invalid_grant"))
+	})
+
+	t.Run("exchange with wrong client secret", func(t *testing.T) {
+		code := authorizeCode(t, authorizeQuery())
+
+		form := url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {redirectURI}}
+		resp := exchange(t, form, "not-the-secret")
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+		assert.Assert(t, strings.Contains(resp.Body.String(), "invalid_client"))
+	})
+
+	t.Run("exchange with PKCE", func(t *testing.T) {
+		verifier := "the-code-verifier-that-is-long-enough-for-pkce"
+		sum := sha256.Sum256([]byte(verifier))
+
+		query := authorizeQuery()
+		query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:]))
+		query.Set("code_challenge_method", "S256")
+
+		code := authorizeCode(t, query)
+		form := url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {redirectURI}, "code_verifier": {"wrong"}}
+		resp := exchange(t, form, string(client.ClientSecret))
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		code = authorizeCode(t, query)
+		form.Set("code", code)
+		form.Set("code_verifier", verifier)
+		resp = exchange(t, form, string(client.ClientSecret))
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+	})
+}
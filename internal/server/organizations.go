@@ -1,11 +1,18 @@
 package server
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
 )
 
 func (a *API) ListOrganizations(c *gin.Context, r *api.ListOrganizationsRequest) (*api.ListResponse[api.Organization], error) {
@@ -54,3 +61,194 @@ func (a *API) CreateOrganization(c *gin.Context, r *api.CreateOrganizationReques
 func (a *API) DeleteOrganization(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
 	return nil, access.DeleteOrganization(c, r.ID)
 }
+
+func (a *API) ExportOrganization(c *gin.Context, r *api.ExportOrganizationRequest) (*api.OrganizationExport, error) {
+	org, export, err := access.ExportOrganization(c, organizationIDOrSelf(c, r.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	return organizationExportToAPI(org, export), nil
+}
+
+func (a *API) ImportOrganization(c *gin.Context, r *api.ImportOrganizationRequest) (*api.ImportOrganizationResponse, error) {
+	if r.Export.Version != api.OrganizationExportVersion {
+		return nil, validate.Error{"export.version": {
+			fmt.Sprintf("version %d is not supported, must be %d", r.Export.Version, api.OrganizationExportVersion),
+		}}
+	}
+
+	export := organizationExportFromAPI(&r.Export)
+	conflicts, err := access.ImportOrganization(c, organizationIDOrSelf(c, r.ID), export, r.DryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &api.ImportOrganizationResponse{
+		DryRun: r.DryRun,
+		Imported: api.ImportCounts{
+			Providers:    len(export.Providers),
+			Users:        len(export.Identities),
+			Groups:       len(export.Groups),
+			Grants:       len(export.Grants),
+			Destinations: len(export.Destinations),
+		},
+	}
+	for _, conflict := range conflicts {
+		resp.Conflicts = append(resp.Conflicts, api.ImportConflict{
+			Kind:  importConflictKind(conflict.Table),
+			Field: conflict.Column,
+			Value: conflict.Value,
+		})
+	}
+	return resp, nil
+}
+
+func organizationIDOrSelf(c *gin.Context, id api.IDOrSelf) uid.ID {
+	if id.IsSelf {
+		return getRequestContext(c).Authenticated.Organization.ID
+	}
+	return id.ID
+}
+
+func importConflictKind(table string) string {
+	if table == "identities" {
+		return "user"
+	}
+	return strings.TrimSuffix(table, "s")
+}
+
+func organizationExportToAPI(org *models.Organization, export *data.OrganizationExport) *api.OrganizationExport {
+	result := &api.OrganizationExport{
+		Version:      api.OrganizationExportVersion,
+		Organization: *org.ToAPI(),
+		Providers:    []api.Provider{},
+		Users:        []api.User{},
+		Groups:       []api.ExportedGroup{},
+		Grants:       []api.Grant{},
+		Destinations: []api.Destination{},
+		Settings:     *export.Settings.ToAPI(),
+	}
+
+	for _, provider := range export.Providers {
+		result.Providers = append(result.Providers, *provider.ToAPI())
+	}
+	for _, identity := range export.Identities {
+		result.Users = append(result.Users, *identity.ToAPI())
+	}
+	for _, group := range export.Groups {
+		exported := api.ExportedGroup{Group: *group.ToAPI(), Users: []uid.ID{}}
+		for _, member := range group.Identities {
+			exported.Users = append(exported.Users, member.ID)
+		}
+		exported.TotalUsers = len(exported.Users)
+		result.Groups = append(result.Groups, exported)
+	}
+	for _, grant := range export.Grants {
+		result.Grants = append(result.Grants, *grant.ToAPI())
+	}
+	for _, destination := range export.Destinations {
+		result.Destinations = append(result.Destinations, *destination.ToAPI())
+	}
+	return result
+}
+
+// organizationExportFromAPI converts an export into models. The models keep
+// the IDs from the export, so that data.ImportOrganization can update the
+// references between them.
+func organizationExportFromAPI(export *api.OrganizationExport) *data.OrganizationExport {
+	result := &data.OrganizationExport{}
+
+	providersByName := map[string]models.Provider{
+		models.InternalInfraProviderName: {
+			Name: models.InternalInfraProviderName,
+			Kind: models.ProviderKindInfra,
+		},
+	}
+	for _, provider := range export.Providers {
+		kind, err := models.ParseProviderKind(provider.Kind)
+		if err != nil || kind == models.ProviderKindInfra {
+			continue
+		}
+		p := models.Provider{
+			Model:    models.Model{ID: provider.ID},
+			Name:     provider.Name,
+			URL:      provider.URL,
+			ClientID: provider.ClientID,
+			Kind:     kind,
+			AuthURL:  provider.AuthURL,
+			Scopes:   provider.Scopes,
+		}
+		result.Providers = append(result.Providers, p)
+		providersByName[p.Name] = p
+	}
+
+	identities := map[uid.ID]models.Identity{}
+	for _, user := range export.Users {
+		identity := models.Identity{Model: models.Model{ID: user.ID}, Name: user.Name}
+		for _, name := range user.ProviderNames {
+			if provider, ok := providersByName[name]; ok {
+				identity.Providers = append(identity.Providers, provider)
+			}
+		}
+		result.Identities = append(result.Identities, identity)
+		identities[identity.ID] = identity
+	}
+
+	for _, exported := range export.Groups {
+		group := models.Group{Model: models.Model{ID: exported.ID}, Name: exported.Name, ParentGroupIDs: exported.Parents}
+		for _, id := range exported.Users {
+			if identity, ok := identities[id]; ok {
+				group.Identities = append(group.Identities, identity)
+			}
+		}
+		result.Groups = append(result.Groups, group)
+	}
+
+	for _, grant := range export.Grants {
+		g := models.Grant{
+			Privilege: grant.Privilege,
+			Resource:  grant.Resource,
+			ExpiresAt: grant.Expires.Time(),
+		}
+		switch {
+		case grant.User != 0:
+			g.Subject = uid.NewIdentityPolymorphicID(grant.User)
+		case grant.Group != 0:
+			g.Subject = uid.NewGroupPolymorphicID(grant.Group)
+		default:
+			continue
+		}
+		result.Grants = append(result.Grants, g)
+	}
+
+	for _, destination := range export.Destinations {
+		result.Destinations = append(result.Destinations, models.Destination{
+			Name:          destination.Name,
+			UniqueID:      destination.UniqueID,
+			ConnectionURL: destination.Connection.URL,
+			ConnectionCA:  string(destination.Connection.CA),
+			Version:       destination.Version,
+			Resources:     destination.Resources,
+			Roles:         destination.Roles,
+		})
+	}
+
+	requirements := export.Settings.PasswordRequirements
+	result.Settings = &models.Settings{
+		LowercaseMin: requirements.LowercaseMin,
+		UppercaseMin: requirements.UppercaseMin,
+		NumberMin:    requirements.NumberMin,
+		SymbolMin:    requirements.SymbolMin,
+		LengthMin:    requirements.LengthMin,
+		RequireMFA:   export.Settings.RequireMFA,
+
+		LockoutThreshold: models.DefaultLockoutThreshold,
+		LockoutDuration:  models.DefaultLockoutDuration,
+	}
+	if lockout := export.Settings.Lockout; lockout != nil {
+		result.Settings.LockoutThreshold = lockout.Threshold
+		result.Settings.LockoutDuration = time.Duration(lockout.Duration)
+	}
+	return result
+}
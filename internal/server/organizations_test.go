@@ -7,10 +7,12 @@ import (
 	"testing"
 
 	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
 
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
 )
 
 func createOrgs(t *testing.T, db data.GormTxn, orgs ...*models.Organization) {
@@ -239,3 +241,104 @@ func TestAPI_DeleteOrganization(t *testing.T) {
 		})
 	}
 }
+
+func TestAPI_ExportImportOrganization(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	adminKey := adminAccessKey(srv)
+	userKey, user := createAccessKey(t, srv.DB(), "someone@example.com")
+
+	group := &models.Group{Name: "developers"}
+	assert.NilError(t, data.CreateGroup(srv.DB(), group))
+	assert.NilError(t, data.AddUsersToGroup(srv.DB(), group.ID, []uid.ID{user.ID}))
+	assert.NilError(t, data.CreateGrant(srv.DB(), &models.Grant{
+		Subject:   uid.NewGroupPolymorphicID(group.ID),
+		Privilege: "edit",
+		Resource:  "staging",
+	}))
+
+	var export api.OrganizationExport
+
+	t.Run("export", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodGet, "/api/organizations/self/export", adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &export))
+		assert.Equal(t, export.Version, api.OrganizationExportVersion)
+		assert.Equal(t, len(export.Groups), 1)
+		assert.Equal(t, export.Groups[0].Name, "developers")
+		assert.DeepEqual(t, export.Groups[0].Users, []uid.ID{user.ID})
+
+		var names []string
+		for _, u := range export.Users {
+			names = append(names, u.Name)
+		}
+		assert.Assert(t, is.Contains(names, "someone@example.com"))
+	})
+
+	t.Run("export requires admin", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodGet, "/api/organizations/self/export", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("import dry run reports conflicts", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/organizations/self/import?dryRun=true", adminKey,
+			api.ImportOrganizationRequest{Export: export})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var result api.ImportOrganizationResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &result))
+		assert.Assert(t, result.DryRun)
+		assert.Assert(t, is.Contains(result.Conflicts,
+			api.ImportConflict{Kind: "group", Field: "name", Value: "developers"}))
+	})
+
+	t.Run("import with conflicts", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/organizations/self/import", adminKey,
+			api.ImportOrganizationRequest{Export: export})
+		assert.Equal(t, resp.Code, http.StatusConflict, resp.Body.String())
+	})
+
+	t.Run("import with unsupported version", func(t *testing.T) {
+		invalid := export
+		invalid.Version = 100
+		resp := doRequest(t, routes, http.MethodPost, "/api/organizations/self/import", adminKey,
+			api.ImportOrganizationRequest{Export: invalid})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("import", func(t *testing.T) {
+		imported := api.OrganizationExport{
+			Version: api.OrganizationExportVersion,
+			Users:   []api.User{{ID: 1001, Name: "new@example.com"}},
+			Groups: []api.ExportedGroup{
+				{Group: api.Group{ID: 1002, Name: "operators"}, Users: []uid.ID{1001}},
+			},
+			Grants: []api.Grant{
+				{ID: 1003, Group: 1002, Privilege: "admin", Resource: "production"},
+			},
+			Settings: export.Settings,
+		}
+		resp := doRequest(t, routes, http.MethodPost, "/api/organizations/self/import", adminKey,
+			api.ImportOrganizationRequest{Export: imported})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var result api.ImportOrganizationResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &result))
+		assert.DeepEqual(t, result.Imported, api.ImportCounts{Users: 1, Groups: 1, Grants: 1})
+
+		group, err := data.GetGroup(srv.DB(), data.ByName("operators"))
+		assert.NilError(t, err)
+		grants, err := data.ListGrants(srv.DB(), nil, data.BySubject(group.PolyID()))
+		assert.NilError(t, err)
+		assert.Equal(t, len(grants), 1)
+		assert.Equal(t, grants[0].Resource, "production")
+	})
+
+	t.Run("import requires admin", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/organizations/self/import?dryRun=true", userKey,
+			api.ImportOrganizationRequest{Export: export})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/access"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
 )
 
 // caution: this endpoint is unauthenticated, do not return sensitive info
@@ -69,6 +71,23 @@ func (a *API) CreateProvider(c *gin.Context, r *api.CreateProviderRequest) (*api
 		provider.DomainAdminEmail = r.API.DomainAdminEmail
 	}
 
+	if r.LDAP != nil && r.Kind == models.ProviderKindLDAP.String() {
+		provider.URL = strings.TrimSpace(r.URL)
+		provider.BindDN = r.LDAP.BindDN
+		provider.BindPassword = models.EncryptedAtRest(r.LDAP.BindPassword)
+		provider.SearchBase = r.LDAP.SearchBase
+		provider.UserFilter = r.LDAP.UserFilter
+		provider.GroupFilter = r.LDAP.GroupFilter
+	}
+
+	if r.SAML != nil && r.Kind == models.ProviderKindSAML.String() {
+		provider.URL = strings.TrimSpace(r.URL)
+		provider.SAMLEntityID = r.SAML.EntityID
+		provider.SAMLCertificate = string(r.SAML.Certificate)
+		provider.SAMLNameAttribute = r.SAML.NameAttribute
+		provider.SAMLGroupsAttribute = r.SAML.GroupsAttribute
+	}
+
 	kind, err := models.ParseProviderKind(r.Kind)
 	if err != nil {
 		return nil, err
@@ -104,6 +123,23 @@ func (a *API) UpdateProvider(c *gin.Context, r *api.UpdateProviderRequest) (*api
 		provider.DomainAdminEmail = r.API.DomainAdminEmail
 	}
 
+	if r.LDAP != nil && r.Kind == models.ProviderKindLDAP.String() {
+		provider.URL = strings.TrimSpace(r.URL)
+		provider.BindDN = r.LDAP.BindDN
+		provider.BindPassword = models.EncryptedAtRest(r.LDAP.BindPassword)
+		provider.SearchBase = r.LDAP.SearchBase
+		provider.UserFilter = r.LDAP.UserFilter
+		provider.GroupFilter = r.LDAP.GroupFilter
+	}
+
+	if r.SAML != nil && r.Kind == models.ProviderKindSAML.String() {
+		provider.URL = strings.TrimSpace(r.URL)
+		provider.SAMLEntityID = r.SAML.EntityID
+		provider.SAMLCertificate = string(r.SAML.Certificate)
+		provider.SAMLNameAttribute = r.SAML.NameAttribute
+		provider.SAMLGroupsAttribute = r.SAML.GroupsAttribute
+	}
+
 	kind, err := models.ParseProviderKind(r.Kind)
 	if err != nil {
 		return nil, err
@@ -125,8 +161,41 @@ func (a *API) DeleteProvider(c *gin.Context, r *api.Resource) (*api.EmptyRespons
 	return nil, access.DeleteProvider(c, r.ID)
 }
 
-// setProviderInfoFromServer checks information provided by an OIDC server
+func (a *API) CreateSCIMAccessKey(c *gin.Context, r *api.CreateSCIMAccessKeyRequest) (*api.CreateAccessKeyResponse, error) {
+	expires := time.Now().UTC().Add(time.Duration(r.TTL))
+	accessKey, raw, err := access.CreateSCIMAccessKey(c, r.ID, expires)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.CreateAccessKeyResponse{
+		ID:         accessKey.ID,
+		Created:    api.Time(accessKey.CreatedAt),
+		Name:       accessKey.Name,
+		IssuedFor:  accessKey.IssuedFor,
+		ProviderID: accessKey.ProviderID,
+		Expires:    api.Time(accessKey.ExpiresAt),
+		AccessKey:  raw,
+	}, nil
+}
+
+// setProviderInfoFromServer checks information provided by an OIDC server,
+// checks that Infra can search the directory of an LDAP provider, or checks
+// the signing certificate of a SAML provider
 func (a *API) setProviderInfoFromServer(c *gin.Context, provider *models.Provider) error {
+	if provider.Kind == models.ProviderKindSAML {
+		_, err := providers.ParseSAMLCertificate(provider.SAMLCertificate)
+		return err
+	}
+
+	if provider.Kind == models.ProviderKindLDAP {
+		ldap, err := a.ldapClient(c, provider)
+		if err != nil {
+			return fmt.Errorf("%w: %s", internal.ErrBadRequest, err)
+		}
+		return ldap.Validate(c)
+	}
+
 	// create a provider client to validate the server and get its info
 	oidc, err := a.providerClient(c, provider, "http://localhost:8301")
 	if err != nil {
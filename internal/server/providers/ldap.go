@@ -0,0 +1,245 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+const ldapRequestTimeout = time.Second * 10
+
+const (
+	// DefaultLDAPUserFilter is used to find users when the provider does not
+	// have a user filter. {username} is replaced by the name used to login.
+	DefaultLDAPUserFilter = "(uid={username})"
+	// DefaultLDAPGroupFilter is used to find the groups of a user when the
+	// provider does not have a group filter. {dn} is replaced by the DN of
+	// the user.
+	DefaultLDAPGroupFilter = "(member={dn})"
+)
+
+// ErrInvalidLDAPCredentials is returned when the directory rejects the
+// password of the user, or the user does not exist.
+var ErrInvalidLDAPCredentials = errors.New("invalid username or password")
+
+// LDAPUser is a user found in an LDAP directory
+type LDAPUser struct {
+	DN     string
+	Email  string
+	Groups []string
+}
+
+type LDAPClient interface {
+	// Validate checks that the provider can bind to the directory with its
+	// bind DN and password.
+	Validate(ctx context.Context) error
+	// Authenticate binds to the directory as the user with the password, and
+	// returns the user and their groups.
+	Authenticate(ctx context.Context, username, password string) (*LDAPUser, error)
+	// GetUser returns the user with the email address and their current
+	// groups, without the password of the user.
+	GetUser(ctx context.Context, email string) (*LDAPUser, error)
+}
+
+type ldapKey struct{}
+
+func LDAPClientFromContext(ctx context.Context) LDAPClient {
+	if raw := ctx.Value(ldapKey{}); raw != nil {
+		return raw.(LDAPClient) // nolint:forcetypeassert
+	}
+	return nil
+}
+
+func WithLDAPClient(ctx context.Context, client LDAPClient) context.Context {
+	return context.WithValue(ctx, ldapKey{}, client)
+}
+
+type ldapClientImplementation struct {
+	URL          string
+	BindDN       string
+	BindPassword string
+	SearchBase   string
+	UserFilter   string
+	GroupFilter  string
+}
+
+func NewLDAPClient(provider models.Provider, bindPassword string) LDAPClient {
+	client := &ldapClientImplementation{
+		URL:          provider.URL,
+		BindDN:       provider.BindDN,
+		BindPassword: bindPassword,
+		SearchBase:   provider.SearchBase,
+		UserFilter:   provider.UserFilter,
+		GroupFilter:  provider.GroupFilter,
+	}
+
+	if !strings.Contains(client.URL, "://") {
+		client.URL = "ldaps://" + client.URL
+	}
+	if client.UserFilter == "" {
+		client.UserFilter = DefaultLDAPUserFilter
+	}
+	if client.GroupFilter == "" {
+		client.GroupFilter = DefaultLDAPGroupFilter
+	}
+	return client
+}
+
+func (l *ldapClientImplementation) Validate(ctx context.Context) error {
+	conn, err := l.connect(ctx)
+	if err != nil {
+		logging.Debugf("error validating ldap provider: %s", err)
+		return newValidationError("url")
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(l.BindDN, l.BindPassword); err != nil {
+		logging.Debugf("error validating ldap provider bind: %s", err)
+		return newValidationError("ldap.bindDN")
+	}
+
+	// the search base must exist
+	_, err = conn.Search(ldap.NewSearchRequest(l.SearchBase,
+		ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, int(ldapRequestTimeout.Seconds()), false,
+		"(objectClass=*)", []string{"dn"}, nil))
+	if err != nil {
+		logging.Debugf("error validating ldap provider search base: %s", err)
+		return newValidationError("ldap.searchBase")
+	}
+	return nil
+}
+
+func (l *ldapClientImplementation) Authenticate(ctx context.Context, username, password string) (*LDAPUser, error) {
+	// most directories accept a bind with an empty password as an
+	// unauthenticated bind, which must not be treated as a login
+	if username == "" || password == "" {
+		return nil, ErrInvalidLDAPCredentials
+	}
+
+	conn, err := l.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(l.BindDN, l.BindPassword); err != nil {
+		return nil, fmt.Errorf("bind as %s: %w", l.BindDN, err)
+	}
+
+	filter := strings.ReplaceAll(l.UserFilter, "{username}", ldap.EscapeFilter(username))
+	user, err := l.searchUser(conn, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(user.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidLDAPCredentials
+		}
+		return nil, fmt.Errorf("bind as user: %w", err)
+	}
+
+	// the user may not be allowed to search for groups, so search as the
+	// provider again
+	if err := conn.Bind(l.BindDN, l.BindPassword); err != nil {
+		return nil, fmt.Errorf("bind as %s: %w", l.BindDN, err)
+	}
+
+	user.Groups, err = l.searchGroups(conn, user.DN)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (l *ldapClientImplementation) GetUser(ctx context.Context, email string) (*LDAPUser, error) {
+	conn, err := l.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(l.BindDN, l.BindPassword); err != nil {
+		return nil, fmt.Errorf("bind as %s: %w", l.BindDN, err)
+	}
+
+	user, err := l.searchUser(conn, fmt.Sprintf("(mail=%s)", ldap.EscapeFilter(email)))
+	if err != nil {
+		return nil, err
+	}
+
+	user.Groups, err = l.searchGroups(conn, user.DN)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// connect opens a connection to the directory. Errors from connecting wrap
+// internal.ErrBadGateway.
+func (l *ldapClientImplementation) connect(ctx context.Context) (*ldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: ldapRequestTimeout}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+
+	conn, err := ldap.DialURL(l.URL, ldap.DialWithDialer(dialer))
+	if err != nil {
+		return nil, fmt.Errorf("%w: connect to ldap server: %v", internal.ErrBadGateway, err)
+	}
+	conn.SetTimeout(ldapRequestTimeout)
+	return conn, nil
+}
+
+// searchUser returns the only user that matches filter. The user must have
+// an email address, which is used as the name of the user in Infra.
+func (l *ldapClientImplementation) searchUser(conn *ldap.Conn, filter string) (*LDAPUser, error) {
+	result, err := conn.Search(ldap.NewSearchRequest(l.SearchBase,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(ldapRequestTimeout.Seconds()), false,
+		filter, []string{"mail"}, nil))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("search for user: %w", err)
+	}
+
+	switch {
+	case len(result.Entries) == 0:
+		return nil, ErrInvalidLDAPCredentials
+	case len(result.Entries) > 1:
+		return nil, fmt.Errorf("user filter matched more than one user")
+	}
+
+	entry := result.Entries[0]
+	email := entry.GetAttributeValue("mail")
+	if email == "" {
+		return nil, fmt.Errorf("user %s does not have a mail attribute", entry.DN)
+	}
+	return &LDAPUser{DN: entry.DN, Email: email}, nil
+}
+
+// searchGroups returns the common names of the groups of the user.
+func (l *ldapClientImplementation) searchGroups(conn *ldap.Conn, userDN string) ([]string, error) {
+	filter := strings.ReplaceAll(l.GroupFilter, "{dn}", ldap.EscapeFilter(userDN))
+	result, err := conn.Search(ldap.NewSearchRequest(l.SearchBase,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(ldapRequestTimeout.Seconds()), false,
+		filter, []string{"cn"}, nil))
+	if err != nil {
+		return nil, fmt.Errorf("search for groups: %w", err)
+	}
+
+	groups := make([]string, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		if name := entry.GetAttributeValue("cn"); name != "" {
+			groups = append(groups, name)
+		}
+	}
+	return groups, nil
+}
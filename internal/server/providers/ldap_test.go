@@ -0,0 +1,386 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/validate"
+)
+
+// testLDAPServer is an in-process LDAP server that supports just enough of
+// the protocol (simple bind, search with equality, presence, and/or filters)
+// to test the LDAP client.
+type testLDAPServer struct {
+	listener net.Listener
+	entries  []testLDAPEntry
+}
+
+type testLDAPEntry struct {
+	dn    string
+	attrs map[string][]string
+}
+
+func (e testLDAPEntry) get(name string) []string {
+	for key, values := range e.attrs {
+		if strings.EqualFold(key, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func setupLDAPServer(t *testing.T, entries []testLDAPEntry) *testLDAPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	srv := &testLDAPServer{listener: listener, entries: entries}
+	go srv.serve()
+	return srv
+}
+
+func (s *testLDAPServer) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *testLDAPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *testLDAPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		var responses []*ber.Packet
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			responses = append(responses, s.bind(op))
+		case ldap.ApplicationSearchRequest:
+			responses = s.search(op)
+		default: // unbind, or anything else
+			return
+		}
+
+		for _, resp := range responses {
+			envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+			envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, ""))
+			envelope.AppendChild(resp)
+			if _, err := conn.Write(envelope.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *testLDAPServer) bind(op *ber.Packet) *ber.Packet {
+	dn := op.Children[1].Value.(string) // nolint:forcetypeassert
+	password := op.Children[2].Data.String()
+
+	code := uint16(ldap.LDAPResultInvalidCredentials)
+	if entry := s.lookup(dn); entry != nil {
+		for _, p := range entry.get("userPassword") {
+			if p == password {
+				code = ldap.LDAPResultSuccess
+			}
+		}
+	}
+	return ldapResult(ldap.ApplicationBindResponse, code)
+}
+
+func (s *testLDAPServer) search(op *ber.Packet) []*ber.Packet {
+	base := op.Children[0].Value.(string) // nolint:forcetypeassert
+	scope := op.Children[1].Value.(int64) // nolint:forcetypeassert
+	filter := op.Children[6]
+	var attributes []string
+	for _, attr := range op.Children[7].Children {
+		attributes = append(attributes, attr.Value.(string)) // nolint:forcetypeassert
+	}
+
+	if s.lookup(base) == nil {
+		return []*ber.Packet{ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultNoSuchObject)}
+	}
+
+	var responses []*ber.Packet
+	for _, entry := range s.entries {
+		switch {
+		case scope == ldap.ScopeBaseObject && !strings.EqualFold(entry.dn, base):
+			continue
+		case !strings.HasSuffix(strings.ToLower(entry.dn), strings.ToLower(base)):
+			continue
+		case !matchFilter(entry, filter):
+			continue
+		}
+
+		resp := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "")
+		resp.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, ""))
+		attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+		for _, name := range attributes {
+			values := entry.get(name)
+			if len(values) == 0 {
+				continue
+			}
+			attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+			attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+			for _, value := range values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, ""))
+			}
+			attr.AppendChild(set)
+			attrs.AppendChild(attr)
+		}
+		resp.AppendChild(attrs)
+		responses = append(responses, resp)
+	}
+	return append(responses, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+}
+
+func (s *testLDAPServer) lookup(dn string) *testLDAPEntry {
+	for i := range s.entries {
+		if strings.EqualFold(s.entries[i].dn, dn) {
+			return &s.entries[i]
+		}
+	}
+	return nil
+}
+
+func matchFilter(entry testLDAPEntry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matchFilter(entry, child) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matchFilter(entry, child) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		return strings.EqualFold(filter.Data.String(), "objectClass") || len(entry.get(filter.Data.String())) > 0
+	case ldap.FilterEqualityMatch:
+		name := filter.Children[0].Data.String()
+		expected := filter.Children[1].Data.String()
+		for _, value := range entry.get(name) {
+			if strings.EqualFold(value, expected) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func ldapResult(tag ber.Tag, code uint16) *ber.Packet {
+	resp := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	resp.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), ""))
+	resp.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	resp.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return resp
+}
+
+var testLDAPEntries = []testLDAPEntry{
+	{dn: "dc=example,dc=com"},
+	{
+		dn:    "cn=infra,dc=example,dc=com",
+		attrs: map[string][]string{"userPassword": {"infra-password"}},
+	},
+	{
+		dn: "uid=alice,ou=people,dc=example,dc=com",
+		attrs: map[string][]string{
+			"uid":          {"alice"},
+			"mail":         {"alice@example.com"},
+			"userPassword": {"alice-password"},
+		},
+	},
+	{
+		dn: "uid=nomail,ou=people,dc=example,dc=com",
+		attrs: map[string][]string{
+			"uid":          {"nomail"},
+			"userPassword": {"nomail-password"},
+		},
+	},
+	{
+		dn: "cn=developers,ou=groups,dc=example,dc=com",
+		attrs: map[string][]string{
+			"cn":     {"developers"},
+			"member": {"uid=alice,ou=people,dc=example,dc=com"},
+		},
+	},
+	{
+		dn: "cn=admins,ou=groups,dc=example,dc=com",
+		attrs: map[string][]string{
+			"cn":     {"admins"},
+			"member": {"uid=bob,ou=people,dc=example,dc=com"},
+		},
+	},
+}
+
+func TestLDAPClient_Validate(t *testing.T) {
+	srv := setupLDAPServer(t, testLDAPEntries)
+
+	provider := models.Provider{
+		Kind:       models.ProviderKindLDAP,
+		URL:        srv.URL(),
+		BindDN:     "cn=infra,dc=example,dc=com",
+		SearchBase: "dc=example,dc=com",
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		err := NewLDAPClient(provider, "infra-password").Validate(context.Background())
+		assert.NilError(t, err)
+	})
+
+	t.Run("wrong bind password", func(t *testing.T) {
+		err := NewLDAPClient(provider, "wrong").Validate(context.Background())
+		var vErr validate.Error
+		assert.Assert(t, errors.As(err, &vErr), err)
+		assert.DeepEqual(t, vErr, validate.Error{"ldap.bindDN": {"invalid provider ldap.bindDN"}})
+	})
+
+	t.Run("search base does not exist", func(t *testing.T) {
+		p := provider
+		p.SearchBase = "dc=example,dc=org"
+		err := NewLDAPClient(p, "infra-password").Validate(context.Background())
+		var vErr validate.Error
+		assert.Assert(t, errors.As(err, &vErr), err)
+		assert.DeepEqual(t, vErr, validate.Error{"ldap.searchBase": {"invalid provider ldap.searchBase"}})
+	})
+
+	t.Run("server not available", func(t *testing.T) {
+		p := provider
+		p.URL = "ldap://127.0.0.1:1"
+		err := NewLDAPClient(p, "infra-password").Validate(context.Background())
+		var vErr validate.Error
+		assert.Assert(t, errors.As(err, &vErr), err)
+		assert.DeepEqual(t, vErr, validate.Error{"url": {"invalid provider url"}})
+	})
+}
+
+func TestLDAPClient_Authenticate(t *testing.T) {
+	srv := setupLDAPServer(t, testLDAPEntries)
+
+	client := NewLDAPClient(models.Provider{
+		Kind:       models.ProviderKindLDAP,
+		URL:        srv.URL(),
+		BindDN:     "cn=infra,dc=example,dc=com",
+		SearchBase: "dc=example,dc=com",
+	}, "infra-password")
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		user, err := client.Authenticate(ctx, "alice", "alice-password")
+		assert.NilError(t, err)
+		expected := &LDAPUser{
+			DN:     "uid=alice,ou=people,dc=example,dc=com",
+			Email:  "alice@example.com",
+			Groups: []string{"developers"},
+		}
+		assert.DeepEqual(t, user, expected)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		_, err := client.Authenticate(ctx, "alice", "wrong")
+		assert.ErrorIs(t, err, ErrInvalidLDAPCredentials)
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		_, err := client.Authenticate(ctx, "alice", "")
+		assert.ErrorIs(t, err, ErrInvalidLDAPCredentials)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		_, err := client.Authenticate(ctx, "mallory", "alice-password")
+		assert.ErrorIs(t, err, ErrInvalidLDAPCredentials)
+	})
+
+	t.Run("username is escaped in the filter", func(t *testing.T) {
+		_, err := client.Authenticate(ctx, "*", "alice-password")
+		assert.ErrorIs(t, err, ErrInvalidLDAPCredentials)
+	})
+
+	t.Run("user without an email address", func(t *testing.T) {
+		_, err := client.Authenticate(ctx, "nomail", "nomail-password")
+		assert.ErrorContains(t, err, "does not have a mail attribute")
+	})
+
+	t.Run("wrong bind password for provider", func(t *testing.T) {
+		client := NewLDAPClient(models.Provider{
+			URL:        srv.URL(),
+			BindDN:     "cn=infra,dc=example,dc=com",
+			SearchBase: "dc=example,dc=com",
+		}, "wrong")
+		_, err := client.Authenticate(ctx, "alice", "alice-password")
+		assert.ErrorContains(t, err, "bind as cn=infra,dc=example,dc=com")
+	})
+
+	t.Run("server not available", func(t *testing.T) {
+		client := NewLDAPClient(models.Provider{URL: "ldap://127.0.0.1:1"}, "infra-password")
+		_, err := client.Authenticate(ctx, "alice", "alice-password")
+		assert.ErrorIs(t, err, internal.ErrBadGateway)
+	})
+
+	t.Run("custom filters", func(t *testing.T) {
+		client := NewLDAPClient(models.Provider{
+			URL:         srv.URL(),
+			BindDN:      "cn=infra,dc=example,dc=com",
+			SearchBase:  "dc=example,dc=com",
+			UserFilter:  "(mail={username})",
+			GroupFilter: "(|(member={dn})(cn=admins))",
+		}, "infra-password")
+		user, err := client.Authenticate(ctx, "alice@example.com", "alice-password")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, user.Groups, []string{"developers", "admins"})
+	})
+}
+
+func TestLDAPClient_GetUser(t *testing.T) {
+	srv := setupLDAPServer(t, testLDAPEntries)
+
+	client := NewLDAPClient(models.Provider{
+		Kind:       models.ProviderKindLDAP,
+		URL:        srv.URL(),
+		BindDN:     "cn=infra,dc=example,dc=com",
+		SearchBase: "dc=example,dc=com",
+	}, "infra-password")
+
+	user, err := client.GetUser(context.Background(), "alice@example.com")
+	assert.NilError(t, err)
+	expected := &LDAPUser{
+		DN:     "uid=alice,ou=people,dc=example,dc=com",
+		Email:  "alice@example.com",
+		Groups: []string{"developers"},
+	}
+	assert.DeepEqual(t, user, expected)
+
+	_, err = client.GetUser(context.Background(), "mallory@example.com")
+	assert.ErrorIs(t, err, ErrInvalidLDAPCredentials)
+}
@@ -0,0 +1,396 @@
+package providers
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+
+	"github.com/infrahq/infra/internal/generate"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+const (
+	samlProtocolNamespace  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	samlAssertionNamespace = "urn:oasis:names:tc:SAML:2.0:assertion"
+	samlMetadataNamespace  = "urn:oasis:names:tc:SAML:2.0:metadata"
+
+	samlBindingHTTPPost     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	samlStatusSuccess       = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	samlConfirmationBearer  = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+	samlNameIDFormatEmail   = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	samlNameIDFormatDefault = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+
+	// samlClockSkew is the difference allowed between the clock of the
+	// identity provider and the clock of the server.
+	samlClockSkew = 3 * time.Minute
+
+	// DefaultSAMLGroupsAttribute is the attribute used to find the groups of
+	// the user when the provider does not have a groups attribute.
+	DefaultSAMLGroupsAttribute = "groups"
+)
+
+// ErrInvalidSAMLResponse is returned when a SAML response can not be used to
+// login, because it is not signed by the identity provider, has expired, or
+// was meant for a different service provider.
+var ErrInvalidSAMLResponse = errors.New("invalid saml response")
+
+// SAMLUser is the user from a SAML assertion
+type SAMLUser struct {
+	Name   string
+	Groups []string
+
+	// AssertionID is the ID of the assertion, which must only be used once.
+	AssertionID string
+	// AssertionExpires is the time after which the assertion is no longer
+	// accepted.
+	AssertionExpires time.Time
+}
+
+// SAMLServiceProvider is Infra acting as the SAML service provider of a
+// provider with kind saml.
+type SAMLServiceProvider struct {
+	// EntityID is the entity ID of Infra, which is also the URL of its metadata
+	EntityID string
+	// ACSURL is the URL of the assertion consumer service, where the identity
+	// provider sends responses
+	ACSURL string
+
+	IdPEntityID     string
+	IdPSSOURL       string
+	IdPCertificate  *x509.Certificate
+	NameAttribute   string
+	GroupsAttribute string
+
+	now func() time.Time
+}
+
+// NewSAMLServiceProvider returns the service provider for the provider. baseURL
+// is the URL of the Infra server used by browsers, like https://infra.example.com.
+func NewSAMLServiceProvider(provider models.Provider, baseURL string) (*SAMLServiceProvider, error) {
+	cert, err := ParseSAMLCertificate(provider.SAMLCertificate)
+	if err != nil {
+		return nil, err
+	}
+
+	sp := &SAMLServiceProvider{
+		EntityID:        fmt.Sprintf("%s/saml/%s/metadata", baseURL, provider.ID),
+		ACSURL:          fmt.Sprintf("%s/saml/%s/acs", baseURL, provider.ID),
+		IdPEntityID:     provider.SAMLEntityID,
+		IdPSSOURL:       provider.URL,
+		IdPCertificate:  cert,
+		NameAttribute:   provider.SAMLNameAttribute,
+		GroupsAttribute: provider.SAMLGroupsAttribute,
+		now:             time.Now,
+	}
+	if sp.GroupsAttribute == "" {
+		sp.GroupsAttribute = DefaultSAMLGroupsAttribute
+	}
+	return sp, nil
+}
+
+// ParseSAMLCertificate parses the PEM encoded signing certificate of a SAML
+// identity provider.
+func ParseSAMLCertificate(raw string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(raw))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, newValidationError("saml.certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, newValidationError("saml.certificate")
+	}
+	return cert, nil
+}
+
+// Metadata returns the metadata of the service provider, which is used to
+// register Infra with the identity provider.
+func (sp *SAMLServiceProvider) Metadata() ([]byte, error) {
+	doc := etree.NewDocument()
+	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
+
+	entity := doc.CreateElement("md:EntityDescriptor")
+	entity.CreateAttr("xmlns:md", samlMetadataNamespace)
+	entity.CreateAttr("entityID", sp.EntityID)
+
+	descriptor := entity.CreateElement("md:SPSSODescriptor")
+	descriptor.CreateAttr("AuthnRequestsSigned", "false")
+	descriptor.CreateAttr("WantAssertionsSigned", "true")
+	descriptor.CreateAttr("protocolSupportEnumeration", samlProtocolNamespace)
+
+	descriptor.CreateElement("md:NameIDFormat").SetText(samlNameIDFormatEmail)
+
+	acs := descriptor.CreateElement("md:AssertionConsumerService")
+	acs.CreateAttr("Binding", samlBindingHTTPPost)
+	acs.CreateAttr("Location", sp.ACSURL)
+	acs.CreateAttr("index", "0")
+	acs.CreateAttr("isDefault", "true")
+
+	doc.Indent(2)
+	return doc.WriteToBytes()
+}
+
+// AuthnRequestURL returns the URL that starts a login at the identity
+// provider, using the HTTP-Redirect binding. The identity provider sends
+// relayState back to the assertion consumer service with its response.
+func (sp *SAMLServiceProvider) AuthnRequestURL(relayState string) (string, error) {
+	id, err := generate.CryptoRandom(32, generate.CharsetAlphaNumeric)
+	if err != nil {
+		return "", err
+	}
+
+	doc := etree.NewDocument()
+	req := doc.CreateElement("samlp:AuthnRequest")
+	req.CreateAttr("xmlns:samlp", samlProtocolNamespace)
+	req.CreateAttr("xmlns:saml", samlAssertionNamespace)
+	// IDs must not start with a number
+	req.CreateAttr("ID", "_"+id)
+	req.CreateAttr("Version", "2.0")
+	req.CreateAttr("IssueInstant", sp.now().UTC().Format(time.RFC3339))
+	req.CreateAttr("Destination", sp.IdPSSOURL)
+	req.CreateAttr("AssertionConsumerServiceURL", sp.ACSURL)
+	req.CreateAttr("ProtocolBinding", samlBindingHTTPPost)
+	req.CreateElement("saml:Issuer").SetText(sp.EntityID)
+
+	policy := req.CreateElement("samlp:NameIDPolicy")
+	policy.CreateAttr("Format", samlNameIDFormatDefault)
+	policy.CreateAttr("AllowCreate", "true")
+
+	raw, err := doc.WriteToBytes()
+	if err != nil {
+		return "", err
+	}
+
+	// the HTTP-Redirect binding uses DEFLATE without a zlib header
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(sp.IdPSSOURL)
+	if err != nil {
+		return "", fmt.Errorf("parse sso url: %w", err)
+	}
+	query := u.Query()
+	query.Set("SAMLRequest", base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if relayState != "" {
+		query.Set("RelayState", relayState)
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+type samlAssertion struct {
+	ID      string `xml:"ID,attr"`
+	Issuer  string `xml:"Issuer"`
+	Subject struct {
+		NameID        string `xml:"NameID"`
+		Confirmations []struct {
+			Method string `xml:"Method,attr"`
+			Data   struct {
+				Recipient    string    `xml:"Recipient,attr"`
+				NotOnOrAfter time.Time `xml:"NotOnOrAfter,attr"`
+			} `xml:"SubjectConfirmationData"`
+		} `xml:"SubjectConfirmation"`
+	} `xml:"Subject"`
+	Conditions struct {
+		NotBefore    time.Time `xml:"NotBefore,attr"`
+		NotOnOrAfter time.Time `xml:"NotOnOrAfter,attr"`
+		Audiences    []string  `xml:"AudienceRestriction>Audience"`
+	} `xml:"Conditions"`
+	Attributes []struct {
+		Name         string   `xml:"Name,attr"`
+		FriendlyName string   `xml:"FriendlyName,attr"`
+		Values       []string `xml:"AttributeValue"`
+	} `xml:"AttributeStatement>Attribute"`
+}
+
+// ParseResponse verifies the base64 encoded response sent to the assertion
+// consumer service, and returns the user from its assertion. Either the
+// response or the assertion must be signed by the identity provider.
+func (sp *SAMLServiceProvider) ParseResponse(encoded string) (*SAMLUser, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode: %v", ErrInvalidSAMLResponse, err)
+	}
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(raw); err != nil {
+		return nil, fmt.Errorf("%w: parse: %v", ErrInvalidSAMLResponse, err)
+	}
+
+	response := doc.Root()
+	if response == nil || response.Tag != "Response" || response.NamespaceURI() != samlProtocolNamespace {
+		return nil, fmt.Errorf("%w: expected a Response element", ErrInvalidSAMLResponse)
+	}
+
+	if dest := response.SelectAttrValue("Destination", ""); dest != "" && dest != sp.ACSURL {
+		return nil, fmt.Errorf("%w: destination %q does not match %q", ErrInvalidSAMLResponse, dest, sp.ACSURL)
+	}
+
+	status := response.FindElement("./Status/StatusCode")
+	if status == nil || status.SelectAttrValue("Value", "") != samlStatusSuccess {
+		return nil, fmt.Errorf("%w: login failed at the identity provider", ErrInvalidSAMLResponse)
+	}
+
+	if response.FindElement("./EncryptedAssertion") != nil {
+		return nil, fmt.Errorf("%w: encrypted assertions are not supported", ErrInvalidSAMLResponse)
+	}
+
+	assertionEl, err := sp.verifiedAssertion(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var assertion samlAssertion
+	if err := etreeutils.NSUnmarshalElement(etreeutils.NewDefaultNSContext(), assertionEl, &assertion); err != nil {
+		return nil, fmt.Errorf("%w: parse assertion: %v", ErrInvalidSAMLResponse, err)
+	}
+
+	expires, err := sp.validateAssertion(assertion)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &SAMLUser{
+		Name:             assertion.Subject.NameID,
+		AssertionID:      assertion.ID,
+		AssertionExpires: expires,
+	}
+	for _, attr := range assertion.Attributes {
+		switch {
+		case sp.NameAttribute != "" && (attr.Name == sp.NameAttribute || attr.FriendlyName == sp.NameAttribute):
+			if len(attr.Values) > 0 {
+				user.Name = attr.Values[0]
+			}
+		case attr.Name == sp.GroupsAttribute || attr.FriendlyName == sp.GroupsAttribute:
+			user.Groups = append(user.Groups, attr.Values...)
+		}
+	}
+
+	if user.Name == "" {
+		return nil, fmt.Errorf("%w: assertion does not have a name for the user", ErrInvalidSAMLResponse)
+	}
+	return user, nil
+}
+
+// verifiedAssertion returns the only assertion of the response, after
+// verifying the signature of the response or of the assertion. Only the
+// element returned by the signature validation is used, so that unsigned
+// elements can not be wrapped around signed ones.
+func (sp *SAMLServiceProvider) verifiedAssertion(response *etree.Element) (*etree.Element, error) {
+	validator := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{
+		Roots: []*x509.Certificate{sp.IdPCertificate},
+	})
+
+	responseSigned := false
+	err := etreeutils.NSFindChildrenIterateCtx(etreeutils.NewDefaultNSContext(), response, dsig.Namespace, dsig.SignatureTag,
+		func(ctx etreeutils.NSContext, el *etree.Element) error {
+			responseSigned = true
+			return etreeutils.ErrTraversalHalted
+		})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSAMLResponse, err)
+	}
+
+	if responseSigned {
+		verified, err := validator.Validate(response)
+		if err != nil {
+			return nil, fmt.Errorf("%w: response signature: %v", ErrInvalidSAMLResponse, err)
+		}
+		response = verified
+	}
+
+	var assertions []*etree.Element
+	err = etreeutils.NSFindChildrenIterateCtx(etreeutils.NewDefaultNSContext(), response, samlAssertionNamespace, "Assertion",
+		func(ctx etreeutils.NSContext, el *etree.Element) error {
+			detached, err := etreeutils.NSDetatch(ctx, el)
+			if err != nil {
+				return err
+			}
+			assertions = append(assertions, detached)
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSAMLResponse, err)
+	}
+	if len(assertions) != 1 {
+		return nil, fmt.Errorf("%w: expected one assertion, found %d", ErrInvalidSAMLResponse, len(assertions))
+	}
+
+	assertion := assertions[0]
+	if responseSigned && assertion.FindElement("./Signature") == nil {
+		return assertion, nil
+	}
+
+	verified, err := validator.Validate(assertion)
+	if err != nil {
+		return nil, fmt.Errorf("%w: assertion signature: %v", ErrInvalidSAMLResponse, err)
+	}
+	return verified, nil
+}
+
+// validateAssertion returns the time after which the assertion is no longer
+// accepted, which is the latest expiry of its valid bearer confirmations.
+func (sp *SAMLServiceProvider) validateAssertion(assertion samlAssertion) (time.Time, error) {
+	now := sp.now()
+
+	if assertion.ID == "" {
+		return time.Time{}, fmt.Errorf("%w: assertion does not have an ID", ErrInvalidSAMLResponse)
+	}
+	if assertion.Issuer != sp.IdPEntityID {
+		return time.Time{}, fmt.Errorf("%w: issuer %q does not match %q", ErrInvalidSAMLResponse, assertion.Issuer, sp.IdPEntityID)
+	}
+
+	conditions := assertion.Conditions
+	if !conditions.NotBefore.IsZero() && now.Add(samlClockSkew).Before(conditions.NotBefore) {
+		return time.Time{}, fmt.Errorf("%w: assertion is not valid yet", ErrInvalidSAMLResponse)
+	}
+	if !conditions.NotOnOrAfter.IsZero() && !now.Add(-samlClockSkew).Before(conditions.NotOnOrAfter) {
+		return time.Time{}, fmt.Errorf("%w: assertion has expired", ErrInvalidSAMLResponse)
+	}
+	if !containsString(conditions.Audiences, sp.EntityID) {
+		return time.Time{}, fmt.Errorf("%w: audience does not include %q", ErrInvalidSAMLResponse, sp.EntityID)
+	}
+
+	var expires time.Time
+	for _, confirmation := range assertion.Subject.Confirmations {
+		data := confirmation.Data
+		switch {
+		case confirmation.Method != samlConfirmationBearer:
+		case data.Recipient != sp.ACSURL:
+		case data.NotOnOrAfter.IsZero() || !now.Add(-samlClockSkew).Before(data.NotOnOrAfter):
+		case data.NotOnOrAfter.After(expires):
+			expires = data.NotOnOrAfter
+		}
+	}
+	if expires.IsZero() {
+		return time.Time{}, fmt.Errorf("%w: no valid bearer subject confirmation", ErrInvalidSAMLResponse)
+	}
+	return expires.Add(samlClockSkew), nil
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
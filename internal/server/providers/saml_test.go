@@ -0,0 +1,261 @@
+package providers
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/testing/saml"
+	"github.com/infrahq/infra/uid"
+)
+
+var testSAMLAttributes = map[string][]string{
+	"email":  {"alice.smith@example.com"},
+	"groups": {"developers", "Everyone"},
+}
+
+func newTestSAMLServiceProvider(t *testing.T, idp *saml.IdP) *SAMLServiceProvider {
+	t.Helper()
+	sp, err := NewSAMLServiceProvider(models.Provider{
+		Model:           models.Model{ID: uid.ID(1234)},
+		Kind:            models.ProviderKindSAML,
+		URL:             "https://idp.example.com/sso",
+		SAMLEntityID:    idp.EntityID,
+		SAMLCertificate: idp.CertificatePEM,
+	}, "https://infra.example.com")
+	assert.NilError(t, err)
+	return sp
+}
+
+func TestNewSAMLServiceProvider(t *testing.T) {
+	idp := saml.NewIdP(t)
+	sp := newTestSAMLServiceProvider(t, idp)
+
+	assert.Equal(t, sp.EntityID, "https://infra.example.com/saml/"+uid.ID(1234).String()+"/metadata")
+	assert.Equal(t, sp.ACSURL, "https://infra.example.com/saml/"+uid.ID(1234).String()+"/acs")
+	assert.Equal(t, sp.GroupsAttribute, DefaultSAMLGroupsAttribute)
+
+	_, err := NewSAMLServiceProvider(models.Provider{SAMLCertificate: "not a certificate"}, "https://infra.example.com")
+	assert.ErrorContains(t, err, "invalid provider saml.certificate")
+}
+
+func TestSAMLServiceProvider_Metadata(t *testing.T) {
+	sp := newTestSAMLServiceProvider(t, saml.NewIdP(t))
+
+	raw, err := sp.Metadata()
+	assert.NilError(t, err)
+
+	doc := etree.NewDocument()
+	assert.NilError(t, doc.ReadFromBytes(raw))
+	assert.Equal(t, doc.Root().SelectAttrValue("entityID", ""), sp.EntityID)
+
+	acs := doc.FindElement("//AssertionConsumerService")
+	assert.Assert(t, acs != nil)
+	assert.Equal(t, acs.SelectAttrValue("Location", ""), sp.ACSURL)
+	assert.Equal(t, acs.SelectAttrValue("Binding", ""), samlBindingHTTPPost)
+}
+
+func TestSAMLServiceProvider_AuthnRequestURL(t *testing.T) {
+	sp := newTestSAMLServiceProvider(t, saml.NewIdP(t))
+
+	raw, err := sp.AuthnRequestURL("/destinations")
+	assert.NilError(t, err)
+
+	u, err := url.Parse(raw)
+	assert.NilError(t, err)
+	assert.Equal(t, u.Host, "idp.example.com")
+	assert.Equal(t, u.Path, "/sso")
+	assert.Equal(t, u.Query().Get("RelayState"), "/destinations")
+
+	compressed, err := base64.StdEncoding.DecodeString(u.Query().Get("SAMLRequest"))
+	assert.NilError(t, err)
+	inflated, err := io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+	assert.NilError(t, err)
+
+	doc := etree.NewDocument()
+	assert.NilError(t, doc.ReadFromBytes(inflated))
+	req := doc.Root()
+	assert.Equal(t, req.Tag, "AuthnRequest")
+	assert.Assert(t, strings.HasPrefix(req.SelectAttrValue("ID", ""), "_"))
+	assert.Equal(t, req.SelectAttrValue("Destination", ""), "https://idp.example.com/sso")
+	assert.Equal(t, req.SelectAttrValue("AssertionConsumerServiceURL", ""), sp.ACSURL)
+	assert.Equal(t, req.FindElement("./Issuer").Text(), sp.EntityID)
+}
+
+func TestSAMLServiceProvider_ParseResponse(t *testing.T) {
+	idp := saml.NewIdP(t)
+	sp := newTestSAMLServiceProvider(t, idp)
+	// responses use seconds, and the time zone of the identity provider
+	notOnOrAfter := time.Now().UTC().Add(5 * time.Minute).Truncate(time.Second)
+
+	type testCase struct {
+		name        string
+		sp          func(t *testing.T) *SAMLServiceProvider
+		opts        saml.ResponseOptions
+		expected    *SAMLUser
+		expectedErr string
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		sp := sp
+		if tc.sp != nil {
+			sp = tc.sp(t)
+		}
+		tc.opts.Destination = sp.ACSURL
+		if tc.opts.Audience == "" {
+			tc.opts.Audience = sp.EntityID
+		}
+		tc.opts.Attributes = testSAMLAttributes
+		user, err := sp.ParseResponse(idp.Response(t, tc.opts))
+		if tc.expectedErr != "" {
+			assert.ErrorIs(t, err, ErrInvalidSAMLResponse)
+			assert.ErrorContains(t, err, tc.expectedErr)
+			return
+		}
+		assert.NilError(t, err)
+		tc.expected.AssertionID = tc.opts.AssertionID
+		tc.expected.AssertionExpires = notOnOrAfter.Add(samlClockSkew)
+		assert.DeepEqual(t, user, tc.expected)
+	}
+
+	testCases := []testCase{
+		{
+			name:     "signed assertion",
+			opts:     saml.ResponseOptions{AssertionID: "_signed-assertion", NotOnOrAfter: notOnOrAfter},
+			expected: &SAMLUser{Name: "alice@example.com", Groups: []string{"developers", "Everyone"}},
+		},
+		{
+			name:     "signed response",
+			opts:     saml.ResponseOptions{AssertionID: "_signed-response", NotOnOrAfter: notOnOrAfter, SignResponse: true},
+			expected: &SAMLUser{Name: "alice@example.com", Groups: []string{"developers", "Everyone"}},
+		},
+		{
+			name: "name from an attribute",
+			sp: func(t *testing.T) *SAMLServiceProvider {
+				sp := newTestSAMLServiceProvider(t, idp)
+				sp.NameAttribute = "email"
+				sp.GroupsAttribute = "roles"
+				return sp
+			},
+			opts:     saml.ResponseOptions{AssertionID: "_name-attribute", NotOnOrAfter: notOnOrAfter},
+			expected: &SAMLUser{Name: "alice.smith@example.com"},
+		},
+		{
+			name: "not signed",
+			opts: saml.ResponseOptions{
+				Modify: func(response *etree.Element) {
+					assertion := response.FindElement("./Assertion")
+					assertion.RemoveChild(assertion.FindElement("./Signature"))
+				},
+			},
+			expectedErr: "assertion signature",
+		},
+		{
+			name: "signed by another key",
+			sp: func(t *testing.T) *SAMLServiceProvider {
+				sp := newTestSAMLServiceProvider(t, idp)
+				other := saml.NewIdP(t)
+				sp.IdPCertificate, _ = ParseSAMLCertificate(other.CertificatePEM)
+				return sp
+			},
+			expectedErr: "assertion signature",
+		},
+		{
+			name: "modified after signing",
+			opts: saml.ResponseOptions{
+				Modify: func(response *etree.Element) {
+					response.FindElement("./Assertion/Subject/NameID").SetText("admin@example.com")
+				},
+			},
+			expectedErr: "assertion signature",
+		},
+		{
+			name: "unsigned assertion added to a signed response",
+			opts: saml.ResponseOptions{
+				Modify: func(response *etree.Element) {
+					injected := response.FindElement("./Assertion").Copy()
+					injected.RemoveChild(injected.FindElement("./Signature"))
+					injected.FindElement("./Subject/NameID").SetText("admin@example.com")
+					response.InsertChildAt(0, injected)
+				},
+			},
+			expectedErr: "expected one assertion, found 2",
+		},
+		{
+			name: "signed assertion moved into a wrapper",
+			opts: saml.ResponseOptions{
+				Modify: func(response *etree.Element) {
+					signed := response.FindElement("./Assertion")
+					response.RemoveChild(signed)
+
+					injected := signed.Copy()
+					injected.RemoveChild(injected.FindElement("./Signature"))
+					injected.FindElement("./Subject/NameID").SetText("admin@example.com")
+					injected.CreateElement("saml:Advice").AddChild(signed)
+					response.AddChild(injected)
+				},
+			},
+			expectedErr: "assertion signature",
+		},
+		{
+			name:        "wrong issuer",
+			opts:        saml.ResponseOptions{Issuer: "https://evil.example.com"},
+			expectedErr: "issuer",
+		},
+		{
+			name:        "wrong audience",
+			opts:        saml.ResponseOptions{Audience: "https://other.example.com"},
+			expectedErr: "audience",
+		},
+		{
+			name:        "wrong recipient",
+			opts:        saml.ResponseOptions{Recipient: "https://other.example.com/acs"},
+			expectedErr: "no valid bearer subject confirmation",
+		},
+		{
+			name:        "expired",
+			opts:        saml.ResponseOptions{NotOnOrAfter: time.Now().Add(-10 * time.Minute)},
+			expectedErr: "assertion has expired",
+		},
+		{
+			name:        "failed status",
+			opts:        saml.ResponseOptions{Status: "urn:oasis:names:tc:SAML:2.0:status:Requester"},
+			expectedErr: "login failed at the identity provider",
+		},
+		{
+			name: "wrong destination",
+			opts: saml.ResponseOptions{
+				Modify: func(response *etree.Element) {
+					response.CreateAttr("Destination", "https://other.example.com/acs")
+				},
+			},
+			expectedErr: "destination",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+
+	t.Run("not base64", func(t *testing.T) {
+		_, err := sp.ParseResponse("%%%")
+		assert.ErrorIs(t, err, ErrInvalidSAMLResponse)
+	})
+
+	t.Run("not a response", func(t *testing.T) {
+		_, err := sp.ParseResponse(base64.StdEncoding.EncodeToString([]byte("<foo/>")))
+		assert.Assert(t, is.ErrorContains(err, "expected a Response element"))
+	})
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/internal/server/providers"
+	"github.com/infrahq/infra/internal/testing/saml"
 )
 
 func TestAPI_ListProviders(t *testing.T) {
@@ -137,6 +138,7 @@ func TestAPI_DeleteProvider(t *testing.T) {
 func TestAPI_CreateProvider(t *testing.T) {
 	srv := setupServer(t, withAdminUser)
 	routes := srv.GenerateRoutes()
+	idp := saml.NewIdP(t)
 
 	type testCase struct {
 		name     string
@@ -259,7 +261,7 @@ func TestAPI_CreateProvider(t *testing.T) {
 				assert.NilError(t, err)
 
 				expected := []api.FieldError{
-					{FieldName: "kind", Errors: []string{"must be one of (oidc, okta, azure, google)"}},
+					{FieldName: "kind", Errors: []string{"must be one of (oidc, okta, azure, google, ldap, saml)"}},
 				}
 				assert.DeepEqual(t, respBody.FieldErrors, expected)
 			},
@@ -303,6 +305,110 @@ func TestAPI_CreateProvider(t *testing.T) {
 				assert.DeepEqual(t, respBody, expected)
 			},
 		},
+		{
+			name: "ldap provider missing ldap config",
+			body: api.CreateProviderRequest{
+				Name: "ad",
+				URL:  "ldaps://ldap.example.com",
+				Kind: string(models.ProviderKindLDAP),
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+				respBody := &api.Error{}
+				err := json.Unmarshal(resp.Body.Bytes(), respBody)
+				assert.NilError(t, err)
+
+				expected := []api.FieldError{
+					{FieldName: "ldap", Errors: []string{"is required"}},
+				}
+				assert.DeepEqual(t, respBody.FieldErrors, expected)
+			},
+		},
+		{
+			name: "valid ldap provider",
+			body: api.CreateProviderRequest{
+				Name: "ad",
+				URL:  "ldaps://ldap.example.com",
+				Kind: string(models.ProviderKindLDAP),
+				LDAP: &api.ProviderLDAP{
+					BindDN:       "cn=infra,dc=example,dc=com",
+					BindPassword: "bind-password",
+					SearchBase:   "dc=example,dc=com",
+					UserFilter:   "(sAMAccountName={username})",
+				},
+			},
+			setup: func(t *testing.T, req *http.Request) {
+				ctx := providers.WithLDAPClient(req.Context(), &fakeLDAPImplementation{})
+				*req = *req.WithContext(ctx)
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+				respBody := &api.Provider{}
+				err := json.Unmarshal(resp.Body.Bytes(), respBody)
+				assert.NilError(t, err)
+				assert.Equal(t, respBody.Kind, string(models.ProviderKindLDAP))
+				assert.Equal(t, respBody.URL, "ldaps://ldap.example.com")
+
+				provider, err := data.GetProvider(srv.DB(), data.ByID(respBody.ID))
+				assert.NilError(t, err)
+				assert.Equal(t, provider.BindDN, "cn=infra,dc=example,dc=com")
+				assert.Equal(t, string(provider.BindPassword), "bind-password")
+				assert.Equal(t, provider.SearchBase, "dc=example,dc=com")
+				assert.Equal(t, provider.UserFilter, "(sAMAccountName={username})")
+			},
+		},
+		{
+			name: "saml provider with invalid certificate",
+			body: api.CreateProviderRequest{
+				Name: "adfs",
+				URL:  "https://idp.example.com/sso",
+				Kind: string(models.ProviderKindSAML),
+				SAML: &api.ProviderSAML{
+					EntityID:    "https://idp.example.com/metadata",
+					Certificate: "not a certificate",
+				},
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+				respBody := &api.Error{}
+				err := json.Unmarshal(resp.Body.Bytes(), respBody)
+				assert.NilError(t, err)
+
+				expected := []api.FieldError{
+					{FieldName: "saml.certificate", Errors: []string{"invalid provider saml.certificate"}},
+				}
+				assert.DeepEqual(t, respBody.FieldErrors, expected)
+			},
+		},
+		{
+			name: "valid saml provider",
+			body: api.CreateProviderRequest{
+				Name: "adfs",
+				URL:  "https://idp.example.com/sso",
+				Kind: string(models.ProviderKindSAML),
+				SAML: &api.ProviderSAML{
+					EntityID:    idp.EntityID,
+					Certificate: api.PEM(idp.CertificatePEM),
+				},
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+				respBody := &api.Provider{}
+				err := json.Unmarshal(resp.Body.Bytes(), respBody)
+				assert.NilError(t, err)
+				assert.Equal(t, respBody.Kind, string(models.ProviderKindSAML))
+				assert.Equal(t, respBody.URL, "https://idp.example.com/sso")
+
+				provider, err := data.GetProvider(srv.DB(), data.ByID(respBody.ID))
+				assert.NilError(t, err)
+				assert.Equal(t, provider.SAMLEntityID, idp.EntityID)
+				assert.Equal(t, provider.SAMLCertificate, idp.CertificatePEM)
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -417,7 +523,7 @@ func TestAPI_UpdateProvider(t *testing.T) {
 				assert.NilError(t, err)
 
 				expected := []api.FieldError{
-					{FieldName: "kind", Errors: []string{"must be one of (oidc, okta, azure, google)"}},
+					{FieldName: "kind", Errors: []string{"must be one of (oidc, okta, azure, google, ldap, saml)"}},
 				}
 				assert.DeepEqual(t, respBody.FieldErrors, expected)
 			},
@@ -498,3 +604,26 @@ func (m *fakeOIDCImplementation) GetUserInfo(_ context.Context, _ *models.Provid
 	}
 	return &providers.UserInfoClaims{}, nil
 }
+
+// fakeLDAPImplementation is a fake ldap directory
+type fakeLDAPImplementation struct {
+	User *providers.LDAPUser
+}
+
+func (m *fakeLDAPImplementation) Validate(_ context.Context) error {
+	return nil
+}
+
+func (m *fakeLDAPImplementation) Authenticate(_ context.Context, _, password string) (*providers.LDAPUser, error) {
+	if m.User == nil || password != "password" {
+		return nil, providers.ErrInvalidLDAPCredentials
+	}
+	return m.User, nil
+}
+
+func (m *fakeLDAPImplementation) GetUser(_ context.Context, _ string) (*providers.LDAPUser, error) {
+	if m.User == nil {
+		return nil, providers.ErrInvalidLDAPCredentials
+	}
+	return m.User, nil
+}
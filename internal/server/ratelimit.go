@@ -0,0 +1,110 @@
+package server
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+
+	"github.com/infrahq/infra/internal"
+)
+
+const (
+	// passwordRateLimit is the sustained rate of requests allowed from a
+	// single IP address to the routes that check passwords.
+	passwordRateLimit = rate.Limit(2)
+	// passwordRateBurst is the number of requests allowed from a single IP
+	// address before the rate limit applies.
+	passwordRateBurst = 60
+	// deviceFlowRateLimit is the sustained rate of requests allowed from a
+	// single IP address to the device flow routes. A client polls once every
+	// interval, so this allows a few device logins at the same time.
+	deviceFlowRateLimit = rate.Limit(1)
+	// deviceFlowRateBurst is the number of requests allowed from a single IP
+	// address to the device flow routes before the rate limit applies.
+	deviceFlowRateBurst = 30
+)
+
+// ipRateLimiter limits the rate of requests by the IP address of the client.
+type ipRateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu      sync.Mutex
+	clients map[string]*rateLimitedClient
+	// lastCleanup is the last time that idle clients were removed.
+	lastCleanup time.Time
+}
+
+type rateLimitedClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newIPRateLimiter(limit rate.Limit, burst int) *ipRateLimiter {
+	return &ipRateLimiter{
+		limit:       limit,
+		burst:       burst,
+		clients:     map[string]*rateLimitedClient{},
+		lastCleanup: time.Now(),
+	}
+}
+
+// reserve returns how long the client with ip must wait before its next
+// request is allowed, or zero if the request is allowed now.
+func (l *ipRateLimiter) reserve(ip string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cleanup(now)
+
+	client, ok := l.clients[ip]
+	if !ok {
+		client = &rateLimitedClient{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[ip] = client
+	}
+	client.lastSeen = now
+
+	if client.limiter.AllowN(now, 1) {
+		return 0
+	}
+	reservation := client.limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	// the request is rejected, so don't count it against the client
+	reservation.CancelAt(now)
+	return delay
+}
+
+// cleanup removes clients that have not made a request for long enough that
+// their limiter would be full again, so that the map does not grow without
+// bound.
+func (l *ipRateLimiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < time.Minute {
+		return
+	}
+	l.lastCleanup = now
+
+	refill := time.Duration(float64(l.burst) / float64(l.limit) * float64(time.Second))
+	for ip, client := range l.clients {
+		if now.Sub(client.lastSeen) > refill {
+			delete(l.clients, ip)
+		}
+	}
+}
+
+// rateLimitMiddleware rejects requests from clients that have exceeded the
+// rate limit of limiter.
+func rateLimitMiddleware(limiter *ipRateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		delay := limiter.reserve(c.ClientIP(), time.Now())
+		if delay > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			sendAPIError(c, internal.ErrTooManyRequests)
+			return
+		}
+		c.Next()
+	}
+}
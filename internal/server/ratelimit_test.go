@@ -0,0 +1,88 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+	"gotest.tools/v3/assert"
+)
+
+func TestIPRateLimiter_Reserve(t *testing.T) {
+	limiter := newIPRateLimiter(rate.Limit(1), 2)
+	now := time.Now()
+
+	assert.Equal(t, limiter.reserve("10.0.0.1", now), time.Duration(0))
+	assert.Equal(t, limiter.reserve("10.0.0.1", now), time.Duration(0))
+
+	delay := limiter.reserve("10.0.0.1", now)
+	assert.Equal(t, delay, time.Second)
+
+	// rejected requests are not counted
+	assert.Equal(t, limiter.reserve("10.0.0.1", now), time.Second)
+
+	// other clients are not limited
+	assert.Equal(t, limiter.reserve("10.0.0.2", now), time.Duration(0))
+
+	// tokens are added back at the limit
+	assert.Equal(t, limiter.reserve("10.0.0.1", now.Add(time.Second)), time.Duration(0))
+
+	t.Run("idle clients are removed", func(t *testing.T) {
+		limiter.reserve("10.0.0.3", now.Add(2*time.Minute))
+		assert.Equal(t, len(limiter.clients), 1)
+	})
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	router := gin.New()
+	router.Use(rateLimitMiddleware(newIPRateLimiter(rate.Limit(0.5), 1)))
+	router.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusOK)
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, resp.Code, http.StatusTooManyRequests, resp.Body.String())
+	assert.Equal(t, resp.Header().Get("Retry-After"), "2")
+}
+
+func TestRoutes_RateLimit(t *testing.T) {
+	srv := setupServer(t)
+	routes := srv.GenerateRoutes()
+
+	send := func(path, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Infra-Version", apiVersionLatest)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("forwarded for header from an untrusted client", func(t *testing.T) {
+		for i := 0; i < passwordRateBurst; i++ {
+			resp := send("/api/login", fmt.Sprintf("10.0.0.%d", i))
+			assert.Assert(t, resp.Code != http.StatusTooManyRequests, "request %d", i)
+		}
+		resp := send("/api/login", "10.0.1.1")
+		assert.Equal(t, resp.Code, http.StatusTooManyRequests, resp.Body.String())
+	})
+
+	t.Run("device flow routes have a separate limit", func(t *testing.T) {
+		for i := 0; i < deviceFlowRateBurst; i++ {
+			resp := send("/api/device/token", "")
+			assert.Assert(t, resp.Code != http.StatusTooManyRequests, "request %d: %v", i, resp.Body.String())
+		}
+		resp := send("/api/device", "")
+		assert.Equal(t, resp.Code, http.StatusTooManyRequests, resp.Body.String())
+	})
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func (a *API) ListRoles(c *gin.Context, r *api.ListRolesRequest) (*api.ListResponse[api.Role], error) {
+	p := models.RequestToPagination(r.PaginationRequest)
+	roles, err := access.ListRoles(c, r.Name, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(roles, models.PaginationToResponse(p), func(role models.Role) api.Role {
+		return *role.ToAPI()
+	})
+
+	return result, nil
+}
+
+func (a *API) GetRole(c *gin.Context, r *api.Resource) (*api.Role, error) {
+	role, err := access.GetRole(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return role.ToAPI(), nil
+}
+
+func (a *API) CreateRole(c *gin.Context, r *api.CreateRoleRequest) (*api.Role, error) {
+	role := &models.Role{
+		Name:        r.Name,
+		Description: r.Description,
+		Rules:       models.PolicyRulesFromAPI(r.Rules),
+	}
+
+	if err := access.CreateRole(c, role); err != nil {
+		return nil, err
+	}
+
+	return role.ToAPI(), nil
+}
+
+func (a *API) UpdateRole(c *gin.Context, r *api.UpdateRoleRequest) (*api.Role, error) {
+	role, err := access.GetRole(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Description = r.Description
+	role.Rules = models.PolicyRulesFromAPI(r.Rules)
+	if err := access.UpdateRole(c, role); err != nil {
+		return nil, err
+	}
+
+	return role.ToAPI(), nil
+}
+
+func (a *API) DeleteRole(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
+	return nil, access.DeleteRole(c, r.ID)
+}
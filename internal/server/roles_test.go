@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+)
+
+func TestAPI_Roles(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	adminKey := adminAccessKey(srv)
+	userKey, _ := createAccessKey(t, srv.DB(), "someone@example.com")
+
+	rules := []api.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get", "list"}},
+	}
+
+	var created api.Role
+
+	t.Run("create", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/roles", adminKey, api.CreateRoleRequest{
+			Name:        "secret-reader",
+			Description: "Read secrets",
+			Rules:       rules,
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+		assert.Equal(t, created.Name, "secret-reader")
+		assert.Equal(t, created.Description, "Read secrets")
+		assert.DeepEqual(t, created.Rules, rules)
+	})
+
+	t.Run("create requires admin", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/roles", userKey, api.CreateRoleRequest{
+			Name:  "pod-reader",
+			Rules: rules,
+		})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("create with duplicate name", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/roles", adminKey, api.CreateRoleRequest{
+			Name:  "secret-reader",
+			Rules: rules,
+		})
+		assert.Equal(t, resp.Code, http.StatusConflict, resp.Body.String())
+	})
+
+	t.Run("create with reserved name", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/roles", adminKey, api.CreateRoleRequest{
+			Name:  "admin",
+			Rules: rules,
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("create with invalid rules", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/roles", adminKey, api.CreateRoleRequest{
+			Name:  "pod-reader",
+			Rules: []api.PolicyRule{{Resources: []string{"pods"}}},
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		var apiErr api.Error
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &apiErr))
+		assert.Equal(t, len(apiErr.FieldErrors), 1)
+		assert.Equal(t, apiErr.FieldErrors[0].FieldName, "rules.verbs")
+	})
+
+	t.Run("list", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodGet, "/api/roles?name=secret-reader", adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var roles api.ListResponse[api.Role]
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &roles))
+		assert.Equal(t, roles.Count, 1)
+		assert.Equal(t, roles.Items[0].ID, created.ID)
+	})
+
+	t.Run("list requires privileges", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodGet, "/api/roles", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("update", func(t *testing.T) {
+		updated := []api.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get", "list", "watch"}},
+		}
+		resp := doRequest(t, routes, http.MethodPut, fmt.Sprintf("/api/roles/%s", created.ID), adminKey, api.UpdateRoleRequest{
+			Description: "Read and watch secrets",
+			Rules:       updated,
+		})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = doRequest(t, routes, http.MethodGet, fmt.Sprintf("/api/roles/%s", created.ID), adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var role api.Role
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &role))
+		assert.Equal(t, role.Name, "secret-reader")
+		assert.Equal(t, role.Description, "Read and watch secrets")
+		assert.DeepEqual(t, role.Rules, updated)
+	})
+
+	t.Run("delete requires admin", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodDelete, fmt.Sprintf("/api/roles/%s", created.ID), userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodDelete, fmt.Sprintf("/api/roles/%s", created.ID), adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		resp = doRequest(t, routes, http.MethodGet, fmt.Sprintf("/api/roles/%s", created.ID), adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+	})
+}
@@ -40,6 +40,12 @@ func (s *Server) GenerateRoutes() Routes {
 
 	router := gin.New()
 	router.NoRoute(a.notFoundHandler)
+	// the client IP is used to rate limit requests, so the X-Forwarded-For
+	// header is only used when the request is from a trusted proxy.
+	if err := router.SetTrustedProxies(s.options.TrustedProxies); err != nil {
+		logging.L.Error().Err(err).Msg("invalid trusted proxies, the remote address is used for the client IP")
+		_ = router.SetTrustedProxies(nil)
+	}
 
 	router.Use(gin.Recovery())
 	router.GET("/healthz", healthHandler)
@@ -51,7 +57,7 @@ func (s *Server) GenerateRoutes() Routes {
 	)
 
 	// This group of middleware only applies to non-ui routes
-	apiGroup := router.Group("/", metrics.Middleware(s.metricsRegistry))
+	apiGroup := router.Group("/", metrics.Middleware(s.metricsRegistry), auditMiddleware(s))
 
 	// auth required, org required
 	authn := apiGroup.Group("/", authenticatedMiddleware(a.server))
@@ -61,6 +67,11 @@ func (s *Server) GenerateRoutes() Routes {
 	get(a, authn, "/api/users/:id", a.GetUser)
 	put(a, authn, "/api/users/:id", a.UpdateUser)
 	del(a, authn, "/api/users/:id", a.DeleteUser)
+	post(a, authn, "/api/users/:id/unlock", a.UnlockUser)
+	post(a, authn, "/api/users/:id/mfa", a.EnrollMFA)
+	post(a, authn, "/api/users/:id/mfa/verify", a.VerifyMFA)
+	post(a, authn, "/api/users/:id/mfa/recovery-codes", a.RegenerateMFARecoveryCodes)
+	del(a, authn, "/api/users/:id/mfa", a.DisableMFA)
 
 	get(a, authn, "/api/access-keys", a.ListAccessKeys)
 	post(a, authn, "/api/access-keys", a.CreateAccessKey)
@@ -71,46 +82,116 @@ func (s *Server) GenerateRoutes() Routes {
 	get(a, authn, "/api/groups/:id", a.GetGroup)
 	del(a, authn, "/api/groups/:id", a.DeleteGroup)
 	patch(a, authn, "/api/groups/:id/users", a.UpdateUsersInGroup)
+	patch(a, authn, "/api/groups/:id/groups", a.UpdateGroupsInGroup)
 
 	get(a, authn, "/api/organizations", a.ListOrganizations)
 	post(a, authn, "/api/organizations", a.CreateOrganization)
 	get(a, authn, "/api/organizations/:id", a.GetOrganization)
 	del(a, authn, "/api/organizations/:id", a.DeleteOrganization)
+	get(a, authn, "/api/organizations/:id/export", a.ExportOrganization)
+	post(a, authn, "/api/organizations/:id/import", a.ImportOrganization)
 
 	get(a, authn, "/api/grants", a.ListGrants)
 	get(a, authn, "/api/grants/:id", a.GetGrant)
 	post(a, authn, "/api/grants", a.CreateGrant)
 	del(a, authn, "/api/grants/:id", a.DeleteGrant)
 
+	get(a, authn, "/api/access-requests", a.ListAccessRequests)
+	get(a, authn, "/api/access-requests/:id", a.GetAccessRequest)
+	post(a, authn, "/api/access-requests", a.CreateAccessRequest)
+	post(a, authn, "/api/access-requests/:id/approve", a.ApproveAccessRequest)
+	post(a, authn, "/api/access-requests/:id/deny", a.DenyAccessRequest)
+
 	post(a, authn, "/api/providers", a.CreateProvider)
 	put(a, authn, "/api/providers/:id", a.UpdateProvider)
 	del(a, authn, "/api/providers/:id", a.DeleteProvider)
+	post(a, authn, "/api/providers/:id/scim-access-keys", a.CreateSCIMAccessKey)
 
 	get(a, authn, "/api/destinations", a.ListDestinations)
 	get(a, authn, "/api/destinations/:id", a.GetDestination)
 	post(a, authn, "/api/destinations", a.CreateDestination)
 	put(a, authn, "/api/destinations/:id", a.UpdateDestination)
 	del(a, authn, "/api/destinations/:id", a.DeleteDestination)
+	get(a, authn, "/api/destinations/:id/grants", a.ListDestinationGrants)
+
+	// auth required, org required, the handler waits outside of a transaction
+	longPoll := apiGroup.Group("/", longPollMiddleware(a.server))
+	get(a, longPoll, "/api/destinations/:id/events", a.ListDestinationEvents)
 
 	post(a, authn, "/api/tokens", a.CreateToken)
+	post(a, authn, "/api/ssh/certificates", a.CreateSSHCertificate)
+	get(a, authn, "/api/ssh/user-ca", a.GetSSHUserCA)
 	post(a, authn, "/api/logout", a.Logout)
 
 	put(a, authn, "/api/settings", a.UpdateSettings)
+	get(a, authn, "/api/settings/signing-keys", a.ListSigningKeys)
+	post(a, authn, "/api/settings/signing-keys/rotate", a.RotateSigningKeys)
+
+	get(a, authn, "/api/audit-events", a.ListAuditEvents)
+
+	get(a, authn, "/api/roles", a.ListRoles)
+	get(a, authn, "/api/roles/:id", a.GetRole)
+	post(a, authn, "/api/roles", a.CreateRole)
+	put(a, authn, "/api/roles/:id", a.UpdateRole)
+	del(a, authn, "/api/roles/:id", a.DeleteRole)
+
+	get(a, authn, "/api/federations", a.ListFederations)
+	get(a, authn, "/api/federations/:id", a.GetFederation)
+	post(a, authn, "/api/federations", a.CreateFederation)
+	put(a, authn, "/api/federations/:id", a.UpdateFederation)
+	del(a, authn, "/api/federations/:id", a.DeleteFederation)
+
+	get(a, authn, "/api/oidc-clients", a.ListOIDCClients)
+	get(a, authn, "/api/oidc-clients/:id", a.GetOIDCClient)
+	post(a, authn, "/api/oidc-clients", a.CreateOIDCClient)
+	put(a, authn, "/api/oidc-clients/:id", a.UpdateOIDCClient)
+	del(a, authn, "/api/oidc-clients/:id", a.DeleteOIDCClient)
+
+	get(a, authn, "/api/device", a.ListDeviceFlowAuthRequests)
+	post(a, authn, "/api/device/approve", a.ApproveDeviceFlow)
+	del(a, authn, "/api/device/:id", a.DeleteDeviceFlowAuthRequest)
+
+	get(a, authn, "/api/webhooks", a.ListWebhooks)
+	get(a, authn, "/api/webhooks/:id", a.GetWebhook)
+	post(a, authn, "/api/webhooks", a.CreateWebhook)
+	del(a, authn, "/api/webhooks/:id", a.DeleteWebhook)
 
 	authn.GET("/api/debug/pprof/*profile", pprofHandler)
 
+	// auth required with a SCIM access key, undocumented in api spec
+	scim := apiGroup.Group("/scim/v2", authenticatedMiddleware(a.server))
+	a.registerSCIMRoutes(scim)
+
+	// the routes that check passwords are rate limited by client IP, to slow
+	// down attempts to guess them.
+	limiter := newIPRateLimiter(passwordRateLimit, passwordRateBurst)
+	// the device flow routes are rate limited to slow down guessing device
+	// codes, and creating device flow requests.
+	deviceFlowLimiter := newIPRateLimiter(deviceFlowRateLimit, deviceFlowRateBurst)
+
 	// no auth required, org not required
 	noAuthnNoOrg := apiGroup.Group("/", unauthenticatedMiddleware(a.server))
-	post(a, noAuthnNoOrg, "/api/signup", a.Signup)
 	get(a, noAuthnNoOrg, "/api/version", a.Version)
 	get(a, noAuthnNoOrg, "/api/server-configuration", a.GetServerConfiguration)
 
+	// no auth required, org not required, rate limited
+	passwordNoOrg := apiGroup.Group("/", rateLimitMiddleware(limiter), unauthenticatedMiddleware(a.server))
+	post(a, passwordNoOrg, "/api/signup", a.Signup)
+
 	// no auth required, org required
 	noAuthnWithOrg := apiGroup.Group("/", unauthenticatedMiddleware(a.server), orgRequired())
 
-	post(a, noAuthnWithOrg, "/api/login", a.Login)
-	post(a, noAuthnWithOrg, "/api/password-reset-request", a.RequestPasswordReset)
-	post(a, noAuthnWithOrg, "/api/password-reset", a.VerifiedPasswordReset)
+	// no auth required, org required, rate limited
+	password := apiGroup.Group("/", rateLimitMiddleware(limiter), unauthenticatedMiddleware(a.server), orgRequired())
+	post(a, password, "/api/login", a.Login)
+	post(a, password, "/api/password-reset-request", a.RequestPasswordReset)
+	post(a, password, "/api/password-reset", a.VerifiedPasswordReset)
+
+	// no auth required, org required, rate limited separately from passwords
+	// so that polling does not use up the login attempts of the client
+	deviceFlow := apiGroup.Group("/", rateLimitMiddleware(deviceFlowLimiter), unauthenticatedMiddleware(a.server), orgRequired())
+	post(a, deviceFlow, "/api/device", a.StartDeviceFlow)
+	post(a, deviceFlow, "/api/device/token", a.PollDeviceFlow)
 
 	get(a, noAuthnWithOrg, "/api/providers/:id", a.GetProvider)
 	get(a, noAuthnWithOrg, "/api/providers", a.ListProviders)
@@ -125,6 +206,26 @@ func (s *Server) GenerateRoutes() Routes {
 		omitFromTelemetry:   true,
 		infraHeaderOptional: true,
 	})
+	add(a, noAuthnWithOrg, route[api.EmptyRequest, OpenIDConfiguration]{
+		method:              http.MethodGet,
+		path:                "/.well-known/openid-configuration",
+		handler:             wellKnownOpenIDConfigurationHandler,
+		omitFromDocs:        true,
+		omitFromTelemetry:   true,
+		infraHeaderOptional: true,
+	})
+
+	// OpenID Connect endpoints for OIDC clients, which use the request and
+	// response formats of OAuth 2.0 instead of the formats of the API
+	noAuthnWithOrg.GET("/oidc/authorize", a.oidcAuthorize)
+	noAuthnWithOrg.POST("/oidc/token", a.oidcToken)
+	noAuthnWithOrg.GET("/oidc/userinfo", a.oidcUserInfo)
+	noAuthnWithOrg.POST("/oidc/userinfo", a.oidcUserInfo)
+
+	// SAML service provider endpoints, which use the bindings of SAML 2.0
+	noAuthnWithOrg.GET("/saml/:id/metadata", a.samlMetadata)
+	noAuthnWithOrg.GET("/saml/:id/login", a.samlLogin)
+	noAuthnWithOrg.POST("/saml/:id/acs", a.samlACS)
 
 	a.deprecatedRoutes(noAuthnNoOrg)
 
@@ -164,6 +265,7 @@ func add[Req, Res any](a *API, r *gin.RouterGroup, route route[Req, Res]) {
 		}
 
 		req := new(Req)
+		c.Set(auditRequestKey, req)
 		if err := bind(c, req); err != nil {
 			sendAPIError(c, err)
 			return
@@ -176,6 +278,7 @@ func add[Req, Res any](a *API, r *gin.RouterGroup, route route[Req, Res]) {
 			sendAPIError(c, err)
 			return
 		}
+		c.Set(auditResponseKey, resp)
 
 		if !route.omitFromTelemetry {
 			a.t.RouteEvent(c, route.path, Properties{"method": strings.ToLower(route.method)})
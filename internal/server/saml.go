@@ -0,0 +1,142 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+// samlServiceProvider returns Infra as the service provider of a saml
+// provider. The URLs of the service provider use the server URL of the
+// organization, so that each organization has its own.
+func (a *API) samlServiceProvider(c *gin.Context, provider *models.Provider) (*providers.SAMLServiceProvider, error) {
+	if provider.Kind != models.ProviderKindSAML {
+		return nil, fmt.Errorf("%w: provider %s is not a saml provider", internal.ErrBadRequest, provider.Name)
+	}
+	serverURL, err := a.serverURL(c)
+	if err != nil {
+		return nil, err
+	}
+	return providers.NewSAMLServiceProvider(*provider, serverURL)
+}
+
+// samlProviderFromPath returns the saml provider from the id in the path of
+// the request.
+func (a *API) samlProviderFromPath(c *gin.Context) (*models.Provider, *providers.SAMLServiceProvider, error) {
+	id, err := uid.Parse([]byte(c.Param("id")))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid provider id", internal.ErrBadRequest)
+	}
+	provider, err := access.GetProvider(c, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	sp, err := a.samlServiceProvider(c, provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	return provider, sp, nil
+}
+
+// samlMetadata serves the metadata of Infra as a service provider, which is
+// used to register Infra with the identity provider.
+func (a *API) samlMetadata(c *gin.Context) {
+	_, sp, err := a.samlProviderFromPath(c)
+	if err != nil {
+		sendAPIError(c, err)
+		return
+	}
+
+	metadata, err := sp.Metadata()
+	if err != nil {
+		sendAPIError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// samlLogin sends the user to the identity provider to login. The identity
+// provider returns next to the assertion consumer service as the relay state.
+func (a *API) samlLogin(c *gin.Context) {
+	_, sp, err := a.samlProviderFromPath(c)
+	if err != nil {
+		sendAPIError(c, err)
+		return
+	}
+
+	authURL, err := sp.AuthnRequestURL(safeRedirectPath(c.Query("next")))
+	if err != nil {
+		sendAPIError(c, err)
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// samlLoginComplete is shown after a successful login. The auth cookie is
+// SameSite=Strict, so it would not be sent with a redirect that follows the
+// cross-site POST from the identity provider. Navigating from this page is a
+// same-site request, which includes the cookie.
+var samlLoginComplete = template.Must(template.New("saml").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta http-equiv="refresh" content="0;url={{.}}">
+<title>Infra</title>
+</head>
+<body><a href="{{.}}">Continue</a></body>
+</html>
+`))
+
+// samlACS is the assertion consumer service, where the identity provider sends
+// the response of a login using the HTTP-POST binding. Users are logged in the
+// same way as with a saml login request to the login endpoint.
+func (a *API) samlACS(c *gin.Context) {
+	id, err := uid.Parse([]byte(c.Param("id")))
+	if err != nil {
+		sendAPIError(c, fmt.Errorf("%w: invalid provider id", internal.ErrBadRequest))
+		return
+	}
+
+	req := &api.LoginRequest{
+		SAML: &api.LoginRequestSAML{
+			ProviderID:   id,
+			SAMLResponse: c.PostForm("SAMLResponse"),
+		},
+	}
+	if err := validate.Validate(req); err != nil {
+		sendAPIError(c, err)
+		return
+	}
+
+	if _, err := a.Login(c, req); err != nil {
+		sendAPIError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := samlLoginComplete.Execute(c.Writer, safeRedirectPath(c.PostForm("RelayState"))); err != nil {
+		logging.L.Error().Err(err).Msg("failed to render saml login page")
+	}
+}
+
+// safeRedirectPath returns path when it is a path on this server, otherwise it
+// returns the path of the dashboard.
+func safeRedirectPath(path string) string {
+	if strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "//") && !strings.HasPrefix(path, "/\\") {
+		return path
+	}
+	return "/"
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/testing/saml"
+)
+
+func TestAPI_SAML(t *testing.T) {
+	srv := setupServer(t, withAdminUser, func(t *testing.T, opts *Options) {
+		assert.NilError(t, opts.ServerURL.Set("https://infra.example.com"))
+	})
+	routes := srv.GenerateRoutes()
+	idp := saml.NewIdP(t)
+
+	provider := &models.Provider{
+		Name:            "adfs",
+		Kind:            models.ProviderKindSAML,
+		URL:             "https://idp.example.com/sso",
+		SAMLEntityID:    idp.EntityID,
+		SAMLCertificate: idp.CertificatePEM,
+	}
+	err := data.CreateProvider(srv.DB(), provider)
+	assert.NilError(t, err)
+
+	baseURL := "https://infra.example.com/saml/" + provider.ID.String()
+
+	t.Run("metadata", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/saml/"+provider.ID.String()+"/metadata", nil)
+		// the host of the request is not used for the URLs
+		req.Host = "evil.example.com"
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Equal(t, resp.Header().Get("Content-Type"), "application/samlmetadata+xml")
+		assert.Assert(t, is.Contains(resp.Body.String(), `entityID="`+baseURL+`/metadata"`))
+		assert.Assert(t, is.Contains(resp.Body.String(), `Location="`+baseURL+`/acs"`))
+	})
+
+	t.Run("metadata of a provider that is not saml", func(t *testing.T) {
+		oidc := &models.Provider{Name: "mockta", Kind: models.ProviderKindOkta}
+		assert.NilError(t, data.CreateProvider(srv.DB(), oidc))
+
+		req := httptest.NewRequest(http.MethodGet, "/saml/"+oidc.ID.String()+"/metadata", nil)
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("login redirects to the identity provider", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/saml/"+provider.ID.String()+"/login?next=/destinations", nil)
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+
+		assert.Equal(t, resp.Code, http.StatusFound, resp.Body.String())
+		location, err := url.Parse(resp.Header().Get("Location"))
+		assert.NilError(t, err)
+		assert.Equal(t, location.Host, "idp.example.com")
+		assert.Equal(t, location.Query().Get("RelayState"), "/destinations")
+		assert.Assert(t, location.Query().Get("SAMLRequest") != "")
+	})
+
+	acs := func(t *testing.T, samlResponse, relayState string) *httptest.ResponseRecorder {
+		form := url.Values{"SAMLResponse": {samlResponse}, "RelayState": {relayState}}
+		req := httptest.NewRequest(http.MethodPost, "/saml/"+provider.ID.String()+"/acs", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("assertion consumer service with invalid response", func(t *testing.T) {
+		resp := acs(t, "not a response", "/")
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("assertion consumer service", func(t *testing.T) {
+		samlResponse := idp.Response(t, saml.ResponseOptions{
+			Destination: baseURL + "/acs",
+			Audience:    baseURL + "/metadata",
+			Attributes:  map[string][]string{"groups": {"developers"}},
+		})
+
+		resp := acs(t, samlResponse, "https://evil.example.com/")
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Equal(t, resp.Header().Get("Cache-Control"), "no-store")
+		assert.Assert(t, is.Contains(resp.Body.String(), `content="0;url=/"`))
+
+		var cookie *http.Cookie
+		for _, c := range resp.Result().Cookies() {
+			if c.Name == cookieAuthorizationName {
+				cookie = c
+			}
+		}
+		assert.Assert(t, cookie != nil, "auth cookie was not set")
+		assert.Assert(t, cookie.Value != "")
+
+		identity, err := data.GetIdentity(srv.DB(), data.Preload("Groups"), data.ByName("alice@example.com"))
+		assert.NilError(t, err)
+		assert.Equal(t, len(identity.Groups), 1)
+		assert.Equal(t, identity.Groups[0].Name, "developers")
+
+		// the same response can not be used to login again
+		resp = acs(t, samlResponse, "/")
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+}
+
+func TestSafeRedirectPath(t *testing.T) {
+	assert.Equal(t, safeRedirectPath("/destinations?x=1"), "/destinations?x=1")
+	assert.Equal(t, safeRedirectPath(""), "/")
+	assert.Equal(t, safeRedirectPath("https://evil.example.com"), "/")
+	assert.Equal(t, safeRedirectPath("//evil.example.com"), "/")
+	assert.Equal(t, safeRedirectPath(`/\evil.example.com`), "/")
+}
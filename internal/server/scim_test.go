@@ -0,0 +1,335 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// scimClient makes requests to the SCIM endpoints the same way an identity
+// provider would.
+type scimClient struct {
+	t         *testing.T
+	routes    Routes
+	accessKey string
+}
+
+func (s scimClient) do(method, path string, body any, into any) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var req *http.Request
+	if body != nil {
+		req = httptest.NewRequest(method, path, jsonBody(s.t, body))
+		req.Header.Set("Content-Type", scimContentType)
+	} else {
+		req = httptest.NewRequest(method, path, nil)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.accessKey)
+
+	resp := httptest.NewRecorder()
+	s.routes.ServeHTTP(resp, req)
+
+	if into != nil && resp.Code < 300 {
+		assert.Equal(s.t, resp.Header().Get("Content-Type"), scimContentType)
+		assert.NilError(s.t, json.Unmarshal(resp.Body.Bytes(), into), resp.Body.String())
+	}
+	return resp
+}
+
+func TestAPI_SCIM(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+	db := srv.DB()
+
+	provider := &models.Provider{
+		Name:         "okta",
+		URL:          "example.okta.com",
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		Kind:         models.ProviderKindOkta,
+	}
+	assert.NilError(t, data.CreateProvider(db, provider))
+
+	createSCIMKey := func(t *testing.T, accessKey string) *httptest.ResponseRecorder {
+		t.Helper()
+		body := api.CreateSCIMAccessKeyRequest{TTL: api.Duration(24 * time.Hour)}
+		req := httptest.NewRequest(http.MethodPost, "/api/providers/"+provider.ID.String()+"/scim-access-keys", jsonBody(t, body))
+		req.Header.Set("Authorization", "Bearer "+accessKey)
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := createSCIMKey(t, adminAccessKey(srv))
+	assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+	var keyResp api.CreateAccessKeyResponse
+	assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &keyResp))
+	assert.Equal(t, keyResp.ProviderID, provider.ID)
+
+	client := scimClient{t: t, routes: routes, accessKey: keyResp.AccessKey}
+
+	t.Run("create key requires admin", func(t *testing.T) {
+		userKey, _ := createAccessKey(t, db, "notadmin@example.com")
+		resp := createSCIMKey(t, userKey)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("scim key can not be used with the infra API", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+		req.Header.Set("Authorization", "Bearer "+keyResp.AccessKey)
+		req.Header.Set("Infra-Version", apiVersionLatest)
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("infra access key can not be used with scim", func(t *testing.T) {
+		c := scimClient{t: t, routes: routes, accessKey: adminAccessKey(srv)}
+		resp := c.do(http.MethodGet, "/scim/v2/Users", nil, nil)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+
+		var scimErr scimErrorResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &scimErr))
+		assert.Equal(t, scimErr.Status, "401")
+	})
+
+	createUser := func(t *testing.T, name string) scimUser {
+		t.Helper()
+		var user scimUser
+		resp := client.do(http.MethodPost, "/scim/v2/Users", map[string]any{
+			"schemas":  []string{scimSchemaUser},
+			"userName": name,
+			"name":     map[string]string{"givenName": "Given", "familyName": "Family"},
+			"emails":   []map[string]any{{"value": name, "primary": true}},
+			"active":   true,
+		}, &user)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		return user
+	}
+
+	alice := createUser(t, "alice@example.com")
+	bob := createUser(t, "bob@example.com")
+
+	t.Run("create user", func(t *testing.T) {
+		assert.Equal(t, alice.UserName, "alice@example.com")
+		assert.Assert(t, alice.Active)
+
+		pu, err := data.GetProviderUser(db, provider.ID, alice.ID)
+		assert.NilError(t, err)
+		assert.Equal(t, pu.Email, "alice@example.com")
+	})
+
+	t.Run("create existing user", func(t *testing.T) {
+		resp := client.do(http.MethodPost, "/scim/v2/Users", map[string]any{"userName": "alice@example.com"}, nil)
+		assert.Equal(t, resp.Code, http.StatusConflict, resp.Body.String())
+
+		var scimErr scimErrorResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &scimErr))
+		assert.Equal(t, scimErr.ScimType, "uniqueness")
+	})
+
+	t.Run("list users with filter", func(t *testing.T) {
+		var users scimListResponse[scimUser]
+		path := "/scim/v2/Users?filter=" + url.QueryEscape(`userName eq "bob@example.com"`)
+		resp := client.do(http.MethodGet, path, nil, &users)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Equal(t, users.TotalResults, 1)
+		assert.Equal(t, users.Resources[0].ID, bob.ID)
+	})
+
+	t.Run("list users with pagination", func(t *testing.T) {
+		var users scimListResponse[scimUser]
+		resp := client.do(http.MethodGet, "/scim/v2/Users?startIndex=2&count=5", nil, &users)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Equal(t, users.TotalResults, 2)
+		assert.Equal(t, users.StartIndex, 2)
+		assert.Equal(t, users.ItemsPerPage, 1)
+		assert.Equal(t, users.Resources[0].ID, bob.ID)
+	})
+
+	t.Run("list users only includes users of the provider", func(t *testing.T) {
+		var users scimListResponse[scimUser]
+		path := "/scim/v2/Users?filter=" + url.QueryEscape(`userName eq "admin@example.com"`)
+		resp := client.do(http.MethodGet, path, nil, &users)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Equal(t, users.TotalResults, 0)
+	})
+
+	t.Run("list users with unsupported filter", func(t *testing.T) {
+		path := "/scim/v2/Users?filter=" + url.QueryEscape(`name.givenName sw "B"`)
+		resp := client.do(http.MethodGet, path, nil, nil)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		var scimErr scimErrorResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &scimErr))
+		assert.Equal(t, scimErr.ScimType, "invalidFilter")
+	})
+
+	var group scimGroup
+	t.Run("create group", func(t *testing.T) {
+		resp := client.do(http.MethodPost, "/scim/v2/Groups", map[string]any{
+			"schemas":     []string{scimSchemaGroup},
+			"displayName": "engineering",
+			"members":     []map[string]string{{"value": alice.ID.String()}},
+		}, &group)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		assert.Equal(t, group.DisplayName, "engineering")
+		assert.DeepEqual(t, group.Members, []scimMember{{Value: alice.ID.String(), Display: "alice@example.com"}})
+
+		g, err := data.GetGroup(db, data.ByID(group.ID))
+		assert.NilError(t, err)
+		assert.Equal(t, g.CreatedByProvider, provider.ID)
+	})
+
+	t.Run("create group with unknown member", func(t *testing.T) {
+		resp := client.do(http.MethodPost, "/scim/v2/Groups", map[string]any{
+			"displayName": "unknown",
+			"members":     []map[string]string{{"value": uid.New().String()}},
+		}, nil)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("patch group members", func(t *testing.T) {
+		var patched scimGroup
+		resp := client.do(http.MethodPatch, "/scim/v2/Groups/"+group.ID.String(), map[string]any{
+			"Operations": []map[string]any{
+				{"op": "add", "path": "members", "value": []map[string]string{{"value": bob.ID.String()}}},
+				{"op": "remove", "path": `members[value eq "` + alice.ID.String() + `"]`},
+				{"op": "replace", "value": map[string]string{"displayName": "platform"}},
+			},
+		}, &patched)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Equal(t, patched.DisplayName, "platform")
+		assert.DeepEqual(t, patched.Members, []scimMember{{Value: bob.ID.String(), Display: "bob@example.com"}})
+
+		var user scimUser
+		resp = client.do(http.MethodGet, "/scim/v2/Users/"+bob.ID.String(), nil, &user)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.DeepEqual(t, user.Groups, []scimMember{{Value: group.ID.String(), Display: "platform"}})
+	})
+
+	t.Run("replace group", func(t *testing.T) {
+		var replaced scimGroup
+		resp := client.do(http.MethodPut, "/scim/v2/Groups/"+group.ID.String(), map[string]any{
+			"displayName": "engineering",
+			"members": []map[string]string{
+				{"value": alice.ID.String()},
+				{"value": bob.ID.String()},
+			},
+		}, &replaced)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Equal(t, replaced.DisplayName, "engineering")
+		assert.Equal(t, len(replaced.Members), 2)
+	})
+
+	t.Run("deactivate user", func(t *testing.T) {
+		key, err := data.CreateAccessKey(db, &models.AccessKey{
+			IssuedFor:  alice.ID,
+			ProviderID: provider.ID,
+			ExpiresAt:  time.Now().Add(time.Hour),
+		})
+		assert.NilError(t, err)
+
+		var user scimUser
+		resp := client.do(http.MethodPatch, "/scim/v2/Users/"+alice.ID.String(), map[string]any{
+			"Operations": []map[string]any{
+				{"op": "replace", "value": map[string]any{"active": false}},
+			},
+		}, &user)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Assert(t, !user.Active)
+
+		_, err = data.ValidateAccessKey(db, key)
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+
+		_, err = data.GetIdentity(db, data.ByID(alice.ID))
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+
+		resp = client.do(http.MethodGet, "/scim/v2/Users/"+alice.ID.String(), nil, nil)
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+
+		var g scimGroup
+		resp = client.do(http.MethodGet, "/scim/v2/Groups/"+group.ID.String(), nil, &g)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.DeepEqual(t, g.Members, []scimMember{{Value: bob.ID.String(), Display: "bob@example.com"}})
+	})
+
+	t.Run("create user that exists without a provider", func(t *testing.T) {
+		dave := &models.Identity{Name: "dave@example.com"}
+		assert.NilError(t, data.CreateIdentity(db, dave))
+
+		user := createUser(t, "dave@example.com")
+		assert.Equal(t, user.ID, dave.ID)
+	})
+
+	t.Run("create user can not take over a local admin", func(t *testing.T) {
+		resp := client.do(http.MethodPost, "/scim/v2/Users", map[string]any{"userName": "admin@example.com"}, nil)
+		assert.Equal(t, resp.Code, http.StatusConflict, resp.Body.String())
+
+		admin, err := data.GetIdentity(db, data.ByName("admin@example.com"))
+		assert.NilError(t, err)
+		_, err = data.GetProviderUser(db, provider.ID, admin.ID)
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+	})
+
+	t.Run("delete user of another provider", func(t *testing.T) {
+		carol := createUser(t, "carol@example.com")
+
+		// carol also logs in with another provider
+		other := &models.Provider{Name: "other", Kind: models.ProviderKindOIDC}
+		assert.NilError(t, data.CreateProvider(db, other))
+		identity, err := data.GetIdentity(db, data.ByID(carol.ID))
+		assert.NilError(t, err)
+		_, err = data.CreateProviderUser(db, other, identity)
+		assert.NilError(t, err)
+
+		key, err := data.CreateAccessKey(db, &models.AccessKey{
+			IssuedFor:  carol.ID,
+			ProviderID: other.ID,
+			ExpiresAt:  time.Now().Add(time.Hour),
+		})
+		assert.NilError(t, err)
+
+		resp := client.do(http.MethodDelete, "/scim/v2/Users/"+carol.ID.String(), nil, nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		// the user still exists, but their sessions were revoked
+		_, err = data.GetIdentity(db, data.ByID(carol.ID))
+		assert.NilError(t, err)
+		_, err = data.ValidateAccessKey(db, key)
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+		_, err = data.GetProviderUser(db, provider.ID, carol.ID)
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+	})
+
+	t.Run("delete group", func(t *testing.T) {
+		resp := client.do(http.MethodDelete, "/scim/v2/Groups/"+group.ID.String(), nil, nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		_, err := data.GetGroup(db, data.ByID(group.ID))
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+	})
+
+	t.Run("groups of other providers are not visible", func(t *testing.T) {
+		other := &models.Group{Name: "manual"}
+		assert.NilError(t, data.CreateGroup(db, other))
+
+		resp := client.do(http.MethodGet, "/scim/v2/Groups/"+other.ID.String(), nil, nil)
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+
+		resp = client.do(http.MethodDelete, "/scim/v2/Groups/"+other.ID.String(), nil, nil)
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+	})
+}
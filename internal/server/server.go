@@ -40,6 +40,9 @@ type Options struct {
 	EnableSignup             bool
 	SessionDuration          time.Duration
 	SessionExtensionDeadline time.Duration
+	// SigningKeyRotationInterval is how often the key used to sign tokens for
+	// destinations is rotated. A zero value disables automatic rotation.
+	SigningKeyRotationInterval time.Duration
 
 	DBFile                  string
 	DBEncryptionKey         string
@@ -58,6 +61,15 @@ type Options struct {
 	SendgridApiKey   string
 
 	BaseDomain string
+	// ServerURL is the URL that browsers and clients use to reach the server,
+	// like https://infra.example.com. It is used to build the URLs that the
+	// server gives to other systems. Organizations with a domain use
+	// https://<domain> instead.
+	ServerURL types.URL
+	// TrustedProxies are the IP addresses or CIDRs of the proxies in front of
+	// the server. The X-Forwarded-For header is used for the client IP only
+	// when the request is from a trusted proxy.
+	TrustedProxies []string
 
 	Keys    []KeyProvider
 	Secrets []SecretProvider
@@ -177,6 +189,30 @@ func (s *Server) Run(ctx context.Context) error {
 		})
 	}
 
+	repeat.Start(ctx, time.Minute, func(context.Context) {
+		s.deleteExpiredGrants()
+		s.deleteOldDestinationEvents()
+	})
+
+	repeat.Start(ctx, time.Minute, func(context.Context) {
+		s.deleteExpiredDeviceFlowAuthRequests()
+		s.deleteExpiredOIDCAuthorizationCodes()
+	})
+
+	repeat.Start(ctx, 10*time.Second, func(ctx context.Context) {
+		s.deliverWebhooks(ctx)
+	})
+
+	repeat.Start(ctx, time.Minute, func(context.Context) {
+		s.createDisconnectedDestinationEvents(time.Now())
+	})
+
+	if s.options.SigningKeyRotationInterval > 0 {
+		repeat.Start(ctx, time.Minute, func(context.Context) {
+			s.rotateSigningKeys()
+		})
+	}
+
 	group, _ := errgroup.WithContext(ctx)
 	for i := range s.routines {
 		group.Go(s.routines[i].run)
@@ -203,6 +239,51 @@ func (s *Server) Run(ctx context.Context) error {
 	return err
 }
 
+// deleteExpiredGrants removes grants which have expired. Expired grants are
+// already excluded from authorization checks, so this only keeps the
+// grants table from collecting grants that will never be valid again.
+func (s *Server) deleteExpiredGrants() {
+	count, err := data.DeleteExpiredGrants(s.db, time.Now())
+	if err != nil {
+		logging.L.Warn().Err(err).Msg("failed to delete expired grants")
+		return
+	}
+	if count > 0 {
+		logging.Debugf("deleted %d expired grants", count)
+	}
+}
+
+// destinationEventRetention is how long destination events are kept. A
+// connector that has not read events for longer than this lists all grants
+// again.
+const destinationEventRetention = 24 * time.Hour
+
+// deleteOldDestinationEvents removes destination events that are older than
+// destinationEventRetention.
+func (s *Server) deleteOldDestinationEvents() {
+	count, err := data.DeleteOldDestinationEvents(s.db, time.Now().Add(-destinationEventRetention))
+	if err != nil {
+		logging.L.Warn().Err(err).Msg("failed to delete old destination events")
+		return
+	}
+	if count > 0 {
+		logging.Debugf("deleted %d old destination events", count)
+	}
+}
+
+// rotateSigningKeys rotates the signing keys of any organization where the
+// active key is older than the rotation interval.
+func (s *Server) rotateSigningKeys() {
+	count, err := data.RotateExpiredSigningKeys(s.db, s.options.SigningKeyRotationInterval)
+	if err != nil {
+		logging.L.Warn().Err(err).Msg("failed to rotate signing keys")
+		return
+	}
+	if count > 0 {
+		logging.Infof("rotated signing keys for %d organizations", count)
+	}
+}
+
 func registerUIRoutes(router *gin.Engine, opts UIOptions) {
 	if opts.ProxyURL.Host != "" {
 		remote := opts.ProxyURL.Value()
@@ -7,6 +7,7 @@ import (
 
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/models"
 )
 
 func (a *API) GetSettings(c *gin.Context, r *api.EmptyRequest) (*api.Settings, error) {
@@ -29,5 +30,28 @@ func (a *API) UpdateSettings(c *gin.Context, s *api.Settings) (*api.Settings, er
 		return nil, err
 	}
 
-	return s, nil
+	return settings.ToAPI(), nil
+}
+
+func (a *API) ListSigningKeys(c *gin.Context, r *api.ListSigningKeysRequest) (*api.ListResponse[api.SigningKey], error) {
+	p := models.RequestToPagination(r.PaginationRequest)
+	keys, err := access.ListSigningKeys(c, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(keys, models.PaginationToResponse(p), func(key models.SigningKey) api.SigningKey {
+		return *key.ToAPI()
+	})
+
+	return result, nil
+}
+
+func (a *API) RotateSigningKeys(c *gin.Context, r *api.RotateSigningKeysRequest) (*api.SigningKey, error) {
+	key, err := access.RotateSigningKeys(c, r.RetirePrevious)
+	if err != nil {
+		return nil, err
+	}
+
+	return key.ToAPI(), nil
 }
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+)
+
+func TestAPI_RotateSigningKeys(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	userKey, _ := createAccessKey(t, srv.DB(), "user@example.com")
+
+	rotate := func(t *testing.T, accessKey string, body api.RotateSigningKeysRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/settings/signing-keys/rotate", jsonBody(t, body))
+		req.Header.Set("Authorization", "Bearer "+accessKey)
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	getJWKs := func(t *testing.T) WellKnownJWKResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var jwks WellKnownJWKResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &jwks))
+		return jwks
+	}
+
+	original := getJWKs(t)
+	assert.Equal(t, len(original.Keys), 1)
+
+	t.Run("requires admin", func(t *testing.T) {
+		resp := rotate(t, userKey, api.RotateSigningKeysRequest{})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("rotate keeps the previous key", func(t *testing.T) {
+		resp := rotate(t, adminAccessKey(srv), api.RotateSigningKeysRequest{})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var key api.SigningKey
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &key))
+		assert.Equal(t, key.State, "active")
+
+		jwks := getJWKs(t)
+		assert.Equal(t, len(jwks.Keys), 2)
+		assert.Equal(t, jwks.Keys[0].KeyID, key.KeyID)
+		assert.Equal(t, jwks.Keys[1].KeyID, original.Keys[0].KeyID)
+	})
+
+	t.Run("rotate and retire the previous key", func(t *testing.T) {
+		resp := rotate(t, adminAccessKey(srv), api.RotateSigningKeysRequest{RetirePrevious: true})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var key api.SigningKey
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &key))
+
+		jwks := getJWKs(t)
+		assert.Equal(t, len(jwks.Keys), 1)
+		assert.Equal(t, jwks.Keys[0].KeyID, key.KeyID)
+	})
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/access"
+)
+
+func (a *API) CreateSSHCertificate(c *gin.Context, r *api.CreateSSHCertificateRequest) (*api.CreateSSHCertificateResponse, error) {
+	rCtx := getRequestContext(c)
+	if rCtx.Authenticated.User == nil {
+		return nil, fmt.Errorf("%w: no identity found in access key", internal.ErrUnauthorized)
+	}
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(r.PublicKey))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid public key: %s", internal.ErrBadRequest, err)
+	}
+	if _, ok := key.(*ssh.Certificate); ok {
+		return nil, fmt.Errorf("%w: public key must not be a certificate", internal.ErrBadRequest)
+	}
+
+	if err := a.UpdateIdentityInfoFromProvider(c); err != nil {
+		// this will fail if the user was removed from the IDP, which means they no longer are a valid user
+		return nil, fmt.Errorf("%w: failed to update identity info from provider: %s", internal.ErrUnauthorized, err)
+	}
+
+	cert, logins, err := access.CreateSSHCertificate(rCtx, r.Destination, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.CreateSSHCertificateResponse{
+		Certificate: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert))),
+		Logins:      logins,
+		Expires:     api.Time(time.Unix(int64(cert.ValidBefore), 0)),
+	}, nil
+}
+
+func (a *API) GetSSHUserCA(c *gin.Context, _ *api.EmptyRequest) (*api.SSHUserCA, error) {
+	ca, err := access.GetSSHUserCA(getRequestContext(c))
+	if err != nil {
+		return nil, err
+	}
+	return &api.SSHUserCA{PublicKey: ca.PublicKey}, nil
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAPI_CreateSSHCertificate(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	db := srv.DB()
+	assert.NilError(t, data.CreateDestination(db, &models.Destination{Name: "web", UniqueID: "web-id"}))
+
+	user := &models.Identity{Name: "alice@example.com"}
+	assert.NilError(t, data.CreateIdentity(db, user))
+	_, err := data.CreateProviderUser(db, data.InfraProvider(db), user)
+	assert.NilError(t, err)
+	accessKey, err := data.CreateAccessKey(db, &models.AccessKey{
+		IssuedFor:  user.ID,
+		ProviderID: data.InfraProvider(db).ID,
+		ExpiresAt:  time.Now().Add(time.Minute),
+	})
+	assert.NilError(t, err)
+
+	group := &models.Group{Name: "deployers"}
+	assert.NilError(t, data.CreateGroup(db, group))
+	assert.NilError(t, data.AddUsersToGroup(db, group.ID, []uid.ID{user.ID}))
+
+	grants := []models.Grant{
+		{Subject: user.PolyID(), Privilege: "ubuntu", Resource: "web"},
+		{Subject: group.PolyID(), Privilege: "deploy", Resource: "web"},
+		{Subject: user.PolyID(), Privilege: "admin", Resource: "w*"},
+		// not for the destination
+		{Subject: user.PolyID(), Privilege: "postgres", Resource: "db"},
+		{Subject: user.PolyID(), Privilege: "root", Resource: "web.default"},
+		{Subject: user.PolyID(), Privilege: "connect", Resource: "web"},
+	}
+	for i := range grants {
+		assert.NilError(t, data.CreateGrant(db, &grants[i]))
+	}
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err)
+	userKey, err := ssh.NewPublicKey(pub)
+	assert.NilError(t, err)
+	publicKey := string(ssh.MarshalAuthorizedKey(userKey))
+
+	type testCase struct {
+		body      api.CreateSSHCertificateRequest
+		accessKey string
+		expected  func(t *testing.T, resp *httptest.ResponseRecorder)
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		req := httptest.NewRequest(http.MethodPost, "/api/ssh/certificates", jsonBody(t, tc.body))
+		req.Header.Set("Infra-Version", apiVersionLatest)
+		if tc.accessKey != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.accessKey)
+		}
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		tc.expected(t, resp)
+	}
+
+	testCases := map[string]testCase{
+		"not authenticated": {
+			body: api.CreateSSHCertificateRequest{Destination: "web", PublicKey: publicKey},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+			},
+		},
+		"invalid public key": {
+			body:      api.CreateSSHCertificateRequest{Destination: "web", PublicKey: "ssh-ed25519 not-a-key"},
+			accessKey: accessKey,
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+			},
+		},
+		"unknown destination": {
+			body:      api.CreateSSHCertificateRequest{Destination: "other", PublicKey: publicKey},
+			accessKey: accessKey,
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+			},
+		},
+		"no grants for the destination": {
+			body:      api.CreateSSHCertificateRequest{Destination: "web", PublicKey: publicKey},
+			accessKey: adminAccessKey(srv),
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+				var apiErr api.Error
+				assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &apiErr))
+				assert.Equal(t, apiErr.Message, "you do not have permission to log in to web")
+			},
+		},
+		"success": {
+			body:      api.CreateSSHCertificateRequest{Destination: "web", PublicKey: publicKey},
+			accessKey: accessKey,
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+				var respBody api.CreateSSHCertificateResponse
+				assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &respBody))
+				assert.DeepEqual(t, respBody.Logins, []string{"admin", "deploy", "ubuntu"})
+
+				parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(respBody.Certificate))
+				assert.NilError(t, err)
+				cert, ok := parsed.(*ssh.Certificate)
+				assert.Assert(t, ok, "expected a certificate, got %T", parsed)
+
+				assert.Equal(t, cert.CertType, uint32(ssh.UserCert))
+				assert.Equal(t, cert.KeyId, "alice@example.com")
+				assert.DeepEqual(t, cert.ValidPrincipals, []string{"web:admin", "web:deploy", "web:ubuntu"})
+				assert.DeepEqual(t, cert.Key.Marshal(), userKey.Marshal())
+				assert.Equal(t, time.Time(respBody.Expires).Unix(), int64(cert.ValidBefore))
+
+				// the certificate is signed by the CA that destinations trust
+				req := httptest.NewRequest(http.MethodGet, "/api/ssh/user-ca", nil)
+				req.Header.Set("Infra-Version", apiVersionLatest)
+				req.Header.Set("Authorization", "Bearer "+accessKey)
+				caResp := httptest.NewRecorder()
+				routes.ServeHTTP(caResp, req)
+				assert.Equal(t, caResp.Code, http.StatusOK, caResp.Body.String())
+
+				var ca api.SSHUserCA
+				assert.NilError(t, json.Unmarshal(caResp.Body.Bytes(), &ca))
+				caKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(ca.PublicKey))
+				assert.NilError(t, err)
+				assert.DeepEqual(t, cert.SignatureKey.Marshal(), caKey.Marshal())
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
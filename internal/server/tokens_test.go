@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"gopkg.in/square/go-jose.v2/jwt"
 	"gotest.tools/v3/assert"
 
 	"github.com/infrahq/infra/api"
@@ -18,19 +20,53 @@ import (
 )
 
 func TestAPI_CreateToken(t *testing.T) {
-	srv := setupServer(t, withAdminUser)
+	srv := setupServer(t, withAdminUser, func(t *testing.T, opts *Options) {
+		assert.NilError(t, opts.ServerURL.Set("https://infra.example.com"))
+	})
 	routes := srv.GenerateRoutes()
 
+	destination := &models.Destination{Name: "production", UniqueID: "unique-id"}
+	err := data.CreateDestination(srv.DB(), destination)
+	assert.NilError(t, err)
+
+	createAccessKeyForUser := func(t *testing.T, name string) (string, *models.Identity) {
+		t.Helper()
+		user := &models.Identity{Name: name}
+		err := data.CreateIdentity(srv.DB(), user)
+		assert.NilError(t, err)
+		_, err = data.CreateProviderUser(srv.DB(), data.InfraProvider(srv.DB()), user)
+		assert.NilError(t, err)
+
+		key := &models.AccessKey{
+			IssuedFor:  user.ID,
+			ProviderID: data.InfraProvider(srv.DB()).ID,
+			ExpiresAt:  time.Now().Add(10 * time.Second),
+		}
+		accessKey, err := data.CreateAccessKey(srv.DB(), key)
+		assert.NilError(t, err)
+		return accessKey, user
+	}
+
 	type testCase struct {
+		body     *api.CreateTokenRequest
+		version  string
 		setup    func(t *testing.T, req *http.Request)
 		expected func(t *testing.T, resp *httptest.ResponseRecorder)
 	}
 
 	run := func(t *testing.T, tc testCase) {
-		// nolint:noctx
-		req, err := http.NewRequest(http.MethodPost, "/api/tokens", nil)
-		assert.NilError(t, err)
-		req.Header.Add("Infra-Version", apiVersionLatest)
+		body := tc.body
+		if body == nil {
+			body = &api.CreateTokenRequest{Destination: "production"}
+		}
+
+		version := tc.version
+		if version == "" {
+			version = apiVersionLatest
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/api/tokens", jsonBody(t, body))
+		req.Header.Add("Infra-Version", version)
 
 		if tc.setup != nil {
 			tc.setup(t, req)
@@ -77,6 +113,90 @@ func TestAPI_CreateToken(t *testing.T) {
 				assert.Assert(t, respBody.Token != "")
 			},
 		},
+		"token is bound to the destination": {
+			setup: func(t *testing.T, req *http.Request) {
+				accessKey, _ := createAccessKeyForUser(t, "vicious@example.com")
+				req.Header.Set("Authorization", "Bearer "+accessKey)
+				// the host of the request is not used for the issuer
+				req.Host = "evil.example.com"
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+				respBody := &api.CreateTokenResponse{}
+				err := json.Unmarshal(resp.Body.Bytes(), respBody)
+				assert.NilError(t, err)
+
+				parsed, err := jwt.ParseSigned(respBody.Token)
+				assert.NilError(t, err)
+
+				var claims jwt.Claims
+				assert.NilError(t, parsed.UnsafeClaimsWithoutVerification(&claims))
+				assert.DeepEqual(t, claims.Audience, jwt.Audience{"production", "unique-id"})
+				assert.Equal(t, claims.Issuer, "https://infra.example.com")
+
+				user, err := data.GetIdentity(srv.DB(), data.ByName("vicious@example.com"))
+				assert.NilError(t, err)
+				assert.Equal(t, claims.Subject, user.ID.String())
+			},
+		},
+		"unknown destination": {
+			body: &api.CreateTokenRequest{Destination: "development"},
+			setup: func(t *testing.T, req *http.Request) {
+				accessKey, _ := createAccessKeyForUser(t, "julia@example.com")
+				req.Header.Set("Authorization", "Bearer "+accessKey)
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+			},
+		},
+		"missing destination": {
+			body: &api.CreateTokenRequest{},
+			setup: func(t *testing.T, req *http.Request) {
+				accessKey, _ := createAccessKeyForUser(t, "gren@example.com")
+				req.Header.Set("Authorization", "Bearer "+accessKey)
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+			},
+		},
+		"missing destination from an older client": {
+			body:    &api.CreateTokenRequest{},
+			version: "0.14.4",
+			setup: func(t *testing.T, req *http.Request) {
+				accessKey, _ := createAccessKeyForUser(t, "radical@example.com")
+				req.Header.Set("Authorization", "Bearer "+accessKey)
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+				respBody := &api.Error{}
+				err := json.Unmarshal(resp.Body.Bytes(), respBody)
+				assert.NilError(t, err)
+				assert.Assert(t, strings.Contains(respBody.Message, "upgrade the CLI and run 'infra login' again"), respBody.Message)
+			},
+		},
+		"destination from an older client": {
+			version: "0.14.4",
+			setup: func(t *testing.T, req *http.Request) {
+				accessKey, _ := createAccessKeyForUser(t, "edward@example.com")
+				req.Header.Set("Authorization", "Bearer "+accessKey)
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+				respBody := &api.CreateTokenResponse{}
+				err := json.Unmarshal(resp.Body.Bytes(), respBody)
+				assert.NilError(t, err)
+
+				parsed, err := jwt.ParseSigned(respBody.Token)
+				assert.NilError(t, err)
+
+				var claims jwt.Claims
+				assert.NilError(t, parsed.UnsafeClaimsWithoutVerification(&claims))
+				assert.DeepEqual(t, claims.Audience, jwt.Audience{"production", "unique-id"})
+			},
+		},
 		"access key directly created for user not in infra provider": {
 			setup: func(t *testing.T, req *http.Request) {
 				user := &models.Identity{
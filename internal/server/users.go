@@ -140,3 +140,7 @@ func (a *API) UpdateUser(c *gin.Context, r *api.UpdateUserRequest) (*api.User, e
 func (a *API) DeleteUser(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
 	return nil, access.DeleteIdentity(c, r.ID)
 }
+
+func (a *API) UnlockUser(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
+	return nil, access.UnlockCredential(c, r.ID)
+}
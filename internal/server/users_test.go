@@ -860,3 +860,44 @@ func TestAPI_UpdateUser(t *testing.T) {
 		})
 	}
 }
+
+func TestAPI_UnlockUser(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	user := &models.Identity{Name: "locked@example.com"}
+	assert.NilError(t, data.CreateIdentity(srv.DB(), user))
+	assert.NilError(t, data.CreateCredential(srv.DB(), &models.Credential{
+		IdentityID:          user.ID,
+		PasswordHash:        []byte("hash"),
+		FailedLoginAttempts: 3,
+		LockedUntil:         time.Now().Add(time.Hour),
+	}))
+
+	run := func(t *testing.T, accessKey string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/users/%s/unlock", user.ID), nil)
+		req.Header.Set("Authorization", "Bearer "+accessKey)
+		req.Header.Set("Infra-Version", apiVersionLatest)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("not authorized", func(t *testing.T) {
+		key, _ := createAccessKey(t, srv.DB(), "someone@example.com")
+		resp := run(t, key)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		resp := run(t, adminAccessKey(srv))
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		creds, err := data.GetCredential(srv.DB(), data.ByIdentityID(user.ID))
+		assert.NilError(t, err)
+		assert.Equal(t, creds.FailedLoginAttempts, 0)
+		assert.Assert(t, creds.LockedUntil.IsZero())
+	})
+}
@@ -0,0 +1,301 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/generate"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/validate"
+)
+
+func (a *API) ListWebhooks(c *gin.Context, r *api.ListWebhooksRequest) (*api.ListResponse[api.Webhook], error) {
+	p := models.RequestToPagination(r.PaginationRequest)
+	webhooks, err := access.ListWebhooks(c, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(webhooks, models.PaginationToResponse(p), func(webhook models.Webhook) api.Webhook {
+		return *webhook.ToAPI()
+	})
+
+	return result, nil
+}
+
+func (a *API) GetWebhook(c *gin.Context, r *api.Resource) (*api.Webhook, error) {
+	webhook, err := access.GetWebhook(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhook.ToAPI(), nil
+}
+
+func (a *API) CreateWebhook(c *gin.Context, r *api.CreateWebhookRequest) (*api.CreateWebhookResponse, error) {
+	if err := validateWebhookURL(r.URL); err != nil {
+		return nil, err
+	}
+
+	var err error
+	secret := r.Secret
+	if secret == "" {
+		secret, err = generate.CryptoRandom(32, generate.CharsetAlphaNumeric)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	webhook := &models.Webhook{
+		URL:        r.URL,
+		Secret:     models.EncryptedAtRest(secret),
+		EventTypes: r.Events,
+	}
+
+	if err := access.CreateWebhook(c, webhook); err != nil {
+		return nil, err
+	}
+
+	return &api.CreateWebhookResponse{Webhook: *webhook.ToAPI(), Secret: secret}, nil
+}
+
+func (a *API) DeleteWebhook(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
+	return nil, access.DeleteWebhook(c, r.ID)
+}
+
+const (
+	// webhookMaxAttempts is the number of times a delivery is attempted before
+	// it is marked as failed.
+	webhookMaxAttempts = 6
+	// webhookRetryInterval is the delay before the first retry of a delivery.
+	// The delay doubles after each failed attempt.
+	webhookRetryInterval = 30 * time.Second
+	// webhookDeliveryRetention is how long deliveries are kept after they are
+	// sent, or have failed.
+	webhookDeliveryRetention = 7 * 24 * time.Hour
+	// webhookDeliveryLease is how long a server has to send a delivery that
+	// it claimed, before another server may attempt it. It must be longer than
+	// the timeout of webhookHTTPClient.
+	webhookDeliveryLease = time.Minute
+)
+
+// webhookHTTPClient sends webhook deliveries. It does not use the proxy from
+// the environment, because a proxy would connect to the webhook address
+// without the checks of webhookDialControl.
+var webhookHTTPClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: webhookDialControl,
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+}
+
+// webhookDialControl prevents webhooks from connecting to addresses of the
+// host of the server, or of the cloud provider metadata service. It checks the
+// address after the hostname is resolved, so a hostname that resolves to one
+// of those addresses is also rejected.
+func webhookDialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip != nil && isBlockedWebhookIP(ip) {
+		return fmt.Errorf("webhook address %v is not allowed", host)
+	}
+	return nil
+}
+
+// metadataServiceIPs are the addresses of cloud provider metadata services
+// which are not in the link-local range.
+var metadataServiceIPs = []net.IP{
+	net.ParseIP("fd00:ec2::254"),
+}
+
+// isBlockedWebhookIP returns true if ip is a loopback, link-local, or
+// unspecified address, or the address of a metadata service. The link-local
+// range includes 169.254.169.254, which is used by most cloud providers.
+func isBlockedWebhookIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, metadata := range metadataServiceIPs {
+		if metadata.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateWebhookURL checks that rawURL is an http or https URL, and that it
+// does not point to the host of the server or a metadata service. Hostnames
+// are checked again when the webhook is delivered, after they are resolved.
+func validateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return validate.Error{"url": {"must be an http or https URL"}}
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if ip := net.ParseIP(host); ip != nil && isBlockedWebhookIP(ip) {
+		return validate.Error{"url": {"must not be a loopback, link-local, or metadata address"}}
+	}
+	switch {
+	case host == "localhost", strings.HasSuffix(host, ".localhost"), host == "metadata.google.internal":
+		return validate.Error{"url": {"must not be a loopback, link-local, or metadata address"}}
+	}
+	return nil
+}
+
+// deliverWebhooks sends the pending webhook deliveries of all organizations.
+// Deliveries that fail are retried with an exponential backoff, until they
+// have been attempted webhookMaxAttempts times. Each delivery is claimed
+// before it is sent, so that deliveries are only sent once when more than one
+// server is running.
+func (s *Server) deliverWebhooks(ctx context.Context) {
+	deliveries, err := data.ListPendingWebhookDeliveries(s.db, time.Now(), 100)
+	if err != nil {
+		logging.L.Warn().Err(err).Msg("failed to list pending webhook deliveries")
+		return
+	}
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		tx := data.NewTransaction(s.db.GormDB(), delivery.OrganizationID)
+		claimed, err := data.ClaimWebhookDelivery(tx, delivery, time.Now(), webhookDeliveryLease)
+		switch {
+		case err != nil:
+			logging.L.Warn().Err(err).Str("delivery", delivery.ID.String()).Msg("failed to claim webhook delivery")
+			continue
+		case !claimed:
+			continue
+		}
+
+		if err := deliverWebhook(ctx, tx, delivery); err != nil {
+			logging.L.Warn().Err(err).Str("delivery", delivery.ID.String()).Msg("failed to deliver webhook")
+		}
+	}
+
+	count, err := data.DeleteOldWebhookDeliveries(s.db, time.Now().Add(-webhookDeliveryRetention))
+	if err != nil {
+		logging.L.Warn().Err(err).Msg("failed to delete old webhook deliveries")
+		return
+	}
+	if count > 0 {
+		logging.Debugf("deleted %d old webhook deliveries", count)
+	}
+}
+
+// deliverWebhook sends a delivery that was claimed by ClaimWebhookDelivery,
+// which already counted the attempt, and saves the result.
+func deliverWebhook(ctx context.Context, tx data.GormTxn, delivery *models.WebhookDelivery) error {
+	webhook, err := data.GetWebhook(tx, data.ByID(delivery.WebhookID))
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		delivery.State = models.WebhookDeliveryStateFailed
+		delivery.LastError = "webhook was deleted"
+		return data.SaveWebhookDelivery(tx, delivery)
+	case err != nil:
+		return err
+	}
+
+	statusCode, sendErr := sendWebhook(ctx, webhook, delivery)
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+
+	switch {
+	case sendErr == nil:
+		delivery.State = models.WebhookDeliveryStateDelivered
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.State = models.WebhookDeliveryStateFailed
+		delivery.LastError = sendErr.Error()
+	default:
+		delivery.LastError = sendErr.Error()
+		backoff := webhookRetryInterval * time.Duration(1<<(delivery.Attempts-1))
+		delivery.NextAttemptAt = time.Now().UTC().Add(backoff)
+	}
+
+	return data.SaveWebhookDelivery(tx, delivery)
+}
+
+// sendWebhook posts the payload of the delivery to the webhook. The payload is
+// signed with the secret of the webhook using HMAC-SHA256, and the signature
+// is sent in the Infra-Signature header.
+func sendWebhook(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Infra/"+internal.FullVersion())
+	req.Header.Set("Infra-Signature", "sha256="+signWebhookPayload(string(webhook.Secret), delivery.Payload))
+	req.Header.Set("Infra-Webhook-Event", delivery.EventType)
+	req.Header.Set("Infra-Webhook-Delivery", delivery.ID.String())
+
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status: %v", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func signWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// createDisconnectedDestinationEvents creates a webhook event for each
+// destination that is disconnected at time now. Each destination is claimed
+// before its event is created, so that only one server creates the event.
+func (s *Server) createDisconnectedDestinationEvents(now time.Time) {
+	destinations, err := data.ListDisconnectedDestinations(s.db, now)
+	if err != nil {
+		logging.L.Warn().Err(err).Msg("failed to list disconnected destinations")
+		return
+	}
+
+	for i := range destinations {
+		destination := &destinations[i]
+		err := s.db.GormDB().Transaction(func(tx *gorm.DB) error {
+			orgTx := data.NewTransaction(tx, destination.OrganizationID)
+			claimed, err := data.ClaimDisconnectedDestination(orgTx, destination, now)
+			if err != nil || !claimed {
+				return err
+			}
+			return data.CreateWebhookEvent(orgTx, api.WebhookEventDestinationDisconnected, destination.ToAPI())
+		})
+		if err != nil {
+			logging.L.Warn().Err(err).Msg("failed to create destination disconnected event")
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func TestAPI_Webhooks(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes()
+
+	adminKey := adminAccessKey(srv)
+	userKey, user := createAccessKey(t, srv.DB(), "someone@example.com")
+
+	var created api.CreateWebhookResponse
+
+	t.Run("create", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/webhooks", adminKey, api.CreateWebhookRequest{
+			URL:    "https://example.com/events",
+			Events: []string{api.WebhookEventGrantCreated},
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+		assert.Equal(t, created.URL, "https://example.com/events")
+		assert.DeepEqual(t, created.Events, []string{api.WebhookEventGrantCreated})
+		assert.Equal(t, len(created.Secret), 32)
+	})
+
+	t.Run("create requires admin", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/webhooks", userKey, api.CreateWebhookRequest{
+			URL:    "https://example.com/events",
+			Events: []string{api.WebhookEventGrantCreated},
+		})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("create with invalid url", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/webhooks", adminKey, api.CreateWebhookRequest{
+			URL:    "ftp://example.com/events",
+			Events: []string{api.WebhookEventGrantCreated},
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("create with a local or metadata url", func(t *testing.T) {
+		for _, u := range []string{
+			"http://127.0.0.1:8080/events",
+			"http://[::1]/events",
+			"http://localhost/events",
+			"http://169.254.169.254/latest/meta-data",
+			"http://metadata.google.internal/computeMetadata/v1",
+		} {
+			resp := doRequest(t, routes, http.MethodPost, "/api/webhooks", adminKey, api.CreateWebhookRequest{
+				URL:    u,
+				Events: []string{api.WebhookEventGrantCreated},
+			})
+			assert.Equal(t, resp.Code, http.StatusBadRequest, u)
+		}
+	})
+
+	t.Run("create with unknown event", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/webhooks", adminKey, api.CreateWebhookRequest{
+			URL:    "https://example.com/events",
+			Events: []string{"grant.updated"},
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("list", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodGet, "/api/webhooks", adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var webhooks api.ListResponse[api.Webhook]
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &webhooks))
+		assert.Equal(t, webhooks.Count, 1)
+		assert.Equal(t, webhooks.Items[0].ID, created.ID)
+	})
+
+	t.Run("list requires admin", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodGet, "/api/webhooks", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("creating a grant queues a delivery", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodPost, "/api/grants", adminKey, api.CreateGrantRequest{
+			User:      user.ID,
+			Privilege: "view",
+			Resource:  "production",
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var grant api.Grant
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &grant))
+
+		deliveries, err := data.ListWebhookDeliveries(srv.DB(), nil)
+		assert.NilError(t, err)
+		assert.Equal(t, len(deliveries), 1)
+		assert.Equal(t, deliveries[0].WebhookID, created.ID)
+		assert.Equal(t, deliveries[0].EventType, api.WebhookEventGrantCreated)
+		assert.Equal(t, deliveries[0].State, models.WebhookDeliveryStatePending)
+
+		var event struct {
+			api.WebhookEvent
+			Data api.Grant `json:"data"`
+		}
+		assert.NilError(t, json.Unmarshal(deliveries[0].Payload, &event))
+		assert.Equal(t, event.Type, api.WebhookEventGrantCreated)
+		assert.Equal(t, event.Data.ID, grant.ID)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		resp := doRequest(t, routes, http.MethodDelete, "/api/webhooks/"+created.ID.String(), adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		resp = doRequest(t, routes, http.MethodGet, "/api/webhooks/"+created.ID.String(), adminKey, nil)
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+
+		deliveries, err := data.ListWebhookDeliveries(srv.DB(), nil)
+		assert.NilError(t, err)
+		assert.Equal(t, len(deliveries), 0)
+	})
+}
+
+func TestServer_DeliverWebhooks(t *testing.T) {
+	srv := setupServer(t)
+	db := srv.DB()
+
+	type received struct {
+		signature string
+		event     string
+		body      []byte
+	}
+	receivedCh := make(chan received, 10)
+	status := http.StatusOK
+
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.Check(t, err)
+		receivedCh <- received{
+			signature: r.Header.Get("Infra-Signature"),
+			event:     r.Header.Get("Infra-Webhook-Event"),
+			body:      body,
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(endpoint.Close)
+
+	// the endpoint listens on a loopback address, which webhookHTTPClient does
+	// not connect to.
+	defaultClient := webhookHTTPClient
+	webhookHTTPClient = endpoint.Client()
+	t.Cleanup(func() {
+		webhookHTTPClient = defaultClient
+	})
+
+	webhook := &models.Webhook{
+		URL:        endpoint.URL,
+		Secret:     "the-secret",
+		EventTypes: []string{api.WebhookEventUserCreated},
+	}
+	assert.NilError(t, data.CreateWebhook(db, webhook))
+
+	getDelivery := func(t *testing.T) models.WebhookDelivery {
+		t.Helper()
+		deliveries, err := data.ListWebhookDeliveries(db, nil)
+		assert.NilError(t, err)
+		assert.Equal(t, len(deliveries), 1)
+		return deliveries[0]
+	}
+
+	t.Run("success", func(t *testing.T) {
+		assert.NilError(t, data.CreateWebhookEvent(db, api.WebhookEventUserCreated, api.User{Name: "a@example.com"}))
+
+		srv.deliverWebhooks(context.Background())
+
+		got := <-receivedCh
+		assert.Equal(t, got.event, api.WebhookEventUserCreated)
+		assert.Equal(t, got.signature, "sha256="+signWebhookPayload("the-secret", got.body))
+
+		delivery := getDelivery(t)
+		assert.Equal(t, delivery.State, models.WebhookDeliveryStateDelivered)
+		assert.Equal(t, delivery.Attempts, 1)
+		assert.Equal(t, delivery.LastStatusCode, http.StatusOK)
+	})
+
+	t.Run("failure is retried", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		// remove the delivery from the previous test
+		assert.NilError(t, data.DeleteWebhooks(db, data.ByID(webhook.ID)))
+		webhook.ID = 0
+		assert.NilError(t, data.CreateWebhook(db, webhook))
+		assert.NilError(t, data.CreateWebhookEvent(db, api.WebhookEventUserCreated, api.User{Name: "b@example.com"}))
+
+		srv.deliverWebhooks(context.Background())
+		<-receivedCh
+
+		delivery := getDelivery(t)
+		assert.Equal(t, delivery.State, models.WebhookDeliveryStatePending)
+		assert.Equal(t, delivery.Attempts, 1)
+		assert.Equal(t, delivery.LastStatusCode, http.StatusInternalServerError)
+		assert.Assert(t, delivery.NextAttemptAt.After(time.Now()))
+
+		// not retried before the next attempt
+		srv.deliverWebhooks(context.Background())
+		assert.Equal(t, getDelivery(t).Attempts, 1)
+
+		// fails after the max attempts
+		delivery.Attempts = webhookMaxAttempts - 1
+		delivery.NextAttemptAt = time.Now().Add(-time.Second)
+		assert.NilError(t, data.SaveWebhookDelivery(db, &delivery))
+
+		srv.deliverWebhooks(context.Background())
+		<-receivedCh
+
+		delivery = getDelivery(t)
+		assert.Equal(t, delivery.State, models.WebhookDeliveryStateFailed)
+		assert.Equal(t, delivery.Attempts, webhookMaxAttempts)
+	})
+
+	t.Run("claimed delivery is not sent by another server", func(t *testing.T) {
+		status = http.StatusOK
+		assert.NilError(t, data.DeleteWebhooks(db, data.ByID(webhook.ID)))
+		webhook.ID = 0
+		assert.NilError(t, data.CreateWebhook(db, webhook))
+		assert.NilError(t, data.CreateWebhookEvent(db, api.WebhookEventUserCreated, api.User{Name: "c@example.com"}))
+
+		// another server lists the same delivery, and claims it first
+		listed := getDelivery(t)
+		other := listed
+		claimed, err := data.ClaimWebhookDelivery(db, &other, time.Now(), webhookDeliveryLease)
+		assert.NilError(t, err)
+		assert.Assert(t, claimed)
+
+		claimed, err = data.ClaimWebhookDelivery(db, &listed, time.Now(), webhookDeliveryLease)
+		assert.NilError(t, err)
+		assert.Assert(t, !claimed)
+
+		srv.deliverWebhooks(context.Background())
+		select {
+		case <-receivedCh:
+			t.Fatal("claimed delivery was sent")
+		default:
+		}
+
+		delivery := getDelivery(t)
+		assert.Equal(t, delivery.State, models.WebhookDeliveryStatePending)
+		assert.Equal(t, delivery.Attempts, 1)
+	})
+}
+
+func TestServer_CreateDisconnectedDestinationEvents(t *testing.T) {
+	srv := setupServer(t)
+	db := srv.DB()
+
+	webhook := &models.Webhook{
+		URL:        "https://hooks.example.com",
+		Secret:     "the-secret",
+		EventTypes: []string{api.WebhookEventDestinationDisconnected},
+	}
+	assert.NilError(t, data.CreateWebhook(db, webhook))
+
+	now := time.Now()
+	destination := &models.Destination{Name: "prod", UniqueID: "prod", LastSeenAt: now.Add(-10 * time.Minute)}
+	assert.NilError(t, data.CreateDestination(db, destination))
+	connected := &models.Destination{Name: "dev", UniqueID: "dev", LastSeenAt: now}
+	assert.NilError(t, data.CreateDestination(db, connected))
+	neverSeen := &models.Destination{Name: "staging", UniqueID: "staging"}
+	assert.NilError(t, data.CreateDestination(db, neverSeen))
+
+	countEvents := func(t *testing.T) int {
+		t.Helper()
+		deliveries, err := data.ListWebhookDeliveries(db, nil)
+		assert.NilError(t, err)
+		return len(deliveries)
+	}
+
+	// every server checks for disconnected destinations
+	srv.createDisconnectedDestinationEvents(now)
+	srv.createDisconnectedDestinationEvents(now)
+	assert.Equal(t, countEvents(t), 1)
+
+	t.Run("event is created again after the destination reconnects", func(t *testing.T) {
+		destination.LastSeenAt = now.Add(time.Minute)
+		assert.NilError(t, data.SaveDestination(db, destination))
+
+		later := now.Add(10 * time.Minute)
+		srv.createDisconnectedDestinationEvents(later)
+		srv.createDisconnectedDestinationEvents(later)
+		// the other destination also disconnected
+		assert.Equal(t, countEvents(t), 3)
+	})
+}
+
+func TestWebhookDialControl(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:80", "[::1]:443", "169.254.169.254:80", "[fe80::1]:80", "0.0.0.0:80", "[fd00:ec2::254]:80"} {
+		assert.ErrorContains(t, webhookDialControl("tcp", address, nil), "is not allowed", address)
+	}
+	for _, address := range []string{"93.184.216.34:443", "10.0.0.5:8080", "[2606:2800:220:1::]:443"} {
+		assert.NilError(t, webhookDialControl("tcp", address, nil), address)
+	}
+
+	t.Run("proxy from the environment is not used", func(t *testing.T) {
+		transport, ok := webhookHTTPClient.Transport.(*http.Transport)
+		assert.Assert(t, ok)
+		assert.Assert(t, transport.Proxy == nil)
+	})
+}
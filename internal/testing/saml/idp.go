@@ -0,0 +1,201 @@
+/*
+Package saml provides a SAML identity provider that signs responses for tests.
+*/
+package saml
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"gotest.tools/v3/assert"
+)
+
+type TestingT interface {
+	assert.TestingT
+	Helper()
+}
+
+const (
+	protocolNamespace  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	assertionNamespace = "urn:oasis:names:tc:SAML:2.0:assertion"
+
+	StatusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+)
+
+// IdP is an identity provider with a self-signed signing certificate.
+type IdP struct {
+	EntityID string
+	// CertificatePEM is the PEM encoded certificate used to sign responses
+	CertificatePEM string
+
+	signer *dsig.SigningContext
+}
+
+func NewIdP(t TestingT) *IdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NilError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NilError(t, err)
+
+	signer, err := dsig.NewSigningContext(key, [][]byte{der})
+	assert.NilError(t, err)
+	signer.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+
+	return &IdP{
+		EntityID:       "https://idp.example.com/metadata",
+		CertificatePEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		signer:         signer,
+	}
+}
+
+// assertionCount is used to give each assertion a unique ID.
+var assertionCount int64
+
+// ResponseOptions are the values of a response. Destination and Audience are
+// required, all other fields have defaults.
+type ResponseOptions struct {
+	// Destination is the URL of the assertion consumer service
+	Destination string
+	// Audience is the entity ID of the service provider
+	Audience string
+
+	// AssertionID defaults to an ID that is unique to the test binary
+	AssertionID string
+	// Recipient defaults to Destination
+	Recipient string
+	// Issuer defaults to the entity ID of the IdP
+	Issuer string
+	// Status defaults to StatusSuccess
+	Status string
+	// NameID defaults to alice@example.com
+	NameID string
+	// NotOnOrAfter defaults to five minutes from now
+	NotOnOrAfter time.Time
+	// Attributes are added to the attribute statement, in order of name
+	Attributes map[string][]string
+
+	// SignResponse signs the response instead of the assertion
+	SignResponse bool
+	// Modify is called with the response element after it is signed
+	Modify func(response *etree.Element)
+}
+
+// Response returns a base64 encoded response, like the one an identity
+// provider sends to the assertion consumer service.
+func (idp *IdP) Response(t TestingT, opts ResponseOptions) string {
+	t.Helper()
+	now := time.Now().UTC()
+	if opts.AssertionID == "" {
+		opts.AssertionID = fmt.Sprintf("_assertion%d", atomic.AddInt64(&assertionCount, 1))
+	}
+	if opts.Recipient == "" {
+		opts.Recipient = opts.Destination
+	}
+	if opts.Issuer == "" {
+		opts.Issuer = idp.EntityID
+	}
+	if opts.Status == "" {
+		opts.Status = StatusSuccess
+	}
+	if opts.NameID == "" {
+		opts.NameID = "alice@example.com"
+	}
+	if opts.NotOnOrAfter.IsZero() {
+		opts.NotOnOrAfter = now.Add(5 * time.Minute)
+	}
+	issueInstant := now.Format(time.RFC3339)
+	notOnOrAfter := opts.NotOnOrAfter.UTC().Format(time.RFC3339)
+
+	assertion := etree.NewElement("saml:Assertion")
+	assertion.CreateAttr("xmlns:saml", assertionNamespace)
+	assertion.CreateAttr("ID", opts.AssertionID)
+	assertion.CreateAttr("Version", "2.0")
+	assertion.CreateAttr("IssueInstant", issueInstant)
+	assertion.CreateElement("saml:Issuer").SetText(opts.Issuer)
+
+	subject := assertion.CreateElement("saml:Subject")
+	subject.CreateElement("saml:NameID").SetText(opts.NameID)
+	confirmation := subject.CreateElement("saml:SubjectConfirmation")
+	confirmation.CreateAttr("Method", "urn:oasis:names:tc:SAML:2.0:cm:bearer")
+	confirmationData := confirmation.CreateElement("saml:SubjectConfirmationData")
+	confirmationData.CreateAttr("Recipient", opts.Recipient)
+	confirmationData.CreateAttr("NotOnOrAfter", notOnOrAfter)
+
+	conditions := assertion.CreateElement("saml:Conditions")
+	conditions.CreateAttr("NotBefore", issueInstant)
+	conditions.CreateAttr("NotOnOrAfter", notOnOrAfter)
+	conditions.CreateElement("saml:AudienceRestriction").CreateElement("saml:Audience").SetText(opts.Audience)
+
+	names := make([]string, 0, len(opts.Attributes))
+	for name := range opts.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statement := assertion.CreateElement("saml:AttributeStatement")
+	for _, name := range names {
+		attr := statement.CreateElement("saml:Attribute")
+		attr.CreateAttr("Name", name)
+		for _, value := range opts.Attributes[name] {
+			attr.CreateElement("saml:AttributeValue").SetText(value)
+		}
+	}
+
+	if !opts.SignResponse {
+		signed, err := idp.signer.SignEnveloped(assertion)
+		assert.NilError(t, err)
+		assertion = signed
+	}
+
+	response := etree.NewElement("samlp:Response")
+	response.CreateAttr("xmlns:samlp", protocolNamespace)
+	response.CreateAttr("xmlns:saml", assertionNamespace)
+	response.CreateAttr("ID", "_response")
+	response.CreateAttr("Version", "2.0")
+	response.CreateAttr("IssueInstant", issueInstant)
+	response.CreateAttr("Destination", opts.Destination)
+	response.CreateElement("saml:Issuer").SetText(opts.Issuer)
+	response.CreateElement("samlp:Status").CreateElement("samlp:StatusCode").CreateAttr("Value", opts.Status)
+	response.AddChild(assertion)
+
+	if opts.SignResponse {
+		signed, err := idp.signer.SignEnveloped(response)
+		assert.NilError(t, err)
+		response = signed
+	}
+
+	doc := etree.NewDocument()
+	doc.SetRoot(response)
+	raw, err := doc.WriteToBytes()
+	assert.NilError(t, err)
+
+	if opts.Modify != nil {
+		// parse the response again, so that every element has a parent
+		doc = etree.NewDocument()
+		assert.NilError(t, doc.ReadFromBytes(raw))
+		opts.Modify(doc.Root())
+
+		raw, err = doc.WriteToBytes()
+		assert.NilError(t, err)
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
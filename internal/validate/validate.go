@@ -27,6 +27,11 @@ func Validate(req Request) error {
 func validateStruct(v reflect.Value) Error {
 	err := make(Error)
 
+	// unexported fields, like those of time.Time, can not be validated
+	if !v.CanInterface() {
+		return err
+	}
+
 	req, ok := v.Interface().(Request)
 	if ok && (v.Kind() != reflect.Pointer || !v.IsNil()) {
 		for _, rule := range req.ValidationRules() {
@@ -0,0 +1,73 @@
+package pki
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// GenerateSSHCA creates the key of a certificate authority for SSH
+// certificates. It returns the private key in PEM, and the public key in the
+// authorized_keys format that sshd reads from TrustedUserCAKeys.
+func GenerateSSHCA() (privateKeyPEM []byte, publicKey string, err error) {
+	pub, prv, err := ed25519.GenerateKey(randReader)
+	if err != nil {
+		return nil, "", fmt.Errorf("generating keys: %w", err)
+	}
+
+	privateKeyPEM, err = MarshalPrivateKey(prv)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, "", fmt.Errorf("ssh public key: %w", err)
+	}
+
+	return privateKeyPEM, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))), nil
+}
+
+// SignSSHUserCert signs an SSH user certificate for key with the CA private
+// key from GenerateSSHCA. The certificate is valid for lifetime, and only
+// for logins as one of the principals.
+func SignSSHUserCert(caKeyPEM []byte, key ssh.PublicKey, keyID string, principals []string, lifetime time.Duration) (*ssh.Certificate, error) {
+	signer, err := ssh.ParsePrivateKey(caKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ca private key: %w", err)
+	}
+
+	var serial [8]byte
+	if _, err := randReader.Read(serial[:]); err != nil {
+		return nil, fmt.Errorf("creating random serial: %w", err)
+	}
+
+	now := time.Now()
+	cert := &ssh.Certificate{
+		Key:             key,
+		Serial:          binary.BigEndian.Uint64(serial[:]),
+		CertType:        ssh.UserCert,
+		KeyId:           keyID,
+		ValidPrincipals: principals,
+		ValidAfter:      uint64(now.Add(-5 * time.Minute).Unix()),
+		ValidBefore:     uint64(now.Add(lifetime).Unix()),
+		Permissions: ssh.Permissions{
+			Extensions: map[string]string{
+				"permit-agent-forwarding": "",
+				"permit-port-forwarding":  "",
+				"permit-pty":              "",
+				"permit-user-rc":          "",
+			},
+		},
+	}
+
+	if err := cert.SignCert(randReader, signer); err != nil {
+		return nil, fmt.Errorf("signing ssh certificate: %w", err)
+	}
+
+	return cert, nil
+}
@@ -0,0 +1,74 @@
+package pki
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"gotest.tools/v3/assert"
+)
+
+func TestSignSSHUserCert(t *testing.T) {
+	caKeyPEM, caPublicKey, err := GenerateSSHCA()
+	assert.NilError(t, err)
+
+	caPub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(caPublicKey))
+	assert.NilError(t, err)
+
+	userPub, _, err := ed25519.GenerateKey(randReader)
+	assert.NilError(t, err)
+	userKey, err := ssh.NewPublicKey(userPub)
+	assert.NilError(t, err)
+
+	cert, err := SignSSHUserCert(caKeyPEM, userKey, "alice@example.com", []string{"web:ubuntu"}, 5*time.Minute)
+	assert.NilError(t, err)
+
+	assert.Equal(t, cert.CertType, uint32(ssh.UserCert))
+	assert.Equal(t, cert.KeyId, "alice@example.com")
+	assert.DeepEqual(t, cert.ValidPrincipals, []string{"web:ubuntu"})
+	assert.DeepEqual(t, cert.Key.Marshal(), userKey.Marshal())
+	assert.DeepEqual(t, cert.SignatureKey.Marshal(), caPub.Marshal())
+
+	validBefore := time.Unix(int64(cert.ValidBefore), 0)
+	assert.Assert(t, time.Until(validBefore) <= 5*time.Minute)
+	assert.Assert(t, time.Until(validBefore) > 4*time.Minute)
+
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return string(auth.Marshal()) == string(caPub.Marshal())
+		},
+	}
+
+	t.Run("principal allowed", func(t *testing.T) {
+		_, err := checker.Authenticate(connMetadata{user: "web:ubuntu"}, cert)
+		assert.NilError(t, err)
+	})
+
+	t.Run("principal not allowed", func(t *testing.T) {
+		_, err := checker.Authenticate(connMetadata{user: "web:root"}, cert)
+		assert.ErrorContains(t, err, `principal "web:root" not in the set of valid principals`)
+	})
+
+	t.Run("signed by a different CA", func(t *testing.T) {
+		otherKeyPEM, _, err := GenerateSSHCA()
+		assert.NilError(t, err)
+
+		other, err := SignSSHUserCert(otherKeyPEM, userKey, "alice@example.com", []string{"web:ubuntu"}, time.Minute)
+		assert.NilError(t, err)
+
+		_, err = checker.Authenticate(connMetadata{user: "web:ubuntu"}, other)
+		assert.ErrorContains(t, err, "certificate signed by unrecognized authority")
+	})
+}
+
+// connMetadata implements ssh.ConnMetadata for CertChecker.Authenticate,
+// which only reads the user.
+type connMetadata struct {
+	ssh.ConnMetadata
+	user string
+}
+
+func (c connMetadata) User() string {
+	return c.user
+}
@@ -1,6 +1,8 @@
 import useSWR from 'swr'
 import { useRouter } from 'next/router'
 
+import { redirectAfterLogin } from '../../lib/login'
+
 export default function Login({ children }) {
   const { data: auth, error } = useSWR('/api/users/self')
   const router = useRouter()
@@ -13,7 +15,7 @@ export default function Login({ children }) {
     // TODO (https://github.com/infrahq/infra/issues/1441): remove me when
     // using an OTP doesn't trigger authentication
     if (router.pathname !== '/login/finish') {
-      router.replace('/')
+      redirectAfterLogin(router, router.query.next)
       return null
     }
   }
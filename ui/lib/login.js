@@ -0,0 +1,27 @@
+// safeNext returns the path to return to after login, or '/' when next is not
+// a path on this server. The OpenID Connect authorization endpoint sends users
+// to the login page with the path to return to as next.
+export function safeNext(next) {
+  if (
+    typeof next === 'string' &&
+    next.startsWith('/') &&
+    !next.startsWith('//') &&
+    !next.startsWith('/\\')
+  ) {
+    return next
+  }
+
+  return '/'
+}
+
+// redirectAfterLogin leaves the login page for next. Paths outside of the UI,
+// like the authorization endpoint, require a full page load.
+export function redirectAfterLogin(router, next) {
+  const path = safeNext(next)
+  if (path === '/') {
+    router.replace('/')
+    return
+  }
+
+  window.location.replace(path)
+}
@@ -0,0 +1,97 @@
+import { useState } from 'react'
+import Head from 'next/head'
+import Link from 'next/link'
+
+import Fullscreen from '../../components/layouts/fullscreen'
+import ErrorMessage from '../../components/error-message'
+
+export default function Device() {
+  const [userCode, setUserCode] = useState('')
+  const [error, setError] = useState('')
+  const [approved, setApproved] = useState(false)
+
+  async function onSubmit(e) {
+    e.preventDefault()
+
+    try {
+      const res = await fetch('/api/device/approve', {
+        method: 'POST',
+        body: JSON.stringify({ userCode }),
+      })
+
+      if (!res.ok) {
+        throw await res.json()
+      }
+
+      setApproved(true)
+    } catch (e) {
+      if (e.code === 404) {
+        setError('invalid or expired code')
+      } else {
+        setError(e.message)
+      }
+    }
+
+    return false
+  }
+
+  return (
+    <div className='px-3 pt-8 pb-3'>
+      <Head>
+        <title>Device Login</title>
+      </Head>
+      <div className='mx-auto flex w-full max-w-xs flex-col items-center justify-center'>
+        <div className='mb-4 rounded-full border border-violet-200/25 p-2.5'>
+          <img alt='infra icon' className='h-12 w-12' src='/infra-color.svg' />
+        </div>
+        <h1 className='text-base font-bold leading-snug'>Device Login</h1>
+      </div>
+      {approved ? (
+        <p className='mt-12 text-center text-xs text-gray-300'>
+          The device is now logged in. You can close this window.
+        </p>
+      ) : (
+        <form onSubmit={onSubmit} className='mt-12 flex flex-col'>
+          <div className='my-2 w-full'>
+            <label
+              htmlFor='userCode'
+              className='text-3xs uppercase text-gray-500'
+            >
+              Code
+            </label>
+            <input
+              required
+              autoFocus
+              name='userCode'
+              placeholder='enter the code shown by infra login'
+              onChange={e => {
+                setUserCode(e.target.value)
+                setError('')
+              }}
+              className={`mb-1 w-full border-b border-gray-800 bg-transparent px-px py-2 text-2xs uppercase placeholder:normal-case placeholder:italic focus:border-b focus:outline-none focus:ring-gray-200 ${
+                error ? 'border-pink-500/60' : ''
+              }`}
+            />
+          </div>
+          <div className='mt-6 flex flex-row items-center justify-end'>
+            <Link href='/'>
+              <a className='border-0 px-6 py-3 text-2xs uppercase text-gray-400 hover:text-white focus:text-white focus:outline-none'>
+                Cancel
+              </a>
+            </Link>
+            <button
+              type='submit'
+              disabled={!userCode}
+              className='rounded-md border border-violet-300 px-5 py-2.5 text-center text-2xs text-violet-100 disabled:opacity-30'
+            >
+              Approve
+            </button>
+          </div>
+          {error && <ErrorMessage message={error} center />}
+        </form>
+      )}
+    </div>
+  )
+}
+
+Device.layout = page => <Fullscreen>{page}</Fullscreen>
@@ -2,6 +2,8 @@ import { useEffect } from 'react'
 import { useRouter } from 'next/router'
 import { useSWRConfig } from 'swr'
 
+import { redirectAfterLogin } from '../../lib/login'
+
 export default function Callback() {
   const { mutate } = useSWRConfig()
   const router = useRouter()
@@ -9,7 +11,7 @@ export default function Callback() {
   const { code, state } = router.query
 
   useEffect(() => {
-    async function login({ providerID, code, redirectURL }) {
+    async function login({ providerID, code, redirectURL, next }) {
       await fetch('/api/login', {
         method: 'POST',
         body: JSON.stringify({
@@ -22,11 +24,12 @@ export default function Callback() {
       })
 
       await mutate('/api/users/self')
-      router.replace('/')
+      redirectAfterLogin(router, next)
     }
 
     const providerID = window.localStorage.getItem('providerID')
     const redirectURL = window.localStorage.getItem('redirectURL')
+    const next = window.localStorage.getItem('next')
 
     if (
       state === window.localStorage.getItem('state') &&
@@ -38,10 +41,12 @@ export default function Callback() {
         providerID,
         code,
         redirectURL,
+        next,
       })
       window.localStorage.removeItem('providerID')
       window.localStorage.removeItem('state')
       window.localStorage.removeItem('redirectURL')
+      window.localStorage.removeItem('next')
     }
   }, [code, state, mutate, router])
 
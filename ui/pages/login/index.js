@@ -2,6 +2,7 @@ import { useRouter } from 'next/router'
 import { useState } from 'react'
 import useSWR, { useSWRConfig } from 'swr'
 import { useServerConfig } from '../../lib/serverconfig'
+import { redirectAfterLogin, safeNext } from '../../lib/login'
 
 import Link from 'next/link'
 
@@ -9,8 +10,9 @@ import { providers as providersList } from '../../lib/providers'
 
 import LoginLayout from '../../components/layouts/login'
 
-function oidcLogin({ id, clientID, authURL, scopes }) {
+function oidcLogin({ id, clientID, authURL, scopes }, next) {
   window.localStorage.setItem('providerID', id)
+  window.localStorage.setItem('next', safeNext(next))
 
   const state = [...Array(10)]
     .map(() => (~~(Math.random() * 36)).toString(36))
@@ -25,7 +27,14 @@ function oidcLogin({ id, clientID, authURL, scopes }) {
   )}&state=${state}`
 }
 
-export function Providers({ providers }) {
+// saml providers send the response to the server, which sets the auth cookie
+function samlLogin({ id }, next) {
+  document.location.href = `/saml/${id}/login?next=${encodeURIComponent(
+    safeNext(next)
+  )}`
+}
+
+export function Providers({ providers, next }) {
   return (
     <>
       <div className='mt-2 w-full max-w-sm'>
@@ -33,7 +42,9 @@ export function Providers({ providers }) {
           p =>
             p.kind && (
               <button
-                onClick={() => oidcLogin(p)}
+                onClick={() =>
+                  p.kind === 'saml' ? samlLogin(p, next) : oidcLogin(p, next)
+                }
                 key={p.id}
                 title={`${p.name} — ${p.url}`}
                 className='my-2 flex w-full items-center rounded-md border border-gray-700 px-4 py-3 hover:border-gray-600'
@@ -62,12 +73,11 @@ export function Providers({ providers }) {
 }
 
 export default function Login() {
-  const { data: { items: providers } = {} } = useSWR(
-    '/api/providers?limit=1000',
-    {
-      fallbackData: [],
-    }
-  )
+  const { data: { items } = {} } = useSWR('/api/providers?limit=1000', {
+    fallbackData: [],
+  })
+  // ldap providers login with a username and password, not a redirect
+  const providers = items?.filter(p => p.kind !== 'ldap')
   const { mutate } = useSWRConfig()
   const router = useRouter()
 
@@ -106,7 +116,7 @@ export default function Login() {
       }
 
       await mutate('/api/users/self')
-      router.replace('/')
+      redirectAfterLogin(router, router.query.next)
     } catch (e) {
       console.error(e)
       setError('Invalid credentials')
@@ -124,7 +134,7 @@ export default function Login() {
       </h2>
       {providers?.length > 0 && (
         <>
-          <Providers providers={providers || []} />
+          <Providers providers={providers || []} next={router.query.next} />
           <div className='relative mt-4 w-full'>
             <div
               className='absolute inset-0 flex items-center'